@@ -0,0 +1,163 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package activity collects the recent activity of a list, items added, completed and merged,
+// and renders it as an Atom feed for feed readers and automation tools.
+package activity
+
+import (
+	"encoding/xml"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// DefaultLimit is the number of entries in a feed.
+const DefaultLimit = 50
+
+// Kinds of activity.
+const (
+	KindAdded     = "added"
+	KindCompleted = "completed"
+	KindMerged    = "merged"
+)
+
+// namespace derives stable entry IDs, so feed readers do not show an entry twice.
+var namespace = uuid.MustParse("5b0f4c1e-8a8e-4b43-9d0c-3f6a2c1b7e21")
+
+// Entry is a single activity on a list.
+type Entry struct {
+	Kind     string
+	ItemID   string
+	ItemName string
+	At       time.Time
+}
+
+// Service collects list activity.
+type Service struct {
+	DB *gorm.DB
+}
+
+// NewService creates a new activity service.
+func NewService(db *gorm.DB) *Service {
+	return &Service{DB: db}
+}
+
+// Recent returns the latest activity of the list, newest first, at most limit entries.
+func (s *Service) Recent(listID string, limit int) ([]Entry, error) {
+	var added []models.ShoppingItem
+	err := s.DB.Where("list_id = ?", listID).Order("created_at DESC").Limit(limit).Find(&added).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var completed []models.ShoppingItem
+	err = s.DB.Where("list_id = ? AND completed = ? AND completed_at IS NOT NULL", listID, true).
+		Order("completed_at DESC").Limit(limit).Find(&completed).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var merges []models.ItemMerge
+	err = s.DB.Where("list_id = ?", listID).Order("created_at DESC").Limit(limit).Find(&merges).Error
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(added)+len(completed)+len(merges))
+	for _, item := range added {
+		entries = append(entries, Entry{Kind: KindAdded, ItemID: item.ID, ItemName: item.Name, At: item.CreatedAt})
+	}
+	for _, item := range completed {
+		entries = append(entries, Entry{Kind: KindCompleted, ItemID: item.ID, ItemName: item.Name, At: *item.CompletedAt})
+	}
+	for _, merge := range merges {
+		entries = append(entries, Entry{Kind: KindMerged, ItemID: merge.ItemID, ItemName: merge.ItemName, At: merge.CreatedAt})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].At.After(entries[j].At)
+	})
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
+
+// atomFeed and atomEntry are the parts of the Atom format the feed uses.
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID       string       `xml:"id"`
+	Title    string       `xml:"title"`
+	Updated  string       `xml:"updated"`
+	Category atomCategory `xml:"category"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// Atom writes the activity of the list as an Atom feed. selfURL is the URL the feed is served
+// under; it is only used for the self link.
+func Atom(w io.Writer, list models.ShoppingList, entries []Entry, selfURL string) error {
+	updated := list.UpdatedAt
+	if len(entries) > 0 && entries[0].At.After(updated) {
+		updated = entries[0].At
+	}
+
+	feed := atomFeed{
+		ID:      "urn:uuid:" + uuid.NewSHA1(namespace, []byte("list/"+list.ID)).String(),
+		Title:   list.Name,
+		Updated: updated.UTC().Format(time.RFC3339),
+		Link:    atomLink{Rel: "self", Href: selfURL},
+		Author:  atomAuthor{Name: "Shopping List"},
+	}
+	for _, entry := range entries {
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:       "urn:uuid:" + uuid.NewSHA1(namespace, []byte(entryKey(entry))).String(),
+			Title:    entry.ItemName + " " + entry.Kind,
+			Updated:  entry.At.UTC().Format(time.RFC3339),
+			Category: atomCategory{Term: entry.Kind},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return encoder.Encode(feed)
+}
+
+// entryKey identifies an entry. An item is added and completed once, but can be merged into
+// several times, so merges are told apart by their time.
+func entryKey(entry Entry) string {
+	key := "item/" + entry.ItemID + "/" + entry.Kind
+	if entry.Kind == KindMerged {
+		key += "/" + strconv.FormatInt(entry.At.UnixNano(), 10)
+	}
+	return key
+}
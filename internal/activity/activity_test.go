@@ -0,0 +1,107 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package activity
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+)
+
+func TestService_Recent(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+
+	base := time.Now().Add(-time.Hour)
+	completedAt := base.Add(30 * time.Minute)
+	records := []models.ShoppingItem{
+		{ID: "milk", ListID: "list", Name: "Milk", CreatedAt: base},
+		{ID: "eggs", ListID: "list", Name: "Eggs", CreatedAt: base.Add(10 * time.Minute), Completed: true, CompletedAt: &completedAt},
+		{ID: "bread", ListID: "list", Name: "Bread", CreatedAt: base.Add(20 * time.Minute)},
+		{ID: "nails", ListID: "other", Name: "Nails", CreatedAt: base.Add(40 * time.Minute)},
+	}
+	for _, record := range records {
+		if err := db.Create(&record).Error; err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+
+	db.Create(&models.ItemMerge{ID: "merge", ListID: "list", ItemID: "milk", ItemName: "Milk", Merged: 1, CreatedAt: base.Add(50 * time.Minute)})
+
+	entries, err := service.Recent("list", DefaultLimit)
+	if err != nil {
+		t.Fatalf("Failed to get activity: %v", err)
+	}
+
+	want := []struct{ kind, item string }{
+		{KindMerged, "milk"},
+		{KindCompleted, "eggs"},
+		{KindAdded, "bread"},
+		{KindAdded, "eggs"},
+		{KindAdded, "milk"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("Expected %d entries, got %d", len(want), len(entries))
+	}
+	for i, w := range want {
+		if entries[i].Kind != w.kind || entries[i].ItemID != w.item {
+			t.Errorf("Entry %d: expected %s %s, got %s %s", i, w.item, w.kind, entries[i].ItemID, entries[i].Kind)
+		}
+	}
+
+	limited, err := service.Recent("list", 2)
+	if err != nil {
+		t.Fatalf("Failed to get activity: %v", err)
+	}
+	if len(limited) != 2 || limited[0].Kind != KindMerged {
+		t.Errorf("Expected the 2 newest entries, got %+v", limited)
+	}
+}
+
+func TestAtom(t *testing.T) {
+	list := models.ShoppingList{ID: "list", Name: "Groceries", UpdatedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	entries := []Entry{
+		{Kind: KindCompleted, ItemID: "eggs", ItemName: "Eggs", At: time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)},
+		{Kind: KindAdded, ItemID: "eggs", ItemName: "Eggs", At: time.Date(2025, 2, 1, 10, 0, 0, 0, time.UTC)},
+	}
+
+	var buf bytes.Buffer
+	if err := Atom(&buf, list, entries, "https://example.com/feeds/token.atom"); err != nil {
+		t.Fatalf("Failed to render feed: %v", err)
+	}
+
+	var feed atomFeed
+	if err := xml.Unmarshal(buf.Bytes(), &feed); err != nil {
+		t.Fatalf("Failed to parse feed: %v", err)
+	}
+	if feed.Title != "Groceries" || feed.Updated != "2025-02-01T12:00:00Z" {
+		t.Errorf("Unexpected feed header: %+v", feed)
+	}
+	if feed.Link.Href != "https://example.com/feeds/token.atom" {
+		t.Errorf("Unexpected self link %q", feed.Link.Href)
+	}
+	if len(feed.Entries) != 2 || feed.Entries[0].Title != "Eggs completed" || feed.Entries[1].Title != "Eggs added" {
+		t.Fatalf("Unexpected entries: %+v", feed.Entries)
+	}
+	if feed.Entries[0].ID == feed.Entries[1].ID {
+		t.Error("Expected distinct entry IDs for adding and completing an item")
+	}
+
+	// Entry IDs are stable across renderings
+	var again bytes.Buffer
+	if err := Atom(&again, list, entries, ""); err != nil {
+		t.Fatalf("Failed to render feed: %v", err)
+	}
+	var second atomFeed
+	if err := xml.Unmarshal(again.Bytes(), &second); err != nil {
+		t.Fatalf("Failed to parse feed: %v", err)
+	}
+	if second.Entries[0].ID != feed.Entries[0].ID {
+		t.Error("Expected stable entry IDs")
+	}
+}
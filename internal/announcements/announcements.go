@@ -0,0 +1,125 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package announcements provides admin broadcast announcements such as maintenance windows
+// or policy changes, shown to all clients and optionally emailed to every user.
+package announcements
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oliverandrich/shopping-list-server/internal/mail"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/pii"
+	"gopkg.in/gomail.v2"
+	"gorm.io/gorm"
+)
+
+// Service provides announcement management and email broadcasting.
+type Service struct {
+	DB     *gorm.DB
+	Mailer *gomail.Dialer
+}
+
+// NewService creates a new announcements service with database and email capabilities.
+func NewService(db *gorm.DB, mailer *gomail.Dialer) *Service {
+	return &Service{
+		DB:     db,
+		Mailer: mailer,
+	}
+}
+
+// CreateAnnouncement stores a new announcement. Announcements without an expiry stay active
+// until they are deleted.
+func (s *Service) CreateAnnouncement(userID, title, body string, expiresAt *time.Time) (*models.Announcement, error) {
+	if expiresAt != nil && expiresAt.Before(time.Now()) {
+		return nil, errors.New("announcement must expire in the future")
+	}
+
+	announcement := models.Announcement{
+		ID:        uuid.New().String(),
+		Title:     strings.TrimSpace(title),
+		Body:      strings.TrimSpace(body),
+		ExpiresAt: expiresAt,
+		CreatedBy: userID,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.DB.Create(&announcement).Error; err != nil {
+		return nil, err
+	}
+
+	return &announcement, nil
+}
+
+// GetActiveAnnouncements retrieves all announcements that have not expired, newest first.
+func (s *Service) GetActiveAnnouncements() ([]models.Announcement, error) {
+	var announcements []models.Announcement
+	err := s.DB.Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Order("created_at DESC").
+		Find(&announcements).Error
+	return announcements, err
+}
+
+// DeleteAnnouncement removes an announcement.
+func (s *Service) DeleteAnnouncement(id string) error {
+	result := s.DB.Where("id = ?", id).Delete(&models.Announcement{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("announcement not found")
+	}
+	return nil
+}
+
+// Broadcast emails the announcement to every user and records when it was sent.
+// It returns the number of users emailed.
+func (s *Service) Broadcast(announcement *models.Announcement) (int, error) {
+	var emails []string
+	if err := s.DB.Model(&models.User{}).Pluck("email", &emails).Error; err != nil {
+		return 0, err
+	}
+	emails, err := pii.DecryptAll(emails)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, email := range emails {
+		if err := s.sendAnnouncement(email, announcement); err != nil {
+			slog.Warn("Failed to send announcement", "announcement", announcement.ID, "error", err)
+			continue
+		}
+		sent++
+	}
+
+	emailedAt := time.Now()
+	announcement.EmailedAt = &emailedAt
+	if err := s.DB.Model(announcement).Update("emailed_at", &emailedAt).Error; err != nil {
+		return sent, err
+	}
+
+	return sent, nil
+}
+
+// sendAnnouncement sends an announcement email to a user.
+func (s *Service) sendAnnouncement(email string, announcement *models.Announcement) error {
+	// Skip email sending in test environment
+	if os.Getenv("GO_ENV") == "test" {
+		return nil
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", os.Getenv("SMTP_FROM"))
+	m.SetHeader("To", email)
+	m.SetHeader("Subject", announcement.Title)
+	m.SetBody("text/plain", announcement.Body+"\n")
+
+	return mail.Send(s.Mailer, m)
+}
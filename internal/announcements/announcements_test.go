@@ -0,0 +1,104 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package announcements
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+)
+
+func TestService_CreateAnnouncement(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db, nil)
+
+	t.Run("create announcement", func(t *testing.T) {
+		announcement, err := service.CreateAnnouncement("admin-id", " Server moving ", "Downtime on Saturday.", nil)
+		if err != nil {
+			t.Fatalf("Failed to create announcement: %v", err)
+		}
+
+		if announcement.Title != "Server moving" {
+			t.Errorf("Expected trimmed title, got '%s'", announcement.Title)
+		}
+	})
+
+	t.Run("reject expiry in the past", func(t *testing.T) {
+		expiresAt := time.Now().Add(-time.Hour)
+		if _, err := service.CreateAnnouncement("admin-id", "Old news", "Body", &expiresAt); err == nil {
+			t.Error("Expected announcement expiring in the past to be rejected")
+		}
+	})
+}
+
+func TestService_GetActiveAnnouncements(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db, nil)
+
+	active, err := service.CreateAnnouncement("admin-id", "Active", "Body", nil)
+	if err != nil {
+		t.Fatalf("Failed to create announcement: %v", err)
+	}
+
+	expiredAt := time.Now().Add(-time.Hour)
+	expired := models.Announcement{
+		ID:        "expired-announcement",
+		Title:     "Expired",
+		Body:      "Body",
+		ExpiresAt: &expiredAt,
+		CreatedBy: "admin-id",
+		CreatedAt: time.Now(),
+	}
+	db.Create(&expired)
+
+	announcements, err := service.GetActiveAnnouncements()
+	if err != nil {
+		t.Fatalf("Failed to get announcements: %v", err)
+	}
+
+	if len(announcements) != 1 || announcements[0].ID != active.ID {
+		t.Errorf("Expected only the active announcement, got %d", len(announcements))
+	}
+
+	t.Run("delete announcement", func(t *testing.T) {
+		if err := service.DeleteAnnouncement(active.ID); err != nil {
+			t.Fatalf("Failed to delete announcement: %v", err)
+		}
+		if err := service.DeleteAnnouncement(active.ID); err == nil {
+			t.Error("Expected error when deleting a missing announcement")
+		}
+	})
+}
+
+func TestService_Broadcast(t *testing.T) {
+	testutils.SetupTestConfig(t)
+	defer testutils.CleanupTestEnv(t)
+
+	db := testutils.SetupTestDB(t)
+	service := NewService(db, nil)
+
+	for _, email := range []string{"one@example.com", "two@example.com"} {
+		user := models.User{ID: email, Email: email, JoinedAt: time.Now(), CreatedAt: time.Now()}
+		db.Create(&user)
+	}
+
+	announcement, err := service.CreateAnnouncement("admin-id", "Maintenance", "Back soon.", nil)
+	if err != nil {
+		t.Fatalf("Failed to create announcement: %v", err)
+	}
+
+	sent, err := service.Broadcast(announcement)
+	if err != nil {
+		t.Fatalf("Failed to broadcast announcement: %v", err)
+	}
+
+	if sent != 2 {
+		t.Errorf("Expected 2 emails, got %d", sent)
+	}
+	if announcement.EmailedAt == nil {
+		t.Error("Expected emailed_at to be set")
+	}
+}
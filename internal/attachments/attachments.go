@@ -0,0 +1,262 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package attachments provides file attachments on list items, including per-user storage
+// quotas, automatic downscaling of large photos and server-side thumbnails. Files are kept
+// in the configured storage backend.
+package attachments
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/pii"
+	"github.com/oliverandrich/shopping-list-server/internal/storage"
+	"gorm.io/gorm"
+)
+
+// ErrQuotaExceeded is returned when an upload would exceed the user's storage quota.
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+// Service provides attachment management on top of a storage backend.
+type Service struct {
+	DB *gorm.DB
+	// Storage keeps the attachment files. Attachments are disabled when nil.
+	Storage storage.Storage
+	// QuotaBytes is the storage available to each user. Zero means unlimited.
+	QuotaBytes int64
+	// MaxFileBytes is the largest file that can be uploaded.
+	MaxFileBytes int64
+	// MaxImageDimension is the longest side large images are downscaled to before storing.
+	MaxImageDimension int
+	// ThumbnailDimension is the longest side of generated thumbnails.
+	ThumbnailDimension int
+}
+
+// NewService creates a new attachments service. Attachments stay disabled until Storage is set.
+func NewService(db *gorm.DB) *Service {
+	return &Service{
+		DB:                 db,
+		MaxFileBytes:       20 * 1024 * 1024,
+		MaxImageDimension:  2048,
+		ThumbnailDimension: 256,
+	}
+}
+
+// Enabled reports whether attachments are configured.
+func (s *Service) Enabled() bool {
+	return s.Storage != nil
+}
+
+// Upload stores a new attachment for an item. Images are downscaled and get a thumbnail.
+func (s *Service) Upload(userID, listID, itemID, fileName string, data []byte) (*models.Attachment, error) {
+	if !s.Enabled() {
+		return nil, errors.New("attachments are disabled")
+	}
+	if len(data) == 0 {
+		return nil, errors.New("attachment is empty")
+	}
+	if int64(len(data)) > s.MaxFileBytes {
+		return nil, errors.New("attachment is too large")
+	}
+
+	var count int64
+	s.DB.Model(&models.ShoppingItem{}).Where("id = ? AND list_id = ?", itemID, listID).Count(&count)
+	if count == 0 {
+		return nil, errors.New("item not found")
+	}
+
+	contentType := http.DetectContentType(data)
+
+	var thumbnail []byte
+	if isImage(contentType) {
+		resized, thumb, err := processImage(data, contentType, s.MaxImageDimension, s.ThumbnailDimension)
+		if err != nil {
+			return nil, err
+		}
+		data, thumbnail = resized, thumb
+	}
+
+	size := int64(len(data) + len(thumbnail))
+	if s.QuotaBytes > 0 {
+		used, err := s.usedBytes(userID)
+		if err != nil {
+			return nil, err
+		}
+		if used+size > s.QuotaBytes {
+			return nil, ErrQuotaExceeded
+		}
+	}
+
+	attachment := models.Attachment{
+		ID:            uuid.New().String(),
+		ListID:        listID,
+		ItemID:        itemID,
+		UserID:        userID,
+		FileName:      filepath.Base(strings.TrimSpace(fileName)),
+		ContentType:   contentType,
+		Size:          int64(len(data)),
+		ThumbnailSize: int64(len(thumbnail)),
+		CreatedAt:     time.Now(),
+	}
+
+	if err := s.Storage.Put(key(attachment.ID, false), data, contentType); err != nil {
+		return nil, err
+	}
+	if thumbnail != nil {
+		if err := s.Storage.Put(key(attachment.ID, true), thumbnail, thumbnailContentType(contentType)); err != nil {
+			s.removeFiles(attachment.ID)
+			return nil, err
+		}
+	}
+
+	if err := s.DB.Create(&attachment).Error; err != nil {
+		s.removeFiles(attachment.ID)
+		return nil, err
+	}
+
+	return &attachment, nil
+}
+
+// GetItemAttachments retrieves all attachments of an item, oldest first.
+func (s *Service) GetItemAttachments(itemID string) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	err := s.DB.Where("item_id = ?", itemID).Order("created_at ASC").Find(&attachments).Error
+	return attachments, err
+}
+
+// GetAttachment retrieves an attachment of an item.
+func (s *Service) GetAttachment(id, itemID string) (*models.Attachment, error) {
+	var attachment models.Attachment
+	if err := s.DB.Where("id = ? AND item_id = ?", id, itemID).First(&attachment).Error; err != nil {
+		return nil, errors.New("attachment not found")
+	}
+	return &attachment, nil
+}
+
+// Open returns the content of an attachment or its thumbnail.
+func (s *Service) Open(attachment *models.Attachment, thumbnail bool) (io.ReadCloser, error) {
+	if thumbnail && attachment.ThumbnailSize == 0 {
+		return nil, errors.New("attachment has no thumbnail")
+	}
+	return s.Storage.Get(key(attachment.ID, thumbnail))
+}
+
+// PresignURL returns a URL for downloading an attachment or its thumbnail directly from the
+// storage backend. It returns storage.ErrPresignNotSupported for backends without presigning.
+func (s *Service) PresignURL(attachment *models.Attachment, thumbnail bool, expires time.Duration) (string, error) {
+	if thumbnail && attachment.ThumbnailSize == 0 {
+		return "", errors.New("attachment has no thumbnail")
+	}
+	return s.Storage.PresignGet(key(attachment.ID, thumbnail), expires)
+}
+
+// ThumbnailContentType returns the content type of an attachment's thumbnail.
+func ThumbnailContentType(attachment *models.Attachment) string {
+	return thumbnailContentType(attachment.ContentType)
+}
+
+// DeleteAttachment removes an attachment and its files.
+func (s *Service) DeleteAttachment(attachment *models.Attachment) error {
+	if err := s.DB.Delete(attachment).Error; err != nil {
+		return err
+	}
+	s.removeFiles(attachment.ID)
+	return nil
+}
+
+// DeleteItemAttachments removes all attachments of an item.
+func (s *Service) DeleteItemAttachments(itemID string) error {
+	return s.deleteWhere("item_id = ?", itemID)
+}
+
+// DeleteListAttachments removes all attachments of the items of a list.
+func (s *Service) DeleteListAttachments(listID string) error {
+	return s.deleteWhere("list_id = ?", listID)
+}
+
+// Usage reports the storage used by a user's attachments.
+func (s *Service) Usage(userID string) (*models.StorageUsage, error) {
+	usage := models.StorageUsage{}
+	err := s.DB.Model(&models.Attachment{}).
+		Select("COUNT(*) AS attachments, COALESCE(SUM(size + thumbnail_size), 0) AS used_bytes").
+		Where("user_id = ?", userID).
+		Scan(&usage).Error
+	if err != nil {
+		return nil, err
+	}
+	// Scan resets the fields the query does not select
+	usage.UserID, usage.QuotaBytes = userID, s.QuotaBytes
+	return &usage, nil
+}
+
+// GetAllUsage reports the storage used by every user with attachments, largest first.
+func (s *Service) GetAllUsage() ([]models.StorageUsage, error) {
+	var usages []models.StorageUsage
+	err := s.DB.Model(&models.Attachment{}).
+		Select("attachments.user_id AS user_id, users.email AS email, COUNT(*) AS attachments, " +
+			"SUM(attachments.size + attachments.thumbnail_size) AS used_bytes").
+		Joins("LEFT JOIN users ON users.id = attachments.user_id").
+		Group("attachments.user_id, users.email").
+		Order("used_bytes DESC").
+		Scan(&usages).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range usages {
+		usages[i].QuotaBytes = s.QuotaBytes
+		if usages[i].Email, err = pii.Decrypt(usages[i].Email); err != nil {
+			return nil, err
+		}
+	}
+	return usages, nil
+}
+
+// usedBytes returns the storage used by a user's attachments including thumbnails.
+func (s *Service) usedBytes(userID string) (int64, error) {
+	usage, err := s.Usage(userID)
+	if err != nil {
+		return 0, err
+	}
+	return usage.UsedBytes, nil
+}
+
+// deleteWhere removes all attachments matching the condition and their files.
+func (s *Service) deleteWhere(query string, args ...interface{}) error {
+	var ids []string
+	if err := s.DB.Model(&models.Attachment{}).Where(query, args...).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if err := s.DB.Where("id IN ?", ids).Delete(&models.Attachment{}).Error; err != nil {
+		return err
+	}
+	for _, id := range ids {
+		s.removeFiles(id)
+	}
+	return nil
+}
+
+// key returns the storage key of an attachment or its thumbnail.
+func key(id string, thumbnail bool) string {
+	if thumbnail {
+		return "attachments/" + id + "_thumb"
+	}
+	return "attachments/" + id
+}
+
+// removeFiles deletes the files of an attachment, ignoring files that do not exist.
+func (s *Service) removeFiles(id string) {
+	_ = s.Storage.Delete(key(id, false))
+	_ = s.Storage.Delete(key(id, true))
+}
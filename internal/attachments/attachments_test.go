@@ -0,0 +1,208 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package attachments
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/storage"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+	"gorm.io/gorm"
+)
+
+func setupTestService(t *testing.T) (*Service, *gorm.DB) {
+	t.Helper()
+
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+	service.Storage = storage.NewLocal(t.TempDir())
+
+	item := models.ShoppingItem{ID: "item-1", ListID: "list-1", Name: "Coffee", Tags: "[]", CreatedAt: time.Now()}
+	if err := db.Create(&item).Error; err != nil {
+		t.Fatalf("Failed to create test item: %v", err)
+	}
+
+	return service, db
+}
+
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestService_Upload(t *testing.T) {
+	service, _ := setupTestService(t)
+	service.MaxImageDimension = 100
+	service.ThumbnailDimension = 20
+
+	t.Run("downscale image and create thumbnail", func(t *testing.T) {
+		attachment, err := service.Upload("user-1", "list-1", "item-1", "photo.png", testPNG(t, 400, 200))
+		if err != nil {
+			t.Fatalf("Failed to upload attachment: %v", err)
+		}
+
+		if attachment.ContentType != "image/png" {
+			t.Errorf("Expected content type image/png, got %s", attachment.ContentType)
+		}
+		if attachment.ThumbnailSize == 0 {
+			t.Error("Expected a thumbnail to be generated")
+		}
+
+		content, err := service.Open(attachment, false)
+		if err != nil {
+			t.Fatalf("Failed to open attachment: %v", err)
+		}
+		defer func() { _ = content.Close() }()
+
+		config, _, err := image.DecodeConfig(content)
+		if err != nil {
+			t.Fatalf("Failed to decode stored image: %v", err)
+		}
+		if config.Width != 100 || config.Height != 50 {
+			t.Errorf("Expected image downscaled to 100x50, got %dx%d", config.Width, config.Height)
+		}
+
+		thumbnail, err := service.Open(attachment, true)
+		if err != nil {
+			t.Fatalf("Failed to open thumbnail: %v", err)
+		}
+		defer func() { _ = thumbnail.Close() }()
+
+		config, _, err = image.DecodeConfig(thumbnail)
+		if err != nil {
+			t.Fatalf("Failed to decode thumbnail: %v", err)
+		}
+		if config.Width != 20 || config.Height != 10 {
+			t.Errorf("Expected thumbnail of 20x10, got %dx%d", config.Width, config.Height)
+		}
+	})
+
+	t.Run("store other files unchanged", func(t *testing.T) {
+		data := []byte("receipt total 12.34")
+		attachment, err := service.Upload("user-1", "list-1", "item-1", "../receipt.txt", data)
+		if err != nil {
+			t.Fatalf("Failed to upload attachment: %v", err)
+		}
+
+		if attachment.FileName != "receipt.txt" {
+			t.Errorf("Expected sanitized file name, got %s", attachment.FileName)
+		}
+		if attachment.ThumbnailSize != 0 {
+			t.Error("Expected no thumbnail for text files")
+		}
+
+		content, err := service.Open(attachment, false)
+		if err != nil {
+			t.Fatalf("Failed to open attachment: %v", err)
+		}
+		defer func() { _ = content.Close() }()
+
+		stored, _ := io.ReadAll(content)
+		if !bytes.Equal(stored, data) {
+			t.Error("Expected file content to be stored unchanged")
+		}
+	})
+
+	t.Run("reject unknown item", func(t *testing.T) {
+		if _, err := service.Upload("user-1", "list-1", "missing", "a.txt", []byte("a")); err == nil {
+			t.Error("Expected upload to unknown item to fail")
+		}
+	})
+
+	t.Run("reject invalid image", func(t *testing.T) {
+		data := append([]byte("\x89PNG\r\n\x1a\n"), []byte("garbage")...)
+		if _, err := service.Upload("user-1", "list-1", "item-1", "broken.png", data); err == nil {
+			t.Error("Expected invalid image to be rejected")
+		}
+	})
+}
+
+func TestService_Quota(t *testing.T) {
+	service, _ := setupTestService(t)
+	service.QuotaBytes = 100
+
+	if _, err := service.Upload("user-1", "list-1", "item-1", "a.txt", bytes.Repeat([]byte("a"), 60)); err != nil {
+		t.Fatalf("Failed to upload attachment: %v", err)
+	}
+
+	_, err := service.Upload("user-1", "list-1", "item-1", "b.txt", bytes.Repeat([]byte("b"), 60))
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected quota to be exceeded, got %v", err)
+	}
+
+	if _, err := service.Upload("user-2", "list-1", "item-1", "c.txt", bytes.Repeat([]byte("c"), 60)); err != nil {
+		t.Errorf("Expected quota to be tracked per user, got %v", err)
+	}
+
+	usage, err := service.Usage("user-1")
+	if err != nil {
+		t.Fatalf("Failed to get usage: %v", err)
+	}
+	if usage.UsedBytes != 60 || usage.Attachments != 1 || usage.QuotaBytes != 100 {
+		t.Errorf("Unexpected usage: %+v", usage)
+	}
+
+	usages, err := service.GetAllUsage()
+	if err != nil {
+		t.Fatalf("Failed to get all usage: %v", err)
+	}
+	if len(usages) != 2 {
+		t.Errorf("Expected usage of 2 users, got %d", len(usages))
+	}
+}
+
+func TestService_DeleteItemAttachments(t *testing.T) {
+	service, _ := setupTestService(t)
+
+	attachment, err := service.Upload("user-1", "list-1", "item-1", "a.txt", []byte("a"))
+	if err != nil {
+		t.Fatalf("Failed to upload attachment: %v", err)
+	}
+
+	if err := service.DeleteItemAttachments("item-1"); err != nil {
+		t.Fatalf("Failed to delete attachments: %v", err)
+	}
+
+	if _, err := service.GetAttachment(attachment.ID, "item-1"); err == nil {
+		t.Error("Expected attachment to be deleted")
+	}
+	if _, err := service.Open(attachment, false); err == nil {
+		t.Error("Expected attachment file to be deleted")
+	}
+}
+
+func TestDownscale(t *testing.T) {
+	data, contentType, err := Downscale(testPNG(t, 300, 100), 150)
+	if err != nil {
+		t.Fatalf("Failed to downscale image: %v", err)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || contentType != "image/png" || config.Width != 150 || config.Height != 50 {
+		t.Errorf("Unexpected downscaled image: %s %+v (%v)", contentType, config, err)
+	}
+
+	if _, _, err := Downscale([]byte("not an image"), 150); err == nil {
+		t.Error("Expected non-images to be rejected")
+	}
+}
@@ -0,0 +1,175 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package attachments
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+// maxImagePixels guards against decompression bombs when decoding uploaded images.
+const maxImagePixels = 64 * 1024 * 1024
+
+// isImage reports whether a content type is an image format that can be resized.
+func isImage(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+		return true
+	}
+	return false
+}
+
+// processImage downscales an image whose longest side exceeds maxDimension and generates
+// a thumbnail. Images that are small enough are kept byte for byte; animated GIFs are never
+// re-encoded.
+func processImage(data []byte, contentType string, maxDimension, thumbnailDimension int) ([]byte, []byte, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, errors.New("invalid image")
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, nil, errors.New("image is too large")
+	}
+
+	img, err := decode(data, contentType)
+	if err != nil {
+		return nil, nil, errors.New("invalid image")
+	}
+
+	if contentType != "image/gif" && exceeds(img, maxDimension) {
+		img = resize(img, maxDimension)
+		if data, err = encode(img, contentType); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	thumbnail, err := encode(resize(img, thumbnailDimension), contentType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return data, thumbnail, nil
+}
+
+// Downscale re-encodes a JPEG, PNG or GIF image so its longest side is at most maxDimension
+// and returns it with its content type. Re-encoding drops metadata such as EXIF data; GIFs
+// are encoded as PNG of their first frame.
+func Downscale(data []byte, maxDimension int) ([]byte, string, error) {
+	contentType := http.DetectContentType(data)
+	if !isImage(contentType) {
+		return nil, "", errors.New("unsupported image format")
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", errors.New("invalid image")
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, "", errors.New("image is too large")
+	}
+
+	img, err := decode(data, contentType)
+	if err != nil {
+		return nil, "", errors.New("invalid image")
+	}
+	encoded, err := encode(resize(img, maxDimension), contentType)
+	if err != nil {
+		return nil, "", err
+	}
+	return encoded, thumbnailContentType(contentType), nil
+}
+
+// thumbnailContentType returns the content type thumbnails of images of the given type are encoded as.
+func thumbnailContentType(contentType string) string {
+	if contentType == "image/jpeg" {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
+
+// decode decodes an image of the given content type.
+func decode(data []byte, contentType string) (image.Image, error) {
+	switch contentType {
+	case "image/jpeg":
+		return jpeg.Decode(bytes.NewReader(data))
+	case "image/png":
+		return png.Decode(bytes.NewReader(data))
+	case "image/gif":
+		return gif.Decode(bytes.NewReader(data))
+	}
+	return nil, errors.New("unsupported image format")
+}
+
+// encode encodes an image as JPEG, or as PNG for formats that may be transparent.
+func encode(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// exceeds reports whether the longest side of an image is larger than maxDimension.
+func exceeds(img image.Image, maxDimension int) bool {
+	bounds := img.Bounds()
+	return maxDimension > 0 && (bounds.Dx() > maxDimension || bounds.Dy() > maxDimension)
+}
+
+// resize scales an image down so its longest side is at most maxDimension, averaging
+// all source pixels that fall into each target pixel. Smaller images are returned as is.
+func resize(src image.Image, maxDimension int) image.Image {
+	if !exceeds(src, maxDimension) {
+		return src
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	newWidth, newHeight := maxDimension, maxDimension
+	if width >= height {
+		newHeight = max(1, height*maxDimension/width)
+	} else {
+		newWidth = max(1, width*maxDimension/height)
+	}
+
+	dst := image.NewRGBA64(image.Rect(0, 0, newWidth, newHeight))
+	for y := 0; y < newHeight; y++ {
+		y0 := bounds.Min.Y + y*height/newHeight
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*height/newHeight)
+		for x := 0; x < newWidth; x++ {
+			x0 := bounds.Min.X + x*width/newWidth
+			x1 := max(x0+1, bounds.Min.X+(x+1)*width/newWidth)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					b += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
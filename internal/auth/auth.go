@@ -6,17 +6,27 @@
 package auth
 
 import (
-	"crypto/rand"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/oliverandrich/shopping-list-server/internal/codes"
+	"github.com/oliverandrich/shopping-list-server/internal/mail"
 	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/pii"
+	"github.com/oliverandrich/shopping-list-server/internal/ratelimit"
 	"gopkg.in/gomail.v2"
 	"gorm.io/gorm"
 )
@@ -26,29 +36,82 @@ type Service struct {
 	DB        *gorm.DB
 	JWTSecret []byte
 	Mailer    *gomail.Dialer
+	// APIKeyRateLimit is the number of requests per minute allowed for API keys without a limit
+	// of their own.
+	APIKeyRateLimit int
+	// MagicLinkLifetime is how long a login code stays valid.
+	MagicLinkLifetime time.Duration
+	// TokenLifetime is how long an issued JWT stays valid.
+	TokenLifetime time.Duration
+	// CodeLength is the length of login codes unless the system settings configure one.
+	CodeLength int
+	// LoginCodeLimit is the number of login codes an address may request per hour, and
+	// VerifyAttemptLimit the number of attempts to verify a code; 0 disables the limit.
+	LoginCodeLimit     int
+	VerifyAttemptLimit int
+	// LoginLinkURL is the page of the client app that logs in with the token of a login link,
+	// e.g. https://app.example/login. Login emails contain only the code while it is empty.
+	LoginLinkURL string
+
+	apiKeyLimiter *ratelimit.Limiter
 }
 
 // NewService creates a new authentication service with database, JWT secret, and email mailer.
 func NewService(db *gorm.DB, jwtSecret []byte, mailer *gomail.Dialer) *Service {
 	return &Service{
-		DB:        db,
-		JWTSecret: jwtSecret,
-		Mailer:    mailer,
+		DB:                 db,
+		JWTSecret:          jwtSecret,
+		Mailer:             mailer,
+		APIKeyRateLimit:    DefaultAPIKeyRateLimit,
+		MagicLinkLifetime:  DefaultMagicLinkLifetime,
+		TokenLifetime:      DefaultTokenLifetime,
+		CodeLength:         DefaultLoginCodeLength,
+		LoginCodeLimit:     DefaultLoginCodeLimit,
+		VerifyAttemptLimit: DefaultVerifyAttemptLimit,
+		apiKeyLimiter:      ratelimit.New(time.Minute),
 	}
 }
 
-// GenerateCode generates a secure 6-digit numeric code for magic link authentication.
-func GenerateCode() string {
-	bytes := make([]byte, 3)
-	if _, err := rand.Read(bytes); err != nil {
-		// Fallback to time-based random if crypto/rand fails
-		return fmt.Sprintf("%06d", time.Now().UnixNano()%1000000)
+// Login code format used when neither the server configuration nor the system settings
+// configure one. Shorter codes are never generated.
+const (
+	DefaultLoginCodeLength   = 6
+	DefaultLoginCodeAlphabet = "numeric"
+)
+
+// LoginCodeFormat is the length and alphabet name of the codes sent for login.
+type LoginCodeFormat struct {
+	Length   int
+	Alphabet string
+}
+
+// LoginCodeFormat returns the login code format of the system settings, falling back to digits
+// of the configured length before setup or for unset or invalid settings.
+func (s *Service) LoginCodeFormat() LoginCodeFormat {
+	format := LoginCodeFormat{Length: max(s.CodeLength, DefaultLoginCodeLength), Alphabet: DefaultLoginCodeAlphabet}
+
+	var settings models.SystemSettings
+	if err := s.DB.First(&settings).Error; err != nil {
+		return format
+	}
+	if settings.LoginCodeLength >= DefaultLoginCodeLength {
+		format.Length = settings.LoginCodeLength
 	}
-	return fmt.Sprintf("%06d", int(bytes[0])<<16|int(bytes[1])<<8|int(bytes[2]))[:6]
+	if _, ok := codes.Alphabets[settings.LoginCodeAlphabet]; ok {
+		format.Alphabet = settings.LoginCodeAlphabet
+	}
+	return format
+}
+
+// GenerateLoginCode generates a login code in the configured format.
+func (s *Service) GenerateLoginCode() string {
+	format := s.LoginCodeFormat()
+	return codes.Generate(format.Length, codes.Alphabets[format.Alphabet])
 }
 
-// SendMagicLink sends a magic link code to the specified email address.
-func (s *Service) SendMagicLink(email, code string) error {
+// SendMagicLink sends a magic link code to the specified email address, along with a link to
+// tap instead for a login link token.
+func (s *Service) SendMagicLink(email, code, linkToken string) error {
 	// Skip email sending in test environment
 	if os.Getenv("GO_ENV") == "test" {
 		return nil
@@ -59,26 +122,33 @@ func (s *Service) SendMagicLink(email, code string) error {
 	m.SetHeader("To", email)
 	m.SetHeader("Subject", "Your Shopping List Login Code")
 
+	link := ""
+	if linkToken != "" {
+		link = fmt.Sprintf("\nOr log in by opening this link: %s\n", s.LoginLinkFor(linkToken))
+	}
 	body := fmt.Sprintf(`
 Your login code is: %s
-
-This code will expire in 15 minutes.
+%s
+This code will expire in %d minutes.
 
 If you didn't request this, please ignore this email.
-	`, code)
+	`, code, link, int(s.MagicLinkLifetime.Minutes()))
 
 	m.SetBody("text/plain", body)
 
-	return s.Mailer.DialAndSend(m)
+	return mail.Send(s.Mailer, m)
 }
 
 // CreateMagicLink creates a new magic link for the given email and returns the code.
 func (s *Service) CreateMagicLink(email string) (string, error) {
-	code := GenerateCode()
-	expiresAt := time.Now().Add(15 * time.Minute)
+	email = mail.NormalizeAddress(email)
 
-	// Clean up old codes for this email
+	code := s.GenerateLoginCode()
+	expiresAt := time.Now().Add(s.MagicLinkLifetime)
+
+	// Clean up old codes for this email, the new code gets its own verification attempts
 	s.DB.Where("email = ?", email).Delete(&models.MagicLink{})
+	s.DB.Where("key = ?", addressKey("verify", email)).Delete(&models.LoginThrottle{})
 
 	// Store magic link
 	magicLink := models.MagicLink{
@@ -94,11 +164,75 @@ func (s *Service) CreateMagicLink(email string) (string, error) {
 	return code, nil
 }
 
+// CreateLoginLink creates a new login link for the given email and returns its token. Like the
+// code sent along with it, the link expires after MagicLinkLifetime and works once.
+func (s *Service) CreateLoginLink(email string) (string, error) {
+	email = mail.NormalizeAddress(email)
+
+	// Clean up old links for this email
+	s.DB.Where("email = ?", email).Delete(&models.LoginLink{})
+
+	link := models.LoginLink{
+		ID:        codes.Hex(16),
+		Email:     email,
+		ExpiresAt: time.Now().Add(s.MagicLinkLifetime),
+	}
+	if err := s.DB.Create(&link).Error; err != nil {
+		return "", err
+	}
+
+	return link.ID + "." + s.signLoginLink(link.ID), nil
+}
+
+// LoginLinkFor returns the URL of the login link with the given token.
+func (s *Service) LoginLinkFor(token string) string {
+	link, err := url.Parse(s.LoginLinkURL)
+	if err != nil {
+		return s.LoginLinkURL
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String()
+}
+
+// signLoginLink returns the signature of a login link ID.
+func (s *Service) signLoginLink(id string) string {
+	mac := hmac.New(sha256.New, s.JWTSecret)
+	mac.Write([]byte("login-link:" + id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyLoginLink verifies the token of a login link like VerifyMagicLinkWithInvitation verifies
+// a code, considering all pending invitations of the address. Tokens with an invalid signature
+// are rejected without a lookup.
+func (s *Service) VerifyLoginLink(token string) (*models.User, *models.Invitation, error) {
+	id, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.signLoginLink(id))) {
+		return nil, nil, errors.New("invalid login link")
+	}
+
+	var link models.LoginLink
+	result := s.DB.Where("id = ? AND used = false AND expires_at > ?", id, time.Now()).First(&link)
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+
+	// The link and the code of a login are used up together
+	link.Used = true
+	s.DB.Save(&link)
+	s.DB.Model(&models.MagicLink{}).Where("email = ?", link.Email).Update("used", true)
+
+	return s.loginUser(link.Email, "")
+}
+
 // VerifyMagicLink verifies a magic link code and returns the associated user.
 func (s *Service) VerifyMagicLink(email, code string) (*models.User, error) {
+	email = mail.NormalizeAddress(email)
+
 	var magicLink models.MagicLink
 	result := s.DB.Where("code = ? AND email = ? AND used = false AND expires_at > ?",
-		code, email, time.Now()).First(&magicLink)
+		codes.Normalize(code), email, time.Now()).First(&magicLink)
 
 	if result.Error != nil {
 		return nil, result.Error
@@ -110,7 +244,7 @@ func (s *Service) VerifyMagicLink(email, code string) (*models.User, error) {
 
 	// Find or create user
 	var user models.User
-	result = s.DB.Where("email = ?", email).First(&user)
+	result = s.DB.Where("email = ?", pii.Encrypt(email)).First(&user)
 	if result.Error != nil {
 		return nil, errors.New("user not found - invitation required for new users")
 	}
@@ -119,27 +253,42 @@ func (s *Service) VerifyMagicLink(email, code string) (*models.User, error) {
 }
 
 // VerifyMagicLinkWithInvitation verifies a magic link and processes any pending invitations.
-func (s *Service) VerifyMagicLinkWithInvitation(email, code string) (*models.User, *models.Invitation, error) {
+// With an invitation code, only the invitation with that code is considered, e.g. when several
+// invitations were sent to the same address.
+func (s *Service) VerifyMagicLinkWithInvitation(email, code, invitationCode string) (*models.User, *models.Invitation, error) {
+	email = mail.NormalizeAddress(email)
+
 	var magicLink models.MagicLink
 	result := s.DB.Where("code = ? AND email = ? AND used = false AND expires_at > ?",
-		code, email, time.Now()).First(&magicLink)
+		codes.Normalize(code), email, time.Now()).First(&magicLink)
 
 	if result.Error != nil {
 		return nil, nil, result.Error
 	}
 
-	// Mark code as used
+	// Mark code as used, together with the login link sent along
 	magicLink.Used = true
 	s.DB.Save(&magicLink)
+	s.DB.Model(&models.LoginLink{}).Where("email = ?", email).Update("used", true)
+
+	return s.loginUser(email, invitationCode)
+}
+
+// loginUser returns the user logging in with a verified email address and the pending list
+// invitation to accept, or creates the user of a new address with an invitation.
+func (s *Service) loginUser(email, invitationCode string) (*models.User, *models.Invitation, error) {
+	pending := s.DB.Where("email = ? AND used = false AND expires_at > ?", pii.Encrypt(email), time.Now())
+	if invitationCode != "" {
+		pending = pending.Where("code = ?", codes.Normalize(invitationCode))
+	}
 
 	// Find existing user
 	var user models.User
-	result = s.DB.Where("email = ?", email).First(&user)
+	result := s.DB.Where("email = ?", pii.Encrypt(email)).First(&user)
 	if result.Error == nil {
 		// User exists, check for pending list invitation
 		var invitation models.Invitation
-		err := s.DB.Where("email = ? AND used = false AND expires_at > ? AND type = ?",
-			email, time.Now(), "list").First(&invitation).Error
+		err := pending.Where("type = ?", "list").First(&invitation).Error
 		if err == nil {
 			return &user, &invitation, nil
 		}
@@ -148,8 +297,7 @@ func (s *Service) VerifyMagicLinkWithInvitation(email, code string) (*models.Use
 
 	// User doesn't exist, check for invitation
 	var invitation models.Invitation
-	err := s.DB.Where("email = ? AND used = false AND expires_at > ?",
-		email, time.Now()).First(&invitation).Error
+	err := pending.First(&invitation).Error
 	if err != nil {
 		return nil, nil, errors.New("invitation required for new users")
 	}
@@ -169,13 +317,47 @@ func (s *Service) VerifyMagicLinkWithInvitation(email, code string) (*models.Use
 	return &user, &invitation, nil
 }
 
+// GetUserByEmail retrieves a registered user by email address.
+func (s *Service) GetUserByEmail(email string) (*models.User, error) {
+	email = mail.NormalizeAddress(email)
+
+	var user models.User
+	if err := s.DB.Where("email = ?", pii.Encrypt(email)).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetPrivacySettings retrieves the privacy preferences of a user.
+func (s *Service) GetPrivacySettings(userID string) (*models.PrivacySettings, error) {
+	var user models.User
+	if err := s.DB.First(&user, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+	return &models.PrivacySettings{HideFromContacts: user.HideFromContacts}, nil
+}
+
+// UpdatePrivacySettings stores the privacy preferences of a user.
+func (s *Service) UpdatePrivacySettings(userID string, settings models.PrivacySettings) error {
+	result := s.DB.Model(&models.User{}).Where("id = ?", userID).
+		Update("hide_from_contacts", settings.HideFromContacts)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
 // GenerateJWT creates a new JWT token for the given user with 30-day expiry.
 func (s *Service) GenerateJWT(user *models.User) (string, error) {
 	claims := &models.JWTClaims{
-		UserID: user.ID,
-		Email:  user.Email,
+		UserID:       user.ID,
+		Email:        user.Email,
+		TokenVersion: user.TokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(30 * 24 * time.Hour)), // 30 days
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.TokenLifetime)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
@@ -184,6 +366,39 @@ func (s *Service) GenerateJWT(user *models.User) (string, error) {
 	return token.SignedString(s.JWTSecret)
 }
 
+// StartSession issues a JWT for a user who just logged in. If the system settings restrict users
+// to a single session, the user's token version is bumped first, which ends all sessions started
+// before.
+func (s *Service) StartSession(user *models.User) (string, error) {
+	var settings models.SystemSettings
+	if err := s.DB.First(&settings).Error; err == nil && settings.SingleSession {
+		if err := s.EndSessions(user.ID); err != nil {
+			return "", err
+		}
+		if err := s.DB.Raw("SELECT token_version FROM users WHERE id = ?", user.ID).Scan(&user.TokenVersion).Error; err != nil {
+			return "", err
+		}
+	}
+	return s.GenerateJWT(user)
+}
+
+// EndSessions logs a user out of all devices by bumping their token version, which revokes every
+// JWT issued before. API keys stay valid.
+func (s *Service) EndSessions(userID string) error {
+	return s.DB.Model(&models.User{}).Where("id = ?", userID).
+		UpdateColumn("token_version", gorm.Expr("token_version + 1")).Error
+}
+
+// sessionEnded reports whether the token was issued before the user's token version was bumped
+// by a newer login or a logout from all devices. Tokens of unknown users are left to the handlers.
+func (s *Service) sessionEnded(claims *models.JWTClaims) (bool, error) {
+	var version int
+	if err := s.DB.Raw("SELECT token_version FROM users WHERE id = ?", claims.UserID).Scan(&version).Error; err != nil {
+		return false, err
+	}
+	return claims.TokenVersion < version, nil
+}
+
 // ValidateJWT validates a JWT token and returns the claims if valid.
 func (s *Service) ValidateJWT(tokenString string) (*models.JWTClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &models.JWTClaims{}, func(_ *jwt.Token) (interface{}, error) {
@@ -226,6 +441,18 @@ func (s *Service) JWTMiddleware() fiber.Handler {
 			})
 		}
 
+		ended, err := s.sessionEnded(claims)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to verify session",
+			})
+		}
+		if ended {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Session ended, please log in again",
+			})
+		}
+
 		// Add user info to context
 		c.Locals("user_id", claims.UserID)
 		c.Locals("user_email", claims.Email)
@@ -233,3 +460,215 @@ func (s *Service) JWTMiddleware() fiber.Handler {
 		return c.Next()
 	}
 }
+
+// OptionalJWTMiddleware returns a Fiber middleware that sets the user of requests with a valid
+// JWT token, like JWTMiddleware, but lets requests without one pass anonymously.
+func (s *Service) OptionalJWTMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		tokenString, ok := strings.CutPrefix(c.Get("Authorization"), "Bearer ")
+		if !ok {
+			return c.Next()
+		}
+		if claims, err := s.ValidateJWT(tokenString); err == nil {
+			if ended, err := s.sessionEnded(claims); err != nil || ended {
+				return c.Next()
+			}
+			c.Locals("user_id", claims.UserID)
+			c.Locals("user_email", claims.Email)
+		}
+		return c.Next()
+	}
+}
+
+// DefaultMagicLinkLifetime is how long a login code stays valid unless configured otherwise.
+const DefaultMagicLinkLifetime = 15 * time.Minute
+
+// DefaultTokenLifetime is how long an issued JWT token stays valid unless configured otherwise.
+const DefaultTokenLifetime = 30 * 24 * time.Hour
+
+// APIKeyHeader is the request header carrying an API key.
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyQueryParam is the query parameter carrying an API key on routes for clients that can
+// only open URLs.
+const APIKeyQueryParam = "key"
+
+// Scopes an API key can be restricted to. Keys without scopes may do everything.
+const (
+	ScopeItemsRead  = "items:read"
+	ScopeItemsWrite = "items:write"
+)
+
+// APIKeyScopes are all scopes an API key can be restricted to.
+var APIKeyScopes = []string{ScopeItemsRead, ScopeItemsWrite}
+
+// DefaultAPIKeyRateLimit is the number of requests per minute allowed for an API key unless
+// configured otherwise.
+const DefaultAPIKeyRateLimit = 60
+
+// apiKeyPrefix marks API keys so they are recognizable in logs and secret scanners.
+const apiKeyPrefix = "sl_"
+
+// hashAPIKey returns the hex-encoded SHA-256 hash under which an API key is stored.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIKey creates a new API key for the user and returns it together with the plaintext key.
+// The key is restricted to the given scopes, or may do everything without scopes. A rate limit
+// of 0 applies the server's default limit.
+func (s *Service) CreateAPIKey(userID, name string, scopes []string, rateLimit int) (*models.APIKey, string, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, "", errors.New("API key name cannot be empty")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(APIKeyScopes, scope) {
+			return nil, "", fmt.Errorf("unknown API key scope %q", scope)
+		}
+	}
+	if rateLimit < 0 {
+		return nil, "", errors.New("API key rate limit cannot be negative")
+	}
+
+	key := apiKeyPrefix + codes.Hex(24)
+
+	apiKey := models.APIKey{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Name:      strings.TrimSpace(name),
+		Prefix:    key[:len(apiKeyPrefix)+6],
+		KeyHash:   hashAPIKey(key),
+		Scopes:    strings.Join(scopes, " "),
+		RateLimit: rateLimit,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.DB.Create(&apiKey).Error; err != nil {
+		return nil, "", err
+	}
+
+	return &apiKey, key, nil
+}
+
+// GetUserAPIKeys retrieves all API keys of the specified user.
+func (s *Service) GetUserAPIKeys(userID string) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := s.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+// RevokeAPIKey deletes an API key if it belongs to the user.
+func (s *Service) RevokeAPIKey(keyID, userID string) error {
+	result := s.DB.Where("id = ? AND user_id = ?", keyID, userID).Delete(&models.APIKey{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("API key not found")
+	}
+	return nil
+}
+
+// ValidateAPIKey looks up the user owning the given API key and records its usage.
+func (s *Service) ValidateAPIKey(key string) (*models.User, error) {
+	_, user, err := s.validateAPIKey(key)
+	return user, err
+}
+
+// validateAPIKey looks up the given API key and the user owning it and records its usage.
+func (s *Service) validateAPIKey(key string) (*models.APIKey, *models.User, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, nil, errors.New("invalid API key")
+	}
+
+	var apiKey models.APIKey
+	if err := s.DB.Where("key_hash = ?", hashAPIKey(key)).First(&apiKey).Error; err != nil {
+		return nil, nil, errors.New("invalid API key")
+	}
+
+	var user models.User
+	if err := s.DB.First(&user, "id = ?", apiKey.UserID).Error; err != nil {
+		return nil, nil, errors.New("invalid API key")
+	}
+
+	now := time.Now()
+	s.DB.Model(&apiKey).Update("last_used_at", &now)
+
+	return &apiKey, &user, nil
+}
+
+// HasScope checks if the API key may act within the scope. Keys without scopes may do everything.
+func HasScope(apiKey *models.APIKey, scope string) bool {
+	if apiKey.Scopes == "" {
+		return true
+	}
+	return slices.Contains(strings.Fields(apiKey.Scopes), scope)
+}
+
+// APIKeyMiddleware returns a Fiber middleware that authenticates requests with an API key
+// in the X-API-Key header and falls back to JWT bearer tokens otherwise.
+func (s *Service) APIKeyMiddleware() fiber.Handler {
+	jwtMiddleware := s.JWTMiddleware()
+
+	return func(c *fiber.Ctx) error {
+		key := c.Get(APIKeyHeader)
+		if key == "" {
+			return jwtMiddleware(c)
+		}
+
+		apiKey, user, err := s.validateAPIKey(key)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid API key",
+			})
+		}
+
+		limit := apiKey.RateLimit
+		if limit == 0 {
+			limit = s.APIKeyRateLimit
+		}
+		if limit > 0 {
+			if retryAfter, ok := s.apiKeyLimiter.Allow(apiKey.ID, limit, time.Now()); !ok {
+				c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(retryAfter.Seconds())+1))
+				return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+					"error": "Rate limit exceeded",
+				})
+			}
+		}
+
+		c.Locals("user_id", user.ID)
+		c.Locals("user_email", user.Email)
+		c.Locals("api_key", apiKey)
+
+		return c.Next()
+	}
+}
+
+// QueryAPIKeyMiddleware returns APIKeyMiddleware that also accepts the API key in the "key"
+// query parameter, for clients like Apple Shortcuts that can only open URLs. Access logs leave
+// out query strings, so the key is not logged.
+func (s *Service) QueryAPIKeyMiddleware() fiber.Handler {
+	apiKeyMiddleware := s.APIKeyMiddleware()
+
+	return func(c *fiber.Ctx) error {
+		if key := c.Query(APIKeyQueryParam); key != "" && c.Get(APIKeyHeader) == "" {
+			c.Request().Header.Set(APIKeyHeader, key)
+		}
+		return apiKeyMiddleware(c)
+	}
+}
+
+// RequireScope returns a Fiber middleware that rejects requests authenticated with an API key
+// lacking the scope. Requests authenticated with a JWT token pass.
+func RequireScope(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		apiKey, ok := c.Locals("api_key").(*models.APIKey)
+		if ok && !HasScope(apiKey, scope) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "API key lacks scope " + scope,
+			})
+		}
+		return c.Next()
+	}
+}
@@ -5,6 +5,7 @@ package auth
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http/httptest"
 	"strings"
@@ -18,29 +19,77 @@ import (
 	"gopkg.in/gomail.v2"
 )
 
-func TestGenerateCode(t *testing.T) {
-	code := GenerateCode()
+func TestService_GenerateLoginCode(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db, []byte("test-secret"), nil)
 
-	if len(code) != 6 {
-		t.Errorf("Expected code length to be 6, got %d", len(code))
+	// Six digits before setup
+	code := service.GenerateLoginCode()
+	if len(code) != 6 || strings.Trim(code, "0123456789") != "" {
+		t.Errorf("Expected 6 digits, got %q", code)
 	}
 
-	// Check that code contains only digits
-	for _, char := range code {
-		if char < '0' || char > '9' {
-			t.Errorf("Expected code to contain only digits, found '%c'", char)
-		}
+	settings := models.SystemSettings{ID: "system", IsSetup: true, LoginCodeLength: 8, LoginCodeAlphabet: "alphanumeric"}
+	if err := db.Create(&settings).Error; err != nil {
+		t.Fatalf("Failed to create settings: %v", err)
+	}
+	if format := service.LoginCodeFormat(); format != (LoginCodeFormat{Length: 8, Alphabet: "alphanumeric"}) {
+		t.Errorf("Unexpected format %+v", format)
 	}
 
-	// Generate multiple codes to ensure they are different
-	codes := make(map[string]bool)
-	for i := 0; i < 100; i++ {
-		code := GenerateCode()
-		if codes[code] {
+	seen := make(map[string]bool)
+	for range 100 {
+		code := service.GenerateLoginCode()
+		if len(code) != 8 || strings.Trim(code, "23456789ABCDEFGHJKLMNPQRSTUVWXYZ") != "" {
+			t.Fatalf("Expected 8 alphanumeric characters, got %q", code)
+		}
+		if seen[code] {
 			t.Errorf("Generated duplicate code: %s", code)
 		}
-		codes[code] = true
+		seen[code] = true
+	}
+
+	t.Run("configured length applies without a length in the settings", func(t *testing.T) {
+		db.Model(&settings).Update("login_code_length", 0)
+		service.CodeLength = 10
+		defer func() { service.CodeLength = DefaultLoginCodeLength }()
+
+		if code := service.GenerateLoginCode(); len(code) != 10 {
+			t.Errorf("Expected 10 characters, got %q", code)
+		}
+	})
+
+	t.Run("invalid settings fall back to the default", func(t *testing.T) {
+		db.Model(&settings).Updates(map[string]any{"login_code_length": 2, "login_code_alphabet": "emoji"})
+		if format := service.LoginCodeFormat(); format != (LoginCodeFormat{Length: 6, Alphabet: "numeric"}) {
+			t.Errorf("Unexpected format %+v", format)
+		}
+	})
+}
+
+func FuzzValidateJWT(f *testing.F) {
+	service := NewService(testutils.SetupTestDB(f), []byte("test-secret"), nil)
+	valid, err := service.GenerateJWT(&models.User{ID: "user-id", Email: "test@example.com"})
+	if err != nil {
+		f.Fatalf("Failed to generate JWT: %v", err)
+	}
+	for _, token := range []string{
+		valid, "", "a.b.c", valid + "x",
+		// alg "none" with the claims of a valid token
+		"eyJhbGciOiJub25lIiwidHlwIjoiSldUIn0." + strings.Split(valid, ".")[1] + ".",
+	} {
+		f.Add(token)
 	}
+
+	f.Fuzz(func(t *testing.T, token string) {
+		claims, err := service.ValidateJWT(token)
+		if (claims == nil) == (err == nil) {
+			t.Fatalf("ValidateJWT(%q): expected either claims or an error, got %v, %v", token, claims, err)
+		}
+		if err == nil && claims.UserID != "user-id" {
+			t.Fatalf("ValidateJWT(%q): accepted a token that was not issued, claims %+v", token, claims)
+		}
+	})
 }
 
 func TestNewService(t *testing.T) {
@@ -185,6 +234,32 @@ func TestService_VerifyMagicLink(t *testing.T) {
 		}
 	})
 
+	t.Run("verify alphanumeric code typed in lowercase", func(t *testing.T) {
+		alphanumeric := models.MagicLink{Code: "K7QX2M9A", Email: email, ExpiresAt: time.Now().Add(time.Hour)}
+		if err := db.Create(&alphanumeric).Error; err != nil {
+			t.Fatalf("Failed to create magic link: %v", err)
+		}
+
+		if _, err := service.VerifyMagicLink(email, " k7qx2m9a "); err != nil {
+			t.Errorf("Expected the code to be accepted, got %v", err)
+		}
+	})
+
+	t.Run("verify with differently cased email", func(t *testing.T) {
+		code, err := service.CreateMagicLink(" Test@Example.com")
+		if err != nil {
+			t.Fatalf("Failed to create magic link: %v", err)
+		}
+
+		verifiedUser, err := service.VerifyMagicLink("TEST@example.com", code)
+		if err != nil {
+			t.Fatalf("Failed to verify magic link: %v", err)
+		}
+		if verifiedUser.ID != user.ID {
+			t.Error("Expected the existing account, not a new one")
+		}
+	})
+
 	t.Run("verify invalid code", func(t *testing.T) {
 		_, err := service.VerifyMagicLink(email, "invalid")
 		if err == nil {
@@ -270,6 +345,21 @@ func TestService_GenerateJWT(t *testing.T) {
 	if len(parts) != 3 {
 		t.Errorf("Expected JWT to have 3 parts, got %d", len(parts))
 	}
+
+	t.Run("configured lifetime", func(t *testing.T) {
+		service.TokenLifetime = time.Hour
+		token, err := service.GenerateJWT(user)
+		if err != nil {
+			t.Fatalf("Failed to generate JWT: %v", err)
+		}
+		claims, err := service.ValidateJWT(token)
+		if err != nil {
+			t.Fatalf("Failed to validate JWT: %v", err)
+		}
+		if remaining := time.Until(claims.RegisteredClaims.ExpiresAt.Time); remaining > time.Hour || remaining < 59*time.Minute {
+			t.Errorf("Expected the token to expire in an hour, got %v", remaining)
+		}
+	})
 }
 
 func TestService_ValidateJWT(t *testing.T) {
@@ -335,7 +425,7 @@ func TestService_SendMagicLink(t *testing.T) {
 		testutils.SetupTestConfig(t)
 		defer testutils.CleanupTestEnv(t)
 
-		err := service.SendMagicLink(email, code)
+		err := service.SendMagicLink(email, code, "")
 		if err != nil {
 			t.Errorf("Expected no error in test environment, got: %v", err)
 		}
@@ -345,7 +435,7 @@ func TestService_SendMagicLink(t *testing.T) {
 		// Clean up any test environment variable
 		testutils.CleanupTestEnv(t)
 
-		err := service.SendMagicLink(email, code)
+		err := service.SendMagicLink(email, code, "")
 		// We expect this to fail since SMTP is not configured, but we're testing that
 		// the function attempts to send email when not in test environment
 		if err == nil {
@@ -357,7 +447,7 @@ func TestService_SendMagicLink(t *testing.T) {
 }
 
 func TestService_JWTMiddleware(t *testing.T) {
-	service := NewService(nil, []byte("test-secret"), nil)
+	service := NewService(testutils.SetupTestDB(t), []byte("test-secret"), nil)
 	middleware := service.JWTMiddleware()
 
 	if middleware == nil {
@@ -491,6 +581,227 @@ func TestService_JWTMiddleware(t *testing.T) {
 	})
 }
 
+func TestService_StartSession(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db, []byte("test-secret"), nil)
+
+	user := models.User{ID: "session-user", Email: testutils.TestEmailAddress(), JoinedAt: time.Now()}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	app := fiber.New()
+	app.Get("/test", service.JWTMiddleware(), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	status := func(token string) int {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp.StatusCode
+	}
+
+	first, err := service.StartSession(&user)
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	second, _ := service.StartSession(&user)
+	if status(first) != fiber.StatusOK || status(second) != fiber.StatusOK {
+		t.Error("Expected concurrent sessions to stay valid by default")
+	}
+
+	settings := models.SystemSettings{ID: "system", IsSetup: true, SingleSession: true}
+	if err := db.Create(&settings).Error; err != nil {
+		t.Fatalf("Failed to create settings: %v", err)
+	}
+
+	third, err := service.StartSession(&user)
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	if user.TokenVersion != 1 {
+		t.Errorf("Expected token version 1, got %d", user.TokenVersion)
+	}
+	if status(first) != fiber.StatusUnauthorized || status(second) != fiber.StatusUnauthorized {
+		t.Error("Expected earlier sessions to end with a new login")
+	}
+	if status(third) != fiber.StatusOK {
+		t.Error("Expected the new session to be valid")
+	}
+
+	fourth, _ := service.StartSession(&user)
+	if status(third) != fiber.StatusUnauthorized || status(fourth) != fiber.StatusOK {
+		t.Error("Expected only the latest session to be valid")
+	}
+}
+
+func TestService_LoginLink(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db, []byte("test-secret"), nil)
+	service.LoginLinkURL = "https://app.example/login?lang=de"
+
+	email := testutils.TestEmailAddress()
+	user := models.User{ID: "link-user", Email: email, JoinedAt: time.Now()}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	t.Run("build link", func(t *testing.T) {
+		if link := service.LoginLinkFor("abc.def"); link != "https://app.example/login?lang=de&token=abc.def" {
+			t.Errorf("Unexpected login link %q", link)
+		}
+	})
+
+	t.Run("reject forged tokens", func(t *testing.T) {
+		token, err := service.CreateLoginLink(email)
+		if err != nil {
+			t.Fatalf("Failed to create login link: %v", err)
+		}
+		id, _, _ := strings.Cut(token, ".")
+		for _, forged := range []string{id, id + ".", id + ".c2lnbmF0dXJl"} {
+			if _, _, err := service.VerifyLoginLink(forged); err == nil {
+				t.Errorf("Expected %q to be rejected", forged)
+			}
+		}
+		other := NewService(db, []byte("other-secret"), nil)
+		if _, _, err := other.VerifyLoginLink(token); err == nil {
+			t.Error("Expected a token signed with another secret to be rejected")
+		}
+	})
+
+	t.Run("log in once", func(t *testing.T) {
+		code, _ := service.CreateMagicLink(email)
+		token, _ := service.CreateLoginLink(email)
+
+		verified, _, err := service.VerifyLoginLink(token)
+		if err != nil {
+			t.Fatalf("Failed to verify login link: %v", err)
+		}
+		if verified.ID != user.ID {
+			t.Errorf("Expected the user of the address, got %s", verified.ID)
+		}
+		if _, _, err := service.VerifyLoginLink(token); err == nil {
+			t.Error("Expected the link to work only once")
+		}
+		if _, _, err := service.VerifyMagicLinkWithInvitation(email, code, ""); err == nil {
+			t.Error("Expected the code sent along to be used up as well")
+		}
+	})
+
+	t.Run("code uses up the link", func(t *testing.T) {
+		code, _ := service.CreateMagicLink(email)
+		token, _ := service.CreateLoginLink(email)
+
+		if _, _, err := service.VerifyMagicLinkWithInvitation(email, code, ""); err != nil {
+			t.Fatalf("Failed to verify code: %v", err)
+		}
+		if _, _, err := service.VerifyLoginLink(token); err == nil {
+			t.Error("Expected the link sent along to be used up")
+		}
+	})
+
+	t.Run("expired link", func(t *testing.T) {
+		token, _ := service.CreateLoginLink(email)
+		db.Model(&models.LoginLink{}).Where("email = ?", email).Update("expires_at", time.Now().Add(-time.Minute))
+
+		if _, _, err := service.VerifyLoginLink(token); err == nil {
+			t.Error("Expected an expired link to be rejected")
+		}
+	})
+}
+
+func TestService_Throttle(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db, []byte("test-secret"), nil)
+	service.LoginCodeLimit = 2
+
+	for i := range 2 {
+		if _, ok, err := service.AllowLoginCode("user@example.com", "192.0.2.1"); err != nil || !ok {
+			t.Fatalf("Expected code %d to be allowed, got %v", i+1, err)
+		}
+	}
+	retryAfter, ok, err := service.AllowLoginCode(" User@Example.com", "192.0.2.2")
+	if err != nil || ok {
+		t.Fatalf("Expected the address to be limited, got %v", err)
+	}
+	if retryAfter <= 59*time.Minute || retryAfter > time.Hour {
+		t.Errorf("Expected to retry in about an hour, got %v", retryAfter)
+	}
+
+	// The counters survive a restart
+	restarted := NewService(db, []byte("test-secret"), nil)
+	restarted.LoginCodeLimit = 2
+	if _, ok, _ := restarted.AllowLoginCode("user@example.com", "192.0.2.3"); ok {
+		t.Error("Expected the limit to be kept in the database")
+	}
+
+	// Rejected requests are not counted, so the IP still has room for other addresses
+	for i := range 8 - 2 {
+		if _, ok, _ := service.AllowLoginCode(fmt.Sprintf("user%d@example.com", i), "192.0.2.1"); !ok {
+			t.Fatalf("Expected request %d from the IP to be allowed", i+3)
+		}
+	}
+	if _, ok, _ := service.AllowLoginCode("new@example.com", "192.0.2.1"); ok {
+		t.Error("Expected the IP to be limited")
+	}
+
+	var stored []models.LoginThrottle
+	db.Find(&stored)
+	for _, counter := range stored {
+		if strings.Contains(counter.Key, "example.com") {
+			t.Errorf("Expected addresses to be stored hashed, got %q", counter.Key)
+		}
+	}
+
+	// Expired windows start over
+	db.Model(&models.LoginThrottle{}).Where("1 = 1").Update("window_end", time.Now().Add(-time.Minute))
+	if _, ok, _ := service.AllowLoginCode("user@example.com", "192.0.2.1"); !ok {
+		t.Error("Expected a new window to allow the request")
+	}
+
+	service.LoginCodeLimit = 0
+	for range 5 {
+		if _, ok, _ := service.AllowLoginCode("user@example.com", "192.0.2.1"); !ok {
+			t.Fatal("Expected no limit when disabled")
+		}
+	}
+}
+
+func TestService_EndSessions(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db, []byte("test-secret"), nil)
+
+	user := models.User{ID: "logout-user", Email: testutils.TestEmailAddress(), JoinedAt: time.Now()}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	phone, _ := service.StartSession(&user)
+	laptop, _ := service.StartSession(&user)
+
+	if err := service.EndSessions(user.ID); err != nil {
+		t.Fatalf("Failed to end sessions: %v", err)
+	}
+	for _, token := range []string{phone, laptop} {
+		claims, err := service.ValidateJWT(token)
+		if err != nil {
+			t.Fatalf("Expected the token to stay well-formed: %v", err)
+		}
+		if ended, _ := service.sessionEnded(claims); !ended {
+			t.Error("Expected all sessions issued before to end")
+		}
+	}
+
+	db.First(&user, "id = ?", user.ID)
+	next, _ := service.StartSession(&user)
+	claims, _ := service.ValidateJWT(next)
+	if ended, _ := service.sessionEnded(claims); ended {
+		t.Error("Expected a new login to start a valid session")
+	}
+}
+
 func TestService_VerifyMagicLinkWithInvitation(t *testing.T) {
 	db := testutils.SetupTestDB(t)
 	service := NewService(db, []byte("test-secret"), nil)
@@ -532,7 +843,7 @@ func TestService_VerifyMagicLinkWithInvitation(t *testing.T) {
 			t.Fatalf("Failed to create magic link: %v", err)
 		}
 
-		verifiedUser, returnedInvitation, err := service.VerifyMagicLinkWithInvitation(email, code)
+		verifiedUser, returnedInvitation, err := service.VerifyMagicLinkWithInvitation(email, code, "")
 		if err != nil {
 			t.Fatalf("Failed to verify magic link with invitation: %v", err)
 		}
@@ -573,7 +884,7 @@ func TestService_VerifyMagicLinkWithInvitation(t *testing.T) {
 			t.Fatalf("Failed to create magic link: %v", err)
 		}
 
-		verifiedUser, returnedInvitation, err := service.VerifyMagicLinkWithInvitation(newEmail, code)
+		verifiedUser, returnedInvitation, err := service.VerifyMagicLinkWithInvitation(newEmail, code, "")
 		if err != nil {
 			t.Fatalf("Failed to verify magic link with invitation: %v", err)
 		}
@@ -608,7 +919,7 @@ func TestService_VerifyMagicLinkWithInvitation(t *testing.T) {
 			t.Fatalf("Failed to create magic link: %v", err)
 		}
 
-		_, _, err = service.VerifyMagicLinkWithInvitation(uninvitedEmail, code)
+		_, _, err = service.VerifyMagicLinkWithInvitation(uninvitedEmail, code, "")
 		if err == nil {
 			t.Error("Expected error for new user without invitation")
 		}
@@ -618,3 +929,150 @@ func TestService_VerifyMagicLinkWithInvitation(t *testing.T) {
 		}
 	})
 }
+
+func TestService_APIKeys(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db, []byte("test-secret"), nil)
+
+	user := models.User{
+		ID:        "api-key-user",
+		Email:     "apikey@example.com",
+		JoinedAt:  time.Now(),
+		CreatedAt: time.Now(),
+	}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	apiKey, key, err := service.CreateAPIKey(user.ID, "Siri", nil, 0)
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+
+	if !strings.HasPrefix(key, "sl_") {
+		t.Errorf("Expected key to start with 'sl_', got '%s'", key)
+	}
+	if apiKey.KeyHash == key {
+		t.Error("API key must not be stored in plaintext")
+	}
+
+	t.Run("validate key", func(t *testing.T) {
+		found, err := service.ValidateAPIKey(key)
+		if err != nil {
+			t.Fatalf("Failed to validate API key: %v", err)
+		}
+		if found.ID != user.ID {
+			t.Errorf("Expected user '%s', got '%s'", user.ID, found.ID)
+		}
+
+		if _, err := service.ValidateAPIKey("sl_invalid"); err == nil {
+			t.Error("Expected invalid key to be rejected")
+		}
+	})
+
+	t.Run("middleware accepts API key and JWT", func(t *testing.T) {
+		app := fiber.New()
+		app.Use(service.APIKeyMiddleware())
+		app.Get("/test", func(c *fiber.Ctx) error {
+			return c.SendString(c.Locals("user_id").(string))
+		})
+
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set(APIKeyHeader, key)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Errorf("Expected status 200 with API key, got %d", resp.StatusCode)
+		}
+
+		token, err := service.GenerateJWT(&user)
+		if err != nil {
+			t.Fatalf("Failed to generate JWT: %v", err)
+		}
+		req = httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err = app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Errorf("Expected status 200 with JWT, got %d", resp.StatusCode)
+		}
+
+		req = httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set(APIKeyHeader, "sl_wrong")
+		resp, err = app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusUnauthorized {
+			t.Errorf("Expected status 401 with wrong key, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("revoke key", func(t *testing.T) {
+		if err := service.RevokeAPIKey(apiKey.ID, "someone-else"); err == nil {
+			t.Error("Expected revoking another user's key to fail")
+		}
+		if err := service.RevokeAPIKey(apiKey.ID, user.ID); err != nil {
+			t.Fatalf("Failed to revoke API key: %v", err)
+		}
+		if _, err := service.ValidateAPIKey(key); err == nil {
+			t.Error("Expected revoked key to be rejected")
+		}
+	})
+}
+
+func TestService_APIKeyScopesAndRateLimit(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db, []byte("test-secret"), nil)
+
+	user := models.User{ID: "scoped-user", Email: "scoped@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	if _, _, err := service.CreateAPIKey(user.ID, "Broken", []string{"lists:delete"}, 0); err == nil {
+		t.Error("Expected unknown scope to be rejected")
+	}
+
+	apiKey, key, err := service.CreateAPIKey(user.ID, "n8n", []string{ScopeItemsRead}, 2)
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+	if !HasScope(apiKey, ScopeItemsRead) || HasScope(apiKey, ScopeItemsWrite) {
+		t.Errorf("Unexpected scopes %q", apiKey.Scopes)
+	}
+	if !HasScope(&models.APIKey{}, ScopeItemsWrite) {
+		t.Error("Expected keys without scopes to have all scopes")
+	}
+
+	app := fiber.New()
+	app.Get("/read", service.APIKeyMiddleware(), RequireScope(ScopeItemsRead), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	app.Post("/write", service.APIKeyMiddleware(), RequireScope(ScopeItemsWrite), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	request := func(method, target string) int {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set(APIKeyHeader, key)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp.StatusCode
+	}
+
+	if status := request("GET", "/read"); status != fiber.StatusOK {
+		t.Errorf("Expected status 200 within scope, got %d", status)
+	}
+	if status := request("POST", "/write"); status != fiber.StatusForbidden {
+		t.Errorf("Expected status 403 outside scope, got %d", status)
+	}
+	if status := request("GET", "/read"); status != fiber.StatusTooManyRequests {
+		t.Errorf("Expected status 429 above the rate limit, got %d", status)
+	}
+}
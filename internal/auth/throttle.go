@@ -0,0 +1,105 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/mail"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// Login limits used unless configured otherwise: login codes an address may request per hour and
+// attempts to verify a code. Clients behind one IP may do ipLimitFactor times as much, so a
+// household sharing a connection is not locked out by one member.
+const (
+	DefaultLoginCodeLimit     = 5
+	DefaultVerifyAttemptLimit = 10
+	ipLimitFactor             = 4
+)
+
+// loginWindow is the window of the login code limits and of the verify limit per IP. The
+// verify limit per address lasts as long as the code.
+const loginWindow = time.Hour
+
+// limit is a login limit on one counter.
+type limit struct {
+	key    string
+	max    int
+	window time.Duration
+}
+
+// AllowLoginCode counts a request for a login code by the address from the client IP. If either
+// is above its limit, nothing is counted and it returns how long until the request is allowed.
+func (s *Service) AllowLoginCode(email, ip string) (time.Duration, bool, error) {
+	if s.LoginCodeLimit <= 0 {
+		return 0, true, nil
+	}
+	return s.throttle(time.Now(),
+		limit{key: addressKey("code", email), max: s.LoginCodeLimit, window: loginWindow},
+		limit{key: "code:ip:" + ip, max: s.LoginCodeLimit * ipLimitFactor, window: loginWindow},
+	)
+}
+
+// AllowVerifyAttempt counts an attempt to verify the code of the address from the client IP,
+// like AllowLoginCode. The attempts per address are reset by requesting a new code.
+func (s *Service) AllowVerifyAttempt(email, ip string) (time.Duration, bool, error) {
+	if s.VerifyAttemptLimit <= 0 {
+		return 0, true, nil
+	}
+	return s.throttle(time.Now(),
+		limit{key: addressKey("verify", email), max: s.VerifyAttemptLimit, window: s.MagicLinkLifetime},
+		limit{key: "verify:ip:" + ip, max: s.VerifyAttemptLimit * ipLimitFactor, window: loginWindow},
+	)
+}
+
+// addressKey returns the key of a counter of an address, which is stored hashed.
+func addressKey(kind, email string) string {
+	sum := sha256.Sum256([]byte(mail.NormalizeAddress(email)))
+	return kind + ":email:" + hex.EncodeToString(sum[:])
+}
+
+// throttle counts a request on every counter of the limits in fixed windows stored in the
+// database, so restarts do not reset them. Requests above any limit are not counted.
+func (s *Service) throttle(now time.Time, limits ...limit) (time.Duration, bool, error) {
+	var retryAfter time.Duration
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		// Drop counters whose window ended long ago, e.g. of clients that never came back
+		if err := tx.Where("window_end < ?", now.Add(-24*time.Hour)).Delete(&models.LoginThrottle{}).Error; err != nil {
+			return err
+		}
+
+		counters := make([]models.LoginThrottle, len(limits))
+		for i, l := range limits {
+			counter := &counters[i]
+			if err := tx.Limit(1).Find(counter, "key = ?", l.key).Error; err != nil {
+				return err
+			}
+			if counter.Key == "" || !now.Before(counter.WindowEnd) {
+				*counter = models.LoginThrottle{Key: l.key, WindowEnd: now.Add(l.window)}
+			}
+			if counter.Count >= l.max {
+				retryAfter = max(retryAfter, counter.WindowEnd.Sub(now))
+			}
+		}
+		if retryAfter > 0 {
+			return nil
+		}
+
+		for i := range counters {
+			counters[i].Count++
+			if err := tx.Save(&counters[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	return retryAfter, retryAfter == 0, nil
+}
@@ -0,0 +1,294 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package budgets tracks what is spent on lists whose items have prices, e.g. from an enrichment
+// hook, reports the spend per month, list and category and emails the owners of a list once a
+// month when its spend exceeds the list's monthly budget. Amounts are in the currency of the
+// list, or the server's default currency.
+package budgets
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oliverandrich/shopping-list-server/internal/currency"
+	"github.com/oliverandrich/shopping-list-server/internal/lists"
+	"github.com/oliverandrich/shopping-list-server/internal/mail"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/pii"
+	"gopkg.in/gomail.v2"
+	"gorm.io/gorm"
+)
+
+// MonthLayout is the format of months in reports and requests, e.g. 2025-03.
+const MonthLayout = "2006-01"
+
+// ErrInvalidMonth is returned for months not formatted like 2025-03.
+var ErrInvalidMonth = errors.New("month must be formatted like 2025-03")
+
+// Service records purchases and reports spend.
+type Service struct {
+	DB     *gorm.DB
+	Mailer *gomail.Dialer
+	Lists  *lists.Service
+	// DefaultCurrency is the currency of lists without their own.
+	DefaultCurrency string
+}
+
+// NewService creates a new budgets service with database and email capabilities.
+func NewService(db *gorm.DB, mailer *gomail.Dialer) *Service {
+	return &Service{DB: db, Mailer: mailer, Lists: lists.NewService(db), DefaultCurrency: currency.Default}
+}
+
+// Currency returns the currency of a list, which is the default currency unless the list has
+// its own.
+func (s *Service) Currency(list models.ShoppingList) string {
+	if list.Currency != "" {
+		return list.Currency
+	}
+	return s.DefaultCurrency
+}
+
+// SetMonthlyBudget sets the monthly budget of a list; zero removes it. Only owners can set it.
+func (s *Service) SetMonthlyBudget(listID, userID string, budget float64) (*models.ShoppingList, error) {
+	if !s.Lists.IsListOwner(listID, userID) {
+		return nil, errors.New("only list owners can update lists")
+	}
+
+	var value *float64
+	if budget > 0 {
+		value = &budget
+	}
+	if err := s.DB.Model(&models.ShoppingList{}).Where("id = ?", listID).Update("monthly_budget", value).Error; err != nil {
+		return nil, err
+	}
+	return s.Lists.GetListByID(listID, userID)
+}
+
+// SetCurrency sets the currency of a list, given as ISO 4217 code in any case; "" returns the
+// list to the default currency. Only owners can set it.
+func (s *Service) SetCurrency(listID, userID, code string) (*models.ShoppingList, error) {
+	if !s.Lists.IsListOwner(listID, userID) {
+		return nil, errors.New("only list owners can update lists")
+	}
+
+	if code != "" {
+		var err error
+		if code, err = currency.Normalize(code); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.DB.Model(&models.ShoppingList{}).Where("id = ?", listID).Update("currency", code).Error; err != nil {
+		return nil, err
+	}
+	return s.Lists.GetListByID(listID, userID)
+}
+
+// RecordPurchase records a checked off item at its price and alerts the owners of the list if
+// this takes the month's spend over budget. Items without price are not recorded.
+func (s *Service) RecordPurchase(item models.ShoppingItem, userID string) error {
+	if item.Price == nil {
+		return nil
+	}
+
+	purchasedAt := time.Now()
+	if item.CompletedAt != nil {
+		purchasedAt = *item.CompletedAt
+	}
+	purchase := models.Purchase{
+		ID:          uuid.New().String(),
+		ListID:      item.ListID,
+		ItemID:      item.ID,
+		ItemName:    item.Name,
+		Category:    item.Category,
+		Price:       *item.Price,
+		UserID:      userID,
+		PurchasedAt: purchasedAt,
+	}
+	if err := s.DB.Create(&purchase).Error; err != nil {
+		return err
+	}
+	return s.checkBudget(item.ListID, purchasedAt)
+}
+
+// UndoPurchase removes the purchase of an item that is no longer checked off, given the time it
+// was checked off.
+func (s *Service) UndoPurchase(itemID string, completedAt time.Time) error {
+	return s.DB.Where("item_id = ? AND purchased_at >= ?", itemID, completedAt).Delete(&models.Purchase{}).Error
+}
+
+// Report sums up the purchases of a month, like 2025-03, on the lists the user is a member of,
+// or only on the given list. An empty month reports the current one. The report has the
+// currency of its lists; if they differ, only the spend per list has a currency.
+func (s *Service) Report(userID, month, listID string) (*models.SpendReport, error) {
+	start, err := parseMonth(month)
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.DB.Where("id IN (?)", s.DB.Model(&models.ListMember{}).Select("list_id").Where("user_id = ?", userID))
+	if listID != "" {
+		query = query.Where("id = ?", listID)
+	}
+	var memberLists []models.ShoppingList
+	if err := query.Order("name ASC").Find(&memberLists).Error; err != nil {
+		return nil, err
+	}
+	if listID != "" && len(memberLists) == 0 {
+		return nil, errors.New("access denied")
+	}
+
+	listIDs := make([]string, len(memberLists))
+	for i, list := range memberLists {
+		listIDs[i] = list.ID
+	}
+	var purchases []models.Purchase
+	err = s.DB.Where("list_id IN ? AND purchased_at >= ? AND purchased_at < ?", listIDs, start, start.AddDate(0, 1, 0)).
+		Find(&purchases).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byList := map[string]float64{}
+	byCategory := map[string]float64{}
+	report := &models.SpendReport{
+		Month:      start.Format(MonthLayout),
+		Purchases:  len(purchases),
+		Lists:      []models.ListSpend{},
+		Categories: []models.CategorySpend{},
+	}
+	for _, purchase := range purchases {
+		report.Total += purchase.Price
+		byList[purchase.ListID] += purchase.Price
+		byCategory[purchase.Category] += purchase.Price
+	}
+	report.Total = round(report.Total)
+	if len(memberLists) == 0 {
+		report.Currency = s.DefaultCurrency
+	}
+
+	for i, list := range memberLists {
+		spend := models.ListSpend{
+			ListID:   list.ID,
+			ListName: list.Name,
+			Total:    round(byList[list.ID]),
+			Budget:   list.MonthlyBudget,
+			Currency: s.Currency(list),
+		}
+		if i == 0 {
+			report.Currency = spend.Currency
+		} else if report.Currency != spend.Currency {
+			report.Currency = ""
+		}
+		if list.MonthlyBudget != nil {
+			spend.OverBudget = spend.Total > *list.MonthlyBudget
+			report.OverBudget = report.OverBudget || spend.OverBudget
+			budget := *list.MonthlyBudget
+			if report.Budget != nil {
+				budget += *report.Budget
+			}
+			report.Budget = &budget
+		}
+		report.Lists = append(report.Lists, spend)
+	}
+
+	for category, total := range byCategory {
+		report.Categories = append(report.Categories, models.CategorySpend{Category: category, Total: round(total)})
+	}
+	// Most spent on first
+	sort.Slice(report.Categories, func(i, j int) bool {
+		if report.Categories[i].Total != report.Categories[j].Total {
+			return report.Categories[i].Total > report.Categories[j].Total
+		}
+		return report.Categories[i].Category < report.Categories[j].Category
+	})
+
+	return report, nil
+}
+
+// checkBudget emails the owners of a list if its spend in the month of the given time exceeds
+// its budget, unless they were already alerted this month.
+func (s *Service) checkBudget(listID string, at time.Time) error {
+	var list models.ShoppingList
+	if err := s.DB.First(&list, "id = ?", listID).Error; err != nil {
+		return err
+	}
+	month := at.Format(MonthLayout)
+	if list.MonthlyBudget == nil || list.BudgetAlertMonth == month {
+		return nil
+	}
+
+	start, _ := parseMonth(month)
+	var spend float64
+	err := s.DB.Model(&models.Purchase{}).
+		Where("list_id = ? AND purchased_at >= ? AND purchased_at < ?", listID, start, start.AddDate(0, 1, 0)).
+		Select("COALESCE(SUM(price), 0)").Scan(&spend).Error
+	if err != nil {
+		return err
+	}
+	if round(spend) <= *list.MonthlyBudget {
+		return nil
+	}
+
+	var emails []string
+	s.DB.Model(&models.User{}).
+		Joins("JOIN list_members ON users.id = list_members.user_id").
+		Where("list_members.list_id = ? AND list_members.role IN ?", listID, lists.OwnerRoles).
+		Pluck("users.email", &emails)
+	if emails, err = pii.DecryptAll(emails); err != nil {
+		return err
+	}
+	for _, email := range emails {
+		if err := s.sendBudgetAlert(email, &list, round(spend)); err != nil {
+			slog.Warn("Failed to send budget alert", "list", listID, "error", err)
+		}
+	}
+
+	return s.DB.Model(&list).UpdateColumn("budget_alert_month", month).Error
+}
+
+// sendBudgetAlert emails an owner of a list that its monthly budget is exceeded.
+func (s *Service) sendBudgetAlert(email string, list *models.ShoppingList, spend float64) error {
+	// Skip email sending in test environment
+	if os.Getenv("GO_ENV") == "test" {
+		return nil
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", os.Getenv("SMTP_FROM"))
+	m.SetHeader("To", email)
+	m.SetHeader("Subject", fmt.Sprintf("%s is over its monthly budget", list.Name))
+	code := s.Currency(*list)
+	m.SetBody("text/plain", fmt.Sprintf(`
+This month, %s has been spent on "%s", more than its monthly budget of %s.
+
+You will not be alerted about this list again until next month.
+`, currency.Format(spend, code, ""), list.Name, currency.Format(*list.MonthlyBudget, code, "")))
+
+	return mail.Send(s.Mailer, m)
+}
+
+// parseMonth returns the start of a month like 2025-03 in local time, or of the current month
+// for an empty string.
+func parseMonth(month string) (time.Time, error) {
+	if month == "" {
+		now := time.Now()
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local), nil
+	}
+	start, err := time.ParseInLocation(MonthLayout, month, time.Local)
+	if err != nil {
+		return time.Time{}, ErrInvalidMonth
+	}
+	return start, nil
+}
+
+// round rounds an amount to cents.
+func round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
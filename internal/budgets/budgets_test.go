@@ -0,0 +1,167 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package budgets
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/currency"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+)
+
+func price(value float64) *float64 {
+	return &value
+}
+
+func TestService_Report(t *testing.T) {
+	t.Setenv("GO_ENV", "test")
+	db := testutils.SetupTestDB(t)
+	service := NewService(db, nil)
+
+	users := []models.User{
+		{ID: "owner-id", Email: "owner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()},
+		{ID: "partner-id", Email: "partner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()},
+	}
+	if err := db.Create(&users).Error; err != nil {
+		t.Fatalf("Failed to create users: %v", err)
+	}
+	groceries, err := service.Lists.CreateList("owner-id", "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	hardware, err := service.Lists.CreateList("owner-id", "Hardware")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	if err := service.Lists.JoinList(groceries.ID, "partner-id"); err != nil {
+		t.Fatalf("Failed to join list: %v", err)
+	}
+
+	if _, err := service.SetMonthlyBudget(groceries.ID, "partner-id", 10); err == nil {
+		t.Error("Expected editors not to set the budget")
+	}
+	list, err := service.SetMonthlyBudget(groceries.ID, "owner-id", 10)
+	if err != nil || list.MonthlyBudget == nil || *list.MonthlyBudget != 10 {
+		t.Fatalf("Failed to set budget: %+v, %v", list, err)
+	}
+
+	if _, err := service.SetCurrency(hardware.ID, "owner-id", "dollars"); err == nil {
+		t.Error("Expected invalid currencies to be rejected")
+	}
+	if list, err := service.SetCurrency(hardware.ID, "owner-id", "usd"); err != nil || service.Currency(*list) != "USD" {
+		t.Fatalf("Failed to set currency: %+v, %v", list, err)
+	}
+	if service.Currency(*groceries) != currency.Default {
+		t.Errorf("Expected lists without currency to use the default, got %q", service.Currency(*groceries))
+	}
+
+	lastMonth := time.Now().AddDate(0, -1, 0)
+	purchases := []models.ShoppingItem{
+		{ID: "milk", ListID: groceries.ID, Name: "Milk", Category: "Dairy", Price: price(1.29)},
+		{ID: "butter", ListID: groceries.ID, Name: "Butter", Category: "Dairy", Price: price(2.49)},
+		{ID: "bread", ListID: groceries.ID, Name: "Bread", Category: "Bakery", Price: price(3.1)},
+		{ID: "screws", ListID: hardware.ID, Name: "Screws", Price: price(4.99)},
+		{ID: "salt", ListID: groceries.ID, Name: "Salt", Category: "Pantry"},
+		{ID: "old", ListID: groceries.ID, Name: "Coffee", Category: "Pantry", Price: price(8), CompletedAt: &lastMonth},
+	}
+	for _, item := range purchases {
+		if err := service.RecordPurchase(item, "partner-id"); err != nil {
+			t.Fatalf("Failed to record purchase: %v", err)
+		}
+	}
+
+	report, err := service.Report("owner-id", "", "")
+	if err != nil {
+		t.Fatalf("Failed to report: %v", err)
+	}
+	if report.Month != time.Now().Format(MonthLayout) || report.Total != 11.87 || report.Purchases != 4 {
+		t.Errorf("Expected 4 purchases for 11.87 this month, got %+v", report)
+	}
+	if report.Budget == nil || *report.Budget != 10 || report.OverBudget {
+		t.Errorf("Expected the budget of 10 not to be exceeded, got %+v", report)
+	}
+	if len(report.Lists) != 2 || report.Lists[0].Total != 6.88 || report.Lists[0].OverBudget || report.Lists[1].Total != 4.99 {
+		t.Errorf("Unexpected list spend %+v", report.Lists)
+	}
+	if report.Currency != "" || report.Lists[0].Currency != "EUR" || report.Lists[1].Currency != "USD" {
+		t.Errorf("Expected the currencies per list only, got %+v", report)
+	}
+	if len(report.Categories) != 3 || report.Categories[0] != (models.CategorySpend{Category: "", Total: 4.99}) ||
+		report.Categories[1] != (models.CategorySpend{Category: "Dairy", Total: 3.78}) {
+		t.Errorf("Expected categories by spend, got %+v", report.Categories)
+	}
+
+	report, err = service.Report("partner-id", lastMonth.Format(MonthLayout), "")
+	if err != nil || report.Total != 8 || len(report.Lists) != 1 {
+		t.Errorf("Expected only last month's purchase on the partner's list, got %+v, %v", report, err)
+	}
+	if _, err := service.Report("partner-id", "", hardware.ID); err == nil {
+		t.Error("Expected reports of other lists to be denied")
+	}
+	if _, err := service.Report("owner-id", "March", ""); !errors.Is(err, ErrInvalidMonth) {
+		t.Errorf("Expected ErrInvalidMonth, got %v", err)
+	}
+
+	// Unchecking the item removes its purchase
+	now := time.Now()
+	if err := service.UndoPurchase("milk", now.Add(-time.Minute)); err != nil {
+		t.Fatalf("Failed to undo purchase: %v", err)
+	}
+	report, _ = service.Report("owner-id", "", groceries.ID)
+	if report.Total != 5.59 || len(report.Lists) != 1 {
+		t.Errorf("Expected the milk to be gone, got %+v", report)
+	}
+}
+
+func TestService_BudgetAlert(t *testing.T) {
+	t.Setenv("GO_ENV", "test")
+	db := testutils.SetupTestDB(t)
+	service := NewService(db, nil)
+
+	owner := models.User{ID: "owner-id", Email: "owner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	if err := db.Create(&owner).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	list, err := service.Lists.CreateList(owner.ID, "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	alerted := func() string {
+		var stored models.ShoppingList
+		db.First(&stored, "id = ?", list.ID)
+		return stored.BudgetAlertMonth
+	}
+
+	// Without budget nothing is alerted
+	if err := service.RecordPurchase(models.ShoppingItem{ID: "wine", ListID: list.ID, Price: price(20)}, owner.ID); err != nil {
+		t.Fatalf("Failed to record purchase: %v", err)
+	}
+	if alerted() != "" {
+		t.Error("Expected no alert without budget")
+	}
+
+	if _, err := service.SetMonthlyBudget(list.ID, owner.ID, 25); err != nil {
+		t.Fatalf("Failed to set budget: %v", err)
+	}
+	if err := service.RecordPurchase(models.ShoppingItem{ID: "cheese", ListID: list.ID, Price: price(5)}, owner.ID); err != nil {
+		t.Fatalf("Failed to record purchase: %v", err)
+	}
+	if alerted() != "" {
+		t.Error("Expected no alert while spend equals the budget")
+	}
+	if err := service.RecordPurchase(models.ShoppingItem{ID: "bread", ListID: list.ID, Price: price(0.01)}, owner.ID); err != nil {
+		t.Fatalf("Failed to record purchase: %v", err)
+	}
+	if alerted() != time.Now().Format(MonthLayout) {
+		t.Errorf("Expected the alert of this month to be recorded, got %q", alerted())
+	}
+
+	if list, err := service.SetMonthlyBudget(list.ID, owner.ID, 0); err != nil || list.MonthlyBudget != nil {
+		t.Errorf("Expected the budget to be removed, got %+v, %v", list, err)
+	}
+}
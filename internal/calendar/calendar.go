@@ -0,0 +1,224 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package calendar provides per-user iCalendar feeds with due-dated items and planned shopping
+// trips, served under a secret URL so calendar apps can subscribe without logging in.
+package calendar
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/codes"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// tokenPrefix marks calendar feed tokens so they are recognizable in logs and secret scanners.
+const tokenPrefix = "slc_"
+
+// TripDuration is the length of the calendar event of a planned shopping trip.
+const TripDuration = time.Hour
+
+// ErrNotFound is returned when the user has no calendar feed.
+var ErrNotFound = errors.New("calendar feed not found")
+
+// ErrInvalidToken is returned when a feed token is unknown or has been revoked.
+var ErrInvalidToken = errors.New("invalid calendar feed token")
+
+// Service manages calendar feeds and renders them.
+type Service struct {
+	DB *gorm.DB
+}
+
+// NewService creates a new calendar service.
+func NewService(db *gorm.DB) *Service {
+	return &Service{DB: db}
+}
+
+// hashToken returns the hex-encoded SHA-256 hash under which a feed token is stored.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetFeed retrieves the calendar feed of the user.
+func (s *Service) GetFeed(userID string) (*models.CalendarFeed, error) {
+	var feed models.CalendarFeed
+	err := s.DB.First(&feed, "user_id = ?", userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+// CreateFeed creates the calendar feed of the user and returns it together with the plaintext
+// token. An existing feed is replaced, so its old URL stops working.
+func (s *Service) CreateFeed(userID string) (*models.CalendarFeed, string, error) {
+	token := tokenPrefix + codes.Hex(24)
+
+	feed := models.CalendarFeed{
+		UserID:    userID,
+		Prefix:    token[:len(tokenPrefix)+6],
+		TokenHash: hashToken(token),
+		CreatedAt: time.Now(),
+	}
+
+	// Save replaces the feed including its last use
+	if err := s.DB.Save(&feed).Error; err != nil {
+		return nil, "", err
+	}
+
+	return &feed, token, nil
+}
+
+// RevokeFeed deletes the calendar feed of the user.
+func (s *Service) RevokeFeed(userID string) error {
+	result := s.DB.Delete(&models.CalendarFeed{}, "user_id = ?", userID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ValidateToken looks up the user owning the feed token and records its usage.
+func (s *Service) ValidateToken(token string) (string, error) {
+	if !strings.HasPrefix(token, tokenPrefix) {
+		return "", ErrInvalidToken
+	}
+
+	var feed models.CalendarFeed
+	if err := s.DB.Where("token_hash = ?", hashToken(token)).First(&feed).Error; err != nil {
+		return "", ErrInvalidToken
+	}
+
+	now := time.Now()
+	s.DB.Model(&feed).Update("last_used_at", &now)
+
+	return feed.UserID, nil
+}
+
+// dueItem is an open item with a due date, joined with the name of its list.
+type dueItem struct {
+	ID       string
+	Name     string
+	ListName string
+	DueDate  time.Time
+}
+
+// tripEvent is a planned shopping trip, joined with the name of its list.
+type tripEvent struct {
+	ID          string
+	ListName    string
+	Note        string
+	ScheduledAt time.Time
+	UpdatedAt   time.Time
+}
+
+// Feed renders the iCalendar feed of the user: open items with a due date and planned shopping
+// trips on all lists the user is a member of.
+func (s *Service) Feed(userID string, now time.Time) (string, error) {
+	memberLists := s.DB.Model(&models.ListMember{}).Select("list_id").Where("user_id = ?", userID)
+
+	var dueItems []dueItem
+	err := s.DB.Model(&models.ShoppingItem{}).
+		Select("shopping_items.id, shopping_items.name, shopping_lists.name AS list_name, shopping_items.due_date").
+		Joins("JOIN shopping_lists ON shopping_lists.id = shopping_items.list_id").
+		Where("shopping_items.list_id IN (?) AND shopping_items.due_date IS NOT NULL AND shopping_items.completed = ?", memberLists, false).
+		Order("shopping_items.due_date ASC").
+		Scan(&dueItems).Error
+	if err != nil {
+		return "", err
+	}
+
+	var trips []tripEvent
+	err = s.DB.Model(&models.ShoppingTrip{}).
+		Select("shopping_trips.id, shopping_lists.name AS list_name, shopping_trips.note, shopping_trips.scheduled_at, shopping_trips.updated_at").
+		Joins("JOIN shopping_lists ON shopping_lists.id = shopping_trips.list_id").
+		Where("shopping_trips.list_id IN (?)", memberLists).
+		Order("shopping_trips.scheduled_at ASC").
+		Scan(&trips).Error
+	if err != nil {
+		return "", err
+	}
+
+	var ics icsWriter
+	ics.line("BEGIN:VCALENDAR")
+	ics.line("VERSION:2.0")
+	ics.line("PRODID:-//shopping-list-server//Shopping List//EN")
+	ics.line("CALSCALE:GREGORIAN")
+	ics.line("X-WR-CALNAME:Shopping List")
+
+	stamp := formatTime(now)
+	for _, item := range dueItems {
+		ics.line("BEGIN:VEVENT")
+		ics.line("UID:item-" + item.ID + "@shopping-list-server")
+		ics.line("DTSTAMP:" + stamp)
+		ics.line("DTSTART;VALUE=DATE:" + item.DueDate.Format("20060102"))
+		ics.line("DTEND;VALUE=DATE:" + item.DueDate.AddDate(0, 0, 1).Format("20060102"))
+		ics.line("SUMMARY:" + escapeText(item.Name+" ("+item.ListName+")"))
+		ics.line("TRANSP:TRANSPARENT")
+		ics.line("END:VEVENT")
+	}
+	for _, trip := range trips {
+		ics.line("BEGIN:VEVENT")
+		ics.line("UID:trip-" + trip.ID + "@shopping-list-server")
+		ics.line("DTSTAMP:" + stamp)
+		ics.line("LAST-MODIFIED:" + formatTime(trip.UpdatedAt))
+		ics.line("DTSTART:" + formatTime(trip.ScheduledAt))
+		ics.line("DTEND:" + formatTime(trip.ScheduledAt.Add(TripDuration)))
+		ics.line("SUMMARY:" + escapeText("Shopping trip: "+trip.ListName))
+		if trip.Note != "" {
+			ics.line("DESCRIPTION:" + escapeText(trip.Note))
+		}
+		ics.line("END:VEVENT")
+	}
+
+	ics.line("END:VCALENDAR")
+	return ics.String(), nil
+}
+
+// formatTime formats a time as iCalendar UTC date-time.
+func formatTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// escapeText escapes an iCalendar TEXT value.
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// icsWriter writes iCalendar content lines, folded to at most 75 octets and ended with CRLF.
+type icsWriter struct {
+	strings.Builder
+}
+
+func (w *icsWriter) line(s string) {
+	// Continuation lines start with a space, which counts towards their length
+	limit := 75
+	for len(s) > limit {
+		// Fold at a rune boundary
+		cut := limit
+		for cut > 0 && !isRuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut] + "\r\n ")
+		s = s[cut:]
+		limit = 74
+	}
+	w.WriteString(s + "\r\n")
+}
+
+// isRuneStart reports whether b is the first byte of a UTF-8 encoded rune.
+func isRuneStart(b byte) bool {
+	return b&0xc0 != 0x80
+}
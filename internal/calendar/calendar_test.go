@@ -0,0 +1,149 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package calendar
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/lists"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+)
+
+func TestService_FeedLifecycle(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+
+	if _, err := service.GetFeed("user"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+
+	feed, token, err := service.CreateFeed("user")
+	if err != nil {
+		t.Fatalf("Failed to create feed: %v", err)
+	}
+	if !strings.HasPrefix(token, tokenPrefix) || !strings.HasPrefix(token, feed.Prefix) {
+		t.Errorf("Unexpected token %q with prefix %q", token, feed.Prefix)
+	}
+
+	userID, err := service.ValidateToken(token)
+	if err != nil || userID != "user" {
+		t.Fatalf("Expected token of user, got %q, %v", userID, err)
+	}
+	stored, err := service.GetFeed("user")
+	if err != nil {
+		t.Fatalf("Failed to get feed: %v", err)
+	}
+	if stored.LastUsedAt == nil {
+		t.Error("Expected last use to be recorded")
+	}
+
+	// Regenerating invalidates the old URL
+	_, regenerated, err := service.CreateFeed("user")
+	if err != nil {
+		t.Fatalf("Failed to regenerate feed: %v", err)
+	}
+	if _, err := service.ValidateToken(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected old token to be invalid, got %v", err)
+	}
+	if _, err := service.ValidateToken(regenerated); err != nil {
+		t.Errorf("Expected regenerated token to be valid, got %v", err)
+	}
+
+	if err := service.RevokeFeed("user"); err != nil {
+		t.Fatalf("Failed to revoke feed: %v", err)
+	}
+	if _, err := service.ValidateToken(regenerated); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected revoked token to be invalid, got %v", err)
+	}
+	if err := service.RevokeFeed("user"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestService_Feed(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+
+	for _, id := range []string{"user", "stranger"} {
+		user := models.User{ID: id, Email: id + "@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+		if err := db.Create(&user).Error; err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+	list, err := lists.NewService(db).CreateList("user", "Groceries, weekly")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	other, err := lists.NewService(db).CreateList("stranger", "Hardware")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	due := time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)
+	scheduled := time.Date(2025, 3, 15, 9, 30, 0, 0, time.UTC)
+	records := []any{
+		&models.ShoppingItem{ID: "cake", ListID: list.ID, Name: "Birthday cake", DueDate: &due, CreatedAt: time.Now()},
+		&models.ShoppingItem{ID: "done", ListID: list.ID, Name: "Candles", DueDate: &due, Completed: true, CreatedAt: time.Now()},
+		&models.ShoppingItem{ID: "milk", ListID: list.ID, Name: "Milk", CreatedAt: time.Now()},
+		&models.ShoppingItem{ID: "nails", ListID: other.ID, Name: "Nails", DueDate: &due, CreatedAt: time.Now()},
+		&models.ShoppingTrip{ID: "trip", ListID: list.ID, ScheduledAt: scheduled, Note: "Bring bags; and coupons", CreatedBy: "user"},
+	}
+	for _, record := range records {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatalf("Failed to create record: %v", err)
+		}
+	}
+
+	ics, err := service.Feed("user", time.Now())
+	if err != nil {
+		t.Fatalf("Failed to render feed: %v", err)
+	}
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"UID:item-cake@shopping-list-server\r\n",
+		"DTSTART;VALUE=DATE:20250314\r\n",
+		"DTEND;VALUE=DATE:20250315\r\n",
+		`SUMMARY:Birthday cake (Groceries\, weekly)` + "\r\n",
+		"UID:trip-trip@shopping-list-server\r\n",
+		"DTSTART:20250315T093000Z\r\n",
+		"DTEND:20250315T103000Z\r\n",
+		`DESCRIPTION:Bring bags\; and coupons` + "\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("Expected feed to contain %q, got:\n%s", want, ics)
+		}
+	}
+	for _, unwanted := range []string{"Candles", "Milk", "Nails"} {
+		if strings.Contains(ics, unwanted) {
+			t.Errorf("Expected feed not to contain %q", unwanted)
+		}
+	}
+}
+
+func TestICSWriter_Folds(t *testing.T) {
+	var w icsWriter
+	w.line("SUMMARY:" + strings.Repeat("ä", 50))
+
+	lines := strings.Split(strings.TrimSuffix(w.String(), "\r\n"), "\r\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+	for _, line := range lines {
+		if len(line) > 75 {
+			t.Errorf("Line exceeds 75 octets: %d", len(line))
+		}
+	}
+	if !strings.HasPrefix(lines[1], " ") {
+		t.Error("Expected continuation line to start with a space")
+	}
+	if strings.Join([]string{lines[0], lines[1][1:]}, "") != "SUMMARY:"+strings.Repeat("ä", 50) {
+		t.Error("Expected folded lines to unfold to the original")
+	}
+}
@@ -0,0 +1,267 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package catalog provides item name normalization and product categorization based on
+// multi-language keyword dictionaries and admin-defined custom mappings.
+package catalog
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// Category describes a product category with its display emoji.
+type Category struct {
+	Name  string `json:"name"`
+	Emoji string `json:"emoji"`
+}
+
+// Dictionary maps lowercase product keywords to categories. It is safe for concurrent use.
+type Dictionary struct {
+	mu       sync.RWMutex
+	keywords map[string]string
+	emojis   map[string]string
+}
+
+// NewDictionary creates an empty dictionary that knows the built-in category emojis.
+func NewDictionary() *Dictionary {
+	dict := &Dictionary{
+		keywords: make(map[string]string),
+		emojis:   make(map[string]string),
+	}
+	for category, emoji := range defaultEmojis {
+		dict.emojis[category] = emoji
+	}
+	return dict
+}
+
+// DefaultDictionary returns a dictionary populated with all built-in languages.
+func DefaultDictionary() *Dictionary {
+	dict := NewDictionary()
+	for _, language := range BuiltinLanguages {
+		data, err := builtinData.ReadFile("data/" + language + ".json")
+		if err != nil {
+			panic(fmt.Sprintf("catalog: missing built-in dictionary %q: %v", language, err))
+		}
+		if err := dict.load(data); err != nil {
+			panic(fmt.Sprintf("catalog: invalid built-in dictionary %q: %v", language, err))
+		}
+	}
+	return dict
+}
+
+// Add maps a keyword to a category, replacing any existing mapping for the keyword.
+func (d *Dictionary) Add(keyword, category string) {
+	keyword = strings.Join(strings.Fields(strings.ToLower(keyword)), " ")
+	if keyword == "" || category == "" {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.keywords[keyword] = category
+}
+
+// LoadDir loads every *.json file in dir into the dictionary. Each file maps category names
+// to keyword lists, the same format as the built-in dictionaries, and overrides existing
+// mappings for the keywords it contains.
+func (d *Dictionary) LoadDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if err := d.load(data); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+func (d *Dictionary) load(data []byte) error {
+	var categories map[string][]string
+	if err := json.Unmarshal(data, &categories); err != nil {
+		return err
+	}
+	for category, keywords := range categories {
+		for _, keyword := range keywords {
+			d.Add(keyword, category)
+		}
+	}
+	return nil
+}
+
+// Lookup finds the category for the given item name by matching the longest known keyword
+// phrase contained in the name.
+func (d *Dictionary) Lookup(name string) (Category, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	words := strings.Fields(strings.ToLower(name))
+	for size := len(words); size > 0; size-- {
+		for start := 0; start+size <= len(words); start++ {
+			phrase := strings.Join(words[start:start+size], " ")
+			if category, ok := d.keywords[phrase]; ok {
+				return Category{Name: category, Emoji: d.emojis[category]}, true
+			}
+		}
+	}
+	return Category{}, false
+}
+
+// Emoji returns the emoji for the given category, or an empty string if it has none.
+func (d *Dictionary) Emoji(category string) string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.emojis[category]
+}
+
+// Service manages custom keyword mappings stored in the database on top of the dictionary.
+type Service struct {
+	DB         *gorm.DB
+	Dictionary *Dictionary
+}
+
+// NewService creates a new catalog service backed by the given dictionary.
+func NewService(db *gorm.DB, dict *Dictionary) *Service {
+	return &Service{
+		DB:         db,
+		Dictionary: dict,
+	}
+}
+
+// LoadMappings applies all stored custom mappings to the dictionary.
+func (s *Service) LoadMappings() error {
+	mappings, err := s.GetMappings()
+	if err != nil {
+		return err
+	}
+	for _, mapping := range mappings {
+		s.Dictionary.Add(mapping.Keyword, mapping.Category)
+	}
+	return nil
+}
+
+// GetMappings retrieves all custom keyword mappings.
+func (s *Service) GetMappings() ([]models.CategoryMapping, error) {
+	var mappings []models.CategoryMapping
+	err := s.DB.Order("keyword ASC").Find(&mappings).Error
+	return mappings, err
+}
+
+// AddMapping stores a custom keyword mapping and applies it to the dictionary immediately.
+func (s *Service) AddMapping(userID, keyword, category, language string) (*models.CategoryMapping, error) {
+	keyword = strings.Join(strings.Fields(strings.ToLower(keyword)), " ")
+	category = strings.TrimSpace(category)
+	if keyword == "" {
+		return nil, errors.New("keyword cannot be empty")
+	}
+	if category == "" {
+		return nil, errors.New("category cannot be empty")
+	}
+
+	mapping := models.CategoryMapping{
+		Keyword:   keyword,
+		Category:  category,
+		Language:  strings.ToLower(strings.TrimSpace(language)),
+		CreatedBy: userID,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.DB.Save(&mapping).Error; err != nil {
+		return nil, err
+	}
+
+	s.Dictionary.Add(mapping.Keyword, mapping.Category)
+	return &mapping, nil
+}
+
+// Normalizer tidies item names and assigns categories when items are written.
+type Normalizer struct {
+	TitleCase      bool
+	AutoCategorize bool
+	Dictionary     *Dictionary
+}
+
+// NewNormalizer creates a new normalizer using the given dictionary.
+func NewNormalizer(dict *Dictionary, titleCase, autoCategorize bool) *Normalizer {
+	return &Normalizer{
+		TitleCase:      titleCase,
+		AutoCategorize: autoCategorize,
+		Dictionary:     dict,
+	}
+}
+
+// NormalizeName trims the name, collapses repeated whitespace and optionally title-cases it.
+func (n *Normalizer) NormalizeName(name string) string {
+	words := strings.Fields(name)
+	if n.TitleCase {
+		for i, word := range words {
+			words[i] = capitalize(word)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// Categorize returns the category for the given name if auto-categorization is enabled
+// and the name matches a dictionary keyword.
+func (n *Normalizer) Categorize(name string) (Category, bool) {
+	if !n.AutoCategorize || n.Dictionary == nil {
+		return Category{}, false
+	}
+	return n.Dictionary.Lookup(name)
+}
+
+// capitalize upper-cases the first letter of a word. Words that already contain capitals
+// are left untouched, so abbreviations like "UHT" or brand names like "iPhone" survive.
+func capitalize(word string) string {
+	if strings.IndexFunc(word, unicode.IsUpper) >= 0 {
+		return word
+	}
+	r, size := utf8.DecodeRuneInString(word)
+	if r == utf8.RuneError {
+		return word
+	}
+	return string(unicode.ToUpper(r)) + word[size:]
+}
+
+// BuiltinLanguages lists the languages shipped with the built-in dictionary.
+var BuiltinLanguages = []string{"en", "de"}
+
+//go:embed data/*.json
+var builtinData embed.FS
+
+var defaultEmojis = map[string]string{
+	"produce":       "🥦",
+	"dairy":         "🥛",
+	"bakery":        "🍞",
+	"meat":          "🥩",
+	"seafood":       "🐟",
+	"frozen":        "🧊",
+	"beverages":     "🥤",
+	"pantry":        "🥫",
+	"snacks":        "🍫",
+	"household":     "🧻",
+	"personal care": "🧴",
+	"baby":          "🍼",
+	"pet":           "🐾",
+}
@@ -0,0 +1,160 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package catalog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+)
+
+func TestNormalizer_NormalizeName(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		titleCase bool
+		expected  string
+	}{
+		{"trims whitespace", "  milk  ", false, "milk"},
+		{"collapses whitespace", "whole   milk\t2%", false, "whole milk 2%"},
+		{"title-cases words", "whole milk", true, "Whole Milk"},
+		{"keeps abbreviations", "UHT milk", true, "UHT Milk"},
+		{"keeps inner capitals", "iPhone charger", true, "iPhone Charger"},
+		{"handles umlauts", "äpfel", true, "Äpfel"},
+		{"empty input", "   ", true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := NewNormalizer(DefaultDictionary(), tt.titleCase, false)
+			if got := n.NormalizeName(tt.input); got != tt.expected {
+				t.Errorf("NormalizeName(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNormalizer_Categorize(t *testing.T) {
+	n := NewNormalizer(DefaultDictionary(), true, true)
+
+	tests := []struct {
+		input    string
+		expected string
+		found    bool
+	}{
+		{"Milk", "dairy", true},
+		{"Organic Bananas", "produce", true},
+		{"Sparkling Water", "beverages", true},
+		{"Orange Juice", "beverages", true},
+		{"Toilet Paper", "household", true},
+		{"Widget", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			category, ok := n.Categorize(tt.input)
+			if ok != tt.found {
+				t.Fatalf("Categorize(%q) found = %v, want %v", tt.input, ok, tt.found)
+			}
+			if category.Name != tt.expected {
+				t.Errorf("Categorize(%q) = %q, want %q", tt.input, category.Name, tt.expected)
+			}
+			if ok && category.Emoji == "" {
+				t.Errorf("Categorize(%q) should return an emoji", tt.input)
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		disabled := NewNormalizer(DefaultDictionary(), true, false)
+		if _, ok := disabled.Categorize("Milk"); ok {
+			t.Error("Categorize should not match when auto-categorization is disabled")
+		}
+	})
+}
+
+func TestDictionary_German(t *testing.T) {
+	dict := DefaultDictionary()
+
+	tests := map[string]string{
+		"Milch":            "dairy",
+		"Frische Brötchen": "bakery",
+		"Äpfel":            "produce",
+		"Toilettenpapier":  "household",
+	}
+
+	for input, expected := range tests {
+		category, ok := dict.Lookup(input)
+		if !ok {
+			t.Errorf("Lookup(%q) should find a category", input)
+			continue
+		}
+		if category.Name != expected {
+			t.Errorf("Lookup(%q) = %q, want %q", input, category.Name, expected)
+		}
+	}
+}
+
+func TestDictionary_LoadDir(t *testing.T) {
+	dir := t.TempDir()
+	data := `{"pantry": ["Sriracha"], "bakery": ["toast"]}`
+	if err := os.WriteFile(filepath.Join(dir, "custom.json"), []byte(data), 0o600); err != nil {
+		t.Fatalf("Failed to write dictionary file: %v", err)
+	}
+
+	dict := DefaultDictionary()
+	if err := dict.LoadDir(dir); err != nil {
+		t.Fatalf("LoadDir failed: %v", err)
+	}
+
+	if category, ok := dict.Lookup("sriracha"); !ok || category.Name != "pantry" {
+		t.Errorf("Expected 'sriracha' to map to 'pantry', got %q", category.Name)
+	}
+
+	t.Run("invalid file", func(t *testing.T) {
+		if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0o600); err != nil {
+			t.Fatalf("Failed to write dictionary file: %v", err)
+		}
+		if err := dict.LoadDir(dir); err == nil {
+			t.Error("LoadDir should fail on invalid JSON")
+		}
+	})
+}
+
+func TestService_AddMapping(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	dict := DefaultDictionary()
+	service := NewService(db, dict)
+
+	mapping, err := service.AddMapping("admin-id", "  Hafer   Drink ", "dairy", "DE")
+	if err != nil {
+		t.Fatalf("AddMapping failed: %v", err)
+	}
+
+	if mapping.Keyword != "hafer drink" {
+		t.Errorf("Expected keyword to be normalized to 'hafer drink', got '%s'", mapping.Keyword)
+	}
+	if mapping.Language != "de" {
+		t.Errorf("Expected language 'de', got '%s'", mapping.Language)
+	}
+
+	if category, ok := dict.Lookup("Bio Hafer Drink"); !ok || category.Name != "dairy" {
+		t.Errorf("Expected mapping to be applied immediately, got %q", category.Name)
+	}
+
+	// A fresh dictionary picks the mapping up from the database
+	fresh := NewService(db, NewDictionary())
+	if err := fresh.LoadMappings(); err != nil {
+		t.Fatalf("LoadMappings failed: %v", err)
+	}
+	if _, ok := fresh.Dictionary.Lookup("hafer drink"); !ok {
+		t.Error("Expected stored mapping to be loaded")
+	}
+
+	if _, err := service.AddMapping("admin-id", " ", "dairy", ""); err == nil {
+		t.Error("AddMapping should reject an empty keyword")
+	}
+}
@@ -0,0 +1,332 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package chat implements the "/shop" slash command for Slack and Discord. A workspace is linked
+// to a user, so everyone in it, e.g. an office kitchen, shares that user's lists.
+package chat
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/codes"
+	"github.com/oliverandrich/shopping-list-server/internal/lists"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/quantity"
+	"gorm.io/gorm"
+)
+
+// Chat platforms with a slash command endpoint.
+const (
+	PlatformSlack   = "slack"
+	PlatformDiscord = "discord"
+)
+
+// LinkCodeLifetime is how long a code for linking a workspace stays valid.
+const LinkCodeLifetime = 15 * time.Minute
+
+// ErrNotLinked is returned when a workspace is not linked to a user.
+var ErrNotLinked = errors.New("workspace not linked")
+
+// ErrInvalidLinkCode is returned when a link code is unknown or expired.
+var ErrInvalidLinkCode = errors.New("invalid or expired link code")
+
+// helpText lists the subcommands of the slash command.
+const helpText = "Usage:\n" +
+	"/shop add <items> [to <list>] - add items, e.g. \"/shop add milk and coffee to kitchen\"\n" +
+	"/shop list [<list>] - show the open items\n" +
+	"/shop link <code> - link this workspace with the code from the app\n" +
+	"/shop unlink - unlink this workspace"
+
+// QuickAddFunc adds the items of a sentence like "milk and eggs to groceries" on behalf of a user.
+type QuickAddFunc func(userID, text string) (*models.ShoppingList, []models.ShoppingItem, error)
+
+// Service links workspaces to users and runs slash commands.
+type Service struct {
+	DB       *gorm.DB
+	Lists    *lists.Service
+	QuickAdd QuickAddFunc
+	// SlackSigningSecret verifies Slack requests. Slack is disabled when empty.
+	SlackSigningSecret string
+	// DiscordPublicKey verifies Discord requests. Discord is disabled when nil.
+	DiscordPublicKey ed25519.PublicKey
+}
+
+// NewService creates a new chat service; the platforms stay disabled until configured.
+func NewService(db *gorm.DB, quickAdd QuickAddFunc) *Service {
+	return &Service{DB: db, Lists: lists.NewService(db), QuickAdd: quickAdd}
+}
+
+// SlackEnabled reports whether the Slack slash command is configured.
+func (s *Service) SlackEnabled() bool {
+	return s.SlackSigningSecret != ""
+}
+
+// DiscordEnabled reports whether the Discord slash command is configured.
+func (s *Service) DiscordEnabled() bool {
+	return len(s.DiscordPublicKey) == ed25519.PublicKeySize
+}
+
+// CreateLinkCode creates a code the user enters with "/shop link <code>" in the workspace to link.
+func (s *Service) CreateLinkCode(userID string) (*models.ChatLinkCode, error) {
+	code := models.ChatLinkCode{
+		Code:      codes.Digits(6),
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(LinkCodeLifetime),
+	}
+	if err := s.DB.Create(&code).Error; err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
+
+// Link connects the workspace to the user who created the code, replacing an earlier link of the
+// workspace.
+func (s *Service) Link(platform, workspaceID, code string) (*models.ChatLink, error) {
+	var linkCode models.ChatLinkCode
+	if err := s.DB.Where("code = ? AND expires_at > ?", code, time.Now()).First(&linkCode).Error; err != nil {
+		return nil, ErrInvalidLinkCode
+	}
+
+	link := models.ChatLink{
+		Platform:    platform,
+		WorkspaceID: workspaceID,
+		UserID:      linkCode.UserID,
+		LinkedAt:    time.Now(),
+	}
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&link).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&linkCode).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// GetLinks retrieves the workspaces linked to the user.
+func (s *Service) GetLinks(userID string) ([]models.ChatLink, error) {
+	var links []models.ChatLink
+	err := s.DB.Where("user_id = ?", userID).Order("linked_at DESC").Find(&links).Error
+	return links, err
+}
+
+// Unlink removes the link of the workspace. With a userID, only a link to that user is removed.
+func (s *Service) Unlink(platform, workspaceID, userID string) error {
+	query := s.DB.Where("platform = ? AND workspace_id = ?", platform, workspaceID)
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	result := query.Delete(&models.ChatLink{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotLinked
+	}
+	return nil
+}
+
+// UserFor returns the ID of the user the workspace is linked to.
+func (s *Service) UserFor(platform, workspaceID string) (string, error) {
+	var link models.ChatLink
+	if err := s.DB.Where("platform = ? AND workspace_id = ?", platform, workspaceID).First(&link).Error; err != nil {
+		return "", ErrNotLinked
+	}
+	return link.UserID, nil
+}
+
+// Reply is the answer to a slash command. Public replies are shown to the whole channel, others
+// only to the user who ran the command.
+type Reply struct {
+	Text   string
+	Public bool
+}
+
+// Run executes the text of a "/shop" command sent from the workspace.
+func (s *Service) Run(platform, workspaceID, text string) Reply {
+	subcommand, args, _ := strings.Cut(strings.TrimSpace(text), " ")
+	args = strings.TrimSpace(args)
+
+	switch strings.ToLower(subcommand) {
+	case "link":
+		if _, err := s.Link(platform, workspaceID, args); err != nil {
+			return Reply{Text: err.Error()}
+		}
+		return Reply{Text: "This workspace is linked."}
+	case "unlink":
+		if err := s.Unlink(platform, workspaceID, ""); err != nil {
+			return Reply{Text: err.Error()}
+		}
+		return Reply{Text: "This workspace is unlinked."}
+	case "add", "list":
+	default:
+		return Reply{Text: helpText}
+	}
+
+	userID, err := s.UserFor(platform, workspaceID)
+	if err != nil {
+		return Reply{Text: "This workspace is not linked yet: create a link code in the app and run /shop link <code>."}
+	}
+
+	if strings.ToLower(subcommand) == "add" {
+		list, items, err := s.QuickAdd(userID, args)
+		if err != nil {
+			return Reply{Text: err.Error()}
+		}
+		names := make([]string, len(items))
+		for i, item := range items {
+			names[i] = item.Name
+		}
+		return Reply{Text: "Added " + strings.Join(names, ", ") + " to " + list.Name + ".", Public: true}
+	}
+	return s.openItems(userID, args)
+}
+
+// openItems lists the open items of the named list or the user's default list.
+func (s *Service) openItems(userID, listName string) Reply {
+	var list *models.ShoppingList
+	var err error
+	if listName != "" {
+		list, err = s.Lists.FindListByName(userID, listName)
+	} else {
+		list, err = s.Lists.GetDefaultList(userID)
+	}
+	if err != nil {
+		return Reply{Text: err.Error()}
+	}
+
+	var items []models.ShoppingItem
+	err = s.DB.Where("list_id = ? AND completed = ?", list.ID, false).
+		Where("snoozed_until IS NULL OR snoozed_until <= ?", time.Now()).
+		Order("created_at ASC").
+		Find(&items).Error
+	if err != nil {
+		return Reply{Text: err.Error()}
+	}
+	if len(items) == 0 {
+		return Reply{Text: list.Name + " has no open items."}
+	}
+
+	lines := make([]string, len(items))
+	for i, item := range items {
+		lines[i] = "• " + item.Name
+		if formatted := quantity.Format(item.Quantity, item.Unit); formatted != "" {
+			lines[i] += " (" + formatted + ")"
+		}
+	}
+	return Reply{Text: list.Name + ":\n" + strings.Join(lines, "\n")}
+}
+
+// ParseSlack returns the workspace and command text of a Slack slash command request.
+func ParseSlack(body []byte) (string, string, error) {
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return "", "", err
+	}
+	if form.Get("team_id") == "" {
+		return "", "", errors.New("missing team_id")
+	}
+	return form.Get("team_id"), form.Get("text"), nil
+}
+
+// SlackResponse returns the Slack response message of a reply.
+func SlackResponse(reply Reply) map[string]any {
+	responseType := "ephemeral"
+	if reply.Public {
+		responseType = "in_channel"
+	}
+	return map[string]any{"response_type": responseType, "text": reply.Text}
+}
+
+// Discord interaction and response types.
+const (
+	DiscordPing               = 1
+	DiscordApplicationCommand = 2
+
+	discordPong            = 1
+	discordChannelMessage  = 4
+	discordSubcommand      = 1
+	discordSubcommandGroup = 2
+	discordEphemeral       = 1 << 6
+)
+
+// discordOption is an option of a Discord application command, possibly a subcommand with
+// options of its own.
+type discordOption struct {
+	Name    string          `json:"name"`
+	Type    int             `json:"type"`
+	Value   any             `json:"value"`
+	Options []discordOption `json:"options"`
+}
+
+// DiscordInteraction is the part of a Discord interaction the slash command uses.
+type DiscordInteraction struct {
+	Type    int    `json:"type"`
+	GuildID string `json:"guild_id"`
+	User    *struct {
+		ID string `json:"id"`
+	} `json:"user"`
+	Data struct {
+		Options []discordOption `json:"options"`
+	} `json:"data"`
+}
+
+// ParseDiscord decodes a Discord interaction.
+func ParseDiscord(body []byte) (*DiscordInteraction, error) {
+	var interaction DiscordInteraction
+	if err := json.Unmarshal(body, &interaction); err != nil {
+		return nil, err
+	}
+	return &interaction, nil
+}
+
+// WorkspaceID returns the Discord server the command was sent from; direct messages count as a
+// workspace of their own per user.
+func (i *DiscordInteraction) WorkspaceID() string {
+	if i.GuildID == "" && i.User != nil {
+		return "user:" + i.User.ID
+	}
+	return i.GuildID
+}
+
+// Text joins the subcommands and option values of the command, e.g. "add milk to kitchen" for
+// the subcommand "add" with the option "items" set to "milk to kitchen".
+func (i *DiscordInteraction) Text() string {
+	return strings.Join(optionWords(i.Data.Options), " ")
+}
+
+func optionWords(options []discordOption) []string {
+	var words []string
+	for _, option := range options {
+		if option.Type == discordSubcommand || option.Type == discordSubcommandGroup {
+			words = append(words, option.Name)
+			words = append(words, optionWords(option.Options)...)
+		} else if option.Value != nil {
+			words = append(words, fmt.Sprint(option.Value))
+		}
+	}
+	return words
+}
+
+// DiscordResponse returns the Discord interaction response of a reply.
+func DiscordResponse(reply Reply) map[string]any {
+	data := map[string]any{"content": reply.Text}
+	if !reply.Public {
+		data["flags"] = discordEphemeral
+	}
+	return map[string]any{"type": discordChannelMessage, "data": data}
+}
+
+// DiscordPong returns the response to Discord's ping when the endpoint is registered.
+func DiscordPong() map[string]any {
+	return map[string]any{"type": discordPong}
+}
@@ -0,0 +1,168 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package chat
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/lists"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+)
+
+func TestService_Run(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+
+	user := models.User{ID: "alice", Email: "alice@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	kitchen, err := lists.NewService(db).CreateList("alice", "Kitchen")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	var added []string
+	service := NewService(db, func(userID, text string) (*models.ShoppingList, []models.ShoppingItem, error) {
+		added = append(added, userID+": "+text)
+		return kitchen, []models.ShoppingItem{{Name: "Milk"}, {Name: "Coffee"}}, nil
+	})
+
+	if reply := service.Run(PlatformSlack, "T1", "dance"); reply.Text != helpText {
+		t.Errorf("Expected help, got %q", reply.Text)
+	}
+	if reply := service.Run(PlatformSlack, "T1", "add milk"); !strings.Contains(reply.Text, "not linked") || reply.Public {
+		t.Errorf("Expected a private request to link, got %+v", reply)
+	}
+	if reply := service.Run(PlatformSlack, "T1", "link 000000"); reply.Text != ErrInvalidLinkCode.Error() {
+		t.Errorf("Expected invalid code, got %q", reply.Text)
+	}
+
+	code, err := service.CreateLinkCode("alice")
+	if err != nil {
+		t.Fatalf("Failed to create link code: %v", err)
+	}
+	if reply := service.Run(PlatformSlack, "T1", "link "+code.Code); reply.Text != "This workspace is linked." {
+		t.Fatalf("Unexpected reply %q", reply.Text)
+	}
+	if _, err := service.UserFor(PlatformDiscord, "T1"); !errors.Is(err, ErrNotLinked) {
+		t.Errorf("Expected the link to be per platform, got %v", err)
+	}
+
+	reply := service.Run(PlatformSlack, "T1", "add milk and coffee to kitchen")
+	if reply.Text != "Added Milk, Coffee to Kitchen." || !reply.Public {
+		t.Errorf("Unexpected reply %+v", reply)
+	}
+	if len(added) != 1 || added[0] != "alice: milk and coffee to kitchen" {
+		t.Errorf("Unexpected quick-adds %v", added)
+	}
+
+	if reply := service.Run(PlatformSlack, "T1", "list kitchen"); reply.Text != "Kitchen has no open items." {
+		t.Errorf("Unexpected reply %q", reply.Text)
+	}
+	item := models.ShoppingItem{ID: "milk", ListID: kitchen.ID, Name: "Milk", Quantity: 2, Unit: "l", Tags: "[]", CreatedAt: time.Now()}
+	if err := db.Create(&item).Error; err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+	if reply := service.Run(PlatformSlack, "T1", "list kitchen"); !strings.Contains(reply.Text, "• Milk (2 l)") {
+		t.Errorf("Expected the open item, got %q", reply.Text)
+	}
+
+	links, err := service.GetLinks("alice")
+	if err != nil || len(links) != 1 {
+		t.Fatalf("Expected one link, got %v, %v", links, err)
+	}
+	if err := service.Unlink(PlatformSlack, "T1", "bob"); !errors.Is(err, ErrNotLinked) {
+		t.Errorf("Expected other users not to unlink the workspace, got %v", err)
+	}
+	if reply := service.Run(PlatformSlack, "T1", "unlink"); reply.Text != "This workspace is unlinked." {
+		t.Errorf("Unexpected reply %q", reply.Text)
+	}
+}
+
+func TestService_VerifySlack(t *testing.T) {
+	service := &Service{SlackSigningSecret: "secret"}
+	body := []byte("team_id=T1&text=add+milk")
+	now := time.Now()
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	signature := "v0=" + hex.EncodeToString(mac.Sum(nil))
+
+	if !service.VerifySlack(timestamp, signature, body, now) {
+		t.Error("Expected a valid signature")
+	}
+	if service.VerifySlack(timestamp, signature, []byte("team_id=T1&text=add+beer"), now) {
+		t.Error("Expected a tampered body to be rejected")
+	}
+	if service.VerifySlack(timestamp, signature, body, now.Add(MaxRequestAge+time.Second)) {
+		t.Error("Expected an old request to be rejected")
+	}
+	if (&Service{}).VerifySlack(timestamp, signature, body, now) {
+		t.Error("Expected requests to be rejected without a signing secret")
+	}
+}
+
+func TestService_VerifyDiscord(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	service := &Service{DiscordPublicKey: publicKey}
+	body := []byte(`{"type":1}`)
+	signature := hex.EncodeToString(ed25519.Sign(privateKey, append([]byte("1700000000"), body...)))
+
+	if !service.VerifyDiscord("1700000000", signature, body) {
+		t.Error("Expected a valid signature")
+	}
+	if service.VerifyDiscord("1700000001", signature, body) {
+		t.Error("Expected a different timestamp to be rejected")
+	}
+	if service.VerifyDiscord("1700000000", "zz", body) {
+		t.Error("Expected a malformed signature to be rejected")
+	}
+}
+
+func TestParseDiscord(t *testing.T) {
+	interaction, err := ParseDiscord([]byte(`{
+		"type": 2,
+		"guild_id": "G1",
+		"data": {"name": "shop", "options": [
+			{"name": "add", "type": 1, "options": [{"name": "items", "type": 3, "value": "milk to kitchen"}]}
+		]}
+	}`))
+	if err != nil {
+		t.Fatalf("Failed to parse interaction: %v", err)
+	}
+	if interaction.Type != DiscordApplicationCommand || interaction.WorkspaceID() != "G1" {
+		t.Errorf("Unexpected interaction %+v", interaction)
+	}
+	if text := interaction.Text(); text != "add milk to kitchen" {
+		t.Errorf("Expected %q, got %q", "add milk to kitchen", text)
+	}
+
+	direct, err := ParseDiscord([]byte(`{"type": 2, "user": {"id": "U1"}, "data": {"options": [{"name": "unlink", "type": 1}]}}`))
+	if err != nil {
+		t.Fatalf("Failed to parse interaction: %v", err)
+	}
+	if direct.WorkspaceID() != "user:U1" || direct.Text() != "unlink" {
+		t.Errorf("Unexpected direct message interaction %+v", direct)
+	}
+
+	response := DiscordResponse(Reply{Text: "hi"})
+	if data := response["data"].(map[string]any); data["flags"] != discordEphemeral {
+		t.Errorf("Expected a private reply, got %v", response)
+	}
+}
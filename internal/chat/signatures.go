@@ -0,0 +1,51 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package chat
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// MaxRequestAge is how old a signed Slack request may be before it is rejected as a replay.
+const MaxRequestAge = 5 * time.Minute
+
+// VerifySlack checks the X-Slack-Signature of a request against the signing secret and rejects
+// requests whose X-Slack-Request-Timestamp is too far from now.
+func (s *Service) VerifySlack(timestamp, signature string, body []byte, now time.Time) bool {
+	if s.SlackSigningSecret == "" {
+		return false
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > MaxRequestAge || age < -MaxRequestAge {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(s.SlackSigningSecret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// VerifyDiscord checks the X-Signature-Ed25519 of a request against the application's public key.
+func (s *Service) VerifyDiscord(timestamp, signature string, body []byte) bool {
+	if len(s.DiscordPublicKey) != ed25519.PublicKeySize {
+		return false
+	}
+
+	sig, err := hex.DecodeString(signature)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return false
+	}
+	return ed25519.Verify(s.DiscordPublicKey, append([]byte(timestamp), body...), sig)
+}
@@ -0,0 +1,336 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package codec re-encodes JSON responses as MessagePack or CBOR for bandwidth-constrained clients
+// like watches and e-ink displays, which ask for them in the Accept header.
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Binary media types.
+const (
+	MediaTypeMessagePack = "application/msgpack"
+	MediaTypeCBOR        = "application/cbor"
+)
+
+// aliases maps alternative names of the media types to them.
+var aliases = map[string]string{
+	MediaTypeMessagePack:    MediaTypeMessagePack,
+	"application/x-msgpack": MediaTypeMessagePack,
+	MediaTypeCBOR:           MediaTypeCBOR,
+}
+
+// Quality returns the quality an Accept header gives a media type, or -1 if it does not list it.
+// Wildcards are ignored, since they never select anything but the JSON default.
+func Quality(accept, mediaType string) float64 {
+	for _, part := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(name), mediaType) {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if q, err := strconv.ParseFloat(value, 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		return quality
+	}
+	return -1
+}
+
+// Negotiate returns the binary media type an Accept header prefers, or "" if the client does not
+// prefer any of them over JSON.
+func Negotiate(accept string) string {
+	best, bestQuality := "", 0.0
+	for alias, mediaType := range aliases {
+		quality := Quality(accept, alias)
+		if quality > bestQuality || (quality == bestQuality && quality > 0 && mediaType < best) {
+			best, bestQuality = mediaType, quality
+		}
+	}
+	if best == "" || bestQuality < Quality(accept, "application/json") {
+		return ""
+	}
+	return best
+}
+
+// FromJSON re-encodes a JSON document in a binary media type. Numbers without a fraction become
+// integers, timestamps stay strings, and map keys are sorted so equal documents encode equally.
+func FromJSON(mediaType string, data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	var w writer
+	switch aliases[mediaType] {
+	case MediaTypeMessagePack:
+		w = &messagePack{}
+	case MediaTypeCBOR:
+		w = &cbor{}
+	default:
+		return nil, errors.New("unsupported media type")
+	}
+	if err := encode(w, value); err != nil {
+		return nil, err
+	}
+	return w.bytes(), nil
+}
+
+// tagSuffixes tell apart the entity tags of the encodings of a JSON document.
+var tagSuffixes = map[string]string{
+	MediaTypeMessagePack: "-msgpack",
+	MediaTypeCBOR:        "-cbor",
+}
+
+// Tag returns the entity tag of the encoding in mediaType of a JSON document tagged etag, e.g.
+// "12-345-cbor" for "12-345", so clients and caches never take one encoding for another.
+func Tag(etag, mediaType string) string {
+	suffix := tagSuffixes[aliases[mediaType]]
+	if suffix == "" || !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	return strings.TrimSuffix(etag, `"`) + suffix + `"`
+}
+
+// Untag turns the entity tags of an If-None-Match header back into the tags of the JSON documents,
+// keeping only those of the encoding in mediaType. It returns "" if none are left.
+func Untag(ifNoneMatch, mediaType string) string {
+	suffix := tagSuffixes[aliases[mediaType]] + `"`
+	var tags []string
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if untagged, ok := strings.CutSuffix(tag, suffix); ok && suffix != `"` {
+			tags = append(tags, untagged+`"`)
+		}
+	}
+	return strings.Join(tags, ", ")
+}
+
+// writer writes the values of a decoded JSON document in a binary format.
+type writer interface {
+	null()
+	boolean(v bool)
+	integer(v int64)
+	float(v float64)
+	text(v string)
+	array(n int)
+	object(n int)
+	bytes() []byte
+}
+
+func encode(w writer, value any) error {
+	switch v := value.(type) {
+	case nil:
+		w.null()
+	case bool:
+		w.boolean(v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			w.integer(i)
+			return nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return err
+		}
+		w.float(f)
+	case string:
+		w.text(v)
+	case []any:
+		w.array(len(v))
+		for _, element := range v {
+			if err := encode(w, element); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+
+		w.object(len(v))
+		for _, key := range keys {
+			w.text(key)
+			if err := encode(w, v[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return errors.New("unsupported JSON value")
+	}
+	return nil
+}
+
+// messagePack writes MessagePack, see https://github.com/msgpack/msgpack/blob/master/spec.md.
+type messagePack struct {
+	buf []byte
+}
+
+func (m *messagePack) null() { m.buf = append(m.buf, 0xc0) }
+
+func (m *messagePack) boolean(v bool) {
+	if v {
+		m.buf = append(m.buf, 0xc3)
+	} else {
+		m.buf = append(m.buf, 0xc2)
+	}
+}
+
+func (m *messagePack) integer(v int64) {
+	switch {
+	case v >= -32 && v < 128:
+		// Positive and negative fixint
+		m.buf = append(m.buf, byte(v))
+	case v >= 0:
+		m.unsigned(0xcc, uint64(v))
+	case v >= math.MinInt8:
+		m.buf = append(m.buf, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		m.buf = binary.BigEndian.AppendUint16(append(m.buf, 0xd1), uint16(v))
+	case v >= math.MinInt32:
+		m.buf = binary.BigEndian.AppendUint32(append(m.buf, 0xd2), uint32(v))
+	default:
+		m.buf = binary.BigEndian.AppendUint64(append(m.buf, 0xd3), uint64(v))
+	}
+}
+
+func (m *messagePack) float(v float64) {
+	m.buf = binary.BigEndian.AppendUint64(append(m.buf, 0xcb), math.Float64bits(v))
+}
+
+func (m *messagePack) text(v string) {
+	switch {
+	case len(v) < 32:
+		m.buf = append(m.buf, 0xa0|byte(len(v)))
+	case len(v) <= math.MaxUint8:
+		m.buf = append(m.buf, 0xd9, byte(len(v)))
+	default:
+		m.sized(0xda, uint64(len(v)))
+	}
+	m.buf = append(m.buf, v...)
+}
+
+func (m *messagePack) array(n int) {
+	if n < 16 {
+		m.buf = append(m.buf, 0x90|byte(n))
+	} else {
+		m.sized(0xdc, uint64(n))
+	}
+}
+
+func (m *messagePack) object(n int) {
+	if n < 16 {
+		m.buf = append(m.buf, 0x80|byte(n))
+	} else {
+		m.sized(0xde, uint64(n))
+	}
+}
+
+func (m *messagePack) bytes() []byte { return m.buf }
+
+// unsigned writes n after the first of four consecutive type bytes for 8, 16, 32 and 64 bit
+// values, choosing the smallest that fits.
+func (m *messagePack) unsigned(first byte, n uint64) {
+	switch {
+	case n <= math.MaxUint8:
+		m.buf = append(m.buf, first, byte(n))
+	case n <= math.MaxUint16:
+		m.buf = binary.BigEndian.AppendUint16(append(m.buf, first+1), uint16(n))
+	case n <= math.MaxUint32:
+		m.buf = binary.BigEndian.AppendUint32(append(m.buf, first+2), uint32(n))
+	default:
+		m.buf = binary.BigEndian.AppendUint64(append(m.buf, first+3), n)
+	}
+}
+
+// sized writes the length of a string, array or map after the first of two type bytes for 16 and 32 bit
+// lengths.
+func (m *messagePack) sized(first byte, n uint64) {
+	if n <= math.MaxUint16 {
+		m.buf = binary.BigEndian.AppendUint16(append(m.buf, first), uint16(n))
+	} else {
+		m.buf = binary.BigEndian.AppendUint32(append(m.buf, first+1), uint32(n))
+	}
+}
+
+// CBOR major types.
+const (
+	cborUnsigned = 0
+	cborNegative = 1
+	cborText     = 3
+	cborArray    = 4
+	cborMap      = 5
+)
+
+// cbor writes CBOR, see RFC 8949.
+type cbor struct {
+	buf []byte
+}
+
+func (c *cbor) null() { c.buf = append(c.buf, 0xf6) }
+
+func (c *cbor) boolean(v bool) {
+	if v {
+		c.buf = append(c.buf, 0xf5)
+	} else {
+		c.buf = append(c.buf, 0xf4)
+	}
+}
+
+func (c *cbor) integer(v int64) {
+	if v >= 0 {
+		c.head(cborUnsigned, uint64(v))
+	} else {
+		c.head(cborNegative, uint64(-1-v))
+	}
+}
+
+func (c *cbor) float(v float64) {
+	c.buf = binary.BigEndian.AppendUint64(append(c.buf, 0xfb), math.Float64bits(v))
+}
+
+func (c *cbor) text(v string) {
+	c.head(cborText, uint64(len(v)))
+	c.buf = append(c.buf, v...)
+}
+
+func (c *cbor) array(n int) { c.head(cborArray, uint64(n)) }
+
+func (c *cbor) object(n int) { c.head(cborMap, uint64(n)) }
+
+func (c *cbor) bytes() []byte { return c.buf }
+
+// head writes the major type with its argument in the shortest form.
+func (c *cbor) head(major byte, n uint64) {
+	major <<= 5
+	switch {
+	case n < 24:
+		c.buf = append(c.buf, major|byte(n))
+	case n <= math.MaxUint8:
+		c.buf = append(c.buf, major|24, byte(n))
+	case n <= math.MaxUint16:
+		c.buf = binary.BigEndian.AppendUint16(append(c.buf, major|25), uint16(n))
+	case n <= math.MaxUint32:
+		c.buf = binary.BigEndian.AppendUint32(append(c.buf, major|26), uint32(n))
+	default:
+		c.buf = binary.BigEndian.AppendUint64(append(c.buf, major|27), n)
+	}
+}
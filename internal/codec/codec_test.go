@@ -0,0 +1,97 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package codec
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept   string
+		expected string
+	}{
+		{"", ""},
+		{"application/json", ""},
+		{"*/*", ""},
+		{"application/msgpack", MediaTypeMessagePack},
+		{"application/x-msgpack", MediaTypeMessagePack},
+		{"application/cbor, application/json;q=0.5", MediaTypeCBOR},
+		{"application/json, application/msgpack;q=0.9", ""},
+		{"application/msgpack;q=0.5, application/cbor;q=0.8", MediaTypeCBOR},
+		{"application/msgpack;q=0", ""},
+	}
+
+	for _, test := range tests {
+		if got := Negotiate(test.accept); got != test.expected {
+			t.Errorf("Negotiate(%q) = %q, expected %q", test.accept, got, test.expected)
+		}
+	}
+}
+
+func TestTag(t *testing.T) {
+	if tag := Tag(`"12-345"`, MediaTypeCBOR); tag != `"12-345-cbor"` {
+		t.Errorf("Expected the encoding in the tag, got %s", tag)
+	}
+	if tag := Tag(`W/"12-345"`, "application/x-msgpack"); tag != `W/"12-345-msgpack"` {
+		t.Errorf("Expected weak tags to stay weak, got %s", tag)
+	}
+	if tag := Tag(`"12-345"`, "application/json"); tag != `"12-345"` {
+		t.Errorf("Expected JSON tags to stay the same, got %s", tag)
+	}
+
+	tests := []struct {
+		ifNoneMatch string
+		expected    string
+	}{
+		{`"12-345-cbor"`, `"12-345"`},
+		{`W/"12-345-cbor", "6-789-cbor"`, `W/"12-345", "6-789"`},
+		{`"12-345"`, ""},
+		{`"12-345-msgpack"`, ""},
+	}
+	for _, test := range tests {
+		if got := Untag(test.ifNoneMatch, MediaTypeCBOR); got != test.expected {
+			t.Errorf("Untag(%q) = %q, expected %q", test.ifNoneMatch, got, test.expected)
+		}
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	document := `{"b":[-1,-200,300,1.5,null,true],"a":"x"}`
+	tests := []struct {
+		mediaType string
+		expected  string
+	}{
+		{MediaTypeMessagePack, "82" + "a161" + "a178" + "a162" + "96" + "ff" + "d1ff38" + "cd012c" + "cb3ff8000000000000" + "c0" + "c3"},
+		{MediaTypeCBOR, "a2" + "6161" + "6178" + "6162" + "86" + "20" + "38c7" + "19012c" + "fb3ff8000000000000" + "f6" + "f5"},
+	}
+
+	for _, test := range tests {
+		encoded, err := FromJSON(test.mediaType, []byte(document))
+		if err != nil {
+			t.Fatalf("Failed to encode %s: %v", test.mediaType, err)
+		}
+		if got := hex.EncodeToString(encoded); got != test.expected {
+			t.Errorf("Unexpected %s encoding:\ngot      %s\nexpected %s", test.mediaType, got, test.expected)
+		}
+	}
+}
+
+func TestFromJSON_LongValues(t *testing.T) {
+	long := strings.Repeat("x", 300)
+	encoded, err := FromJSON(MediaTypeMessagePack, []byte(`["`+long+`"]`))
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	if !bytes.HasPrefix(encoded, []byte{0x91, 0xda, 0x01, 0x2c}) || len(encoded) != 4+300 {
+		t.Errorf("Expected a str16 of 300 bytes, got % x", encoded[:4])
+	}
+
+	if _, err := FromJSON("application/xml", []byte(`{}`)); err == nil {
+		t.Error("Expected an error for an unsupported media type")
+	}
+}
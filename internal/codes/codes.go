@@ -0,0 +1,68 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package codes generates random codes and tokens from crypto/rand, such as login codes and
+// secret tokens, without bias towards any character or value.
+package codes
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+// Alphabets of generated codes.
+const (
+	Numeric = "0123456789"
+	// Alphanumeric leaves out 0, 1, I and O, which are easily confused when typed from an email.
+	Alphanumeric = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+)
+
+// Alphabets maps the names of the alphabets, as used in the server settings, to their characters.
+var Alphabets = map[string]string{
+	"numeric":      Numeric,
+	"alphanumeric": Alphanumeric,
+}
+
+// Generate returns a code of length characters, each drawn uniformly and independently from
+// the alphabet.
+func Generate(length int, alphabet string) string {
+	limit := big.NewInt(int64(len(alphabet)))
+	code := make([]byte, length)
+	for i := range code {
+		index, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			// crypto/rand does not fail on supported platforms; a predictable code would be worse
+			panic("codes: " + err.Error())
+		}
+		code[i] = alphabet[index.Int64()]
+	}
+	return string(code)
+}
+
+// Digits returns a code of n decimal digits, uniformly distributed over all n-digit codes
+// including those with leading zeros.
+func Digits(n int) string {
+	return Generate(n, Numeric)
+}
+
+// Normalize returns a code as typed by a user in the form it was generated in: uppercase and
+// without the spaces and dashes users add for readability, e.g. "ab12-cd34 " becomes "AB12CD34".
+func Normalize(code string) string {
+	return strings.ToUpper(strings.Map(func(r rune) rune {
+		if r == '-' || unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, code))
+}
+
+// Hex returns the lowercase hex encoding of n random bytes.
+func Hex(n int) string {
+	bytes := make([]byte, n)
+	// crypto/rand.Read never returns an error, it crashes the program instead
+	_, _ = rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
@@ -0,0 +1,105 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package codes
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestDigits(t *testing.T) {
+	for n := 1; n <= 20; n++ {
+		code := Digits(n)
+		if len(code) != n {
+			t.Errorf("Expected %d digits, got %q", n, code)
+		}
+		for _, char := range code {
+			if char < '0' || char > '9' {
+				t.Errorf("Expected only digits, got %q", code)
+			}
+		}
+	}
+}
+
+// TestDigits_Uniform checks that every digit is about equally likely at every position. Taking
+// the first six digits of a larger random number, as codes were once generated, fails this for
+// the leading digit.
+func TestDigits_Uniform(t *testing.T) {
+	const samples = 20000
+	var counts [6][10]int
+	for range samples {
+		for position, char := range Digits(6) {
+			counts[position][char-'0']++
+		}
+	}
+
+	// The expected count is 2000 with a standard deviation of about 42
+	for position, digits := range counts {
+		for digit, count := range digits {
+			if count < 1800 || count > 2200 {
+				t.Errorf("Digit %d at position %d occurred %d times, expected about %d", digit, position, count, samples/10)
+			}
+		}
+	}
+}
+
+func TestGenerate_Alphanumeric(t *testing.T) {
+	const samples = 32000
+	counts := map[rune]int{}
+	for range samples / 8 {
+		for _, char := range Generate(8, Alphanumeric) {
+			counts[char]++
+		}
+	}
+
+	if len(counts) != len(Alphanumeric) {
+		t.Errorf("Expected all %d characters to occur, got %d", len(Alphanumeric), len(counts))
+	}
+	// The expected count is 1000 with a standard deviation of about 31
+	for char, count := range counts {
+		if !strings.ContainsRune(Alphanumeric, char) {
+			t.Errorf("Unexpected character %q", char)
+		}
+		if count < 850 || count > 1150 {
+			t.Errorf("Character %q occurred %d times, expected about %d", char, count, samples/len(Alphanumeric))
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	for _, input := range []string{"AB12CD34", "ab12cd34", " ab12-cd34\n", "AB 12 CD 34", "ab-12-cd-34"} {
+		if code := Normalize(input); code != "AB12CD34" {
+			t.Errorf("Normalize(%q) = %q, expected AB12CD34", input, code)
+		}
+	}
+}
+
+func TestHex(t *testing.T) {
+	token := Hex(24)
+	decoded, err := hex.DecodeString(token)
+	if err != nil || len(decoded) != 24 {
+		t.Errorf("Expected 24 hex-encoded bytes, got %q", token)
+	}
+	if Hex(24) == token {
+		t.Error("Expected different tokens")
+	}
+}
+
+func FuzzDigits(f *testing.F) {
+	for _, n := range []uint8{0, 1, 6, 19, 40} {
+		f.Add(n)
+	}
+	f.Fuzz(func(t *testing.T, n uint8) {
+		code := Digits(int(n))
+		if len(code) != int(n) {
+			t.Fatalf("Expected %d digits, got %q", n, code)
+		}
+		for _, char := range code {
+			if char < '0' || char > '9' {
+				t.Fatalf("Expected only digits, got %q", code)
+			}
+		}
+	})
+}
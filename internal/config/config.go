@@ -6,8 +6,14 @@
 package config
 
 import (
+	"errors"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/currency"
 )
 
 // Config holds all configuration values loaded from environment variables.
@@ -19,7 +25,102 @@ type Config struct {
 	SMTPFrom   string
 	JWTSecret  []byte
 	ServerPort string
+	DBDriver   string
 	DBPath     string
+	DBDSN      string
+
+	DBReplicaDSN    string
+	DBReplicaPin    time.Duration
+	DBReplicaMaxLag time.Duration
+
+	MagicLinkTTL time.Duration
+	JWTTTL       time.Duration
+	CodeLength   int
+	LoginLinkURL string
+
+	InMemory           bool
+	InMemoryAdminEmail string
+
+	DBCheckOnStartup    bool
+	DBRepairOnStartup   bool
+	DBWAL               bool
+	DBEncryptionKey     string
+	PIIEncryptionKey    string
+	PIIEncryptionNewKey string
+
+	ItemTitleCase      bool
+	ItemAutoCategorize bool
+	CatalogDataDir     string
+
+	TripReminderLeadHours int
+
+	KioskRefreshSeconds int
+
+	MatrixHomeserver  string
+	MatrixAccessToken string
+	MatrixRoomID      string
+
+	SlackSigningSecret string
+	DiscordPublicKey   string
+
+	FederationKey string
+
+	UpdateCheck bool
+
+	NutritionLookup  bool
+	OpenFoodFactsURL string
+
+	DefaultCurrency string
+
+	LogLevel       string
+	LogFormat      string
+	LogOutput      string
+	LogFile        string
+	LogMaxSizeMB   int
+	LogMaxBackups  int
+	LogAnonymizeIP bool
+
+	StatusRateLimit    int
+	APIKeyRateLimit    int
+	LoginCodeLimit     int
+	VerifyAttemptLimit int
+
+	MetricsEnabled bool
+	MetricsToken   string
+	DBSlowQueryMS  int
+	DevMode        bool
+
+	SnapshotDir           string
+	SnapshotHook          string
+	SnapshotIntervalHours int
+	SnapshotKeep          int
+	SnapshotStorage       bool
+
+	HookItemCreated       string
+	HookLogin             string
+	HookInvitationCreated string
+	HookTimeout           time.Duration
+
+	TermsVersion            string
+	TermsURL                string
+	PrivacyVersion          string
+	PrivacyURL              string
+	RequirePolicyAcceptance bool
+
+	AttachmentsEnabled  bool
+	AttachmentMaxSizeMB int
+	AttachmentQuotaMB   int
+
+	ExportStorage bool
+
+	StorageBackend string
+	StorageDir     string
+	S3Endpoint     string
+	S3Region       string
+	S3Bucket       string
+	S3AccessKey    string
+	S3SecretKey    string
+	S3PathStyle    bool
 }
 
 // Load reads configuration from environment variables and returns a Config instance.
@@ -31,7 +132,102 @@ func Load() *Config {
 		SMTPPass:   os.Getenv("SMTP_PASS"),
 		SMTPFrom:   os.Getenv("SMTP_FROM"),
 		ServerPort: getEnvOrDefault("PORT", ":3000"),
+		DBDriver:   getEnvOrDefault("DB_DRIVER", "sqlite"),
 		DBPath:     getEnvOrDefault("DB_PATH", "shopping.db"),
+		DBDSN:      getEnvOrFile("DB_DSN"),
+
+		DBReplicaDSN:    getEnvOrFile("DB_REPLICA_DSN"),
+		DBReplicaPin:    getEnvAsDurationOrDefault("DB_REPLICA_PIN", 5*time.Second),
+		DBReplicaMaxLag: getEnvAsDurationOrDefault("DB_REPLICA_MAX_LAG", 30*time.Second),
+
+		MagicLinkTTL: getEnvAsDurationOrDefault("MAGIC_LINK_TTL", 15*time.Minute),
+		JWTTTL:       getEnvAsDurationOrDefault("JWT_TTL", 30*24*time.Hour),
+		CodeLength:   getEnvAsIntOrDefault("CODE_LENGTH", 6),
+		LoginLinkURL: os.Getenv("LOGIN_LINK_URL"),
+
+		InMemory:           getEnvAsBoolOrDefault("IN_MEMORY", false),
+		InMemoryAdminEmail: getEnvOrDefault("IN_MEMORY_ADMIN_EMAIL", "admin@example.com"),
+
+		DBCheckOnStartup:    getEnvAsBoolOrDefault("DB_CHECK_ON_STARTUP", true),
+		DBRepairOnStartup:   getEnvAsBoolOrDefault("DB_REPAIR_ON_STARTUP", false),
+		DBWAL:               getEnvAsBoolOrDefault("DB_WAL", false),
+		DBEncryptionKey:     getEnvOrFile("DB_ENCRYPTION_KEY"),
+		PIIEncryptionKey:    getEnvOrFile("PII_ENCRYPTION_KEY"),
+		PIIEncryptionNewKey: getEnvOrFile("PII_ENCRYPTION_NEW_KEY"),
+
+		ItemTitleCase:      getEnvAsBoolOrDefault("ITEM_TITLE_CASE", true),
+		ItemAutoCategorize: getEnvAsBoolOrDefault("ITEM_AUTO_CATEGORIZE", true),
+		CatalogDataDir:     os.Getenv("CATALOG_DATA_DIR"),
+
+		TripReminderLeadHours: getEnvAsIntOrDefault("TRIP_REMINDER_LEAD_HOURS", 24),
+
+		KioskRefreshSeconds: getEnvAsIntOrDefault("KIOSK_REFRESH_SECONDS", 60),
+
+		MatrixHomeserver:  getEnvOrDefault("MATRIX_HOMESERVER", ""),
+		MatrixAccessToken: getEnvOrFile("MATRIX_ACCESS_TOKEN"),
+		MatrixRoomID:      getEnvOrDefault("MATRIX_ROOM_ID", ""),
+
+		SlackSigningSecret: getEnvOrFile("SLACK_SIGNING_SECRET"),
+		DiscordPublicKey:   getEnvOrDefault("DISCORD_PUBLIC_KEY", ""),
+
+		FederationKey: getEnvOrFile("FEDERATION_KEY"),
+
+		UpdateCheck: getEnvAsBoolOrDefault("UPDATE_CHECK", false),
+
+		NutritionLookup:  getEnvAsBoolOrDefault("NUTRITION_LOOKUP", false),
+		OpenFoodFactsURL: getEnvOrDefault("OPEN_FOOD_FACTS_URL", "https://world.openfoodfacts.org"),
+
+		DefaultCurrency: strings.ToUpper(getEnvOrDefault("DEFAULT_CURRENCY", currency.Default)),
+
+		LogLevel:       getEnvOrDefault("LOG_LEVEL", "info"),
+		LogFormat:      getEnvOrDefault("LOG_FORMAT", "text"),
+		LogOutput:      getEnvOrDefault("LOG_OUTPUT", "stdout"),
+		LogFile:        getEnvOrDefault("LOG_FILE", "shopping-list-server.log"),
+		LogMaxSizeMB:   getEnvAsIntOrDefault("LOG_MAX_SIZE_MB", 10),
+		LogMaxBackups:  getEnvAsIntOrDefault("LOG_MAX_BACKUPS", 5),
+		LogAnonymizeIP: getEnvAsBoolOrDefault("LOG_ANONYMIZE_IP", false),
+
+		StatusRateLimit:    getEnvAsIntOrDefault("STATUS_RATE_LIMIT", 30),
+		APIKeyRateLimit:    getEnvAsIntOrDefault("API_KEY_RATE_LIMIT", 60),
+		LoginCodeLimit:     getEnvAsIntOrDefault("LOGIN_CODE_LIMIT", 5),
+		VerifyAttemptLimit: getEnvAsIntOrDefault("VERIFY_ATTEMPT_LIMIT", 10),
+
+		MetricsEnabled: getEnvAsBoolOrDefault("METRICS_ENABLED", false),
+		MetricsToken:   getEnvOrFile("METRICS_TOKEN"),
+		DBSlowQueryMS:  getEnvAsIntOrDefault("DB_SLOW_QUERY_MS", 200),
+		DevMode:        getEnvAsBoolOrDefault("DEV_MODE", false),
+
+		SnapshotDir:           getEnvOrDefault("SNAPSHOT_DIR", "snapshots"),
+		SnapshotHook:          os.Getenv("SNAPSHOT_HOOK"),
+		SnapshotIntervalHours: getEnvAsIntOrDefault("SNAPSHOT_INTERVAL_HOURS", 0),
+		SnapshotKeep:          getEnvAsIntOrDefault("SNAPSHOT_KEEP", 7),
+		SnapshotStorage:       getEnvAsBoolOrDefault("SNAPSHOT_STORAGE", false),
+
+		HookItemCreated:       os.Getenv("HOOK_ITEM_CREATED"),
+		HookLogin:             os.Getenv("HOOK_LOGIN"),
+		HookInvitationCreated: os.Getenv("HOOK_INVITATION_CREATED"),
+		HookTimeout:           getEnvAsDurationOrDefault("HOOK_TIMEOUT", 10*time.Second),
+
+		TermsVersion:            os.Getenv("TERMS_VERSION"),
+		TermsURL:                os.Getenv("TERMS_URL"),
+		PrivacyVersion:          os.Getenv("PRIVACY_VERSION"),
+		PrivacyURL:              os.Getenv("PRIVACY_URL"),
+		RequirePolicyAcceptance: getEnvAsBoolOrDefault("REQUIRE_POLICY_ACCEPTANCE", false),
+
+		AttachmentsEnabled:  getEnvAsBoolOrDefault("ATTACHMENTS_ENABLED", false),
+		AttachmentMaxSizeMB: getEnvAsIntOrDefault("ATTACHMENT_MAX_SIZE_MB", 20),
+		AttachmentQuotaMB:   getEnvAsIntOrDefault("ATTACHMENT_QUOTA_MB", 100),
+
+		ExportStorage: getEnvAsBoolOrDefault("EXPORT_STORAGE", false),
+
+		StorageBackend: getEnvOrDefault("STORAGE_BACKEND", "local"),
+		StorageDir:     getEnvOrDefault("STORAGE_DIR", "data"),
+		S3Endpoint:     os.Getenv("S3_ENDPOINT"),
+		S3Region:       getEnvOrDefault("S3_REGION", "us-east-1"),
+		S3Bucket:       os.Getenv("S3_BUCKET"),
+		S3AccessKey:    os.Getenv("S3_ACCESS_KEY"),
+		S3SecretKey:    os.Getenv("S3_SECRET_KEY"),
+		S3PathStyle:    getEnvAsBoolOrDefault("S3_PATH_STYLE", true),
 	}
 
 	// JWT Secret
@@ -44,6 +240,68 @@ func Load() *Config {
 	return cfg
 }
 
+// Validate checks that the login settings are within sane ranges: codes that expire too
+// quickly cannot be typed in time, long-lived ones or short codes are easier to guess. Hooks
+// must not pile up in the background, so their timeout is capped as well. Prices cannot be
+// formatted in an unknown default currency, and login links need an absolute URL to open.
+// PostgreSQL needs a connection string and cannot use the SQLite-only encryption and WAL, and
+// read replicas are only supported with PostgreSQL.
+func (c *Config) Validate() error {
+	if c.MagicLinkTTL < time.Minute || c.MagicLinkTTL > 24*time.Hour {
+		return errors.New("MAGIC_LINK_TTL must be between 1m and 24h")
+	}
+	if c.JWTTTL < time.Hour || c.JWTTTL > 365*24*time.Hour {
+		return errors.New("JWT_TTL must be between 1h and 8760h")
+	}
+	if c.CodeLength < 6 || c.CodeLength > 12 {
+		return errors.New("CODE_LENGTH must be between 6 and 12")
+	}
+	if c.HookTimeout < time.Second || c.HookTimeout > 5*time.Minute {
+		return errors.New("HOOK_TIMEOUT must be between 1s and 5m")
+	}
+	if !currency.Valid(c.DefaultCurrency) {
+		return errors.New("DEFAULT_CURRENCY must be an ISO 4217 code like EUR")
+	}
+	switch c.DBDriver {
+	case "sqlite":
+	case "postgres":
+		if c.DBDSN == "" {
+			return errors.New("DB_DSN is required with DB_DRIVER=postgres")
+		}
+		if c.DBEncryptionKey != "" || c.DBWAL {
+			return errors.New("DB_ENCRYPTION_KEY and DB_WAL only apply to SQLite")
+		}
+	default:
+		return errors.New("DB_DRIVER must be sqlite or postgres")
+	}
+	if c.DBReplicaDSN != "" {
+		if c.DBDriver != "postgres" {
+			return errors.New("DB_REPLICA_DSN requires DB_DRIVER=postgres")
+		}
+		if c.DBReplicaPin < 0 || c.DBReplicaPin > time.Hour {
+			return errors.New("DB_REPLICA_PIN must be between 0s and 1h")
+		}
+		if c.DBReplicaMaxLag < 0 {
+			return errors.New("DB_REPLICA_MAX_LAG must not be negative")
+		}
+	}
+	if c.LoginLinkURL != "" {
+		if link, err := url.Parse(c.LoginLinkURL); err != nil || !link.IsAbs() || link.Host == "" {
+			return errors.New("LOGIN_LINK_URL must be an absolute URL like https://app.example/login")
+		}
+	}
+	return nil
+}
+
+// DatabaseDSN returns what the database driver connects to: the path of the database file for
+// SQLite, the connection string for PostgreSQL.
+func (c *Config) DatabaseDSN() string {
+	if c.DBDriver == "sqlite" {
+		return c.DBPath
+	}
+	return c.DBDSN
+}
+
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -51,6 +309,20 @@ func getEnvOrDefault(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvOrFile returns the value of the environment variable key or, if unset, the trimmed
+// content of the file named by key_FILE, as used for Docker secrets.
+func getEnvOrFile(key string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	if path := os.Getenv(key + "_FILE"); path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			return strings.TrimSpace(string(data))
+		}
+	}
+	return ""
+}
+
 func getEnvAsIntOrDefault(key string, defaultValue int) int {
 	valueStr := os.Getenv(key)
 	if value, err := strconv.Atoi(valueStr); err == nil {
@@ -58,3 +330,20 @@ func getEnvAsIntOrDefault(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvAsBoolOrDefault(key string, defaultValue bool) bool {
+	valueStr := os.Getenv(key)
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvAsDurationOrDefault parses the environment variable key as a duration such as "15m"
+// or "720h".
+func getEnvAsDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
@@ -5,7 +5,9 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestLoad(t *testing.T) {
@@ -284,3 +286,92 @@ func TestPortFormatting(t *testing.T) {
 		})
 	}
 }
+
+func TestGetEnvOrFile(t *testing.T) {
+	defer func() {
+		_ = os.Unsetenv("TEST_SECRET")
+		_ = os.Unsetenv("TEST_SECRET_FILE")
+	}()
+
+	t.Run("from secret file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "secret")
+		if err := os.WriteFile(path, []byte("file-secret\n"), 0o600); err != nil {
+			t.Fatalf("Failed to write secret file: %v", err)
+		}
+		_ = os.Setenv("TEST_SECRET_FILE", path)
+
+		if value := getEnvOrFile("TEST_SECRET"); value != "file-secret" {
+			t.Errorf("Expected 'file-secret', got '%s'", value)
+		}
+	})
+
+	t.Run("environment variable takes precedence", func(t *testing.T) {
+		_ = os.Setenv("TEST_SECRET", "env-secret")
+
+		if value := getEnvOrFile("TEST_SECRET"); value != "env-secret" {
+			t.Errorf("Expected 'env-secret', got '%s'", value)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_ = os.Unsetenv("TEST_SECRET")
+		_ = os.Setenv("TEST_SECRET_FILE", filepath.Join(t.TempDir(), "missing"))
+
+		if value := getEnvOrFile("TEST_SECRET"); value != "" {
+			t.Errorf("Expected empty value, got '%s'", value)
+		}
+	})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Setenv("MAGIC_LINK_TTL", "10m")
+	t.Setenv("JWT_TTL", "168h")
+	t.Setenv("CODE_LENGTH", "8")
+
+	cfg := Load()
+	if cfg.MagicLinkTTL != 10*time.Minute || cfg.JWTTTL != 7*24*time.Hour || cfg.CodeLength != 8 {
+		t.Fatalf("Unexpected login settings %v, %v, %d", cfg.MagicLinkTTL, cfg.JWTTTL, cfg.CodeLength)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected valid configuration, got %v", err)
+	}
+
+	for name, change := range map[string]func(*Config){
+		"code expires too quickly": func(c *Config) { c.MagicLinkTTL = 30 * time.Second },
+		"code lives too long":      func(c *Config) { c.MagicLinkTTL = 48 * time.Hour },
+		"token lives too long":     func(c *Config) { c.JWTTTL = 2 * 365 * 24 * time.Hour },
+		"code too short":           func(c *Config) { c.CodeLength = 4 },
+		"code too long":            func(c *Config) { c.CodeLength = 20 },
+		"hook timeout too long":    func(c *Config) { c.HookTimeout = time.Hour },
+		"unknown currency":         func(c *Config) { c.DefaultCurrency = "EURO" },
+		"relative login link":      func(c *Config) { c.LoginLinkURL = "/login" },
+		"unknown database driver":  func(c *Config) { c.DBDriver = "mysql" },
+		"postgres without dsn":     func(c *Config) { c.DBDriver = "postgres" },
+		"postgres with wal":        func(c *Config) { c.DBDriver, c.DBDSN, c.DBWAL = "postgres", "host=db", true },
+		"replica with sqlite":      func(c *Config) { c.DBReplicaDSN = "host=replica" },
+		"negative replica pin": func(c *Config) {
+			c.DBDriver, c.DBDSN, c.DBReplicaDSN, c.DBReplicaPin = "postgres", "host=db", "host=replica", -time.Second
+		},
+	} {
+		invalid := *cfg
+		change(&invalid)
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	postgres := *cfg
+	postgres.DBDriver, postgres.DBDSN = "postgres", "postgres://shopping@db/shopping"
+	if err := postgres.Validate(); err != nil {
+		t.Errorf("Expected valid PostgreSQL configuration, got %v", err)
+	}
+	if postgres.DatabaseDSN() != postgres.DBDSN || cfg.DatabaseDSN() != cfg.DBPath {
+		t.Errorf("Unexpected data source names %q, %q", postgres.DatabaseDSN(), cfg.DatabaseDSN())
+	}
+
+	// Unparsable durations fall back to the defaults
+	t.Setenv("MAGIC_LINK_TTL", "fifteen minutes")
+	if cfg := Load(); cfg.MagicLinkTTL != 15*time.Minute {
+		t.Errorf("Expected the default lifetime, got %v", cfg.MagicLinkTTL)
+	}
+}
@@ -0,0 +1,133 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package currency validates ISO 4217 currency codes and formats amounts of money for the
+// language of a client, e.g. "1.234,50 €" for de and "€1,234.50" for en.
+package currency
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/oliverandrich/shopping-list-server/internal/quantity"
+)
+
+// Default is the currency of prices unless the server or a list is configured otherwise.
+const Default = "EUR"
+
+// ErrInvalidCode is returned for codes that are not active ISO 4217 currency codes.
+var ErrInvalidCode = errors.New("currency must be an ISO 4217 code like EUR")
+
+// codes are the active ISO 4217 currency codes with the number of their minor unit digits.
+// Funds and precious metals are left out, they are not what groceries are paid with.
+var codes = map[string]int{
+	"AED": 2, "AFN": 2, "ALL": 2, "AMD": 2, "ANG": 2, "AOA": 2, "ARS": 2, "AUD": 2, "AWG": 2,
+	"AZN": 2, "BAM": 2, "BBD": 2, "BDT": 2, "BGN": 2, "BHD": 3, "BIF": 0, "BMD": 2, "BND": 2,
+	"BOB": 2, "BRL": 2, "BSD": 2, "BTN": 2, "BWP": 2, "BYN": 2, "BZD": 2, "CAD": 2, "CDF": 2,
+	"CHF": 2, "CLP": 0, "CNY": 2, "COP": 2, "CRC": 2, "CUP": 2, "CVE": 2, "CZK": 2, "DJF": 0,
+	"DKK": 2, "DOP": 2, "DZD": 2, "EGP": 2, "ERN": 2, "ETB": 2, "EUR": 2, "FJD": 2, "FKP": 2,
+	"GBP": 2, "GEL": 2, "GHS": 2, "GIP": 2, "GMD": 2, "GNF": 0, "GTQ": 2, "GYD": 2, "HKD": 2,
+	"HNL": 2, "HTG": 2, "HUF": 2, "IDR": 2, "ILS": 2, "INR": 2, "IQD": 3, "IRR": 2, "ISK": 0,
+	"JMD": 2, "JOD": 3, "JPY": 0, "KES": 2, "KGS": 2, "KHR": 2, "KMF": 0, "KPW": 2, "KRW": 0,
+	"KWD": 3, "KYD": 2, "KZT": 2, "LAK": 2, "LBP": 2, "LKR": 2, "LRD": 2, "LSL": 2, "LYD": 3,
+	"MAD": 2, "MDL": 2, "MGA": 2, "MKD": 2, "MMK": 2, "MNT": 2, "MOP": 2, "MRU": 2, "MUR": 2,
+	"MVR": 2, "MWK": 2, "MXN": 2, "MYR": 2, "MZN": 2, "NAD": 2, "NGN": 2, "NIO": 2, "NOK": 2,
+	"NPR": 2, "NZD": 2, "OMR": 3, "PAB": 2, "PEN": 2, "PGK": 2, "PHP": 2, "PKR": 2, "PLN": 2,
+	"PYG": 0, "QAR": 2, "RON": 2, "RSD": 2, "RUB": 2, "RWF": 0, "SAR": 2, "SBD": 2, "SCR": 2,
+	"SDG": 2, "SEK": 2, "SGD": 2, "SHP": 2, "SLE": 2, "SOS": 2, "SRD": 2, "SSP": 2, "STN": 2,
+	"SVC": 2, "SYP": 2, "SZL": 2, "THB": 2, "TJS": 2, "TMT": 2, "TND": 3, "TOP": 2, "TRY": 2,
+	"TTD": 2, "TWD": 2, "TZS": 2, "UAH": 2, "UGX": 0, "USD": 2, "UYU": 2, "UZS": 2, "VED": 2,
+	"VES": 2, "VND": 0, "VUV": 0, "WST": 2, "XAF": 0, "XCD": 2, "XCG": 2, "XOF": 0, "XPF": 0,
+	"YER": 2, "ZAR": 2, "ZMW": 2, "ZWG": 2,
+}
+
+// symbols are the signs of common currencies; other currencies are written with their code.
+var symbols = map[string]string{
+	"EUR": "€", "USD": "$", "GBP": "£", "JPY": "¥", "CNY": "¥", "INR": "₹", "KRW": "₩",
+	"ILS": "₪", "NGN": "₦", "PHP": "₱", "PLN": "zł", "RUB": "₽", "THB": "฿", "TRY": "₺",
+	"UAH": "₴", "VND": "₫",
+}
+
+// spaceGroupLanguages are the decimal comma languages that group thousands with a space
+// instead of a dot, a no-break space.
+var spaceGroupLanguages = map[string]bool{
+	"cs": true, "fi": true, "fr": true, "nb": true, "pl": true, "ru": true, "sv": true,
+}
+
+// Normalize returns a currency code in upper case, or ErrInvalidCode if it is not an active
+// ISO 4217 code.
+func Normalize(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if _, ok := codes[code]; !ok {
+		return "", ErrInvalidCode
+	}
+	return code, nil
+}
+
+// Valid reports whether code is an active ISO 4217 code, ignoring case.
+func Valid(code string) bool {
+	_, err := Normalize(code)
+	return err == nil
+}
+
+// Digits returns the number of minor unit digits of a currency, two for unknown ones.
+func Digits(code string) int {
+	if digits, ok := codes[strings.ToUpper(code)]; ok {
+		return digits
+	}
+	return 2
+}
+
+// Format formats an amount in a currency with the separators of the preferred language of an
+// Accept-Language header value. Languages writing decimals with a comma put the symbol after the
+// amount, others before it; currencies without symbol are written with their code.
+func Format(amount float64, code, acceptLanguage string) string {
+	code = strings.ToUpper(code)
+	digits := Digits(code)
+	formatted := strconv.FormatFloat(math.Abs(amount), 'f', digits, 64)
+	whole, fraction, _ := strings.Cut(formatted, ".")
+
+	decimal, group := ".", ","
+	if quantity.DecimalComma(acceptLanguage) {
+		decimal, group = ",", "."
+		if spaceGroupLanguages[quantity.PrimaryLanguage(acceptLanguage)] {
+			group = "\u00a0"
+		}
+	}
+	number := groupThousands(whole, group)
+	if fraction != "" {
+		number += decimal + fraction
+	}
+	// Amounts rounding to zero are not negative
+	sign := ""
+	if amount < 0 && strings.Trim(number, "0.,\u00a0") != "" {
+		sign = "-"
+	}
+
+	symbol, ok := symbols[code]
+	switch {
+	case quantity.DecimalComma(acceptLanguage):
+		if !ok {
+			symbol = code
+		}
+		return sign + number + " " + symbol
+	case ok:
+		return sign + symbol + number
+	default:
+		return sign + code + " " + number
+	}
+}
+
+// groupThousands inserts the separator between groups of three digits.
+func groupThousands(digits, separator string) string {
+	var b strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteString(separator)
+		}
+		b.WriteRune(digit)
+	}
+	return b.String()
+}
@@ -0,0 +1,52 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package currency
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	for _, code := range []string{"EUR", " usd ", "chf", "JPY"} {
+		if _, err := Normalize(code); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", code, err)
+		}
+	}
+	if code, _ := Normalize(" gbp"); code != "GBP" {
+		t.Errorf("Expected GBP, got %q", code)
+	}
+	for _, code := range []string{"", "EURO", "XXX", "XAU", "€"} {
+		if _, err := Normalize(code); !errors.Is(err, ErrInvalidCode) {
+			t.Errorf("Expected %q to be invalid, got %v", code, err)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		amount         float64
+		code           string
+		acceptLanguage string
+		expected       string
+	}{
+		{1234.5, "EUR", "de-DE,de;q=0.9", "1.234,50 €"},
+		{1234.5, "EUR", "en-US", "€1,234.50"},
+		{1234.5, "EUR", "", "€1,234.50"},
+		{1234567.891, "USD", "en", "$1,234,567.89"},
+		{1234.5, "EUR", "fr", "1\u00a0234,50 €"},
+		{12.5, "CHF", "en", "CHF 12.50"},
+		{12.5, "CHF", "de-CH", "12,50 CHF"},
+		{1500, "JPY", "en", "¥1,500"},
+		{1.2346, "KWD", "en", "KWD 1.235"},
+		{-3, "gbp", "en", "-£3.00"},
+		{-0.001, "EUR", "de", "0,00 €"},
+		{0.99, "EUR", "de", "0,99 €"},
+	}
+	for _, tc := range tests {
+		if got := Format(tc.amount, tc.code, tc.acceptLanguage); got != tc.expected {
+			t.Errorf("Format(%v, %q, %q) = %q; want %q", tc.amount, tc.code, tc.acceptLanguage, got, tc.expected)
+		}
+	}
+}
@@ -1,15 +1,50 @@
 // Licensed under the EUPL-1.2-or-later
 // Copyright (C) 2025 Oliver Andrich
 
-// Package db provides database initialization and connection management using GORM and SQLite.
+// Package db provides database initialization and connection management using GORM and SQLite,
+// optionally encrypted at rest with SQLCipher, or PostgreSQL in binaries built with -tags
+// postgres.
 package db
 
 import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mattn/go-sqlite3"
 	"github.com/oliverandrich/shopping-list-server/internal/models"
+	_ "github.com/oliverandrich/shopping-list-server/internal/pii" // registers the "pii" serializer
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
+// ErrSQLCipherUnavailable is returned when an encryption key is configured but the binary is
+// linked against plain SQLite, which would silently store the data unencrypted.
+var ErrSQLCipherUnavailable = errors.New("database encryption requires a binary built with SQLCipher")
+
+// Database drivers supported by Connect.
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// Connect connects to the database of the driver and performs auto-migration of all models. For
+// SQLite, dsn is the path of the database file, opened with key like InitEncrypted. For
+// PostgreSQL, it is a URL or a keyword/value connection string such as
+// "host=db user=shopping dbname=shopping".
+func Connect(driver, dsn, key string) (*gorm.DB, error) {
+	switch driver {
+	case DriverSQLite:
+		return InitEncrypted(dsn, key)
+	case DriverPostgres:
+		return openBackend(DriverPostgres, dsn)
+	}
+	return nil, fmt.Errorf("unsupported database driver %q, use sqlite or postgres", driver)
+}
+
 // Init initializes the database connection and performs auto-migration of all models.
 func Init(dbPath string) (*gorm.DB, error) {
 	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
@@ -17,19 +52,206 @@ func Init(dbPath string) (*gorm.DB, error) {
 		return nil, err
 	}
 
-	// Auto-migrate the schema
-	err = db.AutoMigrate(
-		&models.SystemSettings{},
-		&models.User{},
-		&models.ShoppingList{},
-		&models.ListMember{},
-		&models.Invitation{},
-		&models.MagicLink{},
-		&models.ShoppingItem{},
-	)
+	return migrate(db)
+}
+
+// InitInMemory initializes a database that lives in memory only and performs auto-migration of
+// all models. The pool is limited to a single connection, since every new connection to
+// ":memory:" would open a separate, empty database.
+func InitInMemory() (*gorm.DB, error) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	return migrate(db)
+}
+
+// InitEncrypted initializes a SQLCipher-encrypted database with the given key and performs
+// auto-migration of all models. An empty key opens the database unencrypted.
+func InitEncrypted(dbPath, key string) (*gorm.DB, error) {
+	if key == "" {
+		return Init(dbPath)
+	}
+
+	db, err := gorm.Open(sqlite.New(sqlite.Config{
+		DriverName: registerKeyedDriver(key),
+		DSN:        dbPath,
+	}), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	if !hasSQLCipher(sqlDB) {
+		_ = sqlDB.Close()
+		return nil, ErrSQLCipherUnavailable
+	}
+
+	return migrate(db)
+}
+
+// EncryptDatabase copies the plaintext database at plainPath into a new SQLCipher database at
+// encryptedPath, encrypted with key.
+func EncryptDatabase(plainPath, encryptedPath, key string) error {
+	if key == "" {
+		return errors.New("encryption key is required")
+	}
+
+	plain, err := sql.Open("sqlite3", plainPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = plain.Close() }()
+
+	// ATTACH and sqlcipher_export must run on the same connection
+	plain.SetMaxOpenConns(1)
+
+	if !hasSQLCipher(plain) {
+		return ErrSQLCipherUnavailable
+	}
+
+	if _, err := plain.Exec("ATTACH DATABASE ? AS encrypted KEY ?", encryptedPath, key); err != nil {
+		return fmt.Errorf("failed to create encrypted database: %w", err)
+	}
+	if _, err := plain.Exec("SELECT sqlcipher_export('encrypted')"); err != nil {
+		return fmt.Errorf("failed to export data: %w", err)
+	}
+	if _, err := plain.Exec("DETACH DATABASE encrypted"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// EnableWAL switches the database to write-ahead logging, which lets readers and snapshots run
+// alongside writes and is required for replication with Litestream. The mode is stored in the
+// database file.
+func EnableWAL(db *gorm.DB) error {
+	var mode string
+	if err := db.Raw("PRAGMA journal_mode = WAL").Scan(&mode).Error; err != nil {
+		return err
+	}
+	if !strings.EqualFold(mode, "wal") {
+		return fmt.Errorf("failed to enable WAL mode, journal mode is %s", mode)
+	}
+	return nil
+}
+
+// Models lists all models in an order in which rows referencing others come after them, as
+// required to copy them into a database with foreign key constraints.
+var Models = []any{
+	&models.SystemSettings{},
+	&models.User{},
+	&models.ShoppingList{},
+	&models.ListMember{},
+	&models.ListAlias{},
+	&models.ListSection{},
+	&models.ListTag{},
+	&models.DisplayToken{},
+	&models.EnrichmentHook{},
+	&models.ListRule{},
+	&models.ItemMerge{},
+	&models.Purchase{},
+	&models.ReplenishSuggestion{},
+	&models.NutritionFacts{},
+	&models.CalendarFeed{},
+	&models.MatrixLink{},
+	&models.ChatLink{},
+	&models.ChatLinkCode{},
+	&models.FeatureFlag{},
+	&models.FeatureFlagOverride{},
+	&models.ListNote{},
+	&models.SmartList{},
+	&models.Invitation{},
+	&models.InvitationList{},
+	&models.MagicLink{},
+	&models.LoginLink{},
+	&models.LoginThrottle{},
+	&models.ShoppingItem{},
+	&models.ItemPoll{},
+	&models.PollVote{},
+	&models.CategoryMapping{},
+	&models.APIKey{},
+	&models.ShoppingTrip{},
+	&models.TripRSVP{},
+	&models.Announcement{},
+	&models.PolicyAcceptance{},
+	&models.Attachment{},
+	&models.AuditEntry{},
+}
+
+// migrate performs auto-migration of all models, normalizes stored email addresses, backfills
+// the update times of items, renames the member role to editor and registers the ListTouchPlugin.
+func migrate(db *gorm.DB) (*gorm.DB, error) {
+	err := db.AutoMigrate(Models...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := normalizeEmails(db); err != nil {
+		return nil, fmt.Errorf("failed to normalize email addresses: %w", err)
+	}
+
+	// Items created before items tracked their updates count as unchanged since creation
+	err = db.Model(&models.ShoppingItem{}).Where("updated_at IS NULL").UpdateColumn("updated_at", gorm.Expr("created_at")).Error
 	if err != nil {
+		return nil, fmt.Errorf("failed to backfill item update times: %w", err)
+	}
+
+	// Members from before viewers existed edit their lists
+	err = db.Model(&models.ListMember{}).Where("role = ?", "member").UpdateColumn("role", "editor").Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate member roles: %w", err)
+	}
+
+	if err := db.Use(&ListTouchPlugin{}); err != nil {
 		return nil, err
 	}
 
 	return db, nil
 }
+
+var (
+	keyedDriversMu sync.Mutex
+	keyedDrivers   = map[string]string{}
+)
+
+// registerKeyedDriver registers, once per key, a SQLite driver that unlocks every new
+// connection with the key and returns its name.
+func registerKeyedDriver(key string) string {
+	keyedDriversMu.Lock()
+	defer keyedDriversMu.Unlock()
+
+	if name, ok := keyedDrivers[key]; ok {
+		return name
+	}
+
+	name := fmt.Sprintf("sqlite3_sqlcipher_%d", len(keyedDrivers))
+	pragma := "PRAGMA key = '" + strings.ReplaceAll(key, "'", "''") + "'"
+	sql.Register(name, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			_, err := conn.Exec(pragma, []driver.Value{})
+			return err
+		},
+	})
+	keyedDrivers[key] = name
+	return name
+}
+
+// hasSQLCipher reports whether the SQLite library behind the connection is SQLCipher.
+func hasSQLCipher(db *sql.DB) bool {
+	var version string
+	if err := db.QueryRow("PRAGMA cipher_version").Scan(&version); err != nil {
+		return false
+	}
+	return version != ""
+}
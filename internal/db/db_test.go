@@ -4,9 +4,17 @@
 package db
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func TestInit_InMemory(t *testing.T) {
@@ -194,3 +202,371 @@ func TestInit_EmptyPath(t *testing.T) {
 		}
 	}
 }
+
+func TestInitInMemory(t *testing.T) {
+	db, err := InitInMemory()
+	if err != nil {
+		t.Fatalf("Failed to initialize in-memory database: %v", err)
+	}
+
+	if err := db.Exec("INSERT INTO system_settings (id, is_setup) VALUES ('1', true)").Error; err != nil {
+		t.Fatalf("Failed to insert row: %v", err)
+	}
+
+	// Concurrent queries must see the same database instead of opening a fresh one
+	errs := make(chan error, 5)
+	for range 5 {
+		go func() {
+			var count int64
+			err := db.Table("system_settings").Count(&count).Error
+			if err == nil && count != 1 {
+				err = errors.New("query ran against a different in-memory database")
+			}
+			errs <- err
+		}()
+	}
+	for range 5 {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestInitEncrypted(t *testing.T) {
+	t.Run("empty key opens plaintext database", func(t *testing.T) {
+		db, err := InitEncrypted(filepath.Join(t.TempDir(), "plain.db"), "")
+		if err != nil {
+			t.Fatalf("Failed to initialize database: %v", err)
+		}
+		if db == nil {
+			t.Fatal("Database should not be nil")
+		}
+	})
+
+	t.Run("key requires SQLCipher", func(t *testing.T) {
+		db, err := InitEncrypted(filepath.Join(t.TempDir(), "encrypted.db"), "secret")
+		if errors.Is(err, ErrSQLCipherUnavailable) {
+			// Plain SQLite builds must refuse to store data unencrypted
+			return
+		}
+		if err != nil {
+			t.Fatalf("Failed to initialize encrypted database: %v", err)
+		}
+
+		var result int
+		if err := db.Raw("SELECT 1").Scan(&result).Error; err != nil || result != 1 {
+			t.Errorf("Failed to query encrypted database: %v", err)
+		}
+	})
+}
+
+func TestEncryptDatabase(t *testing.T) {
+	tempDir := t.TempDir()
+	plainPath := filepath.Join(tempDir, "plain.db")
+	if _, err := Init(plainPath); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	if err := EncryptDatabase(plainPath, filepath.Join(tempDir, "encrypted.db"), ""); err == nil {
+		t.Error("Expected error without encryption key")
+	}
+
+	err := EncryptDatabase(plainPath, filepath.Join(tempDir, "encrypted.db"), "secret")
+	if errors.Is(err, ErrSQLCipherUnavailable) {
+		return
+	}
+	if err != nil {
+		t.Fatalf("Failed to encrypt database: %v", err)
+	}
+
+	if _, err := InitEncrypted(filepath.Join(tempDir, "encrypted.db"), "secret"); err != nil {
+		t.Errorf("Failed to open encrypted database: %v", err)
+	}
+}
+
+func TestNormalizeEmails(t *testing.T) {
+	db, err := InitInMemory()
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	older := time.Now().Add(-time.Hour)
+	for _, row := range []any{
+		&models.User{ID: "original", Email: "Foo@Example.com", JoinedAt: older, CreatedAt: older},
+		&models.User{ID: "duplicate", Email: " foo@example.COM", JoinedAt: time.Now(), CreatedAt: time.Now()},
+		&models.ShoppingList{ID: "shared", Name: "Shared", OwnerID: "original"},
+		&models.ShoppingList{ID: "private", Name: "Private", OwnerID: "duplicate"},
+		&models.ListMember{ListID: "shared", UserID: "original", Role: "owner"},
+		&models.ListMember{ListID: "shared", UserID: "duplicate", Role: "member"},
+		&models.ListMember{ListID: "private", UserID: "duplicate", Role: "owner"},
+		&models.Invitation{ID: "invitation", Code: "ABCD1234", Email: "Bar@Example.com", Type: "server", InvitedBy: "duplicate", ExpiresAt: time.Now().Add(time.Hour)},
+	} {
+		if err := db.Omit(clause.Associations).Create(row).Error; err != nil {
+			t.Fatalf("Failed to create %T: %v", row, err)
+		}
+	}
+
+	if err := normalizeEmails(db); err != nil {
+		t.Fatalf("Failed to normalize emails: %v", err)
+	}
+
+	var users []models.User
+	db.Find(&users)
+	if len(users) != 1 || users[0].ID != "original" || users[0].Email != "foo@example.com" {
+		t.Fatalf("Expected the older account with a normalized email, got %+v", users)
+	}
+
+	var members []models.ListMember
+	db.Order("list_id").Find(&members)
+	if len(members) != 2 || members[0].ListID != "private" || members[0].UserID != "original" || members[1].Role != "owner" {
+		t.Errorf("Expected the memberships to be merged, got %+v", members)
+	}
+	var owner string
+	db.Model(&models.ShoppingList{}).Where("id = ?", "private").Pluck("owner_id", &owner)
+	if owner != "original" {
+		t.Errorf("Expected the list to move to the kept account, got %q", owner)
+	}
+
+	var invitation models.Invitation
+	db.First(&invitation, "id = ?", "invitation")
+	if invitation.Email != "bar@example.com" || invitation.InvitedBy != "original" {
+		t.Errorf("Expected a normalized invitation from the kept account, got %+v", invitation)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	tempDir := t.TempDir()
+	src, err := Open("sqlite:"+filepath.Join(tempDir, "source.db"), "")
+	if err != nil {
+		t.Fatalf("Failed to open source: %v", err)
+	}
+	dst, err := Open("sqlite:"+filepath.Join(tempDir, "target.db"), "")
+	if err != nil {
+		t.Fatalf("Failed to open target: %v", err)
+	}
+
+	user := models.User{ID: "user", Email: "user@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	src.Create(&user)
+	src.Create(&models.ShoppingList{ID: "list", Name: "Groceries", OwnerID: user.ID})
+	src.Create(&models.ListMember{ListID: "list", UserID: user.ID, Role: "owner", JoinedAt: time.Now()})
+	src.Create(&models.ShoppingItem{ID: "milk", ListID: "list", Name: "Milk", Tags: "[]", CreatedAt: time.Now()})
+	src.Create(&models.ShoppingItem{ID: "bread", ListID: "list", Name: "Bread", Completed: true, Tags: "[]", CreatedAt: time.Now()})
+	// Disabled settings must stay disabled, although the column defaults to enabled
+	src.Create(&models.SystemSettings{ID: "settings", IsSetup: true})
+	src.Model(&models.SystemSettings{}).Where("id = ?", "settings").Update("auto_create_default_list", false)
+
+	report, err := Copy(src, dst)
+	if err != nil {
+		t.Fatalf("Failed to copy database: %v", err)
+	}
+	if len(report) != len(Models) {
+		t.Errorf("Expected a report for %d tables, got %d", len(Models), len(report))
+	}
+	for _, table := range report {
+		if table.Table == "shopping_items" && table.Rows != 2 {
+			t.Errorf("Expected 2 copied items, got %d", table.Rows)
+		}
+	}
+
+	var bread models.ShoppingItem
+	if err := dst.First(&bread, "id = ?", "bread").Error; err != nil || !bread.Completed {
+		t.Errorf("Expected the completed item in the target, got %+v", bread)
+	}
+	var copied models.User
+	if err := dst.First(&copied, "id = ?", user.ID).Error; err != nil || copied.Email != user.Email {
+		t.Errorf("Expected the user in the target, got %+v", copied)
+	}
+	var settings models.SystemSettings
+	dst.First(&settings, "id = ?", "settings")
+	if settings.AutoCreateDefaultList || !settings.IsSetup {
+		t.Errorf("Expected the settings to be copied as stored, got %+v", settings)
+	}
+
+	if _, err := Copy(src, dst); !errors.Is(err, ErrTargetNotEmpty) {
+		t.Errorf("Expected copying into a non-empty database to fail, got %v", err)
+	}
+}
+
+func TestOpen_UnsupportedURL(t *testing.T) {
+	if _, err := Open("mysql://localhost/shopping", ""); err == nil {
+		t.Error("Expected an unsupported database URL to be rejected")
+	}
+}
+
+func TestConnect(t *testing.T) {
+	db, err := Connect(DriverSQLite, filepath.Join(t.TempDir(), "shopping.db"), "")
+	if err != nil {
+		t.Fatalf("Failed to connect to SQLite: %v", err)
+	}
+	if db.Dialector.Name() != DriverSQLite {
+		t.Errorf("Expected a SQLite database, got %s", db.Dialector.Name())
+	}
+
+	if _, err := Connect("mysql", "localhost", ""); err == nil {
+		t.Error("Expected an unsupported driver to be rejected")
+	}
+	if _, ok := dialectors[DriverPostgres]; !ok {
+		if _, err := Connect(DriverPostgres, "host=localhost", ""); !errors.Is(err, ErrPostgresUnavailable) {
+			t.Errorf("Expected PostgreSQL to require the build tag, got %v", err)
+		}
+	}
+}
+
+func TestListTouchPlugin(t *testing.T) {
+	db, err := Init(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	db.Create(&models.User{ID: "owner", Email: "owner@example.com", JoinedAt: past, CreatedAt: past})
+	for _, id := range []string{"groceries", "hardware"} {
+		db.Create(&models.ShoppingList{ID: id, Name: id, OwnerID: "owner", CreatedAt: past, UpdatedAt: past})
+	}
+	reset := func() {
+		db.Session(&gorm.Session{AllowGlobalUpdate: true}).Model(&models.ShoppingList{}).UpdateColumn("updated_at", past)
+	}
+	touched := func() []string {
+		var ids []string
+		db.Model(&models.ShoppingList{}).Where("updated_at > ?", past).Order("id").Pluck("id", &ids)
+		return ids
+	}
+
+	tests := []struct {
+		name     string
+		mutate   func() error
+		expected []string
+	}{
+		{"create", func() error {
+			return db.Create(&[]models.ShoppingItem{{ID: "milk", ListID: "groceries", Name: "Milk"}, {ID: "nails", ListID: "hardware", Name: "Nails"}}).Error
+		}, []string{"groceries", "hardware"}},
+		{"save", func() error {
+			item := models.ShoppingItem{ID: "milk", ListID: "groceries", Name: "Oat milk"}
+			return db.Save(&item).Error
+		}, []string{"groceries"}},
+		{"update by condition", func() error {
+			return db.Model(&models.ShoppingItem{}).Where("name = ?", "Nails").UpdateColumn("completed", true).Error
+		}, []string{"hardware"}},
+		{"update matching nothing", func() error {
+			return db.Model(&models.ShoppingItem{}).Where("name = ?", "Bread").Update("completed", true).Error
+		}, nil},
+		{"delete by ID", func() error {
+			return db.Delete(&models.ShoppingItem{ID: "milk"}).Error
+		}, []string{"groceries"}},
+		{"delete by condition", func() error {
+			return db.Where("list_id = ?", "hardware").Delete(&models.ShoppingItem{}).Error
+		}, []string{"hardware"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reset()
+			if err := tt.mutate(); err != nil {
+				t.Fatalf("Failed to change items: %v", err)
+			}
+			if got := touched(); !slices.Equal(got, tt.expected) {
+				t.Errorf("Expected %v to be touched, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestReplicaPlugin(t *testing.T) {
+	tempDir := t.TempDir()
+	primary, err := Init(filepath.Join(tempDir, "primary.db"))
+	if err != nil {
+		t.Fatalf("Failed to initialize primary: %v", err)
+	}
+	// The replica is a separate SQLite database here, so reads show where they ran
+	replicaPath := filepath.Join(tempDir, "replica.db")
+	replicaDB, err := Init(replicaPath)
+	if err != nil {
+		t.Fatalf("Failed to initialize replica: %v", err)
+	}
+	if err := replicaDB.Create(&models.User{ID: "user-1", Email: "replica@example.com"}).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := primary.Create(&models.User{ID: "user-1", Email: "primary@example.com"}).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	if _, err := OpenReplica(DriverSQLite, replicaPath); !errors.Is(err, ErrReplicaUnsupported) {
+		t.Errorf("Expected SQLite replicas to be rejected, got %v", err)
+	}
+	replica := &ReplicaPlugin{Replica: replicaDB, PinDuration: DefaultReplicaPin}
+	if err := primary.Use(replica); err != nil {
+		t.Fatalf("Failed to register plugin: %v", err)
+	}
+	email := func(db *gorm.DB) string {
+		var user models.User
+		if err := db.First(&user, "id = ?", "user-1").Error; err != nil {
+			t.Fatalf("Failed to read user: %v", err)
+		}
+		return user.Email
+	}
+	reader := primary.WithContext(WithReader(context.Background(), "user-1"))
+
+	if got := email(primary); got != "primary@example.com" {
+		t.Errorf("Expected unmarked queries on the primary, got %s", got)
+	}
+	if got := email(reader); got != "replica@example.com" {
+		t.Errorf("Expected reads on the replica, got %s", got)
+	}
+	err = reader.Transaction(func(tx *gorm.DB) error {
+		if got := email(tx); got != "primary@example.com" {
+			t.Errorf("Expected reads within transactions on the primary, got %s", got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+
+	now := time.Now()
+	replica.Pin("user-1", now)
+	if got := email(reader); got != "primary@example.com" {
+		t.Errorf("Expected pinned reads on the primary, got %s", got)
+	}
+	if got := email(primary.WithContext(WithReader(context.Background(), "user-2"))); got != "replica@example.com" {
+		t.Errorf("Expected reads of others on the replica, got %s", got)
+	}
+	if !replica.Reads("user-1", now.Add(DefaultReplicaPin)) {
+		t.Error("Expected the pin to expire")
+	}
+
+	writer := primary.WithContext(WithReader(context.Background(), "user-3"))
+	if err := writer.Create(&models.User{ID: "user-3", Email: "writer@example.com"}).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if replica.Reads("user-3", time.Now()) {
+		t.Error("Expected writes with a reader context to pin their key")
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	db, err := Init(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	db.Create(&models.Announcement{ID: "maintenance", Title: "Maintenance", CreatedAt: time.Now()})
+
+	var deleted int64
+	err = DryRun(db, func(tx *gorm.DB) error {
+		result := tx.Where("id = ?", "maintenance").Delete(&models.Announcement{})
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	if err != nil || deleted != 1 {
+		t.Fatalf("Expected the delete to run, got %d rows and %v", deleted, err)
+	}
+	var count int64
+	db.Model(&models.Announcement{}).Count(&count)
+	if count != 1 {
+		t.Errorf("Expected the delete to be rolled back, got %d announcements", count)
+	}
+
+	failed := errors.New("failed")
+	if err := DryRun(db, func(tx *gorm.DB) error { return failed }); !errors.Is(err, failed) {
+		t.Errorf("Expected errors of the action to be returned, got %v", err)
+	}
+}
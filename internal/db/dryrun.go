@@ -0,0 +1,28 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package db
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// errDryRun rolls back the transaction of a dry run.
+var errDryRun = errors.New("dry run")
+
+// DryRun runs fn in a transaction that is rolled back once fn returns, so fn can run the real
+// statements of an action and report what they affected without changing anything.
+func DryRun(database *gorm.DB, fn func(tx *gorm.DB) error) error {
+	err := database.Transaction(func(tx *gorm.DB) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return errDryRun
+	})
+	if errors.Is(err, errDryRun) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,190 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package db
+
+import (
+	"fmt"
+
+	"github.com/oliverandrich/shopping-list-server/internal/mail"
+	"github.com/oliverandrich/shopping-list-server/internal/pii"
+	"gorm.io/gorm"
+)
+
+// userReference is a column referencing users.id.
+type userReference struct {
+	Table  string
+	Column string
+}
+
+// userReferences lists all columns referencing a user, which are moved over when duplicate
+// accounts are merged.
+var userReferences = []userReference{
+	{Table: "users", Column: "invited_by"},
+	{Table: "system_settings", Column: "initial_admin"},
+	{Table: "shopping_lists", Column: "owner_id"},
+	{Table: "list_members", Column: "user_id"},
+	{Table: "list_notes", Column: "user_id"},
+	{Table: "smart_lists", Column: "user_id"},
+	{Table: "shopping_trips", Column: "created_by"},
+	{Table: "trip_rsvps", Column: "user_id"},
+	{Table: "item_polls", Column: "created_by"},
+	{Table: "poll_votes", Column: "user_id"},
+	{Table: "invitations", Column: "invited_by"},
+	{Table: "attachments", Column: "user_id"},
+	{Table: "shopping_items", Column: "created_by"},
+	{Table: "shopping_items", Column: "completed_by"},
+	{Table: "category_mappings", Column: "created_by"},
+	{Table: "api_keys", Column: "user_id"},
+	{Table: "display_tokens", Column: "created_by"},
+	{Table: "enrichment_hooks", Column: "created_by"},
+	{Table: "list_rules", Column: "created_by"},
+	{Table: "item_merges", Column: "user_id"},
+	{Table: "purchases", Column: "user_id"},
+	{Table: "calendar_feeds", Column: "user_id"},
+	{Table: "matrix_links", Column: "user_id"},
+	{Table: "chat_links", Column: "user_id"},
+	{Table: "chat_link_codes", Column: "user_id"},
+	{Table: "feature_flag_overrides", Column: "user_id"},
+	{Table: "announcements", Column: "created_by"},
+	{Table: "policy_acceptances", Column: "user_id"},
+	{Table: "audit_entries", Column: "actor_id"},
+	{Table: "audit_entries", Column: "target_user_id"},
+}
+
+// normalizeEmails rewrites stored email addresses to their normalized form and merges accounts
+// whose addresses only differed in case or spacing into the oldest of them, which keeps its
+// memberships and gains those of the others. Where both accounts have a row of their own, e.g. a
+// membership in the same list, the oldest account's row wins. It runs on every start and only
+// writes rows that are not normalized yet.
+func normalizeEmails(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var users []struct {
+			ID    string
+			Email string
+		}
+		if err := tx.Raw("SELECT id, email FROM users ORDER BY created_at ASC, id ASC").Scan(&users).Error; err != nil {
+			return err
+		}
+
+		kept := map[string]string{}
+		var renamed []string
+		for _, user := range users {
+			email, err := pii.Decrypt(user.Email)
+			if err != nil {
+				return fmt.Errorf("users %s: %w", user.ID, err)
+			}
+			normalized := mail.NormalizeAddress(email)
+
+			keepID, duplicate := kept[normalized]
+			if duplicate {
+				if err := mergeUser(tx, user.ID, keepID); err != nil {
+					return err
+				}
+				continue
+			}
+			kept[normalized] = user.ID
+			if normalized != email {
+				renamed = append(renamed, normalized)
+			}
+		}
+
+		// Rename only after the duplicates are gone, so the unique index holds
+		for _, email := range renamed {
+			if err := tx.Exec("UPDATE users SET email = ? WHERE id = ?", pii.Encrypt(email), kept[email]).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := normalizeColumn(tx, "invitations", "id", true); err != nil {
+			return err
+		}
+		return normalizeColumn(tx, "magic_links", "code", false)
+	})
+}
+
+// mergeUser moves everything referencing the user duplicateID to keepID and deletes the
+// duplicate.
+func mergeUser(tx *gorm.DB, duplicateID, keepID string) error {
+	for _, ref := range userReferences {
+		if err := moveReference(tx, ref, duplicateID, keepID); err != nil {
+			return err
+		}
+		// Rows that would have clashed with a row of the kept user
+		remove := fmt.Sprintf("DELETE FROM %s WHERE %s = ?", ref.Table, ref.Column)
+		if err := tx.Exec(remove, duplicateID).Error; err != nil {
+			return err
+		}
+	}
+
+	// The kept user may have been a plain member of a list the duplicate owned
+	err := tx.Exec("UPDATE list_members SET role = 'owner' WHERE user_id = ? AND list_id IN (SELECT id FROM shopping_lists WHERE owner_id = ?)", keepID, keepID).Error
+	if err != nil {
+		return err
+	}
+	return tx.Exec("DELETE FROM users WHERE id = ?", duplicateID).Error
+}
+
+// moveReference moves the rows of a column referencing duplicateID to keepID, leaving rows that
+// would clash with a row of keepID behind.
+func moveReference(tx *gorm.DB, ref userReference, duplicateID, keepID string) error {
+	if tx.Dialector.Name() == DriverSQLite {
+		update := fmt.Sprintf("UPDATE OR IGNORE %s SET %s = ? WHERE %s = ?", ref.Table, ref.Column, ref.Column)
+		return tx.Exec(update, keepID, duplicateID).Error
+	}
+
+	// PostgreSQL has no UPDATE OR IGNORE, so rows are moved one by one, each in a savepoint that
+	// is rolled back if the row clashes
+	var rows []string
+	query := fmt.Sprintf("SELECT ctid::text FROM %s WHERE %s = ?", ref.Table, ref.Column)
+	if err := tx.Raw(query, duplicateID).Scan(&rows).Error; err != nil {
+		return err
+	}
+	update := fmt.Sprintf("UPDATE %s SET %s = ? WHERE ctid = ?::tid", ref.Table, ref.Column)
+	for _, row := range rows {
+		if err := tx.SavePoint("move_reference").Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(update, keepID, row).Error; err != nil {
+			if err := tx.RollbackTo("move_reference").Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// normalizeColumn rewrites the email column of every row in table, identified by the key column,
+// to its normalized form.
+func normalizeColumn(tx *gorm.DB, table, key string, encrypted bool) error {
+	var rows []struct {
+		ID    string
+		Email string
+	}
+	if err := tx.Raw(fmt.Sprintf("SELECT %s AS id, email FROM %s", key, table)).Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		email := row.Email
+		if encrypted {
+			var err error
+			if email, err = pii.Decrypt(email); err != nil {
+				return fmt.Errorf("%s %s: %w", table, row.ID, err)
+			}
+		}
+
+		normalized := mail.NormalizeAddress(email)
+		if normalized == email {
+			continue
+		}
+		if encrypted {
+			normalized = pii.Encrypt(normalized)
+		}
+		update := fmt.Sprintf("UPDATE %s SET email = ? WHERE %s = ?", table, key)
+		if err := tx.Exec(update, normalized, row.ID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+//go:build postgres
+
+package db
+
+import "gorm.io/driver/postgres"
+
+// Most installations run on SQLite, so the PostgreSQL driver is left out of default builds.
+func init() {
+	dialectors["postgres"] = postgres.Open
+	dialectors["postgresql"] = postgres.Open
+}
@@ -0,0 +1,25 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+//go:build postgres
+
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestConnect_Postgres(t *testing.T) {
+	for _, name := range []string{"postgres", "postgresql"} {
+		if _, ok := dialectors[name]; !ok {
+			t.Errorf("Expected the %s dialector to be registered", name)
+		}
+	}
+
+	// Nothing listens on port 1, so connecting fails with the error of the driver
+	_, err := Connect(DriverPostgres, "host=127.0.0.1 port=1 connect_timeout=1", "")
+	if err == nil || errors.Is(err, ErrPostgresUnavailable) {
+		t.Errorf("Expected a connection error, got %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Staleness guards of the replica used unless configured otherwise: how long users read from the
+// primary after they changed something, and how far the replica may lag behind before all reads
+// go to the primary.
+const (
+	DefaultReplicaPin    = 5 * time.Second
+	DefaultReplicaMaxLag = 30 * time.Second
+)
+
+// ErrReplicaUnsupported is returned when opening a replica of a SQLite database, which has none.
+var ErrReplicaUnsupported = errors.New("read replicas are only supported with PostgreSQL")
+
+// lagCheckInterval is how often the replication lag of a PostgreSQL replica is queried.
+const lagCheckInterval = time.Second
+
+type readerKey struct{}
+
+// WithReader returns a context marking the queries run with it as reads on behalf of key, e.g.
+// a user ID, which the ReplicaPlugin may send to the replica. Writes and statements within
+// transactions always run on the primary.
+func WithReader(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, readerKey{}, key)
+}
+
+// ReplicaPlugin is a GORM plugin that sends queries marked with WithReader to a read replica,
+// unless their key was pinned to the primary after a write or the replica lags too far behind.
+type ReplicaPlugin struct {
+	Replica *gorm.DB
+	// PinDuration is how long Pin keeps a key on the primary.
+	PinDuration time.Duration
+	// MaxLag is the replication lag above which all reads go to the primary. It is only checked
+	// for PostgreSQL replicas, zero disables the check.
+	MaxLag time.Duration
+
+	mu           sync.Mutex
+	pinned       map[string]time.Time
+	lagCheckedAt time.Time
+	lagging      bool
+}
+
+// OpenReplica connects to the read replica of a PostgreSQL database, see Connect, with the
+// default staleness guards. The replica is not migrated, it follows the primary.
+func OpenReplica(driver, dsn string) (*ReplicaPlugin, error) {
+	switch driver {
+	case DriverPostgres:
+	case DriverSQLite:
+		return nil, ErrReplicaUnsupported
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q, use sqlite or postgres", driver)
+	}
+	open, ok := dialectors[DriverPostgres]
+	if !ok {
+		return nil, ErrPostgresUnavailable
+	}
+
+	replica, err := gorm.Open(open(dsn), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	return &ReplicaPlugin{Replica: replica, PinDuration: DefaultReplicaPin, MaxLag: DefaultReplicaMaxLag}, nil
+}
+
+// Name returns the plugin name.
+func (p *ReplicaPlugin) Name() string {
+	return "db:replica"
+}
+
+// Initialize registers the routing callbacks for queries, and callbacks pinning the key of
+// writes run with a WithReader context, e.g. by GET requests that change something.
+func (p *ReplicaPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Query().Before("gorm:query").Register("db:replica_query", p.route); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("db:replica_row", p.route); err != nil {
+		return err
+	}
+	if err := callbacks.Create().After("gorm:create").Register("db:replica_pin_create", p.pinWriter); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:update").Register("db:replica_pin_update", p.pinWriter); err != nil {
+		return err
+	}
+	return callbacks.Delete().After("gorm:delete").Register("db:replica_pin_delete", p.pinWriter)
+}
+
+// Pin keeps the reads of key on the primary for PinDuration from now, so they see what was just
+// written even while the replica catches up.
+func (p *ReplicaPlugin) Pin(key string, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.pinned == nil {
+		p.pinned = map[string]time.Time{}
+	}
+	for pinned, until := range p.pinned {
+		if !now.Before(until) {
+			delete(p.pinned, pinned)
+		}
+	}
+	p.pinned[key] = now.Add(p.PinDuration)
+}
+
+// Reads reports whether reads of key may go to the replica at now. The replication lag is
+// queried without holding the lock, so a slow replica never holds up Pin or reads of other keys;
+// they use the result of the previous check meanwhile.
+func (p *ReplicaPlugin) Reads(key string, now time.Time) bool {
+	p.mu.Lock()
+	if until, ok := p.pinned[key]; ok && now.Before(until) {
+		p.mu.Unlock()
+		return false
+	}
+	check := p.MaxLag > 0 && p.Replica.Name() == DriverPostgres && now.Sub(p.lagCheckedAt) >= lagCheckInterval
+	if check {
+		// Claim the check, so concurrent reads do not query the replica as well
+		p.lagCheckedAt = now
+	}
+	lagging := p.lagging
+	p.mu.Unlock()
+
+	if !check {
+		return !lagging
+	}
+	lagging = p.lag() > p.MaxLag
+
+	p.mu.Lock()
+	p.lagging = lagging
+	p.mu.Unlock()
+	return !lagging
+}
+
+// lag returns the replication lag of a PostgreSQL replica. A replica that replayed everything it
+// received is current, even if the primary has been idle since the last replayed transaction.
+// Replicas that cannot tell count as lagging, so reads fall back to the primary.
+func (p *ReplicaPlugin) lag() time.Duration {
+	var seconds float64
+	err := p.Replica.Raw(`SELECT CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0) END`).
+		Scan(&seconds).Error
+	if err != nil {
+		slog.Warn("Failed to check replication lag", "error", err)
+		return p.MaxLag + 1
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// route sends a query marked with WithReader to the replica if its key may read from there.
+func (p *ReplicaPlugin) route(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	key, ok := db.Statement.Context.Value(readerKey{}).(string)
+	if !ok {
+		return
+	}
+	// Reads within a transaction must see its writes
+	if _, inTx := db.Statement.ConnPool.(gorm.TxCommitter); inTx {
+		return
+	}
+	if p.Reads(key, time.Now()) {
+		db.Statement.ConnPool = p.Replica.ConnPool
+	}
+}
+
+// pinWriter pins the key of a write run with a WithReader context.
+func (p *ReplicaPlugin) pinWriter(db *gorm.DB) {
+	if key, ok := db.Statement.Context.Value(readerKey{}).(string); ok && key != "" {
+		p.Pin(key, time.Now())
+	}
+}
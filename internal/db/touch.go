@@ -0,0 +1,113 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package db
+
+import (
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// ListTouchPlugin is a GORM plugin that sets the UpdatedAt of a list whenever items of it are
+// created, updated or deleted, in the same transaction as the change. This makes the list's
+// UpdatedAt a reliable anchor for sync tokens, ETags and unseen changes, including changes that
+// leave no item behind. Rows written as maps, like those of Copy, are left alone.
+type ListTouchPlugin struct{}
+
+// Name returns the plugin name.
+func (p *ListTouchPlugin) Name() string {
+	return "db:list_touch"
+}
+
+// Initialize registers the callbacks. Updated and deleted items are looked up before the
+// statement runs, while its conditions still match them.
+func (p *ListTouchPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	err := callbacks.Create().After("gorm:create").Before("gorm:commit_or_rollback_transaction").
+		Register("db:touch_lists_after_create", p.touch)
+	if err != nil {
+		return err
+	}
+	err = callbacks.Update().After("gorm:begin_transaction").Before("gorm:update").
+		Register("db:touch_lists_before_update", p.touch)
+	if err != nil {
+		return err
+	}
+	return callbacks.Delete().After("gorm:begin_transaction").Before("gorm:delete").
+		Register("db:touch_lists_before_delete", p.touch)
+}
+
+// touch updates the lists of the items a statement writes.
+func (p *ListTouchPlugin) touch(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil || db.Statement.Schema.Table != "shopping_items" {
+		return
+	}
+	if db.Statement.ReflectValue.Kind() == reflect.Map {
+		return
+	}
+
+	listIDs, err := p.listIDs(db)
+	if err != nil {
+		_ = db.AddError(err)
+		return
+	}
+	if len(listIDs) == 0 {
+		return
+	}
+
+	err = db.Session(&gorm.Session{NewDB: true}).Model(&models.ShoppingList{}).
+		Where("id IN ?", listIDs).UpdateColumn("updated_at", time.Now()).Error
+	if err != nil {
+		_ = db.AddError(err)
+	}
+}
+
+// listIDs returns the lists of the items a statement writes: those of the items it was given
+// and, for updates and deletes with conditions, those of the items they match.
+func (p *ListTouchPlugin) listIDs(db *gorm.DB) ([]string, error) {
+	var listIDs, itemIDs []string
+	collect := func(value reflect.Value) {
+		value = reflect.Indirect(value)
+		if !value.IsValid() || value.Kind() != reflect.Struct {
+			return
+		}
+		item, ok := value.Interface().(models.ShoppingItem)
+		switch {
+		case !ok:
+		case item.ListID != "":
+			listIDs = append(listIDs, item.ListID)
+		case item.ID != "":
+			itemIDs = append(itemIDs, item.ID)
+		}
+	}
+
+	value := db.Statement.ReflectValue
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := range value.Len() {
+			collect(value.Index(i))
+		}
+	default:
+		collect(value)
+	}
+
+	tx := db.Session(&gorm.Session{NewDB: true}).Model(&models.ShoppingItem{})
+	var matched []string
+	if where, ok := db.Statement.Clauses["WHERE"]; ok {
+		if err := tx.Clauses(where.Expression).Distinct().Pluck("list_id", &matched).Error; err != nil {
+			return nil, err
+		}
+	} else if len(itemIDs) > 0 {
+		if err := tx.Where("id IN ?", itemIDs).Distinct().Pluck("list_id", &matched).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	listIDs = append(listIDs, matched...)
+	slices.Sort(listIDs)
+	return slices.Compact(listIDs), nil
+}
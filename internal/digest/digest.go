@@ -0,0 +1,228 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package digest emails the admin an opt-in weekly summary of the server's operation: new users,
+// admin actions, emails sent and failed, the request error rate and the database growth.
+package digest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/mail"
+	"github.com/oliverandrich/shopping-list-server/internal/metrics"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gopkg.in/gomail.v2"
+	"gorm.io/gorm"
+)
+
+// Interval is the time between two digests.
+const Interval = 7 * 24 * time.Hour
+
+// counters holds the in-memory counts a digest reports the difference of.
+type counters struct {
+	emailsSent   int64
+	emailsFailed int64
+	requests     float64
+	serverErrors float64
+}
+
+// Service collects and sends the digest.
+type Service struct {
+	DB     *gorm.DB
+	Mailer *gomail.Dialer
+
+	mu         sync.Mutex
+	baseline   counters
+	baselineAt time.Time
+}
+
+// NewService creates a new digest service counting emails and requests from now on.
+func NewService(db *gorm.DB, mailer *gomail.Dialer) *Service {
+	return &Service{DB: db, Mailer: mailer, baseline: current(), baselineAt: time.Now()}
+}
+
+// Collect summarizes the time since the last digest, or the last week before the first one.
+func (s *Service) Collect() (*models.OperatorDigest, error) {
+	settings, err := s.settings()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	digest := &models.OperatorDigest{From: now.Add(-Interval), Until: now}
+	if settings.DigestSentAt != nil {
+		digest.From = *settings.DigestSentAt
+	}
+
+	if err := s.DB.Model(&models.User{}).Where("created_at >= ?", digest.From).Count(&digest.NewUsers).Error; err != nil {
+		return nil, err
+	}
+	if err := s.DB.Model(&models.User{}).Count(&digest.TotalUsers).Error; err != nil {
+		return nil, err
+	}
+	if err := s.DB.Model(&models.AuditEntry{}).Where("created_at >= ?", digest.From).Count(&digest.AdminActions).Error; err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	baseline, baselineAt := s.baseline, s.baselineAt
+	s.mu.Unlock()
+	counts := current()
+	digest.CountersSince = baselineAt
+	digest.EmailsSent = counts.emailsSent - baseline.emailsSent
+	digest.EmailsFailed = counts.emailsFailed - baseline.emailsFailed
+	digest.Requests = int64(counts.requests - baseline.requests)
+	digest.ServerErrors = int64(counts.serverErrors - baseline.serverErrors)
+	if digest.Requests > 0 {
+		digest.ErrorRate = float64(digest.ServerErrors) / float64(digest.Requests)
+	}
+
+	if digest.DatabaseBytes, err = s.databaseSize(); err != nil {
+		return nil, err
+	}
+	if settings.DigestDatabaseBytes > 0 {
+		digest.DatabaseGrowthBytes = digest.DatabaseBytes - settings.DigestDatabaseBytes
+	}
+	return digest, nil
+}
+
+// SendDue sends the digest to the admin if it is enabled and the last one was sent at least a
+// week ago. It reports whether a digest was sent.
+func (s *Service) SendDue() (bool, error) {
+	settings, err := s.settings()
+	if err != nil {
+		return false, err
+	}
+	if !settings.WeeklyDigest || settings.InitialAdmin == "" {
+		return false, nil
+	}
+	if settings.DigestSentAt != nil && time.Since(*settings.DigestSentAt) < Interval {
+		return false, nil
+	}
+
+	var admin models.User
+	if err := s.DB.First(&admin, "id = ?", settings.InitialAdmin).Error; err != nil {
+		return false, errors.New("admin not found")
+	}
+
+	digest, err := s.Collect()
+	if err != nil {
+		return false, err
+	}
+	if err := s.send(admin.Email, digest); err != nil {
+		return false, err
+	}
+
+	err = s.DB.Model(&models.SystemSettings{}).Where("id = ?", settings.ID).UpdateColumns(map[string]any{
+		"digest_sent_at":        digest.Until,
+		"digest_database_bytes": digest.DatabaseBytes,
+	}).Error
+	if err != nil {
+		return true, err
+	}
+
+	s.mu.Lock()
+	s.baseline, s.baselineAt = current(), digest.Until
+	s.mu.Unlock()
+	return true, nil
+}
+
+// send emails the digest to the admin.
+func (s *Service) send(email string, digest *models.OperatorDigest) error {
+	// Skip email sending in test environment
+	if os.Getenv("GO_ENV") == "test" {
+		return nil
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", os.Getenv("SMTP_FROM"))
+	m.SetHeader("To", email)
+	m.SetHeader("Subject", fmt.Sprintf("Shopping list server: %d new users this week", digest.NewUsers))
+	m.SetBody("text/plain", Render(digest))
+
+	return mail.Send(s.Mailer, m)
+}
+
+// Render formats the digest as the plain text body of the email.
+func Render(digest *models.OperatorDigest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Weekly report from %s to %s\n\n", digest.From.Format("2006-01-02"), digest.Until.Format("2006-01-02"))
+	fmt.Fprintf(&b, "Users: %d new, %d total\n", digest.NewUsers, digest.TotalUsers)
+	fmt.Fprintf(&b, "Admin actions: %d\n", digest.AdminActions)
+	fmt.Fprintf(&b, "Database: %s (%s)\n", formatBytes(digest.DatabaseBytes), formatGrowth(digest.DatabaseGrowthBytes))
+	fmt.Fprintf(&b, "\nSince %s:\n", digest.CountersSince.Format("2006-01-02 15:04"))
+	fmt.Fprintf(&b, "Emails: %d sent, %d failed\n", digest.EmailsSent, digest.EmailsFailed)
+	if digest.Requests > 0 {
+		fmt.Fprintf(&b, "Requests: %d, %d server errors (%.2f%%)\n", digest.Requests, digest.ServerErrors, digest.ErrorRate*100)
+	} else {
+		b.WriteString("Requests: not counted, set METRICS_ENABLED=true to include them\n")
+	}
+	return b.String()
+}
+
+// databaseSize returns the size of the database in bytes.
+func (s *Service) databaseSize() (int64, error) {
+	if s.DB.Dialector.Name() == "postgres" {
+		var size int64
+		err := s.DB.Raw("SELECT pg_database_size(current_database())").Scan(&size).Error
+		return size, err
+	}
+
+	var pageCount, pageSize int64
+	if err := s.DB.Raw("PRAGMA page_count").Scan(&pageCount).Error; err != nil {
+		return 0, err
+	}
+	if err := s.DB.Raw("PRAGMA page_size").Scan(&pageSize).Error; err != nil {
+		return 0, err
+	}
+	return pageCount * pageSize, nil
+}
+
+// settings loads the system settings.
+func (s *Service) settings() (*models.SystemSettings, error) {
+	var settings models.SystemSettings
+	if err := s.DB.Limit(1).Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// current reads the email and request counters.
+func current() counters {
+	sent, failed := mail.Counts()
+	return counters{
+		emailsSent:   sent,
+		emailsFailed: failed,
+		requests:     metrics.RequestsTotal.Sum(nil),
+		serverErrors: metrics.RequestsTotal.Sum(func(labelValues []string) bool {
+			return strings.HasPrefix(labelValues[2], "5")
+		}),
+	}
+}
+
+// formatBytes formats a size in bytes with a binary unit.
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit && size > -unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value, exp := float64(size)/unit, 0
+	for value >= unit || value <= -unit {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGTPE"[exp])
+}
+
+// formatGrowth formats the database growth, signed.
+func formatGrowth(growth int64) string {
+	if growth >= 0 {
+		return "+" + formatBytes(growth)
+	}
+	return "-" + formatBytes(-growth)
+}
@@ -0,0 +1,96 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package digest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/metrics"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+)
+
+func TestService_SendDue(t *testing.T) {
+	t.Setenv("GO_ENV", "test")
+	db := testutils.SetupTestDB(t)
+	service := NewService(db, nil)
+
+	admin := models.User{ID: "admin", Email: "admin@example.com", JoinedAt: time.Now(), CreatedAt: time.Now().AddDate(0, -1, 0)}
+	newcomer := models.User{ID: "newcomer", Email: "new@example.com", JoinedAt: time.Now(), CreatedAt: time.Now().AddDate(0, 0, -2)}
+	db.Create(&admin)
+	db.Create(&newcomer)
+	db.Create(&models.AuditEntry{ID: "entry", ActorID: admin.ID, Action: "logout", TargetUserID: newcomer.ID, CreatedAt: time.Now()})
+	settings := models.SystemSettings{ID: "settings", IsSetup: true, InitialAdmin: admin.ID}
+	if err := db.Create(&settings).Error; err != nil {
+		t.Fatalf("Failed to create settings: %v", err)
+	}
+
+	if sent, err := service.SendDue(); err != nil || sent {
+		t.Fatalf("Expected no digest before opting in, got %v (%v)", sent, err)
+	}
+
+	db.Model(&settings).Update("weekly_digest", true)
+	metrics.RequestsTotal.Inc("GET", "/digest-test", "200")
+	metrics.RequestsTotal.Inc("GET", "/digest-test", "500")
+
+	digest, err := service.Collect()
+	if err != nil {
+		t.Fatalf("Failed to collect digest: %v", err)
+	}
+	if digest.NewUsers != 1 || digest.TotalUsers != 2 || digest.AdminActions != 1 {
+		t.Errorf("Unexpected user counts: %+v", digest)
+	}
+	if digest.Requests != 2 || digest.ServerErrors != 1 || digest.ErrorRate != 0.5 {
+		t.Errorf("Unexpected request counts: %+v", digest)
+	}
+	if digest.DatabaseBytes <= 0 || digest.DatabaseGrowthBytes != 0 {
+		t.Errorf("Unexpected database size: %+v", digest)
+	}
+
+	if sent, err := service.SendDue(); err != nil || !sent {
+		t.Fatalf("Expected digest to be sent, got %v (%v)", sent, err)
+	}
+	if sent, err := service.SendDue(); err != nil || sent {
+		t.Errorf("Expected one digest per week, got %v (%v)", sent, err)
+	}
+
+	db.First(&settings, "id = ?", settings.ID)
+	if settings.DigestSentAt == nil || settings.DigestDatabaseBytes != digest.DatabaseBytes {
+		t.Errorf("Expected the digest to be recorded, got %+v", settings)
+	}
+	digest, err = service.Collect()
+	if err != nil {
+		t.Fatalf("Failed to collect digest: %v", err)
+	}
+	if digest.NewUsers != 0 || digest.Requests != 0 || !digest.From.Equal(*settings.DigestSentAt) {
+		t.Errorf("Expected counts to start over after sending, got %+v", digest)
+	}
+}
+
+func TestRender(t *testing.T) {
+	text := Render(&models.OperatorDigest{
+		From:                time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC),
+		Until:               time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC),
+		NewUsers:            3,
+		TotalUsers:          12,
+		EmailsSent:          40,
+		EmailsFailed:        2,
+		DatabaseBytes:       5 * 1024 * 1024,
+		DatabaseGrowthBytes: 512 * 1024,
+	})
+
+	for _, expected := range []string{
+		"from 2025-03-03 to 2025-03-10",
+		"Users: 3 new, 12 total",
+		"Database: 5.0 MiB (+512.0 KiB)",
+		"Emails: 40 sent, 2 failed",
+		"METRICS_ENABLED=true",
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected %q in digest:\n%s", expected, text)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package enrichment calls the outbound hook of a list for every new item and merges the price,
+// image and category it returns into the item. Hooks let power users look up store APIs without
+// changes to the server; they run in the background, so a slow or failing hook never delays or
+// fails adding items.
+package enrichment
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/catalog"
+	"github.com/oliverandrich/shopping-list-server/internal/lists"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when a list has no enrichment hook.
+var ErrNotFound = errors.New("enrichment hook not found")
+
+// ErrNotOwner is returned when a user other than a list owner manages the hook.
+var ErrNotOwner = errors.New("only list owners can manage the enrichment hook")
+
+// maxResponseSize limits how much of a hook's response is read.
+const maxResponseSize = 64 << 10
+
+// Service manages enrichment hooks and calls them for new items.
+type Service struct {
+	DB         *gorm.DB
+	Client     *http.Client
+	Dictionary *catalog.Dictionary
+}
+
+// NewService creates a new enrichment service. The dictionary, if any, provides the emoji of
+// categories returned by hooks.
+func NewService(db *gorm.DB, dictionary *catalog.Dictionary) *Service {
+	return &Service{
+		DB:         db,
+		Client:     &http.Client{Timeout: 10 * time.Second},
+		Dictionary: dictionary,
+	}
+}
+
+// GetHook retrieves the enrichment hook of the list if the user is an owner.
+func (s *Service) GetHook(listID, userID string) (*models.EnrichmentHook, error) {
+	if !s.isOwner(listID, userID) {
+		return nil, ErrNotOwner
+	}
+
+	var hook models.EnrichmentHook
+	if err := s.DB.First(&hook, "list_id = ?", listID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// SetHook sets the enrichment hook of the list to the http or https URL if the user is an owner.
+func (s *Service) SetHook(listID, userID, hookURL string) (*models.EnrichmentHook, error) {
+	if !s.isOwner(listID, userID) {
+		return nil, ErrNotOwner
+	}
+	if parsed, err := url.Parse(hookURL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, errors.New("hook URL must be an http or https URL")
+	}
+
+	hook := models.EnrichmentHook{ListID: listID, URL: hookURL, CreatedBy: userID}
+	var existing models.EnrichmentHook
+	if err := s.DB.First(&existing, "list_id = ?", listID).Error; err == nil {
+		hook.CreatedAt = existing.CreatedAt
+	}
+	if err := s.DB.Save(&hook).Error; err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// DeleteHook removes the enrichment hook of the list if the user is an owner.
+func (s *Service) DeleteHook(listID, userID string) error {
+	if !s.isOwner(listID, userID) {
+		return ErrNotOwner
+	}
+
+	result := s.DB.Where("list_id = ?", listID).Delete(&models.EnrichmentHook{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// isOwner reports whether the user is an owner or co-owner of the list.
+func (s *Service) isOwner(listID, userID string) bool {
+	var member models.ListMember
+	err := s.DB.Where("list_id = ? AND user_id = ? AND role IN ?", listID, userID, lists.OwnerRoles).First(&member).Error
+	return err == nil
+}
+
+// Enqueue enriches new items of a list in the background if the list has a hook. Failures are
+// logged, the items stay as they are.
+func (s *Service) Enqueue(listID string, items ...models.ShoppingItem) {
+	if len(items) == 0 {
+		return
+	}
+
+	var hook models.EnrichmentHook
+	if err := s.DB.First(&hook, "list_id = ?", listID).Error; err != nil {
+		return
+	}
+
+	go func() {
+		for _, item := range items {
+			if err := s.Enrich(hook.URL, item); err != nil {
+				slog.Warn("Failed to enrich item", "list", listID, "item", item.ID, "error", err)
+			}
+		}
+	}()
+}
+
+// Enrich posts the item to the hook URL and merges the returned metadata into the stored item.
+func (s *Service) Enrich(hookURL string, item models.ShoppingItem) error {
+	body, err := json.Marshal(models.EnrichmentRequest{
+		ItemID:   item.ID,
+		ListID:   item.ListID,
+		Name:     item.Name,
+		Quantity: item.Quantity,
+		Unit:     item.Unit,
+		Category: item.Category,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, hookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("hook responded with status %d", resp.StatusCode)
+	}
+
+	var metadata models.EnrichmentResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&metadata); err != nil {
+		return fmt.Errorf("invalid hook response: %w", err)
+	}
+
+	updates := s.updates(metadata)
+	if len(updates) == 0 {
+		return nil
+	}
+	updates["updated_at"] = time.Now()
+	return s.DB.Model(&models.ShoppingItem{}).Where("id = ?", item.ID).UpdateColumns(updates).Error
+}
+
+// updates returns the item columns to set from the hook's metadata, skipping invalid values.
+func (s *Service) updates(metadata models.EnrichmentResponse) map[string]any {
+	updates := map[string]any{}
+	if metadata.Price != nil && *metadata.Price >= 0 {
+		updates["price"] = *metadata.Price
+	}
+	if image, err := url.Parse(metadata.ImageURL); err == nil && (image.Scheme == "http" || image.Scheme == "https") {
+		updates["image_url"] = metadata.ImageURL
+	}
+	if metadata.Category != "" {
+		updates["category"] = metadata.Category
+		updates["emoji"] = ""
+		if s.Dictionary != nil {
+			updates["emoji"] = s.Dictionary.Emoji(metadata.Category)
+		}
+	}
+	return updates
+}
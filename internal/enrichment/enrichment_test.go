@@ -0,0 +1,131 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package enrichment
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/oliverandrich/shopping-list-server/internal/catalog"
+	"github.com/oliverandrich/shopping-list-server/internal/lists"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+	"gorm.io/gorm"
+)
+
+func setupList(t *testing.T) (*Service, *gorm.DB, *models.ShoppingList) {
+	t.Helper()
+	db := testutils.SetupTestDB(t)
+
+	owner := models.User{ID: "owner-id", Email: testutils.TestEmailAddress()}
+	if err := db.Create(&owner).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	list, err := lists.NewService(db).CreateList(owner.ID, "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	return NewService(db, catalog.DefaultDictionary()), db, list
+}
+
+func TestService_Hooks(t *testing.T) {
+	service, _, list := setupList(t)
+
+	if _, err := service.GetHook(list.ID, "owner-id"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if _, err := service.SetHook(list.ID, "stranger-id", "https://example.com/hook"); !errors.Is(err, ErrNotOwner) {
+		t.Errorf("Expected ErrNotOwner, got %v", err)
+	}
+	if _, err := service.SetHook(list.ID, "owner-id", "ftp://example.com/hook"); err == nil {
+		t.Error("Expected non-http URLs to be rejected")
+	}
+
+	if _, err := service.SetHook(list.ID, "owner-id", "https://example.com/hook"); err != nil {
+		t.Fatalf("Failed to set hook: %v", err)
+	}
+	if _, err := service.SetHook(list.ID, "owner-id", "https://example.com/other"); err != nil {
+		t.Fatalf("Failed to replace hook: %v", err)
+	}
+	hook, err := service.GetHook(list.ID, "owner-id")
+	if err != nil || hook.URL != "https://example.com/other" {
+		t.Errorf("Expected the replaced hook, got %+v, %v", hook, err)
+	}
+
+	if err := service.DeleteHook(list.ID, "owner-id"); err != nil {
+		t.Fatalf("Failed to delete hook: %v", err)
+	}
+	if err := service.DeleteHook(list.ID, "owner-id"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestService_Enrich(t *testing.T) {
+	service, db, list := setupList(t)
+
+	var received models.EnrichmentRequest
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("Failed to decode hook request: %v", err)
+		}
+		_, _ = w.Write([]byte(`{"price": 1.29, "image_url": "https://example.com/milk.jpg", "category": "Dairy"}`))
+	}))
+	defer hook.Close()
+
+	item := models.ShoppingItem{ID: "item-id", ListID: list.ID, Name: "Milk", Quantity: 2, Unit: "l", Tags: "[]"}
+	if err := db.Create(&item).Error; err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+
+	if err := service.Enrich(hook.URL, item); err != nil {
+		t.Fatalf("Failed to enrich item: %v", err)
+	}
+	if received.ItemID != item.ID || received.Name != "Milk" || received.Unit != "l" {
+		t.Errorf("Unexpected hook request %+v", received)
+	}
+
+	var stored models.ShoppingItem
+	db.First(&stored, "id = ?", item.ID)
+	if stored.Price == nil || *stored.Price != 1.29 || stored.ImageURL != "https://example.com/milk.jpg" || stored.Category != "Dairy" {
+		t.Errorf("Expected metadata to be merged, got %+v", stored)
+	}
+	if stored.Name != "Milk" || stored.Quantity != 2 {
+		t.Errorf("Expected other fields to stay unchanged, got %+v", stored)
+	}
+}
+
+func TestService_EnrichSkipsInvalidMetadata(t *testing.T) {
+	service, db, list := setupList(t)
+
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"price": -1, "image_url": "javascript:alert(1)"}`))
+	}))
+	defer hook.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+
+	item := models.ShoppingItem{ID: "item-id", ListID: list.ID, Name: "Milk", Category: "Dairy", Tags: "[]"}
+	if err := db.Create(&item).Error; err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+
+	if err := service.Enrich(hook.URL, item); err != nil {
+		t.Fatalf("Failed to enrich item: %v", err)
+	}
+	if err := service.Enrich(failing.URL, item); err == nil {
+		t.Error("Expected an error for a failing hook")
+	}
+
+	var stored models.ShoppingItem
+	db.First(&stored, "id = ?", item.ID)
+	if stored.Price != nil || stored.ImageURL != "" || stored.Category != "Dairy" {
+		t.Errorf("Expected the item to stay unchanged, got %+v", stored)
+	}
+}
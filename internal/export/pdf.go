@@ -0,0 +1,261 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package export renders shopping lists in printable formats, e.g. a PDF to pin to the fridge.
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/oliverandrich/shopping-list-server/internal/currency"
+	"github.com/oliverandrich/shopping-list-server/internal/items"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/quantity"
+)
+
+// A4 page layout in PDF points.
+const (
+	pageWidth  = 595.28
+	pageHeight = 841.89
+	margin     = 56.0
+
+	titleSize   = 22.0
+	headingSize = 14.0
+	itemSize    = 12.0
+	footerSize  = 9.0
+
+	itemLeading    = 20.0
+	headingLeading = 30.0
+	checkboxSize   = 10.0
+
+	// maxItemChars keeps item lines within the page width; Helvetica averages about half the
+	// font size per character.
+	maxItemChars = 80
+)
+
+// Object numbers of the fixed PDF objects; pages and their content streams follow.
+const (
+	catalogObject  = 1
+	pagesObject    = 2
+	fontObject     = 3
+	boldFontObject = 4
+	firstPageObj   = 5
+)
+
+// PDF writes a printable A4 document of the open items of a list, grouped by category with a
+// checkbox in front of each item. Completed items are left out. If the items were added by more
+// than one user, each item names who added it. Prices are shown in the currency of the list with
+// the separators of the preferred language of acceptLanguage, followed by their total.
+func PDF(w io.Writer, list models.ShoppingList, listItems []models.ShoppingItem, acceptLanguage string, now time.Time) error {
+	code := list.Currency
+	if code == "" {
+		code = currency.Default
+	}
+	formatPrice := func(price float64) string {
+		return currency.Format(price, code, acceptLanguage)
+	}
+	pages := layout(list.Name, listItems, formatPrice, now)
+
+	var doc pdfWriter
+	doc.header()
+	doc.object(catalogObject, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesObject))
+
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPageObj+2*i)
+	}
+	doc.object(pagesObject, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	doc.object(fontObject, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	doc.object(boldFontObject, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, content := range pages {
+		footer := fmt.Sprintf("BT /F1 %g Tf %g %g Td (%s) Tj ET\n", footerSize, margin, margin/2,
+			pdfString(fmt.Sprintf("Page %d of %d", i+1, len(pages))))
+		content += footer
+
+		pageObj := firstPageObj + 2*i
+		doc.object(pageObj, fmt.Sprintf(
+			"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %g %g] /Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> /Contents %d 0 R >>",
+			pagesObject, pageWidth, pageHeight, fontObject, boldFontObject, pageObj+1))
+		doc.object(pageObj+1, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+
+	doc.trailer()
+	_, err := w.Write(doc.buf.Bytes())
+	return err
+}
+
+// layout distributes the list over as many pages as needed and returns the content stream of
+// each page without its footer.
+func layout(title string, listItems []models.ShoppingItem, formatPrice func(float64) string, now time.Time) []string {
+	var pages []string
+	var page strings.Builder
+	y := pageHeight - margin
+
+	newPage := func() {
+		pages = append(pages, page.String())
+		page.Reset()
+		y = pageHeight - margin
+	}
+	// ensure starts a new page unless the given height fits above the bottom margin
+	ensure := func(height float64) {
+		if y-height < margin {
+			newPage()
+		}
+	}
+	text := func(font string, size, x float64, s string) {
+		fmt.Fprintf(&page, "BT /%s %g Tf %g %.2f Td (%s) Tj ET\n", font, size, x, y, pdfString(s))
+	}
+
+	y -= titleSize
+	text("F2", titleSize, margin, title)
+	y -= itemLeading
+	text("F1", footerSize, margin, now.Format("2006-01-02 15:04"))
+
+	showCreators := sharedItems(listItems)
+	groups, _ := items.GroupOpenByCategory(listItems)
+	if len(groups) == 0 {
+		y -= headingLeading
+		text("F1", itemSize, margin, "Nothing to buy.")
+	}
+
+	total, priced := 0.0, false
+	for _, group := range groups {
+		// Keep a heading together with its first item
+		ensure(headingLeading + itemLeading)
+		y -= headingLeading
+		text("F2", headingSize, margin, group.Category)
+
+		for _, item := range group.Items {
+			ensure(itemLeading)
+			y -= itemLeading
+			fmt.Fprintf(&page, "0.8 w %g %.2f %g %g re S\n", margin, y-1, checkboxSize, checkboxSize)
+
+			line := item.Name
+			if formatted := quantity.Format(item.Quantity, item.Unit); formatted != "" {
+				line += " (" + formatted + ")"
+			}
+			if item.Price != nil {
+				line += ", " + formatPrice(*item.Price)
+				total += *item.Price
+				priced = true
+			}
+			if showCreators && item.CreatedByName != "" {
+				line += " - " + item.CreatedByName
+			}
+			text("F1", itemSize, margin+checkboxSize+8, truncate(line, maxItemChars))
+		}
+	}
+
+	if priced {
+		ensure(headingLeading)
+		y -= headingLeading
+		text("F2", itemSize, margin, "Total: "+formatPrice(total))
+	}
+
+	pages = append(pages, page.String())
+	return pages
+}
+
+// sharedItems reports whether the items were added by more than one user.
+func sharedItems(listItems []models.ShoppingItem) bool {
+	creator := ""
+	for _, item := range listItems {
+		if item.CreatedByName == "" {
+			continue
+		}
+		if creator != "" && item.CreatedByName != creator {
+			return true
+		}
+		creator = item.CreatedByName
+	}
+	return false
+}
+
+// truncate shortens s to at most n characters, ending with an ellipsis if it was cut.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
+
+// winAnsiExtras maps characters outside Latin-1 to their WinAnsiEncoding code.
+var winAnsiExtras = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '‘': 0x91, '’': 0x92,
+	'“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99,
+}
+
+// pdfString encodes s as the content of a PDF literal string in WinAnsiEncoding. Characters
+// the standard fonts cannot show, such as emoji, are dropped.
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			if code, ok := winAnsiExtras[r]; ok {
+				fmt.Fprintf(&b, "\\%03o", code)
+			}
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// pdfWriter assembles a PDF file and keeps track of the object offsets for the xref table.
+type pdfWriter struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+func (p *pdfWriter) header() {
+	// The binary comment marks the file as containing 8-bit data
+	p.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+}
+
+// object writes an indirect object; objects must be written in ascending order starting at 1.
+func (p *pdfWriter) object(number int, body string) {
+	p.offsets = append(p.offsets, p.buf.Len())
+	fmt.Fprintf(&p.buf, "%d 0 obj\n%s\nendobj\n", number, body)
+}
+
+func (p *pdfWriter) trailer() {
+	xref := p.buf.Len()
+	fmt.Fprintf(&p.buf, "xref\n0 %d\n0000000000 65535 f \n", len(p.offsets)+1)
+	for _, offset := range p.offsets {
+		fmt.Fprintf(&p.buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&p.buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(p.offsets)+1, catalogObject, xref)
+}
+
+// FileName returns a file name for the export of a list, e.g. "weekly-groceries.pdf".
+func FileName(listName, extension string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(listName) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+
+	name := strings.TrimSuffix(b.String(), "-")
+	if name == "" {
+		name = "shopping-list"
+	}
+	return name + "." + extension
+}
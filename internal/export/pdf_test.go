@@ -0,0 +1,138 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+)
+
+func TestPDF(t *testing.T) {
+	var listItems []models.ShoppingItem
+	for i := range 60 {
+		listItems = append(listItems, models.ShoppingItem{Name: fmt.Sprintf("Item %02d", i), Category: "Dairy"})
+	}
+	listItems = append(listItems,
+		models.ShoppingItem{Name: "Käse (Gouda)", Quantity: 500, Unit: "g"},
+		models.ShoppingItem{Name: "Bread", Completed: true},
+	)
+
+	var buf bytes.Buffer
+	err := PDF(&buf, models.ShoppingList{Name: "Weekly"}, listItems, "", time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Failed to render PDF: %v", err)
+	}
+	pdf := buf.Bytes()
+
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatal("Expected PDF header and trailer")
+	}
+	if !bytes.Contains(pdf, []byte("/Count 2")) {
+		t.Error("Expected the list to span two pages")
+	}
+	if !bytes.Contains(pdf, []byte(`(K\344se \(Gouda\) \(500 g\))`)) {
+		t.Error("Expected escaped, WinAnsi-encoded item with quantity")
+	}
+	if bytes.Contains(pdf, []byte("Bread")) {
+		t.Error("Expected completed items to be left out")
+	}
+
+	// Every xref entry must point at its object
+	match := regexp.MustCompile(`startxref\n(\d+)`).FindSubmatch(pdf)
+	if match == nil {
+		t.Fatal("Expected startxref")
+	}
+	xref, _ := strconv.Atoi(string(match[1]))
+	entries := strings.Split(string(pdf[xref:]), "\n")[3:]
+	for i := 1; strings.HasSuffix(entries[i-1], " n "); i++ {
+		offset, _ := strconv.Atoi(entries[i-1][:10])
+		if !bytes.HasPrefix(pdf[offset:], fmt.Appendf(nil, "%d 0 obj", i)) {
+			t.Errorf("xref entry %d does not point at its object", i)
+		}
+	}
+}
+
+func TestPDF_Creators(t *testing.T) {
+	render := func(listItems ...models.ShoppingItem) []byte {
+		var buf bytes.Buffer
+		if err := PDF(&buf, models.ShoppingList{Name: "Weekly"}, listItems, "", time.Now()); err != nil {
+			t.Fatalf("Failed to render PDF: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	pdf := render(models.ShoppingItem{Name: "Milk", CreatedByName: "anna"}, models.ShoppingItem{Name: "Eggs", CreatedByName: "ben"})
+	if !bytes.Contains(pdf, []byte("(Milk - anna)")) || !bytes.Contains(pdf, []byte("(Eggs - ben)")) {
+		t.Error("Expected items of a shared list to name who added them")
+	}
+
+	pdf = render(models.ShoppingItem{Name: "Milk", CreatedByName: "anna"}, models.ShoppingItem{Name: "Eggs", CreatedByName: "anna"})
+	if !bytes.Contains(pdf, []byte("(Milk)")) {
+		t.Error("Expected no names when a single user added all items")
+	}
+}
+
+func TestPDF_Prices(t *testing.T) {
+	price := func(value float64) *float64 { return &value }
+	listItems := []models.ShoppingItem{
+		{Name: "Milk", Price: price(1.29)},
+		{Name: "Cheese", Quantity: 500, Unit: "g", Price: price(4.5)},
+		{Name: "Salt"},
+	}
+
+	var buf bytes.Buffer
+	if err := PDF(&buf, models.ShoppingList{Name: "Weekly", Currency: "CHF"}, listItems, "de-CH", time.Now()); err != nil {
+		t.Fatalf("Failed to render PDF: %v", err)
+	}
+	pdf := buf.Bytes()
+	if !bytes.Contains(pdf, []byte("(Milk, 1,29 CHF)")) || !bytes.Contains(pdf, []byte(`(Cheese \(500 g\), 4,50 CHF)`)) {
+		t.Error("Expected prices in the list's currency, formatted for the language")
+	}
+	if !bytes.Contains(pdf, []byte("(Salt)")) || !bytes.Contains(pdf, []byte("(Total: 5,79 CHF)")) {
+		t.Error("Expected the total of the prices")
+	}
+
+	buf.Reset()
+	if err := PDF(&buf, models.ShoppingList{Name: "Weekly"}, listItems[2:], "en", time.Now()); err != nil {
+		t.Fatalf("Failed to render PDF: %v", err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("Total")) {
+		t.Error("Expected no total without prices")
+	}
+}
+
+func TestPDFString(t *testing.T) {
+	testCases := map[string]string{
+		"Milk":         "Milk",
+		`a (b) \ c`:    `a \(b\) \\ c`,
+		"Crème – 2 €":  `Cr\350me \226 2 \200`,
+		"🧀 Cheese":     "Cheese",
+		"Eggs 🥚 Bacon": "Eggs Bacon",
+	}
+	for input, expected := range testCases {
+		if got := pdfString(input); got != expected {
+			t.Errorf("pdfString(%q) = %q; want %q", input, got, expected)
+		}
+	}
+}
+
+func TestFileName(t *testing.T) {
+	testCases := map[string]string{
+		"Weekly Groceries!": "weekly-groceries.pdf",
+		"  DIY / Garden ":   "diy-garden.pdf",
+		"🛒":                 "shopping-list.pdf",
+	}
+	for input, expected := range testCases {
+		if got := FileName(input, "pdf"); got != expected {
+			t.Errorf("FileName(%q) = %q; want %q", input, got, expected)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package extensions lets the admin customize the server without forking it. At extension points
+// such as item creation, login or invitation, the server emits an event to the hook configured for
+// it: an http or https URL receives the event as JSON POST, anything else is run as shell command
+// with the event on stdin. Hooks run in the background with a timeout; a failing hook is logged
+// and never affects the request that emitted the event.
+package extensions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Extension points.
+const (
+	EventItemCreated       = "item.created"
+	EventLogin             = "login"
+	EventInvitationCreated = "invitation.created"
+)
+
+// DefaultTimeout bounds how long a hook may run unless configured otherwise.
+const DefaultTimeout = 10 * time.Second
+
+// maxOutput limits how much output of a failing hook is kept for the log.
+const maxOutput = 4 << 10
+
+// Event is the JSON document a hook receives.
+type Event struct {
+	Event      string    `json:"event"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       any       `json:"data"`
+}
+
+// Service runs the hooks configured for extension points.
+type Service struct {
+	// Hooks maps extension points to a URL or shell command. Points without a hook are skipped.
+	Hooks   map[string]string
+	Timeout time.Duration
+	// Dir is the working directory of hook commands, the system's temporary directory if empty.
+	Dir    string
+	Client *http.Client
+}
+
+// NewService creates an extensions service without any hooks.
+func NewService() *Service {
+	return &Service{
+		Hooks:   map[string]string{},
+		Timeout: DefaultTimeout,
+		Client:  &http.Client{},
+	}
+}
+
+// Emit runs the hook of the extension point in the background, if one is configured.
+func (s *Service) Emit(event string, data any) {
+	if s.Hooks[event] == "" {
+		return
+	}
+
+	go func() {
+		if err := s.Run(event, data); err != nil {
+			slog.Warn("Extension hook failed", "event", event, "error", err)
+		}
+	}()
+}
+
+// Run runs the hook of the extension point and waits for it to finish.
+func (s *Service) Run(event string, data any) error {
+	hook := s.Hooks[event]
+	if hook == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(Event{Event: event, OccurredAt: time.Now().UTC(), Data: data})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.Timeout)
+	defer cancel()
+
+	if strings.HasPrefix(hook, "http://") || strings.HasPrefix(hook, "https://") {
+		return s.post(ctx, hook, body)
+	}
+	return s.exec(ctx, hook, event, body)
+}
+
+// post sends the event to a hook URL, which has to answer with a 2xx status.
+func (s *Service) post(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxOutput))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("hook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// exec runs a hook command with the event on stdin. The command does not inherit the server's
+// environment, which holds secrets such as JWT_SECRET, only PATH and the name of the event in
+// HOOK_EVENT.
+func (s *Service) exec(ctx context.Context, command, event string, body []byte) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = []string{"PATH=" + os.Getenv("PATH"), "HOOK_EVENT=" + event}
+	cmd.Dir = s.Dir
+	if cmd.Dir == "" {
+		cmd.Dir = os.TempDir()
+	}
+	cmd.Stdin = bytes.NewReader(body)
+	// Children of the shell may keep its output open after it was killed
+	cmd.WaitDelay = time.Second
+
+	var output limitedBuffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("hook timed out after %s", s.Timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(output.String()))
+	}
+	return nil
+}
+
+// limitedBuffer keeps the first maxOutput bytes written to it and discards the rest.
+type limitedBuffer struct {
+	bytes.Buffer
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := maxOutput - b.Len(); remaining > 0 {
+		b.Buffer.Write(p[:min(len(p), remaining)])
+	}
+	return len(p), nil
+}
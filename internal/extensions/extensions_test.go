@@ -0,0 +1,88 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package extensions
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestService_RunHTTP(t *testing.T) {
+	var received Event
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("Failed to decode event: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer hook.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	service := NewService()
+	service.Hooks[EventLogin] = hook.URL
+	service.Hooks[EventItemCreated] = failing.URL
+
+	if err := service.Run(EventLogin, map[string]string{"user_id": "alice"}); err != nil {
+		t.Fatalf("Failed to run hook: %v", err)
+	}
+	if received.Event != EventLogin || received.Data.(map[string]any)["user_id"] != "alice" {
+		t.Errorf("Unexpected event %+v", received)
+	}
+
+	if err := service.Run(EventItemCreated, nil); err == nil {
+		t.Error("Expected an error for a failing hook")
+	}
+	if err := service.Run(EventInvitationCreated, nil); err != nil {
+		t.Errorf("Expected extension points without hook to be skipped, got %v", err)
+	}
+}
+
+func TestService_RunCommand(t *testing.T) {
+	t.Setenv("JWT_SECRET", "top-secret")
+	dir := t.TempDir()
+	output := filepath.Join(dir, "event.json")
+
+	service := NewService()
+	service.Dir = dir
+	service.Hooks[EventItemCreated] = `cat > event.json; echo "$HOOK_EVENT $JWT_SECRET" >> event.json`
+
+	if err := service.Run(EventItemCreated, map[string]string{"name": "Milk"}); err != nil {
+		t.Fatalf("Failed to run hook: %v", err)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Failed to read hook output: %v", err)
+	}
+	if !strings.Contains(string(data), `"name":"Milk"`) {
+		t.Errorf("Expected the event on stdin, got %s", data)
+	}
+	if !strings.HasSuffix(strings.TrimSpace(string(data)), EventItemCreated) {
+		t.Errorf("Expected HOOK_EVENT without the server's environment, got %s", data)
+	}
+
+	service.Hooks[EventLogin] = "echo broken >&2; exit 3"
+	if err := service.Run(EventLogin, nil); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("Expected the command's output in the error, got %v", err)
+	}
+
+	service.Timeout = 100 * time.Millisecond
+	service.Hooks[EventLogin] = "sleep 5"
+	start := time.Now()
+	if err := service.Run(EventLogin, nil); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expected a timeout, got %v", err)
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Error("Expected the hook to be killed on timeout")
+	}
+}
@@ -0,0 +1,146 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package fairness compares what the members of a shared list contribute: the items they add,
+// the items they check off and the shopping trips they take, to settle who really does the
+// shopping.
+package fairness
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/items"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// DefaultDays is the period a report covers unless the request asks for another one, and
+// MaxDays the longest period it may ask for.
+const (
+	DefaultDays = 90
+	MaxDays     = 365
+)
+
+// Badges awarded to the members leading a category. Ties award the badge to every leader.
+const (
+	BadgeTopShopper = "top_shopper"
+	BadgeListMaker  = "list_maker"
+	BadgeTripTaker  = "trip_taker"
+)
+
+// ErrInvalidDays is returned for periods outside 1 to MaxDays days.
+var ErrInvalidDays = errors.New("days must be between 1 and 365")
+
+// Service computes fairness reports.
+type Service struct {
+	DB *gorm.DB
+}
+
+// NewService creates a new fairness service.
+func NewService(db *gorm.DB) *Service {
+	return &Service{DB: db}
+}
+
+// Report compares the contributions of the members of a list within the last days days. Items
+// count when they were added or checked off within the period and are still on the list; trips
+// are counted per calendar day in the server's time zone.
+func (s *Service) Report(listID string, days int, now time.Time) (*models.FairnessReport, error) {
+	if days < 1 || days > MaxDays {
+		return nil, ErrInvalidDays
+	}
+	since := now.AddDate(0, 0, -days)
+
+	var members []models.User
+	err := s.DB.Select("users.id", "users.email").
+		Joins("JOIN list_members ON list_members.user_id = users.id").
+		Where("list_members.list_id = ?", listID).
+		Order("list_members.joined_at ASC").
+		Find(&members).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var added []models.ShoppingItem
+	err = s.DB.Select("created_by").
+		Where("list_id = ? AND created_by IS NOT NULL AND created_at >= ?", listID, since).
+		Find(&added).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var completed []models.ShoppingItem
+	err = s.DB.Select("completed_by", "completed_at").
+		Where("list_id = ? AND completed = ? AND completed_by IS NOT NULL AND completed_at >= ?", listID, true, since).
+		Find(&completed).Error
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.FairnessReport{
+		ListID:  listID,
+		Days:    days,
+		Since:   since,
+		Members: make([]models.MemberContribution, 0, len(members)),
+	}
+	byUser := make(map[string]int, len(members))
+	for i, member := range members {
+		byUser[member.ID] = i
+		report.Members = append(report.Members, models.MemberContribution{
+			UserID: member.ID,
+			Name:   items.DisplayName(member.Email),
+			Badges: []string{},
+		})
+	}
+
+	for _, item := range added {
+		if i, ok := byUser[*item.CreatedBy]; ok {
+			report.Members[i].ItemsAdded++
+		}
+	}
+
+	tripDays := make(map[string]map[string]bool, len(members))
+	total := 0
+	for _, item := range completed {
+		i, ok := byUser[*item.CompletedBy]
+		if !ok {
+			continue
+		}
+		report.Members[i].ItemsCompleted++
+		total++
+		if tripDays[*item.CompletedBy] == nil {
+			tripDays[*item.CompletedBy] = map[string]bool{}
+		}
+		tripDays[*item.CompletedBy][item.CompletedAt.Local().Format(time.DateOnly)] = true
+	}
+
+	for i := range report.Members {
+		member := &report.Members[i]
+		member.Trips = len(tripDays[member.UserID])
+		if total > 0 {
+			member.CompletedShare = math.Round(float64(member.ItemsCompleted)/float64(total)*1000) / 10
+		}
+	}
+	award(report.Members, BadgeTopShopper, func(m models.MemberContribution) int { return m.ItemsCompleted })
+	award(report.Members, BadgeListMaker, func(m models.MemberContribution) int { return m.ItemsAdded })
+	award(report.Members, BadgeTripTaker, func(m models.MemberContribution) int { return m.Trips })
+
+	return report, nil
+}
+
+// award gives the badge to the members with the highest count, unless nobody counts anything.
+func award(members []models.MemberContribution, badge string, count func(models.MemberContribution) int) {
+	best := 0
+	for _, member := range members {
+		best = max(best, count(member))
+	}
+	if best == 0 {
+		return
+	}
+	for i := range members {
+		if count(members[i]) == best {
+			members[i].Badges = append(members[i].Badges, badge)
+		}
+	}
+}
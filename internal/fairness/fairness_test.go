@@ -0,0 +1,89 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package fairness
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/lists"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+)
+
+func TestService_Report(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+	listService := lists.NewService(db)
+
+	users := []models.User{
+		{ID: "alice-id", Email: "alice@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()},
+		{ID: "bob-id", Email: "bob@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()},
+	}
+	if err := db.Create(&users).Error; err != nil {
+		t.Fatalf("Failed to create users: %v", err)
+	}
+	list, err := listService.CreateList("alice-id", "Household")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	if err := listService.JoinList(list.ID, "bob-id"); err != nil {
+		t.Fatalf("Failed to join list: %v", err)
+	}
+
+	now := time.Date(2025, 6, 30, 12, 0, 0, 0, time.Local)
+	alice, bob := "alice-id", "bob-id"
+	at := func(days int, hours int) *time.Time {
+		when := now.AddDate(0, 0, -days).Add(time.Duration(hours) * time.Hour)
+		return &when
+	}
+	items := []models.ShoppingItem{
+		{ID: "milk", Name: "Milk", CreatedBy: &alice, CreatedAt: *at(10, 0), Completed: true, CompletedBy: &bob, CompletedAt: at(9, 0)},
+		{ID: "bread", Name: "Bread", CreatedBy: &alice, CreatedAt: *at(10, 0), Completed: true, CompletedBy: &bob, CompletedAt: at(9, 1)},
+		{ID: "eggs", Name: "Eggs", CreatedBy: &alice, CreatedAt: *at(5, 0), Completed: true, CompletedBy: &bob, CompletedAt: at(2, 0)},
+		{ID: "coffee", Name: "Coffee", CreatedBy: &bob, CreatedAt: *at(5, 0), Completed: true, CompletedBy: &alice, CompletedAt: at(1, 0)},
+		{ID: "tea", Name: "Tea", CreatedBy: &alice, CreatedAt: *at(1, 0)},
+		{ID: "old", Name: "Flour", CreatedBy: &bob, CreatedAt: *at(200, 0), Completed: true, CompletedBy: &bob, CompletedAt: at(199, 0)},
+	}
+	for i := range items {
+		items[i].ListID, items[i].Tags = list.ID, "[]"
+	}
+	if err := db.Create(&items).Error; err != nil {
+		t.Fatalf("Failed to create items: %v", err)
+	}
+
+	report, err := service.Report(list.ID, DefaultDays, now)
+	if err != nil {
+		t.Fatalf("Failed to get report: %v", err)
+	}
+	if len(report.Members) != 2 {
+		t.Fatalf("Expected both members in the report, got %+v", report.Members)
+	}
+	byName := map[string]models.MemberContribution{}
+	for _, member := range report.Members {
+		byName[member.Name] = member
+	}
+
+	if got := byName["alice"]; got.ItemsAdded != 4 || got.ItemsCompleted != 1 || got.Trips != 1 || got.CompletedShare != 25 {
+		t.Errorf("Unexpected contribution of alice: %+v", got)
+	}
+	if got := byName["bob"]; got.ItemsAdded != 1 || got.ItemsCompleted != 3 || got.Trips != 2 || got.CompletedShare != 75 {
+		t.Errorf("Unexpected contribution of bob: %+v", got)
+	}
+	if badges := byName["alice"].Badges; !slices.Equal(badges, []string{BadgeListMaker}) {
+		t.Errorf("Expected alice to be the list maker, got %v", badges)
+	}
+	if badges := byName["bob"].Badges; !slices.Equal(badges, []string{BadgeTopShopper, BadgeTripTaker}) {
+		t.Errorf("Expected bob to be top shopper and trip taker, got %v", badges)
+	}
+
+	if _, err := service.Report(list.ID, 0, now); !errors.Is(err, ErrInvalidDays) {
+		t.Errorf("Expected invalid days to be rejected, got %v", err)
+	}
+	if _, err := service.Report(list.ID, MaxDays+1, now); !errors.Is(err, ErrInvalidDays) {
+		t.Errorf("Expected too many days to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,275 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package federation moves lists between server instances as signed bundles. A bundle holds a
+// list with its sections, tag metadata, items and the email addresses of its members; it is
+// signed with a key shared by the instances, so an instance only imports bundles exported by
+// another instance of the same operator, e.g. when moving to a new server.
+package federation
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oliverandrich/shopping-list-server/internal/currency"
+	"github.com/oliverandrich/shopping-list-server/internal/lists"
+	"github.com/oliverandrich/shopping-list-server/internal/mail"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// BundleVersion is the version of the bundle format written by Export.
+const BundleVersion = 1
+
+// ErrNotOwner is returned when a user exports a list they do not own.
+var ErrNotOwner = errors.New("only list owners can export lists")
+
+// ErrNotFound is returned when the list does not exist.
+var ErrNotFound = errors.New("list not found")
+
+// ErrInvalidBundle is returned for bundles with an unknown version, a wrong signature or
+// malformed content.
+var ErrInvalidBundle = errors.New("invalid list bundle")
+
+// Service exports and imports list bundles.
+type Service struct {
+	DB *gorm.DB
+	// Key signs and verifies bundles; federation is disabled without it.
+	Key string
+}
+
+// NewService creates a new federation service. It is disabled until a key is set.
+func NewService(db *gorm.DB) *Service {
+	return &Service{DB: db}
+}
+
+// Enabled reports whether a federation key is configured.
+func (s *Service) Enabled() bool {
+	return s.Key != ""
+}
+
+// Export bundles the list for import on another instance. Attachments are not exported.
+func (s *Service) Export(listID, userID string) (*models.ListBundle, error) {
+	var list models.ShoppingList
+	if err := s.DB.First(&list, "id = ?", listID).Error; err != nil {
+		return nil, ErrNotFound
+	}
+
+	var count int64
+	s.DB.Model(&models.ListMember{}).Where("list_id = ? AND user_id = ? AND role IN ?", listID, userID, lists.OwnerRoles).Count(&count)
+	if count == 0 {
+		return nil, ErrNotOwner
+	}
+
+	export := models.ListExport{
+		Name:           list.Name,
+		StaleAfterDays: list.StaleAfterDays,
+		Currency:       list.Currency,
+		ExportedAt:     time.Now(),
+		Sections:       []models.ExportedSection{},
+		Tags:           []models.ExportedTag{},
+		Items:          []models.ExportedItem{},
+		Members:        []models.ExportedMember{},
+	}
+
+	var sections []models.ListSection
+	if err := s.DB.Where("list_id = ?", listID).Order("position ASC").Find(&sections).Error; err != nil {
+		return nil, err
+	}
+	sectionNames := make(map[string]string, len(sections))
+	for _, section := range sections {
+		sectionNames[section.ID] = section.Name
+		export.Sections = append(export.Sections, models.ExportedSection{Name: section.Name, Position: section.Position})
+	}
+
+	var tags []models.ListTag
+	if err := s.DB.Where("list_id = ?", listID).Order("position ASC").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		export.Tags = append(export.Tags, models.ExportedTag{Name: tag.Name, Color: tag.Color, Icon: tag.Icon, Position: tag.Position})
+	}
+
+	var listItems []models.ShoppingItem
+	if err := s.DB.Where("list_id = ?", listID).Order("created_at ASC").Find(&listItems).Error; err != nil {
+		return nil, err
+	}
+	for _, item := range listItems {
+		exported := models.ExportedItem{
+			Name:         item.Name,
+			Completed:    item.Completed,
+			Tags:         item.Tags,
+			Category:     item.Category,
+			Emoji:        item.Emoji,
+			Quantity:     item.Quantity,
+			Unit:         item.Unit,
+			SnoozedUntil: item.SnoozedUntil,
+			DueDate:      item.DueDate,
+			CompletedAt:  item.CompletedAt,
+			Price:        item.Price,
+			ImageURL:     item.ImageURL,
+			CreatedAt:    item.CreatedAt,
+		}
+		if item.SectionID != nil {
+			exported.Section = sectionNames[*item.SectionID]
+		}
+		export.Items = append(export.Items, exported)
+	}
+
+	var members []models.ListMember
+	if err := s.DB.Where("list_id = ?", listID).Order("joined_at ASC").Find(&members).Error; err != nil {
+		return nil, err
+	}
+	for _, member := range members {
+		var user models.User
+		if err := s.DB.First(&user, "id = ?", member.UserID).Error; err != nil {
+			continue
+		}
+		export.Members = append(export.Members, models.ExportedMember{Email: user.Email, Role: member.Role})
+	}
+
+	payload, err := json.Marshal(export)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ListBundle{
+		Version:   BundleVersion,
+		Payload:   base64.StdEncoding.EncodeToString(payload),
+		Signature: base64.StdEncoding.EncodeToString(s.sign(payload)),
+	}, nil
+}
+
+// Verify checks the version and signature of a bundle and returns its content.
+func (s *Service) Verify(bundle *models.ListBundle) (*models.ListExport, error) {
+	if bundle.Version != BundleVersion {
+		return nil, ErrInvalidBundle
+	}
+	payload, err := base64.StdEncoding.DecodeString(bundle.Payload)
+	if err != nil {
+		return nil, ErrInvalidBundle
+	}
+	signature, err := base64.StdEncoding.DecodeString(bundle.Signature)
+	if err != nil || !hmac.Equal(signature, s.sign(payload)) {
+		return nil, ErrInvalidBundle
+	}
+
+	var export models.ListExport
+	if err := json.Unmarshal(payload, &export); err != nil || strings.TrimSpace(export.Name) == "" {
+		return nil, ErrInvalidBundle
+	}
+	return &export, nil
+}
+
+// Import creates the list of a bundle owned by the user. It returns the list and the members
+// of the bundle to invite to it again, which leaves out the user.
+func (s *Service) Import(userID string, bundle *models.ListBundle) (*models.ShoppingList, []models.ExportedMember, error) {
+	export, err := s.Verify(bundle)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var user models.User
+	if err := s.DB.First(&user, "id = ?", userID).Error; err != nil {
+		return nil, nil, errors.New("user not found")
+	}
+
+	// Bundles of older instances have no currency, their lists keep the default one
+	code, _ := currency.Normalize(export.Currency)
+
+	now := time.Now()
+	list := models.ShoppingList{
+		ID:             uuid.New().String(),
+		Name:           strings.TrimSpace(export.Name),
+		OwnerID:        userID,
+		StaleAfterDays: export.StaleAfterDays,
+		Currency:       code,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&list).Error; err != nil {
+			return err
+		}
+		// Saving zero explicitly, since the column defaults to 14 days
+		if err := tx.Model(&list).Update("stale_after_days", export.StaleAfterDays).Error; err != nil {
+			return err
+		}
+		owner := models.ListMember{ListID: list.ID, UserID: userID, Role: lists.RoleOwner, JoinedAt: now}
+		if err := tx.Create(&owner).Error; err != nil {
+			return err
+		}
+
+		sectionIDs := make(map[string]string, len(export.Sections))
+		for _, exported := range export.Sections {
+			section := models.ListSection{ID: uuid.New().String(), ListID: list.ID, Name: exported.Name, Position: exported.Position, CreatedAt: now}
+			if err := tx.Create(&section).Error; err != nil {
+				return err
+			}
+			sectionIDs[exported.Name] = section.ID
+		}
+
+		for _, exported := range export.Tags {
+			tag := models.ListTag{ListID: list.ID, Name: exported.Name, Color: exported.Color, Icon: exported.Icon, Position: exported.Position, CreatedAt: now}
+			if err := tx.Create(&tag).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, exported := range export.Items {
+			item := models.ShoppingItem{
+				ID:           uuid.New().String(),
+				ListID:       list.ID,
+				Name:         exported.Name,
+				Completed:    exported.Completed,
+				Tags:         exported.Tags,
+				Category:     exported.Category,
+				Emoji:        exported.Emoji,
+				Quantity:     exported.Quantity,
+				Unit:         exported.Unit,
+				SnoozedUntil: exported.SnoozedUntil,
+				DueDate:      exported.DueDate,
+				CompletedAt:  exported.CompletedAt,
+				Price:        exported.Price,
+				ImageURL:     exported.ImageURL,
+				CreatedAt:    exported.CreatedAt,
+			}
+			if item.Tags == "" {
+				item.Tags = "[]"
+			}
+			if id, ok := sectionIDs[exported.Section]; ok {
+				item.SectionID = &id
+			}
+			if err := tx.Create(&item).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	invite := []models.ExportedMember{}
+	for _, member := range export.Members {
+		if mail.NormalizeAddress(member.Email) != mail.NormalizeAddress(user.Email) {
+			invite = append(invite, member)
+		}
+	}
+
+	s.DB.Preload("Owner").First(&list, "id = ?", list.ID)
+	return &list, invite, nil
+}
+
+// sign returns the HMAC-SHA256 of the payload with the federation key.
+func (s *Service) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(s.Key))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
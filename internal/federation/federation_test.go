@@ -0,0 +1,89 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package federation
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+)
+
+func TestService_ExportImport(t *testing.T) {
+	source := NewService(testutils.SetupTestDB(t))
+	source.Key = "federation-test-key"
+	target := NewService(testutils.SetupTestDB(t))
+	target.Key = source.Key
+
+	owner := models.User{ID: "owner", Email: "owner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	partner := models.User{ID: "partner", Email: "partner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	source.DB.Create(&owner)
+	source.DB.Create(&partner)
+	source.DB.Create(&models.ShoppingList{ID: "list", Name: "Groceries", OwnerID: owner.ID, StaleAfterDays: 0})
+	source.DB.Model(&models.ShoppingList{}).Where("id = ?", "list").Update("stale_after_days", 0)
+	source.DB.Create(&models.ListMember{ListID: "list", UserID: owner.ID, Role: "owner", JoinedAt: time.Now()})
+	source.DB.Create(&models.ListMember{ListID: "list", UserID: partner.ID, Role: "member", JoinedAt: time.Now()})
+	source.DB.Create(&models.ListSection{ID: "produce", ListID: "list", Name: "Produce", Position: 1})
+	source.DB.Create(&models.ListTag{ListID: "list", Name: "organic", Color: "#00ff00"})
+	sectionID := "produce"
+	source.DB.Create(&models.ShoppingItem{ID: "apples", ListID: "list", Name: "Apples", Quantity: 6, SectionID: &sectionID, Tags: `["organic"]`, CreatedAt: time.Now()})
+	source.DB.Create(&models.ShoppingItem{ID: "bread", ListID: "list", Name: "Bread", Completed: true, Tags: "[]", CreatedAt: time.Now()})
+
+	if _, err := source.Export("list", partner.ID); !errors.Is(err, ErrNotOwner) {
+		t.Errorf("Expected ErrNotOwner for a member, got %v", err)
+	}
+	if _, err := source.Export("missing", owner.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	bundle, err := source.Export("list", owner.ID)
+	if err != nil {
+		t.Fatalf("Failed to export list: %v", err)
+	}
+
+	t.Run("rejects tampered bundles", func(t *testing.T) {
+		tampered := *bundle
+		tampered.Payload = "e30=" + tampered.Payload[4:]
+		if _, err := target.Verify(&tampered); !errors.Is(err, ErrInvalidBundle) {
+			t.Errorf("Expected ErrInvalidBundle, got %v", err)
+		}
+
+		other := NewService(target.DB)
+		other.Key = "another-key"
+		if _, err := other.Verify(bundle); !errors.Is(err, ErrInvalidBundle) {
+			t.Errorf("Expected a bundle signed with another key to be rejected, got %v", err)
+		}
+	})
+
+	importer := models.User{ID: "importer", Email: "OWNER@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	target.DB.Create(&importer)
+
+	list, invite, err := target.Import(importer.ID, bundle)
+	if err != nil {
+		t.Fatalf("Failed to import list: %v", err)
+	}
+	if list.Name != "Groceries" || list.OwnerID != importer.ID || list.StaleAfterDays != 0 {
+		t.Errorf("Unexpected imported list: %+v", list)
+	}
+	if len(invite) != 1 || invite[0].Email != partner.Email {
+		t.Errorf("Expected only the partner to be invited again, got %+v", invite)
+	}
+
+	var items []models.ShoppingItem
+	target.DB.Where("list_id = ?", list.ID).Order("name").Find(&items)
+	if len(items) != 2 || items[0].Name != "Apples" || items[0].Quantity != 6 || !items[1].Completed {
+		t.Fatalf("Unexpected imported items: %+v", items)
+	}
+	var section models.ListSection
+	target.DB.First(&section, "list_id = ?", list.ID)
+	if items[0].SectionID == nil || *items[0].SectionID != section.ID || section.Name != "Produce" {
+		t.Error("Expected the item to keep its section")
+	}
+	var tag models.ListTag
+	if err := target.DB.First(&tag, "list_id = ? AND name = ?", list.ID, "organic").Error; err != nil || tag.Color != "#00ff00" {
+		t.Error("Expected the tag metadata to be imported")
+	}
+}
@@ -0,0 +1,165 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package flags provides feature flags the admin uses to roll out client features gradually.
+// A flag is on for a percentage of users, chosen stably by user ID, and can be forced on or off
+// for single users.
+package flags
+
+import (
+	"errors"
+	"hash/fnv"
+	"regexp"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when a flag or override does not exist.
+var ErrNotFound = errors.New("feature flag not found")
+
+// ErrInvalidKey is returned for flag keys other than lowercase words joined by underscores.
+var ErrInvalidKey = errors.New("flag key must be lowercase letters, digits and underscores, e.g. price_tracking")
+
+var keyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Service manages feature flags and evaluates them for users.
+type Service struct {
+	DB *gorm.DB
+}
+
+// NewService creates a new feature flag service.
+func NewService(db *gorm.DB) *Service {
+	return &Service{DB: db}
+}
+
+// List retrieves all flags with their user overrides, ordered by key.
+func (s *Service) List() ([]models.FeatureFlag, error) {
+	var flags []models.FeatureFlag
+	err := s.DB.Preload("Overrides").Order("key ASC").Find(&flags).Error
+	return flags, err
+}
+
+// Set creates or updates a flag.
+func (s *Service) Set(key string, rollout int, description string) (*models.FeatureFlag, error) {
+	if !keyPattern.MatchString(key) {
+		return nil, ErrInvalidKey
+	}
+
+	flag := models.FeatureFlag{Key: key, CreatedAt: time.Now()}
+	if err := s.DB.Where("key = ?", key).FirstOrInit(&flag).Error; err != nil {
+		return nil, err
+	}
+	flag.Rollout = rollout
+	flag.Description = description
+	flag.UpdatedAt = time.Now()
+
+	if err := s.DB.Save(&flag).Error; err != nil {
+		return nil, err
+	}
+	return s.get(key)
+}
+
+// Delete removes a flag and its overrides, and returns the number of overrides removed.
+func (s *Service) Delete(key string) (int64, error) {
+	var overrides int64
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		deleted := tx.Where("flag_key = ?", key).Delete(&models.FeatureFlagOverride{})
+		if deleted.Error != nil {
+			return deleted.Error
+		}
+		overrides = deleted.RowsAffected
+
+		result := tx.Where("key = ?", key).Delete(&models.FeatureFlag{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return overrides, nil
+}
+
+// SetOverride forces a flag on or off for a user, regardless of the rollout.
+func (s *Service) SetOverride(key, userID string, enabled bool) (*models.FeatureFlagOverride, error) {
+	if _, err := s.get(key); err != nil {
+		return nil, err
+	}
+
+	override := models.FeatureFlagOverride{FlagKey: key, UserID: userID, Enabled: enabled, CreatedAt: time.Now()}
+	if err := s.DB.Save(&override).Error; err != nil {
+		return nil, err
+	}
+	return &override, nil
+}
+
+// DeleteOverride lets the rollout decide the flag for the user again.
+func (s *Service) DeleteOverride(key, userID string) error {
+	result := s.DB.Where("flag_key = ? AND user_id = ?", key, userID).Delete(&models.FeatureFlagOverride{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ForUser evaluates all flags for the user. Without a user, e.g. for clients that are not logged
+// in, only flags rolled out to everyone are on.
+func (s *Service) ForUser(userID string) (map[string]bool, error) {
+	flags, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]bool, len(flags))
+	for _, flag := range flags {
+		result[flag.Key] = Enabled(flag, userID)
+	}
+	return result, nil
+}
+
+// Enabled reports whether the flag is on for the user: an override decides, otherwise the user
+// is in the rollout when their bucket, derived from the flag key and user ID, is below it.
+func Enabled(flag models.FeatureFlag, userID string) bool {
+	for _, override := range flag.Overrides {
+		if userID != "" && override.UserID == userID {
+			return override.Enabled
+		}
+	}
+
+	switch {
+	case flag.Rollout >= 100:
+		return true
+	case flag.Rollout <= 0 || userID == "":
+		return false
+	}
+	return bucket(flag.Key, userID) < flag.Rollout
+}
+
+// bucket assigns the user a stable number from 0 to 99 per flag, so raising the rollout only adds
+// users and each flag reaches a different subset of them.
+func bucket(key, userID string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key + ":" + userID))
+	return int(h.Sum32() % 100)
+}
+
+func (s *Service) get(key string) (*models.FeatureFlag, error) {
+	var flag models.FeatureFlag
+	err := s.DB.Preload("Overrides").Where("key = ?", key).First(&flag).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &flag, nil
+}
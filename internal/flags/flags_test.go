@@ -0,0 +1,109 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package flags
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+)
+
+func TestService_Flags(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+
+	if _, err := service.Set("Price Tracking", 50, ""); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey, got %v", err)
+	}
+
+	if _, err := service.Set("price_tracking", 0, "Price tracking"); err != nil {
+		t.Fatalf("Failed to set flag: %v", err)
+	}
+	if _, err := service.Set("dark_mode", 100, ""); err != nil {
+		t.Fatalf("Failed to set flag: %v", err)
+	}
+
+	values, err := service.ForUser("alice")
+	if err != nil {
+		t.Fatalf("Failed to evaluate flags: %v", err)
+	}
+	if values["price_tracking"] || !values["dark_mode"] {
+		t.Errorf("Unexpected flags %v", values)
+	}
+
+	if _, err := service.SetOverride("price_tracking", "alice", true); err != nil {
+		t.Fatalf("Failed to set override: %v", err)
+	}
+	if _, err := service.SetOverride("missing", "alice", true); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if values, _ := service.ForUser("alice"); !values["price_tracking"] {
+		t.Error("Expected the override to turn the flag on for alice")
+	}
+	if values, _ := service.ForUser("bob"); values["price_tracking"] {
+		t.Error("Expected the flag to stay off for bob")
+	}
+
+	// Updating the rollout keeps the overrides
+	flag, err := service.Set("price_tracking", 10, "Price tracking")
+	if err != nil {
+		t.Fatalf("Failed to set flag: %v", err)
+	}
+	if flag.Rollout != 10 || len(flag.Overrides) != 1 {
+		t.Errorf("Unexpected flag %+v", flag)
+	}
+
+	if err := service.DeleteOverride("price_tracking", "alice"); err != nil {
+		t.Fatalf("Failed to delete override: %v", err)
+	}
+	if err := service.DeleteOverride("price_tracking", "alice"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	if _, err := service.SetOverride("price_tracking", "bob", false); err != nil {
+		t.Fatalf("Failed to set override: %v", err)
+	}
+	if overrides, err := service.Delete("price_tracking"); err != nil || overrides != 1 {
+		t.Fatalf("Expected the flag to be deleted with 1 override, got %d and %v", overrides, err)
+	}
+	if _, err := service.Delete("price_tracking"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if values, _ := service.ForUser("alice"); len(values) != 1 {
+		t.Errorf("Expected only dark_mode to remain, got %v", values)
+	}
+}
+
+func TestEnabled_Rollout(t *testing.T) {
+	flag := models.FeatureFlag{Key: "price_tracking", Rollout: 30}
+
+	enabled := 0
+	for i := range 1000 {
+		if Enabled(flag, fmt.Sprintf("user-%d", i)) {
+			enabled++
+		}
+	}
+	if enabled < 230 || enabled > 370 {
+		t.Errorf("Expected about 30%% of users, got %d of 1000", enabled)
+	}
+
+	// Raising the rollout keeps the users who already have the flag
+	raised := models.FeatureFlag{Key: "price_tracking", Rollout: 60}
+	for i := range 1000 {
+		userID := fmt.Sprintf("user-%d", i)
+		if Enabled(flag, userID) && !Enabled(raised, userID) {
+			t.Fatalf("Expected %s to keep the flag", userID)
+		}
+	}
+
+	if Enabled(flag, "") {
+		t.Error("Expected partial rollouts to be off for anonymous clients")
+	}
+	if !Enabled(models.FeatureFlag{Key: "price_tracking", Rollout: 100}, "") {
+		t.Error("Expected full rollouts to be on for anonymous clients")
+	}
+}
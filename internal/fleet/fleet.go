@@ -0,0 +1,163 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package fleet exports the configuration of a server as a bundle and imports it on another
+// instance, so operators running several family servers can keep them alike.
+package fleet
+
+import (
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/catalog"
+	"github.com/oliverandrich/shopping-list-server/internal/flags"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/setup"
+	"github.com/oliverandrich/shopping-list-server/internal/version"
+	"gorm.io/gorm"
+)
+
+// Format is the version of the bundle format written by Export. Import rejects other formats.
+const Format = 1
+
+// ErrUnsupportedFormat is returned for bundles of another format version.
+var ErrUnsupportedFormat = fmt.Errorf("unsupported bundle format, expected %d", Format)
+
+// Service exports and imports server bundles.
+type Service struct {
+	DB *gorm.DB
+	// Dictionary receives the imported category mappings, so they apply without a restart.
+	Dictionary *catalog.Dictionary
+}
+
+// NewService creates a new fleet service updating the given dictionary.
+func NewService(db *gorm.DB, dict *catalog.Dictionary) *Service {
+	return &Service{DB: db, Dictionary: dict}
+}
+
+// Export bundles the settings, custom category mappings and feature flags of the server. Every
+// endpoint group is included, those without quota as null, so importing restores the defaults.
+func (s *Service) Export(now time.Time) (*models.ServerBundle, error) {
+	settings, err := setup.NewService(s.DB).GetSettings()
+	if err != nil {
+		return nil, err
+	}
+	quotas := make(map[string]*int, len(setup.DefaultEndpointQuotas))
+	for group := range setup.DefaultEndpointQuotas {
+		quotas[group] = nil
+		if quota, ok := settings.EndpointQuotas[group]; ok {
+			quotas[group] = &quota
+		}
+	}
+
+	bundle := &models.ServerBundle{
+		Format:        Format,
+		ServerVersion: version.Version,
+		ExportedAt:    now,
+		Settings: models.UpdateServerSettingsRequest{
+			AutoCreateDefaultList:    &settings.AutoCreateDefaultList,
+			DefaultListNames:         settings.DefaultListNames,
+			AllowDirectMemberAdd:     &settings.AllowDirectMemberAdd,
+			LoginCodeLength:          &settings.LoginCodeLength,
+			LoginCodeAlphabet:        &settings.LoginCodeAlphabet,
+			SingleSession:            &settings.SingleSession,
+			ActivityRetentionDays:    &settings.ActivityRetentionDays,
+			AuditRetentionDays:       &settings.AuditRetentionDays,
+			ItemHistoryRetentionDays: &settings.ItemHistoryRetentionDays,
+			WeeklyDigest:             &settings.WeeklyDigest,
+			EndpointQuotas:           quotas,
+		},
+		CategoryMappings: []models.CreateCategoryMappingRequest{},
+		FeatureFlags:     []models.BundledFeatureFlag{},
+	}
+
+	mappings, err := catalog.NewService(s.DB, s.Dictionary).GetMappings()
+	if err != nil {
+		return nil, err
+	}
+	for _, mapping := range mappings {
+		bundle.CategoryMappings = append(bundle.CategoryMappings, models.CreateCategoryMappingRequest{
+			Keyword:  mapping.Keyword,
+			Category: mapping.Category,
+			Language: mapping.Language,
+		})
+	}
+
+	featureFlags, err := flags.NewService(s.DB).List()
+	if err != nil {
+		return nil, err
+	}
+	for _, flag := range featureFlags {
+		bundle.FeatureFlags = append(bundle.FeatureFlags, models.BundledFeatureFlag{
+			Key:         flag.Key,
+			Rollout:     flag.Rollout,
+			Description: flag.Description,
+		})
+	}
+
+	return bundle, nil
+}
+
+// Import applies a bundle in one transaction: the settings replace those of the server, default
+// list names of locales missing from the bundle included, while category mappings and feature
+// flags are added or updated and those missing from the bundle are kept. userID is recorded as
+// the creator of the imported mappings.
+func (s *Service) Import(userID string, bundle models.ServerBundle) (*models.ServerBundleImport, error) {
+	if bundle.Format != Format {
+		return nil, ErrUnsupportedFormat
+	}
+
+	result := &models.ServerBundleImport{
+		CategoryMappings: len(bundle.CategoryMappings),
+		FeatureFlags:     len(bundle.FeatureFlags),
+	}
+	var mappings []*models.CategoryMapping
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		setupService := setup.NewService(tx)
+		current, err := setupService.GetSettings()
+		if err != nil {
+			return err
+		}
+		req := bundle.Settings
+		req.DefaultListNames = maps.Clone(req.DefaultListNames)
+		if req.DefaultListNames == nil {
+			req.DefaultListNames = map[string]string{}
+		}
+		for locale := range current.DefaultListNames {
+			if _, ok := req.DefaultListNames[locale]; !ok {
+				// An empty name removes the locale
+				req.DefaultListNames[locale] = ""
+			}
+		}
+		if result.Settings, err = setupService.UpdateSettings(req); err != nil {
+			return err
+		}
+
+		// Mappings go to a scratch dictionary until the transaction commits
+		catalogService := catalog.NewService(tx, catalog.NewDictionary())
+		for _, mapping := range bundle.CategoryMappings {
+			added, err := catalogService.AddMapping(userID, mapping.Keyword, mapping.Category, mapping.Language)
+			if err != nil {
+				return fmt.Errorf("category mapping %q: %w", mapping.Keyword, err)
+			}
+			mappings = append(mappings, added)
+		}
+
+		flagService := flags.NewService(tx)
+		for _, flag := range bundle.FeatureFlags {
+			if _, err := flagService.Set(flag.Key, flag.Rollout, flag.Description); err != nil {
+				return fmt.Errorf("feature flag %q: %w", flag.Key, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, mapping := range mappings {
+		s.Dictionary.Add(mapping.Keyword, mapping.Category)
+	}
+	return result, nil
+}
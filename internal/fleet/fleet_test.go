@@ -0,0 +1,112 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package fleet
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/catalog"
+	"github.com/oliverandrich/shopping-list-server/internal/flags"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/setup"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+)
+
+func TestService_ExportImport(t *testing.T) {
+	testutils.SetupTestConfig(t)
+
+	source := testutils.SetupTestDB(t)
+	if _, err := setup.NewService(source).SetupSystem("admin@example.com"); err != nil {
+		t.Fatalf("Failed to setup source: %v", err)
+	}
+	days, quota := 90, 20
+	_, err := setup.NewService(source).UpdateSettings(models.UpdateServerSettingsRequest{
+		DefaultListNames:      map[string]string{"de": "Einkauf"},
+		ActivityRetentionDays: &days,
+		EndpointQuotas:        map[string]*int{setup.EndpointsExport: &quota},
+	})
+	if err != nil {
+		t.Fatalf("Failed to update settings: %v", err)
+	}
+	if _, err := catalog.NewService(source, catalog.NewDictionary()).AddMapping("admin", "Hafermilch", "Dairy", "de"); err != nil {
+		t.Fatalf("Failed to add mapping: %v", err)
+	}
+	if _, err := flags.NewService(source).Set("price_tracking", 25, "Show prices"); err != nil {
+		t.Fatalf("Failed to set flag: %v", err)
+	}
+
+	bundle, err := NewService(source, catalog.NewDictionary()).Export(time.Now())
+	if err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	if bundle.Format != Format || len(bundle.CategoryMappings) != 1 || len(bundle.FeatureFlags) != 1 {
+		t.Fatalf("Unexpected bundle: %+v", bundle)
+	}
+	if len(bundle.Settings.EndpointQuotas) != len(setup.DefaultEndpointQuotas) {
+		t.Errorf("Expected every endpoint group in the bundle, got %v", bundle.Settings.EndpointQuotas)
+	}
+
+	target := testutils.SetupTestDB(t)
+	if _, err := setup.NewService(target).SetupSystem("other@example.com"); err != nil {
+		t.Fatalf("Failed to setup target: %v", err)
+	}
+	_, err = setup.NewService(target).UpdateSettings(models.UpdateServerSettingsRequest{
+		DefaultListNames: map[string]string{"fr": "Courses"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to update settings: %v", err)
+	}
+	dictionary := catalog.NewDictionary()
+	service := NewService(target, dictionary)
+
+	t.Run("reject other formats", func(t *testing.T) {
+		other := *bundle
+		other.Format = Format + 1
+		if _, err := service.Import("admin", other); !errors.Is(err, ErrUnsupportedFormat) {
+			t.Errorf("Expected unsupported format, got %v", err)
+		}
+	})
+
+	t.Run("roll back invalid bundles", func(t *testing.T) {
+		invalid := *bundle
+		invalid.FeatureFlags = []models.BundledFeatureFlag{{Key: "Not A Key"}}
+		if _, err := service.Import("admin", invalid); err == nil {
+			t.Fatal("Expected an invalid flag key to fail the import")
+		}
+		settings, _ := setup.NewService(target).GetSettings()
+		if settings.ActivityRetentionDays != 0 || settings.DefaultListNames["fr"] != "Courses" {
+			t.Errorf("Expected the settings to be rolled back, got %+v", settings)
+		}
+		if _, ok := dictionary.Lookup("hafermilch"); ok {
+			t.Error("Expected no mappings to be added to the dictionary")
+		}
+	})
+
+	t.Run("import bundle", func(t *testing.T) {
+		result, err := service.Import("admin", *bundle)
+		if err != nil {
+			t.Fatalf("Failed to import: %v", err)
+		}
+		if result.CategoryMappings != 1 || result.FeatureFlags != 1 {
+			t.Errorf("Unexpected import result: %+v", result)
+		}
+
+		settings := result.Settings
+		if settings.ActivityRetentionDays != 90 || settings.EndpointQuotas[setup.EndpointsExport] != 20 {
+			t.Errorf("Expected the settings of the bundle, got %+v", settings)
+		}
+		if len(settings.DefaultListNames) != 1 || settings.DefaultListNames["de"] != "Einkauf" {
+			t.Errorf("Expected the default list names of the bundle, got %v", settings.DefaultListNames)
+		}
+		if category, ok := dictionary.Lookup("Hafermilch"); !ok || category.Name != "Dairy" {
+			t.Errorf("Expected the imported mapping to apply, got %+v", category)
+		}
+		flag, err := flags.NewService(target).List()
+		if err != nil || len(flag) != 1 || flag[0].Key != "price_tracking" || flag[0].Rollout != 25 {
+			t.Errorf("Expected the imported flag, got %+v", flag)
+		}
+	})
+}
@@ -0,0 +1,168 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package hal renders lists, items and members in the HAL hypermedia format
+// (application/hal+json), which adds links to related resources, so generic clients can
+// navigate the API. Clients opt in through the Accept header; plain JSON stays the default.
+package hal
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/oliverandrich/shopping-list-server/internal/codec"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+)
+
+// MediaType is the media type of HAL documents.
+const MediaType = "application/hal+json"
+
+// BasePath is the path prefix of the links.
+const BasePath = "/api/v1"
+
+// Link points to a related resource.
+type Link struct {
+	Href string `json:"href"`
+}
+
+// Links maps relation names to links.
+type Links map[string]Link
+
+// Resource is a HAL document: the fields of a value plus its "_links" and, for collections,
+// the "_embedded" resources.
+type Resource map[string]any
+
+// Accepted reports whether an Accept header asks for HAL, i.e. lists application/hal+json
+// with a quality above zero and not below that of application/json.
+func Accepted(accept string) bool {
+	quality := codec.Quality(accept, MediaType)
+	return quality > 0 && quality >= codec.Quality(accept, "application/json")
+}
+
+// New turns a value into a resource with the given links.
+func New(value any, links Links) (Resource, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	resource := Resource{}
+	if err := json.Unmarshal(data, &resource); err != nil {
+		return nil, err
+	}
+	resource["_links"] = links
+	return resource, nil
+}
+
+// Collection returns a resource embedding the resources under rel.
+func Collection(rel string, resources []Resource, links Links) Resource {
+	if resources == nil {
+		resources = []Resource{}
+	}
+	return Resource{
+		"_links":    links,
+		"_embedded": map[string][]Resource{rel: resources},
+		"count":     len(resources),
+	}
+}
+
+// List returns the resource of a list, linking its items and members.
+func List(list models.ShoppingList) (Resource, error) {
+	return New(list, Links{
+		"self":    {Href: listPath(list.ID)},
+		"items":   {Href: listPath(list.ID) + "/items"},
+		"members": {Href: listPath(list.ID) + "/members"},
+	})
+}
+
+// Lists returns the collection of the user's lists.
+func Lists(lists []models.ShoppingList) (Resource, error) {
+	resources := make([]Resource, 0, len(lists))
+	for _, list := range lists {
+		resource, err := List(list)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, resource)
+	}
+	return Collection("lists", resources, Links{"self": {Href: BasePath + "/lists"}}), nil
+}
+
+// Item returns the resource of an item, linking its list.
+func Item(item models.ShoppingItem) (Resource, error) {
+	links := Links{
+		"self":        {Href: itemPath(item)},
+		"list":        {Href: listPath(item.ListID)},
+		"attachments": {Href: itemPath(item) + "/attachments"},
+	}
+	if item.ImageURL != "" {
+		links["image"] = Link{Href: itemPath(item) + "/image"}
+	}
+	return New(item, links)
+}
+
+// Items returns the collection of the items of a list.
+func Items(listID string, items []models.ShoppingItem) (Resource, error) {
+	resources := make([]Resource, 0, len(items))
+	for _, item := range items {
+		resource, err := Item(item)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, resource)
+	}
+	return Collection("items", resources, Links{
+		"self": {Href: listPath(listID) + "/items"},
+		"list": {Href: listPath(listID)},
+	}), nil
+}
+
+// ItemPage returns the resource of a page of the items of a list with its paging fields, linking
+// the next page if there is one.
+func ItemPage(listID string, page models.ItemPage) (Resource, error) {
+	resource, err := Items(listID, page.Items)
+	if err != nil {
+		return nil, err
+	}
+	resource["total"] = page.Total
+	resource["limit"] = page.Limit
+	resource["offset"] = page.Offset
+	resource["next_offset"] = page.NextOffset
+
+	links := resource["_links"].(Links)
+	links["self"] = Link{Href: pagePath(listID, page.Limit, page.Offset)}
+	if page.NextOffset != nil {
+		links["next"] = Link{Href: pagePath(listID, page.Limit, *page.NextOffset)}
+	}
+	return resource, nil
+}
+
+// Members returns the collection of the members of a list.
+func Members(listID string, users []models.User) (Resource, error) {
+	resources := make([]Resource, 0, len(users))
+	for _, user := range users {
+		resource, err := New(user, Links{
+			"self": {Href: listPath(listID) + "/members/" + user.ID},
+			"list": {Href: listPath(listID)},
+		})
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, resource)
+	}
+	return Collection("members", resources, Links{
+		"self": {Href: listPath(listID) + "/members"},
+		"list": {Href: listPath(listID)},
+	}), nil
+}
+
+func pagePath(listID string, limit, offset int) string {
+	return listPath(listID) + "/items?limit=" + strconv.Itoa(limit) + "&offset=" + strconv.Itoa(offset)
+}
+
+func listPath(listID string) string {
+	return BasePath + "/lists/" + listID
+}
+
+func itemPath(item models.ShoppingItem) string {
+	return listPath(item.ListID) + "/items/" + item.ID
+}
@@ -0,0 +1,76 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package hal
+
+import (
+	"testing"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+)
+
+func TestAccepted(t *testing.T) {
+	tests := []struct {
+		accept   string
+		expected bool
+	}{
+		{"", false},
+		{"application/json", false},
+		{"*/*", false},
+		{"application/hal+json", true},
+		{"application/json, application/hal+json", true},
+		{"application/hal+json;q=0.5, application/json", false},
+		{"application/hal+json; q=0.9, application/json;q=0.8", true},
+		{"application/hal+json;q=0", false},
+	}
+
+	for _, test := range tests {
+		if got := Accepted(test.accept); got != test.expected {
+			t.Errorf("Accepted(%q) = %v, expected %v", test.accept, got, test.expected)
+		}
+	}
+}
+
+func TestItem(t *testing.T) {
+	resource, err := Item(models.ShoppingItem{ID: "item", ListID: "list", Name: "Milk"})
+	if err != nil {
+		t.Fatalf("Failed to build resource: %v", err)
+	}
+	if resource["name"] != "Milk" {
+		t.Errorf("Expected the item fields, got %v", resource)
+	}
+
+	links := resource["_links"].(Links)
+	if links["self"].Href != "/api/v1/lists/list/items/item" || links["list"].Href != "/api/v1/lists/list" {
+		t.Errorf("Unexpected links: %v", links)
+	}
+	if _, ok := links["image"]; ok {
+		t.Error("Expected no image link without an image")
+	}
+}
+
+func TestItems(t *testing.T) {
+	resource, err := Items("list", nil)
+	if err != nil {
+		t.Fatalf("Failed to build resource: %v", err)
+	}
+	embedded := resource["_embedded"].(map[string][]Resource)
+	if items, ok := embedded["items"]; !ok || items == nil || resource["count"] != 0 {
+		t.Errorf("Expected an empty embedded collection, got %v", resource)
+	}
+}
+
+func TestItemPage(t *testing.T) {
+	next := 2
+	resource, err := ItemPage("list", models.ItemPage{Items: []models.ShoppingItem{{ID: "milk", ListID: "list"}}, Total: 3, Limit: 1, Offset: 1, NextOffset: &next})
+	if err != nil {
+		t.Fatalf("Failed to build resource: %v", err)
+	}
+	if resource["total"] != int64(3) || resource["count"] != 1 {
+		t.Errorf("Expected the paging fields, got %v", resource)
+	}
+	links := resource["_links"].(Links)
+	if links["self"].Href != "/api/v1/lists/list/items?limit=1&offset=1" || links["next"].Href != "/api/v1/lists/list/items?limit=1&offset=2" {
+		t.Errorf("Expected links to this and the next page, got %v", links)
+	}
+}
@@ -0,0 +1,154 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+)
+
+// benchListSize is the number of items on the list before each benchmark starts, so queries
+// run against a realistically filled table.
+const benchListSize = 1000
+
+// setupBenchList creates a user with a list of benchListSize items and returns the list ID and
+// a token for the user.
+func setupBenchList(b *testing.B, server *Server) (string, string) {
+	b.Helper()
+
+	user := models.User{
+		ID:        "bench-user-id",
+		Email:     "bench@example.com",
+		JoinedAt:  time.Now(),
+		CreatedAt: time.Now(),
+	}
+	if err := server.DB.Create(&user).Error; err != nil {
+		b.Fatalf("Failed to create bench user: %v", err)
+	}
+
+	list, err := server.Lists.CreateList(user.ID, "Bench List")
+	if err != nil {
+		b.Fatalf("Failed to create bench list: %v", err)
+	}
+
+	items := make([]models.ShoppingItem, benchListSize)
+	for i := range items {
+		items[i] = models.ShoppingItem{
+			ID:        fmt.Sprintf("bench-item-%d", i),
+			ListID:    list.ID,
+			Name:      fmt.Sprintf("Item %d", i),
+			Completed: i%3 == 0,
+			CreatedAt: time.Now(),
+		}
+	}
+	if err := server.DB.CreateInBatches(items, 100).Error; err != nil {
+		b.Fatalf("Failed to create bench items: %v", err)
+	}
+
+	token, err := server.Auth.GenerateJWT(&user)
+	if err != nil {
+		b.Fatalf("Failed to generate JWT: %v", err)
+	}
+
+	return list.ID, token
+}
+
+// benchRequest sends a request through the app and fails the benchmark on an unexpected status.
+func benchRequest(b *testing.B, app *fiber.App, token, method, url string, body any, status int) []byte {
+	b.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		reqBody, err := json.Marshal(body)
+		if err != nil {
+			b.Fatalf("Failed to marshal request: %v", err)
+		}
+		reader = bytes.NewReader(reqBody)
+	}
+
+	req := httptest.NewRequest(method, url, reader)
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		b.Fatalf("Failed to make request: %v", err)
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		b.Fatalf("Failed to read response body: %v", err)
+	}
+	if resp.StatusCode != status {
+		b.Fatalf("Expected status %d, got %d. Body: %s", status, resp.StatusCode, string(respBody))
+	}
+
+	return respBody
+}
+
+func BenchmarkItemCRUD(b *testing.B) {
+	server, app := setupTestServer(b)
+	listID, token := setupBenchList(b, server)
+	itemsURL := "/api/v1/lists/" + listID + "/items"
+
+	b.Run("Create", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			benchRequest(b, app, token, http.MethodPost, itemsURL,
+				models.CreateItemRequest{Name: "Bench Item"}, fiber.StatusCreated)
+		}
+	})
+
+	b.Run("List", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			benchRequest(b, app, token, http.MethodGet, itemsURL, nil, fiber.StatusOK)
+		}
+	})
+
+	b.Run("Update", func(b *testing.B) {
+		b.ReportAllocs()
+		i := 0
+		for b.Loop() {
+			itemURL := fmt.Sprintf("%s/bench-item-%d", itemsURL, i%benchListSize)
+			benchRequest(b, app, token, http.MethodPut, itemURL,
+				models.CreateItemRequest{Name: fmt.Sprintf("Updated %d", i)}, fiber.StatusOK)
+			i++
+		}
+	})
+
+	b.Run("Toggle", func(b *testing.B) {
+		b.ReportAllocs()
+		i := 0
+		for b.Loop() {
+			itemURL := fmt.Sprintf("%s/bench-item-%d/toggle", itemsURL, i%benchListSize)
+			benchRequest(b, app, token, http.MethodPost, itemURL, nil, fiber.StatusOK)
+			i++
+		}
+	})
+
+	b.Run("CreateDelete", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			body := benchRequest(b, app, token, http.MethodPost, itemsURL,
+				models.CreateItemRequest{Name: "Short-lived Item"}, fiber.StatusCreated)
+
+			var item models.ShoppingItem
+			if err := json.Unmarshal(body, &item); err != nil {
+				b.Fatalf("Failed to parse JSON response: %v", err)
+			}
+			benchRequest(b, app, token, http.MethodDelete, itemsURL+"/"+item.ID, nil, fiber.StatusNoContent)
+		}
+	})
+}
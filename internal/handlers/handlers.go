@@ -6,33 +6,406 @@
 package handlers
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/oliverandrich/shopping-list-server/internal/activity"
+	"github.com/oliverandrich/shopping-list-server/internal/announcements"
+	"github.com/oliverandrich/shopping-list-server/internal/attachments"
 	"github.com/oliverandrich/shopping-list-server/internal/auth"
+	"github.com/oliverandrich/shopping-list-server/internal/budgets"
+	"github.com/oliverandrich/shopping-list-server/internal/calendar"
+	"github.com/oliverandrich/shopping-list-server/internal/catalog"
+	"github.com/oliverandrich/shopping-list-server/internal/chat"
+	"github.com/oliverandrich/shopping-list-server/internal/codec"
+	"github.com/oliverandrich/shopping-list-server/internal/db"
+	"github.com/oliverandrich/shopping-list-server/internal/digest"
+	"github.com/oliverandrich/shopping-list-server/internal/enrichment"
+	"github.com/oliverandrich/shopping-list-server/internal/export"
+	"github.com/oliverandrich/shopping-list-server/internal/extensions"
+	"github.com/oliverandrich/shopping-list-server/internal/fairness"
+	"github.com/oliverandrich/shopping-list-server/internal/federation"
+	"github.com/oliverandrich/shopping-list-server/internal/flags"
+	"github.com/oliverandrich/shopping-list-server/internal/fleet"
+	"github.com/oliverandrich/shopping-list-server/internal/hal"
+	"github.com/oliverandrich/shopping-list-server/internal/housekeeping"
+	"github.com/oliverandrich/shopping-list-server/internal/imageproxy"
+	"github.com/oliverandrich/shopping-list-server/internal/integrations"
+	"github.com/oliverandrich/shopping-list-server/internal/integrity"
 	"github.com/oliverandrich/shopping-list-server/internal/invitations"
+	"github.com/oliverandrich/shopping-list-server/internal/items"
+	"github.com/oliverandrich/shopping-list-server/internal/kiosk"
 	"github.com/oliverandrich/shopping-list-server/internal/lists"
+	"github.com/oliverandrich/shopping-list-server/internal/logging"
+	"github.com/oliverandrich/shopping-list-server/internal/matrix"
+	"github.com/oliverandrich/shopping-list-server/internal/metrics"
 	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/moderation"
+	"github.com/oliverandrich/shopping-list-server/internal/nutrition"
+	"github.com/oliverandrich/shopping-list-server/internal/onboarding"
+	"github.com/oliverandrich/shopping-list-server/internal/policies"
+	"github.com/oliverandrich/shopping-list-server/internal/polls"
+	"github.com/oliverandrich/shopping-list-server/internal/quantity"
+	"github.com/oliverandrich/shopping-list-server/internal/quickadd"
+	"github.com/oliverandrich/shopping-list-server/internal/ratelimit"
+	"github.com/oliverandrich/shopping-list-server/internal/realtime"
+	"github.com/oliverandrich/shopping-list-server/internal/replenish"
+	"github.com/oliverandrich/shopping-list-server/internal/retention"
+	"github.com/oliverandrich/shopping-list-server/internal/rules"
+	"github.com/oliverandrich/shopping-list-server/internal/setup"
+	"github.com/oliverandrich/shopping-list-server/internal/smartlists"
+	"github.com/oliverandrich/shopping-list-server/internal/snapshot"
+	"github.com/oliverandrich/shopping-list-server/internal/status"
+	"github.com/oliverandrich/shopping-list-server/internal/storage"
+	"github.com/oliverandrich/shopping-list-server/internal/trips"
 	"github.com/oliverandrich/shopping-list-server/internal/validation"
+	"github.com/oliverandrich/shopping-list-server/internal/version"
 	"gopkg.in/gomail.v2"
 	"gorm.io/gorm"
 )
 
 // Server provides HTTP handlers for the shopping list API with authentication and business logic services.
 type Server struct {
-	DB          *gorm.DB
-	Auth        *auth.Service
-	Lists       *lists.Service
-	Invitations *invitations.Service
+	DB            *gorm.DB
+	Auth          *auth.Service
+	Lists         *lists.Service
+	Invitations   *invitations.Service
+	Setup         *setup.Service
+	Catalog       *catalog.Service
+	Normalizer    *catalog.Normalizer
+	Trips         *trips.Service
+	Polls         *polls.Service
+	Realtime      *realtime.Service
+	Items         *items.Service
+	Announcements *announcements.Service
+	Policies      *policies.Service
+	Attachments   *attachments.Service
+	Integrity     *integrity.Service
+	Onboarding    *onboarding.Service
+	Enrichment    *enrichment.Service
+	Nutrition     *nutrition.Service
+	Budgets       *budgets.Service
+	Extensions    *extensions.Service
+	Rules         *rules.Service
+	Snapshots     *snapshot.Service
+	SmartLists    *smartlists.Service
+	Status        *status.Service
+	Housekeeping  *housekeeping.Service
+	Kiosk         *kiosk.Renderer
+	Calendar      *calendar.Service
+	Activity      *activity.Service
+	Integrations  *integrations.Service
+	Matrix        *matrix.Service
+	Chat          *chat.Service
+	Flags         *flags.Service
+	Moderation    *moderation.Service
+	Federation    *federation.Service
+	Images        *imageproxy.Service
+	Retention     *retention.Service
+	Digest        *digest.Service
+	Replenish     *replenish.Service
+	Fairness      *fairness.Service
+	Fleet         *fleet.Service
+	Quotas        *ratelimit.Limiter
+	Replica       *db.ReplicaPlugin
+	Features      models.CapabilityFeatures
+	Updates       *version.UpdateChecker
+
+	// Exports keeps a copy of every list export. Backends supporting presigned URLs serve the
+	// download, see ExportList. Nil sends exports directly.
+	Exports storage.Storage
+
+	// AnonymizeIP truncates client IPs used as keys, e.g. of login limits, see logging.ClientIP.
+	AnonymizeIP bool
 }
 
 // NewServer creates a new HTTP server with all required services initialized.
 func NewServer(db *gorm.DB, jwtSecret []byte, mailer *gomail.Dialer) *Server {
-	return &Server{
-		DB:          db,
-		Auth:        auth.NewService(db, jwtSecret, mailer),
-		Lists:       lists.NewService(db),
-		Invitations: invitations.NewService(db, mailer),
+	dictionary := catalog.DefaultDictionary()
+	authService := auth.NewService(db, jwtSecret, mailer)
+	server := &Server{
+		DB:            db,
+		Auth:          authService,
+		Lists:         lists.NewService(db),
+		Invitations:   invitations.NewService(db, mailer),
+		Setup:         setup.NewService(db),
+		Catalog:       catalog.NewService(db, dictionary),
+		Normalizer:    catalog.NewNormalizer(dictionary, true, true),
+		Trips:         trips.NewService(db, mailer),
+		Polls:         polls.NewService(db),
+		Realtime:      realtime.NewService(db),
+		Nutrition:     nutrition.NewService(db),
+		Budgets:       budgets.NewService(db, mailer),
+		Items:         items.NewService(db, mailer),
+		Announcements: announcements.NewService(db, mailer),
+		Policies:      policies.NewService(db),
+		Attachments:   attachments.NewService(db),
+		Integrity:     integrity.NewService(db),
+		Onboarding:    onboarding.NewService(db, authService),
+		Enrichment:    enrichment.NewService(db, dictionary),
+		Extensions:    extensions.NewService(),
+		Rules:         rules.NewService(db, mailer, dictionary),
+		Snapshots:     snapshot.NewService(db, "snapshots"),
+		SmartLists:    smartlists.NewService(db),
+		Status:        status.NewService(db, mailer),
+		Housekeeping:  housekeeping.NewService(db),
+		Kiosk:         kiosk.NewRenderer(),
+		Calendar:      calendar.NewService(db),
+		Activity:      activity.NewService(db),
+		Integrations:  integrations.NewService(db),
+		Matrix:        matrix.NewService(db),
+		Flags:         flags.NewService(db),
+		Moderation:    moderation.NewService(db),
+		Federation:    federation.NewService(db),
+		Images:        imageproxy.NewService(),
+		Digest:        digest.NewService(db, mailer),
+		Replenish:     replenish.NewService(db, mailer),
+		Fairness:      fairness.NewService(db),
+		Fleet:         fleet.NewService(db, dictionary),
+		Quotas:        ratelimit.New(time.Minute),
+		Features: models.CapabilityFeatures{
+			RegistrationMode: "invitation",
+		},
+	}
+	server.Chat = chat.NewService(db, server.QuickAddText)
+	server.Retention = retention.NewService(db, server.Attachments)
+	return server
+}
+
+// dbFor returns the database handle for queries of a request, carrying its route and request
+// ID so slow queries can be attributed to it. Queries of GET requests may be served by the read
+// replica, see PinPrimary.
+func (s *Server) dbFor(c *fiber.Ctx) *gorm.DB {
+	ctx := metrics.WithRequest(c.UserContext(), metrics.Request{
+		ID:    c.GetRespHeader(fiber.HeaderXRequestID),
+		Route: c.Route().Path,
+	})
+	if c.Method() == fiber.MethodGet {
+		userID, _ := c.Locals("user_id").(string)
+		ctx = db.WithReader(ctx, userID)
+	}
+	return s.DB.WithContext(ctx)
+}
+
+// listsFor, itemsFor, fairnessFor and budgetsFor return the services for the reads of a request,
+// whose queries may be served by the read replica, see dbFor. Permission checks and writes keep
+// using the services of the server, so they always run on the primary.
+func (s *Server) listsFor(c *fiber.Ctx) *lists.Service {
+	if s.Replica == nil {
+		return s.Lists
+	}
+	return lists.NewService(s.dbFor(c))
+}
+
+func (s *Server) itemsFor(c *fiber.Ctx) *items.Service {
+	if s.Replica == nil {
+		return s.Items
+	}
+	service := *s.Items
+	service.DB = s.dbFor(c)
+	return &service
+}
+
+func (s *Server) fairnessFor(c *fiber.Ctx) *fairness.Service {
+	if s.Replica == nil {
+		return s.Fairness
+	}
+	return fairness.NewService(s.dbFor(c))
+}
+
+func (s *Server) budgetsFor(c *fiber.Ctx) *budgets.Service {
+	if s.Replica == nil {
+		return s.Budgets
+	}
+	service := *s.Budgets
+	service.DB = s.dbFor(c)
+	service.Lists = lists.NewService(service.DB)
+	return &service
+}
+
+// PinPrimary is a middleware that keeps the reads of users on the primary database for a while
+// after they sent anything but a GET request, so they see their own changes while the read
+// replica catches up.
+func (s *Server) PinPrimary(c *fiber.Ctx) error {
+	err := c.Next()
+	if s.Replica == nil || c.Method() == fiber.MethodGet || c.Method() == fiber.MethodHead {
+		return err
+	}
+	if userID, ok := c.Locals("user_id").(string); ok {
+		s.Replica.Pin(userID, time.Now())
+	}
+	return err
+}
+
+// RequireAdmin is a middleware that only lets the system admin pass.
+func (s *Server) RequireAdmin(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(string)
+	if !s.Setup.IsAdmin(userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Admin access required",
+		})
+	}
+	return c.Next()
+}
+
+// Quota returns a middleware that limits how often each user may call the endpoints of a group
+// per minute, see setup.DefaultEndpointQuotas. Requests above the quota get a 429 with the
+// seconds until the next minute starts in Retry-After.
+func (s *Server) Quota(group string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		limit := s.Setup.EndpointQuota(group)
+		if limit == 0 {
+			return c.Next()
+		}
+
+		userID, _ := c.Locals("user_id").(string)
+		if retryAfter, ok := s.Quotas.Allow(group+"\x00"+userID, limit, time.Now()); !ok {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(retryAfter.Seconds())+1))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": "Rate limit exceeded",
+			})
+		}
+		return c.Next()
+	}
+}
+
+// EncodeResponse is a middleware that re-encodes JSON responses as MessagePack or CBOR for clients
+// that prefer them in their Accept header, see codec.Negotiate. ETags of routes behind it are
+// computed over the JSON, so they carry the encoding, see codec.Tag, and the tags of other
+// encodings never match.
+func (s *Server) EncodeResponse(c *fiber.Ctx) error {
+	mediaType := codec.Negotiate(c.Get(fiber.HeaderAccept))
+	c.Vary(fiber.HeaderAccept)
+	if mediaType != "" && c.Get(fiber.HeaderIfNoneMatch) != "" {
+		if tags := codec.Untag(c.Get(fiber.HeaderIfNoneMatch), mediaType); tags != "" {
+			c.Request().Header.Set(fiber.HeaderIfNoneMatch, tags)
+		} else {
+			c.Request().Header.Del(fiber.HeaderIfNoneMatch)
+		}
+	}
+	if err := c.Next(); err != nil || mediaType == "" {
+		return err
+	}
+	if etag := c.GetRespHeader(fiber.HeaderETag); etag != "" {
+		c.Set(fiber.HeaderETag, codec.Tag(etag, mediaType))
+	}
+
+	// 304 responses carry the status text as body, which is never sent
+	body := c.Response().Body()
+	if len(body) == 0 || c.Response().StatusCode() == fiber.StatusNotModified ||
+		!strings.HasPrefix(string(c.Response().Header.ContentType()), fiber.MIMEApplicationJSON) {
+		return nil
+	}
+	encoded, err := codec.FromJSON(mediaType, body)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	c.Set(fiber.HeaderContentType, mediaType)
+	return c.Send(encoded)
+}
+
+// RequirePolicyAcceptance is a middleware that blocks users until they have accepted the current
+// version of all policy documents, if acceptance is enforced.
+func (s *Server) RequirePolicyAcceptance(c *fiber.Ctx) error {
+	if !s.Policies.Enforced() {
+		return c.Next()
+	}
+
+	userID, _ := c.Locals("user_id").(string)
+	pending, err := s.Policies.PendingDocuments(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if len(pending) > 0 {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":   "Policy acceptance required",
+			"pending": pending,
+		})
+	}
+	return c.Next()
+}
+
+// RequireAttachments is a middleware that rejects attachment requests when attachments are disabled.
+func (s *Server) RequireAttachments(c *fiber.Ctx) error {
+	if !s.Attachments.Enabled() {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Attachments are disabled",
+		})
+	}
+	return c.Next()
+}
+
+// RequireMatrix is a middleware that rejects Matrix requests when the Matrix bot is not configured.
+func (s *Server) RequireMatrix(c *fiber.Ctx) error {
+	if !s.Matrix.Enabled() {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Matrix integration is disabled",
+		})
+	}
+	return c.Next()
+}
+
+// RequireFederation is a middleware that rejects list bundle requests when no federation key is configured.
+func (s *Server) RequireFederation(c *fiber.Ctx) error {
+	if !s.Federation.Enabled() {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "List federation is disabled",
+		})
+	}
+	return c.Next()
+}
+
+// validationFailed answers 400 with the field errors as codes and params for clients that
+// localize them, and as messages in the language of the request.
+func validationFailed(c *fiber.Ctx, fieldErrors map[string]validation.Error) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error":   "Validation failed",
+		"details": validation.Messages(fieldErrors, c.Get(fiber.HeaderAcceptLanguage)),
+		"codes":   fieldErrors,
+	})
+}
+
+// respond sends value as JSON, or as the HAL resource built by toHAL if the client asks for
+// application/hal+json.
+func respond(c *fiber.Ctx, status int, value any, toHAL func() (hal.Resource, error)) error {
+	c.Vary(fiber.HeaderAccept)
+	if !hal.Accepted(c.Get(fiber.HeaderAccept)) {
+		return c.Status(status).JSON(value)
+	}
+
+	resource, err := toHAL()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
 	}
+	return c.Status(status).JSON(resource, hal.MediaType)
+}
+
+// notModified sets the Last-Modified header and reports whether the client's copy, dated by the
+// If-Modified-Since header, is still current.
+func notModified(c *fiber.Ctx, lastModified time.Time) bool {
+	lastModified = lastModified.UTC().Truncate(time.Second)
+	c.Set(fiber.HeaderLastModified, lastModified.Format(http.TimeFormat))
+
+	since, err := http.ParseTime(c.Get(fiber.HeaderIfModifiedSince))
+	return err == nil && !lastModified.After(since)
 }
 
 // Health check endpoint
@@ -43,7 +416,150 @@ func (s *Server) Health(c *fiber.Ctx) error {
 	})
 }
 
-// RequestLogin handles magic link authentication requests.
+// GetStatus reports a minimal ok/degraded status with component statuses for uptime monitors.
+// Degraded answers 503 so monitors alert without parsing the body.
+func (s *Server) GetStatus(c *fiber.Ctx) error {
+	report := s.Status.Report()
+
+	code := fiber.StatusOK
+	if report.Status != status.StatusOK {
+		code = fiber.StatusServiceUnavailable
+	}
+	return c.Status(code).JSON(report)
+}
+
+// Capabilities reports the server version, enabled optional features, limits, supported locales
+// and the feature flags, evaluated for the user if the request carries a token.
+func (s *Server) Capabilities(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(string)
+	capabilities, err := s.capabilities(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(capabilities)
+}
+
+// capabilities describes the server with the feature flags evaluated for the user, who may be
+// empty for anonymous requests.
+func (s *Server) capabilities(userID string) (models.CapabilitiesResponse, error) {
+	flagValues, err := s.Flags.ForUser(userID)
+	if err != nil {
+		return models.CapabilitiesResponse{}, err
+	}
+
+	features := s.Features
+	features.PolicyAcceptance = s.Policies.Enforced()
+	features.Attachments = s.Attachments.Enabled()
+	features.Matrix = s.Matrix.Enabled()
+	features.Slack = s.Chat.SlackEnabled()
+	features.Discord = s.Chat.DiscordEnabled()
+	features.Federation = s.Federation.Enabled()
+	loginCode := s.Auth.LoginCodeFormat()
+
+	return models.CapabilitiesResponse{
+		Version:  version.Version,
+		Features: features,
+		Limits: models.CapabilityLimits{
+			LoginCodeLifetimeSeconds:  int(s.Auth.MagicLinkLifetime.Seconds()),
+			LoginCodeLength:           loginCode.Length,
+			LoginCodeAlphabet:         loginCode.Alphabet,
+			TokenLifetimeSeconds:      int(s.Auth.TokenLifetime.Seconds()),
+			InvitationLifetimeSeconds: int(invitations.InvitationLifetime.Seconds()),
+			AttachmentMaxBytes:        s.Attachments.MaxFileBytes,
+			StorageQuotaBytes:         s.Attachments.QuotaBytes,
+		},
+		Locales:  catalog.BuiltinLanguages,
+		Currency: s.Budgets.DefaultCurrency,
+		Flags:    flagValues,
+	}, nil
+}
+
+// Version reports the version, git commit and build date of the running server.
+func (s *Server) Version(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(version.Info())
+}
+
+// AdminVersion reports the build information together with the result of the last update check.
+// The update status is omitted when update checks are disabled.
+func (s *Server) AdminVersion(c *fiber.Ctx) error {
+	info := version.Info()
+	if s.Updates != nil {
+		status := s.Updates.Status()
+		info.Update = &status
+	}
+	return c.Status(fiber.StatusOK).JSON(info)
+}
+
+// GetPolicies retrieves the current version of all policy documents.
+func (s *Server) GetPolicies(c *fiber.Ctx) error {
+	documents := s.Policies.Documents
+	if documents == nil {
+		documents = []models.PolicyDocument{}
+	}
+	return c.Status(fiber.StatusOK).JSON(documents)
+}
+
+// GetPolicyStatus reports which policy documents the authenticated user has accepted and which are pending.
+func (s *Server) GetPolicyStatus(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	pending, err := s.Policies.PendingDocuments(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	acceptances, err := s.Policies.GetAcceptances(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	documents := s.Policies.Documents
+	if documents == nil {
+		documents = []models.PolicyDocument{}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.PolicyStatusResponse{
+		Documents:   documents,
+		Pending:     pending,
+		Acceptances: acceptances,
+		Required:    s.Policies.Enforced(),
+	})
+}
+
+// AcceptPolicy records that the authenticated user accepted the current version of a policy document.
+func (s *Server) AcceptPolicy(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req models.AcceptPolicyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	acceptance, err := s.Policies.Accept(userID, req.Type, req.Version)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(acceptance)
+}
+
+// RequestLogin handles magic link authentication requests. Each address and client IP may
+// only request a limited number of codes per hour.
 func (s *Server) RequestLogin(c *fiber.Ctx) error {
 	var req models.LoginRequest
 	if err := c.BodyParser(&req); err != nil {
@@ -53,11 +569,18 @@ func (s *Server) RequestLogin(c *fiber.Ctx) error {
 	}
 
 	if err := validation.ValidateStruct(req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Validation failed",
-			"details": validation.FormatValidationErrors(err),
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	retryAfter, ok, err := s.Auth.AllowLoginCode(req.Email, logging.ClientIP(c, s.AnonymizeIP))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
 		})
 	}
+	if !ok {
+		return tooManyLoginRequests(c, retryAfter)
+	}
 
 	code, err := s.Auth.CreateMagicLink(req.Email)
 	if err != nil {
@@ -66,7 +589,16 @@ func (s *Server) RequestLogin(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := s.Auth.SendMagicLink(req.Email, code); err != nil {
+	linkToken := ""
+	if s.Auth.LoginLinkURL != "" {
+		if linkToken, err = s.Auth.CreateLoginLink(req.Email); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to create login link",
+			})
+		}
+	}
+
+	if err := s.Auth.SendMagicLink(req.Email, code, linkToken); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to send email",
 		})
@@ -77,7 +609,8 @@ func (s *Server) RequestLogin(c *fiber.Ctx) error {
 	})
 }
 
-// VerifyLogin handles magic link verification and returns JWT tokens.
+// VerifyLogin handles magic link verification and returns JWT tokens. Each code may only be
+// tried a limited number of times, and each client IP a limited number of times per hour.
 func (s *Server) VerifyLogin(c *fiber.Ctx) error {
 	var req models.VerifyRequest
 	if err := c.BodyParser(&req); err != nil {
@@ -87,74 +620,143 @@ func (s *Server) VerifyLogin(c *fiber.Ctx) error {
 	}
 
 	if err := validation.ValidateStruct(req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Validation failed",
-			"details": validation.FormatValidationErrors(err),
-		})
+		return validationFailed(c, validation.Errors(err))
 	}
 
-	user, invitation, err := s.Auth.VerifyMagicLinkWithInvitation(req.Email, req.Code)
+	retryAfter, ok, err := s.Auth.AllowVerifyAttempt(req.Email, logging.ClientIP(c, s.AnonymizeIP))
 	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if !ok {
+		return tooManyLoginRequests(c, retryAfter)
+	}
+
+	user, joined, err := s.Onboarding.Complete(req.Email, req.Code, req.InvitationCode, c.Get(fiber.HeaderAcceptLanguage))
+	if errors.Is(err, onboarding.ErrInvalidCode) {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Invalid or expired code",
 		})
 	}
+	if errors.Is(err, onboarding.ErrInvitationNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Invitation not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to accept invitation",
+		})
+	}
 
-	// Handle invitation acceptance if present
-	if invitation != nil {
-		_, err := s.Invitations.AcceptInvitation(req.Email, invitation.Code)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to accept invitation",
-			})
-		}
+	return s.loggedIn(c, user, joined)
+}
 
-		// For new users with server invitation, create default list
-		if invitation.Type == "server" {
-			_, err := s.Lists.CreateDefaultListForUser(user.ID)
-			if err != nil {
-				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-					"error": "Failed to create default list",
-				})
-			}
-		}
+// tooManyLoginRequests responds with a 429 and the seconds until the login limit allows the
+// request in Retry-After.
+func tooManyLoginRequests(c *fiber.Ctx, retryAfter time.Duration) error {
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(retryAfter.Seconds())+1))
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"error": "Too many login attempts, please try again later",
+	})
+}
 
-		// For list invitations, add user to the list
-		if invitation.Type == "list" && invitation.ListID != nil {
-			err := s.Lists.AddMemberToList(*invitation.ListID, invitation.InvitedBy, user.ID)
-			if err != nil {
-				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-					"error": "Failed to add to list",
-				})
-			}
-		}
+// VerifyLoginLink logs in with the token of a login link, passed as ?token= by the client app
+// the link opened, and returns JWT tokens like VerifyLogin.
+func (s *Server) VerifyLoginLink(c *fiber.Ctx) error {
+	token := c.Query("token")
+	if token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "token is required",
+		})
+	}
+
+	user, joined, err := s.Onboarding.CompleteLink(token, c.Get(fiber.HeaderAcceptLanguage))
+	if errors.Is(err, onboarding.ErrInvalidCode) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid or expired link",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to accept invitation",
+		})
 	}
 
-	token, err := s.Auth.GenerateJWT(user)
+	return s.loggedIn(c, user, joined)
+}
+
+// loggedIn starts a session for a verified user and responds with the token and everything a
+// client needs for its first screen.
+func (s *Server) loggedIn(c *fiber.Ctx, user *models.User, joined []models.JoinedList) error {
+	token, err := s.Auth.StartSession(user)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to generate token",
 		})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(models.LoginResponse{
-		Token: token,
-		User:  *user,
-	})
+	joinedIDs := make([]string, 0, len(joined))
+	for _, list := range joined {
+		joinedIDs = append(joinedIDs, list.ID)
+	}
+	s.Extensions.Emit(extensions.EventLogin, models.LoginEvent{UserID: user.ID, Email: user.Email, JoinedLists: joinedIDs})
+	s.memberAdded(user.ID, joinedIDs...)
+
+	capabilities, err := s.capabilities(user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	response := models.LoginResponse{
+		Token:        token,
+		User:         *user,
+		JoinedLists:  joined,
+		IsAdmin:      s.Setup.IsAdmin(user.ID),
+		Capabilities: capabilities,
+	}
+	if list, err := s.Lists.GetDefaultList(user.ID); err == nil {
+		response.DefaultListID = list.ID
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// LogoutAll ends all sessions of the authenticated user, so every token issued before, including
+// the one of this request, is rejected from now on. API keys stay valid.
+func (s *Server) LogoutAll(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	if err := s.Auth.EndSessions(userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
 }
 
-// GetLists retrieves all shopping lists accessible to the authenticated user.
+// GetLists retrieves all shopping lists accessible to the authenticated user, or only the
+// archived ones with ?archived=true.
 func (s *Server) GetLists(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
 
-	lists, err := s.Lists.GetUserLists(userID)
+	getLists := s.listsFor(c).GetUserLists
+	if c.QueryBool("archived") {
+		getLists = s.listsFor(c).GetArchivedLists
+	}
+	lists, err := getLists(userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(lists)
+	return respond(c, fiber.StatusOK, lists, func() (hal.Resource, error) {
+		return hal.Lists(lists)
+	})
 }
 
 // CreateList creates a new shopping list for the authenticated user.
@@ -169,10 +771,7 @@ func (s *Server) CreateList(c *fiber.Ctx) error {
 	}
 
 	if err := validation.ValidateStruct(req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Validation failed",
-			"details": validation.FormatValidationErrors(err),
-		})
+		return validationFailed(c, validation.Errors(err))
 	}
 
 	list, err := s.Lists.CreateList(userID, req.Name)
@@ -182,7 +781,9 @@ func (s *Server) CreateList(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(list)
+	return respond(c, fiber.StatusCreated, list, func() (hal.Resource, error) {
+		return hal.List(*list)
+	})
 }
 
 // GetList retrieves a specific shopping list by ID.
@@ -190,22 +791,3868 @@ func (s *Server) GetList(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
 	listID := c.Params("id")
 
-	list, err := s.Lists.GetListByID(listID, userID)
+	list, err := s.listsFor(c).GetListByID(listID, userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if notModified(c, list.UpdatedAt) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	if list.SuggestedTags, err = s.listsFor(c).SuggestedTags(listID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return respond(c, fiber.StatusOK, list, func() (hal.Resource, error) {
+		return hal.List(*list)
+	})
+}
+
+// MaxBatchLists is the largest number of lists GetListsBatch returns at once.
+const MaxBatchLists = 100
+
+// GetListsBatch retrieves the lists given as comma-separated "ids", with include=items also their
+// items, for clients restoring their state. Unlike GetListItems, it does not mark lists as seen.
+func (s *Server) GetListsBatch(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var ids []string
+	for _, id := range strings.Split(c.Query("ids"), ",") {
+		if id = strings.TrimSpace(id); id != "" && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 || len(ids) > MaxBatchLists {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ids must name between 1 and " + strconv.Itoa(MaxBatchLists) + " lists",
+		})
+	}
+
+	includeItems := false
+	for _, include := range strings.Split(c.Query("include"), ",") {
+		switch strings.TrimSpace(include) {
+		case "":
+		case "items":
+			includeItems = true
+		default:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "include only supports items",
+			})
+		}
+	}
+
+	lists, err := s.listsFor(c).GetListsByIDs(userID, ids)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	response := models.ListBatchResponse{Lists: lists, Missing: []string{}}
+	found := make(map[string]bool, len(lists))
+	for _, list := range lists {
+		found[list.ID] = true
+	}
+	for _, id := range ids {
+		if !found[id] {
+			response.Missing = append(response.Missing, id)
+		}
+	}
+
+	if includeItems {
+		response.Items, err = s.itemsFor(c).ItemsByList(lists, c.QueryBool("include_snoozed"))
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// UpdateList updates a shopping list's name if the user is the owner.
+func (s *Server) UpdateList(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	var req models.UpdateListRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	before, err := s.Lists.GetListByID(listID, userID)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	list, err := s.Lists.UpdateList(listID, userID, req.Name)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if list.Name != before.Name {
+		s.Realtime.Publish(realtime.EventListRenamed, listID, fiber.Map{"name": list.Name})
+	}
+
+	if req.StaleAfterDays != nil {
+		list, err = s.Lists.SetStaleAfterDays(listID, userID, *req.StaleAfterDays)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	if req.CoOwnersCanDelete != nil {
+		list, err = s.Lists.SetCoOwnersCanDelete(listID, userID, *req.CoOwnersCanDelete)
+		if err != nil {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	if req.SkipDeduplication != nil {
+		list, err = s.Lists.SetSkipDeduplication(listID, userID, *req.SkipDeduplication)
+		if err != nil {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	if req.Archived != nil {
+		list, err = s.Lists.SetArchived(listID, userID, *req.Archived)
+		if err != nil {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	if req.TagPolicy != "" {
+		list, err = s.Lists.SetTagPolicy(listID, userID, req.TagPolicy)
+		if err != nil {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	if req.SuggestedTags != nil {
+		list, err = s.Lists.SetSuggestedTags(listID, userID, req.SuggestedTags)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	if req.MonthlyBudget != nil {
+		list, err = s.Budgets.SetMonthlyBudget(listID, userID, *req.MonthlyBudget)
+		if err != nil {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	if req.Currency != nil {
+		list, err = s.Budgets.SetCurrency(listID, userID, *req.Currency)
+		if err != nil {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	if list.SuggestedTags, err = s.Lists.SuggestedTags(listID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(list)
+}
+
+// MergeList moves the items, and optionally the members, of another list into the list and
+// archives the other list.
+func (s *Server) MergeList(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req models.MergeListsRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	result, err := s.Lists.MergeLists(c.Params("id"), c.Params("otherId"), userID, req.IncludeMembers, req.Duplicates)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		switch {
+		case errors.Is(err, lists.ErrListNotFound):
+			status = fiber.StatusNotFound
+		case errors.Is(err, lists.ErrMergeNotOwner):
+			status = fiber.StatusForbidden
+		case errors.Is(err, lists.ErrInvalidMerge):
+			status = fiber.StatusBadRequest
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
+
+// ExportListBundle exports a list as a signed bundle for import on another instance.
+func (s *Server) ExportListBundle(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	bundle, err := s.Federation.Export(c.Params("id"), userID)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		switch {
+		case errors.Is(err, federation.ErrNotFound):
+			status = fiber.StatusNotFound
+		case errors.Is(err, federation.ErrNotOwner):
+			status = fiber.StatusForbidden
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(bundle)
+}
+
+// ImportListBundle creates a list from a bundle exported by another instance, owned by the
+// authenticated user, and invites the former members of the list again.
+func (s *Server) ImportListBundle(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req models.ListBundle
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	list, members, err := s.Federation.Import(userID, &req)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, federation.ErrInvalidBundle) {
+			status = fiber.StatusBadRequest
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	result := models.ListImportResult{List: *list, Invited: []string{}, NotInvited: map[string]string{}}
+	for _, member := range members {
+		invitation, err := s.Invitations.CreateInvitation(userID, member.Email, "list", &list.ID)
+		if err != nil {
+			result.NotInvited[member.Email] = err.Error()
+			continue
+		}
+		s.invitationCreated(invitation)
+		result.Invited = append(result.Invited, member.Email)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(result)
+}
+
+// DeleteList deletes a shopping list if the user is the owner.
+func (s *Server) DeleteList(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	err := s.Lists.DeleteList(listID, userID)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := s.Attachments.DeleteListAttachments(listID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetListMembers retrieves all members of a shopping list.
+func (s *Server) GetListMembers(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	members, err := s.listsFor(c).GetListMembers(listID, userID)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return respond(c, fiber.StatusOK, members, func() (hal.Resource, error) {
+		return hal.Members(listID, members)
+	})
+}
+
+// AddListMember adds a registered user to a list by email. If the server settings allow it the
+// user becomes a member right away, otherwise they get an in-app invitation without email.
+func (s *Server) AddListMember(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	var req models.AddListMemberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	if !s.Lists.IsListOwner(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Only list owners can add members",
+		})
+	}
+
+	member, err := s.Auth.GetUserByEmail(req.Email)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found, send an invitation instead",
+		})
+	}
+
+	if s.Setup.AllowDirectMemberAdd() {
+		if err := s.Lists.AddMemberToList(listID, userID, member.ID); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		s.memberAdded(member.ID, listID)
+		return c.Status(fiber.StatusCreated).JSON(models.AddListMemberResponse{Added: true})
+	}
+
+	invitation, err := s.Invitations.CreateInAppInvitation(userID, req.Email, listID)
+	if errors.Is(err, invitations.ErrInvitationsFrozen) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	s.invitationCreated(invitation)
+
+	return c.Status(fiber.StatusAccepted).JSON(models.AddListMemberResponse{Invitation: invitation})
+}
+
+// UpdateListMember changes the role of a list member to co-owner, editor or viewer.
+func (s *Server) UpdateListMember(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+	memberID := c.Params("userId")
+
+	var req models.UpdateListMemberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	member, err := s.Lists.SetMemberRole(listID, userID, memberID, req.Role)
+	if errors.Is(err, lists.ErrMemberNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(member)
+}
+
+// RemoveListMember removes a member from a shopping list.
+func (s *Server) RemoveListMember(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+	memberID := c.Params("userId")
+
+	err := s.Lists.RemoveMemberFromList(listID, userID, memberID)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetListAliases retrieves all aliases of a shopping list.
+func (s *Server) GetListAliases(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	aliases, err := s.Lists.GetListAliases(listID, userID)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(aliases)
+}
+
+// GetListSections retrieves the sections of a shopping list in display order.
+func (s *Server) GetListSections(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	sections, err := s.listsFor(c).GetSections(listID, userID)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(sections)
+}
+
+// CreateListSection adds a section to a shopping list.
+func (s *Server) CreateListSection(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	var req models.SectionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	section, err := s.Lists.CreateSection(listID, userID, req.Name, req.Position)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(section)
+}
+
+// UpdateListSection renames or moves a section of a shopping list.
+func (s *Server) UpdateListSection(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+	sectionID := c.Params("sectionId")
+
+	var req models.SectionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	section, err := s.Lists.UpdateSection(listID, sectionID, userID, req.Name, req.Position)
+	if errors.Is(err, lists.ErrSectionNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Section not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(section)
+}
+
+// DeleteListSection removes a section from a shopping list, keeping its items.
+func (s *Server) DeleteListSection(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+	sectionID := c.Params("sectionId")
+
+	err := s.Lists.DeleteSection(listID, sectionID, userID)
+	if errors.Is(err, lists.ErrSectionNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Section not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetListTags retrieves the tag metadata of a shopping list.
+func (s *Server) GetListTags(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	tags, err := s.listsFor(c).GetTags(listID, userID)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(tags)
+}
+
+// CreateListTag adds metadata for a tag of a shopping list.
+func (s *Server) CreateListTag(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	var req models.TagRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	tag, err := s.Lists.CreateTag(listID, userID, req)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(tag)
+}
+
+// UpdateListTag changes the metadata of a list tag, renaming it on all items if needed.
+func (s *Server) UpdateListTag(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	name, err := url.PathUnescape(c.Params("tag"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid tag",
+		})
+	}
+
+	var req models.TagRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	tag, err := s.Lists.UpdateTag(listID, userID, name, req)
+	if errors.Is(err, lists.ErrTagNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Tag not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(tag)
+}
+
+// DeleteListTag removes a tag's metadata and the tag from all items of the list.
+func (s *Server) DeleteListTag(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	name, err := url.PathUnescape(c.Params("tag"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid tag",
+		})
+	}
+
+	err = s.Lists.DeleteTag(listID, userID, name)
+	if errors.Is(err, lists.ErrTagNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Tag not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetListNote retrieves the authenticated user's private note on a shopping list.
+func (s *Server) GetListNote(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	note, err := s.Lists.GetListNote(listID, userID)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(note)
+}
+
+// UpdateListNote replaces the authenticated user's private note on a shopping list.
+func (s *Server) UpdateListNote(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	var req models.UpdateListNoteRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	note, err := s.Lists.SetListNote(listID, userID, req.Note)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(note)
+}
+
+// CreateListAlias adds an alternative name to a shopping list.
+func (s *Server) CreateListAlias(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	var req models.CreateListAliasRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	alias, err := s.Lists.AddListAlias(listID, userID, req.Alias)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(alias)
+}
+
+// DeleteListAlias removes an alias from a shopping list.
+func (s *Server) DeleteListAlias(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	alias, err := url.PathUnescape(c.Params("alias"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid alias",
+		})
+	}
+
+	if err := s.Lists.RemoveListAlias(listID, userID, alias); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetListTrip retrieves the planned shopping trip of a list with all RSVPs.
+func (s *Server) GetListTrip(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	// Check list access
+	if !s.Lists.HasListAccess(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	trip, err := s.Trips.GetTrip(listID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(trip)
+}
+
+// PlanListTrip sets the next shopping trip of a list.
+func (s *Server) PlanListTrip(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	// Check list access
+	if !s.Lists.CanEditList(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	var req models.PlanTripRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	trip, err := s.Trips.PlanTrip(listID, userID, req.ScheduledAt, req.Note)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(trip)
+}
+
+// CancelListTrip removes the planned shopping trip of a list.
+func (s *Server) CancelListTrip(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	// Check list access
+	if !s.Lists.CanEditList(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	if err := s.Trips.CancelTrip(listID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RSVPListTrip records the authenticated user's response to the planned trip of a list.
+func (s *Server) RSVPListTrip(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	// Check list access
+	if !s.Lists.HasListAccess(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	var req models.TripRSVPRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	rsvp, err := s.Trips.RSVP(listID, userID, req.Status)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(rsvp)
+}
+
+// GetItemPoll retrieves the poll on an item with all votes and their tally.
+func (s *Server) GetItemPoll(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	// Check list access
+	if !s.Lists.HasListAccess(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	poll, err := s.Polls.GetPoll(listID, c.Params("itemId"))
+	if err != nil {
+		return pollError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(poll)
+}
+
+// CreateItemPoll starts a poll on an item, replacing any previous poll on it.
+func (s *Server) CreateItemPoll(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	// Check list access; viewers cannot change items
+	if !s.Lists.CanEditList(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	var req models.CreateItemPollRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	poll, err := s.Polls.CreatePoll(listID, c.Params("itemId"), userID, req.Question, req.Options)
+	if err != nil {
+		return pollError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(poll)
+}
+
+// DeleteItemPoll removes the poll on an item.
+func (s *Server) DeleteItemPoll(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+	itemID := c.Params("itemId")
+
+	// Check list access; viewers cannot change items
+	if !s.Lists.CanEditList(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	if _, err := s.Polls.GetPoll(listID, itemID); err != nil {
+		return pollError(c, err)
+	}
+	if err := s.Polls.DeletePoll(itemID); err != nil {
+		return pollError(c, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// VoteItemPoll records the authenticated user's vote in the poll on an item.
+func (s *Server) VoteItemPoll(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	// Check list access
+	if !s.Lists.HasListAccess(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	var req models.PollVoteRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	poll, err := s.Polls.Vote(listID, c.Params("itemId"), userID, *req.Option)
+	if err != nil {
+		return pollError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(poll)
+}
+
+// pollError maps errors of the polls service to responses.
+func pollError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, polls.ErrItemNotFound), errors.Is(err, polls.ErrPollNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, polls.ErrInvalidOptions), errors.Is(err, polls.ErrInvalidResponse):
+		status = fiber.StatusBadRequest
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// maxItemPageSize limits the limit of a page of items.
+const maxItemPageSize = 200
+
+// queryValues splits a comma separated query parameter into its trimmed, non-empty values.
+func queryValues(c *fiber.Ctx, key string) []string {
+	var values []string
+	for _, value := range strings.Split(c.Query(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// GetListItems retrieves the items of a shopping list, optionally filtered by completion, tags,
+// allergens and name. With limit or offset, a page of the items is returned with the total count.
+func (s *Server) GetListItems(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	// Check list access
+	if !s.Lists.HasListAccess(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	query := s.dbFor(c).Where("list_id = ?", listID)
+	if !c.QueryBool("include_snoozed") {
+		query = query.Where("snoozed_until IS NULL OR snoozed_until <= ?", time.Now())
+	}
+	if completed := c.Query("completed"); completed != "" {
+		value, err := strconv.ParseBool(completed)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "completed must be true or false",
+			})
+		}
+		query = query.Where("completed = ?", value)
+	}
+	query = items.WhereNameContains(query, c.Query("q"))
+	allergens := queryValues(c, "allergen")
+	for i, allergen := range allergens {
+		allergens[i] = strings.ToLower(allergen)
+	}
+	query, err := items.WhereContainsAny(query, "allergens", allergens)
+	if err == nil {
+		query, err = items.WhereContainsAny(query, "tags", queryValues(c, "tag"))
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Without limit and offset all items are returned as a plain array, as before pagination
+	paginated := c.Query("limit") != "" || c.Query("offset") != ""
+	limit, offset := c.QueryInt("limit", maxItemPageSize), c.QueryInt("offset", 0)
+	if paginated && (limit < 1 || limit > maxItemPageSize || offset < 0) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "limit must be between 1 and " + strconv.Itoa(maxItemPageSize) + " and offset must not be negative",
+		})
+	}
+
+	// Count and find share the conditions, so they must not add to each other's statement
+	query = query.Session(&gorm.Session{})
+	var total int64
+	if paginated {
+		if err := query.Model(&models.ShoppingItem{}).Count(&total).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		query = query.Limit(limit).Offset(offset)
+	}
+
+	var listItems []models.ShoppingItem
+	if err := query.Order("created_at DESC").Order("id").Find(&listItems).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	now := time.Now()
+	var list models.ShoppingList
+	if err := s.dbFor(c).Select("stale_after_days", "updated_at").First(&list, "id = ?", listID).Error; err == nil {
+		s.Items.MarkStale(listItems, list.StaleAfterDays, now)
+	}
+	if err := s.itemsFor(c).AttachCreators(listItems); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := s.itemsFor(c).AttachPurchaseStats(listItems); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Fetching the items counts as having seen the list
+	_ = s.Lists.MarkSeen(listID, userID)
+
+	if notModified(c, s.itemsFor(c).LastModified(list, listItems, now)) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	if paginated {
+		page := models.ItemPage{Items: listItems, Total: total, Limit: limit, Offset: offset}
+		if next := offset + len(listItems); int64(next) < total {
+			page.NextOffset = &next
+		}
+		return respond(c, fiber.StatusOK, page, func() (hal.Resource, error) {
+			return hal.ItemPage(listID, page)
+		})
+	}
+	return respond(c, fiber.StatusOK, listItems, func() (hal.Resource, error) {
+		return hal.Items(listID, listItems)
+	})
+}
+
+// GetCompactList returns a list with only the id, name and completion of its visible items, open
+// items first, for watch clients. The route adds an ETag, so unchanged lists answer 304 without a
+// body.
+func (s *Server) GetCompactList(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	// Check list access
+	if !s.Lists.HasListAccess(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	var list models.ShoppingList
+	if err := s.dbFor(c).Select("id", "name").First(&list, "id = ?", listID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "List not found",
+		})
+	}
+
+	items := []models.CompactItem{}
+	err := s.dbFor(c).Model(&models.ShoppingItem{}).
+		Select("id", "name", "completed").
+		Where("list_id = ?", listID).
+		Where("snoozed_until IS NULL OR snoozed_until <= ?", time.Now()).
+		Order("completed ASC, created_at DESC").
+		Find(&items).Error
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Clients must revalidate, which costs them only a 304 while the list is unchanged
+	c.Set(fiber.HeaderCacheControl, "private, no-cache")
+	return c.Status(fiber.StatusOK).JSON(models.CompactList{ID: list.ID, Name: list.Name, Items: items})
+}
+
+// ExportList renders a shopping list for printing. The only supported format is "pdf", which is
+// also the default: open items grouped by category with checkboxes and their prices, formatted
+// for the client's language. With export storage, the file is stored below exports/ and clients
+// are redirected to it if the backend supports presigned URLs.
+func (s *Server) ExportList(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	// Check list access
+	if !s.Lists.HasListAccess(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	if format := c.Query("format", "pdf"); format != "pdf" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Unsupported export format, use pdf",
+		})
+	}
+
+	display, err := s.loadDisplay(c, listID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	now := time.Now()
+	fileName := export.FileName(display.List.Name, "pdf")
+	display.List.Currency = s.Budgets.Currency(display.List)
+	var pdf bytes.Buffer
+	if err := export.PDF(&pdf, display.List, display.Items, c.Get(fiber.HeaderAcceptLanguage), now); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if s.Exports != nil {
+		key := "exports/" + listID + "/" + now.UTC().Format("20060102-150405") + "-" + fileName
+		if err := s.Exports.Put(key, pdf.Bytes(), "application/pdf"); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to store export",
+			})
+		}
+
+		// Let clients download directly from the storage backend when it supports presigned URLs
+		presigned, err := s.Exports.PresignGet(key, 15*time.Minute)
+		if err == nil {
+			return c.Redirect(presigned, fiber.StatusTemporaryRedirect)
+		}
+		if !errors.Is(err, storage.ErrPresignNotSupported) {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	c.Set(fiber.HeaderContentType, "application/pdf")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+fileName+`"`)
+	return c.Status(fiber.StatusOK).Send(pdf.Bytes())
+}
+
+// MarkListSeen records that the authenticated user has seen the current state of a list,
+// resetting its unseen changes count.
+func (s *Server) MarkListSeen(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	// Check list access
+	if !s.Lists.HasListAccess(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	if err := s.Lists.MarkSeen(listID, userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// CreateListItem creates a new item in a shopping list.
+func (s *Server) CreateListItem(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	// Check list access; viewers cannot change items
+	if !s.Lists.CanEditList(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	var req models.CreateItemRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	item, ok := s.buildItem(userID, listID, req)
+	if !ok {
+		return validationFailed(c, map[string]validation.Error{"name": {Code: validation.CodeRequired}})
+	}
+
+	if err := s.Lists.ApplyTagPolicy(listID, item.Tags); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := s.insertItem(c, &item, req.SectionID); err != nil {
+		return itemInsertError(c, err)
+	}
+
+	item = s.withCreator(item)
+	return respond(c, fiber.StatusCreated, item, func() (hal.Resource, error) {
+		return hal.Item(item)
+	})
+}
+
+// SmartAddListItem creates an item unless an open item with the same name already exists on
+// any of the user's lists. Matches are returned as duplicates instead, so the client can ask
+// before adding; with force set the item is created anyway.
+func (s *Server) SmartAddListItem(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	// Check list access; viewers cannot change items
+	if !s.Lists.CanEditList(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	var req models.SmartAddItemRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	item, ok := s.buildItem(userID, listID, req.CreateItemRequest)
+	if !ok {
+		return validationFailed(c, map[string]validation.Error{"name": {Code: validation.CodeRequired}})
+	}
+
+	if err := s.Lists.ApplyTagPolicy(listID, item.Tags); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if !req.Force {
+		duplicates, err := s.Items.FindDuplicates(userID, item.Name)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if len(duplicates) > 0 {
+			return c.Status(fiber.StatusOK).JSON(models.SmartAddItemResponse{
+				Created:    false,
+				Duplicates: duplicates,
+			})
+		}
+	}
+
+	if err := s.insertItem(c, &item, req.SectionID); err != nil {
+		return itemInsertError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(models.SmartAddItemResponse{
+		Created: true,
+		Item:    &item,
+	})
+}
+
+// itemInsertError answers a failed insertItem.
+func itemInsertError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, lists.ErrSectionNotFound) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Section not found",
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// insertItem assigns a new item to a section of its list, if any, stores it and queues it for
+// enrichment.
+func (s *Server) insertItem(c *fiber.Ctx, item *models.ShoppingItem, sectionID *string) error {
+	if sectionID != nil && *sectionID != "" {
+		if !s.Lists.SectionExists(item.ListID, *sectionID) {
+			return lists.ErrSectionNotFound
+		}
+		item.SectionID = sectionID
+	}
+
+	if err := s.dbFor(c).Create(item).Error; err != nil {
+		return err
+	}
+	userID, _ := c.Locals("user_id").(string)
+	s.itemsCreated(userID, item)
+	return nil
+}
+
+// itemsCreated runs the rules of the list on new items, queues them for enrichment and the
+// nutrition lookup and emits them to the item hook and the connected members.
+func (s *Server) itemsCreated(userID string, items ...*models.ShoppingItem) {
+	for _, item := range items {
+		s.Rules.Apply(rules.EventItemCreated, userID, item)
+		s.Enrichment.Enqueue(item.ListID, *item)
+		s.Nutrition.Enqueue(*item)
+		s.Realtime.Publish(realtime.EventItemCreated, item.ListID, item)
+		s.Extensions.Emit(extensions.EventItemCreated, models.ItemCreatedEvent{
+			ItemID:    item.ID,
+			ListID:    item.ListID,
+			UserID:    userID,
+			Name:      item.Name,
+			Quantity:  item.Quantity,
+			Unit:      item.Unit,
+			Category:  item.Category,
+			CreatedAt: item.CreatedAt,
+		})
+	}
+}
+
+// UpdateListItem updates an existing shopping list item.
+func (s *Server) UpdateListItem(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+	itemID := c.Params("itemId")
+
+	// Check list access; viewers cannot change items
+	if !s.Lists.CanEditList(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	var item models.ShoppingItem
+	if err := s.dbFor(c).Where("id = ? AND list_id = ?", itemID, listID).First(&item).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Item not found",
+		})
+	}
+
+	var req models.CreateItemRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	lookup, ok := s.applyItemUpdate(&item, req)
+	if !ok {
+		return validationFailed(c, map[string]validation.Error{"name": {Code: validation.CodeRequired}})
+	}
+	if err := s.checkItemInput(listID, req); err != nil {
+		if errors.Is(err, lists.ErrSectionNotFound) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Section not found",
+			})
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := s.dbFor(c).Save(&item).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if lookup {
+		s.Nutrition.Enqueue(item)
+	}
+
+	item = s.withCreator(item)
+	return respond(c, fiber.StatusOK, item, func() (hal.Resource, error) {
+		return hal.Item(item)
+	})
+}
+
+// applyItemUpdate changes an item as requested, without checking the tags and section against
+// the list; see checkItemInput. It reports whether the item may be another product now and needs
+// a new nutrition lookup, and false as second value if the name is empty after normalization.
+func (s *Server) applyItemUpdate(item *models.ShoppingItem, req models.CreateItemRequest) (bool, bool) {
+	parsed := parseItemInput(req)
+	name := s.Normalizer.NormalizeName(parsed.Name)
+	if name == "" {
+		return false, false
+	}
+
+	previousName := item.Name
+	item.Name = name
+	if req.Tags != "" {
+		item.Tags = req.Tags
+	}
+	if parsed.Quantity > 0 {
+		item.Quantity = parsed.Quantity
+		item.Unit = parsed.Unit
+	}
+	s.applyCategory(item, req.Category)
+
+	// A new name or barcode may be another product
+	lookup := item.Name != previousName || (req.Barcode != "" && req.Barcode != item.Barcode)
+	if req.Barcode != "" {
+		item.Barcode = req.Barcode
+	}
+
+	// An empty due date removes it
+	if req.DueDate != nil {
+		item.DueDate = parseDueDate(*req.DueDate)
+	}
+
+	// An empty section ID moves the item out of its section
+	if req.SectionID != nil {
+		item.SectionID = nil
+		if *req.SectionID != "" {
+			item.SectionID = req.SectionID
+		}
+	}
+	return lookup, true
+}
+
+// checkItemInput checks the tags of an item request against the tag policy of the list and
+// returns lists.ErrSectionNotFound for sections of other lists.
+func (s *Server) checkItemInput(listID string, req models.CreateItemRequest) error {
+	if req.Tags != "" {
+		if err := s.Lists.ApplyTagPolicy(listID, req.Tags); err != nil {
+			return err
+		}
+	}
+	if req.SectionID != nil && *req.SectionID != "" && !s.Lists.SectionExists(listID, *req.SectionID) {
+		return lists.ErrSectionNotFound
+	}
+	return nil
+}
+
+// withCreator returns the item with the display name of its creator filled in, or unchanged if
+// it cannot be looked up.
+func (s *Server) withCreator(item models.ShoppingItem) models.ShoppingItem {
+	items := []models.ShoppingItem{item}
+	_ = s.Items.AttachCreators(items)
+	return items[0]
+}
+
+// buildItem creates a new, unsaved item of the user from the request with its name normalized
+// and its quantity and category resolved. It reports false if the name is empty after
+// normalization.
+func (s *Server) buildItem(userID, listID string, req models.CreateItemRequest) (models.ShoppingItem, bool) {
+	parsed := parseItemInput(req)
+	name := s.Normalizer.NormalizeName(parsed.Name)
+	if name == "" {
+		return models.ShoppingItem{}, false
+	}
+
+	if req.Tags == "" {
+		req.Tags = "[]"
+	}
+
+	item := models.ShoppingItem{
+		ID:        uuid.New().String(),
+		ListID:    listID,
+		Name:      name,
+		Completed: false,
+		Tags:      req.Tags,
+		Quantity:  parsed.Quantity,
+		Unit:      parsed.Unit,
+		Barcode:   req.Barcode,
+		CreatedBy: &userID,
+	}
+	s.applyCategory(&item, req.Category)
+	if req.DueDate != nil {
+		item.DueDate = parseDueDate(*req.DueDate)
+	}
+
+	return item, true
+}
+
+// parseDueDate parses a validated due date like 2025-03-01. An empty string gives no due date.
+func parseDueDate(value string) *time.Time {
+	dueDate, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil
+	}
+	return &dueDate
+}
+
+// parseItemInput returns the item's name, quantity and unit. Explicit quantities in the
+// request win; otherwise the name is parsed when the client asked for it.
+func parseItemInput(req models.CreateItemRequest) quantity.Parsed {
+	if req.Quantity > 0 {
+		unit := req.Unit
+		if canonical, ok := quantity.LookupUnit(unit); ok {
+			unit = canonical
+		}
+		return quantity.Parsed{Name: req.Name, Quantity: req.Quantity, Unit: unit}
+	}
+	if req.ParseQuantity {
+		return quantity.Parse(req.Name)
+	}
+	return quantity.Parsed{Name: req.Name}
+}
+
+// applyCategory sets the item's category from the explicit request value or, when none was
+// given, from the product dictionary.
+func (s *Server) applyCategory(item *models.ShoppingItem, category string) {
+	if category != "" {
+		item.Category = category
+		item.Emoji = ""
+		if s.Normalizer.Dictionary != nil {
+			item.Emoji = s.Normalizer.Dictionary.Emoji(category)
+		}
+		return
+	}
+
+	if detected, ok := s.Normalizer.Categorize(item.Name); ok {
+		item.Category = detected.Name
+		item.Emoji = detected.Emoji
+	}
+}
+
+// ToggleListItem toggles the completion status of a shopping list item.
+func (s *Server) ToggleListItem(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+	itemID := c.Params("itemId")
+
+	// Check list access; viewers cannot change items
+	if !s.Lists.CanEditList(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	var item models.ShoppingItem
+	if err := s.dbFor(c).Where("id = ? AND list_id = ?", itemID, listID).First(&item).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Item not found",
+		})
+	}
+
+	previousCompletedAt := item.CompletedAt
+	item.Completed = !item.Completed
+	item.CompletedAt, item.CompletedBy = nil, nil
+	if item.Completed {
+		now := time.Now()
+		item.CompletedAt, item.CompletedBy = &now, &userID
+	}
+	if err := s.dbFor(c).Save(&item).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Spend is tracked on a best-effort basis, without failing the toggle
+	if item.Completed {
+		s.Rules.Apply(rules.EventItemCompleted, userID, &item)
+		_ = s.Budgets.RecordPurchase(item, userID)
+	} else if previousCompletedAt != nil {
+		_ = s.Budgets.UndoPurchase(item.ID, *previousCompletedAt)
+	}
+
+	item = s.withCreator(item)
+	s.Realtime.Publish(realtime.EventItemToggled, listID, item)
+	return respond(c, fiber.StatusOK, item, func() (hal.Resource, error) {
+		return hal.Item(item)
+	})
+}
+
+// SnoozeListItem hides an item from the active list view until the requested time.
+func (s *Server) SnoozeListItem(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+	itemID := c.Params("itemId")
+
+	// Check list access; viewers cannot change items
+	if !s.Lists.CanEditList(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	var req models.SnoozeItemRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	item, err := s.Items.Snooze(listID, itemID, req.Until)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(item)
+}
+
+// UnsnoozeListItem returns a snoozed item to the active list view.
+func (s *Server) UnsnoozeListItem(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+	itemID := c.Params("itemId")
+
+	// Check list access; viewers cannot change items
+	if !s.Lists.CanEditList(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	item, err := s.Items.Unsnooze(listID, itemID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(item)
+}
+
+// DeleteListItem removes an item from a shopping list.
+func (s *Server) DeleteListItem(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+	itemID := c.Params("itemId")
+
+	// Check list access; viewers cannot change items
+	if !s.Lists.CanEditList(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	result := s.dbFor(c).Where("id = ? AND list_id = ?", itemID, listID).Delete(&models.ShoppingItem{})
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": result.Error.Error(),
+		})
+	}
+
+	if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Item not found",
+		})
+	}
+
+	// The item stays in the trash with its attachments and poll until the retention purge
+	s.Realtime.Publish(realtime.EventItemDeleted, listID, fiber.Map{"item_id": itemID})
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetListTrash returns the deleted items of a list that can still be restored.
+func (s *Server) GetListTrash(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	// Check list access
+	if !s.Lists.HasListAccess(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	trashed, err := s.itemsFor(c).Trash(listID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(trashed)
+}
+
+// RestoreListItem moves a deleted item out of the trash and back onto its list.
+func (s *Server) RestoreListItem(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+	itemID := c.Params("itemId")
+
+	// Check list access; viewers cannot change items
+	if !s.Lists.CanEditList(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	item, err := s.Items.Restore(listID, itemID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	s.Realtime.Publish(realtime.EventItemRestored, listID, item)
+	return c.Status(fiber.StatusOK).JSON(item)
+}
+
+// batchError is a failed operation of a batch with the status its single endpoint answers.
+type batchError struct {
+	status  int
+	message string
+	fields  map[string]validation.Error
+}
+
+func (e *batchError) Error() string {
+	return e.message
+}
+
+// BatchListItems applies create, update, toggle and delete operations on the items of a list in
+// one transaction and answers the result of each. Every operation runs in a savepoint of its own,
+// so a failed one does not undo the others unless the batch is atomic; then the transaction is
+// rolled back and 409 is answered. Rules, live updates and purchases follow the commit.
+func (s *Server) BatchListItems(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	// Check list access; viewers cannot change items
+	if !s.Lists.CanEditList(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	var req models.BatchItemsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	// Tag policies and sections are checked before the transaction takes the connection
+	checked := make([]error, len(req.Operations))
+	for i, op := range req.Operations {
+		checked[i] = s.checkBatchOperation(listID, op)
+	}
+
+	results := make([]models.BatchItemResult, 0, len(req.Operations))
+	var effects []func()
+	rolledBack := false
+	err := s.dbFor(c).Transaction(func(tx *gorm.DB) error {
+		for i, op := range req.Operations {
+			result := models.BatchItemResult{Index: i, Op: op.Op, ItemID: op.ItemID}
+			var effect func()
+			err := checked[i]
+			if err == nil {
+				err = tx.Transaction(func(tx *gorm.DB) error {
+					var err error
+					effect, err = s.applyBatchOperation(tx, userID, listID, op, &result)
+					return err
+				})
+			}
+			if err != nil {
+				var failed *batchError
+				if !errors.As(err, &failed) {
+					failed = &batchError{status: fiber.StatusInternalServerError, message: err.Error()}
+				}
+				result.Status = failed.status
+				result.Item = nil
+				result.Error = failed.message
+				if failed.fields != nil {
+					result.Details = validation.Messages(failed.fields, c.Get(fiber.HeaderAcceptLanguage))
+				}
+				results = append(results, result)
+				if req.Atomic {
+					rolledBack = true
+					return err
+				}
+				continue
+			}
+			results = append(results, result)
+			if effect != nil {
+				effects = append(effects, effect)
+			}
+		}
+		return nil
+	})
+	if rolledBack {
+		return c.Status(fiber.StatusConflict).JSON(models.BatchItemsResponse{
+			Committed: false,
+			Results:   results,
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	for _, result := range results {
+		if result.Item != nil {
+			*result.Item = s.withCreator(*result.Item)
+		}
+	}
+	for _, effect := range effects {
+		effect()
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.BatchItemsResponse{
+		Committed: true,
+		Results:   results,
+	})
+}
+
+// checkBatchOperation validates an operation of a batch and checks its item against the tag
+// policy and the sections of the list.
+func (s *Server) checkBatchOperation(listID string, op models.BatchItemOperation) error {
+	if err := validation.ValidateStruct(op); err != nil {
+		return &batchError{status: fiber.StatusBadRequest, message: "Validation failed", fields: validation.Errors(err)}
+	}
+
+	missing := ""
+	switch {
+	case (op.Op == "create" || op.Op == "update") && op.Item == nil:
+		missing = "item"
+	case op.Op != "create" && op.ItemID == "":
+		missing = "item_id"
+	}
+	if missing != "" {
+		return &batchError{
+			status:  fiber.StatusBadRequest,
+			message: "Validation failed",
+			fields:  map[string]validation.Error{missing: {Code: validation.CodeRequired}},
+		}
+	}
+
+	if op.Item != nil {
+		if err := s.checkItemInput(listID, *op.Item); err != nil {
+			if errors.Is(err, lists.ErrSectionNotFound) {
+				return &batchError{status: fiber.StatusBadRequest, message: "Section not found"}
+			}
+			return &batchError{status: fiber.StatusBadRequest, message: err.Error()}
+		}
+	}
+	return nil
+}
+
+// applyBatchOperation applies a checked operation of a batch in the transaction and fills in its
+// result. It returns what is left to do once the transaction is committed, if anything.
+func (s *Server) applyBatchOperation(tx *gorm.DB, userID, listID string, op models.BatchItemOperation, result *models.BatchItemResult) (func(), error) {
+	nameRequired := &batchError{
+		status:  fiber.StatusBadRequest,
+		message: "Validation failed",
+		fields:  map[string]validation.Error{"name": {Code: validation.CodeRequired}},
+	}
+
+	switch op.Op {
+	case "create":
+		item, ok := s.buildItem(userID, listID, *op.Item)
+		if !ok {
+			return nil, nameRequired
+		}
+		// A replayed create finds the item it created before
+		if op.ItemID != "" {
+			var count int64
+			if err := tx.Model(&models.ShoppingItem{}).Where("id = ?", op.ItemID).Count(&count).Error; err != nil {
+				return nil, err
+			}
+			if count > 0 {
+				return nil, &batchError{status: fiber.StatusConflict, message: "Item already exists"}
+			}
+			item.ID = op.ItemID
+		}
+		if op.Item.SectionID != nil && *op.Item.SectionID != "" {
+			item.SectionID = op.Item.SectionID
+		}
+		if err := tx.Create(&item).Error; err != nil {
+			return nil, err
+		}
+		result.Status = fiber.StatusCreated
+		result.ItemID = item.ID
+		result.Item = &item
+		return func() { s.itemsCreated(userID, &item) }, nil
+
+	case "update":
+		item, err := findBatchItem(tx, listID, op.ItemID)
+		if err != nil {
+			return nil, err
+		}
+		lookup, ok := s.applyItemUpdate(&item, *op.Item)
+		if !ok {
+			return nil, nameRequired
+		}
+		if err := tx.Save(&item).Error; err != nil {
+			return nil, err
+		}
+		result.Status = fiber.StatusOK
+		result.Item = &item
+		if !lookup {
+			return nil, nil
+		}
+		return func() { s.Nutrition.Enqueue(item) }, nil
+
+	case "toggle":
+		item, err := findBatchItem(tx, listID, op.ItemID)
+		if err != nil {
+			return nil, err
+		}
+		result.Status = fiber.StatusOK
+		result.Item = &item
+		completed := !item.Completed
+		if op.Completed != nil {
+			completed = *op.Completed
+		}
+		if completed == item.Completed {
+			return nil, nil
+		}
+
+		previousCompletedAt := item.CompletedAt
+		item.Completed = completed
+		item.CompletedAt, item.CompletedBy = nil, nil
+		if completed {
+			now := time.Now()
+			item.CompletedAt, item.CompletedBy = &now, &userID
+		}
+		if err := tx.Save(&item).Error; err != nil {
+			return nil, err
+		}
+		return func() {
+			if item.Completed {
+				s.Rules.Apply(rules.EventItemCompleted, userID, &item)
+				_ = s.Budgets.RecordPurchase(item, userID)
+			} else if previousCompletedAt != nil {
+				_ = s.Budgets.UndoPurchase(item.ID, *previousCompletedAt)
+			}
+			s.Realtime.Publish(realtime.EventItemToggled, listID, item)
+		}, nil
+
+	case "delete":
+		deleted := tx.Where("id = ? AND list_id = ?", op.ItemID, listID).Delete(&models.ShoppingItem{})
+		if deleted.Error != nil {
+			return nil, deleted.Error
+		}
+		if deleted.RowsAffected == 0 {
+			return nil, &batchError{status: fiber.StatusNotFound, message: "Item not found"}
+		}
+		result.Status = fiber.StatusNoContent
+		// Like single deletes, the item keeps its attachments and poll while in the trash
+		return func() {
+			s.Realtime.Publish(realtime.EventItemDeleted, listID, fiber.Map{"item_id": op.ItemID})
+		}, nil
+	}
+	return nil, &batchError{status: fiber.StatusBadRequest, message: "Unknown operation"}
+}
+
+// findBatchItem loads an item of the list in the transaction of a batch.
+func findBatchItem(tx *gorm.DB, listID, itemID string) (models.ShoppingItem, error) {
+	var item models.ShoppingItem
+	if err := tx.Where("id = ? AND list_id = ?", itemID, listID).First(&item).Error; err != nil {
+		return item, &batchError{status: fiber.StatusNotFound, message: "Item not found"}
+	}
+	return item, nil
+}
+
+// GetItemImage sends the product image of an item through the image proxy, so clients do not
+// fetch it from third-party servers.
+func (s *Server) GetItemImage(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	// Check list access
+	if !s.Lists.HasListAccess(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	var item models.ShoppingItem
+	if err := s.dbFor(c).First(&item, "id = ? AND list_id = ?", c.Params("itemId"), listID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Item not found",
+		})
+	}
+	if item.ImageURL == "" {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Item has no image",
+		})
+	}
+
+	image, contentType, err := s.Images.Get(c.Context(), item.ImageURL)
+	if err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderCacheControl, "private, max-age=86400")
+	return c.Send(image)
+}
+
+// GetItemAttachments retrieves all attachments of an item.
+func (s *Server) GetItemAttachments(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+	itemID := c.Params("itemId")
+
+	// Check list access
+	if !s.Lists.HasListAccess(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	attachments, err := s.Attachments.GetItemAttachments(itemID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(attachments)
+}
+
+// UploadItemAttachment attaches the uploaded multipart "file" to an item.
+func (s *Server) UploadItemAttachment(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+	itemID := c.Params("itemId")
+
+	// Check list access; viewers cannot change items
+	if !s.Lists.CanEditList(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Missing file",
+		})
+	}
+	if header.Size > s.Attachments.MaxFileBytes {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"error": "attachment is too large",
+		})
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid file",
+		})
+	}
+	defer func() { _ = file.Close() }()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid file",
+		})
+	}
+
+	attachment, err := s.Attachments.Upload(userID, listID, itemID, header.Filename, data)
+	if errors.Is(err, attachments.ErrQuotaExceeded) {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(attachment)
+}
+
+// DownloadItemAttachment sends the content of an attachment, or its thumbnail with ?thumbnail=true.
+// Storage backends supporting presigned URLs redirect the client to the object instead.
+func (s *Server) DownloadItemAttachment(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+	itemID := c.Params("itemId")
+
+	// Check list access
+	if !s.Lists.HasListAccess(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	attachment, err := s.Attachments.GetAttachment(c.Params("attachmentId"), itemID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	thumbnail := c.QueryBool("thumbnail")
+
+	// Let clients download directly from the storage backend when it supports presigned URLs
+	presigned, err := s.Attachments.PresignURL(attachment, thumbnail, 15*time.Minute)
+	if err == nil {
+		return c.Redirect(presigned, fiber.StatusTemporaryRedirect)
+	}
+	if !errors.Is(err, storage.ErrPresignNotSupported) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	content, err := s.Attachments.Open(attachment, thumbnail)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	contentType := attachment.ContentType
+	if thumbnail {
+		contentType = attachments.ThumbnailContentType(attachment)
+	}
+	if !thumbnail {
+		c.Attachment(attachment.FileName)
+	}
+	c.Set(fiber.HeaderContentType, contentType)
+	return c.SendStream(content)
+}
+
+// DeleteItemAttachment removes an attachment from an item.
+func (s *Server) DeleteItemAttachment(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+	itemID := c.Params("itemId")
+
+	// Check list access; viewers cannot change items
+	if !s.Lists.CanEditList(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	attachment, err := s.Attachments.GetAttachment(c.Params("attachmentId"), itemID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := s.Attachments.DeleteAttachment(attachment); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetStorageUsage reports the attachment storage used by the authenticated user and their quota.
+func (s *Server) GetStorageUsage(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	usage, err := s.Attachments.Usage(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(usage)
+}
+
+// GetContacts retrieves the users the authenticated user shares lists with.
+func (s *Server) GetContacts(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	contacts, err := s.Lists.GetContacts(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(contacts)
+}
+
+// GetSpendReport reports the spend of a month, ?month=2025-03 or the current one, on all lists
+// of the user or, with ?list_id, on one list.
+func (s *Server) GetSpendReport(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	report, err := s.budgetsFor(c).Report(userID, c.Query("month"), c.Query("list_id"))
+	if errors.Is(err, budgets.ErrInvalidMonth) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(report)
+}
+
+// GetListFairness compares what the members of a list contributed within the last ?days=90 days.
+func (s *Server) GetListFairness(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	// Check list access
+	if !s.Lists.HasListAccess(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	report, err := s.fairnessFor(c).Report(listID, c.QueryInt("days", fairness.DefaultDays), time.Now())
+	if errors.Is(err, fairness.ErrInvalidDays) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(report)
+}
+
+// SearchItemHistory searches the completed items of the user's lists, archived ones included, by
+// name and completion date, e.g. ?q=detergent&from=2025-01-01&to=2025-03-31.
+func (s *Server) SearchItemHistory(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	listID := c.Query("list_id")
+	if listID != "" && !s.Lists.HasListAccess(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	history, err := s.Items.History(userID, models.HistoryFilter{
+		Query:  c.Query("q"),
+		ListID: listID,
+		From:   c.Query("from"),
+		To:     c.Query("to"),
+		Limit:  c.QueryInt("limit", 50),
+	})
+	if errors.Is(err, items.ErrInvalidDate) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(history)
+}
+
+// GetReplenishSuggestions returns the items the user's lists likely need soon, or one list's
+// with ?list_id=.
+func (s *Server) GetReplenishSuggestions(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	listID := c.Query("list_id")
+	if listID != "" && !s.Lists.HasListAccess(listID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	suggestions, err := s.Replenish.Suggestions(userID, listID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(suggestions)
+}
+
+// AcceptReplenishSuggestion adds a suggested item to its list and tunes the prediction to when
+// it was needed.
+func (s *Server) AcceptReplenishSuggestion(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	suggestion, err := s.Replenish.Get(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Suggestion not found",
+		})
+	}
+
+	// Check list access; viewers cannot change items
+	if !s.Lists.CanEditList(suggestion.ListID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	item, ok := s.buildItem(userID, suggestion.ListID, models.CreateItemRequest{Name: suggestion.Name})
+	if !ok {
+		return validationFailed(c, map[string]validation.Error{"name": {Code: validation.CodeRequired}})
+	}
+	if err := s.insertItem(c, &item, nil); err != nil {
+		return itemInsertError(c, err)
+	}
+	if err := s.Replenish.Accept(suggestion, time.Now()); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	item = s.withCreator(item)
+	return respond(c, fiber.StatusCreated, item, func() (hal.Resource, error) {
+		return hal.Item(item)
+	})
+}
+
+// DismissReplenishSuggestion hides a suggestion until the item is bought again and stretches
+// the interval it is predicted with.
+func (s *Server) DismissReplenishSuggestion(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	suggestion, err := s.Replenish.Get(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Suggestion not found",
+		})
+	}
+
+	// Check list access; viewers cannot tune the suggestions of a list
+	if !s.Lists.CanEditList(suggestion.ListID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	if err := s.Replenish.Dismiss(suggestion); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetReplenishSettings returns the replenishment preferences of the authenticated user.
+func (s *Server) GetReplenishSettings(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	settings, err := s.Replenish.Settings(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(settings)
+}
+
+// UpdateReplenishSettings changes the replenishment preferences of the authenticated user.
+func (s *Server) UpdateReplenishSettings(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req models.ReplenishSettings
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := s.Replenish.UpdateSettings(userID, req); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(req)
+}
+
+// ConvertUnits converts a quantity between units, e.g. ?quantity=500&from=g&to=kg. Without "to"
+// the quantity is expressed in the unit that reads best; with "pack_size" packs convert into
+// pieces.
+func (s *Server) ConvertUnits(c *fiber.Ctx) error {
+	amount, err := strconv.ParseFloat(c.Query("quantity"), 64)
+	if err != nil || amount < 0 || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid quantity",
+		})
+	}
+
+	packSize := 0.0
+	if raw := c.Query("pack_size"); raw != "" {
+		packSize, err = strconv.ParseFloat(raw, 64)
+		if err != nil || packSize <= 0 || math.IsInf(packSize, 0) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid pack size",
+			})
+		}
+	}
+
+	var result quantity.Amount
+	if to := c.Query("to"); to != "" {
+		converted, err := quantity.Convert(amount, c.Query("from"), to, packSize)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		unit, _ := quantity.LookupUnit(to)
+		result = quantity.Amount{Quantity: converted, Unit: unit}
+	} else {
+		unit, ok := quantity.LookupUnit(c.Query("from"))
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": quantity.ErrUnknownUnit.Error(),
+			})
+		}
+		result = quantity.Normalize(quantity.Amount{Quantity: amount, Unit: unit})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.UnitConversion{
+		Quantity:  result.Quantity,
+		Unit:      result.Unit,
+		Formatted: quantity.FormatLocale(result, c.Get(fiber.HeaderAcceptLanguage)),
+	})
+}
+
+// GetSmartLists retrieves the saved filters of the authenticated user.
+func (s *Server) GetSmartLists(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	smartLists, err := s.SmartLists.GetSmartLists(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(smartLists)
+}
+
+// CreateSmartList stores a new saved filter for the authenticated user.
+func (s *Server) CreateSmartList(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req models.SmartListRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	smartList, err := s.SmartLists.CreateSmartList(userID, req.Name, req.Filter)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(smartList)
+}
+
+// UpdateSmartList replaces the name and filter of a saved filter.
+func (s *Server) UpdateSmartList(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	smartListID := c.Params("id")
+
+	var req models.SmartListRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	smartList, err := s.SmartLists.UpdateSmartList(smartListID, userID, req.Name, req.Filter)
+	if errors.Is(err, smartlists.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Smart list not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(smartList)
+}
+
+// DeleteSmartList removes a saved filter.
+func (s *Server) DeleteSmartList(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	smartListID := c.Params("id")
+
+	err := s.SmartLists.DeleteSmartList(smartListID, userID)
+	if errors.Is(err, smartlists.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Smart list not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetSmartListItems retrieves the items matching a saved filter across the user's lists.
+func (s *Server) GetSmartListItems(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	smartListID := c.Params("id")
+
+	smartList, err := s.SmartLists.GetSmartList(smartListID, userID)
+	if errors.Is(err, smartlists.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Smart list not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	items, err := s.Items.Find(userID, smartList.Filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(items)
+}
+
+// GetPrivacySettings retrieves the privacy preferences of the authenticated user.
+func (s *Server) GetPrivacySettings(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	settings, err := s.Auth.GetPrivacySettings(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(settings)
+}
+
+// UpdatePrivacySettings changes the privacy preferences of the authenticated user.
+func (s *Server) UpdatePrivacySettings(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req models.PrivacySettings
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := s.Auth.UpdatePrivacySettings(userID, req); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(req)
+}
+
+// RunHousekeeping reports the empty and inactive lists and stale invitations of the authenticated
+// user and removes them if requested.
+func (s *Server) RunHousekeeping(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req models.HousekeepingRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	report, err := s.Housekeeping.Run(userID, req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if report.Removed {
+		for _, list := range slices.Concat(report.EmptyLists, report.InactiveLists) {
+			if err := s.Attachments.DeleteListAttachments(list.ID); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": err.Error(),
+				})
+			}
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(report)
+}
+
+// GetAllStorageUsage reports the attachment storage used by every user (admin only).
+func (s *Server) GetAllStorageUsage(c *fiber.Ctx) error {
+	usages, err := s.Attachments.GetAllUsage()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if usages == nil {
+		usages = []models.StorageUsage{}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(usages)
+}
+
+// GetServerSettings retrieves the server settings.
+func (s *Server) GetServerSettings(c *fiber.Ctx) error {
+	settings, err := s.Setup.GetSettings()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(settings)
+}
+
+// UpdateServerSettings changes the default list policy and localized default list names.
+func (s *Server) UpdateServerSettings(c *fiber.Ctx) error {
+	var req models.UpdateServerSettingsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	settings, err := s.Setup.UpdateSettings(req)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(settings)
+}
+
+// ExportServerBundle exports the server settings, category mappings and feature flags as a
+// bundle to import on another instance (admin only).
+func (s *Server) ExportServerBundle(c *fiber.Ctx) error {
+	bundle, err := s.Fleet.Export(time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="server-bundle.json"`)
+	return c.Status(fiber.StatusOK).JSON(bundle)
+}
+
+// ImportServerBundle applies a bundle exported by another instance, or with ?dry_run=true reports
+// what it would change and rolls it back (admin only). Both are recorded in the audit log.
+func (s *Server) ImportServerBundle(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	dryRun := c.QueryBool("dry_run")
+
+	var bundle models.ServerBundle
+	if err := c.BodyParser(&bundle); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(bundle); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	// Dry runs import into a scratch dictionary, as the mappings are rolled back
+	var result *models.ServerBundleImport
+	err := s.runOrPreview(dryRun, func(tx *gorm.DB) (err error) {
+		fleetService := s.Fleet
+		if dryRun {
+			fleetService = fleet.NewService(tx, catalog.NewDictionary())
+		}
+		result, err = fleetService.Import(userID, bundle)
+		return err
+	})
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	result.DryRun = dryRun
+
+	summary := map[string]int{"category_mappings": result.CategoryMappings, "feature_flags": result.FeatureFlags}
+	if err := s.Moderation.RecordServerAction(userID, moderation.ActionImportServerBundle, dryRun, summary); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
+
+// GetRetentionStatus reports the retention policies and when old data is purged next.
+func (s *Server) GetRetentionStatus(c *fiber.Ctx) error {
+	status, err := s.Retention.Status()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(status)
+}
+
+// PurgeRetention deletes the data older than its retention period right away, or with
+// ?dry_run=true only counts it. Both are recorded in the audit log.
+func (s *Server) PurgeRetention(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(string)
+	dryRun := c.QueryBool("dry_run")
+
+	purge := s.Retention.Purge
+	if dryRun {
+		purge = s.Retention.Preview
+	}
+	result, err := purge()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := s.Moderation.RecordServerAction(adminID, moderation.ActionPurgeRetention, dryRun, result.Purged); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
+
+// GetOperatorDigest previews the weekly digest emailed to the admin.
+func (s *Server) GetOperatorDigest(c *fiber.Ctx) error {
+	report, err := s.Digest.Collect()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(report)
+}
+
+// CheckDatabase runs the database integrity check and reports orphaned rows without changing anything.
+func (s *Server) CheckDatabase(c *fiber.Ctx) error {
+	report, err := s.Integrity.Check(false)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(report)
+}
+
+// RepairDatabase runs the database integrity check and deletes orphaned rows, or with
+// ?dry_run=true only reports them. Both are recorded in the audit log.
+func (s *Server) RepairDatabase(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(string)
+	dryRun := c.QueryBool("dry_run")
+
+	report, err := s.Integrity.Check(!dryRun)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	report.DryRun = dryRun
+
+	if err := s.Moderation.RecordServerAction(adminID, moderation.ActionRepairDatabase, dryRun, report.Orphans); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(report)
+}
+
+// CreateSnapshot writes a consistent copy of the database and runs the snapshot hook.
+func (s *Server) CreateSnapshot(c *fiber.Ctx) error {
+	result, err := s.Snapshots.Snapshot()
+	if errors.Is(err, snapshot.ErrNotSQLite) {
+		return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":    err.Error(),
+			"snapshot": result,
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(result)
+}
+
+// CheckpointDatabase checkpoints the WAL using the mode given in the "mode" query parameter.
+func (s *Server) CheckpointDatabase(c *fiber.Ctx) error {
+	result, err := s.Snapshots.Checkpoint(c.Query("mode"))
+	if errors.Is(err, snapshot.ErrInvalidCheckpointMode) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if errors.Is(err, snapshot.ErrNotSQLite) {
+		return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
+
+// CreateInvitation creates a new invitation for server or list access.
+func (s *Server) CreateInvitation(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req models.CreateInvitationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	var invitation *models.Invitation
+	var err error
+	if req.Type == "bundle" {
+		invitation, err = s.Invitations.CreateBundleInvitation(userID, req.Email, req.ListIDs)
+	} else {
+		invitation, err = s.Invitations.CreateInvitation(userID, req.Email, req.Type, req.ListID)
+	}
+	if errors.Is(err, invitations.ErrInvitationsFrozen) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	s.invitationCreated(invitation)
+
+	return c.Status(fiber.StatusCreated).JSON(invitation)
+}
+
+// invitationCreated emits a new invitation to the invitation hook. The code is left out, it is
+// only meant for the invited person.
+func (s *Server) invitationCreated(invitation *models.Invitation) {
+	listIDs := []string{}
+	if invitation.ListID != nil {
+		listIDs = append(listIDs, *invitation.ListID)
+	}
+	for _, list := range invitation.Lists {
+		listIDs = append(listIDs, list.ListID)
+	}
+
+	s.Extensions.Emit(extensions.EventInvitationCreated, models.InvitationCreatedEvent{
+		InvitationID: invitation.ID,
+		Type:         invitation.Type,
+		InvitedBy:    invitation.InvitedBy,
+		Email:        invitation.Email,
+		ListIDs:      listIDs,
+		ExpiresAt:    invitation.ExpiresAt,
+	})
+}
+
+// GetInvitations retrieves all invitations created by the authenticated user.
+func (s *Server) GetInvitations(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	invitations, err := s.Invitations.GetUserInvitations(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(invitations)
+}
+
+// GetReceivedInvitations retrieves the pending invitations addressed to the authenticated user.
+func (s *Server) GetReceivedInvitations(c *fiber.Ctx) error {
+	email := c.Locals("user_email").(string)
+
+	invitations, err := s.Invitations.GetPendingInvitations(email)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(invitations)
+}
+
+// AcceptReceivedInvitation accepts a pending invitation addressed to the authenticated user.
+func (s *Server) AcceptReceivedInvitation(c *fiber.Ctx) error {
+	user := models.User{
+		ID:    c.Locals("user_id").(string),
+		Email: c.Locals("user_email").(string),
+	}
+
+	joined, err := s.Onboarding.Accept(&user, c.Params("id"))
+	if errors.Is(err, onboarding.ErrInvitationNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Invitation not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to accept invitation",
+		})
+	}
+
+	s.memberAdded(user.ID, joined...)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// memberAdded tells the connected members of lists that a user joined them.
+func (s *Server) memberAdded(userID string, listIDs ...string) {
+	for _, listID := range listIDs {
+		s.Realtime.Publish(realtime.EventMemberAdded, listID, fiber.Map{"user_id": userID})
+	}
+}
+
+// DeclineReceivedInvitation deletes a pending invitation addressed to the authenticated user.
+func (s *Server) DeclineReceivedInvitation(c *fiber.Ctx) error {
+	email := c.Locals("user_email").(string)
+
+	if err := s.Invitations.DeclineInvitation(c.Params("id"), email); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RevokeInvitation cancels an invitation if the user is the original inviter.
+func (s *Server) RevokeInvitation(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	invitationID := c.Params("id")
+
+	err := s.Invitations.RevokeInvitation(invitationID, userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetCategoryMappings retrieves all custom category mappings (admin only).
+func (s *Server) GetCategoryMappings(c *fiber.Ctx) error {
+	mappings, err := s.Catalog.GetMappings()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(mappings)
+}
+
+// CreateCategoryMapping adds a custom keyword to category mapping (admin only).
+func (s *Server) CreateCategoryMapping(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req models.CreateCategoryMappingRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	mapping, err := s.Catalog.AddMapping(userID, req.Keyword, req.Category, req.Language)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(mapping)
+}
+
+// GetFeatureFlags lists all feature flags with their user overrides (admin only).
+func (s *Server) GetFeatureFlags(c *fiber.Ctx) error {
+	flagList, err := s.Flags.List()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(flagList)
+}
+
+// SetFeatureFlag creates or updates a feature flag (admin only).
+func (s *Server) SetFeatureFlag(c *fiber.Ctx) error {
+	var req models.FeatureFlagRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	flag, err := s.Flags.Set(c.Params("key"), req.Rollout, req.Description)
+	if errors.Is(err, flags.ErrInvalidKey) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(flag)
+}
+
+// DeleteFeatureFlag removes a feature flag and its overrides, or with ?dry_run=true only counts
+// them (admin only). Both are recorded in the audit log.
+func (s *Server) DeleteFeatureFlag(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(string)
+	dryRun := c.QueryBool("dry_run")
+
+	var overrides int64
+	err := s.runOrPreview(dryRun, func(tx *gorm.DB) (err error) {
+		overrides, err = flags.NewService(tx).Delete(c.Params("key"))
+		return err
+	})
+	if errors.Is(err, flags.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return s.deleted(c, adminID, moderation.ActionDeleteFeatureFlag, dryRun, map[string]int64{
+		"feature_flags":          1,
+		"feature_flag_overrides": overrides,
+	})
+}
+
+// SetFeatureFlagOverride forces a feature flag on or off for a user (admin only).
+func (s *Server) SetFeatureFlagOverride(c *fiber.Ctx) error {
+	var req models.FeatureFlagOverrideRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	var user models.User
+	if err := s.dbFor(c).Select("id").First(&user, "id = ?", c.Params("userId")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	override, err := s.Flags.SetOverride(c.Params("key"), user.ID, req.Enabled)
+	if errors.Is(err, flags.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(override)
+}
+
+// DeleteFeatureFlagOverride lets the rollout decide a feature flag for a user again, or with
+// ?dry_run=true only checks the override exists (admin only). Both are recorded in the audit log.
+func (s *Server) DeleteFeatureFlagOverride(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(string)
+	dryRun := c.QueryBool("dry_run")
+
+	err := s.runOrPreview(dryRun, func(tx *gorm.DB) error {
+		return flags.NewService(tx).DeleteOverride(c.Params("key"), c.Params("userId"))
+	})
+	if errors.Is(err, flags.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return s.deleted(c, adminID, moderation.ActionDeleteFlagOverride, dryRun, map[string]int64{
+		"feature_flag_overrides": 1,
+	})
+}
+
+// RevokeUserSessions logs a user out of all devices (admin only).
+func (s *Server) RevokeUserSessions(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(string)
+
+	err := s.Moderation.RevokeSessions(adminID, c.Params("userId"))
+	if errors.Is(err, moderation.ErrUserNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// FreezeUserInvitations freezes or unfreezes a user's ability to send invitations (admin only).
+func (s *Server) FreezeUserInvitations(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(string)
+
+	var req models.FreezeInvitationsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	err := s.Moderation.SetInvitationsFrozen(adminID, c.Params("userId"), *req.Frozen)
+	if errors.Is(err, moderation.ErrUserNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetAuditLog retrieves the latest admin actions on user accounts and the server (admin only).
+func (s *Server) GetAuditLog(c *fiber.Ctx) error {
+	entries, err := s.Moderation.AuditLog(c.QueryInt("limit", moderation.DefaultAuditLogLimit))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(entries)
+}
+
+// GetAnnouncements retrieves all active admin announcements.
+func (s *Server) GetAnnouncements(c *fiber.Ctx) error {
+	announcements, err := s.Announcements.GetActiveAnnouncements()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(announcements)
+}
+
+// CreateAnnouncement posts a new announcement and optionally emails it to all users (admin only).
+func (s *Server) CreateAnnouncement(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req models.CreateAnnouncementRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	announcement, err := s.Announcements.CreateAnnouncement(userID, req.Title, req.Body, req.ExpiresAt)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if req.SendEmail {
+		if _, err := s.Announcements.Broadcast(announcement); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(announcement)
+}
+
+// DeleteAnnouncement removes an announcement, or with ?dry_run=true only checks it exists (admin
+// only). Both are recorded in the audit log.
+func (s *Server) DeleteAnnouncement(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(string)
+	dryRun := c.QueryBool("dry_run")
+
+	err := s.runOrPreview(dryRun, func(tx *gorm.DB) error {
+		return announcements.NewService(tx, s.Announcements.Mailer).DeleteAnnouncement(c.Params("id"))
+	})
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return s.deleted(c, adminID, moderation.ActionDeleteAnnouncement, dryRun, map[string]int64{
+		"announcements": 1,
+	})
+}
+
+// runOrPreview runs fn against the database, or with dryRun in a transaction that is rolled back
+// afterwards, see db.DryRun.
+func (s *Server) runOrPreview(dryRun bool, fn func(tx *gorm.DB) error) error {
+	if dryRun {
+		return db.DryRun(s.DB, fn)
+	}
+	return fn(s.DB)
+}
+
+// deleted records an admin delete with the rows it removed in the audit log and responds with
+// 204, or with the counted rows for dry runs.
+func (s *Server) deleted(c *fiber.Ctx, adminID, action string, dryRun bool, rows map[string]int64) error {
+	if err := s.Moderation.RecordServerAction(adminID, action, dryRun, rows); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if dryRun {
+		return c.Status(fiber.StatusOK).JSON(models.DeletePreview{Deleted: rows, DryRun: true})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// QuickAdd parses a free-text sentence like "add milk and eggs to groceries", resolves the
+// target list by name and creates all mentioned items.
+func (s *Server) QuickAdd(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req models.QuickAddRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	command := quickadd.Parse(req.Text)
+	if len(command.Items) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":      "No items found in text",
+			"understood": command,
+		})
+	}
+
+	list, err := s.quickAddList(userID, req.ListID, command)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":      err.Error(),
+			"understood": command,
+		})
+	}
+
+	items, err := s.quickAddItems(s.dbFor(c), userID, list.ID, command)
+	if errors.Is(err, lists.ErrReadOnly) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"list":       list,
+		"items":      items,
+		"understood": command,
+	})
+}
+
+// QuickAddText adds the items of a quick-add sentence on behalf of the user, for integrations
+// outside of HTTP such as the Matrix bot.
+func (s *Server) QuickAddText(userID, text string) (*models.ShoppingList, []models.ShoppingItem, error) {
+	command := quickadd.Parse(text)
+	if len(command.Items) == 0 {
+		return nil, nil, errors.New("no items found in text")
+	}
+
+	list, err := s.quickAddList(userID, "", command)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	items, err := s.quickAddItems(s.DB, userID, list.ID, command)
+	if err != nil {
+		return nil, nil, err
+	}
+	return list, items, nil
+}
+
+// quickAddList resolves the target list of a quick-add command: the given list, the list the
+// sentence names or the user's default list.
+func (s *Server) quickAddList(userID, listID string, command quickadd.Command) (*models.ShoppingList, error) {
+	switch {
+	case listID != "":
+		return s.Lists.GetListByID(listID, userID)
+	case command.ListName != "":
+		var list *models.ShoppingList
+		var err error
+		for _, candidate := range quickadd.ListNameCandidates(command.ListName) {
+			if list, err = s.Lists.FindListByName(userID, candidate); err == nil {
+				break
+			}
+		}
+		return list, err
+	default:
+		return s.Lists.GetDefaultList(userID)
+	}
+}
+
+// quickAddItems creates the items of a quick-add command on the list on behalf of the user. It
+// returns lists.ErrReadOnly if the user only views the list.
+func (s *Server) quickAddItems(db *gorm.DB, userID, listID string, command quickadd.Command) ([]models.ShoppingItem, error) {
+	if !s.Lists.CanEditList(listID, userID) {
+		return nil, lists.ErrReadOnly
+	}
+
+	items := make([]models.ShoppingItem, 0, len(command.Items))
+	for _, input := range command.Items {
+		item, ok := s.buildItem(userID, listID, models.CreateItemRequest{Name: input, ParseQuantity: true})
+		if ok {
+			items = append(items, item)
+		}
+	}
+
+	if len(items) > 0 {
+		if err := db.Create(&items).Error; err != nil {
+			return nil, err
+		}
+		for i := range items {
+			s.itemsCreated(userID, &items[i])
+		}
+	}
+	return items, nil
+}
+
+// GetNewItems is the "new item added" polling trigger for automation platforms. It returns the
+// items added after the since cursor, optionally only on the list given by name.
+func (s *Server) GetNewItems(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var listID string
+	if name := c.Query("list"); name != "" {
+		list, err := s.Lists.FindListByName(userID, name)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		listID = list.ID
+	}
+
+	items, since, err := s.Integrations.NewItems(userID, listID, c.Query("since"), integrations.DefaultLimit)
+	if errors.Is(err, integrations.ErrInvalidCursor) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.IntegrationItemsResponse{
+		Items: items,
+		Since: since,
+	})
+}
+
+// AddItemByListName is the "add item" action for automation platforms, which know lists by
+// name rather than ID.
+func (s *Server) AddItemByListName(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req models.IntegrationAddItemRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	list, err := s.Lists.FindListByName(userID, req.List)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if !s.Lists.CanEditList(list.ID, userID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": lists.ErrReadOnly.Error(),
+		})
+	}
+
+	item, ok := s.buildItem(userID, list.ID, models.CreateItemRequest{
+		Name:          req.Name,
+		Quantity:      req.Quantity,
+		Unit:          req.Unit,
+		ParseQuantity: true,
+	})
+	if !ok {
+		return validationFailed(c, map[string]validation.Error{"name": {Code: validation.CodeRequired}})
+	}
+
+	if err := s.dbFor(c).Create(&item).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	s.itemsCreated(userID, &item)
+
+	return c.Status(fiber.StatusCreated).JSON(models.IntegrationItem{
+		ID:        item.ID,
+		ListID:    list.ID,
+		ListName:  list.Name,
+		Name:      item.Name,
+		Quantity:  item.Quantity,
+		Unit:      item.Unit,
+		Category:  item.Category,
+		Completed: item.Completed,
+		CreatedAt: item.CreatedAt,
+	})
+}
+
+// shortcutList resolves the list named in the "list" query parameter of a Shortcuts request, or
+// the user's default list without one.
+func (s *Server) shortcutList(c *fiber.Ctx, userID string) (*models.ShoppingList, error) {
+	if name := c.Query("list"); name != "" {
+		return s.Lists.FindListByName(userID, name)
+	}
+	return s.Lists.GetDefaultList(userID)
+}
+
+// ShortcutAddItem adds the item of the "item" query parameter to a list by name and answers in
+// plain text, so Apple Shortcuts can show the answer as is.
+func (s *Server) ShortcutAddItem(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	name := strings.TrimSpace(c.Query("item"))
+	if name == "" {
+		return c.Status(fiber.StatusBadRequest).SendString("Missing item")
+	}
+
+	list, err := s.shortcutList(c, userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if !s.Lists.CanEditList(list.ID, userID) {
+		return c.Status(fiber.StatusForbidden).SendString(lists.ErrReadOnly.Error())
+	}
+
+	item, ok := s.buildItem(userID, list.ID, models.CreateItemRequest{Name: name, ParseQuantity: true})
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).SendString("Missing item")
+	}
+	if err := s.dbFor(c).Create(&item).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	s.itemsCreated(userID, &item)
+
+	return c.Status(fiber.StatusOK).SendString("Added " + item.Name + " to " + list.Name)
+}
+
+// ShortcutListItems lists the open items of a list by name in plain text, one per line.
+func (s *Server) ShortcutListItems(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	list, err := s.shortcutList(c, userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+
+	var items []models.ShoppingItem
+	err = s.dbFor(c).Where("list_id = ? AND completed = ?", list.ID, false).
+		Where("snoozed_until IS NULL OR snoozed_until <= ?", time.Now()).
+		Order("created_at ASC").
+		Find(&items).Error
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	lines := make([]string, len(items))
+	for i, item := range items {
+		lines[i] = item.Name
+		if formatted := quantity.Format(item.Quantity, item.Unit); formatted != "" {
+			lines[i] += " (" + formatted + ")"
+		}
+	}
+	return c.Status(fiber.StatusOK).SendString(strings.Join(lines, "\n"))
+}
+
+// GetAPIKeys retrieves all API keys of the authenticated user.
+func (s *Server) GetAPIKeys(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	keys, err := s.Auth.GetUserAPIKeys(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(keys)
+}
+
+// CreateAPIKey creates a new API key for the authenticated user. The plaintext key is only
+// returned in this response.
+func (s *Server) CreateAPIKey(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req models.CreateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	apiKey, key, err := s.Auth.CreateAPIKey(userID, req.Name, req.Scopes, req.RateLimit)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(models.CreateAPIKeyResponse{
+		APIKey: *apiKey,
+		Key:    key,
+	})
+}
+
+// RevokeAPIKey deletes an API key of the authenticated user.
+func (s *Server) RevokeAPIKey(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	keyID := c.Params("id")
+
+	if err := s.Auth.RevokeAPIKey(keyID, userID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetDisplayTokens retrieves the read-only display tokens of a list.
+func (s *Server) GetDisplayTokens(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	tokens, err := s.Lists.GetDisplayTokens(listID, userID)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(tokens)
+}
+
+// CreateDisplayToken creates a read-only display token for a list. The plaintext token is only
+// returned in this response.
+func (s *Server) CreateDisplayToken(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	var req models.CreateDisplayTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	displayToken, token, err := s.Lists.CreateDisplayToken(listID, userID, req.Name)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(models.CreateDisplayTokenResponse{
+		DisplayToken: *displayToken,
+		Token:        token,
+	})
+}
+
+// RevokeDisplayToken deletes a display token of a list.
+func (s *Server) RevokeDisplayToken(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	listID := c.Params("id")
+
+	err := s.Lists.RevokeDisplayToken(listID, c.Params("tokenId"), userID)
+	if errors.Is(err, lists.ErrDisplayTokenNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetEnrichmentHook retrieves the enrichment hook of a list.
+func (s *Server) GetEnrichmentHook(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	hook, err := s.Enrichment.GetHook(c.Params("id"), userID)
+	if err != nil {
+		return enrichmentHookError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(hook)
+}
+
+// SetEnrichmentHook sets the URL called for every item created on a list.
+func (s *Server) SetEnrichmentHook(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req models.SetEnrichmentHookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	hook, err := s.Enrichment.SetHook(c.Params("id"), userID, req.URL)
+	if err != nil {
+		return enrichmentHookError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(hook)
+}
+
+// DeleteEnrichmentHook removes the enrichment hook of a list.
+func (s *Server) DeleteEnrichmentHook(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	if err := s.Enrichment.DeleteHook(c.Params("id"), userID); err != nil {
+		return enrichmentHookError(c, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// enrichmentHookError answers a failed enrichment hook operation.
+func enrichmentHookError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusBadRequest
+	switch {
+	case errors.Is(err, enrichment.ErrNotOwner):
+		status = fiber.StatusForbidden
+	case errors.Is(err, enrichment.ErrNotFound):
+		status = fiber.StatusNotFound
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// GetListRules retrieves the automation rules of a list.
+func (s *Server) GetListRules(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	listRules, err := s.Rules.GetRules(c.Params("id"), userID)
+	if err != nil {
+		return ruleError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(listRules)
+}
+
+// CreateListRule adds an automation rule to a list.
+func (s *Server) CreateListRule(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req models.ListRuleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validation.ValidateStruct(req); err != nil {
+		return validationFailed(c, validation.Errors(err))
+	}
+
+	rule, err := s.Rules.CreateRule(c.Params("id"), userID, req)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return ruleError(c, err)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(list)
+	return c.Status(fiber.StatusCreated).JSON(rule)
 }
 
-// UpdateList updates a shopping list's name if the user is the owner.
-func (s *Server) UpdateList(c *fiber.Ctx) error {
+// UpdateListRule replaces an automation rule of a list.
+func (s *Server) UpdateListRule(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
-	listID := c.Params("id")
 
-	var req models.UpdateListRequest
+	var req models.ListRuleRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Invalid request body",
@@ -213,70 +4660,105 @@ func (s *Server) UpdateList(c *fiber.Ctx) error {
 	}
 
 	if err := validation.ValidateStruct(req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Validation failed",
-			"details": validation.FormatValidationErrors(err),
-		})
+		return validationFailed(c, validation.Errors(err))
 	}
 
-	list, err := s.Lists.UpdateList(listID, userID, req.Name)
+	rule, err := s.Rules.UpdateRule(c.Params("id"), c.Params("ruleId"), userID, req)
 	if err != nil {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return ruleError(c, err)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(list)
+	return c.Status(fiber.StatusOK).JSON(rule)
 }
 
-// DeleteList deletes a shopping list if the user is the owner.
-func (s *Server) DeleteList(c *fiber.Ctx) error {
+// DeleteListRule removes an automation rule of a list.
+func (s *Server) DeleteListRule(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
-	listID := c.Params("id")
 
-	err := s.Lists.DeleteList(listID, userID)
-	if err != nil {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+	if err := s.Rules.DeleteRule(c.Params("id"), c.Params("ruleId"), userID); err != nil {
+		return ruleError(c, err)
 	}
 
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
-// GetListMembers retrieves all members of a shopping list.
-func (s *Server) GetListMembers(c *fiber.Ctx) error {
+// ruleError answers a failed rule operation.
+func ruleError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusBadRequest
+	switch {
+	case errors.Is(err, rules.ErrNotOwner):
+		status = fiber.StatusForbidden
+	case errors.Is(err, rules.ErrNotFound):
+		status = fiber.StatusNotFound
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// DeduplicateListItems merges the duplicate open items of a list on demand. It also runs for
+// lists that opted out of the scheduled merge.
+func (s *Server) DeduplicateListItems(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
 	listID := c.Params("id")
 
-	members, err := s.Lists.GetListMembers(listID, userID)
-	if err != nil {
+	if !s.Lists.IsListOwner(listID, userID) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Only list owners can merge duplicate items",
+		})
+	}
+
+	merges, err := s.Items.Deduplicate(listID, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(members)
+	return c.Status(fiber.StatusOK).JSON(merges)
 }
 
-// RemoveListMember removes a member from a shopping list.
-func (s *Server) RemoveListMember(c *fiber.Ctx) error {
+// TokenFromQuery is a middleware that passes the "token" query parameter on as bearer token
+// unless the request has an Authorization header, for browsers, which cannot send headers
+// when opening a WebSocket or an event stream.
+func (s *Server) TokenFromQuery(c *fiber.Ctx) error {
+	if token := c.Query("token"); token != "" && c.Get(fiber.HeaderAuthorization) == "" {
+		c.Request().Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+	}
+	return c.Next()
+}
+
+// ConnectRealtime upgrades the request to a WebSocket that receives the changes of all lists
+// the user is a member of, see the realtime package.
+func (s *Server) ConnectRealtime(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
-	listID := c.Params("id")
-	memberID := c.Params("userId")
 
-	err := s.Lists.RemoveMemberFromList(listID, userID, memberID)
-	if err != nil {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": err.Error(),
+	key := c.Get("Sec-WebSocket-Key")
+	if !realtime.IsUpgrade(c.Get(fiber.HeaderConnection), c.Get(fiber.HeaderUpgrade)) || key == "" {
+		return c.Status(fiber.StatusUpgradeRequired).JSON(fiber.Map{
+			"error": "WebSocket upgrade required",
+		})
+	}
+	if c.Get("Sec-WebSocket-Version") != "13" {
+		c.Set("Sec-WebSocket-Version", "13")
+		return c.Status(fiber.StatusUpgradeRequired).JSON(fiber.Map{
+			"error": "Unsupported WebSocket version",
 		})
 	}
 
-	return c.SendStatus(fiber.StatusNoContent)
+	c.Set(fiber.HeaderUpgrade, "websocket")
+	c.Set(fiber.HeaderConnection, "Upgrade")
+	c.Set("Sec-WebSocket-Accept", realtime.AcceptKey(key))
+	c.Context().Hijack(func(conn net.Conn) {
+		s.Realtime.Serve(conn, userID)
+	})
+	c.Status(fiber.StatusSwitchingProtocols)
+	return nil
 }
 
-// GetListItems retrieves all items from a shopping list.
-func (s *Server) GetListItems(c *fiber.Ctx) error {
+// StreamListEvents streams the changes of a list as Server-Sent Events, for clients that cannot
+// use WebSockets, see the realtime package.
+func (s *Server) StreamListEvents(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
 	listID := c.Params("id")
 
@@ -287,224 +4769,372 @@ func (s *Server) GetListItems(c *fiber.Ctx) error {
 		})
 	}
 
-	var items []models.ShoppingItem
-	err := s.DB.Where("list_id = ?", listID).Order("created_at DESC").Find(&items).Error
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	// Keeps reverse proxies like nginx from buffering the stream
+	c.Set("X-Accel-Buffering", "no")
+
+	// The stream outlives the handler, which owns the memory of its params
+	listID = strings.Clone(listID)
+	shutdown := c.Context().Done()
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		s.Realtime.ServeEvents(w, userID, listID, shutdown)
+	})
+	return nil
+}
+
+// RequireDisplayToken is a middleware that authenticates displays by the token in the
+// X-Display-Token header or the "token" query parameter and stores the list it grants access to.
+func (s *Server) RequireDisplayToken(c *fiber.Ctx) error {
+	token := c.Get(lists.DisplayTokenHeader)
+	if token == "" {
+		token = c.Query("token")
+	}
+	if token == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Display token required",
+		})
+	}
+
+	displayToken, err := s.Lists.ValidateDisplayToken(token)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid display token",
+		})
+	}
+
+	c.Locals("display_list_id", displayToken.ListID)
+	return c.Next()
+}
+
+// GetDisplay returns the read-only view of the list a display token grants access to: the list,
+// its sections and its items without snoozed ones.
+func (s *Server) GetDisplay(c *fiber.Ctx) error {
+	listID := c.Locals("display_list_id").(string)
+
+	display, err := s.loadDisplay(c, listID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "List not found",
+		})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(items)
+	return c.Status(fiber.StatusOK).JSON(display)
 }
 
-// CreateListItem creates a new item in a shopping list.
-func (s *Server) CreateListItem(c *fiber.Ctx) error {
-	userID := c.Locals("user_id").(string)
-	listID := c.Params("id")
+// GetKiosk renders the list a display token grants access to as an auto-refreshing HTML page
+// for wall-mounted tablets and e-ink displays.
+func (s *Server) GetKiosk(c *fiber.Ctx) error {
+	displayToken, err := s.Lists.ValidateDisplayToken(c.Params("displayToken"))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).SendString("Invalid display token")
+	}
 
-	// Check list access
-	if !s.Lists.HasListAccess(listID, userID) {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Access denied",
-		})
+	display, err := s.loadDisplay(c, displayToken.ListID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString("List not found")
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
 	}
 
-	var req models.CreateItemRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return s.Kiosk.Render(c, *display)
+}
+
+// GetActivityFeed serves the recent activity of the list a display token grants access to as an
+// Atom feed for feed readers and automation tools.
+func (s *Server) GetActivityFeed(c *fiber.Ctx) error {
+	displayToken, err := s.Lists.ValidateDisplayToken(strings.TrimSuffix(c.Params("displayToken"), ".atom"))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).SendString("Invalid display token")
 	}
 
-	if err := validation.ValidateStruct(req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Validation failed",
-			"details": validation.FormatValidationErrors(err),
-		})
+	var list models.ShoppingList
+	if err := s.dbFor(c).First(&list, "id = ?", displayToken.ListID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).SendString("List not found")
 	}
 
-	if req.Tags == "" {
-		req.Tags = "[]"
+	entries, err := s.Activity.Recent(list.ID, activity.DefaultLimit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
 	}
 
-	item := models.ShoppingItem{
-		ID:        uuid.New().String(),
-		ListID:    listID,
-		Name:      req.Name,
-		Completed: false,
-		Tags:      req.Tags,
+	c.Set(fiber.HeaderContentType, "application/atom+xml; charset=utf-8")
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return activity.Atom(c, list, entries, c.BaseURL()+c.Path())
+}
+
+// loadDisplay loads the list, its sections and its items without snoozed ones for displays.
+func (s *Server) loadDisplay(c *fiber.Ctx, listID string) (*models.DisplayResponse, error) {
+	var display models.DisplayResponse
+	if err := s.dbFor(c).First(&display.List, "id = ?", listID).Error; err != nil {
+		return nil, err
+	}
+
+	err := s.dbFor(c).Where("list_id = ?", listID).Order("position ASC").Find(&display.Sections).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.dbFor(c).Where("list_id = ?", listID).
+		Where("snoozed_until IS NULL OR snoozed_until <= ?", time.Now()).
+		Order("created_at DESC").
+		Find(&display.Items).Error
+	if err != nil {
+		return nil, err
 	}
+	s.Items.MarkStale(display.Items, display.List.StaleAfterDays, time.Now())
+	if err := s.Items.AttachCreators(display.Items); err != nil {
+		return nil, err
+	}
+
+	return &display, nil
+}
+
+// GetCalendarFeed retrieves the calendar feed of the current user without its secret URL.
+func (s *Server) GetCalendarFeed(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
 
-	if err := s.DB.Create(&item).Error; err != nil {
+	feed, err := s.Calendar.GetFeed(userID)
+	if errors.Is(err, calendar.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(item)
+	return c.Status(fiber.StatusOK).JSON(feed)
 }
 
-// UpdateListItem updates an existing shopping list item.
-func (s *Server) UpdateListItem(c *fiber.Ctx) error {
+// CreateCalendarFeed creates the calendar feed of the current user, replacing an existing one,
+// and returns its secret URL.
+func (s *Server) CreateCalendarFeed(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
-	listID := c.Params("id")
-	itemID := c.Params("itemId")
 
-	// Check list access
-	if !s.Lists.HasListAccess(listID, userID) {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Access denied",
+	feed, token, err := s.Calendar.CreateFeed(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
 		})
 	}
 
-	var item models.ShoppingItem
-	if err := s.DB.Where("id = ? AND list_id = ?", itemID, listID).First(&item).Error; err != nil {
+	return c.Status(fiber.StatusCreated).JSON(models.CreateCalendarFeedResponse{
+		CalendarFeed: *feed,
+		URL:          c.BaseURL() + "/calendar/" + token + ".ics",
+	})
+}
+
+// RevokeCalendarFeed deletes the calendar feed of the current user.
+func (s *Server) RevokeCalendarFeed(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	err := s.Calendar.RevokeFeed(userID)
+	if errors.Is(err, calendar.ErrNotFound) {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Item not found",
+			"error": err.Error(),
 		})
 	}
-
-	var req models.CreateItemRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
 		})
 	}
 
-	if err := validation.ValidateStruct(req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Validation failed",
-			"details": validation.FormatValidationErrors(err),
-		})
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetCalendar serves the iCalendar feed a secret feed token grants access to, so calendar apps
+// can subscribe to it without logging in.
+func (s *Server) GetCalendar(c *fiber.Ctx) error {
+	token := strings.TrimSuffix(c.Params("feedToken"), ".ics")
+
+	userID, err := s.Calendar.ValidateToken(token)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).SendString("Invalid calendar feed token")
 	}
 
-	item.Name = req.Name
-	if req.Tags != "" {
-		item.Tags = req.Tags
+	feed, err := s.Calendar.Feed(userID, time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
 	}
 
-	if err := s.DB.Save(&item).Error; err != nil {
+	c.Set(fiber.HeaderContentType, "text/calendar; charset=utf-8")
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.SendString(feed)
+}
+
+// GetMatrixLink retrieves the Matrix account linked to the current user.
+func (s *Server) GetMatrixLink(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	link, err := s.Matrix.GetLink(userID)
+	if errors.Is(err, matrix.ErrNotLinked) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(item)
+	return c.Status(fiber.StatusOK).JSON(link)
 }
 
-// ToggleListItem toggles the completion status of a shopping list item.
-func (s *Server) ToggleListItem(c *fiber.Ctx) error {
+// CreateMatrixLinkCode creates a code the current user sends to the Matrix bot to link their
+// Matrix account.
+func (s *Server) CreateMatrixLinkCode(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
-	listID := c.Params("id")
-	itemID := c.Params("itemId")
 
-	// Check list access
-	if !s.Lists.HasListAccess(listID, userID) {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Access denied",
+	link, err := s.Matrix.CreateLinkCode(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
 		})
 	}
 
-	var item models.ShoppingItem
-	if err := s.DB.Where("id = ? AND list_id = ?", itemID, listID).First(&item).Error; err != nil {
+	return c.Status(fiber.StatusCreated).JSON(models.MatrixLinkCodeResponse{
+		Command:   "!link " + link.Code,
+		RoomID:    s.Matrix.RoomID,
+		ExpiresAt: *link.CodeExpiresAt,
+	})
+}
+
+// DeleteMatrixLink unlinks the Matrix account of the current user.
+func (s *Server) DeleteMatrixLink(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	err := s.Matrix.Unlink(userID)
+	if errors.Is(err, matrix.ErrNotLinked) {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Item not found",
+			"error": err.Error(),
 		})
 	}
-
-	item.Completed = !item.Completed
-	if err := s.DB.Save(&item).Error; err != nil {
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(item)
+	return c.SendStatus(fiber.StatusNoContent)
 }
 
-// DeleteListItem removes an item from a shopping list.
-func (s *Server) DeleteListItem(c *fiber.Ctx) error {
-	userID := c.Locals("user_id").(string)
-	listID := c.Params("id")
-	itemID := c.Params("itemId")
-
-	// Check list access
-	if !s.Lists.HasListAccess(listID, userID) {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Access denied",
+// SlackCommand answers a "/shop" slash command sent by Slack.
+func (s *Server) SlackCommand(c *fiber.Ctx) error {
+	if !s.Chat.SlackEnabled() {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Slack integration is disabled",
 		})
 	}
-
-	result := s.DB.Where("id = ? AND list_id = ?", itemID, listID).Delete(&models.ShoppingItem{})
-	if result.Error != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": result.Error.Error(),
+	if !s.Chat.VerifySlack(c.Get("X-Slack-Request-Timestamp"), c.Get("X-Slack-Signature"), c.Body(), time.Now()) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid request signature",
 		})
 	}
 
-	if result.RowsAffected == 0 {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Item not found",
+	workspaceID, text, err := chat.ParseSlack(c.Body())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
 		})
 	}
 
-	return c.SendStatus(fiber.StatusNoContent)
+	reply := s.Chat.Run(chat.PlatformSlack, workspaceID, text)
+	return c.Status(fiber.StatusOK).JSON(chat.SlackResponse(reply))
 }
 
-// CreateInvitation creates a new invitation for server or list access.
-func (s *Server) CreateInvitation(c *fiber.Ctx) error {
-	userID := c.Locals("user_id").(string)
+// DiscordCommand answers a "/shop" application command and the endpoint pings sent by Discord.
+func (s *Server) DiscordCommand(c *fiber.Ctx) error {
+	if !s.Chat.DiscordEnabled() {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Discord integration is disabled",
+		})
+	}
+	if !s.Chat.VerifyDiscord(c.Get("X-Signature-Timestamp"), c.Get("X-Signature-Ed25519"), c.Body()) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid request signature",
+		})
+	}
 
-	var req models.CreateInvitationRequest
-	if err := c.BodyParser(&req); err != nil {
+	interaction, err := chat.ParseDiscord(c.Body())
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Invalid request body",
 		})
 	}
 
-	if err := validation.ValidateStruct(req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Validation failed",
-			"details": validation.FormatValidationErrors(err),
-		})
+	switch interaction.Type {
+	case chat.DiscordPing:
+		return c.Status(fiber.StatusOK).JSON(chat.DiscordPong())
+	case chat.DiscordApplicationCommand:
+		reply := s.Chat.Run(chat.PlatformDiscord, interaction.WorkspaceID(), interaction.Text())
+		return c.Status(fiber.StatusOK).JSON(chat.DiscordResponse(reply))
 	}
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error": "Unsupported interaction type",
+	})
+}
 
-	invitation, err := s.Invitations.CreateInvitation(userID, req.Email, req.Type, req.ListID)
+// GetChatLinks lists the Slack and Discord workspaces linked to the current user.
+func (s *Server) GetChatLinks(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	links, err := s.Chat.GetLinks(userID)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(invitation)
+	return c.Status(fiber.StatusOK).JSON(links)
 }
 
-// GetInvitations retrieves all invitations created by the authenticated user.
-func (s *Server) GetInvitations(c *fiber.Ctx) error {
+// CreateChatLinkCode creates a code the current user runs as "/shop link <code>" in a Slack or
+// Discord workspace to link it.
+func (s *Server) CreateChatLinkCode(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
 
-	invitations, err := s.Invitations.GetUserInvitations(userID)
+	code, err := s.Chat.CreateLinkCode(userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(invitations)
+	return c.Status(fiber.StatusCreated).JSON(models.ChatLinkCodeResponse{
+		Command:   "/shop link " + code.Code,
+		ExpiresAt: code.ExpiresAt,
+	})
 }
 
-// RevokeInvitation cancels an invitation if the user is the original inviter.
-func (s *Server) RevokeInvitation(c *fiber.Ctx) error {
+// DeleteChatLink unlinks a workspace from the current user.
+func (s *Server) DeleteChatLink(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
-	invitationID := c.Params("id")
 
-	err := s.Invitations.RevokeInvitation(invitationID, userID)
-	if err != nil {
+	err := s.Chat.Unlink(c.Params("platform"), c.Params("workspaceId"), userID)
+	if errors.Is(err, chat.ErrNotLinked) {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 
 	return c.SendStatus(fiber.StatusNoContent)
 }
@@ -4,24 +4,50 @@
 package handlers
 
 import (
+	"bufio"
 	"bytes"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"image"
+	"image/png"
 	"io"
+	"mime/multipart"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/etag"
+	"github.com/oliverandrich/shopping-list-server/internal/auth"
+	"github.com/oliverandrich/shopping-list-server/internal/codec"
+	"github.com/oliverandrich/shopping-list-server/internal/db"
+	"github.com/oliverandrich/shopping-list-server/internal/imageproxy"
+	"github.com/oliverandrich/shopping-list-server/internal/items"
+	"github.com/oliverandrich/shopping-list-server/internal/lists"
 	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/moderation"
+	"github.com/oliverandrich/shopping-list-server/internal/realtime"
+	"github.com/oliverandrich/shopping-list-server/internal/setup"
+	"github.com/oliverandrich/shopping-list-server/internal/storage"
 	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+	"github.com/oliverandrich/shopping-list-server/internal/version"
 	"gopkg.in/gomail.v2"
+	"gorm.io/gorm"
 )
 
-func setupTestServer(t *testing.T) (*Server, *fiber.App) {
+func setupTestServer(t testing.TB) (*Server, *fiber.App) {
 	t.Helper()
 
 	// Set up test environment
@@ -34,27 +60,167 @@ func setupTestServer(t *testing.T) (*Server, *fiber.App) {
 	app := fiber.New()
 
 	// Add routes
+	app.Get("/status", server.GetStatus)
+	app.Get("/kiosk/:displayToken", server.GetKiosk)
+	app.Get("/feeds/:displayToken", server.GetActivityFeed)
+	app.Get("/calendar/:feedToken", server.GetCalendar)
 	app.Get("/api/v1/health", server.Health)
+	app.Get("/api/v1/capabilities", server.Auth.OptionalJWTMiddleware(), server.Capabilities)
+	app.Get("/api/v1/version", server.Version)
+	app.Get("/api/v1/policies", server.GetPolicies)
 	app.Post("/api/v1/auth/login", server.RequestLogin)
 	app.Post("/api/v1/auth/verify", server.VerifyLogin)
+	app.Get("/api/v1/auth/verify-link", server.VerifyLoginLink)
+	apiKeyAuth := server.Auth.APIKeyMiddleware()
+	app.Post("/api/v1/quick-add", apiKeyAuth, auth.RequireScope(auth.ScopeItemsWrite), server.QuickAdd)
+	app.Get("/api/v1/integrations/items", apiKeyAuth, auth.RequireScope(auth.ScopeItemsRead), server.GetNewItems)
+	app.Post("/api/v1/integrations/items", apiKeyAuth, auth.RequireScope(auth.ScopeItemsWrite), server.AddItemByListName)
+	shortcutAuth := server.Auth.QueryAPIKeyMiddleware()
+	app.Get("/api/v1/shortcuts/add", shortcutAuth, auth.RequireScope(auth.ScopeItemsWrite), server.ShortcutAddItem)
+	app.Get("/api/v1/shortcuts/items", shortcutAuth, auth.RequireScope(auth.ScopeItemsRead), server.ShortcutListItems)
+	app.Get("/api/v1/ws", server.TokenFromQuery, server.Auth.JWTMiddleware(), server.RequirePolicyAcceptance, server.ConnectRealtime)
+	app.Get("/api/v1/lists/:id/events", server.TokenFromQuery, server.Auth.JWTMiddleware(), server.RequirePolicyAcceptance, server.StreamListEvents)
+	app.Post("/api/v1/integrations/slack", server.SlackCommand)
+	app.Post("/api/v1/integrations/discord", server.DiscordCommand)
+	app.Get("/api/v1/display", server.RequireDisplayToken, server.EncodeResponse, server.GetDisplay)
 
 	// Protected routes
-	protected := app.Group("/api/v1", server.Auth.JWTMiddleware())
+	protected := app.Group("/api/v1", server.Auth.JWTMiddleware(), server.PinPrimary)
+	protected.Get("/policies/status", server.GetPolicyStatus)
+	protected.Post("/policies/accept", server.AcceptPolicy)
+	protected.Post("/auth/logout-all", server.LogoutAll)
+	protected.Use(server.RequirePolicyAcceptance)
+	protected.Use("/lists", server.EncodeResponse)
 	protected.Get("/lists", server.GetLists)
 	protected.Post("/lists", server.CreateList)
+	protected.Post("/lists/import", server.RequireFederation, server.Quota(setup.EndpointsImport), server.ImportListBundle)
+	protected.Get("/lists/batch", server.GetListsBatch)
 	protected.Get("/lists/:id", server.GetList)
 	protected.Put("/lists/:id", server.UpdateList)
 	protected.Delete("/lists/:id", server.DeleteList)
+	protected.Post("/lists/:id/seen", server.MarkListSeen)
+	protected.Get("/lists/:id/export", server.Quota(setup.EndpointsExport), server.ExportList)
+	protected.Get("/lists/:id/compact", etag.New(), server.GetCompactList)
 	protected.Get("/lists/:id/members", server.GetListMembers)
+	protected.Post("/lists/:id/members", server.AddListMember)
+	protected.Put("/lists/:id/members/:userId", server.UpdateListMember)
+	protected.Put("/lists/:id/members/:userId/role", server.UpdateListMember)
 	protected.Delete("/lists/:id/members/:userId", server.RemoveListMember)
+	protected.Get("/lists/:id/display-tokens", server.GetDisplayTokens)
+	protected.Post("/lists/:id/display-tokens", server.CreateDisplayToken)
+	protected.Delete("/lists/:id/display-tokens/:tokenId", server.RevokeDisplayToken)
+	protected.Get("/lists/:id/enrichment-hook", server.GetEnrichmentHook)
+	protected.Put("/lists/:id/enrichment-hook", server.SetEnrichmentHook)
+	protected.Delete("/lists/:id/enrichment-hook", server.DeleteEnrichmentHook)
+	protected.Get("/lists/:id/rules", server.GetListRules)
+	protected.Post("/lists/:id/rules", server.CreateListRule)
+	protected.Put("/lists/:id/rules/:ruleId", server.UpdateListRule)
+	protected.Delete("/lists/:id/rules/:ruleId", server.DeleteListRule)
+	protected.Post("/lists/:id/deduplicate", server.DeduplicateListItems)
+	protected.Post("/lists/:id/merge-from/:otherId", server.MergeList)
+	protected.Get("/lists/:id/bundle", server.RequireFederation, server.Quota(setup.EndpointsExport), server.ExportListBundle)
+	protected.Get("/lists/:id/aliases", server.GetListAliases)
+	protected.Post("/lists/:id/aliases", server.CreateListAlias)
+	protected.Delete("/lists/:id/aliases/:alias", server.DeleteListAlias)
+	protected.Get("/lists/:id/sections", server.GetListSections)
+	protected.Post("/lists/:id/sections", server.CreateListSection)
+	protected.Put("/lists/:id/sections/:sectionId", server.UpdateListSection)
+	protected.Delete("/lists/:id/sections/:sectionId", server.DeleteListSection)
+	protected.Get("/lists/:id/tags", server.GetListTags)
+	protected.Post("/lists/:id/tags", server.CreateListTag)
+	protected.Put("/lists/:id/tags/:tag", server.UpdateListTag)
+	protected.Delete("/lists/:id/tags/:tag", server.DeleteListTag)
+	protected.Get("/lists/:id/my-note", server.GetListNote)
+	protected.Put("/lists/:id/my-note", server.UpdateListNote)
+	protected.Get("/lists/:id/trip", server.GetListTrip)
+	protected.Put("/lists/:id/trip", server.PlanListTrip)
+	protected.Delete("/lists/:id/trip", server.CancelListTrip)
+	protected.Post("/lists/:id/trip/rsvp", server.RSVPListTrip)
+	protected.Get("/lists/:id/stats/fairness", server.Quota(setup.EndpointsStats), server.GetListFairness)
+	protected.Get("/lists/:id/trash", server.GetListTrash)
 	protected.Get("/lists/:id/items", server.GetListItems)
 	protected.Post("/lists/:id/items", server.CreateListItem)
+	protected.Post("/lists/:id/items/smart", server.SmartAddListItem)
+	protected.Post("/lists/:id/items/batch", server.BatchListItems)
 	protected.Put("/lists/:id/items/:itemId", server.UpdateListItem)
 	protected.Post("/lists/:id/items/:itemId/toggle", server.ToggleListItem)
+	protected.Post("/lists/:id/items/:itemId/snooze", server.SnoozeListItem)
+	protected.Delete("/lists/:id/items/:itemId/snooze", server.UnsnoozeListItem)
 	protected.Delete("/lists/:id/items/:itemId", server.DeleteListItem)
+	protected.Post("/lists/:id/items/:itemId/restore", server.RestoreListItem)
+	protected.Get("/lists/:id/items/:itemId/poll", server.GetItemPoll)
+	protected.Put("/lists/:id/items/:itemId/poll", server.CreateItemPoll)
+	protected.Delete("/lists/:id/items/:itemId/poll", server.DeleteItemPoll)
+	protected.Post("/lists/:id/items/:itemId/poll/vote", server.VoteItemPoll)
+	protected.Get("/lists/:id/items/:itemId/image", server.GetItemImage)
+	protected.Get("/lists/:id/items/:itemId/attachments", server.RequireAttachments, server.GetItemAttachments)
+	protected.Post("/lists/:id/items/:itemId/attachments", server.RequireAttachments, server.UploadItemAttachment)
+	protected.Get("/lists/:id/items/:itemId/attachments/:attachmentId", server.RequireAttachments, server.DownloadItemAttachment)
+	protected.Delete("/lists/:id/items/:itemId/attachments/:attachmentId", server.RequireAttachments, server.DeleteItemAttachment)
+	protected.Get("/account/storage", server.GetStorageUsage)
+	protected.Get("/account/privacy", server.GetPrivacySettings)
+	protected.Put("/account/privacy", server.UpdatePrivacySettings)
+	protected.Post("/account/housekeeping", server.RunHousekeeping)
+	protected.Get("/account/calendar", server.GetCalendarFeed)
+	protected.Post("/account/calendar", server.CreateCalendarFeed)
+	protected.Delete("/account/calendar", server.RevokeCalendarFeed)
+	protected.Get("/account/matrix", server.RequireMatrix, server.GetMatrixLink)
+	protected.Post("/account/matrix/link", server.RequireMatrix, server.CreateMatrixLinkCode)
+	protected.Delete("/account/matrix", server.RequireMatrix, server.DeleteMatrixLink)
+	protected.Get("/account/chat-links", server.GetChatLinks)
+	protected.Post("/account/chat-links/code", server.CreateChatLinkCode)
+	protected.Delete("/account/chat-links/:platform/:workspaceId", server.DeleteChatLink)
+	protected.Get("/contacts", server.GetContacts)
+	protected.Get("/units/convert", server.ConvertUnits)
+	protected.Get("/reports/spend", server.Quota(setup.EndpointsStats), server.GetSpendReport)
+	protected.Get("/items/history", server.Quota(setup.EndpointsSearch), server.SearchItemHistory)
+	protected.Get("/suggestions/replenish", server.GetReplenishSuggestions)
+	protected.Get("/suggestions/replenish/settings", server.GetReplenishSettings)
+	protected.Put("/suggestions/replenish/settings", server.UpdateReplenishSettings)
+	protected.Post("/suggestions/replenish/:id/accept", server.AcceptReplenishSuggestion)
+	protected.Post("/suggestions/replenish/:id/dismiss", server.DismissReplenishSuggestion)
+	protected.Get("/smart-lists", server.GetSmartLists)
+	protected.Post("/smart-lists", server.CreateSmartList)
+	protected.Put("/smart-lists/:id", server.UpdateSmartList)
+	protected.Delete("/smart-lists/:id", server.DeleteSmartList)
+	protected.Get("/smart-lists/:id/items", server.Quota(setup.EndpointsSearch), server.GetSmartListItems)
 	protected.Post("/invitations", server.CreateInvitation)
 	protected.Get("/invitations", server.GetInvitations)
+	protected.Get("/invitations/received", server.GetReceivedInvitations)
+	protected.Post("/invitations/received/:id/accept", server.AcceptReceivedInvitation)
+	protected.Delete("/invitations/received/:id", server.DeclineReceivedInvitation)
 	protected.Delete("/invitations/:id", server.RevokeInvitation)
+	protected.Get("/api-keys", server.GetAPIKeys)
+	protected.Post("/api-keys", server.CreateAPIKey)
+	protected.Delete("/api-keys/:id", server.RevokeAPIKey)
+	protected.Get("/announcements", server.GetAnnouncements)
+
+	admin := protected.Group("/admin", server.RequireAdmin)
+	admin.Get("/version", server.AdminVersion)
+	admin.Get("/settings", server.GetServerSettings)
+	admin.Put("/settings", server.UpdateServerSettings)
+	admin.Get("/bundle", server.ExportServerBundle)
+	admin.Post("/bundle", server.ImportServerBundle)
+	admin.Get("/catalog/mappings", server.GetCategoryMappings)
+	admin.Post("/catalog/mappings", server.CreateCategoryMapping)
+	admin.Post("/announcements", server.CreateAnnouncement)
+	admin.Get("/storage", server.GetAllStorageUsage)
+	admin.Get("/db/check", server.CheckDatabase)
+	admin.Post("/db/check", server.RepairDatabase)
+	admin.Post("/db/snapshot", server.CreateSnapshot)
+	admin.Post("/db/checkpoint", server.CheckpointDatabase)
+	admin.Get("/retention", server.GetRetentionStatus)
+	admin.Post("/retention/purge", server.PurgeRetention)
+	admin.Get("/digest", server.GetOperatorDigest)
+	admin.Delete("/announcements/:id", server.DeleteAnnouncement)
+	admin.Get("/flags", server.GetFeatureFlags)
+	admin.Put("/flags/:key", server.SetFeatureFlag)
+	admin.Delete("/flags/:key", server.DeleteFeatureFlag)
+	admin.Put("/flags/:key/users/:userId", server.SetFeatureFlagOverride)
+	admin.Delete("/flags/:key/users/:userId", server.DeleteFeatureFlagOverride)
+	admin.Post("/users/:userId/logout", server.RevokeUserSessions)
+	admin.Put("/users/:userId/invitations", server.FreezeUserInvitations)
+	admin.Get("/audit", server.GetAuditLog)
 
 	return server, app
 }
@@ -118,6 +284,109 @@ func TestServer_Health(t *testing.T) {
 	}
 }
 
+func TestServer_Capabilities(t *testing.T) {
+	_, app := setupTestServer(t)
+
+	req := httptest.NewRequest("GET", "/api/v1/capabilities", nil)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	var response models.CapabilitiesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+
+	if response.Version == "" {
+		t.Error("Expected version to be set")
+	}
+	if response.Features.RegistrationMode != "invitation" {
+		t.Errorf("Expected registration mode 'invitation', got %q", response.Features.RegistrationMode)
+	}
+	if response.Limits.LoginCodeLifetimeSeconds != 15*60 {
+		t.Errorf("Expected login code lifetime of 900 seconds, got %d", response.Limits.LoginCodeLifetimeSeconds)
+	}
+	if len(response.Locales) == 0 {
+		t.Error("Expected supported locales")
+	}
+}
+
+func TestServer_Version(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	t.Run("public build info", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/v1/version", nil)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+
+		var response models.VersionResponse
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+
+		if response.Version != version.Version {
+			t.Errorf("Expected version %q, got %q", version.Version, response.Version)
+		}
+		if response.Update != nil {
+			t.Error("Expected no update status on public endpoint")
+		}
+	})
+
+	t.Run("admin sees update status", func(t *testing.T) {
+		releases := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"tag_name": "v9.9.9", "html_url": "https://example.com/releases/v9.9.9"}`))
+		}))
+		defer releases.Close()
+
+		server.Updates = version.NewUpdateChecker()
+		server.Updates.URL = releases.URL
+		if err := server.Updates.Check(); err != nil {
+			t.Fatalf("Update check failed: %v", err)
+		}
+
+		admin, err := server.Setup.SetupSystem("admin@example.com")
+		if err != nil {
+			t.Fatalf("Failed to setup system: %v", err)
+		}
+		adminToken, err := server.Auth.GenerateJWT(admin)
+		if err != nil {
+			t.Fatalf("Failed to generate JWT: %v", err)
+		}
+
+		req := httptest.NewRequest("GET", "/api/v1/admin/version", nil)
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+
+		var response models.VersionResponse
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+
+		if response.Update == nil || response.Update.LatestVersion != "v9.9.9" {
+			t.Errorf("Expected update status with latest version v9.9.9, got %+v", response.Update)
+		}
+	})
+}
+
 func TestServer_RequestLogin(t *testing.T) {
 	_, app := setupTestServer(t)
 
@@ -160,6 +429,83 @@ func TestServer_RequestLogin(t *testing.T) {
 	})
 }
 
+func TestServer_LoginRateLimit(t *testing.T) {
+	server, app := setupTestServer(t)
+	server.Auth.LoginCodeLimit = 2
+	server.Auth.VerifyAttemptLimit = 2
+
+	post := func(target string, body any) *http.Response {
+		t.Helper()
+
+		reqBody, _ := json.Marshal(body)
+		req := httptest.NewRequest("POST", target, bytes.NewReader(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("login codes per address", func(t *testing.T) {
+		for range 2 {
+			if resp := post("/api/v1/auth/login", models.LoginRequest{Email: "limited@example.com"}); resp.StatusCode != fiber.StatusOK {
+				t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+			}
+		}
+		resp := post("/api/v1/auth/login", models.LoginRequest{Email: "Limited@Example.com"})
+		if resp.StatusCode != fiber.StatusTooManyRequests {
+			t.Fatalf("Expected status 429, got %d", resp.StatusCode)
+		}
+		if retryAfter, _ := strconv.Atoi(resp.Header.Get(fiber.HeaderRetryAfter)); retryAfter < 3500 || retryAfter > 3601 {
+			t.Errorf("Expected to retry in about an hour, got %q", resp.Header.Get(fiber.HeaderRetryAfter))
+		}
+		if resp := post("/api/v1/auth/login", models.LoginRequest{Email: "other@example.com"}); resp.StatusCode != fiber.StatusOK {
+			t.Errorf("Expected other addresses to be allowed, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("verify attempts per code", func(t *testing.T) {
+		user := models.User{ID: "verify-user", Email: "verify@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+		server.DB.Create(&user)
+		code, _ := server.Auth.CreateMagicLink(user.Email)
+
+		for range 2 {
+			if resp := post("/api/v1/auth/verify", models.VerifyRequest{Email: user.Email, Code: "wrong"}); resp.StatusCode != fiber.StatusUnauthorized {
+				t.Fatalf("Expected status 401, got %d", resp.StatusCode)
+			}
+		}
+		if resp := post("/api/v1/auth/verify", models.VerifyRequest{Email: user.Email, Code: code}); resp.StatusCode != fiber.StatusTooManyRequests {
+			t.Fatalf("Expected status 429 after too many attempts, got %d", resp.StatusCode)
+		}
+
+		code, _ = server.Auth.CreateMagicLink(user.Email)
+		if resp := post("/api/v1/auth/verify", models.VerifyRequest{Email: user.Email, Code: code}); resp.StatusCode != fiber.StatusOK {
+			t.Errorf("Expected a new code to get new attempts, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("anonymized client IPs", func(t *testing.T) {
+		server.AnonymizeIP = true
+		proxied := fiber.New(fiber.Config{ProxyHeader: fiber.HeaderXForwardedFor})
+		proxied.Post("/login", server.RequestLogin)
+
+		reqBody, _ := json.Marshal(models.LoginRequest{Email: "anonymous@example.com"})
+		req := httptest.NewRequest("POST", "/login", bytes.NewReader(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(fiber.HeaderXForwardedFor, "203.0.113.42")
+		if resp, err := proxied.Test(req); err != nil || resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("Expected status 200, got %v %v", resp, err)
+		}
+
+		var keys []string
+		server.DB.Model(&models.LoginThrottle{}).Where("key LIKE ?", "code:ip:%").Pluck("key", &keys)
+		if !slices.Contains(keys, "code:ip:203.0.113.0") || slices.Contains(keys, "code:ip:203.0.113.42") {
+			t.Errorf("Expected only the anonymized IP to be stored, got %v", keys)
+		}
+	})
+}
+
 func TestServer_VerifyLogin(t *testing.T) {
 	server, app := setupTestServer(t)
 
@@ -222,6 +568,58 @@ func TestServer_VerifyLogin(t *testing.T) {
 		if response.User.ID != user.ID {
 			t.Error("Expected user ID to match")
 		}
+		if response.JoinedLists == nil || len(response.JoinedLists) != 0 {
+			t.Errorf("Expected an empty list of joined lists, got %+v", response.JoinedLists)
+		}
+		if response.DefaultListID != "" || response.IsAdmin {
+			t.Errorf("Expected no default list and no admin role, got %q, %v", response.DefaultListID, response.IsAdmin)
+		}
+		if len(response.Capabilities.Locales) == 0 || response.Capabilities.Limits.LoginCodeLength != 6 {
+			t.Errorf("Expected the capabilities to be included, got %+v", response.Capabilities)
+		}
+	})
+
+	t.Run("joined lists with explicit invitation code", func(t *testing.T) {
+		owner := models.User{ID: "owner-id", Email: "owner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+		if err := server.DB.Create(&owner).Error; err != nil {
+			t.Fatalf("Failed to create owner: %v", err)
+		}
+		list, err := server.Lists.CreateList(owner.ID, "Groceries")
+		if err != nil {
+			t.Fatalf("Failed to create list: %v", err)
+		}
+		invitation, err := server.Invitations.CreateInvitation(owner.ID, user.Email, "list", &list.ID)
+		if err != nil {
+			t.Fatalf("Failed to create invitation: %v", err)
+		}
+		code, err := server.Auth.CreateMagicLink(user.Email)
+		if err != nil {
+			t.Fatalf("Failed to create magic link: %v", err)
+		}
+
+		reqBody, _ := json.Marshal(models.VerifyRequest{Email: user.Email, Code: code, InvitationCode: strings.ToLower(invitation.Code)})
+		req := httptest.NewRequest("POST", "/api/v1/auth/verify", bytes.NewReader(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			t.Fatalf("Expected status 200, got %d. Body: %s", resp.StatusCode, string(body))
+		}
+
+		var response models.LoginResponse
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		if len(response.JoinedLists) != 1 || response.JoinedLists[0] != (models.JoinedList{ID: list.ID, Name: "Groceries"}) {
+			t.Errorf("Expected Groceries to be reported as joined, got %+v", response.JoinedLists)
+		}
+		if response.DefaultListID != list.ID {
+			t.Errorf("Expected the joined list as default list, got %q", response.DefaultListID)
+		}
 	})
 
 	t.Run("invalid request body", func(t *testing.T) {
@@ -447,6 +845,34 @@ func TestServer_UpdateList(t *testing.T) {
 		}
 	})
 
+	t.Run("update stale threshold", func(t *testing.T) {
+		days := 3
+		reqBody, err := json.Marshal(models.UpdateListRequest{Name: "Updated Name", StaleAfterDays: &days})
+		if err != nil {
+			t.Fatalf("Failed to marshal request: %v", err)
+		}
+
+		req := httptest.NewRequest("PUT", "/api/v1/lists/"+list.ID, bytes.NewReader(reqBody))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+
+		var updated models.ShoppingList
+		if err := json.NewDecoder(resp.Body).Decode(&updated); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		if updated.StaleAfterDays != days {
+			t.Errorf("Expected stale after days of %d, got %d", days, updated.StaleAfterDays)
+		}
+	})
+
 	t.Run("invalid request body", func(t *testing.T) {
 		req := httptest.NewRequest("PUT", "/api/v1/lists/"+list.ID, strings.NewReader("invalid json"))
 		req.Header.Set("Authorization", "Bearer "+token)
@@ -794,6 +1220,10 @@ func TestServer_CreateListItem(t *testing.T) {
 		if item.Completed {
 			t.Error("New item should not be completed")
 		}
+
+		if item.CreatedBy == nil || *item.CreatedBy != user.ID || item.CreatedByName != "createitemuser" {
+			t.Errorf("Expected the item to be attributed to its creator, got %v %q", item.CreatedBy, item.CreatedByName)
+		}
 	})
 
 	t.Run("create item without tags", func(t *testing.T) {
@@ -835,6 +1265,118 @@ func TestServer_CreateListItem(t *testing.T) {
 		}
 	})
 
+	t.Run("create item normalizes name and detects category", func(t *testing.T) {
+		createReq := models.CreateItemRequest{
+			Name: "  sparkling    water ",
+		}
+
+		reqBody, err := json.Marshal(createReq)
+		if err != nil {
+			t.Fatalf("Failed to marshal request: %v", err)
+		}
+
+		req := httptest.NewRequest("POST", "/api/v1/lists/"+list.ID+"/items", bytes.NewReader(reqBody))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+
+		if resp.StatusCode != fiber.StatusCreated {
+			body, _ := io.ReadAll(resp.Body)
+			t.Fatalf("Expected status 201, got %d. Body: %s", resp.StatusCode, string(body))
+		}
+
+		var item models.ShoppingItem
+		if err := json.NewDecoder(resp.Body).Decode(&item); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+
+		if item.Name != "Sparkling Water" {
+			t.Errorf("Expected normalized name 'Sparkling Water', got '%s'", item.Name)
+		}
+
+		if item.Category != "beverages" {
+			t.Errorf("Expected category 'beverages', got '%s'", item.Category)
+		}
+
+		if item.Emoji == "" {
+			t.Error("Expected emoji to be set for detected category")
+		}
+	})
+
+	t.Run("create item with quantity parsing", func(t *testing.T) {
+		createReq := models.CreateItemRequest{
+			Name:          "500 g flour",
+			ParseQuantity: true,
+		}
+
+		reqBody, err := json.Marshal(createReq)
+		if err != nil {
+			t.Fatalf("Failed to marshal request: %v", err)
+		}
+
+		req := httptest.NewRequest("POST", "/api/v1/lists/"+list.ID+"/items", bytes.NewReader(reqBody))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+
+		if resp.StatusCode != fiber.StatusCreated {
+			body, _ := io.ReadAll(resp.Body)
+			t.Fatalf("Expected status 201, got %d. Body: %s", resp.StatusCode, string(body))
+		}
+
+		var item models.ShoppingItem
+		if err := json.NewDecoder(resp.Body).Decode(&item); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+
+		if item.Name != "Flour" || item.Quantity != 500 || item.Unit != "g" {
+			t.Errorf("Expected 'Flour' 500 g, got '%s' %v %s", item.Name, item.Quantity, item.Unit)
+		}
+	})
+
+	t.Run("create item without quantity parsing keeps name", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/v1/lists/"+list.ID+"/items", strings.NewReader(`{"name":"3x milk"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+
+		var item models.ShoppingItem
+		if err := json.NewDecoder(resp.Body).Decode(&item); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+
+		if item.Name != "3x Milk" || item.Quantity != 0 {
+			t.Errorf("Expected unparsed name '3x Milk', got '%s' (quantity %v)", item.Name, item.Quantity)
+		}
+	})
+
+	t.Run("create item with whitespace-only name", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/v1/lists/"+list.ID+"/items", strings.NewReader(`{"name":"   "}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+
+		if resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", resp.StatusCode)
+		}
+	})
+
 	t.Run("create item with invalid body", func(t *testing.T) {
 		req := httptest.NewRequest("POST", "/api/v1/lists/"+list.ID+"/items", strings.NewReader("invalid json"))
 		req.Header.Set("Authorization", "Bearer "+token)
@@ -1093,26 +1635,79 @@ func TestServer_DeleteListItem(t *testing.T) {
 			t.Errorf("Expected status 404, got %d", resp.StatusCode)
 		}
 	})
-}
 
-func TestServer_CreateInvitation(t *testing.T) {
-	server, app := setupTestServer(t)
+	t.Run("deleted item is in the trash", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/v1/lists/"+list.ID+"/trash", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
 
-	// Create test user
-	user := models.User{
-		ID:        "invitation-user-id",
-		Email:     "invitationuser@example.com",
-		JoinedAt:  time.Now(),
-		CreatedAt: time.Now(),
-	}
-	err := server.DB.Create(&user).Error
-	if err != nil {
-		t.Fatalf("Failed to create test user: %v", err)
-	}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
 
-	// Generate JWT token
-	token, err := server.Auth.GenerateJWT(&user)
-	if err != nil {
+		var trashed []models.ShoppingItem
+		if err := json.NewDecoder(resp.Body).Decode(&trashed); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		if len(trashed) != 1 || trashed[0].ID != item.ID || !trashed[0].DeletedAt.Valid {
+			t.Errorf("Expected the deleted item in the trash, got %+v", trashed)
+		}
+	})
+
+	t.Run("restore deleted item", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/v1/lists/"+list.ID+"/items/"+item.ID+"/restore", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			t.Fatalf("Expected status 200, got %d. Body: %s", resp.StatusCode, string(body))
+		}
+
+		var restored models.ShoppingItem
+		if err := server.DB.First(&restored, "id = ?", item.ID).Error; err != nil {
+			t.Errorf("Expected the restored item back on the list: %v", err)
+		}
+	})
+
+	t.Run("restore item not in trash", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/v1/lists/"+list.ID+"/items/"+item.ID+"/restore", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestServer_CreateInvitation(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	// Create test user
+	user := models.User{
+		ID:        "invitation-user-id",
+		Email:     "invitationuser@example.com",
+		JoinedAt:  time.Now(),
+		CreatedAt: time.Now(),
+	}
+	err := server.DB.Create(&user).Error
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	// Generate JWT token
+	token, err := server.Auth.GenerateJWT(&user)
+	if err != nil {
 		t.Fatalf("Failed to generate JWT: %v", err)
 	}
 
@@ -1429,3 +2024,4876 @@ func TestServer_ItemAccessControl(t *testing.T) {
 		}
 	})
 }
+
+func TestServer_CategoryMappings(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	admin, err := server.Setup.SetupSystem("admin@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+
+	adminToken, err := server.Auth.GenerateJWT(admin)
+	if err != nil {
+		t.Fatalf("Failed to generate JWT: %v", err)
+	}
+
+	t.Run("admin can add mapping", func(t *testing.T) {
+		reqBody := `{"keyword":"Hafermilch","category":"dairy","language":"de"}`
+		req := httptest.NewRequest("POST", "/api/v1/admin/catalog/mappings", strings.NewReader(reqBody))
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+
+		if resp.StatusCode != fiber.StatusCreated {
+			body, _ := io.ReadAll(resp.Body)
+			t.Fatalf("Expected status 201, got %d. Body: %s", resp.StatusCode, string(body))
+		}
+
+		if category, ok := server.Catalog.Dictionary.Lookup("hafermilch"); !ok || category.Name != "dairy" {
+			t.Errorf("Expected mapping to be active, got %q", category.Name)
+		}
+
+		req = httptest.NewRequest("GET", "/api/v1/admin/catalog/mappings", nil)
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+
+		resp, err = app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+
+		var mappings []models.CategoryMapping
+		if err := json.NewDecoder(resp.Body).Decode(&mappings); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+
+		if len(mappings) != 1 {
+			t.Errorf("Expected 1 mapping, got %d", len(mappings))
+		}
+	})
+
+	t.Run("non-admin is rejected", func(t *testing.T) {
+		reqBody := strings.NewReader(`{"keyword":"foo","category":"pantry"}`)
+		req := createAuthenticatedRequest(t, server, "POST", "/api/v1/admin/catalog/mappings", reqBody)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+
+		if resp.StatusCode != fiber.StatusForbidden {
+			t.Errorf("Expected status 403, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestServer_ServerSettings(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	admin, err := server.Setup.SetupSystem("admin@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+
+	adminToken, err := server.Auth.GenerateJWT(admin)
+	if err != nil {
+		t.Fatalf("Failed to generate JWT: %v", err)
+	}
+
+	updateSettings := func(body string) {
+		t.Helper()
+
+		req := httptest.NewRequest("PUT", "/api/v1/admin/settings", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			t.Fatalf("Expected status 200, got %d. Body: %s", resp.StatusCode, string(body))
+		}
+	}
+
+	// joinServer accepts a server invitation and returns the lists of the new user
+	joinServer := func(email string) []models.ShoppingList {
+		t.Helper()
+
+		if _, err := server.Invitations.CreateInvitation(admin.ID, email, "server", nil); err != nil {
+			t.Fatalf("Failed to create invitation: %v", err)
+		}
+		code, err := server.Auth.CreateMagicLink(email)
+		if err != nil {
+			t.Fatalf("Failed to create magic link: %v", err)
+		}
+
+		reqBody, _ := json.Marshal(models.VerifyRequest{Email: email, Code: code})
+		req := httptest.NewRequest("POST", "/api/v1/auth/verify", bytes.NewReader(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept-Language", "de-DE,de;q=0.9")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+
+		var response models.LoginResponse
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+
+		var lists []models.ShoppingList
+		server.DB.Where("owner_id = ?", response.User.ID).Find(&lists)
+		return lists
+	}
+
+	t.Run("localized default list name", func(t *testing.T) {
+		lists := joinServer("first@example.com")
+		if len(lists) != 1 || lists[0].Name != "Meine Einkaufsliste" {
+			t.Errorf("Expected German default list, got %+v", lists)
+		}
+	})
+
+	t.Run("configured default list name", func(t *testing.T) {
+		updateSettings(`{"default_list_names":{"de":"Wocheneinkauf"}}`)
+
+		lists := joinServer("second@example.com")
+		if len(lists) != 1 || lists[0].Name != "Wocheneinkauf" {
+			t.Errorf("Expected configured default list, got %+v", lists)
+		}
+	})
+
+	t.Run("disable default list", func(t *testing.T) {
+		updateSettings(`{"auto_create_default_list":false}`)
+
+		if lists := joinServer("third@example.com"); len(lists) != 0 {
+			t.Errorf("Expected no default list, got %+v", lists)
+		}
+	})
+
+	t.Run("non-admin is rejected", func(t *testing.T) {
+		req := createAuthenticatedRequest(t, server, "GET", "/api/v1/admin/settings", nil)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+
+		if resp.StatusCode != fiber.StatusForbidden {
+			t.Errorf("Expected status 403, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestServer_AddListMember(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	owner, err := server.Setup.SetupSystem("owner@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+	ownerToken, _ := server.Auth.GenerateJWT(owner)
+
+	guest := models.User{ID: "guest-id", Email: "guest@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	relative := models.User{ID: "relative-id", Email: "relative@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	for _, user := range []*models.User{&guest, &relative} {
+		if err := server.DB.Create(user).Error; err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+	guestToken, _ := server.Auth.GenerateJWT(&guest)
+
+	list, err := server.Lists.CreateList(owner.ID, "Hardware store")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	request := func(method, url, token, body string) *http.Response {
+		t.Helper()
+
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+	membersURL := "/api/v1/lists/" + list.ID + "/members"
+
+	t.Run("in-app invitation", func(t *testing.T) {
+		resp := request("POST", membersURL, ownerToken, `{"email":"guest@example.com"}`)
+		if resp.StatusCode != fiber.StatusAccepted {
+			t.Fatalf("Expected status 202, got %d", resp.StatusCode)
+		}
+
+		resp = request("GET", "/api/v1/invitations/received", guestToken, "")
+		var received []models.Invitation
+		if err := json.NewDecoder(resp.Body).Decode(&received); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		if len(received) != 1 {
+			t.Fatalf("Expected 1 received invitation, got %d", len(received))
+		}
+
+		resp = request("POST", "/api/v1/invitations/received/"+received[0].ID+"/accept", guestToken, "")
+		if resp.StatusCode != fiber.StatusNoContent {
+			t.Fatalf("Expected status 204, got %d", resp.StatusCode)
+		}
+		if !server.Lists.HasListAccess(list.ID, guest.ID) {
+			t.Error("Expected guest to be member after accepting")
+		}
+
+		resp = request("POST", "/api/v1/invitations/received/"+received[0].ID+"/accept", guestToken, "")
+		if resp.StatusCode != fiber.StatusNotFound {
+			t.Errorf("Expected accepted invitation to be gone, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("direct add when allowed", func(t *testing.T) {
+		allow := true
+		if _, err := server.Setup.UpdateSettings(models.UpdateServerSettingsRequest{AllowDirectMemberAdd: &allow}); err != nil {
+			t.Fatalf("Failed to update settings: %v", err)
+		}
+
+		resp := request("POST", membersURL, ownerToken, `{"email":"relative@example.com"}`)
+		if resp.StatusCode != fiber.StatusCreated {
+			t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+		}
+		if !server.Lists.HasListAccess(list.ID, relative.ID) {
+			t.Error("Expected relative to be added directly")
+		}
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		resp := request("POST", membersURL, ownerToken, `{"email":"stranger@example.com"}`)
+		if resp.StatusCode != fiber.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("non-owner is rejected", func(t *testing.T) {
+		resp := request("POST", membersURL, guestToken, `{"email":"relative@example.com"}`)
+		if resp.StatusCode != fiber.StatusForbidden {
+			t.Errorf("Expected status 403, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestServer_DatabaseCheck(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	admin, err := server.Setup.SetupSystem("admin@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+
+	adminToken, err := server.Auth.GenerateJWT(admin)
+	if err != nil {
+		t.Fatalf("Failed to generate JWT: %v", err)
+	}
+
+	// Orphaned item of a list that no longer exists
+	item := models.ShoppingItem{ID: "orphan", ListID: "missing-list", Name: "Milk", Tags: "[]", CreatedAt: time.Now()}
+	if err := server.DB.Create(&item).Error; err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+
+	check := func(method string, query string) models.IntegrityReport {
+		t.Helper()
+
+		req := httptest.NewRequest(method, "/api/v1/admin/db/check"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+
+		var report models.IntegrityReport
+		if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		return report
+	}
+
+	t.Run("check reports orphans", func(t *testing.T) {
+		report := check("GET", "")
+		if report.OK || len(report.Orphans) != 1 || report.Orphans[0].Check != "items_without_list" {
+			t.Errorf("Expected orphaned item to be reported, got %+v", report)
+		}
+	})
+
+	t.Run("dry run keeps orphans", func(t *testing.T) {
+		report := check("POST", "?dry_run=true")
+		if report.OK || report.Repaired || !report.DryRun || len(report.Orphans) != 1 {
+			t.Errorf("Expected the repair to be previewed, got %+v", report)
+		}
+
+		if report := check("GET", ""); len(report.Orphans) != 1 {
+			t.Errorf("Expected the orphan to be kept, got %+v", report.Orphans)
+		}
+		entries, _ := server.Moderation.AuditLog(1)
+		if len(entries) != 1 || entries[0].Action != moderation.ActionRepairDatabase || !entries[0].DryRun {
+			t.Errorf("Expected the dry run in the audit log, got %+v", entries)
+		}
+	})
+
+	t.Run("repair removes orphans", func(t *testing.T) {
+		report := check("POST", "")
+		if !report.OK || !report.Repaired || report.DryRun {
+			t.Errorf("Expected orphans to be repaired, got %+v", report)
+		}
+
+		if report := check("GET", ""); len(report.Orphans) != 0 {
+			t.Errorf("Expected no orphans after repair, got %+v", report.Orphans)
+		}
+	})
+
+	t.Run("non-admin is rejected", func(t *testing.T) {
+		req := createAuthenticatedRequest(t, server, "GET", "/api/v1/admin/db/check", nil)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+
+		if resp.StatusCode != fiber.StatusForbidden {
+			t.Errorf("Expected status 403, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestServer_DatabaseSnapshot(t *testing.T) {
+	server, app := setupTestServer(t)
+	server.Snapshots.Dir = t.TempDir()
+
+	admin, err := server.Setup.SetupSystem("admin@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+
+	adminToken, err := server.Auth.GenerateJWT(admin)
+	if err != nil {
+		t.Fatalf("Failed to generate JWT: %v", err)
+	}
+
+	t.Run("create snapshot", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/v1/admin/db/snapshot", nil)
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusCreated {
+			body, _ := io.ReadAll(resp.Body)
+			t.Fatalf("Expected status 201, got %d. Body: %s", resp.StatusCode, string(body))
+		}
+
+		var snapshot models.SnapshotResponse
+		if err := json.NewDecoder(resp.Body).Decode(&snapshot); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		if _, err := os.Stat(snapshot.Path); err != nil {
+			t.Errorf("Expected snapshot file to exist: %v", err)
+		}
+	})
+
+	t.Run("reject invalid checkpoint mode", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/v1/admin/db/checkpoint?mode=everything", nil)
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestServer_Announcements(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	admin, err := server.Setup.SetupSystem("admin@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+
+	adminToken, err := server.Auth.GenerateJWT(admin)
+	if err != nil {
+		t.Fatalf("Failed to generate JWT: %v", err)
+	}
+
+	var created models.Announcement
+
+	t.Run("admin can post announcement", func(t *testing.T) {
+		reqBody := `{"title":"Server moving","body":"The server moves this weekend.","send_email":true}`
+		req := httptest.NewRequest("POST", "/api/v1/admin/announcements", strings.NewReader(reqBody))
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+
+		if resp.StatusCode != fiber.StatusCreated {
+			body, _ := io.ReadAll(resp.Body)
+			t.Fatalf("Expected status 201, got %d. Body: %s", resp.StatusCode, string(body))
+		}
+
+		if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		if created.EmailedAt == nil {
+			t.Error("Expected announcement to be emailed")
+		}
+	})
+
+	t.Run("users see active announcements", func(t *testing.T) {
+		req := createAuthenticatedRequest(t, server, "GET", "/api/v1/announcements", nil)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+
+		var announcements []models.Announcement
+		if err := json.NewDecoder(resp.Body).Decode(&announcements); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+
+		if len(announcements) != 1 || announcements[0].Title != "Server moving" {
+			t.Errorf("Expected the posted announcement, got %+v", announcements)
+		}
+	})
+
+	t.Run("non-admin is rejected", func(t *testing.T) {
+		reqBody := strings.NewReader(`{"title":"Hi","body":"Hello"}`)
+		req := createAuthenticatedRequest(t, server, "POST", "/api/v1/admin/announcements", reqBody)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+
+		if resp.StatusCode != fiber.StatusForbidden {
+			t.Errorf("Expected status 403, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("admin can preview deleting announcement", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/api/v1/admin/announcements/"+created.ID+"?dry_run=true", nil)
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+
+		var preview models.DeletePreview
+		if err := json.NewDecoder(resp.Body).Decode(&preview); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusOK || !preview.DryRun || preview.Deleted["announcements"] != 1 {
+			t.Errorf("Expected a preview of 1 announcement, got %d %+v", resp.StatusCode, preview)
+		}
+
+		var count int64
+		server.DB.Model(&models.Announcement{}).Where("id = ?", created.ID).Count(&count)
+		if count != 1 {
+			t.Error("Expected the announcement to be kept")
+		}
+		entries, _ := server.Moderation.AuditLog(0)
+		if len(entries) != 1 || entries[0].Action != moderation.ActionDeleteAnnouncement || !entries[0].DryRun {
+			t.Errorf("Expected the dry run in the audit log, got %+v", entries)
+		}
+	})
+
+	t.Run("admin can delete announcement", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/api/v1/admin/announcements/"+created.ID, nil)
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+
+		if resp.StatusCode != fiber.StatusNoContent {
+			t.Errorf("Expected status 204, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestServer_PolicyAcceptance(t *testing.T) {
+	server, app := setupTestServer(t)
+	server.Policies.AddDocument("terms", "2025-01", "https://example.com/terms")
+	server.Policies.Required = true
+
+	user := models.User{
+		ID:        "policy-user-id",
+		Email:     "policyuser@example.com",
+		JoinedAt:  time.Now(),
+		CreatedAt: time.Now(),
+	}
+	if err := server.DB.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	token, err := server.Auth.GenerateJWT(&user)
+	if err != nil {
+		t.Fatalf("Failed to generate JWT: %v", err)
+	}
+
+	getLists := func(t *testing.T) int {
+		t.Helper()
+
+		req := httptest.NewRequest("GET", "/api/v1/lists", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp.StatusCode
+	}
+
+	t.Run("public policy documents", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/v1/policies", nil)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+
+		var documents []models.PolicyDocument
+		if err := json.NewDecoder(resp.Body).Decode(&documents); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		if len(documents) != 1 || documents[0].Version != "2025-01" {
+			t.Errorf("Expected terms document, got %+v", documents)
+		}
+	})
+
+	t.Run("blocked until accepted", func(t *testing.T) {
+		if status := getLists(t); status != fiber.StatusForbidden {
+			t.Errorf("Expected status 403, got %d", status)
+		}
+	})
+
+	t.Run("accept outdated version", func(t *testing.T) {
+		reqBody := `{"type":"terms","version":"2024-01"}`
+		req := httptest.NewRequest("POST", "/api/v1/policies/accept", strings.NewReader(reqBody))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("accept current version", func(t *testing.T) {
+		reqBody := `{"type":"terms","version":"2025-01"}`
+		req := httptest.NewRequest("POST", "/api/v1/policies/accept", strings.NewReader(reqBody))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusCreated {
+			t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+		}
+
+		if status := getLists(t); status != fiber.StatusOK {
+			t.Errorf("Expected status 200 after accepting, got %d", status)
+		}
+	})
+
+	t.Run("status lists acceptance", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/v1/policies/status", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+
+		var status models.PolicyStatusResponse
+		if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		if !status.Required || len(status.Pending) != 0 || len(status.Acceptances) != 1 {
+			t.Errorf("Unexpected policy status: %+v", status)
+		}
+	})
+}
+
+func TestServer_ItemAttachments(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	user := models.User{
+		ID:        "attachment-user-id",
+		Email:     "attachmentuser@example.com",
+		JoinedAt:  time.Now(),
+		CreatedAt: time.Now(),
+	}
+	if err := server.DB.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	list, err := server.Lists.CreateList(user.ID, "Attachment List")
+	if err != nil {
+		t.Fatalf("Failed to create test list: %v", err)
+	}
+
+	item := models.ShoppingItem{ID: "attachment-item-id", ListID: list.ID, Name: "Coffee", Tags: "[]", CreatedAt: time.Now()}
+	if err := server.DB.Create(&item).Error; err != nil {
+		t.Fatalf("Failed to create test item: %v", err)
+	}
+
+	token, err := server.Auth.GenerateJWT(&user)
+	if err != nil {
+		t.Fatalf("Failed to generate JWT: %v", err)
+	}
+
+	attachmentsURL := "/api/v1/lists/" + list.ID + "/items/" + item.ID + "/attachments"
+
+	upload := func(t *testing.T, content string) *http.Response {
+		t.Helper()
+
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		part, err := writer.CreateFormFile("file", "note.txt")
+		if err != nil {
+			t.Fatalf("Failed to create form file: %v", err)
+		}
+		_, _ = part.Write([]byte(content))
+		_ = writer.Close()
+
+		req := httptest.NewRequest("POST", attachmentsURL, &body)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		if resp := upload(t, "hello"); resp.StatusCode != fiber.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", resp.StatusCode)
+		}
+	})
+
+	server.Attachments.Storage = storage.NewLocal(t.TempDir())
+	server.Attachments.QuotaBytes = 10
+
+	var attachment models.Attachment
+
+	t.Run("upload attachment", func(t *testing.T) {
+		resp := upload(t, "hello")
+		if resp.StatusCode != fiber.StatusCreated {
+			body, _ := io.ReadAll(resp.Body)
+			t.Fatalf("Expected status 201, got %d. Body: %s", resp.StatusCode, string(body))
+		}
+
+		if err := json.NewDecoder(resp.Body).Decode(&attachment); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		if attachment.FileName != "note.txt" || attachment.Size != 5 {
+			t.Errorf("Unexpected attachment: %+v", attachment)
+		}
+	})
+
+	t.Run("quota exceeded", func(t *testing.T) {
+		if resp := upload(t, "hello world"); resp.StatusCode != fiber.StatusRequestEntityTooLarge {
+			t.Errorf("Expected status 413, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("download attachment", func(t *testing.T) {
+		req := httptest.NewRequest("GET", attachmentsURL+"/"+attachment.ID, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+
+		body, _ := io.ReadAll(resp.Body)
+		if string(body) != "hello" {
+			t.Errorf("Expected attachment content, got %q", string(body))
+		}
+	})
+
+	t.Run("storage usage", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/v1/account/storage", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+
+		var usage models.StorageUsage
+		if err := json.NewDecoder(resp.Body).Decode(&usage); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		if usage.UsedBytes != 5 || usage.QuotaBytes != 10 {
+			t.Errorf("Unexpected storage usage: %+v", usage)
+		}
+	})
+
+	t.Run("access denied for non-members", func(t *testing.T) {
+		req := createAuthenticatedRequest(t, server, "GET", attachmentsURL, nil)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusForbidden {
+			t.Errorf("Expected status 403, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("deleting the item keeps attachments in the trash", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/api/v1/lists/"+list.ID+"/items/"+item.ID, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusNoContent {
+			t.Fatalf("Expected status 204, got %d", resp.StatusCode)
+		}
+
+		usage, err := server.Attachments.Usage(user.ID)
+		if err != nil {
+			t.Fatalf("Failed to get usage: %v", err)
+		}
+		if usage.Attachments != 1 {
+			t.Errorf("Expected attachments to be kept for a restore, got %d", usage.Attachments)
+		}
+	})
+}
+
+func TestServer_QuickAdd(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	user := models.User{
+		ID:        "quick-add-user-id",
+		Email:     "quickadd@example.com",
+		JoinedAt:  time.Now(),
+		CreatedAt: time.Now(),
+	}
+	if err := server.DB.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	defaultList, err := server.Lists.CreateList(user.ID, "My Shopping List")
+	if err != nil {
+		t.Fatalf("Failed to create test list: %v", err)
+	}
+	groceries, err := server.Lists.CreateList(user.ID, "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create test list: %v", err)
+	}
+
+	_, key, err := server.Auth.CreateAPIKey(user.ID, "Shortcuts", nil, 0)
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+
+	quickAdd := func(t *testing.T, text string) (int, map[string]json.RawMessage) {
+		t.Helper()
+
+		reqBody, err := json.Marshal(models.QuickAddRequest{Text: text})
+		if err != nil {
+			t.Fatalf("Failed to marshal request: %v", err)
+		}
+
+		req := httptest.NewRequest("POST", "/api/v1/quick-add", bytes.NewReader(reqBody))
+		req.Header.Set("X-API-Key", key)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+
+		var response map[string]json.RawMessage
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		return resp.StatusCode, response
+	}
+
+	t.Run("adds items to named list", func(t *testing.T) {
+		status, response := quickAdd(t, "add 2 liters of milk and eggs to groceries")
+		if status != fiber.StatusCreated {
+			t.Fatalf("Expected status 201, got %d", status)
+		}
+
+		var items []models.ShoppingItem
+		if err := json.Unmarshal(response["items"], &items); err != nil {
+			t.Fatalf("Failed to parse items: %v", err)
+		}
+
+		if len(items) != 2 {
+			t.Fatalf("Expected 2 items, got %d", len(items))
+		}
+		if items[0].ListID != groceries.ID {
+			t.Errorf("Expected items in list '%s', got '%s'", groceries.ID, items[0].ListID)
+		}
+		if items[0].Name != "Milk" || items[0].Quantity != 2 || items[0].Unit != "l" {
+			t.Errorf("Expected 2 l Milk, got %v %s %s", items[0].Quantity, items[0].Unit, items[0].Name)
+		}
+	})
+
+	t.Run("resolves list alias", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/v1/lists/"+groceries.ID+"/aliases", strings.NewReader(`{"alias":"Einkaufsliste"}`))
+		token, err := server.Auth.GenerateJWT(&user)
+		if err != nil {
+			t.Fatalf("Failed to generate JWT: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusCreated {
+			t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+		}
+
+		status, response := quickAdd(t, "Füge Butter zur Einkaufsliste hinzu")
+		if status != fiber.StatusCreated {
+			t.Fatalf("Expected status 201, got %d", status)
+		}
+
+		var list models.ShoppingList
+		if err := json.Unmarshal(response["list"], &list); err != nil {
+			t.Fatalf("Failed to parse list: %v", err)
+		}
+		if list.ID != groceries.ID {
+			t.Errorf("Expected alias to resolve to '%s', got '%s'", groceries.ID, list.ID)
+		}
+	})
+
+	t.Run("falls back to default list", func(t *testing.T) {
+		status, response := quickAdd(t, "bread")
+		if status != fiber.StatusCreated {
+			t.Fatalf("Expected status 201, got %d", status)
+		}
+
+		var list models.ShoppingList
+		if err := json.Unmarshal(response["list"], &list); err != nil {
+			t.Fatalf("Failed to parse list: %v", err)
+		}
+		if list.ID != defaultList.ID {
+			t.Errorf("Expected default list '%s', got '%s'", defaultList.ID, list.ID)
+		}
+	})
+
+	t.Run("unknown list", func(t *testing.T) {
+		status, response := quickAdd(t, "add milk to hardware store")
+		if status != fiber.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", status)
+		}
+		if _, ok := response["understood"]; !ok {
+			t.Error("Expected response to include what was understood")
+		}
+	})
+
+	t.Run("rejects missing credentials", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/v1/quick-add", strings.NewReader(`{"text":"milk"}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusUnauthorized {
+			t.Errorf("Expected status 401, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestServer_APIKeys(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	req := createAuthenticatedRequest(t, server, "POST", "/api/v1/api-keys", strings.NewReader(`{"name":"Siri"}`))
+	authHeader := req.Header.Get("Authorization")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+	}
+
+	var created models.CreateAPIKeyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if created.Key == "" {
+		t.Error("Expected plaintext key in create response")
+	}
+
+	req = httptest.NewRequest("GET", "/api/v1/api-keys", nil)
+	req.Header.Set("Authorization", authHeader)
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	if strings.Contains(string(body), created.Key) {
+		t.Error("Listing API keys must not expose the plaintext key")
+	}
+
+	req = httptest.NewRequest("DELETE", "/api/v1/api-keys/"+created.ID, nil)
+	req.Header.Set("Authorization", authHeader)
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_ListTrip(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	user := models.User{
+		ID:        "trip-user-id",
+		Email:     "tripuser@example.com",
+		JoinedAt:  time.Now(),
+		CreatedAt: time.Now(),
+	}
+	if err := server.DB.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	list, err := server.Lists.CreateList(user.ID, "Trip List")
+	if err != nil {
+		t.Fatalf("Failed to create test list: %v", err)
+	}
+
+	token, err := server.Auth.GenerateJWT(&user)
+	if err != nil {
+		t.Fatalf("Failed to generate JWT: %v", err)
+	}
+
+	t.Run("plan trip", func(t *testing.T) {
+		reqBody, err := json.Marshal(models.PlanTripRequest{
+			ScheduledAt: time.Now().Add(24 * time.Hour),
+			Note:        "Farmers market",
+		})
+		if err != nil {
+			t.Fatalf("Failed to marshal request: %v", err)
+		}
+
+		req := httptest.NewRequest("PUT", "/api/v1/lists/"+list.ID+"/trip", bytes.NewReader(reqBody))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			t.Fatalf("Expected status 200, got %d. Body: %s", resp.StatusCode, string(body))
+		}
+
+		var trip models.ShoppingTrip
+		if err := json.NewDecoder(resp.Body).Decode(&trip); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		if trip.Note != "Farmers market" {
+			t.Errorf("Expected note 'Farmers market', got '%s'", trip.Note)
+		}
+	})
+
+	t.Run("rsvp with invalid status", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/v1/lists/"+list.ID+"/trip/rsvp", strings.NewReader(`{"status":"perhaps"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("viewer cannot plan or cancel trip", func(t *testing.T) {
+		viewer := models.User{ID: "trip-viewer-id", Email: "tripviewer@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+		if err := server.DB.Create(&viewer).Error; err != nil {
+			t.Fatalf("Failed to create viewer: %v", err)
+		}
+		if err := server.Lists.JoinList(list.ID, viewer.ID); err != nil {
+			t.Fatalf("Failed to join list: %v", err)
+		}
+		if _, err := server.Lists.SetMemberRole(list.ID, user.ID, viewer.ID, lists.RoleViewer); err != nil {
+			t.Fatalf("Failed to set role: %v", err)
+		}
+		viewerToken, _ := server.Auth.GenerateJWT(&viewer)
+
+		for _, change := range []struct{ method, body string }{
+			{"PUT", `{"scheduled_at":"2030-01-01T10:00:00Z"}`},
+			{"DELETE", ""},
+		} {
+			req := httptest.NewRequest(change.method, "/api/v1/lists/"+list.ID+"/trip", strings.NewReader(change.body))
+			req.Header.Set("Authorization", "Bearer "+viewerToken)
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Failed to make request: %v", err)
+			}
+			if resp.StatusCode != fiber.StatusForbidden {
+				t.Errorf("Expected %s to be forbidden for viewers, got %d", change.method, resp.StatusCode)
+			}
+		}
+
+		// Viewers still answer whether they join
+		req := httptest.NewRequest("POST", "/api/v1/lists/"+list.ID+"/trip/rsvp", strings.NewReader(`{"status":"yes"}`))
+		req.Header.Set("Authorization", "Bearer "+viewerToken)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Errorf("Expected viewers to RSVP, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("non-member cannot see trip", func(t *testing.T) {
+		req := createAuthenticatedRequest(t, server, "GET", "/api/v1/lists/"+list.ID+"/trip", nil)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusForbidden {
+			t.Errorf("Expected status 403, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("cancel trip", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/api/v1/lists/"+list.ID+"/trip", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusNoContent {
+			t.Errorf("Expected status 204, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestServer_SnoozeListItem(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	user := models.User{
+		ID:        "snooze-user-id",
+		Email:     "snoozeuser@example.com",
+		JoinedAt:  time.Now(),
+		CreatedAt: time.Now(),
+	}
+	if err := server.DB.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	list, err := server.Lists.CreateList(user.ID, "Snooze List")
+	if err != nil {
+		t.Fatalf("Failed to create test list: %v", err)
+	}
+
+	item := models.ShoppingItem{ID: "snooze-item-id", ListID: list.ID, Name: "Coffee Beans", Tags: "[]"}
+	if err := server.DB.Create(&item).Error; err != nil {
+		t.Fatalf("Failed to create test item: %v", err)
+	}
+
+	token, err := server.Auth.GenerateJWT(&user)
+	if err != nil {
+		t.Fatalf("Failed to generate JWT: %v", err)
+	}
+
+	countItems := func(t *testing.T, url string) int {
+		t.Helper()
+
+		req := httptest.NewRequest("GET", url, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+
+		var items []models.ShoppingItem
+		if err := json.NewDecoder(resp.Body).Decode(&items); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		return len(items)
+	}
+
+	reqBody, err := json.Marshal(models.SnoozeItemRequest{Until: time.Now().Add(48 * time.Hour)})
+	if err != nil {
+		t.Fatalf("Failed to marshal request: %v", err)
+	}
+
+	req := httptest.NewRequest("POST", "/api/v1/lists/"+list.ID+"/items/"+item.ID+"/snooze", bytes.NewReader(reqBody))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("Expected status 200, got %d. Body: %s", resp.StatusCode, string(body))
+	}
+
+	if n := countItems(t, "/api/v1/lists/"+list.ID+"/items"); n != 0 {
+		t.Errorf("Expected snoozed item to be hidden, got %d items", n)
+	}
+	if n := countItems(t, "/api/v1/lists/"+list.ID+"/items?include_snoozed=true"); n != 1 {
+		t.Errorf("Expected snoozed item with include_snoozed, got %d items", n)
+	}
+
+	req = httptest.NewRequest("DELETE", "/api/v1/lists/"+list.ID+"/items/"+item.ID+"/snooze", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	if n := countItems(t, "/api/v1/lists/"+list.ID+"/items"); n != 1 {
+		t.Errorf("Expected unsnoozed item to be visible, got %d items", n)
+	}
+}
+
+func TestServer_GetListItemsStale(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	user := models.User{
+		ID:        "stale-user-id",
+		Email:     "staleuser@example.com",
+		JoinedAt:  time.Now(),
+		CreatedAt: time.Now(),
+	}
+	if err := server.DB.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	list, err := server.Lists.CreateList(user.ID, "Stale List")
+	if err != nil {
+		t.Fatalf("Failed to create test list: %v", err)
+	}
+
+	old := models.ShoppingItem{ID: "stale-old-item", ListID: list.ID, Name: "Saffron", Tags: "[]", CreatedAt: time.Now().AddDate(0, 0, -30)}
+	fresh := models.ShoppingItem{ID: "stale-fresh-item", ListID: list.ID, Name: "Milk", Tags: "[]", CreatedAt: time.Now()}
+	server.DB.Create(&old)
+	server.DB.Create(&fresh)
+
+	token, err := server.Auth.GenerateJWT(&user)
+	if err != nil {
+		t.Fatalf("Failed to generate JWT: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/lists/"+list.ID+"/items", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	var items []models.ShoppingItem
+	if err := json.NewDecoder(resp.Body).Decode(&items); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+
+	for _, item := range items {
+		if item.Stale != (item.ID == old.ID) {
+			t.Errorf("Unexpected stale=%v for item %s", item.Stale, item.Name)
+		}
+	}
+}
+
+func TestServer_MarkListSeen(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	user := models.User{
+		ID:        "seen-user-id",
+		Email:     "seenuser@example.com",
+		JoinedAt:  time.Now(),
+		CreatedAt: time.Now(),
+	}
+	if err := server.DB.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	list, err := server.Lists.CreateList(user.ID, "Seen List")
+	if err != nil {
+		t.Fatalf("Failed to create test list: %v", err)
+	}
+
+	// Joined an hour ago, so the new item counts as unseen until the list is marked seen
+	server.DB.Model(&models.ListMember{}).Where("list_id = ?", list.ID).Update("joined_at", time.Now().Add(-time.Hour))
+
+	item := models.ShoppingItem{ID: "seen-item-id", ListID: list.ID, Name: "Bread", Tags: "[]", CreatedAt: time.Now()}
+	if err := server.DB.Create(&item).Error; err != nil {
+		t.Fatalf("Failed to create test item: %v", err)
+	}
+
+	token, err := server.Auth.GenerateJWT(&user)
+	if err != nil {
+		t.Fatalf("Failed to generate JWT: %v", err)
+	}
+
+	unseenChanges := func(t *testing.T) int {
+		t.Helper()
+
+		req := httptest.NewRequest("GET", "/api/v1/lists", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+
+		var lists []models.ShoppingList
+		if err := json.NewDecoder(resp.Body).Decode(&lists); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		if len(lists) != 1 {
+			t.Fatalf("Expected 1 list, got %d", len(lists))
+		}
+		return lists[0].UnseenChanges
+	}
+
+	if n := unseenChanges(t); n != 1 {
+		t.Errorf("Expected 1 unseen change, got %d", n)
+	}
+
+	req := httptest.NewRequest("POST", "/api/v1/lists/"+list.ID+"/seen", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", resp.StatusCode)
+	}
+
+	if n := unseenChanges(t); n != 0 {
+		t.Errorf("Expected no unseen changes after marking seen, got %d", n)
+	}
+
+	t.Run("access denied for non-members", func(t *testing.T) {
+		req := createAuthenticatedRequest(t, server, "POST", "/api/v1/lists/"+list.ID+"/seen", nil)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusForbidden {
+			t.Errorf("Expected status 403, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestServer_Contacts(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	owner, err := server.Setup.SetupSystem("owner@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+	ownerToken, _ := server.Auth.GenerateJWT(owner)
+
+	partner := models.User{ID: "partner-id", Email: "partner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	if err := server.DB.Create(&partner).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	partnerToken, _ := server.Auth.GenerateJWT(&partner)
+
+	list, err := server.Lists.CreateList(owner.ID, "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	if err := server.Lists.JoinList(list.ID, partner.ID); err != nil {
+		t.Fatalf("Failed to join list: %v", err)
+	}
+
+	request := func(method, url, token, body string) *http.Response {
+		t.Helper()
+
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+	contacts := func() []models.Contact {
+		t.Helper()
+
+		resp := request("GET", "/api/v1/contacts", ownerToken, "")
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		var result []models.Contact
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		return result
+	}
+
+	if result := contacts(); len(result) != 1 || result[0].Email != "partner@example.com" {
+		t.Errorf("Expected partner as contact, got %+v", result)
+	}
+
+	resp := request("PUT", "/api/v1/account/privacy", partnerToken, `{"hide_from_contacts":true}`)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	resp = request("GET", "/api/v1/account/privacy", partnerToken, "")
+	var settings models.PrivacySettings
+	if err := json.NewDecoder(resp.Body).Decode(&settings); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if !settings.HideFromContacts {
+		t.Error("Expected privacy opt-out to be stored")
+	}
+
+	if result := contacts(); len(result) != 0 {
+		t.Errorf("Expected opted-out partner to be hidden, got %+v", result)
+	}
+}
+
+func TestServer_ConvertUnits(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	user, err := server.Setup.SetupSystem("user@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+	token, _ := server.Auth.GenerateJWT(user)
+
+	convert := func(query, acceptLanguage string) (*http.Response, models.UnitConversion) {
+		t.Helper()
+
+		req := httptest.NewRequest("GET", "/api/v1/units/convert?"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Accept-Language", acceptLanguage)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		var result models.UnitConversion
+		if resp.StatusCode == fiber.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Fatalf("Failed to parse JSON response: %v", err)
+			}
+		}
+		return resp, result
+	}
+
+	resp, result := convert("quantity=500&from=g&to=kg", "de-DE")
+	if resp.StatusCode != fiber.StatusOK || result.Quantity != 0.5 || result.Unit != "kg" || result.Formatted != "0,5 kg" {
+		t.Errorf("Expected 0,5 kg, got %d %+v", resp.StatusCode, result)
+	}
+
+	resp, result = convert("quantity=1500&from=ml", "en")
+	if resp.StatusCode != fiber.StatusOK || result.Formatted != "1.5 l" {
+		t.Errorf("Expected 1.5 l, got %d %+v", resp.StatusCode, result)
+	}
+
+	resp, result = convert("quantity=3&from=packs&to=pieces&pack_size=6", "en")
+	if resp.StatusCode != fiber.StatusOK || result.Quantity != 18 || result.Unit != "piece" {
+		t.Errorf("Expected 18 pieces, got %d %+v", resp.StatusCode, result)
+	}
+
+	for _, query := range []string{"quantity=1&from=kg&to=l", "quantity=1&from=handful", "quantity=NaN&from=g", "quantity=1&from=pack&to=piece&pack_size=0"} {
+		if resp, _ := convert(query, "en"); resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", query, resp.StatusCode)
+		}
+	}
+}
+
+func TestServer_ListNote(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	owner, err := server.Setup.SetupSystem("owner@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+	token, _ := server.Auth.GenerateJWT(owner)
+
+	list, err := server.Lists.CreateList(owner.ID, "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	noteURL := "/api/v1/lists/" + list.ID + "/my-note"
+
+	req := httptest.NewRequest("PUT", noteURL, strings.NewReader(`{"note":"use coupon at checkout"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	req = httptest.NewRequest("GET", noteURL, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+
+	var note models.ListNote
+	if err := json.NewDecoder(resp.Body).Decode(&note); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if note.Note != "use coupon at checkout" {
+		t.Errorf("Expected stored note, got %q", note.Note)
+	}
+}
+
+func TestServer_ListSections(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	owner, err := server.Setup.SetupSystem("owner@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+	token, _ := server.Auth.GenerateJWT(owner)
+
+	list, err := server.Lists.CreateList(owner.ID, "Errands")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	request := func(method, url, body string) *http.Response {
+		t.Helper()
+
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+	listURL := "/api/v1/lists/" + list.ID
+
+	resp := request("POST", listURL+"/sections", `{"name":"Pharmacy"}`)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+	}
+	var section models.ListSection
+	if err := json.NewDecoder(resp.Body).Decode(&section); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+
+	resp = request("POST", listURL+"/items", `{"name":"Aspirin","section_id":"`+section.ID+`"}`)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+	}
+	var item models.ShoppingItem
+	if err := json.NewDecoder(resp.Body).Decode(&item); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if item.SectionID == nil || *item.SectionID != section.ID {
+		t.Errorf("Expected item in section %s, got %v", section.ID, item.SectionID)
+	}
+
+	resp = request("POST", listURL+"/items", `{"name":"Milk","section_id":"missing"}`)
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 for unknown section, got %d", resp.StatusCode)
+	}
+
+	resp = request("PUT", listURL+"/items/"+item.ID, `{"name":"Aspirin","section_id":""}`)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&item); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if item.SectionID != nil {
+		t.Error("Expected item to be moved out of the section")
+	}
+
+	resp = request("DELETE", listURL+"/sections/"+section.ID, "")
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", resp.StatusCode)
+	}
+	resp = request("DELETE", listURL+"/sections/"+section.ID, "")
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_ListTags(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	owner, err := server.Setup.SetupSystem("owner@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+	token, _ := server.Auth.GenerateJWT(owner)
+
+	list, err := server.Lists.CreateList(owner.ID, "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	request := func(method, url, body string) *http.Response {
+		t.Helper()
+
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+	tagsURL := "/api/v1/lists/" + list.ID + "/tags"
+
+	resp := request("POST", tagsURL, `{"name":"on sale","color":"red"}`)
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid color, got %d", resp.StatusCode)
+	}
+
+	resp = request("POST", tagsURL, `{"name":"on sale","color":"#ff5722","icon":"percent"}`)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+	}
+
+	resp = request("PUT", tagsURL+"/on%20sale", `{"name":"on sale","color":"#e64a19","icon":"percent"}`)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var tag models.ListTag
+	if err := json.NewDecoder(resp.Body).Decode(&tag); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if tag.Color != "#e64a19" {
+		t.Errorf("Expected updated color, got %s", tag.Color)
+	}
+
+	resp = request("DELETE", tagsURL+"/on%20sale", "")
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", resp.StatusCode)
+	}
+	resp = request("DELETE", tagsURL+"/on%20sale", "")
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_TagPolicy(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	owner, err := server.Setup.SetupSystem("owner@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+	token, _ := server.Auth.GenerateJWT(owner)
+
+	list, err := server.Lists.CreateList(owner.ID, "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	request := func(method, url, body string) *http.Response {
+		t.Helper()
+
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+	listURL := "/api/v1/lists/" + list.ID
+
+	resp := request("PUT", listURL, `{"name":"Groceries","tag_policy":"sometimes"}`)
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid policy, got %d", resp.StatusCode)
+	}
+
+	resp = request("PUT", listURL, `{"name":"Groceries","tag_policy":"restrict","suggested_tags":["organic","on sale"]}`)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var updated models.ShoppingList
+	if err := json.NewDecoder(resp.Body).Decode(&updated); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if updated.TagPolicy != "restrict" || len(updated.SuggestedTags) != 2 {
+		t.Errorf("Expected restricted list with two suggested tags, got %+v", updated)
+	}
+
+	resp = request("POST", listURL+"/items", `{"name":"Apples","tags":"[\"organic\",\"vegan\"]"}`)
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown tag, got %d", resp.StatusCode)
+	}
+	resp = request("POST", listURL+"/items", `{"name":"Apples","tags":"[\"organic\"]"}`)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Errorf("Expected status 201 for a suggested tag, got %d", resp.StatusCode)
+	}
+
+	resp = request("GET", listURL, "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var fetched models.ShoppingList
+	if err := json.NewDecoder(resp.Body).Decode(&fetched); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if len(fetched.SuggestedTags) != 2 || fetched.SuggestedTags[0].Name != "organic" {
+		t.Errorf("Expected the suggested tags with the list, got %+v", fetched.SuggestedTags)
+	}
+}
+
+func TestServer_SmartLists(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	owner, err := server.Setup.SetupSystem("owner@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+	token, _ := server.Auth.GenerateJWT(owner)
+
+	list, err := server.Lists.CreateList(owner.ID, "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	for _, item := range []models.ShoppingItem{
+		{ID: "item-1", ListID: list.ID, Name: "Milk", Tags: `["urgent"]`, CreatedAt: time.Now()},
+		{ID: "item-2", ListID: list.ID, Name: "Bread", Tags: "[]", CreatedAt: time.Now()},
+	} {
+		if err := server.DB.Create(&item).Error; err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+
+	request := func(method, url, body string) *http.Response {
+		t.Helper()
+
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+
+	resp := request("POST", "/api/v1/smart-lists", `{"name":"Urgent","filter":{"tags":["urgent"]}}`)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+	}
+	var smartList models.SmartList
+	if err := json.NewDecoder(resp.Body).Decode(&smartList); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+
+	resp = request("GET", "/api/v1/smart-lists/"+smartList.ID+"/items", "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var items []models.ShoppingItem
+	if err := json.NewDecoder(resp.Body).Decode(&items); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if len(items) != 1 || items[0].Name != "Milk" {
+		t.Errorf("Expected only the urgent item, got %+v", items)
+	}
+
+	resp = request("GET", "/api/v1/smart-lists/missing/items", "")
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_SmartAddListItem(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	owner, err := server.Setup.SetupSystem("owner@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+	token, _ := server.Auth.GenerateJWT(owner)
+
+	groceries, err := server.Lists.CreateList(owner.ID, "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	party, err := server.Lists.CreateList(owner.ID, "Party")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	smartAdd := func(listID, body string) (int, models.SmartAddItemResponse) {
+		t.Helper()
+
+		req := httptest.NewRequest("POST", "/api/v1/lists/"+listID+"/items/smart", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+
+		var result models.SmartAddItemResponse
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		return resp.StatusCode, result
+	}
+
+	status, result := smartAdd(groceries.ID, `{"name":"Chips"}`)
+	if status != fiber.StatusCreated || !result.Created || result.Item == nil {
+		t.Fatalf("Expected item to be created, got %d %+v", status, result)
+	}
+
+	status, result = smartAdd(party.ID, `{"name":"chips"}`)
+	if status != fiber.StatusOK || result.Created {
+		t.Fatalf("Expected duplicate hint, got %d %+v", status, result)
+	}
+	if len(result.Duplicates) != 1 || result.Duplicates[0].ListName != "Groceries" {
+		t.Errorf("Expected duplicate on Groceries, got %+v", result.Duplicates)
+	}
+
+	status, result = smartAdd(party.ID, `{"name":"chips","force":true}`)
+	if status != fiber.StatusCreated || !result.Created || result.Item.ListID != party.ID {
+		t.Errorf("Expected forced item to be created, got %d %+v", status, result)
+	}
+}
+
+func TestServer_GetStatus(t *testing.T) {
+	server, app := setupTestServer(t)
+	server.Status.Mailer = nil
+
+	req := httptest.NewRequest("GET", "/status", nil)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	var report models.StatusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if report.Status != "ok" || report.Components["db"] != "ok" || report.Components["mail"] != "disabled" {
+		t.Errorf("Unexpected status report: %+v", report)
+	}
+}
+
+func TestServer_RunHousekeeping(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	user := models.User{ID: "housekeeping-user-id", Email: "housekeeping@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	if err := server.DB.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	token, _ := server.Auth.GenerateJWT(&user)
+
+	list, err := server.Lists.CreateList(user.ID, "Forgotten")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	request := func(body string) *http.Response {
+		t.Helper()
+
+		req := httptest.NewRequest("POST", "/api/v1/account/housekeeping", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+
+	if resp := request(`{"inactive_months":0.5}`); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid body, got %d", resp.StatusCode)
+	}
+	if resp := request(`{"inactive_months":500}`); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 for too many months, got %d", resp.StatusCode)
+	}
+
+	resp := request("")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var report models.HousekeepingReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if len(report.EmptyLists) != 1 || report.EmptyLists[0].ID != list.ID || report.Removed {
+		t.Errorf("Expected empty list to be reported, got %+v", report)
+	}
+
+	if resp := request(`{"remove":true}`); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if server.Lists.IsListOwner(list.ID, user.ID) {
+		t.Error("Expected empty list to be removed")
+	}
+}
+
+func TestServer_UpdateListMember(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	owner := models.User{ID: "co-owner-owner-id", Email: "co-owner-owner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	partner := models.User{ID: "co-owner-partner-id", Email: "co-owner-partner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	if err := server.DB.Create(&[]models.User{owner, partner}).Error; err != nil {
+		t.Fatalf("Failed to create users: %v", err)
+	}
+	ownerToken, _ := server.Auth.GenerateJWT(&owner)
+	partnerToken, _ := server.Auth.GenerateJWT(&partner)
+
+	list, err := server.Lists.CreateList(owner.ID, "Shared")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	if err := server.Lists.JoinList(list.ID, partner.ID); err != nil {
+		t.Fatalf("Failed to join list: %v", err)
+	}
+
+	request := func(method, url, token, body string) *http.Response {
+		t.Helper()
+
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+	memberURL := "/api/v1/lists/" + list.ID + "/members/"
+
+	if resp := request("PUT", memberURL+partner.ID, ownerToken, `{"role":"owner"}`); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid role, got %d", resp.StatusCode)
+	}
+	if resp := request("PUT", memberURL+"unknown", ownerToken, `{"role":"co-owner"}`); resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown member, got %d", resp.StatusCode)
+	}
+	if resp := request("PUT", memberURL+owner.ID, partnerToken, `{"role":"member"}`); resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("Expected status 403 for member, got %d", resp.StatusCode)
+	}
+
+	resp := request("PUT", memberURL+partner.ID, ownerToken, `{"role":"co-owner"}`)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var member models.ListMember
+	if err := json.NewDecoder(resp.Body).Decode(&member); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if member.Role != "co-owner" {
+		t.Errorf("Expected role co-owner, got %q", member.Role)
+	}
+
+	if resp := request("DELETE", "/api/v1/lists/"+list.ID, partnerToken, ""); resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("Expected co-owner delete to be forbidden, got %d", resp.StatusCode)
+	}
+	if resp := request("PUT", "/api/v1/lists/"+list.ID, ownerToken, `{"name":"Shared","co_owners_can_delete":true}`); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if resp := request("DELETE", "/api/v1/lists/"+list.ID, partnerToken, ""); resp.StatusCode != fiber.StatusNoContent {
+		t.Errorf("Expected co-owner to delete the list, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_ViewerRole(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	owner := models.User{ID: "viewer-owner-id", Email: "viewer-owner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	kid := models.User{ID: "viewer-kid-id", Email: "viewer-kid@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	if err := server.DB.Create(&[]models.User{owner, kid}).Error; err != nil {
+		t.Fatalf("Failed to create users: %v", err)
+	}
+	ownerToken, _ := server.Auth.GenerateJWT(&owner)
+	kidToken, _ := server.Auth.GenerateJWT(&kid)
+
+	list, err := server.Lists.CreateList(owner.ID, "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	if err := server.Lists.JoinList(list.ID, kid.ID); err != nil {
+		t.Fatalf("Failed to join list: %v", err)
+	}
+	item := models.ShoppingItem{ID: "viewer-item-id", ListID: list.ID, Name: "Milk"}
+	if err := server.DB.Create(&item).Error; err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+
+	request := func(method, url, token, body string) *http.Response {
+		t.Helper()
+
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+	listURL := "/api/v1/lists/" + list.ID
+
+	if resp := request("PUT", listURL+"/members/"+kid.ID+"/role", kidToken, `{"role":"viewer"}`); resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("Expected status 403 for an editor changing roles, got %d", resp.StatusCode)
+	}
+	resp := request("PUT", listURL+"/members/"+kid.ID+"/role", ownerToken, `{"role":"viewer"}`)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	resp = request("GET", listURL+"/members", kidToken, "")
+	var members []models.User
+	if err := json.NewDecoder(resp.Body).Decode(&members); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	for _, member := range members {
+		if member.ID == kid.ID && member.Role != "viewer" {
+			t.Errorf("Expected the kid to be listed as viewer, got %q", member.Role)
+		}
+	}
+
+	if resp := request("GET", listURL+"/items", kidToken, ""); resp.StatusCode != fiber.StatusOK {
+		t.Errorf("Expected viewers to see the items, got %d", resp.StatusCode)
+	}
+	for _, change := range []struct{ method, url, body string }{
+		{"POST", listURL + "/items", `{"name":"Chocolate"}`},
+		{"PUT", listURL + "/items/" + item.ID, `{"name":"Chocolate milk"}`},
+		{"POST", listURL + "/items/" + item.ID + "/toggle", ""},
+		{"DELETE", listURL + "/items/" + item.ID, ""},
+	} {
+		if resp := request(change.method, change.url, kidToken, change.body); resp.StatusCode != fiber.StatusForbidden {
+			t.Errorf("Expected %s %s to be forbidden for viewers, got %d", change.method, change.url, resp.StatusCode)
+		}
+	}
+
+	if resp := request("PUT", listURL+"/members/"+kid.ID, ownerToken, `{"role":"editor"}`); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if resp := request("POST", listURL+"/items/"+item.ID+"/toggle", kidToken, ""); resp.StatusCode != fiber.StatusOK {
+		t.Errorf("Expected editors to toggle items, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_Display(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	user := models.User{ID: "display-user-id", Email: "display@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	if err := server.DB.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	token, _ := server.Auth.GenerateJWT(&user)
+
+	list, err := server.Lists.CreateList(user.ID, "Kitchen")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	item := models.ShoppingItem{ID: "display-item", ListID: list.ID, Name: "Milk", CreatedAt: time.Now()}
+	if err := server.DB.Create(&item).Error; err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+
+	req := httptest.NewRequest("POST", "/api/v1/lists/"+list.ID+"/display-tokens", strings.NewReader(`{"name":"E-Ink"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+	}
+	var created models.CreateDisplayTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+
+	t.Run("token in query parameter", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/display?token="+created.Token, nil))
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		var display models.DisplayResponse
+		if err := json.NewDecoder(resp.Body).Decode(&display); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		if display.List.ID != list.ID || len(display.Items) != 1 || display.Items[0].Name != "Milk" {
+			t.Errorf("Unexpected display response: %+v", display)
+		}
+	})
+
+	t.Run("token in header", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/v1/display", nil)
+		req.Header.Set("X-Display-Token", created.Token)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Errorf("Expected status 200, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("missing or revoked token", func(t *testing.T) {
+		resp, _ := app.Test(httptest.NewRequest("GET", "/api/v1/display", nil))
+		if resp.StatusCode != fiber.StatusUnauthorized {
+			t.Errorf("Expected status 401 without token, got %d", resp.StatusCode)
+		}
+
+		req := httptest.NewRequest("DELETE", "/api/v1/lists/"+list.ID+"/display-tokens/"+created.ID, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, _ = app.Test(req)
+		if resp.StatusCode != fiber.StatusNoContent {
+			t.Fatalf("Expected status 204, got %d", resp.StatusCode)
+		}
+
+		resp, _ = app.Test(httptest.NewRequest("GET", "/api/v1/display?token="+created.Token, nil))
+		if resp.StatusCode != fiber.StatusUnauthorized {
+			t.Errorf("Expected status 401 for revoked token, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestServer_GetKiosk(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	user := models.User{ID: "kiosk-user-id", Email: "kiosk@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	if err := server.DB.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	list, err := server.Lists.CreateList(user.ID, "Hallway")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	item := models.ShoppingItem{ID: "kiosk-item", ListID: list.ID, Name: "Light bulbs", Category: "Household", CreatedAt: time.Now()}
+	if err := server.DB.Create(&item).Error; err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+	_, token, err := server.Lists.CreateDisplayToken(list.ID, user.ID, "Tablet")
+	if err != nil {
+		t.Fatalf("Failed to create display token: %v", err)
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/kiosk/"+token, nil))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "text/html") {
+		t.Errorf("Expected HTML, got %s", contentType)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "Light bulbs") || !strings.Contains(string(body), "Household") {
+		t.Errorf("Expected item grouped by category, got %s", body)
+	}
+
+	resp, _ = app.Test(httptest.NewRequest("GET", "/kiosk/sld_invalid", nil))
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("Expected status 401 for invalid token, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_ExportList(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	user := models.User{ID: "export-user-id", Email: "export@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	if err := server.DB.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	token, _ := server.Auth.GenerateJWT(&user)
+
+	list, err := server.Lists.CreateList(user.ID, "Fridge List")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	item := models.ShoppingItem{ID: "export-item", ListID: list.ID, Name: "Butter", Category: "Dairy", CreatedAt: time.Now()}
+	if err := server.DB.Create(&item).Error; err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+
+	request := func(url string) *http.Response {
+		t.Helper()
+
+		req := httptest.NewRequest("GET", url, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+
+	resp := request("/api/v1/lists/" + list.ID + "/export?format=pdf")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Content-Type") != "application/pdf" {
+		t.Errorf("Expected PDF, got %s", resp.Header.Get("Content-Type"))
+	}
+	if disposition := resp.Header.Get("Content-Disposition"); !strings.Contains(disposition, `filename="fridge-list.pdf"`) {
+		t.Errorf("Expected file name from list name, got %s", disposition)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if !bytes.HasPrefix(body, []byte("%PDF-")) || !bytes.Contains(body, []byte("(Butter)")) {
+		t.Error("Expected PDF containing the item")
+	}
+
+	// With export storage, a copy of the export is kept below exports/
+	dir := t.TempDir()
+	server.Exports = storage.NewLocal(dir)
+	if resp := request("/api/v1/lists/" + list.ID + "/export"); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200 from local storage, got %d", resp.StatusCode)
+	}
+	stored, err := filepath.Glob(filepath.Join(dir, "exports", list.ID, "*-fridge-list.pdf"))
+	if err != nil || len(stored) != 1 {
+		t.Fatalf("Expected one stored export, got %v", stored)
+	}
+	if content, _ := os.ReadFile(stored[0]); !bytes.HasPrefix(content, []byte("%PDF-")) {
+		t.Error("Expected the stored export to be the PDF")
+	}
+
+	if resp := request("/api/v1/lists/" + list.ID + "/export?format=docx"); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 for unsupported format, got %d", resp.StatusCode)
+	}
+	if resp := request("/api/v1/lists/other-list/export"); resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("Expected status 403 for other lists, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_CalendarFeed(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	user := models.User{ID: "calendar-user-id", Email: "calendar@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	if err := server.DB.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	token, _ := server.Auth.GenerateJWT(&user)
+
+	list, err := server.Lists.CreateList(user.ID, "Party")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	request := func(method, target, body string) *http.Response {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+
+	resp := request("POST", "/api/v1/lists/"+list.ID+"/items", `{"name":"Cake","due_date":"2025-06-01"}`)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+	}
+	if resp := request("POST", "/api/v1/lists/"+list.ID+"/items", `{"name":"Cake","due_date":"tomorrow"}`); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid due date, got %d", resp.StatusCode)
+	}
+
+	if resp := request("GET", "/api/v1/account/calendar", ""); resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("Expected status 404 without feed, got %d", resp.StatusCode)
+	}
+
+	resp = request("POST", "/api/v1/account/calendar", "")
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+	}
+	var created models.CreateCalendarFeedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	feedURL, err := url.Parse(created.URL)
+	if err != nil || !strings.HasSuffix(feedURL.Path, ".ics") {
+		t.Fatalf("Unexpected feed URL %q", created.URL)
+	}
+
+	resp, err = app.Test(httptest.NewRequest("GET", feedURL.Path, nil))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "text/calendar") {
+		t.Errorf("Expected calendar content type, got %q", contentType)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "DTSTART;VALUE=DATE:20250601") {
+		t.Errorf("Expected feed to contain the due item, got:\n%s", body)
+	}
+
+	if resp := request("DELETE", "/api/v1/account/calendar", ""); resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", resp.StatusCode)
+	}
+	resp, _ = app.Test(httptest.NewRequest("GET", feedURL.Path, nil))
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("Expected status 401 for revoked feed, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_GetActivityFeed(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	user := models.User{ID: "feed-user-id", Email: "feed@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	if err := server.DB.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	token, _ := server.Auth.GenerateJWT(&user)
+
+	list, err := server.Lists.CreateList(user.ID, "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	item := models.ShoppingItem{ID: "feed-item", ListID: list.ID, Name: "Oat milk", CreatedAt: time.Now()}
+	if err := server.DB.Create(&item).Error; err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+	_, displayToken, err := server.Lists.CreateDisplayToken(list.ID, user.ID, "Feed reader")
+	if err != nil {
+		t.Fatalf("Failed to create display token: %v", err)
+	}
+
+	req := httptest.NewRequest("POST", "/api/v1/lists/"+list.ID+"/items/"+item.ID+"/toggle", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	var toggled models.ShoppingItem
+	if err := json.NewDecoder(resp.Body).Decode(&toggled); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if toggled.CompletedAt == nil {
+		t.Error("Expected completion time to be set")
+	}
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/feeds/"+displayToken+".atom", nil))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "application/atom+xml") {
+		t.Errorf("Expected Atom content type, got %q", contentType)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	for _, want := range []string{"<title>Oat milk completed</title>", "<title>Oat milk added</title>"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("Expected feed to contain %q, got:\n%s", want, body)
+		}
+	}
+
+	resp, _ = app.Test(httptest.NewRequest("GET", "/feeds/sld_invalid.atom", nil))
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("Expected status 401 for invalid token, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_Integrations(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	user := models.User{ID: "integration-user-id", Email: "integration@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	if err := server.DB.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if _, err := server.Lists.CreateList(user.ID, "Groceries"); err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	_, writeKey, err := server.Auth.CreateAPIKey(user.ID, "Zapier", nil, 0)
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+	_, readKey, err := server.Auth.CreateAPIKey(user.ID, "n8n", []string{auth.ScopeItemsRead}, 0)
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+
+	request := func(method, target, key, body string) *http.Response {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(auth.APIKeyHeader, key)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+	poll := func(query string) models.IntegrationItemsResponse {
+		t.Helper()
+		resp := request("GET", "/api/v1/integrations/items"+query, readKey, "")
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		var polled models.IntegrationItemsResponse
+		if err := json.NewDecoder(resp.Body).Decode(&polled); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		return polled
+	}
+
+	initial := poll("")
+	if len(initial.Items) != 0 {
+		t.Fatalf("Expected no items, got %+v", initial.Items)
+	}
+
+	resp := request("POST", "/api/v1/integrations/items", writeKey, `{"list":"groceries","name":"Milk","quantity":2,"unit":"l"}`)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+	}
+	var added models.IntegrationItem
+	if err := json.NewDecoder(resp.Body).Decode(&added); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if added.ListName != "Groceries" || added.Quantity != 2 || added.Unit != "l" {
+		t.Errorf("Unexpected item %+v", added)
+	}
+
+	first := poll("")
+	if len(first.Items) != 1 || first.Items[0].ID != added.ID || first.Since == "" {
+		t.Fatalf("Expected the new item with a cursor, got %+v", first)
+	}
+	if again := poll("?since=" + url.QueryEscape(first.Since)); len(again.Items) != 0 {
+		t.Errorf("Expected no items after the cursor, got %+v", again.Items)
+	}
+
+	if resp := request("POST", "/api/v1/integrations/items", readKey, `{"list":"Groceries","name":"Eggs"}`); resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("Expected status 403 without write scope, got %d", resp.StatusCode)
+	}
+	if resp := request("POST", "/api/v1/integrations/items", writeKey, `{"list":"Hardware","name":"Nails"}`); resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown list, got %d", resp.StatusCode)
+	}
+	if resp := request("GET", "/api/v1/integrations/items?since=not-a-cursor", readKey, ""); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid cursor, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_SlackCommand(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	user := models.User{ID: "slack-user-id", Email: "slack@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	if err := server.DB.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	token, _ := server.Auth.GenerateJWT(&user)
+	if _, err := server.Lists.CreateList(user.ID, "Kitchen"); err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	command := func(text, signature string) *http.Response {
+		body := url.Values{"team_id": {"T123"}, "text": {text}}.Encode()
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		if signature == "" {
+			mac := hmac.New(sha256.New, []byte("slack-secret"))
+			mac.Write([]byte("v0:" + timestamp + ":" + body))
+			signature = "v0=" + hex.EncodeToString(mac.Sum(nil))
+		}
+		req := httptest.NewRequest("POST", "/api/v1/integrations/slack", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+		req.Header.Set("X-Slack-Signature", signature)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+	reply := func(resp *http.Response) map[string]string {
+		t.Helper()
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		var message map[string]string
+		if err := json.NewDecoder(resp.Body).Decode(&message); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		return message
+	}
+
+	if resp := command("help", ""); resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("Expected status 404 while disabled, got %d", resp.StatusCode)
+	}
+	server.Chat.SlackSigningSecret = "slack-secret"
+	if resp := command("help", "v0=forged"); resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("Expected status 401 for invalid signature, got %d", resp.StatusCode)
+	}
+
+	req := httptest.NewRequest("POST", "/api/v1/account/chat-links/code", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+	}
+	var code models.ChatLinkCodeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&code); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+
+	if message := reply(command(strings.TrimPrefix(code.Command, "/shop "), "")); message["text"] != "This workspace is linked." || message["response_type"] != "ephemeral" {
+		t.Fatalf("Unexpected reply %v", message)
+	}
+	message := reply(command("add milk to kitchen", ""))
+	if message["text"] != "Added Milk to Kitchen." || message["response_type"] != "in_channel" {
+		t.Errorf("Unexpected reply %v", message)
+	}
+
+	req = httptest.NewRequest("DELETE", "/api/v1/account/chat-links/slack/T123", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	if resp, _ := app.Test(req); resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", resp.StatusCode)
+	}
+	if message := reply(command("list", "")); !strings.Contains(message["text"], "not linked") {
+		t.Errorf("Expected unlinked workspace, got %v", message)
+	}
+}
+
+func TestServer_Shortcuts(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	user := models.User{ID: "shortcuts-user-id", Email: "shortcuts@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	if err := server.DB.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if _, err := server.Lists.CreateList(user.ID, "Groceries"); err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	_, key, err := server.Auth.CreateAPIKey(user.ID, "iPhone", nil, 0)
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+
+	get := func(target string) (int, string) {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest("GET", target, nil))
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if status, _ := get("/api/v1/shortcuts/add?list=Groceries&item=Milk&key=sl_invalid"); status != fiber.StatusUnauthorized {
+		t.Errorf("Expected status 401 for invalid key, got %d", status)
+	}
+
+	status, body := get("/api/v1/shortcuts/add?list=groceries&item=" + url.QueryEscape("2 l milk") + "&key=" + key)
+	if status != fiber.StatusOK || body != "Added Milk to Groceries" {
+		t.Fatalf("Unexpected answer %d %q", status, body)
+	}
+	if status, _ := get("/api/v1/shortcuts/add?list=Groceries&key=" + key); status != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 without item, got %d", status)
+	}
+	if status, _ := get("/api/v1/shortcuts/add?list=Hardware&item=Nails&key=" + key); status != fiber.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown list, got %d", status)
+	}
+
+	status, body = get("/api/v1/shortcuts/items?list=Groceries&key=" + key)
+	if status != fiber.StatusOK || body != "Milk (2 l)" {
+		t.Errorf("Unexpected answer %d %q", status, body)
+	}
+}
+
+func TestServer_GetCompactList(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	user := models.User{ID: "watch-user-id", Email: "watch@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	if err := server.DB.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	token, _ := server.Auth.GenerateJWT(&user)
+
+	list, err := server.Lists.CreateList(user.ID, "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	for _, item := range []models.ShoppingItem{
+		{ID: "bread", ListID: list.ID, Name: "Bread", Completed: true, Tags: "[]", CreatedAt: time.Now()},
+		{ID: "milk", ListID: list.ID, Name: "Milk", Quantity: 2, Unit: "l", Tags: "[]", CreatedAt: time.Now().Add(-time.Hour)},
+	} {
+		if err := server.DB.Create(&item).Error; err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+
+	request := func(method, target, etag string) *http.Response {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+
+	resp := request("GET", "/api/v1/lists/"+list.ID+"/compact", "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	expected := `{"id":"` + list.ID + `","name":"Groceries","items":[{"id":"milk","name":"Milk","completed":false},{"id":"bread","name":"Bread","completed":true}]}`
+	if string(body) != expected {
+		t.Errorf("Expected %s, got %s", expected, body)
+	}
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected an ETag")
+	}
+
+	resp = request("GET", "/api/v1/lists/"+list.ID+"/compact", etag)
+	if resp.StatusCode != fiber.StatusNotModified {
+		t.Errorf("Expected status 304 for unchanged list, got %d", resp.StatusCode)
+	}
+
+	request("POST", "/api/v1/lists/"+list.ID+"/items/milk/toggle", "")
+	resp = request("GET", "/api/v1/lists/"+list.ID+"/compact", etag)
+	if resp.StatusCode != fiber.StatusOK || resp.Header.Get("ETag") == etag {
+		t.Errorf("Expected a new ETag after a change, got %d %q", resp.StatusCode, resp.Header.Get("ETag"))
+	}
+
+	if resp := request("GET", "/api/v1/lists/unknown/compact", ""); resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("Expected status 403 for foreign list, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_FeatureFlags(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	admin, err := server.Setup.SetupSystem("admin@example.com")
+	if err != nil {
+		t.Fatalf("Failed to set up system: %v", err)
+	}
+	adminToken, _ := server.Auth.GenerateJWT(admin)
+	user := models.User{ID: "flags-user-id", Email: "flags@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	if err := server.DB.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	userToken, _ := server.Auth.GenerateJWT(&user)
+
+	request := func(method, target, token, body string) *http.Response {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+	capabilityFlags := func(token string) map[string]bool {
+		t.Helper()
+		var response models.CapabilitiesResponse
+		if err := json.NewDecoder(request("GET", "/api/v1/capabilities", token, "").Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		return response.Flags
+	}
+
+	if resp := request("PUT", "/api/v1/admin/flags/price_tracking", userToken, `{"rollout":0}`); resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("Expected status 403 for non-admins, got %d", resp.StatusCode)
+	}
+	if resp := request("PUT", "/api/v1/admin/flags/price_tracking", adminToken, `{"rollout":101}`); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid rollout, got %d", resp.StatusCode)
+	}
+	if resp := request("PUT", "/api/v1/admin/flags/price_tracking", adminToken, `{"rollout":0,"description":"Price tracking"}`); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if resp := request("PUT", "/api/v1/admin/flags/price_tracking/users/"+user.ID, adminToken, `{"enabled":true}`); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if resp := request("PUT", "/api/v1/admin/flags/price_tracking/users/unknown", adminToken, `{"enabled":true}`); resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown user, got %d", resp.StatusCode)
+	}
+
+	if flags := capabilityFlags(userToken); !flags["price_tracking"] {
+		t.Errorf("Expected the flag on for the user, got %v", flags)
+	}
+	if flags := capabilityFlags(adminToken); flags["price_tracking"] {
+		t.Errorf("Expected the flag off for the admin, got %v", flags)
+	}
+	if flags := capabilityFlags(""); flags["price_tracking"] {
+		t.Errorf("Expected the flag off without a token, got %v", flags)
+	}
+
+	// Dry runs count what would be deleted and keep it
+	preview := func(target string) models.DeletePreview {
+		t.Helper()
+		resp := request("DELETE", target+"?dry_run=true", adminToken, "")
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		var preview models.DeletePreview
+		if err := json.NewDecoder(resp.Body).Decode(&preview); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		return preview
+	}
+	if p := preview("/api/v1/admin/flags/price_tracking/users/" + user.ID); !p.DryRun || p.Deleted["feature_flag_overrides"] != 1 {
+		t.Errorf("Expected a preview of 1 override, got %+v", p)
+	}
+	if p := preview("/api/v1/admin/flags/price_tracking"); p.Deleted["feature_flags"] != 1 || p.Deleted["feature_flag_overrides"] != 1 {
+		t.Errorf("Expected a preview of the flag and its override, got %+v", p)
+	}
+	if resp := request("DELETE", "/api/v1/admin/flags/missing?dry_run=true", adminToken, ""); resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown flags, got %d", resp.StatusCode)
+	}
+	if flags := capabilityFlags(userToken); !flags["price_tracking"] {
+		t.Errorf("Expected the flag and override to be kept, got %v", flags)
+	}
+
+	if resp := request("DELETE", "/api/v1/admin/flags/price_tracking", adminToken, ""); resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", resp.StatusCode)
+	}
+	entries, _ := server.Moderation.AuditLog(0)
+	if len(entries) != 3 || entries[0].Action != moderation.ActionDeleteFeatureFlag || entries[0].DryRun {
+		t.Errorf("Expected the deletes and their dry runs in the audit log, got %+v", entries)
+	}
+	if flags := capabilityFlags(userToken); len(flags) != 0 {
+		t.Errorf("Expected no flags, got %v", flags)
+	}
+}
+
+func TestServer_EnrichmentHook(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	owner := models.User{ID: "enrichment-owner-id", Email: "enrichment-owner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	member := models.User{ID: "enrichment-member-id", Email: "enrichment-member@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	for _, user := range []*models.User{&owner, &member} {
+		if err := server.DB.Create(user).Error; err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+	ownerToken, _ := server.Auth.GenerateJWT(&owner)
+	memberToken, _ := server.Auth.GenerateJWT(&member)
+
+	list, err := server.Lists.CreateList(owner.ID, "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	if err := server.Lists.JoinList(list.ID, member.ID); err != nil {
+		t.Fatalf("Failed to join list: %v", err)
+	}
+
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"price": 0.99, "image_url": "https://example.com/milk.jpg"}`))
+	}))
+	defer hook.Close()
+
+	request := func(method, target, token, body string) *http.Response {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+	hookURL := "/api/v1/lists/" + list.ID + "/enrichment-hook"
+
+	if resp := request("GET", hookURL, ownerToken, ""); resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("Expected status 404 without a hook, got %d", resp.StatusCode)
+	}
+	if resp := request("PUT", hookURL, memberToken, `{"url":"`+hook.URL+`"}`); resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("Expected status 403 for members, got %d", resp.StatusCode)
+	}
+	if resp := request("PUT", hookURL, ownerToken, `{"url":"not a url"}`); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid URL, got %d", resp.StatusCode)
+	}
+	if resp := request("PUT", hookURL, ownerToken, `{"url":"`+hook.URL+`"}`); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	resp := request("POST", "/api/v1/lists/"+list.ID+"/items", memberToken, `{"name":"Milk"}`)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+	}
+	var item models.ShoppingItem
+	if err := json.NewDecoder(resp.Body).Decode(&item); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+
+	// The hook is called in the background
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var stored models.ShoppingItem
+		server.DB.First(&stored, "id = ?", item.ID)
+		if stored.Price != nil && stored.ImageURL == "https://example.com/milk.jpg" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the item to be enriched, got %+v", stored)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if resp := request("DELETE", hookURL, ownerToken, ""); resp.StatusCode != fiber.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_ListRules(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	owner := models.User{ID: "rules-owner-id", Email: "rules-owner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	if err := server.DB.Create(&owner).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	token, _ := server.Auth.GenerateJWT(&owner)
+	list, err := server.Lists.CreateList(owner.ID, "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	request := func(method, target, body string) *http.Response {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+	rulesURL := "/api/v1/lists/" + list.ID + "/rules"
+
+	if resp := request("POST", rulesURL, `{"name":"Organic","event":"item_created","condition":"name contains","actions":[{"type":"add_tag","value":"organic"}]}`); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid condition, got %d", resp.StatusCode)
+	}
+	if resp := request("POST", rulesURL, `{"name":"Organic","event":"item_created","condition":"true","actions":[{"type":"delete","value":"x"}]}`); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown action, got %d", resp.StatusCode)
+	}
+	if resp := request("POST", rulesURL, `{"name":"Organic","event":"item_created","condition":"name contains \"bio\"","actions":[{"type":"add_tag","value":"organic"}]}`); resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+	}
+
+	resp := request("POST", "/api/v1/lists/"+list.ID+"/items", `{"name":"Bio Milk"}`)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+	}
+	var item models.ShoppingItem
+	if err := json.NewDecoder(resp.Body).Decode(&item); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if item.Tags != `["organic"]` {
+		t.Errorf("Expected the rule to tag the new item, got %q", item.Tags)
+	}
+
+	var listRules []models.ListRule
+	if err := json.NewDecoder(request("GET", rulesURL, "").Body).Decode(&listRules); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if len(listRules) != 1 || !listRules[0].Enabled {
+		t.Fatalf("Expected one enabled rule, got %+v", listRules)
+	}
+	if resp := request("DELETE", rulesURL+"/"+listRules[0].ID, ""); resp.StatusCode != fiber.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_Moderation(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	admin, err := server.Setup.SetupSystem("admin@example.com")
+	if err != nil {
+		t.Fatalf("Failed to set up system: %v", err)
+	}
+	adminToken, _ := server.Auth.GenerateJWT(admin)
+	user := models.User{ID: "moderated-user-id", Email: "moderated@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	if err := server.DB.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	userToken, _ := server.Auth.GenerateJWT(&user)
+
+	request := func(method, target, token, body string) *http.Response {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+
+	if resp := request("PUT", "/api/v1/admin/users/"+user.ID+"/invitations", userToken, `{"frozen":true}`); resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("Expected status 403 for non-admins, got %d", resp.StatusCode)
+	}
+	if resp := request("PUT", "/api/v1/admin/users/"+user.ID+"/invitations", adminToken, `{}`); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 without frozen, got %d", resp.StatusCode)
+	}
+	if resp := request("PUT", "/api/v1/admin/users/unknown/invitations", adminToken, `{"frozen":true}`); resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown user, got %d", resp.StatusCode)
+	}
+	if resp := request("PUT", "/api/v1/admin/users/"+user.ID+"/invitations", adminToken, `{"frozen":true}`); resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", resp.StatusCode)
+	}
+	if resp := request("POST", "/api/v1/invitations", userToken, `{"email":"friend@example.com","type":"server"}`); resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("Expected status 403 for frozen invitations, got %d", resp.StatusCode)
+	}
+
+	if resp := request("POST", "/api/v1/admin/users/"+user.ID+"/logout", adminToken, ""); resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", resp.StatusCode)
+	}
+	if resp := request("GET", "/api/v1/lists", userToken, ""); resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("Expected status 401 after forced logout, got %d", resp.StatusCode)
+	}
+
+	resp := request("GET", "/api/v1/admin/audit", adminToken, "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var entries []models.AuditEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 audit entries, got %+v", entries)
+	}
+	for _, entry := range entries {
+		if entry.ActorID != admin.ID || entry.TargetUserID != user.ID {
+			t.Errorf("Unexpected audit entry %+v", entry)
+		}
+	}
+}
+
+func TestServer_ValidationErrorLanguage(t *testing.T) {
+	_, app := setupTestServer(t)
+
+	req := httptest.NewRequest("POST", "/api/v1/auth/login", strings.NewReader(`{"email":"not-an-email"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Language", "de-DE,de;q=0.9")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", resp.StatusCode)
+	}
+
+	var response struct {
+		Details map[string]string `json:"details"`
+		Codes   map[string]struct {
+			Code string `json:"code"`
+		} `json:"codes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if response.Details["email"] != "Muss eine gültige E-Mail-Adresse sein" {
+		t.Errorf("Expected a German message, got %q", response.Details["email"])
+	}
+	if response.Codes["email"].Code != "email" {
+		t.Errorf("Expected code email, got %+v", response.Codes["email"])
+	}
+}
+
+func TestServer_DeduplicateListItems(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	owner := models.User{ID: "dedupe-owner-id", Email: "dedupe-owner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	member := models.User{ID: "dedupe-member-id", Email: "dedupe-member@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	server.DB.Create(&owner)
+	server.DB.Create(&member)
+	list, err := server.Lists.CreateList(owner.ID, "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	server.DB.Create(&models.ListMember{ListID: list.ID, UserID: member.ID, Role: "member", JoinedAt: time.Now()})
+
+	server.DB.Create(&models.ShoppingItem{ID: "dedupe-milk-1", ListID: list.ID, Name: "Milk", Quantity: 1, Tags: "[]", CreatedAt: time.Now().Add(-time.Minute)})
+	server.DB.Create(&models.ShoppingItem{ID: "dedupe-milk-2", ListID: list.ID, Name: "milk", Quantity: 2, Tags: "[]", CreatedAt: time.Now()})
+
+	request := func(user *models.User, method, target, body string) *http.Response {
+		token, _ := server.Auth.GenerateJWT(user)
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+	dedupeURL := "/api/v1/lists/" + list.ID + "/deduplicate"
+
+	if resp := request(&member, "POST", dedupeURL, ""); resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("Expected status 403 for a member, got %d", resp.StatusCode)
+	}
+
+	// Opting out of the scheduled job does not block a requested merge
+	resp := request(&owner, "PUT", "/api/v1/lists/"+list.ID, `{"name":"Groceries","skip_deduplication":true}`)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var updated models.ShoppingList
+	json.NewDecoder(resp.Body).Decode(&updated)
+	if !updated.SkipDeduplication {
+		t.Error("Expected the list to opt out of deduplication")
+	}
+
+	resp = request(&owner, "POST", dedupeURL, "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var merges []models.ItemMerge
+	json.NewDecoder(resp.Body).Decode(&merges)
+	if len(merges) != 1 || merges[0].ItemID != "dedupe-milk-1" || merges[0].Quantity != 3 {
+		t.Errorf("Unexpected merges: %+v", merges)
+	}
+
+	var count int64
+	server.DB.Model(&models.ShoppingItem{}).Where("list_id = ?", list.ID).Count(&count)
+	if count != 1 {
+		t.Errorf("Expected 1 item after the merge, got %d", count)
+	}
+}
+
+func TestServer_MergeList(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	owner := models.User{ID: "list-merge-owner-id", Email: "list-merge-owner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	server.DB.Create(&owner)
+	token, _ := server.Auth.GenerateJWT(&owner)
+	target, _ := server.Lists.CreateList(owner.ID, "Groceries")
+	source, _ := server.Lists.CreateList(owner.ID, "Groceries 2")
+	server.DB.Create(&models.ShoppingItem{ID: "list-merge-item", ListID: source.ID, Name: "Coffee", Tags: "[]", CreatedAt: time.Now()})
+
+	request := func(method, target, body string) *http.Response {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+	mergeURL := "/api/v1/lists/" + target.ID + "/merge-from/" + source.ID
+
+	if resp := request("POST", mergeURL, `{"duplicates":"drop"}`); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown duplicate mode, got %d", resp.StatusCode)
+	}
+	if resp := request("POST", "/api/v1/lists/"+target.ID+"/merge-from/missing", ""); resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("Expected status 404 for a missing list, got %d", resp.StatusCode)
+	}
+
+	resp := request("POST", mergeURL, "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var result models.ListMergeResult
+	json.NewDecoder(resp.Body).Decode(&result)
+	if result.MovedItems != 1 || result.List.ID != target.ID {
+		t.Errorf("Unexpected merge result: %+v", result)
+	}
+
+	var lists []models.ShoppingList
+	json.NewDecoder(request("GET", "/api/v1/lists?archived=true", "").Body).Decode(&lists)
+	if len(lists) != 1 || lists[0].ID != source.ID {
+		t.Errorf("Expected the source list to be archived, got %+v", lists)
+	}
+
+	if resp := request("PUT", "/api/v1/lists/"+source.ID, `{"name":"Groceries 2","archived":false}`); resp.StatusCode != fiber.StatusOK {
+		t.Errorf("Expected status 200 when restoring the list, got %d", resp.StatusCode)
+	}
+	json.NewDecoder(request("GET", "/api/v1/lists", "").Body).Decode(&lists)
+	if len(lists) != 2 {
+		t.Errorf("Expected the restored list among the lists, got %d", len(lists))
+	}
+}
+
+func TestServer_ListBundle(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	owner := models.User{ID: "bundle-owner-id", Email: "bundle-owner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	partner := models.User{ID: "bundle-partner-id", Email: "bundle-partner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	server.DB.Create(&owner)
+	server.DB.Create(&partner)
+	token, _ := server.Auth.GenerateJWT(&owner)
+	list, _ := server.Lists.CreateList(owner.ID, "Groceries")
+	server.DB.Create(&models.ListMember{ListID: list.ID, UserID: partner.ID, Role: "member", JoinedAt: time.Now()})
+	server.DB.Create(&models.ShoppingItem{ID: "bundle-item", ListID: list.ID, Name: "Coffee", Tags: "[]", CreatedAt: time.Now()})
+
+	request := func(method, target, body string) *http.Response {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+	bundleURL := "/api/v1/lists/" + list.ID + "/bundle"
+
+	if resp := request("GET", bundleURL, ""); resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("Expected status 404 without federation key, got %d", resp.StatusCode)
+	}
+
+	server.Federation.Key = "handler-federation-key"
+	resp := request("GET", bundleURL, "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp := request("POST", "/api/v1/lists/import", `{"version":1,"payload":"e30=","signature":"AAAA"}`); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 for a forged bundle, got %d", resp.StatusCode)
+	}
+
+	resp = request("POST", "/api/v1/lists/import", string(body))
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+	}
+	var result models.ListImportResult
+	json.NewDecoder(resp.Body).Decode(&result)
+	if result.List.ID == list.ID || result.List.Name != "Groceries" {
+		t.Errorf("Expected a new list, got %+v", result.List)
+	}
+	if len(result.Invited) != 1 || result.Invited[0] != partner.Email {
+		t.Errorf("Expected the partner to be invited, got %+v", result)
+	}
+
+	var count int64
+	server.DB.Model(&models.ShoppingItem{}).Where("list_id = ?", result.List.ID).Count(&count)
+	if count != 1 {
+		t.Errorf("Expected 1 imported item, got %d", count)
+	}
+}
+
+func TestServer_ItemImage(t *testing.T) {
+	server, app := setupTestServer(t)
+	server.Images.Client = imageproxy.NewClient(true)
+	server.Images.Storage = storage.NewLocal(t.TempDir())
+
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 1024, 256))); err != nil {
+		t.Fatalf("Failed to encode image: %v", err)
+	}
+	fetches := 0
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		if r.URL.Query().Get("utm_source") != "" {
+			t.Errorf("Expected tracking parameters to be stripped, got %s", r.URL)
+		}
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(encoded.Bytes())
+	}))
+	defer origin.Close()
+
+	user := models.User{ID: "image-user-id", Email: "image@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	other := models.User{ID: "image-other-id", Email: "image-other@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	server.DB.Create(&user)
+	server.DB.Create(&other)
+	token, _ := server.Auth.GenerateJWT(&user)
+	otherToken, _ := server.Auth.GenerateJWT(&other)
+	list, _ := server.Lists.CreateList(user.ID, "Groceries")
+	server.DB.Create(&models.ShoppingItem{ID: "image-item", ListID: list.ID, Name: "Milk", Tags: "[]",
+		ImageURL: origin.URL + "/milk.png?utm_source=shop", CreatedAt: time.Now()})
+	server.DB.Create(&models.ShoppingItem{ID: "plain-item", ListID: list.ID, Name: "Bread", Tags: "[]", CreatedAt: time.Now()})
+
+	request := func(token, itemID string) *http.Response {
+		req := httptest.NewRequest("GET", "/api/v1/lists/"+list.ID+"/items/"+itemID+"/image", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+
+	for range 2 {
+		resp := request(token, "image-item")
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		config, _, err := image.DecodeConfig(resp.Body)
+		if err != nil || config.Width != imageproxy.DefaultMaxDimension || config.Height != 128 {
+			t.Errorf("Expected a downscaled image, got %+v (%v)", config, err)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("Expected the image to be fetched once, got %d", fetches)
+	}
+
+	if resp := request(token, "plain-item"); resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("Expected status 404 for an item without image, got %d", resp.StatusCode)
+	}
+	if resp := request(otherToken, "image-item"); resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("Expected status 403 for non-members, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_Retention(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	admin, err := server.Setup.SetupSystem("admin@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+	adminToken, _ := server.Auth.GenerateJWT(admin)
+	user := models.User{ID: "retention-user-id", Email: "retention@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	server.DB.Create(&user)
+	userToken, _ := server.Auth.GenerateJWT(&user)
+
+	old := time.Now().AddDate(-1, 0, 0)
+	server.DB.Create(&models.AuditEntry{ID: "old-entry", ActorID: admin.ID, Action: "logout", TargetUserID: user.ID, CreatedAt: old})
+
+	request := func(token, method, target, body string) *http.Response {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+
+	if resp := request(userToken, "GET", "/api/v1/admin/retention", ""); resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("Expected status 403 for non-admins, got %d", resp.StatusCode)
+	}
+	if resp := request(adminToken, "PUT", "/api/v1/admin/settings", `{"audit_retention_days": -1}`); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 for a negative retention period, got %d", resp.StatusCode)
+	}
+	if resp := request(adminToken, "PUT", "/api/v1/admin/settings", `{"audit_retention_days": 180}`); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	resp := request(adminToken, "GET", "/api/v1/admin/retention", "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var status models.RetentionStatus
+	json.NewDecoder(resp.Body).Decode(&status)
+	for _, policy := range status.Policies {
+		if policy.Data == "audit" && (policy.Days != 180 || policy.Rows != 1 || policy.NextPurgeAt == nil) {
+			t.Errorf("Unexpected audit policy: %+v", policy)
+		}
+	}
+
+	resp = request(adminToken, "POST", "/api/v1/admin/retention/purge?dry_run=true", "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var preview models.RetentionPurgeResult
+	json.NewDecoder(resp.Body).Decode(&preview)
+	if !preview.DryRun || preview.Purged["audit"] != 1 {
+		t.Errorf("Expected the old audit entry to be counted, got %+v", preview)
+	}
+	if err := server.DB.First(&models.AuditEntry{}, "id = ?", "old-entry").Error; err != nil {
+		t.Errorf("Expected the dry run to keep the old audit entry: %v", err)
+	}
+
+	resp = request(adminToken, "POST", "/api/v1/admin/retention/purge", "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var result models.RetentionPurgeResult
+	json.NewDecoder(resp.Body).Decode(&result)
+	if result.DryRun || result.Purged["audit"] != 1 {
+		t.Errorf("Expected the old audit entry to be purged, got %+v", result)
+	}
+
+	entries, _ := server.Moderation.AuditLog(0)
+	if len(entries) != 2 || entries[0].Action != moderation.ActionPurgeRetention || entries[0].DryRun || !entries[1].DryRun {
+		t.Errorf("Expected the purge and its dry run in the audit log, got %+v", entries)
+	}
+}
+
+func TestServer_OperatorDigest(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	admin, err := server.Setup.SetupSystem("admin@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+	adminToken, _ := server.Auth.GenerateJWT(admin)
+	user := models.User{ID: "digest-user-id", Email: "digest@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	server.DB.Create(&user)
+	userToken, _ := server.Auth.GenerateJWT(&user)
+
+	request := func(token, method, target, body string) *http.Response {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+
+	if resp := request(userToken, "GET", "/api/v1/admin/digest", ""); resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("Expected status 403 for non-admins, got %d", resp.StatusCode)
+	}
+
+	resp := request(adminToken, "GET", "/api/v1/admin/digest", "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var report models.OperatorDigest
+	json.NewDecoder(resp.Body).Decode(&report)
+	if report.NewUsers != 2 || report.TotalUsers != 2 || report.DatabaseBytes <= 0 {
+		t.Errorf("Unexpected digest: %+v", report)
+	}
+
+	if resp := request(adminToken, "PUT", "/api/v1/admin/settings", `{"weekly_digest": true}`); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if sent, err := server.Digest.SendDue(); err != nil || !sent {
+		t.Errorf("Expected the digest to be sent after opting in, got %v (%v)", sent, err)
+	}
+}
+
+func TestServer_EndpointQuota(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	admin, err := server.Setup.SetupSystem("admin@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+	adminToken, _ := server.Auth.GenerateJWT(admin)
+	user := models.User{ID: "quota-user-id", Email: "quota@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	server.DB.Create(&user)
+	userToken, _ := server.Auth.GenerateJWT(&user)
+	smartList, err := server.SmartLists.CreateSmartList(user.ID, "Dairy", models.ItemFilter{Categories: []string{"Dairy"}})
+	if err != nil {
+		t.Fatalf("Failed to create smart list: %v", err)
+	}
+
+	request := func(token, method, target, body string) *http.Response {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+
+	if resp := request(adminToken, "PUT", "/api/v1/admin/settings", `{"endpoint_quotas": {"uploads": 5}}`); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown endpoint group, got %d", resp.StatusCode)
+	}
+	if resp := request(adminToken, "PUT", "/api/v1/admin/settings", `{"endpoint_quotas": {"search": 2}}`); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	searchURL := "/api/v1/smart-lists/" + smartList.ID + "/items"
+	for i := range 2 {
+		if resp := request(userToken, "GET", searchURL, ""); resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("Expected request %d within the quota to succeed, got %d", i+1, resp.StatusCode)
+		}
+	}
+	resp := request(userToken, "GET", searchURL, "")
+	if resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Fatalf("Expected status 429 above the quota, got %d", resp.StatusCode)
+	}
+	if retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After")); err != nil || retryAfter < 1 || retryAfter > 60 {
+		t.Errorf("Expected Retry-After in seconds, got %q", resp.Header.Get("Retry-After"))
+	}
+	if resp := request(adminToken, "GET", "/api/v1/smart-lists", ""); resp.StatusCode != fiber.StatusOK {
+		t.Errorf("Expected other endpoints to be unaffected, got %d", resp.StatusCode)
+	}
+
+	if resp := request(adminToken, "PUT", "/api/v1/admin/settings", `{"endpoint_quotas": {"search": 0}}`); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if resp := request(userToken, "GET", searchURL, ""); resp.StatusCode != fiber.StatusOK {
+		t.Errorf("Expected no quota after setting it to 0, got %d", resp.StatusCode)
+	}
+	if resp := request(adminToken, "PUT", "/api/v1/admin/settings", `{"endpoint_quotas": {"search": null}}`); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if quota := server.Setup.EndpointQuota(setup.EndpointsSearch); quota != setup.DefaultEndpointQuotas[setup.EndpointsSearch] {
+		t.Errorf("Expected the default quota to be restored, got %d", quota)
+	}
+
+	// Stats and reports share their own quota
+	if resp := request(adminToken, "PUT", "/api/v1/admin/settings", `{"endpoint_quotas": {"stats": 1}}`); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if resp := request(userToken, "GET", "/api/v1/reports/spend", ""); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected the first report within the quota to succeed, got %d", resp.StatusCode)
+	}
+	if resp := request(userToken, "GET", "/api/v1/reports/spend", ""); resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Errorf("Expected status 429 above the stats quota, got %d", resp.StatusCode)
+	}
+	if resp := request(userToken, "GET", searchURL, ""); resp.StatusCode != fiber.StatusOK {
+		t.Errorf("Expected the search quota to be unaffected, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_GetListsBatch(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	user := models.User{ID: "batch-user-id", Email: "batch@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	other := models.User{ID: "batch-other-id", Email: "batch-other@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	server.DB.Create(&user)
+	server.DB.Create(&other)
+	token, _ := server.Auth.GenerateJWT(&user)
+
+	var ids []string
+	for i := range 4 {
+		list, _ := server.Lists.CreateList(user.ID, fmt.Sprintf("List %d", i))
+		ids = append(ids, list.ID)
+		for j := range i {
+			server.DB.Create(&models.ShoppingItem{ID: fmt.Sprintf("batch-item-%d-%d", i, j), ListID: list.ID, Name: "Milk", Tags: "[]", CreatedAt: time.Now()})
+		}
+	}
+	foreign, _ := server.Lists.CreateList(other.ID, "Foreign")
+
+	queries := 0
+	countQueries := func(*gorm.DB) { queries++ }
+	server.DB.Callback().Query().After("gorm:query").Register("test:count_queries", countQueries)
+	server.DB.Callback().Row().After("gorm:row").Register("test:count_rows", countQueries)
+
+	request := func(target string) (*http.Response, int) {
+		queries = 0
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp, queries
+	}
+
+	resp, _ := request("/api/v1/lists/batch?ids=" + ids[2] + "," + foreign.ID + "," + ids[1] + "&include=items")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var batch models.ListBatchResponse
+	json.NewDecoder(resp.Body).Decode(&batch)
+	if len(batch.Lists) != 2 || batch.Lists[0].ID != ids[2] || batch.Lists[1].ID != ids[1] {
+		t.Errorf("Expected the accessible lists in request order, got %+v", batch.Lists)
+	}
+	if len(batch.Missing) != 1 || batch.Missing[0] != foreign.ID {
+		t.Errorf("Expected the foreign list to be missing, got %v", batch.Missing)
+	}
+	if len(batch.Items[ids[2]]) != 2 || len(batch.Items[ids[1]]) != 1 || batch.Items[foreign.ID] != nil {
+		t.Errorf("Unexpected items: %+v", batch.Items)
+	}
+
+	_, few := request("/api/v1/lists/batch?ids=" + ids[0] + "&include=items")
+	_, many := request("/api/v1/lists/batch?ids=" + strings.Join(ids, ",") + "&include=items")
+	if few != many {
+		t.Errorf("Expected a constant number of queries, got %d for one list and %d for four", few, many)
+	}
+
+	resp, _ = request("/api/v1/lists/batch?ids=" + ids[0])
+	batch = models.ListBatchResponse{}
+	json.NewDecoder(resp.Body).Decode(&batch)
+	if len(batch.Lists) != 1 || batch.Items != nil {
+		t.Errorf("Expected the list without items, got %+v", batch)
+	}
+
+	for _, target := range []string{"/api/v1/lists/batch", "/api/v1/lists/batch?ids=" + ids[0] + "&include=members"} {
+		if resp, _ := request(target); resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", target, resp.StatusCode)
+		}
+	}
+}
+
+func TestServer_HAL(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	user := models.User{ID: "hal-user-id", Email: "hal@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	server.DB.Create(&user)
+	token, _ := server.Auth.GenerateJWT(&user)
+	list, _ := server.Lists.CreateList(user.ID, "Groceries")
+	server.DB.Create(&models.ShoppingItem{ID: "hal-item", ListID: list.ID, Name: "Milk", Tags: "[]", CreatedAt: time.Now()})
+
+	request := func(target, accept string) *http.Response {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+
+	resp := request("/api/v1/lists/"+list.ID, "")
+	if resp.Header.Get("Content-Type") != fiber.MIMEApplicationJSON {
+		t.Errorf("Expected plain JSON by default, got %q", resp.Header.Get("Content-Type"))
+	}
+	var plain map[string]any
+	json.NewDecoder(resp.Body).Decode(&plain)
+	if _, ok := plain["_links"]; ok {
+		t.Error("Expected no links in plain JSON")
+	}
+
+	type links map[string]struct {
+		Href string `json:"href"`
+	}
+	resp = request("/api/v1/lists/"+list.ID, "application/hal+json")
+	if resp.StatusCode != fiber.StatusOK || resp.Header.Get("Content-Type") != "application/hal+json" {
+		t.Fatalf("Expected a HAL response, got %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(resp.Header.Get("Vary"), "Accept") {
+		t.Errorf("Expected the response to vary by Accept, got %q", resp.Header.Get("Vary"))
+	}
+	var listResource struct {
+		Name  string `json:"name"`
+		Links links  `json:"_links"`
+	}
+	json.NewDecoder(resp.Body).Decode(&listResource)
+	if listResource.Name != "Groceries" ||
+		listResource.Links["items"].Href != "/api/v1/lists/"+list.ID+"/items" ||
+		listResource.Links["members"].Href != "/api/v1/lists/"+list.ID+"/members" {
+		t.Errorf("Unexpected list resource: %+v", listResource)
+	}
+
+	resp = request("/api/v1/lists/"+list.ID+"/items", "application/hal+json")
+	var itemsResource struct {
+		Embedded struct {
+			Items []struct {
+				Name  string `json:"name"`
+				Links links  `json:"_links"`
+			} `json:"items"`
+		} `json:"_embedded"`
+	}
+	json.NewDecoder(resp.Body).Decode(&itemsResource)
+	if items := itemsResource.Embedded.Items; len(items) != 1 || items[0].Links["list"].Href != "/api/v1/lists/"+list.ID {
+		t.Errorf("Expected the item to link its list, got %+v", items)
+	}
+
+	resp = request("/api/v1/lists/"+list.ID+"/members", "application/hal+json")
+	var membersResource struct {
+		Embedded struct {
+			Members []struct {
+				Links links `json:"_links"`
+			} `json:"members"`
+		} `json:"_embedded"`
+	}
+	json.NewDecoder(resp.Body).Decode(&membersResource)
+	if members := membersResource.Embedded.Members; len(members) != 1 || members[0].Links["self"].Href != "/api/v1/lists/"+list.ID+"/members/"+user.ID {
+		t.Errorf("Unexpected members: %+v", members)
+	}
+}
+
+func TestServer_EncodeResponse(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	user := models.User{ID: "encode-user-id", Email: "encode@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	server.DB.Create(&user)
+	token, _ := server.Auth.GenerateJWT(&user)
+	list, _ := server.Lists.CreateList(user.ID, "Groceries")
+	server.DB.Create(&models.ShoppingItem{ID: "encode-item", ListID: list.ID, Name: "Milk", Tags: "[]", CreatedAt: time.Now()})
+
+	request := func(accept string) (*http.Response, []byte) {
+		req := httptest.NewRequest("GET", "/api/v1/lists/"+list.ID+"/items", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Accept", accept)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return resp, body
+	}
+
+	resp, jsonBody := request("application/json")
+	if resp.Header.Get("Content-Type") != fiber.MIMEApplicationJSON {
+		t.Fatalf("Expected JSON, got %q", resp.Header.Get("Content-Type"))
+	}
+
+	for _, mediaType := range []string{codec.MediaTypeMessagePack, codec.MediaTypeCBOR} {
+		resp, body := request(mediaType)
+		if resp.StatusCode != fiber.StatusOK || resp.Header.Get("Content-Type") != mediaType {
+			t.Fatalf("Expected %s, got %d %q", mediaType, resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		if !strings.Contains(resp.Header.Get("Vary"), "Accept") {
+			t.Errorf("Expected the response to vary by Accept, got %q", resp.Header.Get("Vary"))
+		}
+		expected, _ := codec.FromJSON(mediaType, jsonBody)
+		if !bytes.Equal(body, expected) {
+			t.Errorf("Expected the %s encoding of the JSON response", mediaType)
+		}
+		if len(body) >= len(jsonBody) {
+			t.Errorf("Expected %s to be smaller than JSON, got %d and %d bytes", mediaType, len(body), len(jsonBody))
+		}
+	}
+
+	// Every encoding has its own ETag, so cached copies are only revalidated in their encoding
+	compact := func(accept, etag string) *http.Response {
+		req := httptest.NewRequest("GET", "/api/v1/lists/"+list.ID+"/compact", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Accept", accept)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+	jsonTag := compact("application/json", "").Header.Get("ETag")
+	resp = compact(codec.MediaTypeMessagePack, "")
+	msgpackTag := resp.Header.Get("ETag")
+	if msgpackTag == "" || msgpackTag == jsonTag || !strings.Contains(resp.Header.Get("Vary"), "Accept") {
+		t.Fatalf("Expected an ETag of the encoding varying by Accept, got %q for JSON and %q", jsonTag, msgpackTag)
+	}
+	if resp := compact(codec.MediaTypeMessagePack, msgpackTag); resp.StatusCode != fiber.StatusNotModified {
+		t.Errorf("Expected status 304 for the tag of the encoding, got %d", resp.StatusCode)
+	}
+	if resp := compact(codec.MediaTypeMessagePack, jsonTag); resp.StatusCode != fiber.StatusOK || resp.Header.Get("ETag") != msgpackTag {
+		t.Errorf("Expected the JSON tag not to match MessagePack, got %d %q", resp.StatusCode, resp.Header.Get("ETag"))
+	}
+	if resp := compact("application/json", msgpackTag); resp.StatusCode != fiber.StatusOK {
+		t.Errorf("Expected the MessagePack tag not to match JSON, got %d", resp.StatusCode)
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/lists/"+list.ID+"/items", nil)
+	req.Header.Set("Authorization", "Bearer invalid")
+	req.Header.Set("Accept", codec.MediaTypeMessagePack)
+	resp, _ = app.Test(req)
+	if resp.StatusCode != fiber.StatusUnauthorized || resp.Header.Get("Content-Type") != fiber.MIMEApplicationJSON {
+		t.Errorf("Expected errors before the middleware to stay JSON, got %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+}
+
+func TestServer_ConditionalGet(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	user := models.User{ID: "conditional-user-id", Email: "conditional@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	server.DB.Create(&user)
+	token, _ := server.Auth.GenerateJWT(&user)
+	list, _ := server.Lists.CreateList(user.ID, "Groceries")
+	past := time.Now().Add(-time.Hour)
+	server.DB.Create(&models.ShoppingItem{ID: "conditional-item", ListID: list.ID, Name: "Milk", Tags: "[]", CreatedAt: past, UpdatedAt: past})
+	server.DB.Model(&models.ShoppingList{}).Where("id = ?", list.ID).UpdateColumn("updated_at", past)
+
+	request := func(method, target, ifModifiedSince string) *http.Response {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		if ifModifiedSince != "" {
+			req.Header.Set("If-Modified-Since", ifModifiedSince)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+
+	for _, target := range []string{"/api/v1/lists/" + list.ID, "/api/v1/lists/" + list.ID + "/items"} {
+		resp := request("GET", target, "")
+		lastModified := resp.Header.Get("Last-Modified")
+		if resp.StatusCode != fiber.StatusOK || lastModified != past.UTC().Format(http.TimeFormat) {
+			t.Fatalf("Expected %s to be last modified at %v, got %d %q", target, past, resp.StatusCode, lastModified)
+		}
+		if resp := request("GET", target, lastModified); resp.StatusCode != fiber.StatusNotModified {
+			t.Errorf("Expected 304 for an unchanged %s, got %d", target, resp.StatusCode)
+		}
+		if resp := request("GET", target, past.Add(-time.Minute).UTC().Format(http.TimeFormat)); resp.StatusCode != fiber.StatusOK {
+			t.Errorf("Expected 200 for an outdated copy of %s, got %d", target, resp.StatusCode)
+		}
+	}
+
+	itemsTarget := "/api/v1/lists/" + list.ID + "/items"
+	since := past.UTC().Format(http.TimeFormat)
+	if resp := request("DELETE", itemsTarget+"/conditional-item", ""); resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("Expected item to be deleted, got %d", resp.StatusCode)
+	}
+	if resp := request("GET", itemsTarget, since); resp.StatusCode != fiber.StatusOK {
+		t.Errorf("Expected deleting an item to modify the items, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_ItemPoll(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	owner := models.User{ID: "poll-owner-id", Email: "poll-owner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	member := models.User{ID: "poll-member-id", Email: "poll-member@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	outsider := models.User{ID: "poll-outsider-id", Email: "poll-outsider@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	for _, user := range []*models.User{&owner, &member, &outsider} {
+		server.DB.Create(user)
+	}
+	list, _ := server.Lists.CreateList(owner.ID, "Groceries")
+	server.DB.Create(&models.ListMember{ListID: list.ID, UserID: member.ID, Role: "member", JoinedAt: time.Now()})
+	server.DB.Create(&models.ShoppingItem{ID: "poll-item", ListID: list.ID, Name: "Peanut butter", Tags: "[]", CreatedAt: time.Now()})
+
+	request := func(user *models.User, method, target string, body any) *http.Response {
+		token, _ := server.Auth.GenerateJWT(user)
+		reqBody, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, "/api/v1/lists/"+list.ID+"/items/poll-item"+target, bytes.NewReader(reqBody))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+
+	if resp := request(&owner, "GET", "/poll", nil); resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("Expected 404 without a poll, got %d", resp.StatusCode)
+	}
+	if resp := request(&owner, "PUT", "/poll", fiber.Map{"question": "Which one?", "options": []string{"Crunchy"}}); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected 400 for a single option, got %d", resp.StatusCode)
+	}
+	if resp := request(&owner, "PUT", "/poll", fiber.Map{"question": "Which one?", "options": []string{"Crunchy", "Smooth"}}); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected poll to be created, got %d", resp.StatusCode)
+	}
+
+	if resp := request(&outsider, "POST", "/poll/vote", fiber.Map{"option": 0}); resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("Expected outsiders not to vote, got %d", resp.StatusCode)
+	}
+	if resp := request(&member, "POST", "/poll/vote", fiber.Map{}); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected 400 without an option, got %d", resp.StatusCode)
+	}
+	if resp := request(&member, "POST", "/poll/vote", fiber.Map{"option": 5}); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown option, got %d", resp.StatusCode)
+	}
+	request(&owner, "POST", "/poll/vote", fiber.Map{"option": 0})
+	resp := request(&member, "POST", "/poll/vote", fiber.Map{"option": 0})
+	var poll models.ItemPoll
+	json.NewDecoder(resp.Body).Decode(&poll)
+	if resp.StatusCode != fiber.StatusOK || len(poll.Tally) != 2 || poll.Tally[0] != 2 {
+		t.Errorf("Expected two votes for the first option, got %d %v", resp.StatusCode, poll.Tally)
+	}
+
+	if resp := request(&member, "DELETE", "", nil); resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("Expected item to be deleted, got %d", resp.StatusCode)
+	}
+	var polls, votes int64
+	server.DB.Model(&models.ItemPoll{}).Count(&polls)
+	server.DB.Model(&models.PollVote{}).Count(&votes)
+	if polls != 1 || votes != 2 {
+		t.Errorf("Expected the poll to stay with its item in the trash, got %d polls and %d votes", polls, votes)
+	}
+}
+
+func TestServer_Realtime(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	owner, err := server.Setup.SetupSystem("owner@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+	token, _ := server.Auth.GenerateJWT(owner)
+
+	list, err := server.Lists.CreateList(owner.ID, "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/ws", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusUpgradeRequired {
+		t.Errorf("Expected status 426 without upgrade, got %d", resp.StatusCode)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go func() { _ = app.Listener(listener) }()
+	t.Cleanup(func() { _ = app.Shutdown() })
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	// Browsers pass the token as query parameter
+	fmt.Fprintf(conn, "GET /api/v1/ws?token=%s HTTP/1.1\r\nHost: localhost\r\nConnection: Upgrade\r\n"+
+		"Upgrade: websocket\r\nSec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n", token)
+	reader := bufio.NewReader(conn)
+	resp, err = http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("Failed to read handshake: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != realtime.AcceptKey("dGhlIHNhbXBsZSBub25jZQ==") {
+		t.Fatalf("Expected WebSocket handshake, got %d %v", resp.StatusCode, resp.Header)
+	}
+	for server.Realtime.Connections() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	req = httptest.NewRequest("POST", "/api/v1/lists/"+list.ID+"/items", strings.NewReader(`{"name":"Milk"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	if resp, err := app.Test(req); err != nil || resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+
+	var head [2]byte
+	if _, err := io.ReadFull(reader, head[:]); err != nil {
+		t.Fatalf("Failed to read event: %v", err)
+	}
+	payload := make([]byte, head[1]&0x7f)
+	if head[1]&0x7f == 126 {
+		var length [2]byte
+		_, _ = io.ReadFull(reader, length[:])
+		payload = make([]byte, int(length[0])<<8|int(length[1]))
+	}
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		t.Fatalf("Failed to read event: %v", err)
+	}
+	var event struct {
+		Event  string              `json:"event"`
+		ListID string              `json:"list_id"`
+		Data   models.ShoppingItem `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		t.Fatalf("Failed to parse event %q: %v", payload, err)
+	}
+	if event.Event != realtime.EventItemCreated || event.ListID != list.ID || event.Data.Name != "Milk" {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+}
+
+func TestServer_ListEvents(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	owner, err := server.Setup.SetupSystem("owner@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+	token, _ := server.Auth.GenerateJWT(owner)
+	stranger := models.User{ID: "stranger-id", Email: "stranger@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	if err := server.DB.Create(&stranger).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	strangerToken, _ := server.Auth.GenerateJWT(&stranger)
+
+	list, err := server.Lists.CreateList(owner.ID, "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	other, err := server.Lists.CreateList(owner.ID, "Hardware")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/lists/"+list.ID+"/events?token="+strangerToken, nil)
+	if resp, err := app.Test(req); err != nil || resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("Expected status 403 for non-members, got %v, %v", resp, err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go func() { _ = app.Listener(listener) }()
+	t.Cleanup(func() { _ = app.Shutdown() })
+
+	// EventSource cannot send headers either, so the token is passed as query parameter
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get("http://" + listener.Addr().String() + "/api/v1/lists/" + list.ID + "/events?token=" + token)
+	if err != nil {
+		t.Fatalf("Failed to open event stream: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != fiber.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %d %v", resp.StatusCode, resp.Header)
+	}
+	reader := bufio.NewReader(resp.Body)
+	if line, err := reader.ReadString('\n'); err != nil || line != "retry: 5000\n" {
+		t.Fatalf("Expected the retry interval first, got %q, %v", line, err)
+	}
+	for server.Realtime.Connections() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	for _, listID := range []string{other.ID, list.ID} {
+		req := httptest.NewRequest("POST", "/api/v1/lists/"+listID+"/items", strings.NewReader(`{"name":"Milk"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		if resp, err := app.Test(req); err != nil || resp.StatusCode != fiber.StatusCreated {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+
+	var data string
+	for !strings.HasPrefix(data, "data: ") {
+		if data, err = reader.ReadString('\n'); err != nil {
+			t.Fatalf("Failed to read event: %v", err)
+		}
+	}
+	var event struct {
+		Event  string              `json:"event"`
+		ListID string              `json:"list_id"`
+		Data   models.ShoppingItem `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(data, "data: ")), &event); err != nil {
+		t.Fatalf("Failed to parse event %q: %v", data, err)
+	}
+	if event.Event != realtime.EventItemCreated || event.ListID != list.ID || event.Data.Name != "Milk" {
+		t.Errorf("Expected only the event of the streamed list, got %+v", event)
+	}
+}
+
+func TestServer_AllergenFilter(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	owner, err := server.Setup.SetupSystem("owner@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+	token, _ := server.Auth.GenerateJWT(owner)
+
+	list, err := server.Lists.CreateList(owner.ID, "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	request := func(method, url, body string) *http.Response {
+		t.Helper()
+
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+	itemsURL := "/api/v1/lists/" + list.ID + "/items"
+
+	resp := request("POST", itemsURL, `{"name":"Oats","barcode":"not-a-code"}`)
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid barcode, got %d", resp.StatusCode)
+	}
+	resp = request("POST", itemsURL, `{"name":"Oats","barcode":"4000417025005"}`)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+	}
+	var oats models.ShoppingItem
+	if err := json.NewDecoder(resp.Body).Decode(&oats); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if oats.Barcode != "4000417025005" || oats.Allergens != nil {
+		t.Errorf("Expected the barcode without allergens yet, got %q %v", oats.Barcode, oats.Allergens)
+	}
+
+	// Set the facts the lookup would find
+	server.DB.Model(&models.ShoppingItem{}).Where("id = ?", oats.ID).Update("allergens", `["gluten"]`)
+	for _, item := range []models.ShoppingItem{
+		{ID: "milk", ListID: list.ID, Name: "Milk", Allergens: []string{"milk"}},
+		{ID: "apples", ListID: list.ID, Name: "Apples", Allergens: []string{}},
+	} {
+		if err := server.DB.Create(&item).Error; err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+
+	for query, expected := range map[string][]string{
+		"":                         {"Apples", "Milk", "Oats"},
+		"?allergen=gluten":         {"Oats"},
+		"?allergen=Milk,%20gluten": {"Milk", "Oats"},
+		"?allergen=peanuts":        {},
+	} {
+		resp := request("GET", itemsURL+query, "")
+		var items []models.ShoppingItem
+		if err := json.NewDecoder(resp.Body).Decode(&items); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		names := []string{}
+		for _, item := range items {
+			names = append(names, item.Name)
+		}
+		slices.Sort(names)
+		if !slices.Equal(names, expected) {
+			t.Errorf("Expected %v for %q, got %v", expected, query, names)
+		}
+	}
+}
+
+func TestServer_VerifyLoginLink(t *testing.T) {
+	server, app := setupTestServer(t)
+	server.Auth.LoginLinkURL = "https://app.example/login"
+
+	owner, err := server.Setup.SetupSystem("owner@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+	if _, err := server.Invitations.CreateInvitation(owner.ID, "new@example.com", "server", nil); err != nil {
+		t.Fatalf("Failed to create invitation: %v", err)
+	}
+
+	verify := func(token string) *http.Response {
+		t.Helper()
+
+		req := httptest.NewRequest("GET", "/api/v1/auth/verify-link?token="+url.QueryEscape(token), nil)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+
+	if resp := verify(""); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 without token, got %d", resp.StatusCode)
+	}
+	if resp := verify("forged.token"); resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("Expected status 401 for a forged token, got %d", resp.StatusCode)
+	}
+
+	token, err := server.Auth.CreateLoginLink("new@example.com")
+	if err != nil {
+		t.Fatalf("Failed to create login link: %v", err)
+	}
+	resp := verify(token)
+	if resp.StatusCode != fiber.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("Expected status 200, got %d. Body: %s", resp.StatusCode, string(body))
+	}
+	var response models.LoginResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if response.Token == "" || response.User.Email != "new@example.com" || response.DefaultListID == "" {
+		t.Errorf("Expected the invited user to be onboarded, got %+v", response)
+	}
+
+	if resp := verify(token); resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("Expected status 401 for a used link, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_LogoutAll(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	user, err := server.Setup.SetupSystem("owner@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+	phone, _ := server.Auth.GenerateJWT(user)
+	laptop, _ := server.Auth.GenerateJWT(user)
+
+	request := func(token, method, target string) *http.Response {
+		t.Helper()
+
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+
+	if resp := request(phone, "POST", "/api/v1/auth/logout-all"); resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", resp.StatusCode)
+	}
+	for _, token := range []string{phone, laptop} {
+		if resp := request(token, "GET", "/api/v1/lists"); resp.StatusCode != fiber.StatusUnauthorized {
+			t.Errorf("Expected status 401 after logging out everywhere, got %d", resp.StatusCode)
+		}
+	}
+}
+
+func TestServer_ReadReplica(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	user, err := server.Setup.SetupSystem("owner@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+	token, _ := server.Auth.GenerateJWT(user)
+	list, err := server.Lists.CreateList(user.ID, "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	// The replica is a copy of the primary that does not follow it, so stale reads show
+	replicaDB, err := db.Init(filepath.Join(t.TempDir(), "replica.db"))
+	if err != nil {
+		t.Fatalf("Failed to initialize replica: %v", err)
+	}
+	if _, err := db.Copy(server.DB, replicaDB); err != nil {
+		t.Fatalf("Failed to copy primary: %v", err)
+	}
+	replica := &db.ReplicaPlugin{Replica: replicaDB, PinDuration: db.DefaultReplicaPin}
+	if err := server.DB.Use(replica); err != nil {
+		t.Fatalf("Failed to register replica: %v", err)
+	}
+	server.Replica = replica
+
+	request := func(method, target, body string) *http.Response {
+		t.Helper()
+
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+	count := func(target string) int {
+		t.Helper()
+
+		var entries []json.RawMessage
+		if err := json.NewDecoder(request("GET", target, "").Body).Decode(&entries); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		return len(entries)
+	}
+	itemsURL := "/api/v1/lists/" + list.ID + "/items"
+	lists := count("/api/v1/lists")
+
+	// Changes made on the primary behind the user's back are not on the replica yet
+	milk := models.ShoppingItem{ID: "milk", ListID: list.ID, Name: "Milk", Tags: "[]"}
+	if err := server.DB.Create(&milk).Error; err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+	if _, err := server.Lists.CreateList(user.ID, "Hardware"); err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	for target, expected := range map[string]int{
+		itemsURL:                                 0,
+		"/api/v1/lists":                          lists,
+		"/api/v1/lists/" + list.ID + "/sections": 0,
+	} {
+		if got := count(target); got != expected {
+			t.Errorf("%s: expected %d entries from the replica, got %d", target, expected, got)
+		}
+	}
+
+	// Permission checks see the primary, e.g. lists the replica does not know yet
+	var other models.ShoppingList
+	server.DB.Where("name = ?", "Hardware").First(&other)
+	if resp := request("GET", "/api/v1/lists/"+other.ID+"/items", ""); resp.StatusCode != fiber.StatusOK {
+		t.Errorf("Expected access to be checked on the primary, got %d", resp.StatusCode)
+	}
+
+	// Users read their own changes from the primary
+	if resp := request("POST", itemsURL, `{"name":"Bread"}`); resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+	}
+	if got := count(itemsURL); got != 2 {
+		t.Errorf("Expected reads from the primary after a write, got %d items", got)
+	}
+	if got := count("/api/v1/lists"); got != lists+1 {
+		t.Errorf("Expected lists from the primary after a write, got %d", got)
+	}
+}
+
+func TestServer_ServerBundle(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	admin, err := server.Setup.SetupSystem("admin@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+	adminToken, _ := server.Auth.GenerateJWT(admin)
+
+	request := func(method, target, body string) *http.Response {
+		t.Helper()
+
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+
+	request("POST", "/api/v1/admin/catalog/mappings", `{"keyword": "oat drink", "category": "Dairy"}`)
+	resp := request("GET", "/api/v1/admin/bundle", "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	exported, _ := io.ReadAll(resp.Body)
+	var bundle models.ServerBundle
+	if err := json.Unmarshal(exported, &bundle); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if len(bundle.CategoryMappings) != 1 || bundle.CategoryMappings[0].Keyword != "oat drink" {
+		t.Errorf("Expected the mapping in the bundle, got %+v", bundle.CategoryMappings)
+	}
+
+	if resp := request("POST", "/api/v1/admin/bundle", `{"format": 1, "feature_flags": [{"key": "beta", "rollout": 200}]}`); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid rollout, got %d", resp.StatusCode)
+	}
+	resp = request("POST", "/api/v1/admin/bundle", string(exported))
+	if resp.StatusCode != fiber.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("Expected status 200, got %d. Body: %s", resp.StatusCode, string(body))
+	}
+	var result models.ServerBundleImport
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if result.CategoryMappings != 1 || result.Settings == nil || result.DryRun {
+		t.Errorf("Unexpected import result: %+v", result)
+	}
+
+	// Dry runs report the import and roll it back
+	preview := `{"format": 1, "category_mappings": [{"keyword": "kombucha", "category": "Beverages"}], "feature_flags": [{"key": "beta", "rollout": 50}]}`
+	resp = request("POST", "/api/v1/admin/bundle?dry_run=true", preview)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	result = models.ServerBundleImport{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if !result.DryRun || result.CategoryMappings != 1 || result.FeatureFlags != 1 {
+		t.Errorf("Unexpected preview: %+v", result)
+	}
+	if flags, _ := server.Flags.List(); len(flags) != 0 {
+		t.Errorf("Expected the flag to be rolled back, got %+v", flags)
+	}
+	if _, ok := server.Catalog.Dictionary.Lookup("kombucha"); ok {
+		t.Error("Expected the mapping not to reach the dictionary")
+	}
+	entries, _ := server.Moderation.AuditLog(0)
+	if len(entries) != 2 || entries[0].Action != moderation.ActionImportServerBundle || !entries[0].DryRun {
+		t.Errorf("Expected the import and its dry run in the audit log, got %+v", entries)
+	}
+}
+
+func TestServer_GetListFairness(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	owner, err := server.Setup.SetupSystem("owner@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+	token, _ := server.Auth.GenerateJWT(owner)
+
+	list, err := server.Lists.CreateList(owner.ID, "Household")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	item := models.ShoppingItem{ID: "fairness-item-id", ListID: list.ID, Name: "Milk", Tags: "[]", CreatedBy: &owner.ID}
+	if err := server.DB.Create(&item).Error; err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+
+	request := func(method, url string) *http.Response {
+		t.Helper()
+
+		req := httptest.NewRequest(method, url, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+
+	request("POST", "/api/v1/lists/"+list.ID+"/items/"+item.ID+"/toggle")
+	var toggled models.ShoppingItem
+	server.DB.First(&toggled, "id = ?", item.ID)
+	if toggled.CompletedBy == nil || *toggled.CompletedBy != owner.ID {
+		t.Errorf("Expected the item to be checked off by the owner, got %v", toggled.CompletedBy)
+	}
+
+	resp := request("GET", "/api/v1/lists/"+list.ID+"/stats/fairness")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var report models.FairnessReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if report.Days != 90 || len(report.Members) != 1 {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+	if member := report.Members[0]; member.ItemsAdded != 1 || member.ItemsCompleted != 1 || member.Trips != 1 || member.CompletedShare != 100 {
+		t.Errorf("Unexpected contribution: %+v", member)
+	}
+
+	if resp := request("GET", "/api/v1/lists/"+list.ID+"/stats/fairness?days=1000"); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 for too many days, got %d", resp.StatusCode)
+	}
+
+	request("POST", "/api/v1/lists/"+list.ID+"/items/"+item.ID+"/toggle")
+	server.DB.First(&toggled, "id = ?", item.ID)
+	if toggled.CompletedBy != nil {
+		t.Errorf("Expected unchecking to clear completed_by, got %v", *toggled.CompletedBy)
+	}
+}
+
+func TestServer_SpendReport(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	owner, err := server.Setup.SetupSystem("owner@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+	token, _ := server.Auth.GenerateJWT(owner)
+
+	list, err := server.Lists.CreateList(owner.ID, "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	price := 12.5
+	item := models.ShoppingItem{ID: "spend-item-id", ListID: list.ID, Name: "Coffee", Category: "Pantry", Price: &price}
+	if err := server.DB.Create(&item).Error; err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+
+	request := func(method, url, body string) *http.Response {
+		t.Helper()
+
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+	report := func() models.SpendReport {
+		t.Helper()
+
+		resp := request("GET", "/api/v1/reports/spend?list_id="+list.ID, "")
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		var report models.SpendReport
+		if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		return report
+	}
+
+	if resp := request("PUT", "/api/v1/lists/"+list.ID, `{"name":"Groceries","monthly_budget":-1}`); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 for a negative budget, got %d", resp.StatusCode)
+	}
+	resp := request("PUT", "/api/v1/lists/"+list.ID, `{"name":"Groceries","monthly_budget":10}`)
+	var updated models.ShoppingList
+	if err := json.NewDecoder(resp.Body).Decode(&updated); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if updated.MonthlyBudget == nil || *updated.MonthlyBudget != 10 {
+		t.Errorf("Expected the budget to be set, got %v", updated.MonthlyBudget)
+	}
+
+	request("POST", "/api/v1/lists/"+list.ID+"/items/"+item.ID+"/toggle", "")
+	spend := report()
+	if spend.Total != 12.5 || !spend.OverBudget || len(spend.Categories) != 1 || spend.Categories[0].Category != "Pantry" {
+		t.Errorf("Expected the coffee to exceed the budget, got %+v", spend)
+	}
+
+	request("POST", "/api/v1/lists/"+list.ID+"/items/"+item.ID+"/toggle", "")
+	if spend := report(); spend.Total != 0 || spend.OverBudget {
+		t.Errorf("Expected unchecking to remove the purchase, got %+v", spend)
+	}
+
+	if resp := request("GET", "/api/v1/reports/spend?month=2025-13", ""); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid month, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_ListCurrency(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	owner, err := server.Setup.SetupSystem("owner@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+	token, _ := server.Auth.GenerateJWT(owner)
+
+	list, err := server.Lists.CreateList(owner.ID, "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	request := func(method, url, body string) *http.Response {
+		t.Helper()
+
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+
+	var capabilities models.CapabilitiesResponse
+	if err := json.NewDecoder(request("GET", "/api/v1/capabilities", "").Body).Decode(&capabilities); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if capabilities.Currency != "EUR" {
+		t.Errorf("Expected the default currency EUR, got %q", capabilities.Currency)
+	}
+
+	resp := request("PUT", "/api/v1/lists/"+list.ID, `{"name":"Groceries","currency":"euro"}`)
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("Expected status 400 for an invalid currency, got %d", resp.StatusCode)
+	}
+	var validationError struct {
+		Codes map[string]struct {
+			Code string `json:"code"`
+		} `json:"codes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&validationError); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if validationError.Codes["currency"].Code != "currency" {
+		t.Errorf("Expected the currency code error, got %+v", validationError.Codes)
+	}
+
+	resp = request("PUT", "/api/v1/lists/"+list.ID, `{"name":"Groceries","currency":"chf"}`)
+	var updated models.ShoppingList
+	if err := json.NewDecoder(resp.Body).Decode(&updated); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if updated.Currency != "CHF" {
+		t.Errorf("Expected the normalized currency, got %q", updated.Currency)
+	}
+
+	// The owner's default list is still in euros
+	var report models.SpendReport
+	if err := json.NewDecoder(request("GET", "/api/v1/reports/spend", "").Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if report.Currency != "" || len(report.Lists) != 2 {
+		t.Errorf("Expected no common currency of the lists, got %+v", report)
+	}
+	report = models.SpendReport{}
+	if err := json.NewDecoder(request("GET", "/api/v1/reports/spend?list_id="+list.ID, "").Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if report.Currency != "CHF" || len(report.Lists) != 1 || report.Lists[0].Currency != "CHF" {
+		t.Errorf("Expected the report in the list's currency, got %+v", report)
+	}
+
+	resp = request("PUT", "/api/v1/lists/"+list.ID, `{"name":"Groceries","currency":""}`)
+	updated = models.ShoppingList{}
+	if err := json.NewDecoder(resp.Body).Decode(&updated); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if updated.Currency != "" {
+		t.Errorf("Expected the list to use the default currency again, got %q", updated.Currency)
+	}
+}
+
+func TestServer_ItemHistory(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	owner, err := server.Setup.SetupSystem("owner@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+	token, _ := server.Auth.GenerateJWT(owner)
+
+	list, err := server.Lists.CreateList(owner.ID, "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	foreign := models.ShoppingList{ID: "foreign-list", Name: "Foreign", OwnerID: "someone-else"}
+	if err := server.DB.Create(&foreign).Error; err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	request := func(url string) *http.Response {
+		t.Helper()
+
+		req := httptest.NewRequest("GET", url, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+
+	req := httptest.NewRequest("POST", "/api/v1/lists/"+list.ID+"/items", strings.NewReader(`{"name":"Detergent"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil || resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+	var item models.ShoppingItem
+	if err := json.NewDecoder(resp.Body).Decode(&item); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	req = httptest.NewRequest("POST", "/api/v1/lists/"+list.ID+"/items/"+item.ID+"/toggle", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	if resp, err := app.Test(req); err != nil || resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Failed to toggle item: %v", err)
+	}
+
+	resp = request("/api/v1/items/history?q=deterg&from=" + time.Now().AddDate(0, 0, -7).Format(items.DateLayout))
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var history models.ItemHistory
+	if err := json.NewDecoder(resp.Body).Decode(&history); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if history.Total != 1 || history.Items[0].ID != item.ID || history.Items[0].ListName != "Groceries" || history.LastCompletedAt == nil {
+		t.Errorf("Expected the checked off detergent, got %+v", history)
+	}
+
+	for url, status := range map[string]int{
+		"/api/v1/items/history?to=yesterday":          fiber.StatusBadRequest,
+		"/api/v1/items/history?list_id=" + foreign.ID: fiber.StatusForbidden,
+	} {
+		if resp := request(url); resp.StatusCode != status {
+			t.Errorf("Expected status %d for %s, got %d", status, url, resp.StatusCode)
+		}
+	}
+}
+
+func TestServer_ReplenishSuggestions(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	owner, err := server.Setup.SetupSystem("owner@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+	token, _ := server.Auth.GenerateJWT(owner)
+
+	list, err := server.Lists.CreateList(owner.ID, "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	now := time.Now()
+	for _, purchase := range []struct {
+		name    string
+		daysAgo int
+	}{{"Milk", 30}, {"Milk", 20}, {"Milk", 10}, {"Coffee", 50}, {"Coffee", 35}, {"Coffee", 20}} {
+		completedAt := now.AddDate(0, 0, -purchase.daysAgo)
+		item := models.ShoppingItem{
+			ID:          purchase.name + "-" + strconv.Itoa(purchase.daysAgo),
+			ListID:      list.ID,
+			Name:        purchase.name,
+			Tags:        "[]",
+			Completed:   true,
+			CompletedAt: &completedAt,
+		}
+		if err := server.DB.Create(&item).Error; err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+	if _, err := server.Replenish.AnalyzeList(list.ID, now); err != nil {
+		t.Fatalf("Failed to analyze list: %v", err)
+	}
+
+	request := func(method, url, body string) *http.Response {
+		t.Helper()
+
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+	suggestions := func() []models.ReplenishSuggestion {
+		t.Helper()
+
+		resp := request("GET", "/api/v1/suggestions/replenish?list_id="+list.ID, "")
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		var suggestions []models.ReplenishSuggestion
+		if err := json.NewDecoder(resp.Body).Decode(&suggestions); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		return suggestions
+	}
+
+	found := suggestions()
+	if len(found) != 2 || found[0].Name != "Coffee" || found[1].Name != "Milk" {
+		t.Fatalf("Expected coffee and milk, got %+v", found)
+	}
+
+	// Accepting adds the item to the list, which ends the suggestion
+	resp := request("POST", "/api/v1/suggestions/replenish/"+found[1].ID+"/accept", "")
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+	}
+	var item models.ShoppingItem
+	if err := json.NewDecoder(resp.Body).Decode(&item); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if item.Name != "Milk" || item.ListID != list.ID || item.Completed {
+		t.Errorf("Expected an open milk item on the list, got %+v", item)
+	}
+
+	if resp := request("POST", "/api/v1/suggestions/replenish/"+found[0].ID+"/dismiss", ""); resp.StatusCode != fiber.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", resp.StatusCode)
+	}
+	if found := suggestions(); len(found) != 0 {
+		t.Errorf("Expected no suggestions after the feedback, got %+v", found)
+	}
+	if resp := request("POST", "/api/v1/suggestions/replenish/"+found[0].ID+"/dismiss", ""); resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("Expected status 404 for a closed suggestion, got %d", resp.StatusCode)
+	}
+	if resp := request("GET", "/api/v1/suggestions/replenish?list_id=foreign", ""); resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("Expected status 403 for a foreign list, got %d", resp.StatusCode)
+	}
+
+	if resp := request("PUT", "/api/v1/suggestions/replenish/settings", `{"weekly_email": true}`); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var settings models.ReplenishSettings
+	if err := json.NewDecoder(request("GET", "/api/v1/suggestions/replenish/settings", "").Body).Decode(&settings); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if !settings.WeeklyEmail {
+		t.Error("Expected the weekly email to be on")
+	}
+}
+
+func TestServer_ListItemsPagination(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	owner, err := server.Setup.SetupSystem("owner@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+	token, _ := server.Auth.GenerateJWT(owner)
+
+	list, err := server.Lists.CreateList(owner.ID, "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	created := time.Now().Add(-time.Hour)
+	for i, item := range []models.ShoppingItem{
+		{ID: "milk", Name: "Milk", Tags: `["dairy"]`},
+		{ID: "butter", Name: "Butter", Tags: `["dairy"]`, Completed: true},
+		{ID: "oat-milk", Name: "Oat Milk", Tags: `["vegan"]`},
+		{ID: "bread", Name: "Bread"},
+		{ID: "apples", Name: "Apples", Completed: true},
+	} {
+		item.ListID = list.ID
+		item.CreatedAt = created.Add(time.Duration(i) * time.Minute)
+		if err := server.DB.Create(&item).Error; err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+
+	request := func(query string) *http.Response {
+		t.Helper()
+
+		req := httptest.NewRequest("GET", "/api/v1/lists/"+list.ID+"/items"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		return resp
+	}
+	names := func(items []models.ShoppingItem) []string {
+		names := []string{}
+		for _, item := range items {
+			names = append(names, item.Name)
+		}
+		return names
+	}
+
+	// Filters without limit or offset keep the plain array, newest first
+	for query, expected := range map[string][]string{
+		"?completed=false":       {"Bread", "Oat Milk", "Milk"},
+		"?completed=true":        {"Apples", "Butter"},
+		"?tag=dairy":             {"Butter", "Milk"},
+		"?tag=dairy,vegan":       {"Oat Milk", "Butter", "Milk"},
+		"?q=MILK":                {"Oat Milk", "Milk"},
+		"?q=milk&completed=true": {},
+	} {
+		var items []models.ShoppingItem
+		if err := json.NewDecoder(request(query).Body).Decode(&items); err != nil {
+			t.Fatalf("Failed to parse JSON response for %q: %v", query, err)
+		}
+		if !slices.Equal(names(items), expected) {
+			t.Errorf("Expected %v for %q, got %v", expected, query, names(items))
+		}
+	}
+
+	var page models.ItemPage
+	if err := json.NewDecoder(request("?limit=2").Body).Decode(&page); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if page.Total != 5 || page.Limit != 2 || page.Offset != 0 || page.NextOffset == nil || *page.NextOffset != 2 ||
+		!slices.Equal(names(page.Items), []string{"Apples", "Bread"}) {
+		t.Errorf("Unexpected first page %+v", page)
+	}
+
+	page = models.ItemPage{}
+	if err := json.NewDecoder(request("?limit=2&offset=4").Body).Decode(&page); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if page.Total != 5 || page.NextOffset != nil || !slices.Equal(names(page.Items), []string{"Milk"}) {
+		t.Errorf("Unexpected last page %+v", page)
+	}
+
+	page = models.ItemPage{}
+	if err := json.NewDecoder(request("?offset=1&completed=false").Body).Decode(&page); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if page.Total != 3 || page.Limit != 200 || !slices.Equal(names(page.Items), []string{"Oat Milk", "Milk"}) {
+		t.Errorf("Expected the filtered page with the default limit, got %+v", page)
+	}
+
+	// Pages are negotiated like every other list response
+	for _, accept := range []string{"application/hal+json", codec.MediaTypeMessagePack} {
+		req := httptest.NewRequest("GET", "/api/v1/lists/"+list.ID+"/items?limit=2", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Accept", accept)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusOK || resp.Header.Get("Content-Type") != accept {
+			t.Errorf("Expected a page as %s, got status %d and %q", accept, resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+	}
+
+	for _, query := range []string{"?limit=0", "?limit=201", "?offset=-1", "?completed=maybe"} {
+		if resp := request(query); resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("Expected status 400 for %q, got %d", query, resp.StatusCode)
+		}
+	}
+}
+
+func TestServer_BatchListItems(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	owner, err := server.Setup.SetupSystem("owner@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+	token, _ := server.Auth.GenerateJWT(owner)
+
+	list, err := server.Lists.CreateList(owner.ID, "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	for _, item := range []models.ShoppingItem{
+		{ID: "11111111-1111-1111-1111-111111111111", Name: "Milk"},
+		{ID: "22222222-2222-2222-2222-222222222222", Name: "Bread"},
+	} {
+		item.ListID = list.ID
+		if err := server.DB.Create(&item).Error; err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+
+	batch := func(body string) (int, models.BatchItemsResponse) {
+		t.Helper()
+
+		req := httptest.NewRequest("POST", "/api/v1/lists/"+list.ID+"/items/batch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		var response models.BatchItemsResponse
+		if resp.StatusCode == fiber.StatusOK || resp.StatusCode == fiber.StatusConflict {
+			if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to parse JSON response: %v", err)
+			}
+		}
+		return resp.StatusCode, response
+	}
+	statuses := func(response models.BatchItemsResponse) []int {
+		statuses := []int{}
+		for _, result := range response.Results {
+			statuses = append(statuses, result.Status)
+		}
+		return statuses
+	}
+
+	// A queue replayed after being offline: the new item is referred to by its client ID
+	const eggs = "33333333-3333-3333-3333-333333333333"
+	queue := `{"operations": [
+		{"op": "create", "item_id": "` + eggs + `", "item": {"name": "Eggs"}},
+		{"op": "toggle", "item_id": "` + eggs + `", "completed": true},
+		{"op": "update", "item_id": "11111111-1111-1111-1111-111111111111", "item": {"name": "Oat Milk"}},
+		{"op": "delete", "item_id": "44444444-4444-4444-4444-444444444444"},
+		{"op": "delete", "item_id": "22222222-2222-2222-2222-222222222222"},
+		{"op": "update", "item_id": "11111111-1111-1111-1111-111111111111"}
+	]}`
+	status, response := batch(queue)
+	if status != fiber.StatusOK || !response.Committed {
+		t.Fatalf("Expected a committed batch, got %d %+v", status, response)
+	}
+	expected := []int{fiber.StatusCreated, fiber.StatusOK, fiber.StatusOK, fiber.StatusNotFound, fiber.StatusNoContent, fiber.StatusBadRequest}
+	if !slices.Equal(statuses(response), expected) {
+		t.Errorf("Expected statuses %v, got %v", expected, statuses(response))
+	}
+	if item := response.Results[1].Item; item == nil || !item.Completed || item.CompletedAt == nil {
+		t.Errorf("Expected the created item to be completed, got %+v", item)
+	}
+	if response.Results[5].Details["item"] == "" {
+		t.Errorf("Expected the missing item to be reported, got %+v", response.Results[5])
+	}
+
+	var items []models.ShoppingItem
+	server.DB.Where("list_id = ?", list.ID).Order("name").Find(&items)
+	if len(items) != 2 || items[0].Name != "Eggs" || !items[0].Completed || items[1].Name != "Oat Milk" {
+		t.Errorf("Unexpected items after the batch: %+v", items)
+	}
+
+	// Replaying the queue does not create the item twice or flip it back
+	_, response = batch(queue)
+	if response.Results[0].Status != fiber.StatusConflict || response.Results[1].Status != fiber.StatusOK ||
+		response.Results[1].Item == nil || !response.Results[1].Item.Completed {
+		t.Errorf("Expected the replayed create to conflict and the toggle to keep the item completed, got %+v", response.Results)
+	}
+
+	// An atomic batch is rolled back as a whole
+	status, response = batch(`{"atomic": true, "operations": [
+		{"op": "create", "item": {"name": "Butter"}},
+		{"op": "toggle", "item_id": "44444444-4444-4444-4444-444444444444"},
+		{"op": "create", "item": {"name": "Cheese"}}
+	]}`)
+	if status != fiber.StatusConflict || response.Committed {
+		t.Errorf("Expected a rolled back batch, got %d %+v", status, response)
+	}
+	if !slices.Equal(statuses(response), []int{fiber.StatusCreated, fiber.StatusNotFound}) {
+		t.Errorf("Expected the batch to stop at the failed operation, got %v", statuses(response))
+	}
+	var count int64
+	server.DB.Model(&models.ShoppingItem{}).Where("list_id = ? AND name = ?", list.ID, "Butter").Count(&count)
+	if count != 0 {
+		t.Error("Expected the created item to be rolled back")
+	}
+
+	if status, _ := batch(`{"operations": []}`); status != fiber.StatusBadRequest {
+		t.Errorf("Expected 400 for an empty batch, got %d", status)
+	}
+	status, response = batch(`{"operations": [{"op": "rename", "item_id": "` + eggs + `"}]}`)
+	if status != fiber.StatusOK || !slices.Equal(statuses(response), []int{fiber.StatusBadRequest}) {
+		t.Errorf("Expected the unknown operation to fail on its own, got %d %+v", status, response)
+	}
+}
@@ -0,0 +1,49 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/openapi"
+	"github.com/oliverandrich/shopping-list-server/internal/version"
+)
+
+// message is the body of responses that only confirm a request.
+type message struct {
+	Message string `json:"message"`
+}
+
+// OpenAPI returns the OpenAPI document of the core routes of the API: logging in, lists, sections
+// and items. The schemas are generated from the models the handlers read and write, and in dev
+// mode, requests and responses are validated against them, see openapi.Middleware.
+func OpenAPI() *openapi.Document {
+	doc := openapi.New("Shopping List API", version.Version)
+	ok := func(model any) map[int]any { return map[int]any{fiber.StatusOK: model} }
+	created := func(model any) map[int]any { return map[int]any{fiber.StatusCreated: model} }
+	noContent := map[int]any{fiber.StatusNoContent: nil}
+
+	doc.Add("GET", "/api/v1/version", nil, ok(models.VersionResponse{}))
+	doc.Add("POST", "/api/v1/auth/login", models.LoginRequest{}, ok(message{}))
+	doc.Add("POST", "/api/v1/auth/verify", models.VerifyRequest{}, ok(models.LoginResponse{}))
+
+	doc.Add("GET", "/api/v1/lists", nil, ok([]models.ShoppingList{}))
+	doc.Add("POST", "/api/v1/lists", models.CreateListRequest{}, created(models.ShoppingList{}))
+	doc.Add("GET", "/api/v1/lists/batch", nil, ok(models.ListBatchResponse{}))
+	doc.Add("GET", "/api/v1/lists/:id", nil, ok(models.ShoppingList{}))
+	doc.Add("PUT", "/api/v1/lists/:id", models.UpdateListRequest{}, ok(models.ShoppingList{}))
+	doc.Add("DELETE", "/api/v1/lists/:id", nil, noContent)
+	doc.Add("GET", "/api/v1/lists/:id/members", nil, ok([]models.User{}))
+
+	doc.Add("GET", "/api/v1/lists/:id/sections", nil, ok([]models.ListSection{}))
+	doc.Add("POST", "/api/v1/lists/:id/sections", models.SectionRequest{}, created(models.ListSection{}))
+
+	doc.Add("GET", "/api/v1/lists/:id/items", nil, ok(openapi.OneOf([]models.ShoppingItem{}, models.ItemPage{})))
+	doc.Add("POST", "/api/v1/lists/:id/items", models.CreateItemRequest{}, created(models.ShoppingItem{}))
+	doc.Add("PUT", "/api/v1/lists/:id/items/:itemId", models.CreateItemRequest{}, ok(models.ShoppingItem{}))
+	doc.Add("POST", "/api/v1/lists/:id/items/:itemId/toggle", nil, ok(models.ShoppingItem{}))
+	doc.Add("DELETE", "/api/v1/lists/:id/items/:itemId", nil, noContent)
+
+	return doc
+}
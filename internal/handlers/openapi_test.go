@@ -0,0 +1,91 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/openapi"
+)
+
+func TestServer_OpenAPI(t *testing.T) {
+	server, app := setupTestServer(t)
+
+	var buf bytes.Buffer
+	previous := slog.Default()
+	defer slog.SetDefault(previous)
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	// Validate everything the routes of the test server answer, as in dev mode
+	checked := fiber.New()
+	checked.Use(openapi.Middleware(OpenAPI()))
+	checked.Mount("/", app)
+
+	user, err := server.Setup.SetupSystem("owner@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+	token, _ := server.Auth.GenerateJWT(user)
+
+	request := func(method, target, body string, expected int) []byte {
+		t.Helper()
+
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := checked.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		if resp.StatusCode != expected {
+			t.Fatalf("%s %s: expected status %d, got %d", method, target, expected, resp.StatusCode)
+		}
+		respBody, _ := io.ReadAll(resp.Body)
+		return respBody
+	}
+	var list models.ShoppingList
+	if err := json.Unmarshal(request("POST", "/api/v1/lists", `{"name":"Groceries"}`, fiber.StatusCreated), &list); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	listURL := "/api/v1/lists/" + list.ID
+	var item models.ShoppingItem
+	if err := json.Unmarshal(request("POST", listURL+"/items", `{"name":"Milk","quantity":2,"unit":"l"}`, fiber.StatusCreated), &item); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+
+	request("GET", "/api/v1/version", "", fiber.StatusOK)
+	request("POST", "/api/v1/auth/login", `{"email":"owner@example.com"}`, fiber.StatusOK)
+	request("POST", "/api/v1/auth/verify", `{"email":"owner@example.com","code":"000000"}`, fiber.StatusUnauthorized)
+	request("GET", "/api/v1/lists", "", fiber.StatusOK)
+	request("GET", "/api/v1/lists/batch?ids="+list.ID+",missing&include=items", "", fiber.StatusOK)
+	request("GET", listURL, "", fiber.StatusOK)
+	request("PUT", listURL, `{"name":"Weekly groceries"}`, fiber.StatusOK)
+	request("GET", listURL+"/members", "", fiber.StatusOK)
+	request("POST", listURL+"/sections", `{"name":"Dairy"}`, fiber.StatusCreated)
+	request("GET", listURL+"/sections", "", fiber.StatusOK)
+	request("GET", listURL+"/items", "", fiber.StatusOK)
+	request("GET", listURL+"/items?limit=1", "", fiber.StatusOK)
+	request("PUT", listURL+"/items/"+item.ID, `{"name":"Oat milk","tags":"[\"vegan\"]"}`, fiber.StatusOK)
+	request("POST", listURL+"/items/"+item.ID+"/toggle", "", fiber.StatusOK)
+	request("DELETE", listURL+"/items/"+item.ID, "", fiber.StatusNoContent)
+	request("DELETE", listURL, "", fiber.StatusNoContent)
+	request("GET", listURL, "", fiber.StatusNotFound)
+	if buf.Len() != 0 {
+		t.Fatalf("Expected the responses to match the API schema, got %s", buf.String())
+	}
+
+	// Drift is logged, not rejected
+	request("POST", "/api/v1/lists", `{"name":"Hardware","colour":"red"}`, fiber.StatusCreated)
+	if !strings.Contains(buf.String(), "request $: unexpected property colour") {
+		t.Errorf("Expected the unknown property to be logged, got %s", buf.String())
+	}
+}
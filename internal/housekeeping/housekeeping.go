@@ -0,0 +1,149 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package housekeeping helps long-time users declutter their account by finding empty lists,
+// lists without activity and stale invitations, and optionally removing them.
+package housekeeping
+
+import (
+	"slices"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/lists"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// DefaultInactiveMonths is used when a request does not specify after how many months without
+// activity a list counts as inactive.
+const DefaultInactiveMonths = 6
+
+// Service finds and removes clutter in a user's account.
+type Service struct {
+	DB *gorm.DB
+}
+
+// NewService creates a new housekeeping service.
+func NewService(db *gorm.DB) *Service {
+	return &Service{DB: db}
+}
+
+// listStats holds the item counts of a list used to classify it.
+type listStats struct {
+	ListID      string
+	ItemCount   int
+	RecentItems int
+}
+
+// Run reports the empty and inactive lists the user owns and the expired invitations they
+// sent. If req.Remove is set, the reported lists and invitations are deleted in one
+// transaction; req.ListIDs restricts the deletion to a subset of the reported lists.
+//
+// A list is inactive when neither the list was changed nor an item was added to it within
+// req.InactiveMonths. Only owned lists are reported, as members cannot delete a list.
+func (s *Service) Run(userID string, req models.HousekeepingRequest) (*models.HousekeepingReport, error) {
+	months := req.InactiveMonths
+	if months == 0 {
+		months = DefaultInactiveMonths
+	}
+	cutoff := time.Now().AddDate(0, -months, 0)
+
+	report := &models.HousekeepingReport{
+		InactiveMonths:   months,
+		EmptyLists:       []models.HousekeepingList{},
+		InactiveLists:    []models.HousekeepingList{},
+		StaleInvitations: []models.Invitation{},
+	}
+
+	var owned []models.ShoppingList
+	if err := s.DB.Where("owner_id = ?", userID).Order("created_at ASC").Find(&owned).Error; err != nil {
+		return nil, err
+	}
+
+	var stats []listStats
+	err := s.DB.Model(&models.ShoppingItem{}).
+		Select("list_id, COUNT(*) AS item_count, COUNT(CASE WHEN created_at > ? THEN 1 END) AS recent_items", cutoff).
+		Where("list_id IN (?)", s.DB.Model(&models.ShoppingList{}).Select("id").Where("owner_id = ?", userID)).
+		Group("list_id").
+		Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+	statsByList := make(map[string]listStats, len(stats))
+	for _, stat := range stats {
+		statsByList[stat.ListID] = stat
+	}
+
+	for _, list := range owned {
+		stat := statsByList[list.ID]
+		entry := models.HousekeepingList{
+			ID:           list.ID,
+			Name:         list.Name,
+			ItemCount:    stat.ItemCount,
+			LastActivity: list.UpdatedAt,
+		}
+
+		switch {
+		case stat.ItemCount == 0:
+			report.EmptyLists = append(report.EmptyLists, entry)
+		case stat.RecentItems == 0 && list.UpdatedAt.Before(cutoff):
+			var latest models.ShoppingItem
+			err := s.DB.Select("created_at").Where("list_id = ?", list.ID).Order("created_at DESC").First(&latest).Error
+			if err != nil {
+				return nil, err
+			}
+			if latest.CreatedAt.After(entry.LastActivity) {
+				entry.LastActivity = latest.CreatedAt
+			}
+			report.InactiveLists = append(report.InactiveLists, entry)
+		}
+	}
+
+	err = s.DB.Preload("Lists").
+		Where("invited_by = ? AND used = false AND expires_at < ?", userID, time.Now()).
+		Order("created_at ASC").
+		Find(&report.StaleInvitations).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Remove {
+		if err := s.remove(userID, report, req.ListIDs); err != nil {
+			return nil, err
+		}
+		report.Removed = true
+	}
+
+	return report, nil
+}
+
+// remove deletes the lists and invitations of the report. If listIDs is not empty, only the
+// reported lists contained in it are deleted and the report is narrowed down accordingly.
+func (s *Service) remove(userID string, report *models.HousekeepingReport, listIDs []string) error {
+	if len(listIDs) > 0 {
+		notSelected := func(list models.HousekeepingList) bool {
+			return !slices.Contains(listIDs, list.ID)
+		}
+		report.EmptyLists = slices.DeleteFunc(report.EmptyLists, notSelected)
+		report.InactiveLists = slices.DeleteFunc(report.InactiveLists, notSelected)
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		listService := lists.NewService(tx)
+		for _, list := range slices.Concat(report.EmptyLists, report.InactiveLists) {
+			if err := listService.DeleteList(list.ID, userID); err != nil {
+				return err
+			}
+		}
+
+		for _, invitation := range report.StaleInvitations {
+			if err := tx.Where("invitation_id = ?", invitation.ID).Delete(&models.InvitationList{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&models.Invitation{}, "id = ?", invitation.ID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
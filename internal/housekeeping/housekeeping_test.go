@@ -0,0 +1,120 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package housekeeping
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/lists"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+	"gorm.io/gorm"
+)
+
+func createList(t *testing.T, db *gorm.DB, name string, updatedAt time.Time, itemCreatedAt ...time.Time) string {
+	t.Helper()
+
+	list, err := lists.NewService(db).CreateList("owner", name)
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	for i, createdAt := range itemCreatedAt {
+		item := models.ShoppingItem{ID: fmt.Sprintf("%s-item-%d", list.ID, i), ListID: list.ID, Name: "Milk", CreatedAt: createdAt}
+		if err := db.Create(&item).Error; err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+	// Creating items touches the list, so it is backdated afterwards
+	if err := db.Model(list).UpdateColumn("updated_at", updatedAt).Error; err != nil {
+		t.Fatalf("Failed to backdate list: %v", err)
+	}
+	return list.ID
+}
+
+func TestService_Run(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+
+	owner := models.User{ID: "owner", Email: "owner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	if err := db.Create(&owner).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	old := time.Now().AddDate(-1, 0, 0)
+	empty := createList(t, db, "Empty", time.Now())
+	inactive := createList(t, db, "Holiday", old, old, old.Add(time.Hour))
+	active := createList(t, db, "Weekly", old, old, time.Now())
+
+	invitations := []models.Invitation{
+		{ID: "expired", Code: "expired", Email: "a@example.com", Type: "list", InvitedBy: "owner", ExpiresAt: time.Now().Add(-time.Hour)},
+		{ID: "pending", Code: "pending", Email: "b@example.com", Type: "list", InvitedBy: "owner", ExpiresAt: time.Now().Add(time.Hour)},
+		{ID: "other", Code: "other", Email: "c@example.com", Type: "list", InvitedBy: "someone", ExpiresAt: time.Now().Add(-time.Hour)},
+	}
+	if err := db.Create(&invitations).Error; err != nil {
+		t.Fatalf("Failed to create invitations: %v", err)
+	}
+
+	t.Run("report only", func(t *testing.T) {
+		report, err := service.Run("owner", models.HousekeepingRequest{})
+		if err != nil {
+			t.Fatalf("Failed to run housekeeping: %v", err)
+		}
+
+		if report.InactiveMonths != DefaultInactiveMonths || report.Removed {
+			t.Errorf("Unexpected report: %+v", report)
+		}
+		if len(report.EmptyLists) != 1 || report.EmptyLists[0].ID != empty {
+			t.Errorf("Expected empty list to be reported, got %+v", report.EmptyLists)
+		}
+		if len(report.InactiveLists) != 1 || report.InactiveLists[0].ID != inactive {
+			t.Fatalf("Expected inactive list to be reported, got %+v", report.InactiveLists)
+		}
+		if report.InactiveLists[0].ItemCount != 2 || !report.InactiveLists[0].LastActivity.After(old) {
+			t.Errorf("Expected latest item to be the last activity, got %+v", report.InactiveLists[0])
+		}
+		if len(report.StaleInvitations) != 1 || report.StaleInvitations[0].ID != "expired" {
+			t.Errorf("Expected expired invitation to be reported, got %+v", report.StaleInvitations)
+		}
+	})
+
+	t.Run("longer inactivity threshold", func(t *testing.T) {
+		report, err := service.Run("owner", models.HousekeepingRequest{InactiveMonths: 24})
+		if err != nil {
+			t.Fatalf("Failed to run housekeeping: %v", err)
+		}
+		if len(report.InactiveLists) != 0 {
+			t.Errorf("Expected no inactive lists, got %+v", report.InactiveLists)
+		}
+	})
+
+	t.Run("remove selected lists", func(t *testing.T) {
+		report, err := service.Run("owner", models.HousekeepingRequest{Remove: true, ListIDs: []string{inactive}})
+		if err != nil {
+			t.Fatalf("Failed to run housekeeping: %v", err)
+		}
+		if !report.Removed || len(report.EmptyLists) != 0 || len(report.InactiveLists) != 1 {
+			t.Errorf("Expected only the selected list to be removed, got %+v", report)
+		}
+
+		var remaining []string
+		db.Model(&models.ShoppingList{}).Order("name").Pluck("id", &remaining)
+		if len(remaining) != 2 || remaining[0] != empty || remaining[1] != active {
+			t.Errorf("Expected empty and active lists to remain, got %v", remaining)
+		}
+
+		var itemCount int64
+		db.Model(&models.ShoppingItem{}).Where("list_id = ?", inactive).Count(&itemCount)
+		if itemCount != 0 {
+			t.Errorf("Expected items of the removed list to be deleted, got %d", itemCount)
+		}
+
+		var invitationIDs []string
+		db.Model(&models.Invitation{}).Order("id").Pluck("id", &invitationIDs)
+		if len(invitationIDs) != 2 || invitationIDs[0] != "other" || invitationIDs[1] != "pending" {
+			t.Errorf("Expected only the stale invitation to be removed, got %v", invitationIDs)
+		}
+	})
+}
@@ -0,0 +1,180 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package imageproxy fetches the product images referenced by items, e.g. set by an enrichment
+// hook, on behalf of clients. Images are downscaled, re-encoded without metadata and cached
+// in the storage backend, so clients never contact third-party servers themselves.
+package imageproxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/attachments"
+	"github.com/oliverandrich/shopping-list-server/internal/storage"
+)
+
+// DefaultMaxDimension is the longest side proxied images are downscaled to.
+const DefaultMaxDimension = 512
+
+// ErrFetchFailed is returned when an image cannot be fetched from its origin or is not a
+// supported image.
+var ErrFetchFailed = errors.New("failed to fetch image")
+
+// trackingParams are query parameters that only identify the visitor or campaign; they are
+// removed before fetching, so the origin cannot tell where the image was shown.
+var trackingParams = []string{"fbclid", "gclid", "dclid", "msclkid", "mc_cid", "mc_eid", "igshid", "_hsenc", "_hsmi"}
+
+// Service proxies and caches images.
+type Service struct {
+	// Storage caches proxied images. Without it, every request fetches from the origin.
+	Storage storage.Storage
+	Client  *http.Client
+	// MaxBytes limits the size of fetched images.
+	MaxBytes int64
+	// MaxDimension is the longest side images are downscaled to.
+	MaxDimension int
+}
+
+// NewService creates a new image proxy that refuses to fetch from loopback and private
+// network addresses.
+func NewService() *Service {
+	return &Service{
+		Client:       NewClient(false),
+		MaxBytes:     10 * 1024 * 1024,
+		MaxDimension: DefaultMaxDimension,
+	}
+}
+
+// NewClient returns the HTTP client the proxy fetches with. Unless allowPrivate is set, it
+// refuses to connect to loopback, private and link-local addresses, so item images cannot be
+// used to reach services on the server's network.
+func NewClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+				ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+				return fmt.Errorf("refusing to connect to %s", host)
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: 15 * time.Second, Transport: transport}
+}
+
+// Get returns the proxied image of imageURL and its content type, from the cache if possible.
+func (s *Service) Get(ctx context.Context, imageURL string) ([]byte, string, error) {
+	cleaned, err := StripTrackers(imageURL)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := cacheKey(cleaned, s.MaxDimension)
+	if s.Storage != nil {
+		if cached, err := s.cached(key); err == nil {
+			return cached, http.DetectContentType(cached), nil
+		}
+	}
+
+	data, err := s.fetch(ctx, cleaned)
+	if err != nil {
+		return nil, "", err
+	}
+	image, contentType, err := attachments.Downscale(data, s.MaxDimension)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrFetchFailed, err)
+	}
+
+	if s.Storage != nil {
+		if err := s.Storage.Put(key, image, contentType); err != nil {
+			slog.Warn("Failed to cache proxied image", "key", key, "error", err)
+		}
+	}
+	return image, contentType, nil
+}
+
+// StripTrackers validates an http(s) image URL and removes tracking query parameters, such as
+// utm_source, and the fragment.
+func StripTrackers(imageURL string) (string, error) {
+	parsed, err := url.Parse(imageURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", fmt.Errorf("%w: invalid image URL", ErrFetchFailed)
+	}
+
+	query := parsed.Query()
+	for name := range query {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "utm_") || slices.Contains(trackingParams, lower) {
+			query.Del(name)
+		}
+	}
+	parsed.RawQuery = query.Encode()
+	parsed.Fragment = ""
+	return parsed.String(), nil
+}
+
+// fetch downloads an image without forwarding anything about the client.
+func (s *Service) fetch(ctx context.Context, imageURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFetchFailed, err)
+	}
+	req.Header.Set("Accept", "image/jpeg, image/png, image/gif")
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFetchFailed, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: origin answered %d", ErrFetchFailed, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, s.MaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFetchFailed, err)
+	}
+	if int64(len(data)) > s.MaxBytes {
+		return nil, fmt.Errorf("%w: image is too large", ErrFetchFailed)
+	}
+	return data, nil
+}
+
+// cached reads a cached image.
+func (s *Service) cached(key string) ([]byte, error) {
+	reader, err := s.Storage.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = reader.Close() }()
+	return io.ReadAll(reader)
+}
+
+// cacheKey derives the storage key of an image from its URL and size.
+func cacheKey(imageURL string, maxDimension int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%s", maxDimension, imageURL)))
+	return "images/" + hex.EncodeToString(sum[:])
+}
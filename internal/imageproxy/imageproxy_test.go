@@ -0,0 +1,95 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package imageproxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/oliverandrich/shopping-list-server/internal/storage"
+)
+
+func TestStripTrackers(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://shop.example.com/milk.png", "https://shop.example.com/milk.png"},
+		{"https://shop.example.com/milk.png?size=large&utm_source=app&UTM_Campaign=x&fbclid=1#top", "https://shop.example.com/milk.png?size=large"},
+	}
+	for _, tt := range tests {
+		got, err := StripTrackers(tt.url)
+		if err != nil || got != tt.want {
+			t.Errorf("StripTrackers(%q) = %q, %v; want %q", tt.url, got, err, tt.want)
+		}
+	}
+
+	for _, invalid := range []string{"file:///etc/passwd", "ftp://example.com/a.png", "https://"} {
+		if _, err := StripTrackers(invalid); !errors.Is(err, ErrFetchFailed) {
+			t.Errorf("Expected %q to be rejected, got %v", invalid, err)
+		}
+	}
+}
+
+func TestService_Get(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1024, 512))); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+
+	fetches := 0
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		if r.URL.Query().Get("utm_source") != "" {
+			t.Error("Expected tracking parameters to be stripped")
+		}
+		if r.URL.Path == "/missing.png" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer origin.Close()
+
+	service := NewService()
+	service.Storage = storage.NewLocal(t.TempDir())
+
+	t.Run("refuses private addresses", func(t *testing.T) {
+		if _, _, err := service.Get(context.Background(), origin.URL+"/milk.png"); !errors.Is(err, ErrFetchFailed) {
+			t.Errorf("Expected fetching from loopback to fail, got %v", err)
+		}
+	})
+
+	service.Client = NewClient(true)
+
+	data, contentType, err := service.Get(context.Background(), origin.URL+"/milk.png?utm_source=app")
+	if err != nil {
+		t.Fatalf("Failed to proxy image: %v", err)
+	}
+	if contentType != "image/png" {
+		t.Errorf("Expected image/png, got %s", contentType)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width != DefaultMaxDimension || config.Height != DefaultMaxDimension/2 {
+		t.Errorf("Expected the image to be downscaled, got %+v (%v)", config, err)
+	}
+
+	// The second request is served from the cache
+	if _, _, err := service.Get(context.Background(), origin.URL+"/milk.png"); err != nil {
+		t.Fatalf("Failed to proxy cached image: %v", err)
+	}
+	if fetches != 1 {
+		t.Errorf("Expected 1 fetch from the origin, got %d", fetches)
+	}
+
+	if _, _, err := service.Get(context.Background(), origin.URL+"/missing.png"); !errors.Is(err, ErrFetchFailed) {
+		t.Errorf("Expected a missing image to fail, got %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package integrations provides the polling triggers of the integration routes for low-code
+// automation platforms such as n8n or Zapier.
+package integrations
+
+import (
+	"encoding/base64"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// DefaultLimit is the maximum number of items returned by a single poll.
+const DefaultLimit = 100
+
+// ErrInvalidCursor is returned when a since cursor cannot be decoded.
+var ErrInvalidCursor = errors.New("invalid since cursor")
+
+// Service answers integration polls.
+type Service struct {
+	DB *gorm.DB
+}
+
+// NewService creates a new integrations service.
+func NewService(db *gorm.DB) *Service {
+	return &Service{DB: db}
+}
+
+// cursor marks the last item a client has seen. Items created at the same time are ordered by ID.
+type cursor struct {
+	CreatedAt time.Time
+	ItemID    string
+}
+
+// encodeCursor returns the opaque since cursor of the item.
+func encodeCursor(c cursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(c.CreatedAt.UnixNano(), 10) + ":" + c.ItemID))
+}
+
+// decodeCursor parses an opaque since cursor.
+func decodeCursor(value string) (cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+	nanos, itemID, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return cursor{}, ErrInvalidCursor
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+	return cursor{CreatedAt: time.Unix(0, n), ItemID: itemID}, nil
+}
+
+// NewItems returns the items added to the user's lists after the since cursor, oldest first,
+// together with the cursor to pass on the next poll. Without a cursor the latest items are
+// returned. An empty listID covers all lists of the user.
+func (s *Service) NewItems(userID, listID, since string, limit int) ([]models.IntegrationItem, string, error) {
+	query := s.DB.Model(&models.ShoppingItem{}).
+		Select("shopping_items.id, shopping_items.list_id, shopping_lists.name AS list_name, shopping_items.name, "+
+			"shopping_items.quantity, shopping_items.unit, shopping_items.category, shopping_items.completed, shopping_items.created_at").
+		Joins("JOIN shopping_lists ON shopping_lists.id = shopping_items.list_id").
+		Joins("JOIN list_members ON list_members.list_id = shopping_items.list_id AND list_members.user_id = ?", userID)
+	if listID != "" {
+		query = query.Where("shopping_items.list_id = ?", listID)
+	}
+
+	var items []models.IntegrationItem
+	if since == "" {
+		// The latest items serve as sample data when setting up a trigger
+		if err := query.Order("shopping_items.created_at DESC, shopping_items.id DESC").Limit(limit).Scan(&items).Error; err != nil {
+			return nil, "", err
+		}
+		slices.Reverse(items)
+	} else {
+		after, err := decodeCursor(since)
+		if err != nil {
+			return nil, "", err
+		}
+		err = query.Where("shopping_items.created_at > ? OR (shopping_items.created_at = ? AND shopping_items.id > ?)",
+			after.CreatedAt, after.CreatedAt, after.ItemID).
+			Order("shopping_items.created_at ASC, shopping_items.id ASC").
+			Limit(limit).
+			Scan(&items).Error
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	if items == nil {
+		items = []models.IntegrationItem{}
+	}
+	if len(items) == 0 {
+		return items, since, nil
+	}
+	last := items[len(items)-1]
+	return items, encodeCursor(cursor{CreatedAt: last.CreatedAt, ItemID: last.ID}), nil
+}
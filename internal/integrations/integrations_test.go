@@ -0,0 +1,107 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package integrations
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/lists"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+)
+
+func TestService_NewItems(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+
+	for _, id := range []string{"user", "stranger"} {
+		user := models.User{ID: id, Email: id + "@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+		if err := db.Create(&user).Error; err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+	groceries, err := lists.NewService(db).CreateList("user", "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	hardware, err := lists.NewService(db).CreateList("user", "Hardware")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	other, err := lists.NewService(db).CreateList("stranger", "Other")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	base := time.Now().Add(-time.Hour)
+	create := func(id, listID string, createdAt time.Time) {
+		t.Helper()
+		item := models.ShoppingItem{ID: id, ListID: listID, Name: id, Tags: "[]", CreatedAt: createdAt}
+		if err := db.Create(&item).Error; err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+	create("a-milk", groceries.ID, base)
+	create("b-nails", hardware.ID, base.Add(time.Minute))
+	create("c-eggs", groceries.ID, base.Add(time.Minute))
+	create("d-secret", other.ID, base.Add(2*time.Minute))
+
+	items, since, err := service.NewItems("user", "", "", DefaultLimit)
+	if err != nil {
+		t.Fatalf("Failed to poll items: %v", err)
+	}
+	if len(items) != 3 || items[0].ID != "a-milk" || items[2].ID != "c-eggs" {
+		t.Fatalf("Expected the user's items oldest first, got %+v", items)
+	}
+	if items[0].ListName != "Groceries" {
+		t.Errorf("Expected list name, got %q", items[0].ListName)
+	}
+
+	t.Run("nothing new", func(t *testing.T) {
+		items, next, err := service.NewItems("user", "", since, DefaultLimit)
+		if err != nil {
+			t.Fatalf("Failed to poll items: %v", err)
+		}
+		if len(items) != 0 || next != since {
+			t.Errorf("Expected no items and the same cursor, got %d items", len(items))
+		}
+	})
+
+	t.Run("pages through items created at the same time", func(t *testing.T) {
+		first, cursor, err := service.NewItems("user", "", "", 2)
+		if err != nil {
+			t.Fatalf("Failed to poll items: %v", err)
+		}
+		if len(first) != 2 || first[1].ID != "c-eggs" {
+			t.Fatalf("Expected the latest 2 items, got %+v", first)
+		}
+
+		create("e-bread", groceries.ID, base.Add(3*time.Minute))
+		next, _, err := service.NewItems("user", "", cursor, 2)
+		if err != nil {
+			t.Fatalf("Failed to poll items: %v", err)
+		}
+		if len(next) != 1 || next[0].ID != "e-bread" {
+			t.Errorf("Expected only the new item, got %+v", next)
+		}
+	})
+
+	t.Run("single list", func(t *testing.T) {
+		items, _, err := service.NewItems("user", hardware.ID, "", DefaultLimit)
+		if err != nil {
+			t.Fatalf("Failed to poll items: %v", err)
+		}
+		if len(items) != 1 || items[0].ID != "b-nails" {
+			t.Errorf("Expected the hardware item, got %+v", items)
+		}
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		if _, _, err := service.NewItems("user", "", "not a cursor", DefaultLimit); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("Expected ErrInvalidCursor, got %v", err)
+		}
+	})
+}
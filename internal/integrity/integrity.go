@@ -0,0 +1,124 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package integrity checks the database for corruption and orphaned rows left behind by
+// interrupted writes, and optionally removes those orphans.
+package integrity
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// orphanCheck describes rows of Table whose Column references a missing row in Parent.
+type orphanCheck struct {
+	Name   string
+	Table  string
+	Column string
+	Parent string
+}
+
+// orphanChecks lists all references checked for orphans. Attachments are excluded because
+// removing them also requires deleting their stored files.
+var orphanChecks = []orphanCheck{
+	{Name: "members_without_list", Table: "list_members", Column: "list_id", Parent: "shopping_lists"},
+	{Name: "members_without_user", Table: "list_members", Column: "user_id", Parent: "users"},
+	{Name: "items_without_list", Table: "shopping_items", Column: "list_id", Parent: "shopping_lists"},
+	{Name: "aliases_without_list", Table: "list_aliases", Column: "list_id", Parent: "shopping_lists"},
+	{Name: "sections_without_list", Table: "list_sections", Column: "list_id", Parent: "shopping_lists"},
+	{Name: "tags_without_list", Table: "list_tags", Column: "list_id", Parent: "shopping_lists"},
+	{Name: "display_tokens_without_list", Table: "display_tokens", Column: "list_id", Parent: "shopping_lists"},
+	{Name: "enrichment_hooks_without_list", Table: "enrichment_hooks", Column: "list_id", Parent: "shopping_lists"},
+	{Name: "rules_without_list", Table: "list_rules", Column: "list_id", Parent: "shopping_lists"},
+	{Name: "merges_without_list", Table: "item_merges", Column: "list_id", Parent: "shopping_lists"},
+	{Name: "purchases_without_list", Table: "purchases", Column: "list_id", Parent: "shopping_lists"},
+	{Name: "replenish_suggestions_without_list", Table: "replenish_suggestions", Column: "list_id", Parent: "shopping_lists"},
+	{Name: "notes_without_list", Table: "list_notes", Column: "list_id", Parent: "shopping_lists"},
+	{Name: "notes_without_user", Table: "list_notes", Column: "user_id", Parent: "users"},
+	{Name: "trips_without_list", Table: "shopping_trips", Column: "list_id", Parent: "shopping_lists"},
+	{Name: "rsvps_without_trip", Table: "trip_rsvps", Column: "trip_id", Parent: "shopping_trips"},
+	{Name: "polls_without_item", Table: "item_polls", Column: "item_id", Parent: "shopping_items"},
+	{Name: "votes_without_poll", Table: "poll_votes", Column: "poll_id", Parent: "item_polls"},
+	{Name: "smart_lists_without_user", Table: "smart_lists", Column: "user_id", Parent: "users"},
+	{Name: "api_keys_without_user", Table: "api_keys", Column: "user_id", Parent: "users"},
+	{Name: "calendar_feeds_without_user", Table: "calendar_feeds", Column: "user_id", Parent: "users"},
+	{Name: "matrix_links_without_user", Table: "matrix_links", Column: "user_id", Parent: "users"},
+	{Name: "chat_links_without_user", Table: "chat_links", Column: "user_id", Parent: "users"},
+	{Name: "chat_link_codes_without_user", Table: "chat_link_codes", Column: "user_id", Parent: "users"},
+	{Name: "feature_flag_overrides_without_user", Table: "feature_flag_overrides", Column: "user_id", Parent: "users"},
+}
+
+// Service provides database integrity checks.
+type Service struct {
+	DB *gorm.DB
+}
+
+// NewService creates a new integrity service.
+func NewService(db *gorm.DB) *Service {
+	return &Service{DB: db}
+}
+
+// Check runs SQLite's integrity check on SQLite databases and counts orphaned rows. With repair
+// set, orphaned rows are deleted in a single transaction; corruption reported by SQLite itself
+// cannot be repaired and requires restoring a backup.
+func (s *Service) Check(repair bool) (*models.IntegrityReport, error) {
+	report := &models.IntegrityReport{
+		Errors:    []string{},
+		Orphans:   []models.OrphanReport{},
+		CheckedAt: time.Now(),
+	}
+
+	// Other databases check their storage on their own
+	if s.DB.Dialector.Name() == "sqlite" {
+		var results []string
+		if err := s.DB.Raw("PRAGMA integrity_check").Scan(&results).Error; err != nil {
+			return nil, err
+		}
+		for _, result := range results {
+			if result != "ok" {
+				report.Errors = append(report.Errors, result)
+			}
+		}
+	}
+
+	for _, check := range orphanChecks {
+		var count int64
+		if err := s.DB.Raw(orphanQuery("SELECT COUNT(*)", check)).Scan(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			report.Orphans = append(report.Orphans, models.OrphanReport{
+				Check: check.Name,
+				Table: check.Table,
+				Count: count,
+			})
+		}
+	}
+
+	if repair && len(report.Orphans) > 0 {
+		err := s.DB.Transaction(func(tx *gorm.DB) error {
+			for _, check := range orphanChecks {
+				if err := tx.Exec(orphanQuery("DELETE", check)).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		report.Repaired = true
+	}
+
+	report.OK = len(report.Errors) == 0 && (len(report.Orphans) == 0 || report.Repaired)
+	return report, nil
+}
+
+// orphanQuery builds a statement operating on the orphaned rows of a check.
+func orphanQuery(statement string, check orphanCheck) string {
+	return fmt.Sprintf("%s FROM %s WHERE %s NOT IN (SELECT id FROM %s)",
+		statement, check.Table, check.Column, check.Parent)
+}
@@ -0,0 +1,84 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package integrity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+)
+
+func TestService_Check(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+
+	user := models.User{ID: "user-1", Email: "user@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	list := models.ShoppingList{ID: "list-1", Name: "Groceries", OwnerID: user.ID, CreatedAt: time.Now()}
+	member := models.ListMember{ListID: list.ID, UserID: user.ID, Role: "owner", JoinedAt: time.Now()}
+	item := models.ShoppingItem{ID: "item-1", ListID: list.ID, Name: "Milk", Tags: "[]", CreatedAt: time.Now()}
+	for _, record := range []interface{}{&user, &list, &member, &item} {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatalf("Failed to create test data: %v", err)
+		}
+	}
+
+	t.Run("healthy database", func(t *testing.T) {
+		report, err := service.Check(false)
+		if err != nil {
+			t.Fatalf("Failed to check database: %v", err)
+		}
+		if !report.OK || len(report.Errors) != 0 || len(report.Orphans) != 0 {
+			t.Errorf("Expected healthy database, got %+v", report)
+		}
+	})
+
+	// Simulate an interrupted delete that only removed the list itself
+	if err := db.Exec("DELETE FROM shopping_lists WHERE id = ?", list.ID).Error; err != nil {
+		t.Fatalf("Failed to delete list: %v", err)
+	}
+
+	t.Run("report orphans", func(t *testing.T) {
+		report, err := service.Check(false)
+		if err != nil {
+			t.Fatalf("Failed to check database: %v", err)
+		}
+		if report.OK || report.Repaired {
+			t.Error("Expected orphans to be reported without repair")
+		}
+
+		counts := map[string]int64{}
+		for _, orphan := range report.Orphans {
+			counts[orphan.Check] = orphan.Count
+		}
+		if counts["members_without_list"] != 1 || counts["items_without_list"] != 1 {
+			t.Errorf("Expected orphaned member and item, got %+v", report.Orphans)
+		}
+	})
+
+	t.Run("repair orphans", func(t *testing.T) {
+		report, err := service.Check(true)
+		if err != nil {
+			t.Fatalf("Failed to repair database: %v", err)
+		}
+		if !report.OK || !report.Repaired {
+			t.Errorf("Expected orphans to be repaired, got %+v", report)
+		}
+
+		var items int64
+		db.Model(&models.ShoppingItem{}).Count(&items)
+		if items != 0 {
+			t.Errorf("Expected orphaned item to be deleted, got %d items", items)
+		}
+
+		report, err = service.Check(false)
+		if err != nil {
+			t.Fatalf("Failed to check database: %v", err)
+		}
+		if !report.OK || len(report.Orphans) != 0 {
+			t.Errorf("Expected healthy database after repair, got %+v", report)
+		}
+	})
+}
@@ -5,15 +5,19 @@
 package invitations
 
 import (
-	"crypto/rand"
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/oliverandrich/shopping-list-server/internal/codes"
+	"github.com/oliverandrich/shopping-list-server/internal/lists"
+	"github.com/oliverandrich/shopping-list-server/internal/mail"
 	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/pii"
 	"gopkg.in/gomail.v2"
 	"gorm.io/gorm"
 )
@@ -32,18 +36,35 @@ func NewService(db *gorm.DB, mailer *gomail.Dialer) *Service {
 	}
 }
 
+// ErrInvitationsFrozen is returned when the admin froze the inviter's invitations.
+var ErrInvitationsFrozen = errors.New("sending invitations has been disabled for this account")
+
+// InvitationLifetime is how long an invitation can be accepted after it was created.
+const InvitationLifetime = 7 * 24 * time.Hour
+
 // GenerateInvitationCode generates a secure 8-character hexadecimal invitation code.
 func GenerateInvitationCode() string {
-	bytes := make([]byte, 4)
-	if _, err := rand.Read(bytes); err != nil {
-		// Fallback to time-based random if crypto/rand fails
-		return fmt.Sprintf("%X", time.Now().UnixNano())[:8]
-	}
-	return fmt.Sprintf("%X", bytes)
+	return strings.ToUpper(codes.Hex(4))
 }
 
-// CreateInvitation creates a new invitation for server or list access.
+// CreateInvitation creates a new invitation for server or list access and emails the code.
 func (s *Service) CreateInvitation(inviterID, email, invType string, listID *string) (*models.Invitation, error) {
+	return s.createInvitation(inviterID, email, invType, listID, true)
+}
+
+// CreateInAppInvitation invites a registered user to a list without sending an email. The user
+// accepts it from their received invitations.
+func (s *Service) CreateInAppInvitation(inviterID, email, listID string) (*models.Invitation, error) {
+	return s.createInvitation(inviterID, email, "list", &listID, false)
+}
+
+func (s *Service) createInvitation(inviterID, email, invType string, listID *string, sendEmail bool) (*models.Invitation, error) {
+	email = mail.NormalizeAddress(email)
+
+	if err := s.checkNotFrozen(inviterID); err != nil {
+		return nil, err
+	}
+
 	// Validate invitation type
 	if invType != "server" && invType != "list" {
 		return nil, errors.New("invalid invitation type")
@@ -56,7 +77,7 @@ func (s *Service) CreateInvitation(inviterID, email, invType string, listID *str
 		}
 
 		var member models.ListMember
-		err := s.DB.Where("list_id = ? AND user_id = ? AND role = ?", *listID, inviterID, "owner").First(&member).Error
+		err := s.DB.Where("list_id = ? AND user_id = ? AND role IN ?", *listID, inviterID, lists.OwnerRoles).First(&member).Error
 		if err != nil {
 			return nil, errors.New("user is not the owner of this list")
 		}
@@ -64,7 +85,7 @@ func (s *Service) CreateInvitation(inviterID, email, invType string, listID *str
 
 	// Check if user is already registered
 	var existingUser models.User
-	err := s.DB.Where("email = ?", email).First(&existingUser).Error
+	err := s.DB.Where("email = ?", pii.Encrypt(email)).First(&existingUser).Error
 	if err == nil {
 		// User exists, check if they're already a member of the list (for list invitations)
 		if invType == "list" {
@@ -78,15 +99,24 @@ func (s *Service) CreateInvitation(inviterID, email, invType string, listID *str
 		}
 	}
 
-	// Check for existing unused invitations for this email and type
+	// Invitations are deduplicated per type and list, so a person can be invited to several
+	// lists at the same time
+	sameInvitation := func() *gorm.DB {
+		query := s.DB.Where("email = ? AND used = false AND type = ?", pii.Encrypt(email), invType)
+		if listID != nil {
+			return query.Where("list_id = ?", *listID)
+		}
+		return query.Where("list_id IS NULL")
+	}
+
 	var existingInvitation models.Invitation
-	err = s.DB.Where("email = ? AND used = false AND type = ?", email, invType).First(&existingInvitation).Error
+	err = sameInvitation().Where("expires_at > ?", time.Now()).First(&existingInvitation).Error
 	if err == nil {
 		return nil, errors.New("user is already invited")
 	}
 
-	// Delete any existing unused invitations for this email (of any type)
-	s.DB.Where("email = ? AND used = false", email).Delete(&models.Invitation{})
+	// Replace expired invitations of the same type and list
+	sameInvitation().Delete(&models.Invitation{})
 
 	// Create new invitation
 	invitation := models.Invitation{
@@ -96,7 +126,7 @@ func (s *Service) CreateInvitation(inviterID, email, invType string, listID *str
 		Type:      invType,
 		ListID:    listID,
 		InvitedBy: inviterID,
-		ExpiresAt: time.Now().Add(7 * 24 * time.Hour), // 7 days
+		ExpiresAt: time.Now().Add(InvitationLifetime),
 		Used:      false,
 		CreatedAt: time.Now(),
 	}
@@ -105,21 +135,102 @@ func (s *Service) CreateInvitation(inviterID, email, invType string, listID *str
 		return nil, err
 	}
 
+	// Send invitation email (skip in test environment)
+	if sendEmail && os.Getenv("GO_ENV") != "test" {
+		if err := s.SendInvitationEmail(&invitation); err != nil {
+			// Log error but don't fail invitation creation
+			slog.Warn("Failed to send invitation email", "invitation", invitation.ID, "error", err)
+		}
+	}
+
+	return &invitation, nil
+}
+
+// CreateBundleInvitation creates a single invitation that grants membership to several lists of
+// the inviter on acceptance. Lists the invited user is already a member of are skipped.
+func (s *Service) CreateBundleInvitation(inviterID, email string, listIDs []string) (*models.Invitation, error) {
+	email = mail.NormalizeAddress(email)
+
+	if err := s.checkNotFrozen(inviterID); err != nil {
+		return nil, err
+	}
+
+	invitation := models.Invitation{
+		ID:        uuid.New().String(),
+		Code:      GenerateInvitationCode(),
+		Email:     email,
+		Type:      "bundle",
+		InvitedBy: inviterID,
+		ExpiresAt: time.Now().Add(InvitationLifetime),
+		Used:      false,
+		CreatedAt: time.Now(),
+	}
+
+	var existingUser models.User
+	userExists := s.DB.Where("email = ?", pii.Encrypt(email)).First(&existingUser).Error == nil
+
+	seen := map[string]bool{}
+	for _, listID := range listIDs {
+		if seen[listID] {
+			continue
+		}
+		seen[listID] = true
+
+		var member models.ListMember
+		err := s.DB.Where("list_id = ? AND user_id = ? AND role IN ?", listID, inviterID, lists.OwnerRoles).First(&member).Error
+		if err != nil {
+			return nil, errors.New("user is not the owner of this list")
+		}
+
+		if userExists {
+			err = s.DB.Where("list_id = ? AND user_id = ?", listID, existingUser.ID).First(&member).Error
+			if err == nil {
+				continue
+			}
+		}
+
+		invitation.Lists = append(invitation.Lists, models.InvitationList{InvitationID: invitation.ID, ListID: listID})
+	}
+
+	if len(invitation.Lists) == 0 {
+		if len(listIDs) == 0 {
+			return nil, errors.New("list_ids required for bundle invitations")
+		}
+		return nil, errors.New("user is already a member of these lists")
+	}
+
+	if err := s.DB.Create(&invitation).Error; err != nil {
+		return nil, err
+	}
+
 	// Send invitation email (skip in test environment)
 	if os.Getenv("GO_ENV") != "test" {
 		if err := s.SendInvitationEmail(&invitation); err != nil {
 			// Log error but don't fail invitation creation
-			fmt.Printf("Warning: Failed to send invitation email: %v\n", err)
+			slog.Warn("Failed to send invitation email", "invitation", invitation.ID, "error", err)
 		}
 	}
 
 	return &invitation, nil
 }
 
+// checkNotFrozen returns ErrInvitationsFrozen if the inviter may not send invitations.
+func (s *Service) checkNotFrozen(inviterID string) error {
+	var frozen int64
+	if err := s.DB.Model(&models.User{}).Where("id = ? AND invitations_frozen = ?", inviterID, true).Count(&frozen).Error; err != nil {
+		return err
+	}
+	if frozen > 0 {
+		return ErrInvitationsFrozen
+	}
+	return nil
+}
+
 // SendInvitationEmail sends an invitation email to the specified recipient.
 func (s *Service) SendInvitationEmail(invitation *models.Invitation) error {
 	var inviterEmail string
 	s.DB.Model(&models.User{}).Select("email").Where("id = ?", invitation.InvitedBy).Scan(&inviterEmail)
+	inviterEmail, _ = pii.Decrypt(inviterEmail)
 
 	m := gomail.NewMessage()
 	m.SetHeader("From", os.Getenv("SMTP_FROM"))
@@ -137,6 +248,24 @@ This invitation will expire in 7 days.
 
 To accept this invitation, use the code when logging in for the first time.
 `, inviterEmail, invitation.Code)
+	} else if invitation.Type == "bundle" {
+		listIDs := make([]string, len(invitation.Lists))
+		for i, item := range invitation.Lists {
+			listIDs[i] = item.ListID
+		}
+		var listNames []string
+		s.DB.Model(&models.ShoppingList{}).Where("id IN ?", listIDs).Order("name").Pluck("name", &listNames)
+
+		subject = "Invitation to shared shopping lists"
+		body = fmt.Sprintf(`
+You've been invited to join the shopping lists "%s" by %s.
+
+Your invitation code is: %s
+
+This invitation will expire in 7 days.
+
+To accept this invitation, use the code when logging in.
+`, strings.Join(listNames, `", "`), inviterEmail, invitation.Code)
 	} else {
 		var listName string
 		s.DB.Model(&models.ShoppingList{}).Select("name").Where("id = ?", invitation.ListID).Scan(&listName)
@@ -156,14 +285,32 @@ To accept this invitation, use the code when logging in.
 	m.SetHeader("Subject", subject)
 	m.SetBody("text/plain", body)
 
-	return s.Mailer.DialAndSend(m)
+	return mail.Send(s.Mailer, m)
+}
+
+// GetPendingInvitations retrieves all unused and unexpired invitations for an email address,
+// oldest first.
+func (s *Service) GetPendingInvitations(email string) ([]models.Invitation, error) {
+	email = mail.NormalizeAddress(email)
+
+	var invitations []models.Invitation
+	err := s.DB.Preload("Lists").
+		Where("email = ? AND used = false AND expires_at > ?", pii.Encrypt(email), time.Now()).
+		Order("created_at ASC").
+		Find(&invitations).Error
+	if err != nil {
+		return nil, err
+	}
+	return invitations, nil
 }
 
 // AcceptInvitation marks an invitation as used and returns it if valid.
 func (s *Service) AcceptInvitation(email, code string) (*models.Invitation, error) {
+	email = mail.NormalizeAddress(email)
+
 	var invitation models.Invitation
 	err := s.DB.Where("email = ? AND code = ? AND used = false AND expires_at > ?",
-		email, strings.ToUpper(code), time.Now()).First(&invitation).Error
+		pii.Encrypt(email), codes.Normalize(code), time.Now()).First(&invitation).Error
 	if err != nil {
 		return nil, errors.New("invalid or expired invitation")
 	}
@@ -178,10 +325,24 @@ func (s *Service) AcceptInvitation(email, code string) (*models.Invitation, erro
 // GetUserInvitations retrieves all invitations created by the specified user.
 func (s *Service) GetUserInvitations(userID string) ([]models.Invitation, error) {
 	var invitations []models.Invitation
-	err := s.DB.Where("invited_by = ?", userID).Order("created_at DESC").Find(&invitations).Error
+	err := s.DB.Preload("Lists").Where("invited_by = ?", userID).Order("created_at DESC").Find(&invitations).Error
 	return invitations, err
 }
 
+// DeclineInvitation deletes a pending invitation addressed to the given email address.
+func (s *Service) DeclineInvitation(invitationID, email string) error {
+	email = mail.NormalizeAddress(email)
+
+	result := s.DB.Where("id = ? AND email = ? AND used = false", invitationID, pii.Encrypt(email)).Delete(&models.Invitation{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("invitation not found or already used")
+	}
+	return s.DB.Where("invitation_id = ?", invitationID).Delete(&models.InvitationList{}).Error
+}
+
 // RevokeInvitation cancels an invitation if the user is the original inviter.
 func (s *Service) RevokeInvitation(invitationID, userID string) error {
 	result := s.DB.Where("id = ? AND invited_by = ? AND used = false", invitationID, userID).Delete(&models.Invitation{})
@@ -191,5 +352,5 @@ func (s *Service) RevokeInvitation(invitationID, userID string) error {
 	if result.RowsAffected == 0 {
 		return errors.New("invitation not found or already used")
 	}
-	return nil
+	return s.DB.Where("invitation_id = ?", invitationID).Delete(&models.InvitationList{}).Error
 }
@@ -57,6 +57,15 @@ func TestGenerateInvitationCode(t *testing.T) {
 	}
 }
 
+func TestGenerateInvitationCode_Properties(t *testing.T) {
+	for range 1000 {
+		code := GenerateInvitationCode()
+		if len(code) != 8 || strings.Trim(code, "0123456789ABCDEF") != "" {
+			t.Fatalf("Expected 8 uppercase hex characters, got %q", code)
+		}
+	}
+}
+
 func TestService_CreateInvitation_ServerType(t *testing.T) {
 	db := testutils.SetupTestDB(t)
 	// Set up test environment
@@ -251,6 +260,96 @@ func TestService_CreateInvitation_ListType(t *testing.T) {
 		}
 	})
 
+	t.Run("invite to several lists concurrently", func(t *testing.T) {
+		second := models.ShoppingList{ID: "second-list-id", Name: "Hardware store", OwnerID: owner.ID, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+		if err := db.Create(&second).Error; err != nil {
+			t.Fatalf("Failed to create second list: %v", err)
+		}
+		if err := db.Create(&models.ListMember{ListID: second.ID, UserID: owner.ID, Role: "owner", JoinedAt: time.Now()}).Error; err != nil {
+			t.Fatalf("Failed to create list member: %v", err)
+		}
+
+		email := "concurrent@example.com"
+		firstID, secondID := list.ID, second.ID
+		if _, err := service.CreateInvitation(owner.ID, email, "list", &firstID); err != nil {
+			t.Fatalf("Failed to create first invitation: %v", err)
+		}
+		if _, err := service.CreateInvitation(owner.ID, email, "list", &secondID); err != nil {
+			t.Fatalf("Failed to create second invitation: %v", err)
+		}
+
+		pending, err := service.GetPendingInvitations(email)
+		if err != nil {
+			t.Fatalf("Failed to get pending invitations: %v", err)
+		}
+		if len(pending) != 2 {
+			t.Errorf("Expected both invitations to stay pending, got %d", len(pending))
+		}
+
+		if _, err := service.CreateInvitation(owner.ID, email, "list", &firstID); err == nil || !strings.Contains(err.Error(), "already invited") {
+			t.Errorf("Expected duplicate invitation to the same list to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("replace expired invitation", func(t *testing.T) {
+		email := "expired@example.com"
+		listID := list.ID
+		expired, err := service.CreateInvitation(owner.ID, email, "list", &listID)
+		if err != nil {
+			t.Fatalf("Failed to create invitation: %v", err)
+		}
+		db.Model(expired).Update("expires_at", time.Now().Add(-time.Hour))
+
+		if _, err := service.CreateInvitation(owner.ID, email, "list", &listID); err != nil {
+			t.Fatalf("Expected expired invitation to be replaced, got %v", err)
+		}
+
+		var count int64
+		db.Model(&models.Invitation{}).Where("email = ?", email).Count(&count)
+		if count != 1 {
+			t.Errorf("Expected expired invitation to be deleted, got %d invitations", count)
+		}
+	})
+
+	t.Run("bundle invitation", func(t *testing.T) {
+		bundled := models.ShoppingList{ID: "bundled-list-id", Name: "Pharmacy", OwnerID: owner.ID, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+		if err := db.Create(&bundled).Error; err != nil {
+			t.Fatalf("Failed to create list: %v", err)
+		}
+		if err := db.Create(&models.ListMember{ListID: bundled.ID, UserID: owner.ID, Role: "owner", JoinedAt: time.Now()}).Error; err != nil {
+			t.Fatalf("Failed to create list member: %v", err)
+		}
+
+		invitation, err := service.CreateBundleInvitation(owner.ID, "bundle@example.com", []string{list.ID, bundled.ID, list.ID})
+		if err != nil {
+			t.Fatalf("Failed to create bundle invitation: %v", err)
+		}
+		if invitation.Type != "bundle" || len(invitation.Lists) != 2 {
+			t.Errorf("Expected bundle with 2 lists, got %+v", invitation)
+		}
+
+		pending, err := service.GetPendingInvitations("bundle@example.com")
+		if err != nil || len(pending) != 1 || len(pending[0].Lists) != 2 {
+			t.Errorf("Expected pending bundle with its lists, got %+v (%v)", pending, err)
+		}
+
+		if _, err := service.CreateBundleInvitation(owner.ID, "bundle@example.com", []string{"foreign-list"}); err == nil {
+			t.Error("Expected bundle with a list not owned by the inviter to be rejected")
+		}
+		if _, err := service.CreateBundleInvitation(owner.ID, "bundle@example.com", nil); err == nil {
+			t.Error("Expected bundle without lists to be rejected")
+		}
+
+		if err := service.RevokeInvitation(invitation.ID, owner.ID); err != nil {
+			t.Fatalf("Failed to revoke invitation: %v", err)
+		}
+		var items int64
+		db.Model(&models.InvitationList{}).Where("invitation_id = ?", invitation.ID).Count(&items)
+		if items != 0 {
+			t.Errorf("Expected line items to be deleted with the invitation, got %d", items)
+		}
+	})
+
 	t.Run("create list invitation as non-owner", func(t *testing.T) {
 		// Create another user who is not the owner
 		nonOwner := models.User{
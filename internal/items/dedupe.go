@@ -0,0 +1,195 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package items
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/quantity"
+	"gorm.io/gorm"
+)
+
+// NormalizeName returns the form of an item name duplicates are detected by: lower case with
+// surrounding and repeated whitespace removed.
+func NormalizeName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// DuplicateKey identifies the items that are duplicates of each other: those with the same
+// normalized name and units that convert into each other, like g and kg.
+func DuplicateKey(item models.ShoppingItem) string {
+	return NormalizeName(item.Name) + "\x00" + quantity.Dimension(item.Unit)
+}
+
+// Deduplicate merges duplicate open items of a list, see DuplicateKey; snoozed items are left
+// alone. The oldest item of each group is kept and receives the summed quantity, converted
+// into a common unit if needed, the tags of all items and their attachments, the others are
+// deleted. Every merge is recorded for the list activity. userID is the user who requested the
+// merge, empty for the scheduled job.
+func (s *Service) Deduplicate(listID, userID string) ([]models.ItemMerge, error) {
+	var open []models.ShoppingItem
+	err := s.DB.Where("list_id = ? AND completed = ? AND snoozed_until IS NULL", listID, false).
+		Order("created_at ASC").Find(&open).Error
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string][]models.ShoppingItem)
+	var keys []string
+	for _, item := range open {
+		key := DuplicateKey(item)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], item)
+	}
+
+	merges := []models.ItemMerge{}
+	for _, key := range keys {
+		if len(groups[key]) < 2 {
+			continue
+		}
+		var merge *models.ItemMerge
+		err := s.DB.Transaction(func(tx *gorm.DB) error {
+			var err error
+			merge, err = MergeGroup(tx, groups[key], userID)
+			return err
+		})
+		if err != nil {
+			return merges, err
+		}
+		merges = append(merges, *merge)
+	}
+	return merges, nil
+}
+
+// DeduplicateAll merges the duplicate open items of every list that did not opt out. It
+// returns the number of merges.
+func (s *Service) DeduplicateAll() (int, error) {
+	var listIDs []string
+	err := s.DB.Model(&models.ShoppingList{}).Where("skip_deduplication = ?", false).Pluck("id", &listIDs).Error
+	if err != nil {
+		return 0, err
+	}
+
+	merged := 0
+	for _, listID := range listIDs {
+		merges, err := s.Deduplicate(listID, "")
+		merged += len(merges)
+		if err != nil {
+			return merged, err
+		}
+	}
+	return merged, nil
+}
+
+// MergeGroup folds the other items of a duplicate group into the first one and records the
+// merge for userID. It runs on tx, so callers can merge as part of a larger transaction.
+func MergeGroup(tx *gorm.DB, group []models.ShoppingItem, userID string) (*models.ItemMerge, error) {
+	keep := group[0]
+	tags := decodeTags(keep.Tags)
+	ids := make([]string, 0, len(group)-1)
+
+	// A quantity of zero means none was given, which counts as one once quantities are summed.
+	amounts := make([]quantity.Amount, 0, len(group))
+	counted := false
+	for _, item := range group {
+		if item.Quantity > 0 {
+			counted = true
+		}
+		amounts = append(amounts, quantity.Amount{Quantity: max(item.Quantity, 1), Unit: item.Unit})
+	}
+	sum, ok := quantity.Add(amounts...)
+	if !ok {
+		return nil, errors.New("quantities of duplicates cannot be added up")
+	}
+	if !counted {
+		sum = quantity.Amount{Unit: keep.Unit}
+	}
+
+	for _, item := range group[1:] {
+		ids = append(ids, item.ID)
+		for _, tag := range decodeTags(item.Tags) {
+			if !containsFold(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		if keep.Category == "" && item.Category != "" {
+			keep.Category, keep.Emoji = item.Category, item.Emoji
+		}
+		if keep.SectionID == nil {
+			keep.SectionID = item.SectionID
+		}
+		if item.DueDate != nil && (keep.DueDate == nil || item.DueDate.Before(*keep.DueDate)) {
+			keep.DueDate = item.DueDate
+		}
+	}
+
+	encoded, err := json.Marshal(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	merge := models.ItemMerge{
+		ID:       uuid.New().String(),
+		ListID:   keep.ListID,
+		ItemID:   keep.ID,
+		ItemName: keep.Name,
+		Merged:   len(ids),
+		Quantity: sum.Quantity,
+		Unit:     sum.Unit,
+		UserID:   userID,
+	}
+
+	err = tx.Model(&models.ShoppingItem{}).Where("id = ?", keep.ID).UpdateColumns(map[string]any{
+		"quantity":   sum.Quantity,
+		"unit":       sum.Unit,
+		"tags":       string(encoded),
+		"category":   keep.Category,
+		"emoji":      keep.Emoji,
+		"section_id": keep.SectionID,
+		"due_date":   keep.DueDate,
+		"updated_at": time.Now(),
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Model(&models.Attachment{}).Where("item_id IN ?", ids).
+		UpdateColumns(map[string]any{"item_id": keep.ID, "list_id": keep.ListID}).Error
+	if err != nil {
+		return nil, err
+	}
+	// Merged duplicates live on in the kept item, so they do not go to the trash
+	if err := tx.Unscoped().Where("id IN ?", ids).Delete(&models.ShoppingItem{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Create(&merge).Error; err != nil {
+		return nil, err
+	}
+	return &merge, nil
+}
+
+// decodeTags parses the JSON tag list of an item; malformed tags count as none.
+func decodeTags(encoded string) []string {
+	var tags []string
+	if err := json.Unmarshal([]byte(encoded), &tags); err != nil || tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+// containsFold reports whether tags contains tag, ignoring case.
+func containsFold(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package items
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// Find retrieves the items matching a filter across all lists the user is a member of, newest
+// first. Empty criteria match everything; tags, categories and allergens match if any of the
+// given values match.
+func (s *Service) Find(userID string, filter models.ItemFilter) ([]models.ShoppingItem, error) {
+	now := time.Now()
+
+	query := s.DB.Model(&models.ShoppingItem{}).
+		Where("list_id IN (?)", s.DB.Model(&models.ListMember{}).Select("list_id").Where("user_id = ?", userID))
+
+	if len(filter.ListIDs) > 0 {
+		query = query.Where("list_id IN ?", filter.ListIDs)
+	}
+	if len(filter.Categories) > 0 {
+		query = query.Where("category IN ?", filter.Categories)
+	}
+	query, err := WhereContainsAny(query, "tags", filter.Tags)
+	if err != nil {
+		return nil, err
+	}
+	query, err = WhereContainsAny(query, "allergens", filter.Allergens)
+	if err != nil {
+		return nil, err
+	}
+	query = WhereNameContains(query, filter.Query)
+	if filter.Completed != nil {
+		query = query.Where("completed = ?", *filter.Completed)
+	}
+	if !filter.IncludeSnoozed {
+		query = query.Where("snoozed_until IS NULL OR snoozed_until <= ?", now)
+	}
+
+	var items []models.ShoppingItem
+	if err := query.Order("created_at DESC").Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	if err := s.markStaleAcrossLists(items, now); err != nil {
+		return nil, err
+	}
+
+	if filter.Stale == nil {
+		return items, nil
+	}
+	matching := items[:0]
+	for _, item := range items {
+		if item.Stale == *filter.Stale {
+			matching = append(matching, item)
+		}
+	}
+	return matching, nil
+}
+
+// FindDuplicates retrieves the open items named like name, ignoring case, on all lists the
+// user is a member of.
+func (s *Service) FindDuplicates(userID, name string) ([]models.DuplicateMatch, error) {
+	duplicates := []models.DuplicateMatch{}
+	err := s.DB.Table("shopping_items").
+		Select("shopping_items.id AS item_id, shopping_items.list_id, shopping_lists.name AS list_name, "+
+			"shopping_items.name, shopping_items.quantity, shopping_items.unit").
+		Joins("JOIN shopping_lists ON shopping_lists.id = shopping_items.list_id").
+		Joins("JOIN list_members ON list_members.list_id = shopping_items.list_id AND list_members.user_id = ?", userID).
+		Where("LOWER(shopping_items.name) = LOWER(?) AND shopping_items.completed = ?", strings.TrimSpace(name), false).
+		Where("shopping_items.deleted_at IS NULL").
+		Order("shopping_items.created_at DESC").
+		Scan(&duplicates).Error
+	return duplicates, err
+}
+
+// markStaleAcrossLists sets the stale flag on items of several lists, each according to the
+// threshold of its own list.
+func (s *Service) markStaleAcrossLists(items []models.ShoppingItem, now time.Time) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	listIDs := make([]string, 0)
+	seen := map[string]bool{}
+	for _, item := range items {
+		if !seen[item.ListID] {
+			seen[item.ListID] = true
+			listIDs = append(listIDs, item.ListID)
+		}
+	}
+
+	var lists []models.ShoppingList
+	if err := s.DB.Select("id", "stale_after_days").Where("id IN ?", listIDs).Find(&lists).Error; err != nil {
+		return err
+	}
+	staleAfterDays := make(map[string]int, len(lists))
+	for _, list := range lists {
+		staleAfterDays[list.ID] = list.StaleAfterDays
+	}
+
+	for i := range items {
+		items[i].Stale = IsStale(items[i], staleAfterDays[items[i].ListID], now)
+	}
+	return nil
+}
+
+// WhereContainsAny restricts a query to items whose JSON array column, tags or allergens, holds
+// any of the values. No values leave the query unchanged.
+func WhereContainsAny(query *gorm.DB, column string, values []string) (*gorm.DB, error) {
+	if len(values) == 0 {
+		return query, nil
+	}
+
+	// The column stores a JSON array, so match the quoted value
+	conditions := make([]string, 0, len(values))
+	args := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		quoted, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, column+` LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(string(quoted))+"%")
+	}
+	return query.Where(strings.Join(conditions, " OR "), args...), nil
+}
+
+// WhereNameContains restricts a query to items whose name contains q, ignoring case. An empty q
+// leaves the query unchanged.
+func WhereNameContains(query *gorm.DB, q string) *gorm.DB {
+	if q = strings.TrimSpace(q); q == "" {
+		return query
+	}
+	return query.Where(`LOWER(name) LIKE ? ESCAPE '\'`, "%"+escapeLike(strings.ToLower(q))+"%")
+}
+
+// escapeLike escapes the LIKE wildcards in a value for use with ESCAPE '\'.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
@@ -0,0 +1,65 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package items
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+)
+
+// OtherCategory groups items without a category; it is sorted last.
+const OtherCategory = "Other"
+
+// CategoryGroup holds the open items of one category.
+type CategoryGroup struct {
+	Category string
+	Items    []models.ShoppingItem
+}
+
+// GroupOpenByCategory groups the open items by category in alphabetical order with
+// uncategorized items last, each group sorted by item name. It also returns the number of
+// completed items, which are left out.
+func GroupOpenByCategory(list []models.ShoppingItem) ([]CategoryGroup, int) {
+	completed := 0
+	byCategory := map[string][]models.ShoppingItem{}
+	for _, item := range list {
+		if item.Completed {
+			completed++
+			continue
+		}
+
+		category := strings.TrimSpace(item.Category)
+		if category == "" {
+			category = OtherCategory
+		}
+		byCategory[category] = append(byCategory[category], item)
+	}
+
+	groups := make([]CategoryGroup, 0, len(byCategory))
+	for category, items := range byCategory {
+		slices.SortFunc(items, func(a, b models.ShoppingItem) int {
+			return cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		})
+		groups = append(groups, CategoryGroup{Category: category, Items: items})
+	}
+	slices.SortFunc(groups, func(a, b CategoryGroup) int {
+		if a.Category == OtherCategory || b.Category == OtherCategory {
+			return cmp.Compare(otherRank(a.Category), otherRank(b.Category))
+		}
+		return cmp.Compare(strings.ToLower(a.Category), strings.ToLower(b.Category))
+	})
+
+	return groups, completed
+}
+
+// otherRank sorts the other category after all named ones.
+func otherRank(category string) int {
+	if category == OtherCategory {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,154 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package items
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// DateLayout is the format of the dates bounding history searches, e.g. 2025-03-01.
+const DateLayout = "2006-01-02"
+
+// MaxHistoryLimit limits the number of completed items returned by a history search.
+const MaxHistoryLimit = 200
+
+// ErrInvalidDate is returned for history bounds not formatted like 2025-03-01.
+var ErrInvalidDate = errors.New("dates must be formatted like 2025-03-01")
+
+// History searches the completed items kept on the lists the user is a member of, archived lists
+// included, most recently completed first, e.g. to answer when detergent was last bought. From
+// and to are days like 2025-03-01 in local time, both inclusive and optional. LastCompletedAt is
+// the most recent completion of all matching items, not only of those returned. How long
+// completed items are kept depends on the item history retention.
+func (s *Service) History(userID string, filter models.HistoryFilter) (*models.ItemHistory, error) {
+	query := s.DB.Model(&models.ShoppingItem{}).
+		Where("list_id IN (?)", s.DB.Model(&models.ListMember{}).Select("list_id").Where("user_id = ?", userID)).
+		Where("completed = ? AND completed_at IS NOT NULL", true)
+	if filter.ListID != "" {
+		query = query.Where("list_id = ?", filter.ListID)
+	}
+	query = WhereNameContains(query, filter.Query)
+	if filter.From != "" {
+		from, err := time.ParseInLocation(DateLayout, filter.From, time.Local)
+		if err != nil {
+			return nil, ErrInvalidDate
+		}
+		query = query.Where("completed_at >= ?", from)
+	}
+	if filter.To != "" {
+		to, err := time.ParseInLocation(DateLayout, filter.To, time.Local)
+		if err != nil {
+			return nil, ErrInvalidDate
+		}
+		query = query.Where("completed_at < ?", to.AddDate(0, 0, 1))
+	}
+
+	limit := filter.Limit
+	if limit <= 0 || limit > MaxHistoryLimit {
+		limit = MaxHistoryLimit
+	}
+
+	history := &models.ItemHistory{Items: []models.ItemHistoryEntry{}}
+	query = query.Session(&gorm.Session{})
+	if err := query.Count(&history.Total).Error; err != nil {
+		return nil, err
+	}
+	var found []models.ShoppingItem
+	if err := query.Order("completed_at DESC").Order("id").Limit(limit).Find(&found).Error; err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return history, nil
+	}
+	history.LastCompletedAt = found[0].CompletedAt
+
+	if err := s.AttachCreators(found); err != nil {
+		return nil, err
+	}
+	if err := s.AttachPurchaseStats(found); err != nil {
+		return nil, err
+	}
+	listIDs := make([]string, 0, len(found))
+	for _, item := range found {
+		listIDs = append(listIDs, item.ListID)
+	}
+	var lists []models.ShoppingList
+	if err := s.DB.Select("id", "name", "archived_at").Where("id IN ?", listIDs).Find(&lists).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[string]models.ShoppingList, len(lists))
+	for _, list := range lists {
+		byID[list.ID] = list
+	}
+	for _, item := range found {
+		list := byID[item.ListID]
+		history.Items = append(history.Items, models.ItemHistoryEntry{
+			ShoppingItem: item,
+			ListName:     list.Name,
+			ListArchived: list.ArchivedAt != nil,
+		})
+	}
+	return history, nil
+}
+
+// AttachPurchaseStats fills in when items of the same name, ignoring case, were last checked off
+// on the list of each item and, given two purchases or more, the average number of days between
+// them, e.g. to hint that milk is bought every 12 days. Purchases are the completed items kept in
+// the history, so items checked off again count once, at their last completion.
+func (s *Service) AttachPurchaseStats(items []models.ShoppingItem) error {
+	listIDs := make([]string, 0, len(items))
+	for _, item := range items {
+		if !slices.Contains(listIDs, item.ListID) {
+			listIDs = append(listIDs, item.ListID)
+		}
+	}
+	if len(listIDs) == 0 {
+		return nil
+	}
+
+	// Names are compared here, SQLite only lowercases ASCII letters
+	var purchases []models.ShoppingItem
+	err := s.DB.Select("list_id", "name", "completed_at").
+		Where("list_id IN ? AND completed = ? AND completed_at IS NOT NULL", listIDs, true).
+		Order("completed_at").Find(&purchases).Error
+	if err != nil {
+		return err
+	}
+
+	type stats struct {
+		first, last time.Time
+		count       int
+	}
+	byName := make(map[string]*stats)
+	for _, purchase := range purchases {
+		key := purchase.ListID + "/" + strings.ToLower(purchase.Name)
+		if byName[key] == nil {
+			byName[key] = &stats{first: *purchase.CompletedAt}
+		}
+		byName[key].last = *purchase.CompletedAt
+		byName[key].count++
+	}
+
+	for i := range items {
+		found := byName[items[i].ListID+"/"+strings.ToLower(items[i].Name)]
+		if found == nil {
+			continue
+		}
+		last := found.last
+		items[i].LastPurchasedAt = &last
+		if found.count > 1 {
+			days := found.last.Sub(found.first).Hours() / 24 / float64(found.count-1)
+			days = math.Round(days*10) / 10
+			items[i].PurchaseIntervalDays = &days
+		}
+	}
+	return nil
+}
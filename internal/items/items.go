@@ -0,0 +1,267 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package items provides shopping item state management such as snoozing items, stale-item
+// detection, merging duplicate items and the background jobs that maintain item state.
+package items
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/mail"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/pii"
+	"gopkg.in/gomail.v2"
+	"gorm.io/gorm"
+)
+
+// Service provides shopping item state operations.
+type Service struct {
+	DB     *gorm.DB
+	Mailer *gomail.Dialer
+	// NudgeInterval is the minimum time between two stale-item nudges for the same list.
+	NudgeInterval time.Duration
+}
+
+// NewService creates a new items service with database and email capabilities.
+func NewService(db *gorm.DB, mailer *gomail.Dialer) *Service {
+	return &Service{
+		DB:            db,
+		Mailer:        mailer,
+		NudgeInterval: 7 * 24 * time.Hour,
+	}
+}
+
+// Snooze hides an item from the active list view until the given time.
+func (s *Service) Snooze(listID, itemID string, until time.Time) (*models.ShoppingItem, error) {
+	if !until.After(time.Now()) {
+		return nil, errors.New("snooze time must be in the future")
+	}
+
+	var item models.ShoppingItem
+	if err := s.DB.Where("id = ? AND list_id = ?", itemID, listID).First(&item).Error; err != nil {
+		return nil, errors.New("item not found")
+	}
+
+	item.SnoozedUntil = &until
+	if err := s.DB.Save(&item).Error; err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+// Unsnooze returns a snoozed item to the active list view immediately.
+func (s *Service) Unsnooze(listID, itemID string) (*models.ShoppingItem, error) {
+	var item models.ShoppingItem
+	if err := s.DB.Where("id = ? AND list_id = ?", itemID, listID).First(&item).Error; err != nil {
+		return nil, errors.New("item not found")
+	}
+
+	item.SnoozedUntil = nil
+	if err := s.DB.Save(&item).Error; err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+// WakeSnoozedItems clears the snooze of all items whose snooze time has passed and returns
+// the number of items that surfaced again.
+func (s *Service) WakeSnoozedItems() (int64, error) {
+	result := s.DB.Model(&models.ShoppingItem{}).
+		Where("snoozed_until IS NOT NULL AND snoozed_until <= ?", time.Now()).
+		Update("snoozed_until", nil)
+	return result.RowsAffected, result.Error
+}
+
+// IsStale reports whether an open item has been on the list for more than staleAfterDays.
+// A staleAfterDays of zero disables stale detection.
+func IsStale(item models.ShoppingItem, staleAfterDays int, now time.Time) bool {
+	if item.Completed || staleAfterDays <= 0 {
+		return false
+	}
+	return item.CreatedAt.Before(now.AddDate(0, 0, -staleAfterDays))
+}
+
+// MarkStale sets the stale flag on all given items according to the list's threshold.
+func (s *Service) MarkStale(items []models.ShoppingItem, staleAfterDays int, now time.Time) {
+	for i := range items {
+		items[i].Stale = IsStale(items[i], staleAfterDays, now)
+	}
+}
+
+// DisplayName returns the name a user is shown as to other members: the local part of their
+// email address, since accounts have no name of their own.
+func DisplayName(email string) string {
+	name, _, _ := strings.Cut(email, "@")
+	return name
+}
+
+// AttachCreators fills in the display name of the user who created each item.
+func (s *Service) AttachCreators(items []models.ShoppingItem) error {
+	userIDs := make([]string, 0, len(items))
+	for _, item := range items {
+		if item.CreatedBy != nil && !slices.Contains(userIDs, *item.CreatedBy) {
+			userIDs = append(userIDs, *item.CreatedBy)
+		}
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	var users []models.User
+	if err := s.DB.Select("id", "email").Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		return err
+	}
+	names := make(map[string]string, len(users))
+	for _, user := range users {
+		names[user.ID] = DisplayName(user.Email)
+	}
+	for i := range items {
+		if items[i].CreatedBy != nil {
+			items[i].CreatedByName = names[*items[i].CreatedBy]
+		}
+	}
+	return nil
+}
+
+// LastModified returns when the items of a list as seen at now last changed: the latest update of
+// the list or an item, a snooze running out or an item becoming stale. Deleting items updates the
+// list, see db.ListTouchPlugin.
+func (s *Service) LastModified(list models.ShoppingList, items []models.ShoppingItem, now time.Time) time.Time {
+	lastModified := list.UpdatedAt
+	latest := func(t time.Time) {
+		if t.After(lastModified) && !t.After(now) {
+			lastModified = t
+		}
+	}
+	for _, item := range items {
+		latest(item.UpdatedAt)
+		if item.SnoozedUntil != nil {
+			latest(*item.SnoozedUntil)
+		}
+		if item.Stale {
+			latest(item.CreatedAt.AddDate(0, 0, list.StaleAfterDays))
+		}
+	}
+	return lastModified
+}
+
+// ItemsByList returns the items of the lists, newest first, marked stale according to their
+// list and with their creators, in a constant number of queries. Every list has an entry, empty if it has no items. Snoozed items are
+// left out unless includeSnoozed is set.
+func (s *Service) ItemsByList(lists []models.ShoppingList, includeSnoozed bool) (map[string][]models.ShoppingItem, error) {
+	byList := make(map[string][]models.ShoppingItem, len(lists))
+	listIDs := make([]string, 0, len(lists))
+	staleAfterDays := make(map[string]int, len(lists))
+	for _, list := range lists {
+		byList[list.ID] = []models.ShoppingItem{}
+		listIDs = append(listIDs, list.ID)
+		staleAfterDays[list.ID] = list.StaleAfterDays
+	}
+	if len(listIDs) == 0 {
+		return byList, nil
+	}
+
+	now := time.Now()
+	query := s.DB.Where("list_id IN ?", listIDs)
+	if !includeSnoozed {
+		query = query.Where("snoozed_until IS NULL OR snoozed_until <= ?", now)
+	}
+	var found []models.ShoppingItem
+	if err := query.Order("created_at DESC").Find(&found).Error; err != nil {
+		return nil, err
+	}
+	if err := s.AttachCreators(found); err != nil {
+		return nil, err
+	}
+
+	for _, item := range found {
+		item.Stale = IsStale(item, staleAfterDays[item.ListID], now)
+		byList[item.ListID] = append(byList[item.ListID], item)
+	}
+	return byList, nil
+}
+
+// SendStaleNudges emails the members of every list that has stale items and was not nudged
+// within the nudge interval. It returns the number of lists nudged.
+func (s *Service) SendStaleNudges() (int, error) {
+	now := time.Now()
+
+	var lists []models.ShoppingList
+	err := s.DB.Where("stale_after_days > 0 AND (stale_nudge_sent_at IS NULL OR stale_nudge_sent_at <= ?)",
+		now.Add(-s.NudgeInterval)).Find(&lists).Error
+	if err != nil {
+		return 0, err
+	}
+
+	nudged := 0
+	for i := range lists {
+		list := &lists[i]
+
+		var staleItems []string
+		s.DB.Model(&models.ShoppingItem{}).
+			Where("list_id = ? AND completed = ? AND created_at < ?", list.ID, false, now.AddDate(0, 0, -list.StaleAfterDays)).
+			Order("created_at ASC").
+			Pluck("name", &staleItems)
+		if len(staleItems) == 0 {
+			continue
+		}
+
+		var emails []string
+		s.DB.Model(&models.User{}).
+			Joins("JOIN list_members ON users.id = list_members.user_id").
+			Where("list_members.list_id = ?", list.ID).
+			Pluck("users.email", &emails)
+		if emails, err = pii.DecryptAll(emails); err != nil {
+			return nudged, err
+		}
+
+		for _, email := range emails {
+			if err := s.sendStaleNudge(email, list, staleItems); err != nil {
+				slog.Warn("Failed to send stale item nudge", "list", list.ID, "error", err)
+			}
+		}
+
+		sent := time.Now()
+		if err := s.DB.Model(list).Update("stale_nudge_sent_at", &sent).Error; err != nil {
+			return nudged, err
+		}
+		nudged++
+	}
+
+	return nudged, nil
+}
+
+// sendStaleNudge sends a stale item nudge email to a list member.
+func (s *Service) sendStaleNudge(email string, list *models.ShoppingList, staleItems []string) error {
+	// Skip email sending in test environment
+	if os.Getenv("GO_ENV") == "test" {
+		return nil
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", os.Getenv("SMTP_FROM"))
+	m.SetHeader("To", email)
+	m.SetHeader("Subject", fmt.Sprintf("%d items waiting on %s", len(staleItems), list.Name))
+
+	body := fmt.Sprintf(`
+These items have been open on "%s" for more than %d days:
+
+`, list.Name, list.StaleAfterDays)
+	for _, name := range staleItems {
+		body += fmt.Sprintf("- %s\n", name)
+	}
+	body += "\nBuy them, or remove them if they are no longer needed.\n"
+
+	m.SetBody("text/plain", body)
+
+	return mail.Send(s.Mailer, m)
+}
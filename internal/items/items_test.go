@@ -0,0 +1,622 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package items
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+)
+
+func createTestItem(t *testing.T, service *Service, id, listID string) models.ShoppingItem {
+	t.Helper()
+
+	item := models.ShoppingItem{
+		ID:        id,
+		ListID:    listID,
+		Name:      testutils.TestItemName(),
+		Tags:      "[]",
+		CreatedAt: time.Now(),
+	}
+	if err := service.DB.Create(&item).Error; err != nil {
+		t.Fatalf("Failed to create test item: %v", err)
+	}
+	return item
+}
+
+func TestService_Snooze(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db, nil)
+	item := createTestItem(t, service, "snooze-item", "snooze-list")
+
+	t.Run("snooze item", func(t *testing.T) {
+		until := time.Now().Add(7 * 24 * time.Hour)
+		snoozed, err := service.Snooze(item.ListID, item.ID, until)
+		if err != nil {
+			t.Fatalf("Failed to snooze item: %v", err)
+		}
+		if snoozed.SnoozedUntil == nil || !snoozed.SnoozedUntil.Equal(until) {
+			t.Error("Expected snoozed_until to be set")
+		}
+	})
+
+	t.Run("reject past time", func(t *testing.T) {
+		if _, err := service.Snooze(item.ListID, item.ID, time.Now().Add(-time.Hour)); err == nil {
+			t.Error("Expected snooze in the past to be rejected")
+		}
+	})
+
+	t.Run("reject item from other list", func(t *testing.T) {
+		if _, err := service.Snooze("other-list", item.ID, time.Now().Add(time.Hour)); err == nil {
+			t.Error("Expected item from another list to be rejected")
+		}
+	})
+
+	t.Run("unsnooze item", func(t *testing.T) {
+		unsnoozed, err := service.Unsnooze(item.ListID, item.ID)
+		if err != nil {
+			t.Fatalf("Failed to unsnooze item: %v", err)
+		}
+		if unsnoozed.SnoozedUntil != nil {
+			t.Error("Expected snoozed_until to be cleared")
+		}
+	})
+}
+
+func TestService_WakeSnoozedItems(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db, nil)
+
+	expired := createTestItem(t, service, "expired-item", "wake-list")
+	pending := createTestItem(t, service, "pending-item", "wake-list")
+
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+	db.Model(&expired).Update("snoozed_until", &past)
+	db.Model(&pending).Update("snoozed_until", &future)
+
+	woken, err := service.WakeSnoozedItems()
+	if err != nil {
+		t.Fatalf("Failed to wake snoozed items: %v", err)
+	}
+	if woken != 1 {
+		t.Errorf("Expected 1 woken item, got %d", woken)
+	}
+
+	var reloaded models.ShoppingItem
+	db.First(&reloaded, "id = ?", pending.ID)
+	if reloaded.SnoozedUntil == nil {
+		t.Error("Expected item with future snooze to stay snoozed")
+	}
+}
+
+func TestIsStale(t *testing.T) {
+	now := time.Now()
+	old := models.ShoppingItem{CreatedAt: now.AddDate(0, 0, -20)}
+	fresh := models.ShoppingItem{CreatedAt: now.AddDate(0, 0, -2)}
+	completed := models.ShoppingItem{CreatedAt: now.AddDate(0, 0, -20), Completed: true}
+
+	tests := []struct {
+		name  string
+		item  models.ShoppingItem
+		days  int
+		stale bool
+	}{
+		{"old open item", old, 14, true},
+		{"fresh item", fresh, 14, false},
+		{"completed item", completed, 14, false},
+		{"detection disabled", old, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsStale(tt.item, tt.days, now); got != tt.stale {
+				t.Errorf("Expected stale=%v, got %v", tt.stale, got)
+			}
+		})
+	}
+}
+
+func TestService_AttachCreators(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db, nil)
+
+	anna := models.User{ID: "anna", Email: "anna.berg@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	db.Create(&anna)
+	unknown := "unknown"
+	items := []models.ShoppingItem{
+		{Name: "Milk", CreatedBy: &anna.ID},
+		{Name: "Eggs", CreatedBy: &unknown},
+		{Name: "Bread"},
+	}
+
+	if err := service.AttachCreators(items); err != nil {
+		t.Fatalf("Failed to attach creators: %v", err)
+	}
+	if items[0].CreatedByName != "anna.berg" || items[1].CreatedByName != "" || items[2].CreatedByName != "" {
+		t.Errorf("Unexpected creator names: %q, %q, %q", items[0].CreatedByName, items[1].CreatedByName, items[2].CreatedByName)
+	}
+}
+
+func TestService_LastModified(t *testing.T) {
+	service := &Service{}
+	now := time.Now()
+	list := models.ShoppingList{UpdatedAt: now.AddDate(0, 0, -30), StaleAfterDays: 14}
+
+	updated := models.ShoppingItem{CreatedAt: now.AddDate(0, 0, -10), UpdatedAt: now.AddDate(0, 0, -5)}
+	if got := service.LastModified(list, []models.ShoppingItem{updated}, now); !got.Equal(updated.UpdatedAt) {
+		t.Errorf("Expected the latest item update, got %v", got)
+	}
+
+	woken := now.AddDate(0, 0, -1)
+	snoozed := models.ShoppingItem{CreatedAt: now.AddDate(0, 0, -10), UpdatedAt: now.AddDate(0, 0, -10), SnoozedUntil: &woken}
+	if got := service.LastModified(list, []models.ShoppingItem{updated, snoozed}, now); !got.Equal(woken) {
+		t.Errorf("Expected the end of the snooze, got %v", got)
+	}
+
+	stale := models.ShoppingItem{CreatedAt: now.AddDate(0, 0, -16), UpdatedAt: now.AddDate(0, 0, -16), Stale: true}
+	if got := service.LastModified(list, []models.ShoppingItem{stale}, now); !got.Equal(now.AddDate(0, 0, -2)) {
+		t.Errorf("Expected the time the item became stale, got %v", got)
+	}
+
+	if got := service.LastModified(list, nil, now); !got.Equal(list.UpdatedAt) {
+		t.Errorf("Expected the list update without items, got %v", got)
+	}
+}
+
+func TestService_SendStaleNudges(t *testing.T) {
+	testutils.SetupTestConfig(t)
+	defer testutils.CleanupTestEnv(t)
+
+	db := testutils.SetupTestDB(t)
+	service := NewService(db, nil)
+
+	staleList := models.ShoppingList{ID: "stale-list", Name: "Stale", OwnerID: "owner", StaleAfterDays: 7}
+	freshList := models.ShoppingList{ID: "fresh-list", Name: "Fresh", OwnerID: "owner", StaleAfterDays: 7}
+	db.Create(&staleList)
+	db.Create(&freshList)
+
+	old := createTestItem(t, service, "old-item", staleList.ID)
+	db.Model(&old).Update("created_at", time.Now().AddDate(0, 0, -10))
+	createTestItem(t, service, "new-item", freshList.ID)
+
+	nudged, err := service.SendStaleNudges()
+	if err != nil {
+		t.Fatalf("Failed to send stale nudges: %v", err)
+	}
+	if nudged != 1 {
+		t.Errorf("Expected 1 nudged list, got %d", nudged)
+	}
+
+	var reloaded models.ShoppingList
+	db.First(&reloaded, "id = ?", staleList.ID)
+	if reloaded.StaleNudgeSentAt == nil {
+		t.Error("Expected stale nudge time to be recorded")
+	}
+
+	// A second run within the nudge interval does not nudge again
+	nudged, err = service.SendStaleNudges()
+	if err != nil {
+		t.Fatalf("Failed to send stale nudges: %v", err)
+	}
+	if nudged != 0 {
+		t.Errorf("Expected no nudges within the interval, got %d", nudged)
+	}
+}
+
+func TestService_Find(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db, nil)
+
+	now := time.Now()
+	lists := []models.ShoppingList{
+		{ID: "home", Name: "Home", OwnerID: "user-1", StaleAfterDays: 14},
+		{ID: "work", Name: "Work", OwnerID: "user-1", StaleAfterDays: 0},
+		{ID: "foreign", Name: "Foreign", OwnerID: "user-2", StaleAfterDays: 14},
+	}
+	for i := range lists {
+		if err := db.Create(&lists[i]).Error; err != nil {
+			t.Fatalf("Failed to create list: %v", err)
+		}
+	}
+	for _, member := range []models.ListMember{
+		{ListID: "home", UserID: "user-1", Role: "owner", JoinedAt: now},
+		{ListID: "work", UserID: "user-1", Role: "owner", JoinedAt: now},
+		{ListID: "foreign", UserID: "user-2", Role: "owner", JoinedAt: now},
+	} {
+		if err := db.Create(&member).Error; err != nil {
+			t.Fatalf("Failed to create member: %v", err)
+		}
+	}
+	// Creating saves the column default of 14 days instead of zero
+	db.Model(&models.ShoppingList{}).Where("id = ?", "work").Update("stale_after_days", 0)
+
+	snoozedUntil := now.Add(24 * time.Hour)
+	items := []models.ShoppingItem{
+		{ID: "milk", ListID: "home", Name: "Milk", Tags: `["urgent"]`, Category: "dairy", CreatedAt: now},
+		{ID: "batteries", ListID: "home", Name: "Batteries", Tags: "[]", CreatedAt: now.AddDate(0, 0, -30)},
+		{ID: "toner", ListID: "work", Name: "Toner", Tags: `["urgent","office"]`, CreatedAt: now.AddDate(0, 0, -30)},
+		{ID: "paper", ListID: "work", Name: "Paper", Tags: "[]", Completed: true, CreatedAt: now},
+		{ID: "cheese", ListID: "home", Name: "Cheese", Tags: `["urgent"]`, Category: "dairy", SnoozedUntil: &snoozedUntil, CreatedAt: now},
+		{ID: "secret", ListID: "foreign", Name: "Secret", Tags: `["urgent"]`, CreatedAt: now},
+	}
+	for i := range items {
+		if err := db.Create(&items[i]).Error; err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+
+	ids := func(items []models.ShoppingItem) map[string]bool {
+		result := map[string]bool{}
+		for _, item := range items {
+			result[item.ID] = true
+		}
+		return result
+	}
+	yes, no := true, false
+
+	testCases := []struct {
+		name     string
+		filter   models.ItemFilter
+		expected []string
+	}{
+		{"everything visible", models.ItemFilter{}, []string{"milk", "batteries", "toner", "paper"}},
+		{"urgent across lists", models.ItemFilter{Tags: []string{"urgent"}, Completed: &no}, []string{"milk", "toner"}},
+		{"including snoozed", models.ItemFilter{Tags: []string{"urgent"}, IncludeSnoozed: true}, []string{"milk", "toner", "cheese"}},
+		{"category", models.ItemFilter{Categories: []string{"dairy"}}, []string{"milk"}},
+		{"list and query", models.ItemFilter{ListIDs: []string{"work"}, Query: "PAP"}, []string{"paper"}},
+		{"stale", models.ItemFilter{Stale: &yes}, []string{"batteries"}},
+		{"completed", models.ItemFilter{Completed: &yes}, []string{"paper"}},
+		{"no wildcard match", models.ItemFilter{Query: "%"}, []string{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			found, err := service.Find("user-1", tc.filter)
+			if err != nil {
+				t.Fatalf("Failed to find items: %v", err)
+			}
+
+			got := ids(found)
+			if len(got) != len(tc.expected) {
+				t.Fatalf("Expected %v, got %v", tc.expected, got)
+			}
+			for _, id := range tc.expected {
+				if !got[id] {
+					t.Errorf("Expected %s in %v", id, got)
+				}
+			}
+		})
+	}
+}
+
+func TestService_History(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db, nil)
+
+	now := time.Now()
+	archivedAt := now.AddDate(0, -1, 0)
+	for _, list := range []models.ShoppingList{
+		{ID: "home", Name: "Home", OwnerID: "user-1"},
+		{ID: "old", Name: "Old Home", OwnerID: "user-1", ArchivedAt: &archivedAt},
+		{ID: "foreign", Name: "Foreign", OwnerID: "user-2"},
+	} {
+		if err := db.Create(&list).Error; err != nil {
+			t.Fatalf("Failed to create list: %v", err)
+		}
+	}
+	for _, member := range []models.ListMember{
+		{ListID: "home", UserID: "user-1", Role: "owner", JoinedAt: now},
+		{ListID: "old", UserID: "user-1", Role: "owner", JoinedAt: now},
+		{ListID: "foreign", UserID: "user-2", Role: "owner", JoinedAt: now},
+	} {
+		if err := db.Create(&member).Error; err != nil {
+			t.Fatalf("Failed to create member: %v", err)
+		}
+	}
+
+	day := func(value string) *time.Time {
+		parsed, _ := time.ParseInLocation(DateLayout, value, time.Local)
+		parsed = parsed.Add(12 * time.Hour)
+		return &parsed
+	}
+	for _, item := range []models.ShoppingItem{
+		{ID: "detergent-1", ListID: "old", Name: "Detergent", Completed: true, CompletedAt: day("2025-01-10")},
+		{ID: "detergent-2", ListID: "home", Name: "Laundry detergent", Completed: true, CompletedAt: day("2025-03-05")},
+		{ID: "detergent-3", ListID: "home", Name: "Detergent", CompletedAt: nil},
+		{ID: "detergent-4", ListID: "foreign", Name: "Detergent", Completed: true, CompletedAt: day("2025-04-01")},
+		{ID: "milk", ListID: "home", Name: "Milk", Completed: true, CompletedAt: day("2025-03-31")},
+	} {
+		item.Tags = "[]"
+		if err := db.Create(&item).Error; err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+
+	history, err := service.History("user-1", models.HistoryFilter{Query: "detergent"})
+	if err != nil {
+		t.Fatalf("Failed to search history: %v", err)
+	}
+	if history.Total != 2 || len(history.Items) != 2 || history.Items[0].ID != "detergent-2" || history.Items[1].ID != "detergent-1" {
+		t.Fatalf("Expected the completed detergent of both own lists, newest first, got %+v", history)
+	}
+	if !history.LastCompletedAt.Equal(*day("2025-03-05")) {
+		t.Errorf("Expected the last purchase on 2025-03-05, got %v", history.LastCompletedAt)
+	}
+	if entry := history.Items[1]; entry.ListName != "Old Home" || !entry.ListArchived {
+		t.Errorf("Expected the item of the archived list, got %+v", entry)
+	}
+
+	for filter, expected := range map[models.HistoryFilter][]string{
+		{From: "2025-03-05"}:                   {"milk", "detergent-2"},
+		{To: "2025-03-05"}:                     {"detergent-2", "detergent-1"},
+		{From: "2025-02-01", To: "2025-03-31"}: {"milk", "detergent-2"},
+		{ListID: "old"}:                        {"detergent-1"},
+		{Limit: 1}:                             {"milk"},
+		{Query: "bread"}:                       {},
+	} {
+		history, err := service.History("user-1", filter)
+		if err != nil {
+			t.Fatalf("Failed to search history with %+v: %v", filter, err)
+		}
+		ids := []string{}
+		for _, entry := range history.Items {
+			ids = append(ids, entry.ID)
+		}
+		if strings.Join(ids, ",") != strings.Join(expected, ",") {
+			t.Errorf("Expected %v for %+v, got %v", expected, filter, ids)
+		}
+	}
+
+	if history, _ := service.History("user-1", models.HistoryFilter{Limit: 1}); history.Total != 3 {
+		t.Errorf("Expected the total of all matches, got %d", history.Total)
+	}
+	if history, _ := service.History("user-1", models.HistoryFilter{Query: "bread"}); history.LastCompletedAt != nil {
+		t.Errorf("Expected no last completion without matches, got %v", history.LastCompletedAt)
+	}
+	if _, err := service.History("user-1", models.HistoryFilter{From: "March"}); !errors.Is(err, ErrInvalidDate) {
+		t.Errorf("Expected ErrInvalidDate, got %v", err)
+	}
+}
+
+func TestService_AttachPurchaseStats(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db, nil)
+
+	first := time.Date(2025, 3, 1, 10, 0, 0, 0, time.Local)
+	day := func(days int) *time.Time {
+		bought := first.AddDate(0, 0, days)
+		return &bought
+	}
+	for _, item := range []models.ShoppingItem{
+		{ID: "milk-1", ListID: "home", Name: "Milk", Completed: true, CompletedAt: day(0)},
+		{ID: "milk-2", ListID: "home", Name: "milk", Completed: true, CompletedAt: day(13)},
+		{ID: "milk-3", ListID: "home", Name: "Milk", Completed: true, CompletedAt: day(24)},
+		{ID: "eggs", ListID: "home", Name: "Eggs", Completed: true, CompletedAt: day(20)},
+		{ID: "milk-other", ListID: "office", Name: "Milk", Completed: true, CompletedAt: day(30)},
+	} {
+		item.Tags = "[]"
+		if err := db.Create(&item).Error; err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+
+	items := []models.ShoppingItem{
+		{ID: "milk", ListID: "home", Name: "MILK"},
+		{ID: "eggs", ListID: "home", Name: "Eggs"},
+		{ID: "bread", ListID: "home", Name: "Bread"},
+		{ID: "cake", ListID: "party", Name: "Milk"},
+	}
+	if err := service.AttachPurchaseStats(items); err != nil {
+		t.Fatalf("Failed to attach purchase stats: %v", err)
+	}
+
+	if milk := items[0]; milk.LastPurchasedAt == nil || !milk.LastPurchasedAt.Equal(*day(24)) ||
+		milk.PurchaseIntervalDays == nil || *milk.PurchaseIntervalDays != 12 {
+		t.Errorf("Expected milk last bought on day 24 every 12 days, got %v and %v", milk.LastPurchasedAt, milk.PurchaseIntervalDays)
+	}
+	if eggs := items[1]; eggs.LastPurchasedAt == nil || eggs.PurchaseIntervalDays != nil {
+		t.Errorf("Expected eggs bought once without interval, got %v and %v", eggs.LastPurchasedAt, eggs.PurchaseIntervalDays)
+	}
+	for _, item := range items[2:] {
+		if item.LastPurchasedAt != nil || item.PurchaseIntervalDays != nil {
+			t.Errorf("Expected no purchases of %s on %s, got %+v", item.Name, item.ListID, item)
+		}
+	}
+}
+
+func TestService_FindDuplicates(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db, nil)
+
+	now := time.Now()
+	for _, list := range []models.ShoppingList{
+		{ID: "home", Name: "Home", OwnerID: "user-1"},
+		{ID: "foreign", Name: "Foreign", OwnerID: "user-2"},
+	} {
+		if err := db.Create(&list).Error; err != nil {
+			t.Fatalf("Failed to create list: %v", err)
+		}
+		member := models.ListMember{ListID: list.ID, UserID: list.OwnerID, Role: "owner", JoinedAt: now}
+		if err := db.Create(&member).Error; err != nil {
+			t.Fatalf("Failed to create member: %v", err)
+		}
+	}
+	for _, item := range []models.ShoppingItem{
+		{ID: "open", ListID: "home", Name: "Milk", Quantity: 2, Unit: "l", Tags: "[]", CreatedAt: now},
+		{ID: "done", ListID: "home", Name: "Milk", Completed: true, Tags: "[]", CreatedAt: now},
+		{ID: "foreign", ListID: "foreign", Name: "Milk", Tags: "[]", CreatedAt: now},
+	} {
+		if err := db.Create(&item).Error; err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+
+	duplicates, err := service.FindDuplicates("user-1", "milk")
+	if err != nil {
+		t.Fatalf("Failed to find duplicates: %v", err)
+	}
+	if len(duplicates) != 1 {
+		t.Fatalf("Expected 1 duplicate, got %+v", duplicates)
+	}
+	if duplicates[0].ItemID != "open" || duplicates[0].ListName != "Home" || duplicates[0].Quantity != 2 {
+		t.Errorf("Unexpected duplicate: %+v", duplicates[0])
+	}
+
+	if duplicates, _ := service.FindDuplicates("user-1", "Bread"); len(duplicates) != 0 {
+		t.Errorf("Expected no duplicates, got %+v", duplicates)
+	}
+}
+
+func TestGroupOpenByCategory(t *testing.T) {
+	groups, completed := GroupOpenByCategory([]models.ShoppingItem{
+		{Name: "milk", Category: "Dairy"},
+		{Name: "Batteries"},
+		{Name: "apples", Category: " Produce "},
+		{Name: "Butter", Category: "Dairy"},
+		{Name: "Bread", Category: "Bakery", Completed: true},
+	})
+
+	if completed != 1 {
+		t.Errorf("Expected 1 completed item, got %d", completed)
+	}
+	var categories []string
+	for _, group := range groups {
+		categories = append(categories, group.Category)
+	}
+	if strings.Join(categories, ",") != "Dairy,Produce,Other" {
+		t.Errorf("Expected categories in alphabetical order with Other last, got %v", categories)
+	}
+	if groups[0].Items[0].Name != "Butter" || groups[0].Items[1].Name != "milk" {
+		t.Errorf("Expected items sorted by name, got %+v", groups[0].Items)
+	}
+}
+
+func TestService_Deduplicate(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db, nil)
+
+	list := models.ShoppingList{ID: "dedupe-list", Name: "Dedupe", OwnerID: "owner"}
+	optedOut := models.ShoppingList{ID: "opted-out-list", Name: "Opted out", OwnerID: "owner", SkipDeduplication: true}
+	db.Create(&list)
+	db.Create(&optedOut)
+
+	now := time.Now()
+	items := []models.ShoppingItem{
+		{ID: "milk-1", ListID: list.ID, Name: "Milk", Quantity: 2, Unit: "l", Tags: `["dairy"]`, CreatedAt: now.Add(-time.Hour)},
+		{ID: "milk-2", ListID: list.ID, Name: "  milk ", Quantity: 1, Unit: "L", Tags: `["organic","Dairy"]`, CreatedAt: now},
+		{ID: "milk-3", ListID: list.ID, Name: "Milk", Quantity: 500, Unit: "ml", Tags: "[]", CreatedAt: now},
+		{ID: "milk-done", ListID: list.ID, Name: "Milk", Completed: true, Tags: "[]", CreatedAt: now},
+		{ID: "bread-1", ListID: list.ID, Name: "Bread", Tags: "[]", CreatedAt: now.Add(-time.Hour)},
+		{ID: "bread-2", ListID: list.ID, Name: "bread", Tags: "[]", CreatedAt: now},
+		{ID: "eggs-1", ListID: optedOut.ID, Name: "Eggs", Tags: "[]", CreatedAt: now},
+		{ID: "eggs-2", ListID: optedOut.ID, Name: "Eggs", Tags: "[]", CreatedAt: now},
+	}
+	for i := range items {
+		if err := db.Create(&items[i]).Error; err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+	db.Create(&models.Attachment{ID: "milk-photo", ListID: list.ID, ItemID: "milk-2", UserID: "owner"})
+
+	merged, err := service.DeduplicateAll()
+	if err != nil {
+		t.Fatalf("Failed to merge duplicates: %v", err)
+	}
+	if merged != 2 {
+		t.Errorf("Expected 2 merges, got %d", merged)
+	}
+
+	var milk models.ShoppingItem
+	db.First(&milk, "id = ?", "milk-1")
+	if milk.Quantity != 3.5 || milk.Unit != "l" {
+		t.Errorf("Expected merged quantity 3.5 l, got %v %s", milk.Quantity, milk.Unit)
+	}
+	if milk.Tags != `["dairy","organic"]` {
+		t.Errorf("Expected merged tags, got %s", milk.Tags)
+	}
+
+	var bread models.ShoppingItem
+	db.First(&bread, "id = ?", "bread-1")
+	if bread.Quantity != 0 {
+		t.Errorf("Expected items without quantity to stay without quantity, got %v", bread.Quantity)
+	}
+
+	var remaining []string
+	db.Model(&models.ShoppingItem{}).Order("id").Pluck("id", &remaining)
+	if strings.Join(remaining, ",") != "bread-1,eggs-1,eggs-2,milk-1,milk-done" {
+		t.Errorf("Unexpected remaining items: %v", remaining)
+	}
+
+	var attachment models.Attachment
+	db.First(&attachment, "id = ?", "milk-photo")
+	if attachment.ItemID != "milk-1" {
+		t.Errorf("Expected attachment to move to the kept item, got %s", attachment.ItemID)
+	}
+
+	var recorded int64
+	db.Model(&models.ItemMerge{}).Where("list_id = ?", list.ID).Count(&recorded)
+	if recorded != 2 {
+		t.Errorf("Expected 2 recorded merges, got %d", recorded)
+	}
+
+	// Requested merges also cover lists that opted out of the scheduled job
+	merges, err := service.Deduplicate(optedOut.ID, "owner")
+	if err != nil {
+		t.Fatalf("Failed to merge duplicates: %v", err)
+	}
+	if len(merges) != 1 || merges[0].ItemID != "eggs-1" || merges[0].Merged != 1 || merges[0].UserID != "owner" {
+		t.Errorf("Unexpected merges: %+v", merges)
+	}
+}
+
+func TestService_Trash(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db, nil)
+	item := createTestItem(t, service, "trash-item", "trash-list")
+	createTestItem(t, service, "kept-item", "trash-list")
+
+	if err := db.Delete(&item).Error; err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+	var open int64
+	db.Model(&models.ShoppingItem{}).Where("list_id = ?", "trash-list").Count(&open)
+	if open != 1 {
+		t.Errorf("Expected the deleted item to leave the list, got %d items", open)
+	}
+
+	trashed, err := service.Trash("trash-list")
+	if err != nil {
+		t.Fatalf("Failed to get trash: %v", err)
+	}
+	if len(trashed) != 1 || trashed[0].ID != item.ID || !trashed[0].DeletedAt.Valid {
+		t.Fatalf("Expected the deleted item in the trash, got %+v", trashed)
+	}
+
+	if _, err := service.Restore("other-list", item.ID); err == nil {
+		t.Error("Expected restoring into another list to fail")
+	}
+	if _, err := service.Restore("trash-list", "kept-item"); err == nil {
+		t.Error("Expected restoring an item that is not in the trash to fail")
+	}
+
+	restored, err := service.Restore("trash-list", item.ID)
+	if err != nil {
+		t.Fatalf("Failed to restore item: %v", err)
+	}
+	if restored.DeletedAt.Valid {
+		t.Error("Expected the restored item to no longer be deleted")
+	}
+	db.Model(&models.ShoppingItem{}).Where("list_id = ?", "trash-list").Count(&open)
+	if open != 2 {
+		t.Errorf("Expected the restored item back on the list, got %d items", open)
+	}
+	if trashed, _ := service.Trash("trash-list"); len(trashed) != 0 {
+		t.Errorf("Expected an empty trash, got %d items", len(trashed))
+	}
+}
@@ -0,0 +1,47 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package items
+
+import (
+	"errors"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+)
+
+// TrashDays is the number of days deleted items stay in the trash of their list before they
+// are purged for good.
+const TrashDays = 30
+
+// Trash returns the deleted items of a list that can still be restored, most recently deleted
+// first.
+func (s *Service) Trash(listID string) ([]models.ShoppingItem, error) {
+	trashed := []models.ShoppingItem{}
+	err := s.DB.Unscoped().
+		Where("list_id = ? AND deleted_at IS NOT NULL", listID).
+		Order("deleted_at DESC").
+		Find(&trashed).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := s.AttachCreators(trashed); err != nil {
+		return nil, err
+	}
+	return trashed, nil
+}
+
+// Restore moves a deleted item of a list out of the trash and back onto the list.
+func (s *Service) Restore(listID, itemID string) (*models.ShoppingItem, error) {
+	var item models.ShoppingItem
+	err := s.DB.Unscoped().
+		Where("id = ? AND list_id = ? AND deleted_at IS NOT NULL", itemID, listID).
+		First(&item).Error
+	if err != nil {
+		return nil, errors.New("item not found in trash")
+	}
+
+	if err := s.DB.Unscoped().Model(&item).Update("deleted_at", nil).Error; err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
@@ -0,0 +1,89 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package kiosk renders a list as a minimal, auto-refreshing HTML page for wall-mounted tablets
+// and e-ink displays.
+package kiosk
+
+import (
+	"embed"
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/items"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/quantity"
+)
+
+//go:embed templates/kiosk.html
+var templates embed.FS
+
+var page = template.Must(template.ParseFS(templates, "templates/kiosk.html"))
+
+// DefaultRefresh is how often the page reloads itself unless configured otherwise.
+const DefaultRefresh = time.Minute
+
+// Renderer renders kiosk pages.
+type Renderer struct {
+	// Refresh is how often the page reloads itself.
+	Refresh time.Duration
+}
+
+// NewRenderer creates a kiosk renderer with the default refresh interval.
+func NewRenderer() *Renderer {
+	return &Renderer{Refresh: DefaultRefresh}
+}
+
+// Item is an open item as shown on the page.
+type Item struct {
+	Name     string
+	Emoji    string
+	Quantity string
+}
+
+// Group holds the open items of one category.
+type Group struct {
+	Category string
+	Items    []Item
+}
+
+// Page is the data the kiosk template is rendered with.
+type Page struct {
+	Title          string
+	RefreshSeconds int
+	Groups         []Group
+	Completed      int
+	UpdatedAt      string
+}
+
+// NewPage builds the page of a list: open items grouped by category and the number of completed
+// items.
+func (r *Renderer) NewPage(display models.DisplayResponse, now time.Time) Page {
+	p := Page{
+		Title:          display.List.Name,
+		RefreshSeconds: max(int(r.Refresh.Seconds()), 1),
+		UpdatedAt:      now.Format("15:04"),
+	}
+
+	groups, completed := items.GroupOpenByCategory(display.Items)
+	p.Completed = completed
+	for _, group := range groups {
+		g := Group{Category: group.Category}
+		for _, item := range group.Items {
+			g.Items = append(g.Items, Item{
+				Name:     item.Name,
+				Emoji:    item.Emoji,
+				Quantity: quantity.Format(item.Quantity, item.Unit),
+			})
+		}
+		p.Groups = append(p.Groups, g)
+	}
+
+	return p
+}
+
+// Render writes the page of a list as HTML.
+func (r *Renderer) Render(w io.Writer, display models.DisplayResponse) error {
+	return page.Execute(w, r.NewPage(display, time.Now()))
+}
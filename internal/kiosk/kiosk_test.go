@@ -0,0 +1,77 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package kiosk
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+)
+
+func testDisplay() models.DisplayResponse {
+	return models.DisplayResponse{
+		List: models.ShoppingList{Name: "Kitchen"},
+		Items: []models.ShoppingItem{
+			{Name: "milk", Category: "Dairy", Quantity: 2, Unit: "l"},
+			{Name: "Batteries"},
+			{Name: "apples", Category: "Produce", Emoji: "🍎"},
+			{Name: "Butter", Category: "Dairy"},
+			{Name: "Bread", Category: "Bakery", Completed: true},
+		},
+	}
+}
+
+func TestRenderer_NewPage(t *testing.T) {
+	renderer := &Renderer{Refresh: 30 * time.Second}
+	page := renderer.NewPage(testDisplay(), time.Date(2025, 3, 1, 8, 5, 0, 0, time.UTC))
+
+	if page.Title != "Kitchen" || page.RefreshSeconds != 30 || page.Completed != 1 || page.UpdatedAt != "08:05" {
+		t.Errorf("Unexpected page: %+v", page)
+	}
+
+	var categories []string
+	for _, group := range page.Groups {
+		categories = append(categories, group.Category)
+	}
+	if strings.Join(categories, ",") != "Dairy,Produce,Other" {
+		t.Errorf("Expected categories in alphabetical order with Other last, got %v", categories)
+	}
+
+	dairy := page.Groups[0].Items
+	if len(dairy) != 2 || dairy[0].Name != "Butter" || dairy[1].Quantity != "2 l" {
+		t.Errorf("Expected dairy items sorted by name with quantity, got %+v", dairy)
+	}
+}
+
+func TestRenderer_Render(t *testing.T) {
+	display := testDisplay()
+	display.List.Name = "<Kitchen>"
+
+	var buf bytes.Buffer
+	if err := NewRenderer().Render(&buf, display); err != nil {
+		t.Fatalf("Failed to render page: %v", err)
+	}
+
+	html := buf.String()
+	if !strings.Contains(html, `<meta http-equiv="refresh" content="60">`) {
+		t.Error("Expected page to refresh itself")
+	}
+	if strings.Contains(html, "<Kitchen>") || !strings.Contains(html, "&lt;Kitchen&gt;") {
+		t.Error("Expected list name to be escaped")
+	}
+	if !strings.Contains(html, "<h2>Dairy</h2>") || strings.Contains(html, "Bread") {
+		t.Errorf("Expected open items grouped by category, got %s", html)
+	}
+
+	buf.Reset()
+	if err := NewRenderer().Render(&buf, models.DisplayResponse{}); err != nil {
+		t.Fatalf("Failed to render empty page: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Nothing to buy.") {
+		t.Error("Expected empty list message")
+	}
+}
@@ -0,0 +1,109 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package lists
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oliverandrich/shopping-list-server/internal/codes"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+)
+
+// DisplayTokenHeader is the request header carrying a display token. Displays that cannot set
+// headers pass the token in the "token" query parameter instead.
+const DisplayTokenHeader = "X-Display-Token"
+
+// displayTokenPrefix marks display tokens so they are recognizable in logs and secret scanners.
+const displayTokenPrefix = "sld_"
+
+// ErrDisplayTokenNotFound is returned when a display token does not exist or belongs to another list.
+var ErrDisplayTokenNotFound = errors.New("display token not found")
+
+// ErrInvalidDisplayToken is returned when a display token is unknown or has been revoked.
+var ErrInvalidDisplayToken = errors.New("invalid display token")
+
+// hashDisplayToken returns the hex-encoded SHA-256 hash under which a display token is stored.
+func hashDisplayToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateDisplayToken creates a read-only token for the list and returns it together with the
+// plaintext token. Only list owners can create display tokens.
+func (s *Service) CreateDisplayToken(listID, userID, name string) (*models.DisplayToken, string, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, "", errors.New("display token name cannot be empty")
+	}
+
+	if !s.IsListOwner(listID, userID) {
+		return nil, "", errors.New("only list owners can manage display tokens")
+	}
+
+	token := displayTokenPrefix + codes.Hex(24)
+
+	displayToken := models.DisplayToken{
+		ID:        uuid.New().String(),
+		ListID:    listID,
+		CreatedBy: userID,
+		Name:      strings.TrimSpace(name),
+		Prefix:    token[:len(displayTokenPrefix)+6],
+		TokenHash: hashDisplayToken(token),
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.DB.Create(&displayToken).Error; err != nil {
+		return nil, "", err
+	}
+
+	return &displayToken, token, nil
+}
+
+// GetDisplayTokens retrieves the display tokens of the list if the user is an owner.
+func (s *Service) GetDisplayTokens(listID, userID string) ([]models.DisplayToken, error) {
+	if !s.IsListOwner(listID, userID) {
+		return nil, errors.New("only list owners can manage display tokens")
+	}
+
+	var tokens []models.DisplayToken
+	err := s.DB.Where("list_id = ?", listID).Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// RevokeDisplayToken deletes a display token of the list if the user is an owner.
+func (s *Service) RevokeDisplayToken(listID, tokenID, userID string) error {
+	if !s.IsListOwner(listID, userID) {
+		return errors.New("only list owners can manage display tokens")
+	}
+
+	result := s.DB.Where("id = ? AND list_id = ?", tokenID, listID).Delete(&models.DisplayToken{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDisplayTokenNotFound
+	}
+	return nil
+}
+
+// ValidateDisplayToken looks up the display token and records its usage.
+func (s *Service) ValidateDisplayToken(token string) (*models.DisplayToken, error) {
+	if !strings.HasPrefix(token, displayTokenPrefix) {
+		return nil, ErrInvalidDisplayToken
+	}
+
+	var displayToken models.DisplayToken
+	if err := s.DB.Where("token_hash = ?", hashDisplayToken(token)).First(&displayToken).Error; err != nil {
+		return nil, ErrInvalidDisplayToken
+	}
+
+	now := time.Now()
+	s.DB.Model(&displayToken).Update("last_used_at", &now)
+
+	return &displayToken, nil
+}
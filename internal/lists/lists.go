@@ -7,6 +7,7 @@ package lists
 
 import (
 	"errors"
+	"sort"
 	"strings"
 	"time"
 
@@ -25,15 +26,75 @@ func NewService(db *gorm.DB) *Service {
 	return &Service{DB: db}
 }
 
-// GetUserLists retrieves all shopping lists accessible to the given user.
+// GetUserLists retrieves all shopping lists accessible to the given user, except archived ones.
 func (s *Service) GetUserLists(userID string) ([]models.ShoppingList, error) {
+	return s.userLists(userID, "shopping_lists.archived_at IS NULL")
+}
+
+// GetArchivedLists retrieves the archived shopping lists accessible to the given user.
+func (s *Service) GetArchivedLists(userID string) ([]models.ShoppingList, error) {
+	return s.userLists(userID, "shopping_lists.archived_at IS NOT NULL")
+}
+
+// userLists retrieves the shopping lists accessible to the given user that match the condition.
+func (s *Service) userLists(userID, condition string) ([]models.ShoppingList, error) {
 	var lists []models.ShoppingList
 	err := s.DB.Joins("JOIN list_members ON shopping_lists.id = list_members.list_id").
 		Where("list_members.user_id = ?", userID).
+		Where(condition).
 		Preload("Owner").
 		Order("shopping_lists.created_at DESC").
 		Find(&lists).Error
-	return lists, err
+	if err != nil {
+		return nil, err
+	}
+
+	unseen, err := s.countUnseenChanges(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range lists {
+		lists[i].UnseenChanges = unseen[lists[i].ID]
+	}
+	return lists, nil
+}
+
+// countUnseenChanges counts per list the items added since the user last viewed it.
+// Members who never viewed a list count changes since they joined.
+func (s *Service) countUnseenChanges(userID string) (map[string]int, error) {
+	var rows []struct {
+		ListID string
+		Count  int
+	}
+	err := s.DB.Model(&models.ShoppingItem{}).
+		Select("shopping_items.list_id AS list_id, COUNT(*) AS count").
+		Joins("JOIN list_members ON list_members.list_id = shopping_items.list_id AND list_members.user_id = ?", userID).
+		Where("shopping_items.created_at > COALESCE(list_members.last_seen_at, list_members.joined_at)").
+		Group("shopping_items.list_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.ListID] = row.Count
+	}
+	return counts, nil
+}
+
+// MarkSeen records that the user has seen the current state of the list.
+func (s *Service) MarkSeen(listID, userID string) error {
+	result := s.DB.Model(&models.ListMember{}).
+		Where("list_id = ? AND user_id = ?", listID, userID).
+		Update("last_seen_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("list not found or access denied")
+	}
+	return nil
 }
 
 // GetListByID retrieves a specific shopping list if the user has access to it.
@@ -49,6 +110,175 @@ func (s *Service) GetListByID(listID, userID string) (*models.ShoppingList, erro
 	return &list, nil
 }
 
+// GetListsByIDs retrieves the lists with the given IDs that are accessible to the user, in the
+// order of ids, including archived ones. It takes the same number of queries for any number of lists.
+func (s *Service) GetListsByIDs(userID string, ids []string) ([]models.ShoppingList, error) {
+	var found []models.ShoppingList
+	err := s.DB.Joins("JOIN list_members ON shopping_lists.id = list_members.list_id").
+		Where("list_members.user_id = ? AND shopping_lists.id IN ?", userID, ids).
+		Preload("Owner").
+		Find(&found).Error
+	if err != nil {
+		return nil, err
+	}
+
+	unseen, err := s.countUnseenChanges(userID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]models.ShoppingList, len(found))
+	for _, list := range found {
+		list.UnseenChanges = unseen[list.ID]
+		byID[list.ID] = list
+	}
+
+	lists := make([]models.ShoppingList, 0, len(found))
+	for _, id := range ids {
+		if list, ok := byID[id]; ok {
+			lists = append(lists, list)
+			delete(byID, id)
+		}
+	}
+	return lists, nil
+}
+
+// FindListByName retrieves a list accessible to the user by its case-insensitive name or alias.
+func (s *Service) FindListByName(userID, name string) (*models.ShoppingList, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("list name cannot be empty")
+	}
+
+	var list models.ShoppingList
+	err := s.DB.Joins("JOIN list_members ON shopping_lists.id = list_members.list_id").
+		Where("list_members.user_id = ? AND LOWER(shopping_lists.name) = LOWER(?)", userID, name).
+		Where("shopping_lists.archived_at IS NULL").
+		Preload("Owner").
+		Order("shopping_lists.created_at ASC").
+		First(&list).Error
+	if err == nil {
+		return &list, nil
+	}
+
+	// Fall back to list aliases
+	err = s.DB.Joins("JOIN list_members ON shopping_lists.id = list_members.list_id").
+		Joins("JOIN list_aliases ON shopping_lists.id = list_aliases.list_id").
+		Where("list_members.user_id = ? AND LOWER(list_aliases.alias) = LOWER(?)", userID, name).
+		Where("shopping_lists.archived_at IS NULL").
+		Preload("Owner").
+		Order("shopping_lists.created_at ASC").
+		First(&list).Error
+	if err != nil {
+		return nil, errors.New("list not found or access denied")
+	}
+	return &list, nil
+}
+
+// GetListAliases retrieves all aliases of a shopping list if the user has access.
+func (s *Service) GetListAliases(listID, userID string) ([]models.ListAlias, error) {
+	if !s.HasListAccess(listID, userID) {
+		return nil, errors.New("access denied")
+	}
+
+	var aliases []models.ListAlias
+	err := s.DB.Where("list_id = ?", listID).Order("alias ASC").Find(&aliases).Error
+	return aliases, err
+}
+
+// GetListNote retrieves the user's private note on a shopping list. A user without a note gets
+// an empty one.
+func (s *Service) GetListNote(listID, userID string) (*models.ListNote, error) {
+	if !s.HasListAccess(listID, userID) {
+		return nil, errors.New("access denied")
+	}
+
+	note := models.ListNote{ListID: listID, UserID: userID}
+	err := s.DB.Where("list_id = ? AND user_id = ?", listID, userID).First(&note).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// SetListNote replaces the user's private note on a shopping list. An empty note deletes it.
+func (s *Service) SetListNote(listID, userID, text string) (*models.ListNote, error) {
+	if !s.HasListAccess(listID, userID) {
+		return nil, errors.New("access denied")
+	}
+
+	note := models.ListNote{ListID: listID, UserID: userID, Note: strings.TrimSpace(text)}
+	if note.Note == "" {
+		err := s.DB.Where("list_id = ? AND user_id = ?", listID, userID).Delete(&models.ListNote{}).Error
+		return &note, err
+	}
+
+	if err := s.DB.Save(&note).Error; err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// AddListAlias adds an alternative name to a shopping list if the user is the owner.
+func (s *Service) AddListAlias(listID, userID, alias string) (*models.ListAlias, error) {
+	alias = strings.Join(strings.Fields(alias), " ")
+	if alias == "" {
+		return nil, errors.New("alias cannot be empty")
+	}
+
+	if !s.IsListOwner(listID, userID) {
+		return nil, errors.New("only list owners can add aliases")
+	}
+
+	var existing models.ListAlias
+	err := s.DB.Where("list_id = ? AND LOWER(alias) = LOWER(?)", listID, alias).First(&existing).Error
+	if err == nil {
+		return nil, errors.New("alias already exists for this list")
+	}
+
+	listAlias := models.ListAlias{
+		ListID:    listID,
+		Alias:     alias,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.DB.Create(&listAlias).Error; err != nil {
+		return nil, err
+	}
+
+	return &listAlias, nil
+}
+
+// RemoveListAlias removes an alias from a shopping list if the user is the owner.
+func (s *Service) RemoveListAlias(listID, userID, alias string) error {
+	if !s.IsListOwner(listID, userID) {
+		return errors.New("only list owners can remove aliases")
+	}
+
+	result := s.DB.Where("list_id = ? AND LOWER(alias) = LOWER(?)", listID, alias).Delete(&models.ListAlias{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("alias not found")
+	}
+
+	return nil
+}
+
+// GetDefaultList retrieves the list the user joined first, which is their default list.
+func (s *Service) GetDefaultList(userID string) (*models.ShoppingList, error) {
+	var list models.ShoppingList
+	err := s.DB.Joins("JOIN list_members ON shopping_lists.id = list_members.list_id").
+		Where("list_members.user_id = ? AND shopping_lists.archived_at IS NULL", userID).
+		Preload("Owner").
+		Order("list_members.joined_at ASC").
+		First(&list).Error
+	if err != nil {
+		return nil, errors.New("no list found")
+	}
+	return &list, nil
+}
+
 // CreateList creates a new shopping list with the user as owner and adds them as a member.
 func (s *Service) CreateList(userID, name string) (*models.ShoppingList, error) {
 	// Validate inputs
@@ -81,7 +311,7 @@ func (s *Service) CreateList(userID, name string) (*models.ShoppingList, error)
 	member := models.ListMember{
 		ListID:   list.ID,
 		UserID:   userID,
-		Role:     "owner",
+		Role:     RoleOwner,
 		JoinedAt: time.Now(),
 	}
 
@@ -129,6 +359,52 @@ func (s *Service) UpdateList(listID, userID, name string) (*models.ShoppingList,
 	return &list, nil
 }
 
+// SetStaleAfterDays sets after how many days open items of the list are considered stale.
+// Zero disables stale detection for the list.
+func (s *Service) SetStaleAfterDays(listID, userID string, days int) (*models.ShoppingList, error) {
+	if days < 0 {
+		return nil, errors.New("stale after days cannot be negative")
+	}
+
+	if !s.IsListOwner(listID, userID) {
+		return nil, errors.New("only list owners can update lists")
+	}
+
+	var list models.ShoppingList
+	if err := s.DB.First(&list, "id = ?", listID).Error; err != nil {
+		return nil, errors.New("list not found")
+	}
+
+	list.StaleAfterDays = days
+	list.UpdatedAt = time.Now()
+
+	if err := s.DB.Save(&list).Error; err != nil {
+		return nil, err
+	}
+
+	s.DB.Preload("Owner").First(&list, "id = ?", list.ID)
+	return &list, nil
+}
+
+// SetSkipDeduplication sets whether the scheduled merge of duplicate items skips the list.
+func (s *Service) SetSkipDeduplication(listID, userID string, skip bool) (*models.ShoppingList, error) {
+	if !s.IsListOwner(listID, userID) {
+		return nil, errors.New("only list owners can update lists")
+	}
+
+	var list models.ShoppingList
+	if err := s.DB.First(&list, "id = ?", listID).Error; err != nil {
+		return nil, errors.New("list not found")
+	}
+
+	if err := s.DB.Model(&list).Update("skip_deduplication", skip).Error; err != nil {
+		return nil, err
+	}
+
+	s.DB.Preload("Owner").First(&list, "id = ?", list.ID)
+	return &list, nil
+}
+
 // DeleteList deletes a shopping list if the user is the owner.
 func (s *Service) DeleteList(listID, userID string) error {
 	// Validate inputs
@@ -139,16 +415,48 @@ func (s *Service) DeleteList(listID, userID string) error {
 		return errors.New("user ID cannot be empty")
 	}
 
-	// Check if user is owner
+	// Check if user is owner, or a co-owner if the list allows it
 	if !s.IsListOwner(listID, userID) {
 		return errors.New("only list owners can delete lists")
 	}
+	if !s.CanDeleteList(listID, userID) {
+		return errors.New("co-owners are not allowed to delete this list")
+	}
 
 	// Delete list members
 	s.DB.Where("list_id = ?", listID).Delete(&models.ListMember{})
 
-	// Delete list items
-	s.DB.Where("list_id = ?", listID).Delete(&models.ShoppingItem{})
+	// Delete list items including the trash, their merge history, purchases and replenishment
+	// suggestions
+	s.DB.Unscoped().Where("list_id = ?", listID).Delete(&models.ShoppingItem{})
+	s.DB.Where("list_id = ?", listID).Delete(&models.ItemMerge{})
+	s.DB.Where("list_id = ?", listID).Delete(&models.Purchase{})
+	s.DB.Where("list_id = ?", listID).Delete(&models.ReplenishSuggestion{})
+
+	// Delete list aliases
+	s.DB.Where("list_id = ?", listID).Delete(&models.ListAlias{})
+
+	// Delete list sections and tag metadata
+	s.DB.Where("list_id = ?", listID).Delete(&models.ListSection{})
+	s.DB.Where("list_id = ?", listID).Delete(&models.ListTag{})
+
+	// Revoke display tokens and remove the enrichment hook and rules
+	s.DB.Where("list_id = ?", listID).Delete(&models.DisplayToken{})
+	s.DB.Where("list_id = ?", listID).Delete(&models.EnrichmentHook{})
+	s.DB.Where("list_id = ?", listID).Delete(&models.ListRule{})
+
+	// Delete the members' private notes
+	s.DB.Where("list_id = ?", listID).Delete(&models.ListNote{})
+
+	// Delete planned trips and their RSVPs
+	s.DB.Where("trip_id IN (?)", s.DB.Model(&models.ShoppingTrip{}).Select("id").Where("list_id = ?", listID)).
+		Delete(&models.TripRSVP{})
+	s.DB.Where("list_id = ?", listID).Delete(&models.ShoppingTrip{})
+
+	// Delete item polls and their votes
+	s.DB.Where("poll_id IN (?)", s.DB.Model(&models.ItemPoll{}).Select("id").Where("list_id = ?", listID)).
+		Delete(&models.PollVote{})
+	s.DB.Where("list_id = ?", listID).Delete(&models.ItemPoll{})
 
 	// Delete the list
 	result := s.DB.Delete(&models.ShoppingList{}, "id = ?", listID)
@@ -162,6 +470,65 @@ func (s *Service) DeleteList(listID, userID string) error {
 	return nil
 }
 
+// GetContacts returns the users who share at least one list with the given user, those sharing
+// the most lists first. Users who opted out of contact books are left out.
+func (s *Service) GetContacts(userID string) ([]models.Contact, error) {
+	if strings.TrimSpace(userID) == "" {
+		return nil, errors.New("user ID cannot be empty")
+	}
+
+	var shared []struct {
+		UserID      string
+		SharedLists int
+	}
+	err := s.DB.Table("list_members AS other").
+		Select("other.user_id AS user_id, COUNT(DISTINCT other.list_id) AS shared_lists").
+		Joins("JOIN list_members AS mine ON mine.list_id = other.list_id AND mine.user_id = ?", userID).
+		Joins("JOIN users ON users.id = other.user_id").
+		Where("other.user_id <> ? AND users.hide_from_contacts = ?", userID, false).
+		Group("other.user_id").
+		Scan(&shared).Error
+	if err != nil {
+		return nil, err
+	}
+
+	contacts := []models.Contact{}
+	if len(shared) == 0 {
+		return contacts, nil
+	}
+
+	ids := make([]string, len(shared))
+	for i, row := range shared {
+		ids[i] = row.UserID
+	}
+
+	// Load the users through the model so encrypted emails are decrypted
+	var users []models.User
+	if err := s.DB.Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	emails := make(map[string]string, len(users))
+	for _, user := range users {
+		emails[user.ID] = user.Email
+	}
+
+	for _, row := range shared {
+		contacts = append(contacts, models.Contact{
+			UserID:      row.UserID,
+			Email:       emails[row.UserID],
+			SharedLists: row.SharedLists,
+		})
+	}
+	sort.Slice(contacts, func(i, j int) bool {
+		if contacts[i].SharedLists != contacts[j].SharedLists {
+			return contacts[i].SharedLists > contacts[j].SharedLists
+		}
+		return contacts[i].Email < contacts[j].Email
+	})
+
+	return contacts, nil
+}
+
 // GetListMembers retrieves all members of a shopping list if the user has access.
 func (s *Service) GetListMembers(listID, userID string) ([]models.User, error) {
 	// Validate inputs
@@ -181,7 +548,22 @@ func (s *Service) GetListMembers(listID, userID string) ([]models.User, error) {
 	err := s.DB.Joins("JOIN list_members ON users.id = list_members.user_id").
 		Where("list_members.list_id = ?", listID).
 		Find(&users).Error
-	return users, err
+	if err != nil {
+		return nil, err
+	}
+
+	var members []models.ListMember
+	if err := s.DB.Where("list_id = ?", listID).Find(&members).Error; err != nil {
+		return nil, err
+	}
+	roles := make(map[string]string, len(members))
+	for _, member := range members {
+		roles[member.UserID] = member.Role
+	}
+	for i := range users {
+		users[i].Role = roles[users[i].ID]
+	}
+	return users, nil
 }
 
 // AddMemberToList adds a new member to a shopping list if the user is the owner.
@@ -213,13 +595,30 @@ func (s *Service) AddMemberToList(listID, userID, newMemberID string) error {
 	member := models.ListMember{
 		ListID:   listID,
 		UserID:   newMemberID,
-		Role:     "member",
+		Role:     RoleEditor,
 		JoinedAt: time.Now(),
 	}
 
 	return s.DB.Create(&member).Error
 }
 
+// JoinList adds a user as member of an existing list, e.g. when accepting an invitation. Joining a
+// list the user is already a member of succeeds without changes, so retries are safe.
+func (s *Service) JoinList(listID, userID string) error {
+	var list models.ShoppingList
+	if err := s.DB.First(&list, "id = ?", listID).Error; err != nil {
+		return errors.New("list not found")
+	}
+
+	member := models.ListMember{
+		ListID:   listID,
+		UserID:   userID,
+		Role:     RoleEditor,
+		JoinedAt: time.Now(),
+	}
+	return s.DB.Where("list_id = ? AND user_id = ?", listID, userID).FirstOrCreate(&member).Error
+}
+
 // RemoveMemberFromList removes a member from a shopping list if the user is the owner.
 func (s *Service) RemoveMemberFromList(listID, userID, memberID string) error {
 	// Validate inputs
@@ -238,30 +637,33 @@ func (s *Service) RemoveMemberFromList(listID, userID, memberID string) error {
 		return errors.New("access denied")
 	}
 
-	// Don't allow owner to remove themselves if they're the only owner
-	if userID == memberID && s.IsListOwner(listID, userID) {
-		var ownerCount int64
-		s.DB.Model(&models.ListMember{}).Where("list_id = ? AND role = ?", listID, "owner").Count(&ownerCount)
-		if ownerCount <= 1 {
-			return errors.New("cannot remove the last owner from the list")
-		}
+	var member models.ListMember
+	if err := s.DB.Where("list_id = ? AND user_id = ?", listID, memberID).First(&member).Error; err != nil {
+		return ErrMemberNotFound
 	}
 
-	result := s.DB.Where("list_id = ? AND user_id = ?", listID, memberID).Delete(&models.ListMember{})
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return errors.New("member not found")
+	if member.Role == RoleOwner {
+		// Co-owners can't remove the owner, and the owner can only leave if a co-owner takes over
+		if userID != memberID {
+			return errors.New("the list owner cannot be removed")
+		}
+		if err := s.handOverOwnership(listID, memberID); err != nil {
+			return err
+		}
+	} else {
+		if err := s.DB.Where("list_id = ? AND user_id = ?", listID, memberID).Delete(&models.ListMember{}).Error; err != nil {
+			return err
+		}
 	}
 
-	return nil
+	// The removed member's private note is of no use anymore
+	return s.DB.Where("list_id = ? AND user_id = ?", listID, memberID).Delete(&models.ListNote{}).Error
 }
 
-// IsListOwner checks if the given user is the owner of the specified list.
+// IsListOwner checks if the given user is the owner or a co-owner of the specified list.
 func (s *Service) IsListOwner(listID, userID string) bool {
 	var member models.ListMember
-	err := s.DB.Where("list_id = ? AND user_id = ? AND role = ?", listID, userID, "owner").First(&member).Error
+	err := s.DB.Where("list_id = ? AND user_id = ? AND role IN ?", listID, userID, OwnerRoles).First(&member).Error
 	return err == nil
 }
 
@@ -272,7 +674,7 @@ func (s *Service) HasListAccess(listID, userID string) bool {
 	return err == nil
 }
 
-// CreateDefaultListForUser creates a default shopping list for a new user.
-func (s *Service) CreateDefaultListForUser(userID string) (*models.ShoppingList, error) {
-	return s.CreateList(userID, "My Shopping List")
+// CreateDefaultListForUser creates a default shopping list with the given name for a new user.
+func (s *Service) CreateDefaultListForUser(userID, name string) (*models.ShoppingList, error) {
+	return s.CreateList(userID, name)
 }
@@ -4,6 +4,8 @@
 package lists
 
 import (
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -189,6 +191,68 @@ func TestService_GetUserLists(t *testing.T) {
 	})
 }
 
+func TestService_UnseenChanges(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+
+	owner := models.User{ID: "unseen-owner", Email: testutils.TestEmailAddress(), JoinedAt: time.Now(), CreatedAt: time.Now()}
+	member := models.User{ID: "unseen-member", Email: "unseen-member@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	db.Create(&owner)
+	db.Create(&member)
+
+	list, err := service.CreateList(owner.ID, "Shared List")
+	if err != nil {
+		t.Fatalf("Failed to create test list: %v", err)
+	}
+	if err := service.AddMemberToList(list.ID, owner.ID, member.ID); err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+
+	// Joined an hour ago, so the new items count as unseen until the list is marked seen
+	db.Model(&models.ListMember{}).Where("list_id = ?", list.ID).Update("joined_at", time.Now().Add(-time.Hour))
+
+	for _, id := range []string{"unseen-item-1", "unseen-item-2"} {
+		item := models.ShoppingItem{ID: id, ListID: list.ID, Name: "Item", Tags: "[]", CreatedAt: time.Now()}
+		db.Create(&item)
+	}
+
+	unseenFor := func(t *testing.T, userID string) int {
+		t.Helper()
+		lists, err := service.GetUserLists(userID)
+		if err != nil {
+			t.Fatalf("Failed to get lists: %v", err)
+		}
+		if len(lists) != 1 {
+			t.Fatalf("Expected 1 list, got %d", len(lists))
+		}
+		return lists[0].UnseenChanges
+	}
+
+	t.Run("count items added since joining", func(t *testing.T) {
+		if n := unseenFor(t, member.ID); n != 2 {
+			t.Errorf("Expected 2 unseen changes, got %d", n)
+		}
+	})
+
+	t.Run("mark seen resets count", func(t *testing.T) {
+		if err := service.MarkSeen(list.ID, member.ID); err != nil {
+			t.Fatalf("Failed to mark list as seen: %v", err)
+		}
+		if n := unseenFor(t, member.ID); n != 0 {
+			t.Errorf("Expected 0 unseen changes, got %d", n)
+		}
+		if n := unseenFor(t, owner.ID); n != 2 {
+			t.Errorf("Expected owner to still have 2 unseen changes, got %d", n)
+		}
+	})
+
+	t.Run("mark seen without membership", func(t *testing.T) {
+		if err := service.MarkSeen(list.ID, "stranger"); err == nil {
+			t.Error("Expected error when marking list seen without membership")
+		}
+	})
+}
+
 func TestService_GetListByID(t *testing.T) {
 	db := testutils.SetupTestDB(t)
 	service := NewService(db)
@@ -302,6 +366,28 @@ func TestService_UpdateList(t *testing.T) {
 			t.Error("Expected error when updating non-existent list")
 		}
 	})
+
+	t.Run("default stale threshold", func(t *testing.T) {
+		if list.StaleAfterDays != 14 {
+			t.Errorf("Expected default stale after days of 14, got %d", list.StaleAfterDays)
+		}
+	})
+
+	t.Run("set stale threshold as owner", func(t *testing.T) {
+		updatedList, err := service.SetStaleAfterDays(list.ID, user.ID, 0)
+		if err != nil {
+			t.Fatalf("Failed to set stale after days: %v", err)
+		}
+		if updatedList.StaleAfterDays != 0 {
+			t.Errorf("Expected stale after days of 0, got %d", updatedList.StaleAfterDays)
+		}
+	})
+
+	t.Run("set stale threshold as non-owner", func(t *testing.T) {
+		if _, err := service.SetStaleAfterDays(list.ID, "other-user-id", 3); err == nil {
+			t.Error("Expected error when setting stale threshold as non-owner")
+		}
+	})
 }
 
 func TestService_DeleteList(t *testing.T) {
@@ -491,8 +577,8 @@ func TestService_AddMemberToList(t *testing.T) {
 			t.Fatal("Member should be added to list")
 		}
 
-		if member.Role != "member" {
-			t.Errorf("Expected role to be 'member', got '%s'", member.Role)
+		if member.Role != RoleEditor {
+			t.Errorf("Expected role to be 'editor', got '%s'", member.Role)
 		}
 	})
 
@@ -532,7 +618,7 @@ func TestService_CreateDefaultListForUser(t *testing.T) {
 		t.Fatalf("Failed to create test user: %v", err)
 	}
 
-	list, err := service.CreateDefaultListForUser(user.ID)
+	list, err := service.CreateDefaultListForUser(user.ID, "My Shopping List")
 	if err != nil {
 		t.Fatalf("Failed to create default list: %v", err)
 	}
@@ -546,6 +632,395 @@ func TestService_CreateDefaultListForUser(t *testing.T) {
 	}
 }
 
+func TestService_JoinList(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+
+	owner := models.User{ID: "owner-id", Email: "owner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	guest := models.User{ID: "guest-id", Email: "guest@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	for _, user := range []*models.User{&owner, &guest} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	list, err := service.CreateList(owner.ID, testutils.TestListName())
+	if err != nil {
+		t.Fatalf("Failed to create test list: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := service.JoinList(list.ID, guest.ID); err != nil {
+			t.Fatalf("Failed to join list (attempt %d): %v", i+1, err)
+		}
+	}
+
+	var member models.ListMember
+	if err := db.Where("list_id = ? AND user_id = ?", list.ID, guest.ID).First(&member).Error; err != nil {
+		t.Fatalf("Expected membership: %v", err)
+	}
+	if member.Role != RoleEditor {
+		t.Errorf("Expected role 'editor', got '%s'", member.Role)
+	}
+
+	if err := service.JoinList(list.ID, owner.ID); err != nil || !service.IsListOwner(list.ID, owner.ID) {
+		t.Error("Expected joining an owned list to keep the owner role")
+	}
+
+	if err := service.JoinList("missing", guest.ID); err == nil {
+		t.Error("Expected joining a missing list to fail")
+	}
+}
+
+func TestService_GetContacts(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+
+	users := []models.User{
+		{ID: "owner-id", Email: "owner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()},
+		{ID: "partner-id", Email: "partner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()},
+		{ID: "friend-id", Email: "friend@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()},
+		{ID: "private-id", Email: "private@example.com", JoinedAt: time.Now(), CreatedAt: time.Now(), HideFromContacts: true},
+		{ID: "stranger-id", Email: "stranger@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()},
+	}
+	for i := range users {
+		if err := db.Create(&users[i]).Error; err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	home, _ := service.CreateList("owner-id", "Home")
+	party, _ := service.CreateList("owner-id", "Party")
+	other, _ := service.CreateList("stranger-id", "Other")
+	for _, membership := range []struct{ listID, userID string }{
+		{home.ID, "partner-id"},
+		{party.ID, "partner-id"},
+		{party.ID, "friend-id"},
+		{party.ID, "private-id"},
+		{other.ID, "friend-id"},
+	} {
+		if err := service.JoinList(membership.listID, membership.userID); err != nil {
+			t.Fatalf("Failed to join list: %v", err)
+		}
+	}
+
+	contacts, err := service.GetContacts("owner-id")
+	if err != nil {
+		t.Fatalf("Failed to get contacts: %v", err)
+	}
+	if len(contacts) != 2 {
+		t.Fatalf("Expected 2 contacts, got %d: %+v", len(contacts), contacts)
+	}
+	if contacts[0].UserID != "partner-id" || contacts[0].SharedLists != 2 || contacts[0].Email != "partner@example.com" {
+		t.Errorf("Expected partner sharing 2 lists first, got %+v", contacts[0])
+	}
+	if contacts[1].UserID != "friend-id" || contacts[1].SharedLists != 1 {
+		t.Errorf("Expected friend sharing 1 list second, got %+v", contacts[1])
+	}
+
+	contacts, err = service.GetContacts("private-id")
+	if err != nil {
+		t.Fatalf("Failed to get contacts: %v", err)
+	}
+	if len(contacts) != 3 {
+		t.Errorf("Expected opted-out users to still see their own contacts, got %d", len(contacts))
+	}
+
+	if _, err := service.GetContacts(""); err == nil {
+		t.Error("Expected error for empty user ID")
+	}
+}
+
+func TestService_ListNotes(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+
+	owner := models.User{ID: "owner-id", Email: "owner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	guest := models.User{ID: "guest-id", Email: "guest@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	for _, user := range []*models.User{&owner, &guest} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	list, err := service.CreateList(owner.ID, testutils.TestListName())
+	if err != nil {
+		t.Fatalf("Failed to create test list: %v", err)
+	}
+
+	note, err := service.GetListNote(list.ID, owner.ID)
+	if err != nil || note.Note != "" {
+		t.Fatalf("Expected empty note, got %+v, %v", note, err)
+	}
+
+	if _, err := service.SetListNote(list.ID, guest.ID, "mine"); err == nil {
+		t.Error("Expected non-members to be denied")
+	}
+
+	if _, err := service.SetListNote(list.ID, owner.ID, " use coupon at checkout "); err != nil {
+		t.Fatalf("Failed to set note: %v", err)
+	}
+	if _, err := service.SetListNote(list.ID, owner.ID, "use coupon at checkout!"); err != nil {
+		t.Fatalf("Failed to update note: %v", err)
+	}
+
+	if err := service.JoinList(list.ID, guest.ID); err != nil {
+		t.Fatalf("Failed to join list: %v", err)
+	}
+	if note, _ := service.GetListNote(list.ID, guest.ID); note.Note != "" {
+		t.Errorf("Expected other members not to see the note, got %q", note.Note)
+	}
+	if note, _ := service.GetListNote(list.ID, owner.ID); note.Note != "use coupon at checkout!" {
+		t.Errorf("Expected updated note, got %q", note.Note)
+	}
+
+	if _, err := service.SetListNote(list.ID, guest.ID, "bring bags"); err != nil {
+		t.Fatalf("Failed to set note: %v", err)
+	}
+	if err := service.RemoveMemberFromList(list.ID, guest.ID, guest.ID); err != nil {
+		t.Fatalf("Failed to leave list: %v", err)
+	}
+	var count int64
+	db.Model(&models.ListNote{}).Where("user_id = ?", guest.ID).Count(&count)
+	if count != 0 {
+		t.Error("Expected note of removed member to be deleted")
+	}
+
+	if _, err := service.SetListNote(list.ID, owner.ID, ""); err != nil {
+		t.Fatalf("Failed to clear note: %v", err)
+	}
+	db.Model(&models.ListNote{}).Count(&count)
+	if count != 0 {
+		t.Error("Expected empty note to be deleted")
+	}
+}
+
+func TestService_Sections(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+
+	owner := models.User{ID: "owner-id", Email: "owner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	if err := db.Create(&owner).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	list, err := service.CreateList(owner.ID, testutils.TestListName())
+	if err != nil {
+		t.Fatalf("Failed to create test list: %v", err)
+	}
+
+	supermarket, err := service.CreateSection(list.ID, owner.ID, "Supermarket", nil)
+	if err != nil {
+		t.Fatalf("Failed to create section: %v", err)
+	}
+	pharmacy, err := service.CreateSection(list.ID, owner.ID, " Pharmacy ", nil)
+	if err != nil {
+		t.Fatalf("Failed to create section: %v", err)
+	}
+	if supermarket.Position != 0 || pharmacy.Position != 1 || pharmacy.Name != "Pharmacy" {
+		t.Errorf("Expected sections to be appended in order, got %+v and %+v", supermarket, pharmacy)
+	}
+
+	if _, err := service.CreateSection(list.ID, "stranger-id", "Bakery", nil); err == nil {
+		t.Error("Expected non-members to be denied")
+	}
+
+	first := 0
+	if _, err := service.UpdateSection(list.ID, pharmacy.ID, owner.ID, "Drugstore", &first); err != nil {
+		t.Fatalf("Failed to update section: %v", err)
+	}
+	if _, err := service.UpdateSection(list.ID, "missing", owner.ID, "Bakery", nil); !errors.Is(err, ErrSectionNotFound) {
+		t.Errorf("Expected ErrSectionNotFound, got %v", err)
+	}
+
+	sections, err := service.GetSections(list.ID, owner.ID)
+	if err != nil {
+		t.Fatalf("Failed to get sections: %v", err)
+	}
+	if len(sections) != 2 || sections[0].Name != "Supermarket" || sections[1].Name != "Drugstore" {
+		t.Errorf("Unexpected sections: %+v", sections)
+	}
+
+	item := models.ShoppingItem{ID: "item-1", ListID: list.ID, Name: "Aspirin", Tags: "[]", SectionID: &pharmacy.ID, CreatedAt: time.Now()}
+	if err := db.Create(&item).Error; err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+
+	if err := service.DeleteSection(list.ID, pharmacy.ID, owner.ID); err != nil {
+		t.Fatalf("Failed to delete section: %v", err)
+	}
+	if err := db.First(&item, "id = ?", item.ID).Error; err != nil {
+		t.Fatalf("Expected item to be kept: %v", err)
+	}
+	if item.SectionID != nil {
+		t.Error("Expected item to be moved out of the deleted section")
+	}
+	if service.SectionExists(list.ID, pharmacy.ID) {
+		t.Error("Expected section to be deleted")
+	}
+}
+
+func TestService_Tags(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+
+	owner := models.User{ID: "owner-id", Email: "owner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	if err := db.Create(&owner).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	list, err := service.CreateList(owner.ID, testutils.TestListName())
+	if err != nil {
+		t.Fatalf("Failed to create test list: %v", err)
+	}
+
+	items := []models.ShoppingItem{
+		{ID: "item-1", ListID: list.ID, Name: "Apples", Tags: `["organic","fruit"]`, CreatedAt: time.Now()},
+		{ID: "item-2", ListID: list.ID, Name: "Pears", Tags: `["bio","organic"]`, CreatedAt: time.Now()},
+		{ID: "item-3", ListID: list.ID, Name: "Bread", Tags: "[]", CreatedAt: time.Now()},
+	}
+	for i := range items {
+		if err := db.Create(&items[i]).Error; err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+	tagsOf := func(id string) string {
+		var item models.ShoppingItem
+		db.First(&item, "id = ?", id)
+		return item.Tags
+	}
+
+	if _, err := service.CreateTag(list.ID, owner.ID, models.TagRequest{Name: "fruit", Color: "#ff0000"}); err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+	tag, err := service.CreateTag(list.ID, owner.ID, models.TagRequest{Name: "organic", Color: "#4caf50", Icon: "leaf"})
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+	if tag.Position != 1 {
+		t.Errorf("Expected tag to be appended at position 1, got %d", tag.Position)
+	}
+	if _, err := service.CreateTag(list.ID, owner.ID, models.TagRequest{Name: "organic"}); err == nil {
+		t.Error("Expected duplicate tag to be rejected")
+	}
+	if _, err := service.CreateTag(list.ID, "stranger-id", models.TagRequest{Name: "dairy"}); err == nil {
+		t.Error("Expected non-members to be denied")
+	}
+
+	tag, err = service.UpdateTag(list.ID, owner.ID, "organic", models.TagRequest{Name: "bio", Color: "#388e3c", Icon: "leaf"})
+	if err != nil {
+		t.Fatalf("Failed to rename tag: %v", err)
+	}
+	if tag.Name != "bio" || tag.Color != "#388e3c" {
+		t.Errorf("Unexpected tag after update: %+v", tag)
+	}
+	if tags := tagsOf("item-1"); tags != `["bio","fruit"]` {
+		t.Errorf("Expected tag to be renamed on item, got %s", tags)
+	}
+	if tags := tagsOf("item-2"); tags != `["bio"]` {
+		t.Errorf("Expected renamed tag to be merged on item, got %s", tags)
+	}
+
+	if _, err := service.UpdateTag(list.ID, owner.ID, "missing", models.TagRequest{Name: "x"}); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("Expected ErrTagNotFound, got %v", err)
+	}
+
+	if err := service.DeleteTag(list.ID, owner.ID, "fruit"); err != nil {
+		t.Fatalf("Failed to delete tag: %v", err)
+	}
+	if tags := tagsOf("item-1"); tags != `["bio"]` {
+		t.Errorf("Expected deleted tag to be removed from item, got %s", tags)
+	}
+	if tags := tagsOf("item-3"); tags != "[]" {
+		t.Errorf("Expected untagged item to be unchanged, got %s", tags)
+	}
+
+	tags, err := service.GetTags(list.ID, owner.ID)
+	if err != nil {
+		t.Fatalf("Failed to get tags: %v", err)
+	}
+	if len(tags) != 1 || tags[0].Name != "bio" {
+		t.Errorf("Unexpected tags: %+v", tags)
+	}
+}
+
+func TestService_TagPolicy(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+
+	owner := models.User{ID: "owner-id", Email: "owner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	member := models.User{ID: "member-id", Email: "member@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	for _, user := range []models.User{owner, member} {
+		if err := db.Create(&user).Error; err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	list, err := service.CreateList(owner.ID, testutils.TestListName())
+	if err != nil {
+		t.Fatalf("Failed to create test list: %v", err)
+	}
+	if err := service.AddMemberToList(list.ID, owner.ID, member.ID); err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+	if _, err := service.CreateTag(list.ID, owner.ID, models.TagRequest{Name: "organic"}); err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	if _, err := service.SetSuggestedTags(list.ID, member.ID, []string{"organic"}); err == nil {
+		t.Error("Expected members not to choose suggested tags")
+	}
+	if _, err := service.SetSuggestedTags(list.ID, owner.ID, []string{"organic", "on sale"}); err != nil {
+		t.Fatalf("Failed to set suggested tags: %v", err)
+	}
+	suggested, err := service.SuggestedTags(list.ID)
+	if err != nil || len(suggested) != 2 || suggested[0].Name != "organic" || suggested[1].Name != "on sale" {
+		t.Fatalf("Expected organic and the added on sale tag, got %+v (%v)", suggested, err)
+	}
+	if _, err := service.SetSuggestedTags(list.ID, owner.ID, []string{"on sale"}); err != nil {
+		t.Fatalf("Failed to set suggested tags: %v", err)
+	}
+	suggested, _ = service.SuggestedTags(list.ID)
+	if len(suggested) != 1 || suggested[0].Name != "on sale" {
+		t.Errorf("Expected only on sale to stay suggested, got %+v", suggested)
+	}
+
+	if err := service.ApplyTagPolicy(list.ID, `["anything"]`); err != nil {
+		t.Errorf("Expected open lists to accept any tag, got %v", err)
+	}
+
+	if _, err := service.SetTagPolicy(list.ID, owner.ID, "sometimes"); err == nil {
+		t.Error("Expected an invalid policy to be rejected")
+	}
+	if _, err := service.SetTagPolicy(list.ID, member.ID, TagPolicyRestrict); err == nil {
+		t.Error("Expected members not to change the tag policy")
+	}
+	updated, err := service.SetTagPolicy(list.ID, owner.ID, TagPolicyRestrict)
+	if err != nil || updated.TagPolicy != TagPolicyRestrict {
+		t.Fatalf("Failed to restrict tags: %+v (%v)", updated, err)
+	}
+	if err := service.ApplyTagPolicy(list.ID, `["organic","on sale"]`); err != nil {
+		t.Errorf("Expected known tags to be accepted, got %v", err)
+	}
+	if err := service.ApplyTagPolicy(list.ID, `["organic","vegan"]`); !errors.Is(err, ErrUnknownTag) {
+		t.Errorf("Expected ErrUnknownTag, got %v", err)
+	}
+	if err := service.ApplyTagPolicy(list.ID, "organic"); err == nil {
+		t.Error("Expected tags that are no JSON array to be rejected")
+	}
+
+	if _, err := service.SetTagPolicy(list.ID, owner.ID, TagPolicyCreate); err != nil {
+		t.Fatalf("Failed to set tag policy: %v", err)
+	}
+	if err := service.ApplyTagPolicy(list.ID, `["vegan","vegan"]`); err != nil {
+		t.Fatalf("Expected unknown tags to be created, got %v", err)
+	}
+	tags, _ := service.GetTags(list.ID, member.ID)
+	if len(tags) != 3 || tags[2].Name != "vegan" || tags[2].Suggested {
+		t.Errorf("Expected vegan to be appended without being suggested, got %+v", tags)
+	}
+}
+
 func TestService_GetListMembers(t *testing.T) {
 	db := testutils.SetupTestDB(t)
 	service := NewService(db)
@@ -809,3 +1284,412 @@ func TestService_RemoveMemberFromList(t *testing.T) {
 		}
 	})
 }
+
+func TestService_ListAliases(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+
+	owner := models.User{ID: "alias-owner", Email: "aliasowner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	member := models.User{ID: "alias-member", Email: "aliasmember@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	for _, user := range []models.User{owner, member} {
+		if err := db.Create(&user).Error; err != nil {
+			t.Fatalf("Failed to create test user: %v", err)
+		}
+	}
+
+	list, err := service.CreateList(owner.ID, "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	if err := service.AddMemberToList(list.ID, owner.ID, member.ID); err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+
+	t.Run("owner adds alias", func(t *testing.T) {
+		alias, err := service.AddListAlias(list.ID, owner.ID, "  Einkaufsliste ")
+		if err != nil {
+			t.Fatalf("Failed to add alias: %v", err)
+		}
+		if alias.Alias != "Einkaufsliste" {
+			t.Errorf("Expected trimmed alias 'Einkaufsliste', got '%s'", alias.Alias)
+		}
+
+		if _, err := service.AddListAlias(list.ID, owner.ID, "einkaufsliste"); err == nil {
+			t.Error("Expected duplicate alias to be rejected")
+		}
+	})
+
+	t.Run("member cannot add alias", func(t *testing.T) {
+		if _, err := service.AddListAlias(list.ID, member.ID, "Food"); err == nil {
+			t.Error("Expected non-owner to be rejected")
+		}
+	})
+
+	t.Run("find list by alias", func(t *testing.T) {
+		found, err := service.FindListByName(member.ID, "EINKAUFSLISTE")
+		if err != nil {
+			t.Fatalf("Failed to find list by alias: %v", err)
+		}
+		if found.ID != list.ID {
+			t.Errorf("Expected list '%s', got '%s'", list.ID, found.ID)
+		}
+
+		found, err = service.FindListByName(member.ID, "groceries")
+		if err != nil || found.ID != list.ID {
+			t.Error("Expected list to be found by name")
+		}
+
+		if _, err := service.FindListByName("stranger", "Einkaufsliste"); err == nil {
+			t.Error("Expected non-members not to find the list")
+		}
+	})
+
+	t.Run("list and remove aliases", func(t *testing.T) {
+		aliases, err := service.GetListAliases(list.ID, member.ID)
+		if err != nil {
+			t.Fatalf("Failed to get aliases: %v", err)
+		}
+		if len(aliases) != 1 {
+			t.Fatalf("Expected 1 alias, got %d", len(aliases))
+		}
+
+		if err := service.RemoveListAlias(list.ID, owner.ID, "Einkaufsliste"); err != nil {
+			t.Fatalf("Failed to remove alias: %v", err)
+		}
+		if err := service.RemoveListAlias(list.ID, owner.ID, "Einkaufsliste"); err == nil {
+			t.Error("Expected removing a missing alias to fail")
+		}
+	})
+}
+
+func TestService_CoOwners(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+
+	users := []models.User{
+		{ID: "owner-id", Email: "owner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()},
+		{ID: "partner-id", Email: "partner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()},
+		{ID: "member-id", Email: "member@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()},
+	}
+	if err := db.Create(&users).Error; err != nil {
+		t.Fatalf("Failed to create users: %v", err)
+	}
+
+	list, err := service.CreateList("owner-id", "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	if err := service.JoinList(list.ID, "partner-id"); err != nil {
+		t.Fatalf("Failed to join list: %v", err)
+	}
+	if err := service.JoinList(list.ID, "member-id"); err != nil {
+		t.Fatalf("Failed to join list: %v", err)
+	}
+
+	if _, err := service.SetMemberRole(list.ID, "member-id", "partner-id", RoleCoOwner); err == nil {
+		t.Error("Expected members not to promote others")
+	}
+
+	member, err := service.SetMemberRole(list.ID, "owner-id", "partner-id", RoleCoOwner)
+	if err != nil {
+		t.Fatalf("Failed to promote member: %v", err)
+	}
+	if member.Role != RoleCoOwner || !service.IsListOwner(list.ID, "partner-id") || service.IsPrimaryOwner(list.ID, "partner-id") {
+		t.Errorf("Expected partner to be co-owner, got role %q", member.Role)
+	}
+
+	if _, err := service.SetMemberRole(list.ID, "partner-id", "owner-id", RoleEditor); !errors.Is(err, ErrOwnerRoleFixed) {
+		t.Errorf("Expected the owner not to be demoted, got %v", err)
+	}
+	if _, err := service.SetMemberRole(list.ID, "owner-id", "unknown-id", RoleCoOwner); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("Expected unknown member to be rejected, got %v", err)
+	}
+	if _, err := service.SetMemberRole(list.ID, "owner-id", "member-id", RoleOwner); err == nil {
+		t.Error("Expected promotion to owner to be rejected")
+	}
+
+	t.Run("co-owner has full rights", func(t *testing.T) {
+		if _, err := service.UpdateList(list.ID, "partner-id", "Household"); err != nil {
+			t.Errorf("Expected co-owner to rename the list, got %v", err)
+		}
+		if err := service.AddMemberToList(list.ID, "partner-id", "member-id"); err == nil {
+			t.Error("Expected existing member not to be added again")
+		}
+		if err := service.RemoveMemberFromList(list.ID, "partner-id", "owner-id"); err == nil {
+			t.Error("Expected co-owner not to remove the owner")
+		}
+	})
+
+	t.Run("deleting requires permission", func(t *testing.T) {
+		if service.CanDeleteList(list.ID, "partner-id") {
+			t.Error("Expected co-owner not to delete the list by default")
+		}
+		if err := service.DeleteList(list.ID, "partner-id"); err == nil {
+			t.Error("Expected co-owner delete to be rejected")
+		}
+
+		if _, err := service.SetCoOwnersCanDelete(list.ID, "partner-id", true); err == nil {
+			t.Error("Expected only the owner to allow co-owners to delete")
+		}
+		updated, err := service.SetCoOwnersCanDelete(list.ID, "owner-id", true)
+		if err != nil || !updated.CoOwnersCanDelete {
+			t.Fatalf("Failed to allow co-owners to delete: %v", err)
+		}
+		if !service.CanDeleteList(list.ID, "partner-id") || service.CanDeleteList(list.ID, "member-id") {
+			t.Error("Expected only owners to be allowed to delete")
+		}
+	})
+
+	t.Run("owner leaving hands over to co-owner", func(t *testing.T) {
+		if err := service.RemoveMemberFromList(list.ID, "owner-id", "owner-id"); err != nil {
+			t.Fatalf("Failed to leave list: %v", err)
+		}
+		if !service.IsPrimaryOwner(list.ID, "partner-id") {
+			t.Error("Expected co-owner to become owner")
+		}
+
+		var stored models.ShoppingList
+		db.First(&stored, "id = ?", list.ID)
+		if stored.OwnerID != "partner-id" {
+			t.Errorf("Expected list owner to be updated, got %s", stored.OwnerID)
+		}
+
+		if err := service.RemoveMemberFromList(list.ID, "partner-id", "partner-id"); err == nil {
+			t.Error("Expected last owner not to leave")
+		}
+	})
+}
+
+func TestService_Viewers(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+
+	users := []models.User{
+		{ID: "owner-id", Email: "owner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()},
+		{ID: "kid-id", Email: "kid@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()},
+	}
+	if err := db.Create(&users).Error; err != nil {
+		t.Fatalf("Failed to create users: %v", err)
+	}
+
+	list, err := service.CreateList("owner-id", "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	if err := service.JoinList(list.ID, "kid-id"); err != nil {
+		t.Fatalf("Failed to join list: %v", err)
+	}
+	if !service.CanEditList(list.ID, "kid-id") {
+		t.Error("Expected new members to edit the list")
+	}
+
+	if _, err := service.SetMemberRole(list.ID, "kid-id", "kid-id", RoleCoOwner); err == nil {
+		t.Error("Expected editors not to change roles")
+	}
+	member, err := service.SetMemberRole(list.ID, "owner-id", "kid-id", RoleViewer)
+	if err != nil || member.Role != RoleViewer {
+		t.Fatalf("Failed to make member viewer: %+v, %v", member, err)
+	}
+
+	if !service.HasListAccess(list.ID, "kid-id") || service.CanEditList(list.ID, "kid-id") {
+		t.Error("Expected viewers to see but not edit the list")
+	}
+	if !service.CanEditList(list.ID, "owner-id") || service.CanEditList(list.ID, "stranger-id") {
+		t.Error("Expected only members other than viewers to edit the list")
+	}
+	if _, err := service.CreateSection(list.ID, "kid-id", "Dairy", nil); err == nil {
+		t.Error("Expected viewers not to add sections")
+	}
+	if _, err := service.CreateTag(list.ID, "kid-id", models.TagRequest{Name: "organic"}); err == nil {
+		t.Error("Expected viewers not to add tags")
+	}
+
+	members, err := service.GetListMembers(list.ID, "kid-id")
+	if err != nil {
+		t.Fatalf("Failed to get members: %v", err)
+	}
+	roles := map[string]string{}
+	for _, member := range members {
+		roles[member.ID] = member.Role
+	}
+	if roles["owner-id"] != RoleOwner || roles["kid-id"] != RoleViewer {
+		t.Errorf("Expected the members' roles, got %v", roles)
+	}
+
+	// "member" is still accepted for editors
+	member, err = service.SetMemberRole(list.ID, "owner-id", "kid-id", "member")
+	if err != nil || member.Role != RoleEditor || !service.CanEditList(list.ID, "kid-id") {
+		t.Errorf("Expected the kid to be editor again, got %+v, %v", member, err)
+	}
+}
+
+func TestService_DisplayTokens(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+
+	users := []models.User{
+		{ID: "owner-id", Email: "owner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()},
+		{ID: "member-id", Email: "member@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()},
+	}
+	if err := db.Create(&users).Error; err != nil {
+		t.Fatalf("Failed to create users: %v", err)
+	}
+	list, err := service.CreateList("owner-id", "Kitchen")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	if err := service.JoinList(list.ID, "member-id"); err != nil {
+		t.Fatalf("Failed to join list: %v", err)
+	}
+
+	if _, _, err := service.CreateDisplayToken(list.ID, "member-id", "E-Ink"); err == nil {
+		t.Error("Expected members not to create display tokens")
+	}
+
+	displayToken, token, err := service.CreateDisplayToken(list.ID, "owner-id", " E-Ink ")
+	if err != nil {
+		t.Fatalf("Failed to create display token: %v", err)
+	}
+	if displayToken.Name != "E-Ink" || !strings.HasPrefix(token, displayToken.Prefix) || displayToken.TokenHash == token {
+		t.Errorf("Unexpected display token: %+v", displayToken)
+	}
+
+	validated, err := service.ValidateDisplayToken(token)
+	if err != nil || validated.ListID != list.ID {
+		t.Fatalf("Expected token to grant access to the list, got %v", err)
+	}
+	if _, err := service.ValidateDisplayToken("sld_unknown"); !errors.Is(err, ErrInvalidDisplayToken) {
+		t.Errorf("Expected unknown token to be rejected, got %v", err)
+	}
+
+	tokens, err := service.GetDisplayTokens(list.ID, "owner-id")
+	if err != nil || len(tokens) != 1 || tokens[0].LastUsedAt == nil {
+		t.Fatalf("Expected 1 used display token, got %+v (%v)", tokens, err)
+	}
+
+	if err := service.RevokeDisplayToken(list.ID, "unknown", "owner-id"); !errors.Is(err, ErrDisplayTokenNotFound) {
+		t.Errorf("Expected unknown token not to be found, got %v", err)
+	}
+	if err := service.RevokeDisplayToken(list.ID, displayToken.ID, "owner-id"); err != nil {
+		t.Fatalf("Failed to revoke display token: %v", err)
+	}
+	if _, err := service.ValidateDisplayToken(token); err == nil {
+		t.Error("Expected revoked token to be rejected")
+	}
+}
+
+func TestService_MergeLists(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+
+	owner := models.User{ID: "merge-owner", Email: "merge-owner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	partner := models.User{ID: "merge-partner", Email: "merge-partner@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	db.Create(&owner)
+	db.Create(&partner)
+
+	target, _ := service.CreateList(owner.ID, "Groceries")
+	source, _ := service.CreateList(partner.ID, "Groceries too")
+	if err := service.AddMemberToList(source.ID, partner.ID, owner.ID); err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+
+	t.Run("requires owning both lists", func(t *testing.T) {
+		if _, err := service.MergeLists(target.ID, source.ID, owner.ID, false, ""); !errors.Is(err, ErrMergeNotOwner) {
+			t.Errorf("Expected ErrMergeNotOwner, got %v", err)
+		}
+		if _, err := service.MergeLists(target.ID, target.ID, owner.ID, false, ""); !errors.Is(err, ErrInvalidMerge) {
+			t.Errorf("Expected ErrInvalidMerge, got %v", err)
+		}
+		if _, err := service.MergeLists(target.ID, "missing", owner.ID, false, ""); !errors.Is(err, ErrListNotFound) {
+			t.Errorf("Expected ErrListNotFound, got %v", err)
+		}
+	})
+
+	if _, err := service.SetMemberRole(source.ID, partner.ID, owner.ID, RoleCoOwner); err != nil {
+		t.Fatalf("Failed to promote member: %v", err)
+	}
+
+	produce := models.ListSection{ID: "target-produce", ListID: target.ID, Name: "Produce"}
+	sourceProduce := models.ListSection{ID: "source-produce", ListID: source.ID, Name: "produce"}
+	db.Create(&produce)
+	db.Create(&sourceProduce)
+
+	now := time.Now()
+	for _, item := range []models.ShoppingItem{
+		{ID: "target-milk", ListID: target.ID, Name: "Milk", Quantity: 1, Tags: "[]", CreatedAt: now},
+		{ID: "source-milk", ListID: source.ID, Name: "milk", Quantity: 2, Tags: "[]", CreatedAt: now},
+		{ID: "source-apples", ListID: source.ID, Name: "Apples", SectionID: &sourceProduce.ID, Tags: "[]", CreatedAt: now},
+		{ID: "source-bread", ListID: source.ID, Name: "Bread", Completed: true, Tags: "[]", CreatedAt: now},
+	} {
+		if err := db.Create(&item).Error; err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+
+	result, err := service.MergeLists(target.ID, source.ID, owner.ID, true, "")
+	if err != nil {
+		t.Fatalf("Failed to merge lists: %v", err)
+	}
+	if result.MovedItems != 2 || result.MergedItems != 1 || result.SkippedItems != 0 || result.AddedMembers != 1 {
+		t.Errorf("Unexpected merge result: %+v", result)
+	}
+
+	var milk models.ShoppingItem
+	db.First(&milk, "id = ?", "target-milk")
+	if milk.Quantity != 3 {
+		t.Errorf("Expected merged quantity 3, got %v", milk.Quantity)
+	}
+	var count int64
+	db.Model(&models.ShoppingItem{}).Where("id = ?", "source-milk").Count(&count)
+	if count != 0 {
+		t.Error("Expected the duplicate to be merged away")
+	}
+
+	var apples models.ShoppingItem
+	db.First(&apples, "id = ?", "source-apples")
+	if apples.ListID != target.ID || apples.SectionID == nil || *apples.SectionID != produce.ID {
+		t.Errorf("Expected apples in the target's produce section, got %+v", apples)
+	}
+
+	var member models.ListMember
+	db.First(&member, "list_id = ? AND user_id = ?", target.ID, partner.ID)
+	if member.Role != RoleCoOwner {
+		t.Errorf("Expected the source owner to become co-owner, got %q", member.Role)
+	}
+
+	lists, _ := service.GetUserLists(owner.ID)
+	if len(lists) != 1 || lists[0].ID != target.ID {
+		t.Errorf("Expected the archived source to be hidden, got %d lists", len(lists))
+	}
+	archived, _ := service.GetArchivedLists(owner.ID)
+	if len(archived) != 1 || archived[0].ID != source.ID {
+		t.Errorf("Expected the source among the archived lists, got %d lists", len(archived))
+	}
+	if _, err := service.MergeLists(source.ID, target.ID, owner.ID, false, ""); !errors.Is(err, ErrInvalidMerge) {
+		t.Errorf("Expected merging into an archived list to fail, got %v", err)
+	}
+
+	t.Run("skip duplicates", func(t *testing.T) {
+		extra, _ := service.CreateList(owner.ID, "Extra")
+		db.Create(&models.ShoppingItem{ID: "extra-milk", ListID: extra.ID, Name: "Milk", Tags: "[]", CreatedAt: now})
+
+		result, err := service.MergeLists(target.ID, extra.ID, owner.ID, false, DuplicatesSkip)
+		if err != nil {
+			t.Fatalf("Failed to merge lists: %v", err)
+		}
+		if result.SkippedItems != 1 || result.MovedItems != 0 {
+			t.Errorf("Unexpected merge result: %+v", result)
+		}
+
+		var item models.ShoppingItem
+		db.First(&item, "id = ?", "extra-milk")
+		if item.ListID != extra.ID {
+			t.Error("Expected the skipped duplicate to stay on the source list")
+		}
+
+		restored, err := service.SetArchived(extra.ID, owner.ID, false)
+		if err != nil || restored.ArchivedAt != nil {
+			t.Errorf("Expected the list to be restored, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,220 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package lists
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/items"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// How MergeLists handles open items that are already on the target list.
+const (
+	DuplicatesMerge = "merge"
+	DuplicatesKeep  = "keep"
+	DuplicatesSkip  = "skip"
+)
+
+// ErrListNotFound is returned when a list does not exist.
+var ErrListNotFound = errors.New("list not found")
+
+// ErrMergeNotOwner is returned when the user does not own both lists of a merge.
+var ErrMergeNotOwner = errors.New("only owners of both lists can merge them")
+
+// ErrInvalidMerge is returned when merging a list into itself or into an archived list.
+var ErrInvalidMerge = errors.New("a list can only be merged into another list that is not archived")
+
+// MergeLists moves the items of the source list, and with includeMembers its members, into
+// the target list and archives the source. Open items whose normalized name and unit match an
+// open item of the target are handled as duplicates says, see the Duplicates constants. Item
+// sections are mapped to the target section of the same name. The user has to own both lists.
+func (s *Service) MergeLists(targetID, sourceID, userID string, includeMembers bool, duplicates string) (*models.ListMergeResult, error) {
+	if duplicates == "" {
+		duplicates = DuplicatesMerge
+	}
+	if targetID == sourceID {
+		return nil, ErrInvalidMerge
+	}
+
+	var target, source models.ShoppingList
+	if err := s.DB.First(&target, "id = ?", targetID).Error; err != nil {
+		return nil, ErrListNotFound
+	}
+	if err := s.DB.First(&source, "id = ?", sourceID).Error; err != nil {
+		return nil, ErrListNotFound
+	}
+	if !s.IsListOwner(targetID, userID) || !s.IsListOwner(sourceID, userID) {
+		return nil, ErrMergeNotOwner
+	}
+	if target.ArchivedAt != nil {
+		return nil, ErrInvalidMerge
+	}
+
+	result := models.ListMergeResult{}
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		sections, err := sectionMapping(tx, targetID, sourceID)
+		if err != nil {
+			return err
+		}
+
+		var open []models.ShoppingItem
+		err = tx.Where("list_id = ? AND completed = ?", targetID, false).Order("created_at ASC").Find(&open).Error
+		if err != nil {
+			return err
+		}
+		existing := make(map[string]models.ShoppingItem, len(open))
+		for _, item := range open {
+			key := items.DuplicateKey(item)
+			if _, ok := existing[key]; !ok {
+				existing[key] = item
+			}
+		}
+
+		var moving []models.ShoppingItem
+		if err := tx.Where("list_id = ?", sourceID).Order("created_at ASC").Find(&moving).Error; err != nil {
+			return err
+		}
+		for _, item := range moving {
+			duplicate, isDuplicate := existing[items.DuplicateKey(item)]
+			isDuplicate = isDuplicate && !item.Completed
+			if isDuplicate && duplicates == DuplicatesSkip {
+				result.SkippedItems++
+				continue
+			}
+
+			var sectionID *string
+			if item.SectionID != nil {
+				sectionID = sections[*item.SectionID]
+			}
+			err := tx.Model(&models.ShoppingItem{}).Where("id = ?", item.ID).
+				UpdateColumns(map[string]any{"list_id": targetID, "section_id": sectionID, "updated_at": time.Now()}).Error
+			if err != nil {
+				return err
+			}
+			err = tx.Model(&models.Attachment{}).Where("item_id = ?", item.ID).Update("list_id", targetID).Error
+			if err != nil {
+				return err
+			}
+
+			if isDuplicate && duplicates == DuplicatesMerge {
+				item.ListID, item.SectionID = targetID, sectionID
+				if _, err := items.MergeGroup(tx, []models.ShoppingItem{duplicate, item}, userID); err != nil {
+					return err
+				}
+				// Later duplicates are merged into the updated item
+				tx.First(&duplicate, "id = ?", duplicate.ID)
+				existing[items.DuplicateKey(duplicate)] = duplicate
+				result.MergedItems++
+				continue
+			}
+			result.MovedItems++
+		}
+
+		if includeMembers {
+			added, err := mergeMembers(tx, targetID, sourceID)
+			if err != nil {
+				return err
+			}
+			result.AddedMembers = added
+		}
+
+		now := time.Now()
+		if err := tx.Model(&models.ShoppingList{}).Where("id = ?", targetID).Update("updated_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.ShoppingList{}).Where("id = ?", sourceID).
+			UpdateColumns(map[string]any{"archived_at": now, "updated_at": now}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.DB.Preload("Owner").First(&result.List, "id = ?", targetID)
+	return &result, nil
+}
+
+// SetArchived archives or restores a list. Archived lists are hidden from the user's lists.
+func (s *Service) SetArchived(listID, userID string, archived bool) (*models.ShoppingList, error) {
+	if !s.IsListOwner(listID, userID) {
+		return nil, errors.New("only list owners can update lists")
+	}
+
+	var list models.ShoppingList
+	if err := s.DB.First(&list, "id = ?", listID).Error; err != nil {
+		return nil, ErrListNotFound
+	}
+
+	var archivedAt *time.Time
+	if archived {
+		if list.ArchivedAt != nil {
+			return &list, nil
+		}
+		now := time.Now()
+		archivedAt = &now
+	}
+	if err := s.DB.Model(&list).Update("archived_at", archivedAt).Error; err != nil {
+		return nil, err
+	}
+
+	s.DB.Preload("Owner").First(&list, "id = ?", list.ID)
+	return &list, nil
+}
+
+// sectionMapping maps the sections of the source list to the target section of the same name,
+// ignoring case. Sections without a counterpart map to nil.
+func sectionMapping(tx *gorm.DB, targetID, sourceID string) (map[string]*string, error) {
+	var targetSections, sourceSections []models.ListSection
+	if err := tx.Where("list_id = ?", targetID).Find(&targetSections).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("list_id = ?", sourceID).Find(&sourceSections).Error; err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]string, len(targetSections))
+	for _, section := range targetSections {
+		byName[strings.ToLower(section.Name)] = section.ID
+	}
+	mapping := make(map[string]*string, len(sourceSections))
+	for _, section := range sourceSections {
+		if id, ok := byName[strings.ToLower(section.Name)]; ok {
+			mapping[section.ID] = &id
+		}
+	}
+	return mapping, nil
+}
+
+// mergeMembers adds the members of the source list to the target list. Members keep their
+// role, except that the owner of the source list becomes co-owner; members of both lists keep
+// their role on the target. It returns the number of members added.
+func mergeMembers(tx *gorm.DB, targetID, sourceID string) (int, error) {
+	var members []models.ListMember
+	if err := tx.Where("list_id = ?", sourceID).Find(&members).Error; err != nil {
+		return 0, err
+	}
+
+	added := 0
+	for _, member := range members {
+		var count int64
+		tx.Model(&models.ListMember{}).Where("list_id = ? AND user_id = ?", targetID, member.UserID).Count(&count)
+		if count > 0 {
+			continue
+		}
+
+		role := member.Role
+		if role == RoleOwner {
+			role = RoleCoOwner
+		}
+		err := tx.Create(&models.ListMember{ListID: targetID, UserID: member.UserID, Role: role, JoinedAt: time.Now()}).Error
+		if err != nil {
+			return added, err
+		}
+		added++
+	}
+	return added, nil
+}
@@ -0,0 +1,135 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package lists
+
+import (
+	"errors"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// Roles of list members. The owner created the list or took it over, co-owners have the same
+// rights except that they may only delete the list if its owner allows it. Editors change the
+// items, sections and tags of the list, viewers only see them.
+const (
+	RoleOwner   = "owner"
+	RoleCoOwner = "co-owner"
+	RoleEditor  = "editor"
+	RoleViewer  = "viewer"
+)
+
+// roleMember is the former name of RoleEditor, still accepted when changing roles.
+const roleMember = "member"
+
+// OwnerRoles are the roles with full control over a list.
+var OwnerRoles = []string{RoleOwner, RoleCoOwner}
+
+// ErrReadOnly is returned when a viewer tries to change a list.
+var ErrReadOnly = errors.New("viewers cannot change the list")
+
+// ErrMemberNotFound is returned when a user is not a member of the list.
+var ErrMemberNotFound = errors.New("member not found")
+
+// ErrOwnerRoleFixed is returned when changing the role of the list owner.
+var ErrOwnerRoleFixed = errors.New("the role of the list owner cannot be changed")
+
+// IsPrimaryOwner checks if the given user is the owner of the list, not just a co-owner.
+func (s *Service) IsPrimaryOwner(listID, userID string) bool {
+	var member models.ListMember
+	err := s.DB.Where("list_id = ? AND user_id = ? AND role = ?", listID, userID, RoleOwner).First(&member).Error
+	return err == nil
+}
+
+// CanDeleteList checks if the given user may delete the list: the owner always, co-owners only if
+// the list allows it.
+func (s *Service) CanDeleteList(listID, userID string) bool {
+	if s.IsPrimaryOwner(listID, userID) {
+		return true
+	}
+
+	var list models.ShoppingList
+	if err := s.DB.First(&list, "id = ?", listID).Error; err != nil {
+		return false
+	}
+	return list.CoOwnersCanDelete && s.IsListOwner(listID, userID)
+}
+
+// SetCoOwnersCanDelete sets whether co-owners may delete the list. Only the owner can change it.
+func (s *Service) SetCoOwnersCanDelete(listID, userID string, allowed bool) (*models.ShoppingList, error) {
+	if !s.IsPrimaryOwner(listID, userID) {
+		return nil, errors.New("only the list owner can change who may delete the list")
+	}
+
+	var list models.ShoppingList
+	if err := s.DB.First(&list, "id = ?", listID).Error; err != nil {
+		return nil, errors.New("list not found")
+	}
+
+	if err := s.DB.Model(&list).Update("co_owners_can_delete", allowed).Error; err != nil {
+		return nil, err
+	}
+
+	s.DB.Preload("Owner").First(&list, "id = ?", list.ID)
+	return &list, nil
+}
+
+// CanEditList checks if the given user is a member of the list who may change its items, which
+// all members but viewers may.
+func (s *Service) CanEditList(listID, userID string) bool {
+	var member models.ListMember
+	err := s.DB.Where("list_id = ? AND user_id = ? AND role <> ?", listID, userID, RoleViewer).First(&member).Error
+	return err == nil
+}
+
+// SetMemberRole makes a member co-owner, editor or viewer. Owners and co-owners can change roles;
+// the role of the owner itself is fixed.
+func (s *Service) SetMemberRole(listID, userID, memberID, role string) (*models.ListMember, error) {
+	if role == roleMember {
+		role = RoleEditor
+	}
+	if role != RoleCoOwner && role != RoleEditor && role != RoleViewer {
+		return nil, errors.New("role must be co-owner, editor or viewer")
+	}
+
+	if !s.IsListOwner(listID, userID) {
+		return nil, errors.New("only list owners can change member roles")
+	}
+
+	var member models.ListMember
+	if err := s.DB.Where("list_id = ? AND user_id = ?", listID, memberID).First(&member).Error; err != nil {
+		return nil, ErrMemberNotFound
+	}
+	if member.Role == RoleOwner {
+		return nil, ErrOwnerRoleFixed
+	}
+
+	if err := s.DB.Model(&member).Update("role", role).Error; err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// handOverOwnership removes the owner from the list and makes the longest-standing co-owner the
+// new owner. It fails if the list has no co-owner to take over.
+func (s *Service) handOverOwnership(listID, ownerID string) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var successor models.ListMember
+		err := tx.Where("list_id = ? AND role = ?", listID, RoleCoOwner).Order("joined_at ASC").First(&successor).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("cannot remove the last owner from the list")
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&successor).Update("role", RoleOwner).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.ShoppingList{}).Where("id = ?", listID).Update("owner_id", successor.UserID).Error; err != nil {
+			return err
+		}
+		return tx.Where("list_id = ? AND user_id = ?", listID, ownerID).Delete(&models.ListMember{}).Error
+	})
+}
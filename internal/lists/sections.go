@@ -0,0 +1,123 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package lists
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrSectionNotFound is returned when a section does not exist in the given list.
+var ErrSectionNotFound = errors.New("section not found")
+
+// GetSections retrieves the sections of a shopping list in display order.
+func (s *Service) GetSections(listID, userID string) ([]models.ListSection, error) {
+	if !s.HasListAccess(listID, userID) {
+		return nil, errors.New("access denied")
+	}
+
+	var sections []models.ListSection
+	err := s.DB.Where("list_id = ?", listID).Order("position ASC, created_at ASC").Find(&sections).Error
+	return sections, err
+}
+
+// CreateSection adds a section to a shopping list. Without a position the section is appended
+// after the existing ones.
+func (s *Service) CreateSection(listID, userID, name string, position *int) (*models.ListSection, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return nil, errors.New("section name cannot be empty")
+	}
+
+	if !s.CanEditList(listID, userID) {
+		return nil, errors.New("access denied")
+	}
+
+	section := models.ListSection{
+		ID:        uuid.New().String(),
+		ListID:    listID,
+		Name:      name,
+		CreatedAt: time.Now(),
+	}
+	if position != nil {
+		section.Position = *position
+	} else {
+		err := s.DB.Model(&models.ListSection{}).Where("list_id = ?", listID).
+			Select("COALESCE(MAX(position) + 1, 0)").Scan(&section.Position).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.DB.Create(&section).Error; err != nil {
+		return nil, err
+	}
+
+	return &section, nil
+}
+
+// UpdateSection renames a section and, if a position is given, moves it.
+func (s *Service) UpdateSection(listID, sectionID, userID, name string, position *int) (*models.ListSection, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return nil, errors.New("section name cannot be empty")
+	}
+
+	if !s.CanEditList(listID, userID) {
+		return nil, errors.New("access denied")
+	}
+
+	var section models.ListSection
+	if err := s.DB.Where("id = ? AND list_id = ?", sectionID, listID).First(&section).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSectionNotFound
+		}
+		return nil, err
+	}
+
+	section.Name = name
+	if position != nil {
+		section.Position = *position
+	}
+
+	if err := s.DB.Save(&section).Error; err != nil {
+		return nil, err
+	}
+
+	return &section, nil
+}
+
+// DeleteSection removes a section from a shopping list. Its items stay on the list without
+// a section.
+func (s *Service) DeleteSection(listID, sectionID, userID string) error {
+	if !s.CanEditList(listID, userID) {
+		return errors.New("access denied")
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND list_id = ?", sectionID, listID).Delete(&models.ListSection{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrSectionNotFound
+		}
+
+		return tx.Model(&models.ShoppingItem{}).
+			Where("list_id = ? AND section_id = ?", listID, sectionID).
+			Update("section_id", nil).Error
+	})
+}
+
+// SectionExists reports whether a section belongs to the given list.
+func (s *Service) SectionExists(listID, sectionID string) bool {
+	var count int64
+	s.DB.Model(&models.ListSection{}).Where("id = ? AND list_id = ?", sectionID, listID).Count(&count)
+	return count > 0
+}
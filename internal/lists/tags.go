@@ -0,0 +1,326 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package lists
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrTagNotFound is returned when a list has no metadata for a tag.
+var ErrTagNotFound = errors.New("tag not found")
+
+// ErrUnknownTag is returned when an item is tagged with a tag its restricted list does not define.
+var ErrUnknownTag = errors.New("unknown tag")
+
+// Tag policies of a list, see models.ShoppingList.TagPolicy.
+const (
+	TagPolicyOpen     = "open"
+	TagPolicyCreate   = "create"
+	TagPolicyRestrict = "restrict"
+)
+
+// GetTags retrieves the tag metadata of a shopping list in display order.
+func (s *Service) GetTags(listID, userID string) ([]models.ListTag, error) {
+	if !s.HasListAccess(listID, userID) {
+		return nil, errors.New("access denied")
+	}
+
+	var tags []models.ListTag
+	err := s.DB.Where("list_id = ?", listID).Order("position ASC, name ASC").Find(&tags).Error
+	return tags, err
+}
+
+// CreateTag adds metadata for a tag of a shopping list. Without a position the tag is
+// appended after the existing ones.
+func (s *Service) CreateTag(listID, userID string, req models.TagRequest) (*models.ListTag, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("tag name cannot be empty")
+	}
+
+	if !s.CanEditList(listID, userID) {
+		return nil, errors.New("access denied")
+	}
+
+	var count int64
+	s.DB.Model(&models.ListTag{}).Where("list_id = ? AND name = ?", listID, name).Count(&count)
+	if count > 0 {
+		return nil, errors.New("tag already exists for this list")
+	}
+
+	tag := models.ListTag{
+		ListID:    listID,
+		Name:      name,
+		Color:     req.Color,
+		Icon:      req.Icon,
+		CreatedAt: time.Now(),
+	}
+	if req.Position != nil {
+		tag.Position = *req.Position
+	} else {
+		err := s.DB.Model(&models.ListTag{}).Where("list_id = ?", listID).
+			Select("COALESCE(MAX(position) + 1, 0)").Scan(&tag.Position).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.DB.Create(&tag).Error; err != nil {
+		return nil, err
+	}
+
+	return &tag, nil
+}
+
+// UpdateTag changes the metadata of a tag. Renaming a tag also renames it on all items of the list.
+func (s *Service) UpdateTag(listID, userID, name string, req models.TagRequest) (*models.ListTag, error) {
+	newName := strings.TrimSpace(req.Name)
+	if newName == "" {
+		return nil, errors.New("tag name cannot be empty")
+	}
+
+	if !s.CanEditList(listID, userID) {
+		return nil, errors.New("access denied")
+	}
+
+	var tag models.ListTag
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("list_id = ? AND name = ?", listID, name).First(&tag).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTagNotFound
+			}
+			return err
+		}
+
+		tag.Color = req.Color
+		tag.Icon = req.Icon
+		if req.Position != nil {
+			tag.Position = *req.Position
+		}
+
+		if newName != name {
+			var count int64
+			tx.Model(&models.ListTag{}).Where("list_id = ? AND name = ?", listID, newName).Count(&count)
+			if count > 0 {
+				return errors.New("tag already exists for this list")
+			}
+
+			// The name is part of the primary key, so the row is replaced
+			if err := tx.Where("list_id = ? AND name = ?", listID, name).Delete(&models.ListTag{}).Error; err != nil {
+				return err
+			}
+			tag.Name = newName
+			if err := tx.Create(&tag).Error; err != nil {
+				return err
+			}
+			return rewriteItemTags(tx, listID, name, newName)
+		}
+
+		return tx.Save(&tag).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &tag, nil
+}
+
+// DeleteTag removes the metadata of a tag and the tag itself from all items of the list.
+func (s *Service) DeleteTag(listID, userID, name string) error {
+	if !s.CanEditList(listID, userID) {
+		return errors.New("access denied")
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("list_id = ? AND name = ?", listID, name).Delete(&models.ListTag{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTagNotFound
+		}
+
+		return rewriteItemTags(tx, listID, name, "")
+	})
+}
+
+// SuggestedTags retrieves the suggested tags of a shopping list in display order.
+func (s *Service) SuggestedTags(listID string) ([]models.ListTag, error) {
+	var tags []models.ListTag
+	err := s.DB.Where("list_id = ? AND suggested = ?", listID, true).Order("position ASC, name ASC").Find(&tags).Error
+	return tags, err
+}
+
+// SetSuggestedTags makes the named tags the suggested tags of a list, adding metadata for tags
+// that have none yet. Only owners can choose them.
+func (s *Service) SetSuggestedTags(listID, userID string, names []string) (*models.ShoppingList, error) {
+	if !s.IsListOwner(listID, userID) {
+		return nil, errors.New("only list owners can update lists")
+	}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.ListTag{}).Where("list_id = ? AND suggested = ?", listID, true).
+			Update("suggested", false).Error
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				return errors.New("tag name cannot be empty")
+			}
+			result := tx.Model(&models.ListTag{}).Where("list_id = ? AND name = ?", listID, name).
+				Update("suggested", true)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				if err := createTag(tx, listID, name, true); err != nil {
+					return err
+				}
+			}
+		}
+
+		// The suggested tags are part of the list, so conditional requests must see the change
+		return tx.Model(&models.ShoppingList{}).Where("id = ?", listID).Update("updated_at", time.Now()).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetListByID(listID, userID)
+}
+
+// SetTagPolicy sets how tags without metadata are handled on items of a list. Only owners can
+// change it.
+func (s *Service) SetTagPolicy(listID, userID, policy string) (*models.ShoppingList, error) {
+	if policy != TagPolicyOpen && policy != TagPolicyCreate && policy != TagPolicyRestrict {
+		return nil, errors.New("invalid tag policy")
+	}
+	if !s.IsListOwner(listID, userID) {
+		return nil, errors.New("only list owners can update lists")
+	}
+
+	if err := s.DB.Model(&models.ShoppingList{}).Where("id = ?", listID).Update("tag_policy", policy).Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetListByID(listID, userID)
+}
+
+// ApplyTagPolicy checks the JSON tag array of an item against the tag policy of its list. With
+// the "create" policy, metadata is added for unknown tags; with "restrict", they are rejected
+// with ErrUnknownTag. Lists with the "open" policy accept any tags, even if they are no array.
+func (s *Service) ApplyTagPolicy(listID, tags string) error {
+	var list models.ShoppingList
+	if err := s.DB.Select("id", "tag_policy").First(&list, "id = ?", listID).Error; err != nil {
+		return errors.New("list not found")
+	}
+	if list.TagPolicy == "" || list.TagPolicy == TagPolicyOpen {
+		return nil
+	}
+
+	var names []string
+	if tags != "" {
+		if err := json.Unmarshal([]byte(tags), &names); err != nil {
+			return errors.New("tags must be a JSON array of strings")
+		}
+	}
+
+	var known []string
+	if err := s.DB.Model(&models.ListTag{}).Where("list_id = ?", listID).Pluck("name", &known).Error; err != nil {
+		return err
+	}
+
+	var unknown []string
+	for _, name := range names {
+		if name != "" && !slices.Contains(known, name) && !slices.Contains(unknown, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	if list.TagPolicy == TagPolicyRestrict {
+		return fmt.Errorf("%w: %s", ErrUnknownTag, strings.Join(unknown, ", "))
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		for _, name := range unknown {
+			if err := createTag(tx, listID, name, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// createTag adds metadata for a tag after the existing tags of a list.
+func createTag(tx *gorm.DB, listID, name string, suggested bool) error {
+	tag := models.ListTag{
+		ListID:    listID,
+		Name:      name,
+		Suggested: suggested,
+		CreatedAt: time.Now(),
+	}
+	err := tx.Model(&models.ListTag{}).Where("list_id = ?", listID).
+		Select("COALESCE(MAX(position) + 1, 0)").Scan(&tag.Position).Error
+	if err != nil {
+		return err
+	}
+	return tx.Create(&tag).Error
+}
+
+// rewriteItemTags renames a tag in the JSON tag arrays of all items of a list, or removes it
+// if newName is empty. Items whose tags are not a JSON array are left untouched.
+func rewriteItemTags(tx *gorm.DB, listID, oldName, newName string) error {
+	var items []models.ShoppingItem
+	if err := tx.Select("id", "tags").Where("list_id = ?", listID).Find(&items).Error; err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		var tags []string
+		if err := json.Unmarshal([]byte(item.Tags), &tags); err != nil {
+			continue
+		}
+
+		changed := false
+		rewritten := make([]string, 0, len(tags))
+		seen := map[string]bool{}
+		for _, tag := range tags {
+			if tag == oldName {
+				changed = true
+				tag = newName
+			}
+			if tag == "" || seen[tag] {
+				continue
+			}
+			seen[tag] = true
+			rewritten = append(rewritten, tag)
+		}
+		if !changed {
+			continue
+		}
+
+		encoded, err := json.Marshal(rewritten)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.ShoppingItem{}).Where("id = ?", item.ID).Update("tags", string(encoded)).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package logging
+
+import (
+	"net"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AnonymizeIP truncates an IP address so it no longer identifies a single client: IPv4
+// addresses keep their /24 network, IPv6 addresses their /48 network. Values that are not IP
+// addresses are returned unchanged.
+func AnonymizeIP(address string) string {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return address
+	}
+	if ipv4 := ip.To4(); ipv4 != nil {
+		return ipv4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(48, 128)).String()
+}
+
+// ClientIP returns the client IP of a request, anonymized if requested. Use it wherever IPs
+// are logged or used as keys so the anonymization applies consistently.
+func ClientIP(c *fiber.Ctx, anonymize bool) string {
+	if anonymize {
+		return AnonymizeIP(c.IP())
+	}
+	return c.IP()
+}
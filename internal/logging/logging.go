@@ -0,0 +1,99 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package logging configures the structured application logger: its level, text or JSON
+// format, and output to stdout, a size-rotated file or syslog. Messages written with the
+// standard log package are routed through the same logger.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Config describes the logger setup.
+type Config struct {
+	// Level is one of debug, info, warn or error.
+	Level string
+	// Format is text or json.
+	Format string
+	// Output is stdout, stderr, file or syslog.
+	Output string
+	// File is the log file used with the file output.
+	File string
+	// MaxSizeMB is the size at which the log file is rotated.
+	MaxSizeMB int
+	// MaxBackups is the number of rotated log files kept.
+	MaxBackups int
+}
+
+// Setup installs the configured logger as the default slog logger and returns its output, so
+// other writers such as the HTTP access log can share it. Close the output on shutdown.
+func Setup(cfg Config) (io.WriteCloser, error) {
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := openOutput(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "", "text":
+		handler = slog.NewTextHandler(output, options)
+	case "json":
+		handler = slog.NewJSONHandler(output, options)
+	default:
+		_ = output.Close()
+		return nil, fmt.Errorf("unknown log format %q, use text or json", cfg.Format)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	return output, nil
+}
+
+// ParseLevel converts a level name to a slog level. An empty name is info.
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if name == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return slog.LevelInfo, fmt.Errorf("unknown log level %q, use debug, info, warn or error", name)
+	}
+	return level, nil
+}
+
+// openOutput opens the configured log output.
+func openOutput(cfg Config) (io.WriteCloser, error) {
+	switch strings.ToLower(cfg.Output) {
+	case "", "stdout":
+		return nopCloser{os.Stdout}, nil
+	case "stderr":
+		return nopCloser{os.Stderr}, nil
+	case "file":
+		if cfg.File == "" {
+			return nil, fmt.Errorf("LOG_FILE is required for file output")
+		}
+		return NewRotatingFile(cfg.File, int64(cfg.MaxSizeMB)*1024*1024, cfg.MaxBackups)
+	case "syslog":
+		return openSyslog()
+	}
+	return nil, fmt.Errorf("unknown log output %q, use stdout, stderr, file or syslog", cfg.Output)
+}
+
+// nopCloser keeps the standard streams open when the output is closed.
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,146 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestParseLevel(t *testing.T) {
+	testCases := map[string]slog.Level{
+		"":      slog.LevelInfo,
+		"debug": slog.LevelDebug,
+		"WARN":  slog.LevelWarn,
+		"error": slog.LevelError,
+	}
+	for name, expected := range testCases {
+		level, err := ParseLevel(name)
+		if err != nil || level != expected {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", name, level, err, expected)
+		}
+	}
+
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("Expected unknown level to be rejected")
+	}
+}
+
+func TestSetup(t *testing.T) {
+	previous := slog.Default()
+	defer slog.SetDefault(previous)
+
+	path := filepath.Join(t.TempDir(), "server.log")
+	output, err := Setup(Config{Level: "warn", Format: "json", Output: "file", File: path})
+	if err != nil {
+		t.Fatalf("Failed to set up logging: %v", err)
+	}
+
+	slog.Info("hidden")
+	slog.Warn("shown", "list", "groceries")
+	_ = output.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if strings.Contains(string(data), "hidden") {
+		t.Error("Expected messages below the level to be dropped")
+	}
+	if !strings.Contains(string(data), `"msg":"shown","list":"groceries"`) {
+		t.Errorf("Expected JSON log line, got %s", data)
+	}
+
+	if _, err := Setup(Config{Format: "xml"}); err == nil {
+		t.Error("Expected unknown format to be rejected")
+	}
+	if _, err := Setup(Config{Output: "file"}); err == nil {
+		t.Error("Expected file output without file to be rejected")
+	}
+	if _, err := Setup(Config{Output: "printer"}); err == nil {
+		t.Error("Expected unknown output to be rejected")
+	}
+}
+
+func TestAnonymizeIP(t *testing.T) {
+	testCases := map[string]string{
+		"203.0.113.42":                 "203.0.113.0",
+		"::ffff:203.0.113.42":          "203.0.113.0",
+		"2001:db8:abcd:12:34:56:78:9a": "2001:db8:abcd::",
+		"unknown":                      "unknown",
+	}
+	for address, expected := range testCases {
+		if anonymized := AnonymizeIP(address); anonymized != expected {
+			t.Errorf("AnonymizeIP(%q) = %q, want %q", address, anonymized, expected)
+		}
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.log")
+	file, err := NewRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("Failed to open log file: %v", err)
+	}
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := file.Write([]byte(line)); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+	}
+	if err := file.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	expected := map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	}
+	for name, content := range expected {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		if !bytes.Equal(data, []byte(content)) {
+			t.Errorf("Expected %q in %s, got %q", content, name, data)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("Expected only 2 backups to be kept")
+	}
+
+	if _, err := file.Write([]byte("late")); err == nil {
+		t.Error("Expected writes after close to fail")
+	}
+}
+
+func TestAccessLog_MasksTokens(t *testing.T) {
+	previous := slog.Default()
+	defer slog.SetDefault(previous)
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	app := fiber.New()
+	app.Use(AccessLog(false))
+	app.Get("/kiosk/:displayToken", func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+
+	if _, err := app.Test(httptest.NewRequest("GET", "/kiosk/sld_secret", nil)); err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+
+	logged := buf.String()
+	if strings.Contains(logged, "sld_secret") || !strings.Contains(logged, "path=/kiosk/[redacted]") {
+		t.Errorf("Expected token to be masked, got %s", logged)
+	}
+}
@@ -0,0 +1,65 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package logging
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AccessLog is a middleware that logs every request through the default slog logger. Server
+// errors are logged at error level, client errors at warn level and all others at info level.
+// With anonymizeIP set, client IPs are truncated before they are logged. Credentials in the
+// path, i.e. route parameters named like "displayToken", are masked.
+func AccessLog(anonymizeIP bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			if fiberErr, ok := err.(*fiber.Error); ok {
+				status = fiberErr.Code
+			} else {
+				status = fiber.StatusInternalServerError
+			}
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= fiber.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= fiber.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		slog.Log(c.UserContext(), level, "request",
+			"method", c.Method(),
+			"path", loggedPath(c),
+			"status", status,
+			"duration", time.Since(start),
+			"ip", ClientIP(c, anonymizeIP),
+			"request_id", c.GetRespHeader(fiber.HeaderXRequestID),
+		)
+		return err
+	}
+}
+
+// loggedPath returns the request path with the values of route parameters ending in "Token"
+// masked, so credentials passed in the path do not end up in the logs.
+func loggedPath(c *fiber.Ctx) string {
+	path := c.Path()
+	for _, name := range c.Route().Params {
+		if !strings.HasSuffix(name, "Token") {
+			continue
+		}
+		if value := c.Params(name); value != "" {
+			path = strings.Replace(path, value, "[redacted]", 1)
+		}
+	}
+	return path
+}
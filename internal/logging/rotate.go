@@ -0,0 +1,107 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package logging
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// RotatingFile is a log file that is rotated once it exceeds a maximum size. Rotated files are
+// renamed to name.1, name.2 and so on, keeping at most MaxBackups of them.
+type RotatingFile struct {
+	Path       string
+	MaxBytes   int64
+	MaxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewRotatingFile opens or creates the log file at path. A maxBytes of zero disables rotation.
+func NewRotatingFile(path string, maxBytes int64, maxBackups int) (*RotatingFile, error) {
+	r := &RotatingFile{Path: path, MaxBytes: maxBytes, MaxBackups: maxBackups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Write appends to the log file, rotating it first if the write would exceed the maximum size.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	if r.MaxBytes > 0 && r.size > 0 && r.size+int64(len(p)) > r.MaxBytes {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Close closes the log file.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// open opens the log file for appending.
+func (r *RotatingFile) open() error {
+	file, err := os.OpenFile(r.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+// rotate shifts the backups, moves the current file to the first backup and reopens it. If the
+// file cannot be moved, logging continues in the current file rather than stopping.
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+
+	if r.MaxBackups > 0 {
+		_ = os.Remove(backupName(r.Path, r.MaxBackups))
+		for i := r.MaxBackups - 1; i >= 1; i-- {
+			_ = os.Rename(backupName(r.Path, i), backupName(r.Path, i+1))
+		}
+		_ = os.Rename(r.Path, backupName(r.Path, 1))
+	} else {
+		_ = os.Remove(r.Path)
+	}
+
+	return r.open()
+}
+
+// backupName returns the name of the n-th rotated log file.
+func backupName(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
@@ -0,0 +1,16 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+//go:build windows || plan9
+
+package logging
+
+import (
+	"errors"
+	"io"
+)
+
+// openSyslog reports that syslog is not available on this platform.
+func openSyslog() (io.WriteCloser, error) {
+	return nil, errors.New("syslog output is not supported on this platform")
+}
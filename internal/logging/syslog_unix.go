@@ -0,0 +1,16 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+//go:build !windows && !plan9
+
+package logging
+
+import (
+	"io"
+	"log/syslog"
+)
+
+// openSyslog connects to the local syslog daemon.
+func openSyslog() (io.WriteCloser, error) {
+	return syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, "shopping-list-server")
+}
@@ -0,0 +1,68 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package mail sends emails through SMTP or, in log-only mode, writes them to the log instead,
+// e.g. when running in memory for benchmarks and integration tests.
+package mail
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/text/unicode/norm"
+	"gopkg.in/gomail.v2"
+)
+
+var logOnly atomic.Bool
+
+// sent and failed count the emails passed to Send since the server started.
+var sent, failed atomic.Int64
+
+// SetLogOnly switches between sending emails and logging them.
+func SetLogOnly(enabled bool) {
+	logOnly.Store(enabled)
+}
+
+// LogOnly reports whether emails are logged instead of sent.
+func LogOnly() bool {
+	return logOnly.Load()
+}
+
+// Send delivers a message through the dialer, or logs it in log-only mode.
+func Send(dialer *gomail.Dialer, m *gomail.Message) error {
+	if !LogOnly() {
+		if err := dialer.DialAndSend(m); err != nil {
+			failed.Add(1)
+			return err
+		}
+		sent.Add(1)
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if _, err := m.WriteTo(&buf); err != nil {
+		return err
+	}
+	slog.Info("email not sent, log-only mode",
+		"to", strings.Join(m.GetHeader("To"), ", "),
+		"subject", strings.Join(m.GetHeader("Subject"), " "),
+		"message", buf.String(),
+	)
+	sent.Add(1)
+	return nil
+}
+
+// Counts returns the number of emails sent and failed since the server started. Logged emails
+// count as sent.
+func Counts() (int64, int64) {
+	return sent.Load(), failed.Load()
+}
+
+// NormalizeAddress returns the canonical form of an email address under which users and
+// invitations are stored and looked up: trimmed, in Unicode normalization form NFC and lowercase,
+// so "Foo@Example.com " and "foo@example.com" are the same account.
+func NormalizeAddress(address string) string {
+	return strings.ToLower(norm.NFC.String(strings.TrimSpace(address)))
+}
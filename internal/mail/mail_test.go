@@ -0,0 +1,58 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package mail
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"gopkg.in/gomail.v2"
+)
+
+func TestSend_LogOnly(t *testing.T) {
+	SetLogOnly(true)
+	defer SetLogOnly(false)
+
+	previous := slog.Default()
+	defer slog.SetDefault(previous)
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", "server@example.com")
+	m.SetHeader("To", "user@example.com")
+	m.SetHeader("Subject", "Your Shopping List Login Code")
+	m.SetBody("text/plain", "Your login code is: 123456")
+
+	sentBefore, failedBefore := Counts()
+
+	// The dialer points nowhere, so sending would fail
+	if err := Send(gomail.NewDialer("invalid.invalid", 25, "", ""), m); err != nil {
+		t.Fatalf("Expected email to be logged, got %v", err)
+	}
+
+	logged := buf.String()
+	if !strings.Contains(logged, "to=user@example.com") || !strings.Contains(logged, "123456") {
+		t.Errorf("Expected email in log, got %s", logged)
+	}
+	if sentAfter, failedAfter := Counts(); sentAfter != sentBefore+1 || failedAfter != failedBefore {
+		t.Errorf("Expected logged email to count as sent, got %d sent and %d failed", sentAfter-sentBefore, failedAfter-failedBefore)
+	}
+}
+
+func TestNormalizeAddress(t *testing.T) {
+	tests := map[string]string{
+		"foo@example.com":       "foo@example.com",
+		" Foo@Example.COM\n":    "foo@example.com",
+		"jose\u0301@example.es": "jos\u00e9@example.es", // decomposed é is composed
+		"JOS\u00c9@example.es":  "jos\u00e9@example.es",
+	}
+	for input, expected := range tests {
+		if got := NormalizeAddress(input); got != expected {
+			t.Errorf("NormalizeAddress(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
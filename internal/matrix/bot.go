@@ -0,0 +1,206 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package matrix
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/activity"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+)
+
+// syncTimeout is how long a sync waits for new events before returning empty.
+const syncTimeout = 30 * time.Second
+
+// retryDelay is how long the bot waits after a failed sync.
+const retryDelay = 10 * time.Second
+
+// helpText lists the commands the bot understands.
+const helpText = `Commands:
+!add <items> [to <list>] - add items, e.g. "!add milk and eggs to groceries"
+!link <code> - link your Matrix account with the code from the app
+!unlink - unlink your Matrix account
+!help - show this help`
+
+// QuickAddFunc adds the items of a sentence like "milk and eggs to groceries" on behalf of a user.
+type QuickAddFunc func(userID, text string) (*models.ShoppingList, []models.ShoppingItem, error)
+
+// Bot answers commands in its room and posts the activity on the lists of linked users there.
+type Bot struct {
+	Client   *Client
+	Links    *Service
+	Activity *activity.Service
+	QuickAdd QuickAddFunc
+
+	self string
+
+	mu           sync.Mutex
+	lastActivity time.Time
+}
+
+// NewBot creates a bot for the room of the links service. Activity is posted from now on.
+func NewBot(client *Client, links *Service, activityService *activity.Service, quickAdd QuickAddFunc) *Bot {
+	return &Bot{
+		Client:       client,
+		Links:        links,
+		Activity:     activityService,
+		QuickAdd:     quickAdd,
+		lastActivity: time.Now(),
+	}
+}
+
+// Run joins the room and answers commands until the context is cancelled.
+func (b *Bot) Run(ctx context.Context) error {
+	self, err := b.Client.Whoami(ctx)
+	if err != nil {
+		return err
+	}
+	b.self = self
+
+	if err := b.Client.JoinRoom(ctx, b.Links.RoomID); err != nil {
+		return err
+	}
+
+	// The first sync only marks where to start, so old messages are not answered again
+	resp, err := b.Client.Sync(ctx, "", 0)
+	if err != nil {
+		return err
+	}
+	since := resp.NextBatch
+	slog.Info("Matrix bot started", "user", self, "room", b.Links.RoomID)
+
+	for {
+		resp, err := b.Client.Sync(ctx, since, syncTimeout)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			slog.Warn("Matrix sync failed", "error", err)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(retryDelay):
+			}
+			continue
+		}
+		since = resp.NextBatch
+
+		for _, event := range resp.Rooms.Join[b.Links.RoomID].Timeline.Events {
+			if event.Type != "m.room.message" || event.Sender == b.self {
+				continue
+			}
+			reply, ok := b.Handle(event.Sender, event.Content.Body)
+			if !ok {
+				continue
+			}
+			if err := b.Client.SendText(ctx, b.Links.RoomID, reply); err != nil {
+				slog.Warn("Matrix reply failed", "error", err)
+			}
+		}
+	}
+}
+
+// Handle answers a message of a Matrix user. Messages that are no commands are ignored.
+func (b *Bot) Handle(sender, body string) (string, bool) {
+	body = strings.TrimSpace(body)
+	if !strings.HasPrefix(body, "!") {
+		return "", false
+	}
+	command, args, _ := strings.Cut(body, " ")
+	args = strings.TrimSpace(args)
+
+	switch strings.ToLower(command) {
+	case "!help":
+		return helpText, true
+	case "!link":
+		if _, err := b.Links.Link(args, sender); err != nil {
+			return err.Error(), true
+		}
+		return "Your Matrix account is linked.", true
+	case "!unlink":
+		userID, err := b.Links.UserFor(sender)
+		if err == nil {
+			err = b.Links.Unlink(userID)
+		}
+		if err != nil {
+			return err.Error(), true
+		}
+		return "Your Matrix account is unlinked.", true
+	case "!add":
+		return b.add(sender, args), true
+	}
+	return "Unknown command. " + helpText, true
+}
+
+// add adds items on behalf of the user the sender is linked to.
+func (b *Bot) add(sender, text string) string {
+	userID, err := b.Links.UserFor(sender)
+	if errors.Is(err, ErrNotLinked) {
+		return "Link your Matrix account first: create a link code in the app and send it here with !link <code>."
+	}
+	if err != nil {
+		return err.Error()
+	}
+
+	list, items, err := b.QuickAdd(userID, text)
+	if err != nil {
+		return err.Error()
+	}
+
+	names := make([]string, len(items))
+	for i, item := range items {
+		names[i] = item.Name
+	}
+	return "Added " + strings.Join(names, ", ") + " to " + list.Name + "."
+}
+
+// PostActivity posts the items added and completed since the last call on the lists of linked
+// users to the room.
+func (b *Bot) PostActivity(ctx context.Context, now time.Time) error {
+	b.mu.Lock()
+	since := b.lastActivity
+	b.lastActivity = now
+	b.mu.Unlock()
+
+	lines, err := b.activitySince(since, now)
+	if err != nil || len(lines) == 0 {
+		return err
+	}
+	return b.Client.SendText(ctx, b.Links.RoomID, strings.Join(lines, "\n"))
+}
+
+// activitySince describes the activity between since and now on the lists of linked users,
+// oldest first.
+func (b *Bot) activitySince(since, now time.Time) ([]string, error) {
+	db := b.Links.DB
+	linkedUsers := db.Model(&models.MatrixLink{}).Select("user_id").Where("matrix_user_id IS NOT NULL")
+
+	var lists []models.ShoppingList
+	err := db.Where("id IN (?)", db.Model(&models.ListMember{}).Select("list_id").Where("user_id IN (?)", linkedUsers)).
+		Order("name ASC").
+		Find(&lists).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, list := range lists {
+		entries, err := b.Activity.Recent(list.ID, activity.DefaultLimit)
+		if err != nil {
+			return nil, err
+		}
+		for i := len(entries) - 1; i >= 0; i-- {
+			entry := entries[i]
+			if entry.At.After(since) && !entry.At.After(now) {
+				lines = append(lines, list.Name+": "+entry.ItemName+" "+entry.Kind)
+			}
+		}
+	}
+	return lines, nil
+}
@@ -0,0 +1,145 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package matrix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Client is a minimal client of the Matrix client-server API with just what the bot needs:
+// syncing, joining a room and sending text messages.
+type Client struct {
+	Homeserver string
+	Token      string
+	HTTP       *http.Client
+
+	txn atomic.Int64
+}
+
+// NewClient creates a client for the homeserver, authenticated with the access token of the
+// bot account.
+func NewClient(homeserver, token string) *Client {
+	c := &Client{
+		Homeserver: strings.TrimSuffix(homeserver, "/"),
+		Token:      token,
+		// Long-polling syncs take up to syncTimeout
+		HTTP: &http.Client{Timeout: syncTimeout + 30*time.Second},
+	}
+	c.txn.Store(time.Now().UnixNano())
+	return c
+}
+
+// Event is a room event as delivered by a sync.
+type Event struct {
+	Type    string `json:"type"`
+	Sender  string `json:"sender"`
+	EventID string `json:"event_id"`
+	Content struct {
+		MsgType string `json:"msgtype"`
+		Body    string `json:"body"`
+	} `json:"content"`
+}
+
+// SyncResponse holds the parts of a sync response the bot uses.
+type SyncResponse struct {
+	NextBatch string `json:"next_batch"`
+	Rooms     struct {
+		Join map[string]struct {
+			Timeline struct {
+				Events []Event `json:"events"`
+			} `json:"timeline"`
+		} `json:"join"`
+	} `json:"rooms"`
+}
+
+// do sends a request to the homeserver and decodes the JSON response into out, if given.
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.Homeserver+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		var matrixErr struct {
+			ErrCode string `json:"errcode"`
+			Error   string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&matrixErr)
+		return fmt.Errorf("matrix %s %s failed with status %d: %s %s", method, path, resp.StatusCode, matrixErr.ErrCode, matrixErr.Error)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Whoami returns the Matrix user ID of the bot account.
+func (c *Client) Whoami(ctx context.Context) (string, error) {
+	var resp struct {
+		UserID string `json:"user_id"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/_matrix/client/v3/account/whoami", nil, &resp); err != nil {
+		return "", err
+	}
+	return resp.UserID, nil
+}
+
+// JoinRoom joins the room, which also accepts a pending invitation to it.
+func (c *Client) JoinRoom(ctx context.Context, roomID string) error {
+	return c.do(ctx, http.MethodPost, "/_matrix/client/v3/join/"+url.PathEscape(roomID), struct{}{}, nil)
+}
+
+// SendText sends a plain text message to the room.
+func (c *Client) SendText(ctx context.Context, roomID, text string) error {
+	txnID := strconv.FormatInt(c.txn.Add(1), 10)
+	content := map[string]string{"msgtype": "m.text", "body": text}
+	return c.do(ctx, http.MethodPut,
+		"/_matrix/client/v3/rooms/"+url.PathEscape(roomID)+"/send/m.room.message/"+txnID, content, nil)
+}
+
+// Sync returns the events since the given batch token, waiting up to timeout for new ones.
+// An empty since token starts a new sync.
+func (c *Client) Sync(ctx context.Context, since string, timeout time.Duration) (*SyncResponse, error) {
+	query := url.Values{}
+	query.Set("timeout", strconv.FormatInt(timeout.Milliseconds(), 10))
+	if since != "" {
+		query.Set("since", since)
+	}
+
+	var resp SyncResponse
+	if err := c.do(ctx, http.MethodGet, "/_matrix/client/v3/sync?"+query.Encode(), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
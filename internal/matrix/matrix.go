@@ -0,0 +1,124 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package matrix provides an optional Matrix bot that posts list activity to a room and accepts
+// commands like "!add milk to groceries" from Matrix users linked to server users.
+package matrix
+
+import (
+	"errors"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/codes"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// LinkCodeLifetime is how long a code for linking a Matrix account stays valid.
+const LinkCodeLifetime = 15 * time.Minute
+
+// ErrNotLinked is returned when a user has not linked a Matrix account.
+var ErrNotLinked = errors.New("no Matrix account linked")
+
+// ErrInvalidLinkCode is returned when a link code is unknown or expired.
+var ErrInvalidLinkCode = errors.New("invalid or expired link code")
+
+// Service links server users to Matrix accounts.
+type Service struct {
+	DB *gorm.DB
+	// RoomID is the room of the bot. The integration is disabled when empty.
+	RoomID string
+}
+
+// NewService creates a new Matrix service; it stays disabled until a room is set.
+func NewService(db *gorm.DB) *Service {
+	return &Service{DB: db}
+}
+
+// Enabled reports whether the Matrix bot is configured.
+func (s *Service) Enabled() bool {
+	return s.RoomID != ""
+}
+
+// GetLink retrieves the Matrix link of the user.
+func (s *Service) GetLink(userID string) (*models.MatrixLink, error) {
+	var link models.MatrixLink
+	err := s.DB.First(&link, "user_id = ?", userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotLinked
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// CreateLinkCode creates a code the user sends to the bot as "!link <code>" from the Matrix
+// account to link. A linked account stays linked until the code is used.
+func (s *Service) CreateLinkCode(userID string) (*models.MatrixLink, error) {
+	expiresAt := time.Now().Add(LinkCodeLifetime)
+
+	link, err := s.GetLink(userID)
+	if errors.Is(err, ErrNotLinked) {
+		link = &models.MatrixLink{UserID: userID, CreatedAt: time.Now()}
+	} else if err != nil {
+		return nil, err
+	}
+	link.Code = codes.Digits(6)
+	link.CodeExpiresAt = &expiresAt
+
+	if err := s.DB.Save(link).Error; err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// Link connects the Matrix account that sent the code to the user who created it. A Matrix
+// account can only be linked to one user at a time.
+func (s *Service) Link(code, matrixUserID string) (*models.MatrixLink, error) {
+	var link models.MatrixLink
+	err := s.DB.Where("code = ? AND code_expires_at > ?", code, time.Now()).First(&link).Error
+	if err != nil {
+		return nil, ErrInvalidLinkCode
+	}
+
+	now := time.Now()
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		// Take the Matrix account over from a previous link
+		if err := tx.Where("matrix_user_id = ? AND user_id <> ?", matrixUserID, link.UserID).Delete(&models.MatrixLink{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&link).Updates(map[string]any{
+			"matrix_user_id":  matrixUserID,
+			"linked_at":       &now,
+			"code":            "",
+			"code_expires_at": nil,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetLink(link.UserID)
+}
+
+// Unlink removes the Matrix link of the user.
+func (s *Service) Unlink(userID string) error {
+	result := s.DB.Delete(&models.MatrixLink{}, "user_id = ?", userID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotLinked
+	}
+	return nil
+}
+
+// UserFor returns the ID of the user the Matrix account is linked to.
+func (s *Service) UserFor(matrixUserID string) (string, error) {
+	var link models.MatrixLink
+	if err := s.DB.Where("matrix_user_id = ?", matrixUserID).First(&link).Error; err != nil {
+		return "", ErrNotLinked
+	}
+	return link.UserID, nil
+}
@@ -0,0 +1,213 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package matrix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/activity"
+	"github.com/oliverandrich/shopping-list-server/internal/lists"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+	"gorm.io/gorm"
+)
+
+func createUser(t *testing.T, db *gorm.DB, id string) {
+	t.Helper()
+
+	user := models.User{ID: id, Email: id + "@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+}
+
+func TestService_Link(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+
+	if service.Enabled() {
+		t.Error("Expected the integration to be disabled without a room")
+	}
+	if _, err := service.GetLink("alice"); !errors.Is(err, ErrNotLinked) {
+		t.Fatalf("Expected ErrNotLinked, got %v", err)
+	}
+
+	pending, err := service.CreateLinkCode("alice")
+	if err != nil {
+		t.Fatalf("Failed to create link code: %v", err)
+	}
+	if pending.Code == "" || pending.MatrixUserID != nil {
+		t.Fatalf("Expected a pending link, got %+v", pending)
+	}
+
+	if _, err := service.Link("000000-wrong", "@alice:example.org"); !errors.Is(err, ErrInvalidLinkCode) {
+		t.Errorf("Expected ErrInvalidLinkCode, got %v", err)
+	}
+
+	link, err := service.Link(pending.Code, "@alice:example.org")
+	if err != nil {
+		t.Fatalf("Failed to link: %v", err)
+	}
+	if link.MatrixUserID == nil || *link.MatrixUserID != "@alice:example.org" || link.LinkedAt == nil {
+		t.Errorf("Expected a linked account, got %+v", link)
+	}
+	if _, err := service.Link(pending.Code, "@mallory:example.org"); !errors.Is(err, ErrInvalidLinkCode) {
+		t.Errorf("Expected used code to be invalid, got %v", err)
+	}
+
+	userID, err := service.UserFor("@alice:example.org")
+	if err != nil || userID != "alice" {
+		t.Errorf("Expected alice, got %q, %v", userID, err)
+	}
+
+	t.Run("account moves to the user linking it last", func(t *testing.T) {
+		code, err := service.CreateLinkCode("bob")
+		if err != nil {
+			t.Fatalf("Failed to create link code: %v", err)
+		}
+		if _, err := service.Link(code.Code, "@alice:example.org"); err != nil {
+			t.Fatalf("Failed to link: %v", err)
+		}
+		if userID, _ := service.UserFor("@alice:example.org"); userID != "bob" {
+			t.Errorf("Expected bob, got %q", userID)
+		}
+		if _, err := service.GetLink("alice"); !errors.Is(err, ErrNotLinked) {
+			t.Errorf("Expected alice's link to be removed, got %v", err)
+		}
+	})
+
+	t.Run("expired code", func(t *testing.T) {
+		code, err := service.CreateLinkCode("carol")
+		if err != nil {
+			t.Fatalf("Failed to create link code: %v", err)
+		}
+		db.Model(code).Update("code_expires_at", time.Now().Add(-time.Minute))
+		if _, err := service.Link(code.Code, "@carol:example.org"); !errors.Is(err, ErrInvalidLinkCode) {
+			t.Errorf("Expected ErrInvalidLinkCode, got %v", err)
+		}
+	})
+
+	if err := service.Unlink("bob"); err != nil {
+		t.Fatalf("Failed to unlink: %v", err)
+	}
+	if err := service.Unlink("bob"); !errors.Is(err, ErrNotLinked) {
+		t.Errorf("Expected ErrNotLinked, got %v", err)
+	}
+}
+
+func TestBot_Handle(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+	service.RoomID = "!room:example.org"
+
+	var added []string
+	bot := NewBot(nil, service, activity.NewService(db), func(userID, text string) (*models.ShoppingList, []models.ShoppingItem, error) {
+		added = append(added, userID+": "+text)
+		return &models.ShoppingList{Name: "Groceries"}, []models.ShoppingItem{{Name: "Milk"}}, nil
+	})
+
+	if _, ok := bot.Handle("@alice:example.org", "hello there"); ok {
+		t.Error("Expected plain messages to be ignored")
+	}
+	if reply, _ := bot.Handle("@alice:example.org", "!add milk to groceries"); !strings.Contains(reply, "!link") {
+		t.Errorf("Expected unlinked users to be asked to link, got %q", reply)
+	}
+
+	code, err := service.CreateLinkCode("alice")
+	if err != nil {
+		t.Fatalf("Failed to create link code: %v", err)
+	}
+	if reply, _ := bot.Handle("@alice:example.org", "!link "+code.Code); reply != "Your Matrix account is linked." {
+		t.Fatalf("Unexpected reply %q", reply)
+	}
+
+	reply, _ := bot.Handle("@alice:example.org", "!add milk to groceries")
+	if reply != "Added Milk to Groceries." {
+		t.Errorf("Unexpected reply %q", reply)
+	}
+	if len(added) != 1 || added[0] != "alice: milk to groceries" {
+		t.Errorf("Unexpected quick-adds %v", added)
+	}
+
+	if reply, _ := bot.Handle("@alice:example.org", "!unlink"); reply != "Your Matrix account is unlinked." {
+		t.Errorf("Unexpected reply %q", reply)
+	}
+	if reply, _ := bot.Handle("@alice:example.org", "!dance"); !strings.HasPrefix(reply, "Unknown command") {
+		t.Errorf("Unexpected reply %q", reply)
+	}
+}
+
+func TestBot_PostActivity(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+	service.RoomID = "!room:example.org"
+
+	var mu sync.Mutex
+	var messages []string
+	homeserver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method == http.MethodPut && strings.Contains(r.URL.Path, "/send/m.room.message/") {
+			var content map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&content)
+			mu.Lock()
+			messages = append(messages, content["body"])
+			mu.Unlock()
+		}
+		_, _ = w.Write([]byte(`{"event_id":"$1"}`))
+	}))
+	defer homeserver.Close()
+
+	createUser(t, db, "alice")
+	createUser(t, db, "bob")
+	groceries, err := lists.NewService(db).CreateList("alice", "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	private, err := lists.NewService(db).CreateList("bob", "Private")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	code, _ := service.CreateLinkCode("alice")
+	if _, err := service.Link(code.Code, "@alice:example.org"); err != nil {
+		t.Fatalf("Failed to link: %v", err)
+	}
+
+	bot := NewBot(NewClient(homeserver.URL, "secret"), service, activity.NewService(db), nil)
+	start := time.Now()
+	for _, item := range []models.ShoppingItem{
+		{ID: "old", ListID: groceries.ID, Name: "Bread", Tags: "[]", CreatedAt: start.Add(-time.Hour)},
+		{ID: "new", ListID: groceries.ID, Name: "Milk", Tags: "[]", CreatedAt: start.Add(time.Second)},
+		{ID: "hidden", ListID: private.ID, Name: "Gift", Tags: "[]", CreatedAt: start.Add(time.Second)},
+	} {
+		if err := db.Create(&item).Error; err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+
+	if err := bot.PostActivity(context.Background(), start.Add(time.Minute)); err != nil {
+		t.Fatalf("Failed to post activity: %v", err)
+	}
+	if len(messages) != 1 || messages[0] != "Groceries: Milk added" {
+		t.Errorf("Expected only the new item on the linked user's list, got %q", messages)
+	}
+
+	// Nothing new, nothing posted
+	if err := bot.PostActivity(context.Background(), start.Add(2*time.Minute)); err != nil {
+		t.Fatalf("Failed to post activity: %v", err)
+	}
+	if len(messages) != 1 {
+		t.Errorf("Expected no further message, got %q", messages)
+	}
+}
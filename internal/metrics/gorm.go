@@ -0,0 +1,90 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package metrics
+
+import (
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SlowQueries counts queries slower than the slow query threshold per route.
+var SlowQueries = Default.NewCounter("db_slow_queries_total",
+	"Number of database queries slower than the threshold by originating route.", "route")
+
+const startKey = "metrics:start"
+
+// SlowQueryPlugin is a GORM plugin that logs queries slower than Threshold together with the
+// route and request ID they originate from, and counts them in SlowQueries. Queries not run
+// with a request context are attributed to the route "unknown".
+type SlowQueryPlugin struct {
+	Threshold time.Duration
+}
+
+// Name returns the plugin name.
+func (p *SlowQueryPlugin) Name() string {
+	return "metrics:slow_queries"
+}
+
+// Initialize registers the timing callbacks for all kinds of statements.
+func (p *SlowQueryPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	processors := []struct {
+		name   string
+		before func(name string, fn func(*gorm.DB)) error
+		after  func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", callbacks.Create().Before("gorm:create").Register, callbacks.Create().After("gorm:create").Register},
+		{"query", callbacks.Query().Before("gorm:query").Register, callbacks.Query().After("gorm:query").Register},
+		{"update", callbacks.Update().Before("gorm:update").Register, callbacks.Update().After("gorm:update").Register},
+		{"delete", callbacks.Delete().Before("gorm:delete").Register, callbacks.Delete().After("gorm:delete").Register},
+		{"row", callbacks.Row().Before("gorm:row").Register, callbacks.Row().After("gorm:row").Register},
+		{"raw", callbacks.Raw().Before("gorm:raw").Register, callbacks.Raw().After("gorm:raw").Register},
+	}
+
+	for _, processor := range processors {
+		if err := processor.before("metrics:before_"+processor.name, p.before); err != nil {
+			return err
+		}
+		if err := processor.after("metrics:after_"+processor.name, p.after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *SlowQueryPlugin) before(db *gorm.DB) {
+	db.InstanceSet(startKey, time.Now())
+}
+
+func (p *SlowQueryPlugin) after(db *gorm.DB) {
+	value, ok := db.InstanceGet(startKey)
+	if !ok {
+		return
+	}
+	start, ok := value.(time.Time)
+	if !ok {
+		return
+	}
+
+	elapsed := time.Since(start)
+	if p.Threshold <= 0 || elapsed < p.Threshold {
+		return
+	}
+
+	request, ok := RequestFrom(db.Statement.Context)
+	if !ok {
+		request.Route = "unknown"
+	}
+
+	SlowQueries.Inc(request.Route)
+	slog.Warn("slow query",
+		"duration", elapsed,
+		"route", request.Route,
+		"request_id", request.ID,
+		"table", db.Statement.Table,
+		"sql", db.Statement.SQL.String(),
+	)
+}
@@ -0,0 +1,89 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HTTP request metrics, labeled with the route pattern rather than the path so list and item
+// IDs do not create a series each.
+var (
+	RequestsTotal = Default.NewCounter("http_requests_total",
+		"Number of HTTP requests by method, route and status.", "method", "route", "status")
+	RequestDuration = Default.NewSummary("http_request_duration_seconds",
+		"Duration of HTTP requests by method and route.", "method", "route")
+)
+
+// Middleware records the count and duration of every request per route.
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			status = fiberErr.Code
+		} else if err != nil {
+			status = fiber.StatusInternalServerError
+		}
+
+		route := c.Route().Path
+		RequestsTotal.Inc(c.Method(), route, strconv.Itoa(status))
+		RequestDuration.Observe(time.Since(start).Seconds(), c.Method(), route)
+		return err
+	}
+}
+
+// Handler exposes the registry in the Prometheus text format. With a token set, scrapers have
+// to send it as bearer token.
+func Handler(registry *Registry, token string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if token != "" {
+			expected := []byte("Bearer " + token)
+			if subtle.ConstantTimeCompare([]byte(c.Get(fiber.HeaderAuthorization)), expected) != 1 {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+					"error": "Invalid metrics token",
+				})
+			}
+		}
+
+		var buf bytes.Buffer
+		if err := registry.Write(&buf); err != nil {
+			return err
+		}
+
+		c.Set(fiber.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+		return c.Send(buf.Bytes())
+	}
+}
+
+type requestKey struct{}
+
+// Request identifies the request a database query originates from.
+type Request struct {
+	ID    string
+	Route string
+}
+
+// WithRequest attaches the originating request to a context passed to GORM via WithContext,
+// so slow queries can be attributed to a route.
+func WithRequest(ctx context.Context, request Request) context.Context {
+	return context.WithValue(ctx, requestKey{}, request)
+}
+
+// RequestFrom returns the request attached to a context, if any.
+func RequestFrom(ctx context.Context) (Request, bool) {
+	if ctx == nil {
+		return Request{}, false
+	}
+	request, ok := ctx.Value(requestKey{}).(Request)
+	return request, ok
+}
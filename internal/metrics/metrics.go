@@ -0,0 +1,220 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package metrics provides a minimal metrics registry exposed in the Prometheus text format,
+// per-route request metrics and a GORM plugin that logs and counts slow queries.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Registry holds all metrics exposed by the server.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+// metric is a family of values sharing a name and label names.
+type metric interface {
+	write(w io.Writer) error
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Default is the registry used by the server.
+var Default = NewRegistry()
+
+// NewCounter registers a counter with the given label names.
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	counter := &Counter{family: newFamily(name, help, labels)}
+	r.register(counter)
+	return counter
+}
+
+// NewSummary registers a summary tracking the sum and count of observations.
+func (r *Registry) NewSummary(name, help string, labels ...string) *Summary {
+	summary := &Summary{family: newFamily(name, help, labels)}
+	r.register(summary)
+	return summary
+}
+
+// Write writes all metrics in the Prometheus text exposition format.
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+
+	for _, m := range metrics {
+		if err := m.write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+// family stores one value set per combination of label values.
+type family struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	sum         float64
+	count       uint64
+}
+
+func newFamily(name, help string, labels []string) family {
+	return family{name: name, help: help, labels: labels, series: map[string]*series{}}
+}
+
+// observe adds a value to the series of the given label values.
+func (f *family) observe(value float64, labelValues []string) {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", f.name, len(f.labels), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		f.series[key] = s
+	}
+	s.sum += value
+	s.count++
+}
+
+// snapshot returns a copy of all series ordered by their label values.
+func (f *family) snapshot() []series {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]series, len(keys))
+	for i, key := range keys {
+		result[i] = *f.series[key]
+	}
+	return result
+}
+
+// labelString formats label values as {name="value",...}.
+func (f *family) labelString(values []string) string {
+	if len(f.labels) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, len(f.labels))
+	for i, name := range f.labels {
+		pairs[i] = name + `="` + escapeLabel(values[i]) + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func (f *family) header(w io.Writer, kind string) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, kind)
+	return err
+}
+
+// Counter is a value that only increases, such as the number of requests.
+type Counter struct {
+	family
+}
+
+// Inc increments the counter for the given label values.
+func (c *Counter) Inc(labelValues ...string) {
+	c.observe(1, labelValues)
+}
+
+// Value returns the current value for the given label values.
+func (c *Counter) Value(labelValues ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if s, ok := c.series[strings.Join(labelValues, "\xff")]; ok {
+		return s.sum
+	}
+	return 0
+}
+
+// Sum returns the total of all series whose label values match, or of all series if match is nil.
+func (c *Counter) Sum(match func(labelValues []string) bool) float64 {
+	total := 0.0
+	for _, s := range c.snapshot() {
+		if match == nil || match(s.labelValues) {
+			total += s.sum
+		}
+	}
+	return total
+}
+
+func (c *Counter) write(w io.Writer) error {
+	if err := c.header(w, "counter"); err != nil {
+		return err
+	}
+	for _, s := range c.snapshot() {
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", c.name, c.labelString(s.labelValues), formatFloat(s.sum)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Summary tracks the sum and count of observations such as request durations.
+type Summary struct {
+	family
+}
+
+// Observe records a value for the given label values.
+func (s *Summary) Observe(value float64, labelValues ...string) {
+	s.observe(value, labelValues)
+}
+
+func (s *Summary) write(w io.Writer) error {
+	if err := s.header(w, "summary"); err != nil {
+		return err
+	}
+	for _, series := range s.snapshot() {
+		labels := s.labelString(series.labelValues)
+		if _, err := fmt.Fprintf(w, "%s_sum%s %s\n%s_count%s %d\n",
+			s.name, labels, formatFloat(series.sum), s.name, labels, series.count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
@@ -0,0 +1,118 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+)
+
+func TestRegistry_Write(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.NewCounter("requests_total", "Requests.", "route")
+	summary := registry.NewSummary("duration_seconds", "Durations.", "route")
+
+	counter.Inc("/lists/:id")
+	counter.Inc("/lists/:id")
+	counter.Inc(`/a"b`)
+	summary.Observe(0.5, "/lists")
+	summary.Observe(0.25, "/lists")
+
+	var buf bytes.Buffer
+	if err := registry.Write(&buf); err != nil {
+		t.Fatalf("Failed to write metrics: %v", err)
+	}
+
+	expected := `# HELP requests_total Requests.
+# TYPE requests_total counter
+requests_total{route="/a\"b"} 1
+requests_total{route="/lists/:id"} 2
+# HELP duration_seconds Durations.
+# TYPE duration_seconds summary
+duration_seconds_sum{route="/lists"} 0.75
+duration_seconds_count{route="/lists"} 2
+`
+	if buf.String() != expected {
+		t.Errorf("Unexpected exposition:\n%s\nwant:\n%s", buf.String(), expected)
+	}
+	if counter.Value("/lists/:id") != 2 {
+		t.Errorf("Expected counter value 2, got %v", counter.Value("/lists/:id"))
+	}
+	if sum := counter.Sum(nil); sum != 3 {
+		t.Errorf("Expected a sum of 3, got %v", sum)
+	}
+	if sum := counter.Sum(func(labels []string) bool { return strings.HasPrefix(labels[0], "/lists") }); sum != 2 {
+		t.Errorf("Expected a sum of 2 for matching series, got %v", sum)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	app := fiber.New()
+	app.Use(Middleware())
+	app.Get("/metrics", Handler(Default, "secret"))
+	app.Get("/lists/:id", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	if _, err := app.Test(httptest.NewRequest("GET", "/lists/abc", nil)); err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	if RequestsTotal.Value("GET", "/lists/:id", "204") < 1 {
+		t.Error("Expected request to be counted by route pattern")
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/metrics", nil))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("Expected status 401 without token, got %d", resp.StatusCode)
+	}
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), `http_requests_total{method="GET",route="/lists/:id",status="204"}`) {
+		t.Errorf("Expected request metric in exposition, got:\n%s", body)
+	}
+}
+
+func TestSlowQueryPlugin(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	if err := db.Use(&SlowQueryPlugin{Threshold: time.Nanosecond}); err != nil {
+		t.Fatalf("Failed to register plugin: %v", err)
+	}
+
+	before := SlowQueries.Value("/lists/:id/items")
+	ctx := WithRequest(context.Background(), Request{ID: "req-1", Route: "/lists/:id/items"})
+
+	var items []models.ShoppingItem
+	if err := db.WithContext(ctx).Find(&items).Error; err != nil {
+		t.Fatalf("Failed to query: %v", err)
+	}
+	if SlowQueries.Value("/lists/:id/items") != before+1 {
+		t.Error("Expected slow query to be counted for its route")
+	}
+
+	before = SlowQueries.Value("unknown")
+	if err := db.Find(&items).Error; err != nil {
+		t.Fatalf("Failed to query: %v", err)
+	}
+	if SlowQueries.Value("unknown") != before+1 {
+		t.Error("Expected query without request to be counted as unknown")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 )
 
 // SystemSettings represents the global configuration and setup status of the application.
@@ -17,46 +18,250 @@ type SystemSettings struct {
 	IsSetup      bool      `gorm:"default:false" json:"is_setup"`
 	SetupAt      time.Time `json:"setup_at"`
 	InitialAdmin string    `json:"initial_admin"`
+	// AutoCreateDefaultList creates a default list for users joining via server invitation.
+	AutoCreateDefaultList bool `gorm:"default:true" json:"auto_create_default_list"`
+	// DefaultListNames overrides the built-in default list name per locale, e.g. {"de": "Einkauf"}.
+	DefaultListNames map[string]string `gorm:"serializer:json" json:"default_list_names"`
+	// AllowDirectMemberAdd lets list owners add registered users without an invitation.
+	AllowDirectMemberAdd bool `gorm:"default:false" json:"allow_direct_member_add"`
+	// LoginCodeLength is the number of characters of the codes sent for login; 0 uses the
+	// length of the server configuration.
+	LoginCodeLength int `json:"login_code_length"`
+	// LoginCodeAlphabet is the alphabet of login codes, "numeric" or "alphanumeric".
+	LoginCodeAlphabet string `gorm:"default:numeric" json:"login_code_alphabet"`
+	// SingleSession ends all previous sessions of a user when they log in again.
+	SingleSession bool `gorm:"default:false" json:"single_session"`
+	// ActivityRetentionDays is the number of days item merges are kept in the list activity;
+	// 0 keeps them forever.
+	ActivityRetentionDays int `json:"activity_retention_days"`
+	// AuditRetentionDays is the number of days moderation audit entries are kept; 0 keeps them forever.
+	AuditRetentionDays int `json:"audit_retention_days"`
+	// ItemHistoryRetentionDays is the number of days completed items are kept after completion;
+	// 0 keeps them forever.
+	ItemHistoryRetentionDays int `json:"item_history_retention_days"`
+	// WeeklyDigest emails the admin a weekly summary of the server's operation.
+	WeeklyDigest bool `gorm:"default:false" json:"weekly_digest"`
+	// DigestSentAt is when the last weekly digest was sent.
+	DigestSentAt *time.Time `json:"digest_sent_at"`
+	// DigestDatabaseBytes is the database size reported by the last digest, to report its growth.
+	DigestDatabaseBytes int64 `json:"-"`
+	// EndpointQuotas overrides the requests per minute and user allowed for groups of expensive
+	// endpoints, e.g. {"export": 20}; 0 removes the quota.
+	EndpointQuotas map[string]int `gorm:"serializer:json" json:"endpoint_quotas"`
 }
 
 // User represents a user account in the shopping list system.
 type User struct {
 	ID        string    `gorm:"primarykey" json:"id"`
-	Email     string    `gorm:"unique;not null" json:"email"`
+	Email     string    `gorm:"unique;not null;serializer:pii" json:"email"`
 	InvitedBy *string   `json:"invited_by"`
 	JoinedAt  time.Time `json:"joined_at"`
 	CreatedAt time.Time `json:"created_at"`
+	// HideFromContacts keeps the user out of other users' contact books.
+	HideFromContacts bool `gorm:"default:false" json:"hide_from_contacts"`
+	// TokenVersion is bumped to invalidate all JWTs issued before.
+	TokenVersion int `gorm:"default:0" json:"-"`
+	// InvitationsFrozen keeps the user from sending invitations, set by the admin.
+	InvitationsFrozen bool `gorm:"default:false" json:"invitations_frozen"`
+	// Role is the user's role on a list, filled in when listing its members.
+	Role string `gorm:"-" json:"role,omitempty"`
+	// ReplenishReminders emails the user the items their lists likely need soon once a week;
+	// ReplenishRemindedAt is when they were last emailed.
+	ReplenishReminders  bool       `gorm:"default:false" json:"-"`
+	ReplenishRemindedAt *time.Time `json:"-"`
 }
 
 // ShoppingList represents a shopping list that can be shared among users.
 type ShoppingList struct {
+	ID               string     `gorm:"primarykey" json:"id"`
+	Name             string     `gorm:"not null" json:"name"`
+	OwnerID          string     `gorm:"not null;index" json:"owner_id"`
+	Owner            User       `gorm:"foreignKey:OwnerID" json:"owner"`
+	StaleAfterDays   int        `gorm:"default:14" json:"stale_after_days"`
+	StaleNudgeSentAt *time.Time `json:"-"`
+	// CoOwnersCanDelete allows co-owners to delete the list, which is otherwise reserved to the owner.
+	CoOwnersCanDelete bool `gorm:"default:false" json:"co_owners_can_delete"`
+	// SkipDeduplication opts the list out of the scheduled merge of duplicate items.
+	SkipDeduplication bool `gorm:"default:false" json:"skip_deduplication"`
+	// TagPolicy decides what happens to item tags without metadata on the list: "open" accepts
+	// them, "create" adds metadata for them and "restrict" rejects them.
+	TagPolicy string `gorm:"default:'open'" json:"tag_policy"`
+	// SuggestedTags are filled in for a single list, see ListTag.Suggested.
+	SuggestedTags []ListTag `gorm:"-" json:"suggested_tags,omitempty"`
+	// MonthlyBudget is what the members plan to spend on the list per month, nil for no budget.
+	// BudgetAlertMonth is the last month, like 2025-03, its owners were alerted of exceeding it.
+	MonthlyBudget    *float64 `json:"monthly_budget"`
+	BudgetAlertMonth string   `json:"-"`
+	// Currency is the ISO 4217 code of the prices and budget of the list, empty for the server's
+	// default currency.
+	Currency string `json:"currency"`
+	// ArchivedAt is set when the list was archived, e.g. after it was merged into another list.
+	ArchivedAt    *time.Time `gorm:"index" json:"archived_at"`
+	UnseenChanges int        `gorm:"-" json:"unseen_changes"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// ListMember represents a user's membership in a shopping list with their role.
+type ListMember struct {
+	ListID     string     `gorm:"primarykey" json:"list_id"`
+	UserID     string     `gorm:"primarykey" json:"user_id"`
+	Role       string     `gorm:"default:'editor'" json:"role"`
+	JoinedAt   time.Time  `json:"joined_at"`
+	LastSeenAt *time.Time `json:"last_seen_at"`
+}
+
+// ListAlias represents an alternative name of a shopping list used to resolve spoken list names.
+type ListAlias struct {
+	ListID    string    `gorm:"primarykey" json:"list_id"`
+	Alias     string    `gorm:"primarykey" json:"alias"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ListSection is a header inside a shopping list that items can be assigned to, e.g. to separate
+// the supermarket from the pharmacy on a single trip list.
+type ListSection struct {
 	ID        string    `gorm:"primarykey" json:"id"`
+	ListID    string    `gorm:"not null;index" json:"list_id"`
 	Name      string    `gorm:"not null" json:"name"`
-	OwnerID   string    `gorm:"not null;index" json:"owner_id"`
-	Owner     User      `gorm:"foreignKey:OwnerID" json:"owner"`
+	Position  int       `gorm:"default:0" json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ListTag holds the display metadata of a tag used on a list's items, so all clients render it
+// identically. Tags are matched by name against the items' tag arrays.
+type ListTag struct {
+	ListID    string    `gorm:"primarykey" json:"list_id"`
+	Name      string    `gorm:"primarykey" json:"name"`
+	Color     string    `json:"color"`
+	Icon      string    `json:"icon"`
+	Position  int       `gorm:"default:0" json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+	// Suggested tags are offered by clients as chips when tagging items. Only owners choose them.
+	Suggested bool `gorm:"default:false" json:"suggested"`
+}
+
+// SmartList is a user's saved filter, exposed as a virtual list of the matching items across
+// all lists the user is a member of.
+type SmartList struct {
+	ID        string     `gorm:"primarykey" json:"id"`
+	UserID    string     `gorm:"not null;index" json:"-"`
+	Name      string     `gorm:"not null" json:"name"`
+	Filter    ItemFilter `gorm:"serializer:json" json:"filter"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// ItemFilter selects items across lists. Empty criteria match everything; tags and categories
+// match if any of the given values match.
+type ItemFilter struct {
+	ListIDs        []string `json:"list_ids,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+	Categories     []string `json:"categories,omitempty"`
+	Query          string   `json:"query,omitempty"`
+	Completed      *bool    `json:"completed,omitempty"`
+	Stale          *bool    `json:"stale,omitempty"`
+	IncludeSnoozed bool     `json:"include_snoozed,omitempty"`
+	// Allergens matches items containing any of the allergens, e.g. "gluten".
+	Allergens []string `json:"allergens,omitempty"`
+}
+
+// HistoryFilter selects completed items in a history search. From and To are days like
+// 2025-03-01, both inclusive.
+type HistoryFilter struct {
+	Query  string
+	ListID string
+	From   string
+	To     string
+	Limit  int
+}
+
+// ItemHistory is the result of a history search. Total counts all matching items and
+// LastCompletedAt is the most recent completion among them, nil if none matched.
+type ItemHistory struct {
+	Items           []ItemHistoryEntry `json:"items"`
+	Total           int64              `json:"total"`
+	LastCompletedAt *time.Time         `json:"last_completed_at"`
+}
+
+// ItemHistoryEntry is a completed item with the list it is on, which may be archived.
+type ItemHistoryEntry struct {
+	ShoppingItem
+	ListName     string `json:"list_name"`
+	ListArchived bool   `json:"list_archived"`
+}
+
+// ListNote is a member's private scratchpad on a shopping list, never shown to other members.
+type ListNote struct {
+	ListID    string    `gorm:"primarykey" json:"list_id"`
+	UserID    string    `gorm:"primarykey" json:"-"`
+	Note      string    `gorm:"type:text" json:"note"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ShoppingTrip represents the next planned shopping trip of a list.
+type ShoppingTrip struct {
+	ID             string     `gorm:"primarykey" json:"id"`
+	ListID         string     `gorm:"not null;uniqueIndex" json:"list_id"`
+	ScheduledAt    time.Time  `gorm:"not null;index" json:"scheduled_at"`
+	Note           string     `json:"note"`
+	CreatedBy      string     `gorm:"not null" json:"created_by"`
+	ReminderSentAt *time.Time `json:"reminder_sent_at"`
+	RSVPs          []TripRSVP `gorm:"foreignKey:TripID" json:"rsvps"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TripRSVP represents a list member's response to a planned shopping trip.
+type TripRSVP struct {
+	TripID    string    `gorm:"primarykey" json:"trip_id"`
+	UserID    string    `gorm:"primarykey" json:"user_id"`
+	Status    string    `gorm:"not null" json:"status"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ItemPoll represents a poll among the members of a list about an item, e.g. which brand to buy.
+type ItemPoll struct {
+	ID        string     `gorm:"primarykey" json:"id"`
+	ItemID    string     `gorm:"not null;uniqueIndex" json:"item_id"`
+	ListID    string     `gorm:"not null;index" json:"list_id"`
+	Question  string     `gorm:"not null" json:"question"`
+	Options   []string   `gorm:"serializer:json" json:"options"`
+	CreatedBy string     `gorm:"not null" json:"created_by"`
+	Votes     []PollVote `gorm:"foreignKey:PollID" json:"votes"`
+	// Tally counts the votes per option, in the order of the options.
+	Tally     []int     `gorm:"-" json:"tally"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
-// ListMember represents a user's membership in a shopping list with their role.
-type ListMember struct {
-	ListID   string    `gorm:"primarykey" json:"list_id"`
-	UserID   string    `gorm:"primarykey" json:"user_id"`
-	Role     string    `gorm:"default:'member'" json:"role"`
-	JoinedAt time.Time `json:"joined_at"`
+// PollVote represents a list member's vote in an item poll.
+type PollVote struct {
+	PollID string `gorm:"primarykey" json:"poll_id"`
+	UserID string `gorm:"primarykey" json:"user_id"`
+	// Option is the index of the chosen option.
+	Option    int       `json:"option"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // Invitation represents an invitation for a user to join the system or a specific list.
 type Invitation struct {
 	ID        string    `gorm:"primarykey" json:"id"`
 	Code      string    `gorm:"unique;not null" json:"code"`
-	Email     string    `gorm:"not null" json:"email"`
+	Email     string    `gorm:"not null;serializer:pii" json:"email"`
 	Type      string    `gorm:"not null" json:"type"`
 	ListID    *string   `json:"list_id"`
 	InvitedBy string    `gorm:"not null" json:"invited_by"`
 	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
 	Used      bool      `gorm:"default:false" json:"used"`
 	CreatedAt time.Time `json:"created_at"`
+	// Lists holds the lists granted by a bundle invitation.
+	Lists []InvitationList `gorm:"foreignKey:InvitationID" json:"lists,omitempty"`
+}
+
+// InvitationList is a line item of a bundle invitation granting membership to one list.
+type InvitationList struct {
+	InvitationID string `gorm:"primarykey" json:"-"`
+	ListID       string `gorm:"primarykey" json:"list_id"`
 }
 
 // MagicLink represents a temporary authentication code sent via email.
@@ -67,15 +272,301 @@ type MagicLink struct {
 	Used      bool      `gorm:"default:false" json:"used"`
 }
 
+// LoginLink represents the one-time token of a clickable login link, sent via email along with
+// the login code. The token is the ID signed with the JWT secret, so the ID alone cannot log in.
+type LoginLink struct {
+	ID        string    `gorm:"primarykey" json:"id"`
+	Email     string    `gorm:"not null;index" json:"email"`
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+	Used      bool      `gorm:"default:false" json:"used"`
+}
+
+// LoginThrottle counts login codes requested or verification attempts made per address or
+// client IP within a fixed window, see auth.Service.AllowLoginCode.
+type LoginThrottle struct {
+	Key       string    `gorm:"primarykey" json:"key"`
+	Count     int       `gorm:"not null" json:"count"`
+	WindowEnd time.Time `gorm:"not null;index" json:"window_end"`
+}
+
 // ShoppingItem represents an item in a shopping list.
 type ShoppingItem struct {
+	ID           string       `gorm:"primarykey" json:"id"`
+	ListID       string       `gorm:"not null;index" json:"list_id"`
+	List         ShoppingList `gorm:"foreignKey:ListID" json:"list,omitempty"`
+	Name         string       `json:"name"`
+	Completed    bool         `json:"completed" gorm:"default:false"`
+	Tags         string       `json:"tags" gorm:"default:'[]'"`
+	Category     string       `json:"category"`
+	Emoji        string       `json:"emoji"`
+	Quantity     float64      `json:"quantity"`
+	Unit         string       `json:"unit"`
+	SectionID    *string      `gorm:"index" json:"section_id"`
+	SnoozedUntil *time.Time   `gorm:"index" json:"snoozed_until"`
+	DueDate      *time.Time   `gorm:"index" json:"due_date"`
+	CompletedAt  *time.Time   `gorm:"index" json:"completed_at"`
+	// CompletedBy is the user who checked the item off, nil while it is open.
+	CompletedBy *string `gorm:"index" json:"completed_by"`
+	// LastPurchasedAt is when an item of the same name was last checked off on the list, and
+	// PurchaseIntervalDays the average number of days between these purchases; both are filled
+	// in for responses from the item history.
+	LastPurchasedAt      *time.Time `gorm:"-" json:"last_purchased_at,omitempty"`
+	PurchaseIntervalDays *float64   `gorm:"-" json:"purchase_interval_days,omitempty"`
+	// Price and ImageURL are filled in by the list's enrichment hook, if any.
+	Price    *float64 `json:"price"`
+	ImageURL string   `json:"image_url"`
+	Stale    bool     `gorm:"-" json:"stale"`
+	// NutritionGrade and Allergens are looked up in Open Food Facts by Barcode or name, if enabled.
+	// Allergens is null until the item was found.
+	Barcode        string   `gorm:"index" json:"barcode"`
+	NutritionGrade string   `json:"nutrition_grade"`
+	Allergens      []string `gorm:"serializer:json" json:"allergens"`
+	// CreatedBy is the user who added the item, nil for items added before it was recorded.
+	// CreatedByName is their display name, filled in for responses.
+	CreatedBy     *string   `gorm:"index" json:"created_by"`
+	CreatedByName string    `gorm:"-" json:"created_by_name,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	// DeletedAt is set when the item is moved to the trash of its list. Queries leave trashed
+	// items out unless they are unscoped.
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+}
+
+// Purchase records the price of an item when it was checked off, so spend reports outlive the
+// item. Checking the item off again removes the purchase.
+type Purchase struct {
+	ID          string    `gorm:"primarykey" json:"id"`
+	ListID      string    `gorm:"not null;index" json:"list_id"`
+	ItemID      string    `gorm:"not null;index" json:"item_id"`
+	ItemName    string    `json:"item_name"`
+	Category    string    `json:"category"`
+	Price       float64   `json:"price"`
+	UserID      string    `gorm:"index" json:"user_id"`
+	PurchasedAt time.Time `gorm:"index" json:"purchased_at"`
+}
+
+// ReplenishSuggestion tracks an item of a list that is bought regularly, to suggest it again once
+// its next purchase is due. Items are matched by Key, their lowercased name. Factor stretches or
+// shrinks the average interval between purchases and is tuned by accepting and dismissing the
+// suggestion.
+type ReplenishSuggestion struct {
+	ID              string    `gorm:"primarykey" json:"id"`
+	ListID          string    `gorm:"not null;uniqueIndex:idx_replenish_list_key" json:"list_id"`
+	Key             string    `gorm:"not null;uniqueIndex:idx_replenish_list_key" json:"-"`
+	Name            string    `json:"name"`
+	ListName        string    `gorm:"-" json:"list_name,omitempty"`
+	Purchases       int       `json:"purchases"`
+	LastPurchasedAt time.Time `json:"last_purchased_at"`
+	IntervalDays    float64   `json:"interval_days"`
+	Factor          float64   `gorm:"default:1" json:"-"`
+	DueAt           time.Time `gorm:"index" json:"due_at"`
+	// Status is "open" while the item is suggested, "waiting" until it is due and "accepted" or
+	// "dismissed" after feedback, until the item is bought again.
+	Status    string    `gorm:"index" json:"status"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NutritionFacts caches what Open Food Facts knows about a product, keyed by barcode or by
+// normalized name. Products that were not found are cached too, so they are not looked up again.
+type NutritionFacts struct {
+	Key            string    `gorm:"primarykey" json:"-"`
+	Barcode        string    `json:"barcode"`
+	ProductName    string    `json:"product_name"`
+	NutritionGrade string    `json:"nutrition_grade"`
+	Allergens      []string  `gorm:"serializer:json" json:"allergens"`
+	Found          bool      `json:"found"`
+	FetchedAt      time.Time `json:"fetched_at"`
+}
+
+// ItemMerge records that duplicate open items of a list were merged into one item.
+type ItemMerge struct {
+	ID       string  `gorm:"primarykey" json:"id"`
+	ListID   string  `gorm:"not null;index" json:"list_id"`
+	ItemID   string  `gorm:"not null;index" json:"item_id"`
+	ItemName string  `json:"item_name"`
+	Merged   int     `json:"merged"`
+	Quantity float64 `json:"quantity"`
+	Unit     string  `json:"unit"`
+	// UserID is the user who requested the merge; it is empty for the scheduled job.
+	UserID    string    `gorm:"index" json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Attachment represents a file, usually a photo, attached to a shopping item.
+type Attachment struct {
+	ID            string    `gorm:"primarykey" json:"id"`
+	ListID        string    `gorm:"not null;index" json:"list_id"`
+	ItemID        string    `gorm:"not null;index" json:"item_id"`
+	UserID        string    `gorm:"not null;index" json:"user_id"`
+	FileName      string    `json:"file_name"`
+	ContentType   string    `json:"content_type"`
+	Size          int64     `json:"size"`
+	ThumbnailSize int64     `json:"thumbnail_size"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// CategoryMapping represents an admin-defined keyword to category mapping used for auto-categorization.
+type CategoryMapping struct {
+	Keyword   string    `gorm:"primarykey" json:"keyword"`
+	Category  string    `gorm:"not null" json:"category"`
+	Language  string    `json:"language"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// APIKey represents a long-lived API key used by integrations such as voice assistants. Scopes is a
+// space-separated list of scopes the key is restricted to, empty for all; RateLimit is the number
+// of requests per minute, 0 for the server default.
+type APIKey struct {
+	ID         string     `gorm:"primarykey" json:"id"`
+	UserID     string     `gorm:"not null;index" json:"user_id"`
+	Name       string     `gorm:"not null" json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `gorm:"unique;not null" json:"-"`
+	Scopes     string     `json:"scopes"`
+	RateLimit  int        `json:"rate_limit"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// DisplayToken grants read-only access to a single list, e.g. for a kitchen e-ink dashboard.
+type DisplayToken struct {
+	ID         string     `gorm:"primarykey" json:"id"`
+	ListID     string     `gorm:"not null;index" json:"list_id"`
+	CreatedBy  string     `gorm:"not null" json:"created_by"`
+	Name       string     `gorm:"not null" json:"name"`
+	Prefix     string     `json:"prefix"`
+	TokenHash  string     `gorm:"unique;not null" json:"-"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// EnrichmentHook is a URL the server calls for every item created on a list, merging the price,
+// image and category it returns into the item.
+type EnrichmentHook struct {
+	ListID    string    `gorm:"primarykey" json:"list_id"`
+	URL       string    `gorm:"not null" json:"url"`
+	CreatedBy string    `gorm:"not null" json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ListRule is an automation rule of a list: when an item event matches the condition, e.g. an
+// item tagged "pharmacy" was added, the actions run, e.g. notifying a member.
+type ListRule struct {
 	ID        string       `gorm:"primarykey" json:"id"`
 	ListID    string       `gorm:"not null;index" json:"list_id"`
-	List      ShoppingList `gorm:"foreignKey:ListID" json:"list,omitempty"`
-	Name      string       `json:"name"`
-	Completed bool         `json:"completed" gorm:"default:false"`
-	Tags      string       `json:"tags" gorm:"default:'[]'"`
+	CreatedBy string       `gorm:"not null" json:"created_by"`
+	Name      string       `gorm:"not null" json:"name"`
+	Event     string       `gorm:"not null" json:"event"`
+	Condition string       `gorm:"not null" json:"condition"`
+	Actions   []RuleAction `gorm:"serializer:json" json:"actions"`
+	Enabled   bool         `json:"enabled"`
 	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// RuleAction is what a rule does with a matching item: add_tag and set_category take the tag or
+// category as value, notify the ID of the list member to email.
+type RuleAction struct {
+	Type  string `json:"type" validate:"required,oneof=add_tag set_category notify"`
+	Value string `json:"value" validate:"required,max=100"`
+}
+
+// CalendarFeed is a user's secret iCalendar feed URL with due-dated items and planned shopping trips.
+type CalendarFeed struct {
+	UserID     string     `gorm:"primarykey" json:"-"`
+	Prefix     string     `json:"prefix"`
+	TokenHash  string     `gorm:"unique;not null" json:"-"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// MatrixLink connects a user to a Matrix account the Matrix bot acts on behalf of. Until the user
+// sends the link code to the bot, MatrixUserID is empty.
+type MatrixLink struct {
+	UserID        string     `gorm:"primarykey" json:"-"`
+	MatrixUserID  *string    `gorm:"uniqueIndex" json:"matrix_user_id"`
+	Code          string     `gorm:"index" json:"-"`
+	CodeExpiresAt *time.Time `json:"-"`
+	LinkedAt      *time.Time `json:"linked_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// ChatLink connects a Slack workspace or Discord server to a user, so slash commands from it act
+// on behalf of that user.
+type ChatLink struct {
+	Platform    string    `gorm:"primarykey" json:"platform"`
+	WorkspaceID string    `gorm:"primarykey" json:"workspace_id"`
+	UserID      string    `gorm:"not null;index" json:"-"`
+	LinkedAt    time.Time `json:"linked_at"`
+}
+
+// ChatLinkCode is a short-lived code a user enters with "/shop link <code>" to link a workspace.
+type ChatLinkCode struct {
+	Code      string    `gorm:"primarykey" json:"code"`
+	UserID    string    `gorm:"not null;index" json:"-"`
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+}
+
+// FeatureFlag lets the admin roll out a client feature gradually. Rollout is the percentage of
+// users the flag is on for; Overrides force it on or off for single users.
+type FeatureFlag struct {
+	Key         string                `gorm:"primarykey" json:"key"`
+	Description string                `json:"description"`
+	Rollout     int                   `gorm:"not null;default:0" json:"rollout"`
+	Overrides   []FeatureFlagOverride `gorm:"foreignKey:FlagKey" json:"overrides"`
+	CreatedAt   time.Time             `json:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at"`
+}
+
+// FeatureFlagOverride forces a feature flag on or off for a user.
+type FeatureFlagOverride struct {
+	FlagKey   string    `gorm:"primarykey" json:"-"`
+	UserID    string    `gorm:"primarykey" json:"user_id"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Announcement represents an admin broadcast message such as a maintenance window shown to all users.
+type Announcement struct {
+	ID        string     `gorm:"primarykey" json:"id"`
+	Title     string     `gorm:"not null" json:"title"`
+	Body      string     `gorm:"not null" json:"body"`
+	ExpiresAt *time.Time `gorm:"index" json:"expires_at"`
+	CreatedBy string     `gorm:"not null" json:"created_by"`
+	EmailedAt *time.Time `json:"emailed_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// PolicyAcceptance records that a user accepted a specific version of a policy document.
+type PolicyAcceptance struct {
+	UserID       string    `gorm:"primarykey" json:"user_id"`
+	DocumentType string    `gorm:"primarykey" json:"document_type"`
+	Version      string    `gorm:"primarykey" json:"version"`
+	AcceptedAt   time.Time `json:"accepted_at"`
+}
+
+// AuditEntry records an admin action on a user account, such as ending their sessions, or on
+// the whole server, such as purging old data.
+type AuditEntry struct {
+	ID      string `gorm:"primarykey" json:"id"`
+	ActorID string `gorm:"not null" json:"actor_id"`
+	Action  string `gorm:"not null" json:"action"`
+	// TargetUserID is empty for actions on the whole server, such as purges.
+	TargetUserID string `gorm:"not null;index" json:"target_user_id"`
+	// DryRun marks previews of an action that changed nothing.
+	DryRun bool `gorm:"default:false" json:"dry_run"`
+	// Details summarizes the rows affected by the action as JSON, if it counts any.
+	Details   string    `json:"details,omitempty"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// PolicyDocument describes the current version of a policy document such as the terms of service.
+type PolicyDocument struct {
+	Type    string `json:"type"`
+	Version string `json:"version"`
+	URL     string `json:"url"`
 }
 
 // LoginRequest represents a request to initiate login via magic link.
@@ -87,12 +578,105 @@ type LoginRequest struct {
 type VerifyRequest struct {
 	Email string `json:"email" validate:"required,email"`
 	Code  string `json:"code" validate:"required"`
+	// InvitationCode optionally picks the invitation to accept when several are pending.
+	InvitationCode string `json:"invitation_code"`
 }
 
 // CreateItemRequest represents a request to create a new shopping item.
 type CreateItemRequest struct {
-	Name string `json:"name" validate:"required"`
-	Tags string `json:"tags"`
+	Name          string  `json:"name" validate:"required"`
+	Tags          string  `json:"tags"`
+	Category      string  `json:"category"`
+	Quantity      float64 `json:"quantity" validate:"gte=0"`
+	Unit          string  `json:"unit"`
+	ParseQuantity bool    `json:"parse_quantity"`
+	SectionID     *string `json:"section_id"`
+	// DueDate is a date like 2025-03-01; an empty string removes the due date on update.
+	DueDate *string `json:"due_date" validate:"omitzero,datetime=2006-01-02"`
+	// Barcode is the EAN or UPC of the product, used to look up its nutrition facts.
+	Barcode string `json:"barcode" validate:"omitempty,numeric,min=8,max=14"`
+}
+
+// IntegrationAddItemRequest represents a request of an automation platform to add an item to a
+// list given by its name or alias.
+type IntegrationAddItemRequest struct {
+	List     string  `json:"list" validate:"required"`
+	Name     string  `json:"name" validate:"required"`
+	Quantity float64 `json:"quantity" validate:"gte=0"`
+	Unit     string  `json:"unit"`
+}
+
+// SmartAddItemRequest represents a request to add an item only if no open item with the same
+// name exists on the user's lists, unless Force is set.
+type SmartAddItemRequest struct {
+	CreateItemRequest
+	Force bool `json:"force"`
+}
+
+// SmartAddItemResponse tells whether the item was created or lists the open items it duplicates.
+type SmartAddItemResponse struct {
+	Created    bool             `json:"created"`
+	Item       *ShoppingItem    `json:"item,omitempty"`
+	Duplicates []DuplicateMatch `json:"duplicates,omitempty"`
+}
+
+// BatchItemsRequest represents item changes applied in order in one transaction, e.g. those a
+// client queued while offline. Unless Atomic is set, failed operations are skipped and the
+// others are kept.
+type BatchItemsRequest struct {
+	Operations []BatchItemOperation `json:"operations" validate:"required,min=1,max=100"`
+	Atomic     bool                 `json:"atomic"`
+}
+
+// BatchItemOperation is one change of a batch. Creates may bring their own item ID, so later
+// operations can refer to the new item; toggles set Completed if given instead of flipping the
+// item, which makes replaying them harmless.
+type BatchItemOperation struct {
+	Op        string             `json:"op" validate:"required,oneof=create update toggle delete"`
+	ItemID    string             `json:"item_id" validate:"omitempty,uuid"`
+	Item      *CreateItemRequest `json:"item"`
+	Completed *bool              `json:"completed"`
+}
+
+// BatchItemResult is the outcome of an operation of a batch, with the status the endpoint of the
+// single operation would have answered.
+type BatchItemResult struct {
+	Index   int               `json:"index"`
+	Op      string            `json:"op"`
+	Status  int               `json:"status"`
+	ItemID  string            `json:"item_id,omitempty"`
+	Item    *ShoppingItem     `json:"item,omitempty"`
+	Error   string            `json:"error,omitempty"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// BatchItemsResponse lists the results of a batch; Committed is false if an atomic batch was
+// rolled back.
+type BatchItemsResponse struct {
+	Committed bool              `json:"committed"`
+	Results   []BatchItemResult `json:"results"`
+}
+
+// UnitConversion is a converted quantity with its display form in the caller's language.
+type UnitConversion struct {
+	Quantity  float64 `json:"quantity"`
+	Unit      string  `json:"unit"`
+	Formatted string  `json:"formatted"`
+}
+
+// DuplicateMatch is an open item with the same name as an item about to be added.
+type DuplicateMatch struct {
+	ItemID   string  `json:"item_id"`
+	ListID   string  `json:"list_id"`
+	ListName string  `json:"list_name"`
+	Name     string  `json:"name"`
+	Quantity float64 `json:"quantity"`
+	Unit     string  `json:"unit"`
+}
+
+// SnoozeItemRequest represents a request to hide an item from the active list until a given time.
+type SnoozeItemRequest struct {
+	Until time.Time `json:"until" validate:"required"`
 }
 
 // CreateListRequest represents a request to create a new shopping list.
@@ -100,9 +684,300 @@ type CreateListRequest struct {
 	Name string `json:"name" validate:"required"`
 }
 
+// UpdateServerSettingsRequest represents a request to change the server settings. Omitted fields
+// are left unchanged; an empty name removes the override for that locale.
+type UpdateServerSettingsRequest struct {
+	AutoCreateDefaultList *bool             `json:"auto_create_default_list"`
+	DefaultListNames      map[string]string `json:"default_list_names"`
+	AllowDirectMemberAdd  *bool             `json:"allow_direct_member_add"`
+	LoginCodeLength       *int              `json:"login_code_length" validate:"omitempty,min=0,max=12"`
+	LoginCodeAlphabet     *string           `json:"login_code_alphabet" validate:"omitempty,oneof=numeric alphanumeric"`
+	SingleSession         *bool             `json:"single_session"`
+	// Retention periods in days, 0 keeps the data forever.
+	ActivityRetentionDays    *int  `json:"activity_retention_days" validate:"omitempty,min=0"`
+	AuditRetentionDays       *int  `json:"audit_retention_days" validate:"omitempty,min=0"`
+	ItemHistoryRetentionDays *int  `json:"item_history_retention_days" validate:"omitempty,min=0"`
+	WeeklyDigest             *bool `json:"weekly_digest"`
+	// EndpointQuotas sets the quota of endpoint groups; null restores the default.
+	EndpointQuotas map[string]*int `json:"endpoint_quotas"`
+}
+
+// ListBatchResponse holds several lists fetched at once. Items maps the ID of every list to its
+// items if they were requested; Missing holds the requested IDs of lists that do not exist or
+// are not accessible.
+type ListBatchResponse struct {
+	Lists   []ShoppingList            `json:"lists"`
+	Items   map[string][]ShoppingItem `json:"items,omitempty"`
+	Missing []string                  `json:"missing"`
+}
+
+// AddListMemberRequest represents a request to add a registered user to a list by email.
+type AddListMemberRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// AddListMemberResponse tells whether the user was added directly or invited in-app.
+type AddListMemberResponse struct {
+	Added      bool        `json:"added"`
+	Invitation *Invitation `json:"invitation,omitempty"`
+}
+
+// Contact is a user the caller shares at least one list with, offered as quick-pick when
+// sharing another list.
+type Contact struct {
+	UserID      string `json:"user_id"`
+	Email       string `json:"email"`
+	SharedLists int    `json:"shared_lists"`
+}
+
+// PrivacySettings holds the user's privacy preferences.
+type PrivacySettings struct {
+	HideFromContacts bool `json:"hide_from_contacts"`
+}
+
+// ReplenishSettings represents the replenishment preferences of the authenticated user.
+type ReplenishSettings struct {
+	// WeeklyEmail emails the suggestions of all lists of the user once a week, if there are any.
+	WeeklyEmail bool `json:"weekly_email"`
+}
+
+// HousekeepingRequest represents a request to find and optionally remove clutter in an account.
+type HousekeepingRequest struct {
+	// InactiveMonths is the number of months without activity after which a list is reported, default 6.
+	InactiveMonths int  `json:"inactive_months" validate:"omitempty,min=1,max=120"`
+	Remove         bool `json:"remove"`
+	// ListIDs restricts removal to these of the reported lists; stale invitations are always removed.
+	ListIDs []string `json:"list_ids" validate:"omitempty,max=1000"`
+}
+
+// HousekeepingList is a list reported by housekeeping as a candidate for deletion.
+type HousekeepingList struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	ItemCount    int       `json:"item_count"`
+	LastActivity time.Time `json:"last_activity"`
+}
+
+// HousekeepingReport lists the clutter found in an account and whether it was removed.
+type HousekeepingReport struct {
+	InactiveMonths   int                `json:"inactive_months"`
+	EmptyLists       []HousekeepingList `json:"empty_lists"`
+	InactiveLists    []HousekeepingList `json:"inactive_lists"`
+	StaleInvitations []Invitation       `json:"stale_invitations"`
+	Removed          bool               `json:"removed"`
+}
+
+// SectionRequest represents a request to create or update a list section.
+type SectionRequest struct {
+	Name     string `json:"name" validate:"required"`
+	Position *int   `json:"position" validate:"omitempty,gte=0"`
+}
+
+// TagRequest represents a request to create or update the metadata of a list tag.
+type TagRequest struct {
+	Name     string `json:"name" validate:"required,max=50"`
+	Color    string `json:"color" validate:"omitempty,hexcolor"`
+	Icon     string `json:"icon" validate:"max=50"`
+	Position *int   `json:"position" validate:"omitempty,gte=0"`
+}
+
+// SmartListRequest represents a request to create or update a smart list.
+type SmartListRequest struct {
+	Name   string     `json:"name" validate:"required"`
+	Filter ItemFilter `json:"filter"`
+}
+
+// UpdateListNoteRequest represents a request to replace the caller's private note on a list.
+type UpdateListNoteRequest struct {
+	Note string `json:"note" validate:"max=10000"`
+}
+
+// CreateListAliasRequest represents a request to add an alias to a shopping list.
+type CreateListAliasRequest struct {
+	Alias string `json:"alias" validate:"required"`
+}
+
 // UpdateListRequest represents a request to update a shopping list.
 type UpdateListRequest struct {
-	Name string `json:"name" validate:"required"`
+	Name              string `json:"name" validate:"required"`
+	StaleAfterDays    *int   `json:"stale_after_days" validate:"omitempty,gte=0"`
+	CoOwnersCanDelete *bool  `json:"co_owners_can_delete"`
+	SkipDeduplication *bool  `json:"skip_deduplication"`
+	Archived          *bool  `json:"archived"`
+	TagPolicy         string `json:"tag_policy" validate:"omitempty,oneof=open create restrict"`
+	// SuggestedTags replaces the suggested tags of the list; omitting it leaves them unchanged.
+	SuggestedTags []string `json:"suggested_tags" validate:"omitempty,max=50,dive,required,max=50"`
+	// MonthlyBudget sets the budget of the list; 0 removes it.
+	MonthlyBudget *float64 `json:"monthly_budget" validate:"omitempty,gte=0"`
+	// Currency sets the currency of the list, e.g. "CHF"; "" uses the server's default again.
+	Currency *string `json:"currency" validate:"omitempty,currency"`
+}
+
+// MergeListsRequest represents a request to merge another list into a shopping list.
+// Duplicates decides what happens to open items that are already on the list: "merge" (the
+// default) folds them into the existing item, "keep" moves them anyway and "skip" leaves them
+// on the archived source list.
+type MergeListsRequest struct {
+	IncludeMembers bool   `json:"include_members"`
+	Duplicates     string `json:"duplicates" validate:"omitempty,oneof=merge keep skip"`
+}
+
+// ListBundle is a list exported for another server instance. Payload is the base64-encoded
+// JSON of a ListExport and Signature its base64-encoded HMAC-SHA256 with the federation key.
+type ListBundle struct {
+	Version   int    `json:"version" validate:"required"`
+	Payload   string `json:"payload" validate:"required"`
+	Signature string `json:"signature" validate:"required"`
+}
+
+// ListExport is the content of a list bundle. Members are placeholders identified by email
+// address, since user IDs differ between instances.
+type ListExport struct {
+	Name           string            `json:"name"`
+	StaleAfterDays int               `json:"stale_after_days"`
+	Currency       string            `json:"currency,omitempty"`
+	ExportedAt     time.Time         `json:"exported_at"`
+	Sections       []ExportedSection `json:"sections"`
+	Tags           []ExportedTag     `json:"tags"`
+	Items          []ExportedItem    `json:"items"`
+	Members        []ExportedMember  `json:"members"`
+}
+
+// ExportedSection is a section of an exported list.
+type ExportedSection struct {
+	Name     string `json:"name"`
+	Position int    `json:"position"`
+}
+
+// ExportedTag is the display metadata of a tag of an exported list.
+type ExportedTag struct {
+	Name     string `json:"name"`
+	Color    string `json:"color"`
+	Icon     string `json:"icon"`
+	Position int    `json:"position"`
+}
+
+// ExportedItem is an item of an exported list; Section is the name of its section, if any.
+type ExportedItem struct {
+	Name         string     `json:"name"`
+	Completed    bool       `json:"completed"`
+	Tags         string     `json:"tags"`
+	Category     string     `json:"category"`
+	Emoji        string     `json:"emoji"`
+	Quantity     float64    `json:"quantity"`
+	Unit         string     `json:"unit"`
+	Section      string     `json:"section,omitempty"`
+	SnoozedUntil *time.Time `json:"snoozed_until,omitempty"`
+	DueDate      *time.Time `json:"due_date,omitempty"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	Price        *float64   `json:"price,omitempty"`
+	ImageURL     string     `json:"image_url,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// ExportedMember is a member placeholder of an exported list.
+type ExportedMember struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// ListImportResult reports an imported list and which of its former members were invited to
+// it again. Members whose invitation failed, e.g. because they are already invited, are listed
+// with the reason.
+type ListImportResult struct {
+	List       ShoppingList      `json:"list"`
+	Invited    []string          `json:"invited"`
+	NotInvited map[string]string `json:"not_invited"`
+}
+
+// ListMergeResult reports the outcome of merging a list into another.
+type ListMergeResult struct {
+	List         ShoppingList `json:"list"`
+	MovedItems   int          `json:"moved_items"`
+	MergedItems  int          `json:"merged_items"`
+	SkippedItems int          `json:"skipped_items"`
+	AddedMembers int          `json:"added_members"`
+}
+
+// SpendReport is what was spent in a month on the lists of a user, in total and per list and
+// category. Budget is the sum of the lists' budgets, nil if none has one; OverBudget is set if any
+// list exceeds its budget.
+type SpendReport struct {
+	Month      string   `json:"month"`
+	Total      float64  `json:"total"`
+	Budget     *float64 `json:"budget"`
+	OverBudget bool     `json:"over_budget"`
+	Purchases  int      `json:"purchases"`
+	// Currency is the currency of all reported lists, empty if their currencies differ.
+	Currency   string          `json:"currency"`
+	Lists      []ListSpend     `json:"lists"`
+	Categories []CategorySpend `json:"categories"`
+}
+
+// ListSpend is what was spent on a list in a month.
+type ListSpend struct {
+	ListID     string   `json:"list_id"`
+	ListName   string   `json:"list_name"`
+	Total      float64  `json:"total"`
+	Budget     *float64 `json:"budget"`
+	OverBudget bool     `json:"over_budget"`
+	Currency   string   `json:"currency"`
+}
+
+// CategorySpend is what was spent on a category in a month; items without category are
+// reported under an empty category.
+type CategorySpend struct {
+	Category string  `json:"category"`
+	Total    float64 `json:"total"`
+}
+
+// FairnessReport compares what the members of a list contributed within the last Days days.
+type FairnessReport struct {
+	ListID  string               `json:"list_id"`
+	Days    int                  `json:"days"`
+	Since   time.Time            `json:"since"`
+	Members []MemberContribution `json:"members"`
+}
+
+// MemberContribution counts the items a member added and checked off and the trips they took,
+// a trip being a day on which they checked off items. CompletedShare is their percentage of all
+// items checked off. Badges name the categories the member leads, e.g. "top_shopper".
+type MemberContribution struct {
+	UserID         string   `json:"user_id"`
+	Name           string   `json:"name"`
+	ItemsAdded     int      `json:"items_added"`
+	ItemsCompleted int      `json:"items_completed"`
+	Trips          int      `json:"trips"`
+	CompletedShare float64  `json:"completed_share"`
+	Badges         []string `json:"badges"`
+}
+
+// UpdateListMemberRequest represents a request to change the role of a list member. "member" is
+// the former name of "editor".
+type UpdateListMemberRequest struct {
+	Role string `json:"role" validate:"required,oneof=co-owner editor viewer member"`
+}
+
+// PlanTripRequest represents a request to plan the next shopping trip of a list.
+type PlanTripRequest struct {
+	ScheduledAt time.Time `json:"scheduled_at" validate:"required"`
+	Note        string    `json:"note"`
+}
+
+// TripRSVPRequest represents a member's response to a planned shopping trip.
+type TripRSVPRequest struct {
+	Status string `json:"status" validate:"required,oneof=yes no maybe"`
+}
+
+// CreateItemPollRequest represents a request to start a poll about an item.
+type CreateItemPollRequest struct {
+	Question string   `json:"question" validate:"required,max=200"`
+	Options  []string `json:"options" validate:"required,min=2,max=10,dive,required,max=100"`
+}
+
+// PollVoteRequest represents a member's vote in an item poll.
+type PollVoteRequest struct {
+	Option *int `json:"option" validate:"required,min=0"`
 }
 
 // CreateInvitationRequest represents a request to create an invitation.
@@ -110,6 +985,152 @@ type CreateInvitationRequest struct {
 	Email  string  `json:"email" validate:"required,email"`
 	Type   string  `json:"type" validate:"required"`
 	ListID *string `json:"list_id"`
+	// ListIDs selects the lists of a bundle invitation.
+	ListIDs []string `json:"list_ids"`
+}
+
+// CreateCategoryMappingRequest represents a request to add a custom category mapping.
+type CreateCategoryMappingRequest struct {
+	Keyword  string `json:"keyword" validate:"required"`
+	Category string `json:"category" validate:"required"`
+	Language string `json:"language"`
+}
+
+// FeatureFlagRequest represents a request to create or update a feature flag.
+type FeatureFlagRequest struct {
+	Rollout     int    `json:"rollout" validate:"min=0,max=100"`
+	Description string `json:"description"`
+}
+
+// ServerBundle holds the configuration of a server to set up another instance alike: its
+// settings, custom category mappings and feature flags. Overrides of feature flags refer to the
+// users of the exporting server and are left out.
+type ServerBundle struct {
+	Format           int                            `json:"format" validate:"required"`
+	ServerVersion    string                         `json:"server_version"`
+	ExportedAt       time.Time                      `json:"exported_at"`
+	Settings         UpdateServerSettingsRequest    `json:"settings"`
+	CategoryMappings []CreateCategoryMappingRequest `json:"category_mappings" validate:"dive"`
+	FeatureFlags     []BundledFeatureFlag           `json:"feature_flags" validate:"dive"`
+}
+
+// BundledFeatureFlag is a feature flag in a server bundle.
+type BundledFeatureFlag struct {
+	Key         string `json:"key" validate:"required"`
+	Rollout     int    `json:"rollout" validate:"min=0,max=100"`
+	Description string `json:"description"`
+}
+
+// ServerBundleImport reports what importing a server bundle changed.
+type ServerBundleImport struct {
+	Settings         *SystemSettings `json:"settings"`
+	CategoryMappings int             `json:"category_mappings"`
+	FeatureFlags     int             `json:"feature_flags"`
+	// DryRun marks previews of an import that was rolled back.
+	DryRun bool `json:"dry_run"`
+}
+
+// FeatureFlagOverrideRequest represents a request to force a feature flag on or off for a user.
+type FeatureFlagOverrideRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+// FreezeInvitationsRequest represents a request to freeze or unfreeze a user's invitations.
+type FreezeInvitationsRequest struct {
+	Frozen *bool `json:"frozen" validate:"required"`
+}
+
+// CreateAPIKeyRequest represents a request to create a new API key.
+type CreateAPIKeyRequest struct {
+	Name      string   `json:"name" validate:"required"`
+	Scopes    []string `json:"scopes" validate:"omitempty,dive,oneof=items:read items:write"`
+	RateLimit int      `json:"rate_limit" validate:"omitempty,min=1,max=10000"`
+}
+
+// CreateDisplayTokenRequest represents a request to create a read-only display token for a list.
+type CreateDisplayTokenRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// ListRuleRequest represents a request to create or replace an automation rule of a list.
+type ListRuleRequest struct {
+	Name      string       `json:"name" validate:"required,max=100"`
+	Event     string       `json:"event" validate:"required,oneof=item_created item_completed"`
+	Condition string       `json:"condition" validate:"required,max=1000"`
+	Actions   []RuleAction `json:"actions" validate:"required,min=1,max=10,dive"`
+	Enabled   *bool        `json:"enabled"`
+}
+
+// SetEnrichmentHookRequest represents a request to set the enrichment hook of a list.
+type SetEnrichmentHookRequest struct {
+	URL string `json:"url" validate:"required,url,max=2000"`
+}
+
+// EnrichmentRequest is the body the server posts to an enrichment hook for a new item.
+type EnrichmentRequest struct {
+	ItemID   string  `json:"item_id"`
+	ListID   string  `json:"list_id"`
+	Name     string  `json:"name"`
+	Quantity float64 `json:"quantity"`
+	Unit     string  `json:"unit"`
+	Category string  `json:"category"`
+}
+
+// EnrichmentResponse is the metadata an enrichment hook returns for an item. Omitted fields
+// leave the item unchanged.
+type EnrichmentResponse struct {
+	Price    *float64 `json:"price"`
+	ImageURL string   `json:"image_url"`
+	Category string   `json:"category"`
+}
+
+// ItemCreatedEvent is the data of the item.created extension hook.
+type ItemCreatedEvent struct {
+	ItemID    string    `json:"item_id"`
+	ListID    string    `json:"list_id"`
+	UserID    string    `json:"user_id"`
+	Name      string    `json:"name"`
+	Quantity  float64   `json:"quantity"`
+	Unit      string    `json:"unit"`
+	Category  string    `json:"category"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LoginEvent is the data of the login extension hook.
+type LoginEvent struct {
+	UserID      string   `json:"user_id"`
+	Email       string   `json:"email"`
+	JoinedLists []string `json:"joined_lists"`
+}
+
+// InvitationCreatedEvent is the data of the invitation.created extension hook.
+type InvitationCreatedEvent struct {
+	InvitationID string    `json:"invitation_id"`
+	Type         string    `json:"type"`
+	InvitedBy    string    `json:"invited_by"`
+	Email        string    `json:"email"`
+	ListIDs      []string  `json:"list_ids"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// CreateAnnouncementRequest represents a request to post an announcement, optionally emailing it to all users.
+type CreateAnnouncementRequest struct {
+	Title     string     `json:"title" validate:"required"`
+	Body      string     `json:"body" validate:"required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	SendEmail bool       `json:"send_email"`
+}
+
+// AcceptPolicyRequest represents a request to accept the current version of a policy document.
+type AcceptPolicyRequest struct {
+	Type    string `json:"type" validate:"required"`
+	Version string `json:"version" validate:"required"`
+}
+
+// QuickAddRequest represents a free-text request to add one or more items to a list.
+type QuickAddRequest struct {
+	Text   string `json:"text" validate:"required"`
+	ListID string `json:"list_id"`
 }
 
 // AcceptInvitationRequest represents a request to accept an invitation.
@@ -126,11 +1147,281 @@ type SetupRequest struct {
 type LoginResponse struct {
 	Token string `json:"token"`
 	User  User   `json:"user"`
+	// JoinedLists are the lists the user joined by accepting invitations during this login.
+	JoinedLists []JoinedList `json:"joined_lists"`
+	// DefaultListID is the list the user joined first, empty if the user has no list.
+	DefaultListID string `json:"default_list_id"`
+	// IsAdmin tells whether the user may use the admin routes.
+	IsAdmin      bool                 `json:"is_admin"`
+	Capabilities CapabilitiesResponse `json:"capabilities"`
+}
+
+// JoinedList is a list joined by accepting an invitation, e.g. to tell the user
+// "You've been added to 'Groceries'".
+type JoinedList struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// CompactList is the minimal shape of a list and its items for watch clients.
+type CompactList struct {
+	ID    string        `json:"id"`
+	Name  string        `json:"name"`
+	Items []CompactItem `json:"items"`
+}
+
+// CompactItem is an item of a CompactList.
+type CompactItem struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Completed bool   `json:"completed"`
+}
+
+// IntegrationItem is the flat item shape of the integration routes. Its fields are kept stable
+// for automation platforms.
+type IntegrationItem struct {
+	ID        string    `json:"id"`
+	ListID    string    `json:"list_id"`
+	ListName  string    `json:"list_name"`
+	Name      string    `json:"name"`
+	Quantity  float64   `json:"quantity"`
+	Unit      string    `json:"unit"`
+	Category  string    `json:"category"`
+	Completed bool      `json:"completed"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ItemPage represents a page of the items of a list. Total counts all items matching the filter;
+// NextOffset is nil on the last page.
+type ItemPage struct {
+	Items      []ShoppingItem `json:"items"`
+	Total      int64          `json:"total"`
+	Limit      int            `json:"limit"`
+	Offset     int            `json:"offset"`
+	NextOffset *int           `json:"next_offset"`
+}
+
+// IntegrationItemsResponse represents a poll for new items. Since is the cursor to pass on the
+// next poll.
+type IntegrationItemsResponse struct {
+	Items []IntegrationItem `json:"items"`
+	Since string            `json:"since"`
+}
+
+// MatrixLinkCodeResponse represents a code for linking a Matrix account, which the user sends to
+// the bot in its room.
+type MatrixLinkCodeResponse struct {
+	Command   string    `json:"command"`
+	RoomID    string    `json:"room_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ChatLinkCodeResponse represents a code for linking a Slack or Discord workspace, which the user
+// runs as a slash command there.
+type ChatLinkCodeResponse struct {
+	Command   string    `json:"command"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CreateAPIKeyResponse represents a newly created API key including the plaintext key,
+// which is only returned once.
+type CreateAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// CreateDisplayTokenResponse represents a newly created display token including the plaintext
+// token, which is only returned once.
+type CreateDisplayTokenResponse struct {
+	DisplayToken
+	Token string `json:"token"`
+}
+
+// DisplayResponse is the read-only view of a list served to displays.
+type DisplayResponse struct {
+	List     ShoppingList   `json:"list"`
+	Sections []ListSection  `json:"sections"`
+	Items    []ShoppingItem `json:"items"`
+}
+
+// CreateCalendarFeedResponse represents a newly created calendar feed including its secret URL,
+// which is only returned once.
+type CreateCalendarFeedResponse struct {
+	CalendarFeed
+	URL string `json:"url"`
+}
+
+// PolicyStatusResponse describes the current policy documents and which of them the user still has to accept.
+type PolicyStatusResponse struct {
+	Documents   []PolicyDocument   `json:"documents"`
+	Pending     []PolicyDocument   `json:"pending"`
+	Acceptances []PolicyAcceptance `json:"acceptances"`
+	Required    bool               `json:"required"`
+}
+
+// StorageUsage describes how much attachment storage a user occupies compared to the quota.
+// A quota of zero means unlimited storage.
+type StorageUsage struct {
+	UserID      string `json:"user_id"`
+	Email       string `json:"email,omitempty"`
+	Attachments int64  `json:"attachments"`
+	UsedBytes   int64  `json:"used_bytes"`
+	QuotaBytes  int64  `json:"quota_bytes"`
+}
+
+// StatusResponse is the minimal public status for uptime monitors. Components are "ok",
+// "degraded", "down" or "disabled".
+type StatusResponse struct {
+	Status     string            `json:"status"`
+	Components map[string]string `json:"components"`
+}
+
+// IntegrityReport contains the result of a database integrity check.
+type IntegrityReport struct {
+	OK       bool           `json:"ok"`
+	Errors   []string       `json:"errors"`
+	Orphans  []OrphanReport `json:"orphans"`
+	Repaired bool           `json:"repaired"`
+	// DryRun marks reports of a repair that was only previewed.
+	DryRun    bool      `json:"dry_run"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// OrphanReport counts rows referencing a record that no longer exists.
+type OrphanReport struct {
+	Check string `json:"check"`
+	Table string `json:"table"`
+	Count int64  `json:"count"`
+}
+
+// SnapshotResponse describes a database snapshot written to disk.
+type SnapshotResponse struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	// StorageKey is the key of the copy in blob storage, empty if snapshots are not stored.
+	StorageKey string    `json:"storage_key,omitempty"`
+	HookRun    bool      `json:"hook_run"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// RetentionPolicy reports how long a kind of data is kept and when rows are purged next. Data
+// is "activity", "audit" or "item_history"; NextPurgeAt is nil while nothing is due to expire.
+type RetentionPolicy struct {
+	Data        string     `json:"data"`
+	Days        int        `json:"days"`
+	Rows        int64      `json:"rows"`
+	OldestAt    *time.Time `json:"oldest_at"`
+	NextPurgeAt *time.Time `json:"next_purge_at"`
+}
+
+// RetentionStatus reports the retention policies and the runs of the purge job.
+type RetentionStatus struct {
+	LastRunAt *time.Time        `json:"last_run_at"`
+	NextRunAt *time.Time        `json:"next_run_at"`
+	Policies  []RetentionPolicy `json:"policies"`
+}
+
+// RetentionPurgeResult counts the rows deleted per kind of data by a purge.
+type RetentionPurgeResult struct {
+	Purged map[string]int64 `json:"purged"`
+	// DryRun marks previews, whose Purged counts the rows a purge would delete.
+	DryRun bool      `json:"dry_run"`
+	RanAt  time.Time `json:"ran_at"`
+}
+
+// DeletePreview counts the rows per kind of data an admin delete run with ?dry_run=true would
+// remove.
+type DeletePreview struct {
+	Deleted map[string]int64 `json:"deleted"`
+	DryRun  bool             `json:"dry_run"`
+}
+
+// OperatorDigest summarizes the operation of the server for the admin. Emails and requests are
+// counted in memory, so they cover the time since CountersSince, the later of the last digest and
+// the server start; requests are only counted with metrics enabled. DatabaseGrowthBytes is 0 for
+// the first digest.
+type OperatorDigest struct {
+	From                time.Time `json:"from"`
+	Until               time.Time `json:"until"`
+	NewUsers            int64     `json:"new_users"`
+	TotalUsers          int64     `json:"total_users"`
+	AdminActions        int64     `json:"admin_actions"`
+	CountersSince       time.Time `json:"counters_since"`
+	EmailsSent          int64     `json:"emails_sent"`
+	EmailsFailed        int64     `json:"emails_failed"`
+	Requests            int64     `json:"requests"`
+	ServerErrors        int64     `json:"server_errors"`
+	ErrorRate           float64   `json:"error_rate"`
+	DatabaseBytes       int64     `json:"database_bytes"`
+	DatabaseGrowthBytes int64     `json:"database_growth_bytes"`
+}
+
+// CheckpointResponse contains the result of a WAL checkpoint. For databases not in WAL mode
+// both frame counts are -1.
+type CheckpointResponse struct {
+	Mode               string `json:"mode"`
+	Busy               bool   `json:"busy"`
+	LogFrames          int    `json:"log_frames"`
+	CheckpointedFrames int    `json:"checkpointed_frames"`
+}
+
+// CapabilitiesResponse describes the server version, optional features, limits, supported
+// locales and the feature flags of the caller so clients can adapt their UI.
+type CapabilitiesResponse struct {
+	Version  string             `json:"version"`
+	Features CapabilityFeatures `json:"features"`
+	Limits   CapabilityLimits   `json:"limits"`
+	Locales  []string           `json:"locales"`
+	// Currency is the default currency of lists without their own.
+	Currency string          `json:"currency"`
+	Flags    map[string]bool `json:"flags"`
+}
+
+// CapabilityFeatures lists which optional features are enabled on the server.
+type CapabilityFeatures struct {
+	WebSockets       bool   `json:"websockets"`
+	Webhooks         bool   `json:"webhooks"`
+	Attachments      bool   `json:"attachments"`
+	Matrix           bool   `json:"matrix"`
+	Slack            bool   `json:"slack"`
+	Discord          bool   `json:"discord"`
+	Federation       bool   `json:"federation"`
+	PolicyAcceptance bool   `json:"policy_acceptance"`
+	RegistrationMode string `json:"registration_mode"`
+}
+
+// CapabilityLimits lists the limits and quotas enforced by the server.
+type CapabilityLimits struct {
+	LoginCodeLifetimeSeconds  int    `json:"login_code_lifetime_seconds"`
+	LoginCodeLength           int    `json:"login_code_length"`
+	LoginCodeAlphabet         string `json:"login_code_alphabet"`
+	TokenLifetimeSeconds      int    `json:"token_lifetime_seconds"`
+	InvitationLifetimeSeconds int    `json:"invitation_lifetime_seconds"`
+	AttachmentMaxBytes        int64  `json:"attachment_max_bytes"`
+	StorageQuotaBytes         int64  `json:"storage_quota_bytes"`
+}
+
+// VersionResponse describes the build of the running server and, for admins, the result
+// of the last update check.
+type VersionResponse struct {
+	Version   string        `json:"version"`
+	Commit    string        `json:"commit"`
+	BuildDate string        `json:"build_date"`
+	Update    *UpdateStatus `json:"update,omitempty"`
+}
+
+// UpdateStatus describes the latest published release and whether it is newer than the running server.
+type UpdateStatus struct {
+	LatestVersion   string     `json:"latest_version"`
+	ReleaseURL      string     `json:"release_url"`
+	UpdateAvailable bool       `json:"update_available"`
+	CheckedAt       *time.Time `json:"checked_at"`
 }
 
 // JWTClaims represents the custom claims included in JWT tokens.
 type JWTClaims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
+	UserID       string `json:"user_id"`
+	Email        string `json:"email"`
+	TokenVersion int    `json:"token_version,omitempty"`
 	jwt.RegisteredClaims
 }
@@ -0,0 +1,122 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package moderation lets the admin handle misbehaving accounts on semi-public instances by
+// ending all their sessions or freezing their invitations. Every action is recorded in the
+// audit log, along with destructive actions on the server and their dry runs.
+package moderation
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrUserNotFound is returned when the user acted on does not exist.
+var ErrUserNotFound = errors.New("user not found")
+
+// Actions recorded in the audit log.
+const (
+	ActionRevokeSessions      = "revoke_sessions"
+	ActionFreezeInvitations   = "freeze_invitations"
+	ActionUnfreezeInvitations = "unfreeze_invitations"
+	ActionPurgeRetention      = "purge_retention"
+	ActionRepairDatabase      = "repair_database"
+	ActionDeleteAnnouncement  = "delete_announcement"
+	ActionDeleteFeatureFlag   = "delete_feature_flag"
+	ActionDeleteFlagOverride  = "delete_feature_flag_override"
+	ActionImportServerBundle  = "import_server_bundle"
+)
+
+// DefaultAuditLogLimit is the number of audit log entries returned when no limit is given.
+const DefaultAuditLogLimit = 100
+
+// Service performs moderation actions and records them in the audit log.
+type Service struct {
+	DB *gorm.DB
+}
+
+// NewService creates a new moderation service.
+func NewService(db *gorm.DB) *Service {
+	return &Service{DB: db}
+}
+
+// RevokeSessions ends all sessions of the user by bumping their token version, which invalidates
+// every JWT issued before. API keys are not affected.
+func (s *Service) RevokeSessions(adminID, userID string) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).Where("id = ?", userID).
+			UpdateColumn("token_version", gorm.Expr("token_version + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrUserNotFound
+		}
+		return record(tx, adminID, ActionRevokeSessions, userID)
+	})
+}
+
+// SetInvitationsFrozen freezes or unfreezes the user's ability to send invitations. Invitations
+// sent before stay valid.
+func (s *Service) SetInvitationsFrozen(adminID, userID string, frozen bool) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).Where("id = ?", userID).UpdateColumn("invitations_frozen", frozen)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrUserNotFound
+		}
+
+		action := ActionUnfreezeInvitations
+		if frozen {
+			action = ActionFreezeInvitations
+		}
+		return record(tx, adminID, action, userID)
+	})
+}
+
+// RecordServerAction records an action on the whole server in the audit log, with a summary of
+// the affected rows stored as JSON. Dry runs are recorded as well, so previews are traceable.
+func (s *Service) RecordServerAction(adminID, action string, dryRun bool, summary any) error {
+	details, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+
+	return s.DB.Create(&models.AuditEntry{
+		ID:        uuid.New().String(),
+		ActorID:   adminID,
+		Action:    action,
+		DryRun:    dryRun,
+		Details:   string(details),
+		CreatedAt: time.Now(),
+	}).Error
+}
+
+// AuditLog retrieves the latest audit log entries, newest first, at most limit entries.
+func (s *Service) AuditLog(limit int) ([]models.AuditEntry, error) {
+	if limit <= 0 {
+		limit = DefaultAuditLogLimit
+	}
+
+	var entries []models.AuditEntry
+	err := s.DB.Order("created_at DESC").Limit(limit).Find(&entries).Error
+	return entries, err
+}
+
+// record adds an entry to the audit log.
+func record(tx *gorm.DB, actorID, action, targetUserID string) error {
+	return tx.Create(&models.AuditEntry{
+		ID:           uuid.New().String(),
+		ActorID:      actorID,
+		Action:       action,
+		TargetUserID: targetUserID,
+		CreatedAt:    time.Now(),
+	}).Error
+}
@@ -0,0 +1,75 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package moderation
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+)
+
+func TestService_Moderation(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+
+	user := models.User{ID: "user-id", Email: testutils.TestEmailAddress(), JoinedAt: time.Now()}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	if err := service.RevokeSessions("admin-id", "unknown"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+	if err := service.RevokeSessions("admin-id", user.ID); err != nil {
+		t.Fatalf("Failed to revoke sessions: %v", err)
+	}
+	if err := service.SetInvitationsFrozen("admin-id", user.ID, true); err != nil {
+		t.Fatalf("Failed to freeze invitations: %v", err)
+	}
+
+	var stored models.User
+	db.First(&stored, "id = ?", user.ID)
+	if stored.TokenVersion != 1 || !stored.InvitationsFrozen {
+		t.Errorf("Expected bumped token version and frozen invitations, got %+v", stored)
+	}
+
+	if err := service.SetInvitationsFrozen("admin-id", user.ID, false); err != nil {
+		t.Fatalf("Failed to unfreeze invitations: %v", err)
+	}
+
+	entries, err := service.AuditLog(0)
+	if err != nil {
+		t.Fatalf("Failed to get audit log: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 audit entries, got %d", len(entries))
+	}
+	actions := map[string]bool{}
+	for _, entry := range entries {
+		actions[entry.Action] = true
+		if entry.ActorID != "admin-id" || entry.TargetUserID != user.ID {
+			t.Errorf("Unexpected audit entry %+v", entry)
+		}
+	}
+	for _, action := range []string{ActionRevokeSessions, ActionFreezeInvitations, ActionUnfreezeInvitations} {
+		if !actions[action] {
+			t.Errorf("Expected %s in the audit log", action)
+		}
+	}
+
+	if entries, _ := service.AuditLog(1); len(entries) != 1 {
+		t.Errorf("Expected the limit to apply, got %d entries", len(entries))
+	}
+
+	if err := service.RecordServerAction("admin-id", ActionPurgeRetention, true, map[string]int64{"audit": 2}); err != nil {
+		t.Fatalf("Failed to record server action: %v", err)
+	}
+	entries, _ = service.AuditLog(1)
+	if len(entries) != 1 || entries[0].Action != ActionPurgeRetention || !entries[0].DryRun || entries[0].Details != `{"audit":2}` {
+		t.Errorf("Expected the dry run in the audit log, got %+v", entries)
+	}
+}
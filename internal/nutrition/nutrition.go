@@ -0,0 +1,244 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package nutrition looks up the Nutri-Score grade and allergens of items in Open Food Facts, by
+// barcode or else by name, so allergy-conscious households can see and filter items containing
+// an allergen. Results, including products that were not found, are cached locally; lookups run
+// in the background and never delay or fail adding items.
+package nutrition
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/items"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/version"
+	"gorm.io/gorm"
+)
+
+// DefaultBaseURL is the Open Food Facts instance looked up by default.
+const DefaultBaseURL = "https://world.openfoodfacts.org"
+
+// CacheTTL is how long looked up facts are used before they are fetched again.
+const CacheTTL = 30 * 24 * time.Hour
+
+// maxResponseSize limits how much of an Open Food Facts response is read.
+const maxResponseSize = 1 << 20
+
+// fields are the product fields requested from Open Food Facts.
+const fields = "code,product_name,nutrition_grades,allergens_tags"
+
+// grades are the valid Nutri-Score grades; Open Food Facts also reports "unknown" and
+// "not-applicable".
+var grades = []string{"a", "b", "c", "d", "e"}
+
+// Service looks up and caches nutrition facts.
+type Service struct {
+	DB     *gorm.DB
+	Client *http.Client
+	// BaseURL is the Open Food Facts instance; lookups are disabled while it is empty.
+	BaseURL string
+}
+
+// NewService creates a nutrition service with lookups disabled.
+func NewService(db *gorm.DB) *Service {
+	return &Service{
+		DB:     db,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Enabled reports whether items are looked up.
+func (s *Service) Enabled() bool {
+	return s.BaseURL != ""
+}
+
+// Enqueue looks up new or changed items in the background if lookups are enabled. Failures are
+// logged, the items stay as they are.
+func (s *Service) Enqueue(items ...models.ShoppingItem) {
+	if !s.Enabled() || len(items) == 0 {
+		return
+	}
+
+	go func() {
+		for _, item := range items {
+			if err := s.Enrich(item); err != nil {
+				slog.Warn("Failed to look up nutrition facts", "list", item.ListID, "item", item.ID, "error", err)
+			}
+		}
+	}()
+}
+
+// Enrich looks up the facts of an item and stores its grade and allergens. Unknown products
+// clear them, so facts of a previous barcode do not linger.
+func (s *Service) Enrich(item models.ShoppingItem) error {
+	facts, err := s.Lookup(item.Barcode, item.Name)
+	if err != nil {
+		return err
+	}
+
+	var allergens any
+	if facts.Found {
+		encoded, err := json.Marshal(facts.Allergens)
+		if err != nil {
+			return err
+		}
+		allergens = string(encoded)
+	}
+	return s.DB.Model(&models.ShoppingItem{}).Where("id = ?", item.ID).UpdateColumns(map[string]any{
+		"nutrition_grade": facts.NutritionGrade,
+		"allergens":       allergens,
+		"updated_at":      time.Now(),
+	}).Error
+}
+
+// Lookup returns the facts of a product by barcode or, without one, by name, from the cache if
+// they are recent enough.
+func (s *Service) Lookup(barcode, name string) (*models.NutritionFacts, error) {
+	barcode = strings.TrimSpace(barcode)
+	name = items.NormalizeName(name)
+	key := "barcode:" + barcode
+	if barcode == "" {
+		key = "name:" + name
+	}
+
+	// Cache misses are expected, so look up without the error First logs for them
+	var cached models.NutritionFacts
+	result := s.DB.Where("key = ?", key).Limit(1).Find(&cached)
+	if result.Error == nil && result.RowsAffected > 0 && time.Since(cached.FetchedAt) < CacheTTL {
+		return &cached, nil
+	}
+	if !s.Enabled() {
+		return nil, errors.New("nutrition lookups are disabled")
+	}
+
+	var product *offProduct
+	var err error
+	if barcode != "" {
+		product, err = s.fetchByBarcode(barcode)
+	} else {
+		product, err = s.search(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	facts := models.NutritionFacts{Key: key, Barcode: barcode, FetchedAt: time.Now()}
+	if product != nil {
+		facts.Found = true
+		facts.Barcode = product.Code
+		facts.ProductName = product.ProductName
+		facts.NutritionGrade = normalizeGrade(product.NutritionGrades)
+		facts.Allergens = normalizeAllergens(product.AllergensTags)
+	}
+	if err := s.DB.Save(&facts).Error; err != nil {
+		return nil, err
+	}
+	return &facts, nil
+}
+
+// offProduct holds the requested fields of an Open Food Facts product.
+type offProduct struct {
+	Code            string   `json:"code"`
+	ProductName     string   `json:"product_name"`
+	NutritionGrades string   `json:"nutrition_grades"`
+	AllergensTags   []string `json:"allergens_tags"`
+}
+
+// fetchByBarcode fetches a product by its barcode; unknown products give nil.
+func (s *Service) fetchByBarcode(barcode string) (*offProduct, error) {
+	var response struct {
+		Status  int         `json:"status"`
+		Product *offProduct `json:"product"`
+	}
+	found, err := s.get("/api/v2/product/"+url.PathEscape(barcode)+".json?fields="+fields, &response)
+	if err != nil || !found || response.Status != 1 || response.Product == nil {
+		return nil, err
+	}
+	if response.Product.Code == "" {
+		response.Product.Code = barcode
+	}
+	return response.Product, nil
+}
+
+// search fetches the most popular product matching a name; no match gives nil.
+func (s *Service) search(name string) (*offProduct, error) {
+	query := url.Values{
+		"search_terms":  {name},
+		"search_simple": {"1"},
+		"action":        {"process"},
+		"json":          {"1"},
+		"page_size":     {"1"},
+		"fields":        {fields},
+	}
+	var response struct {
+		Products []offProduct `json:"products"`
+	}
+	found, err := s.get("/cgi/search.pl?"+query.Encode(), &response)
+	if err != nil || !found || len(response.Products) == 0 {
+		return nil, err
+	}
+	return &response.Products[0], nil
+}
+
+// get fetches a path of the Open Food Facts API into v. It reports false for 404 responses,
+// which Open Food Facts answers for unknown products.
+func (s *Service) get(path string, v any) (bool, error) {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(s.BaseURL, "/")+path, nil)
+	if err != nil {
+		return false, err
+	}
+	// Open Food Facts asks clients to identify themselves
+	req.Header.Set("User-Agent", "shopping-list-server/"+version.Version)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("open food facts responded with status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(v); err != nil {
+		return false, fmt.Errorf("invalid open food facts response: %w", err)
+	}
+	return true, nil
+}
+
+// normalizeGrade returns a Nutri-Score grade from a to e, or "" if there is none.
+func normalizeGrade(grade string) string {
+	grade = strings.ToLower(strings.TrimSpace(grade))
+	if slices.Contains(grades, grade) {
+		return grade
+	}
+	return ""
+}
+
+// normalizeAllergens turns allergen tags like "en:gluten" into their names, e.g. "gluten".
+func normalizeAllergens(tags []string) []string {
+	allergens := []string{}
+	for _, tag := range tags {
+		if _, name, found := strings.Cut(tag, ":"); found {
+			tag = name
+		}
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !slices.Contains(allergens, tag) {
+			allergens = append(allergens, tag)
+		}
+	}
+	return allergens
+}
@@ -0,0 +1,133 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package nutrition
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+)
+
+// fakeOpenFoodFacts answers product and search requests like Open Food Facts and counts them.
+func fakeOpenFoodFacts(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if !strings.HasPrefix(r.Header.Get("User-Agent"), "shopping-list-server/") {
+			t.Errorf("Expected the server to identify itself, got %q", r.Header.Get("User-Agent"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/v2/product/4000417025005.json":
+			_, _ = w.Write([]byte(`{"status":1,"product":{"code":"4000417025005","product_name":"Oat Flakes","nutrition_grades":"a","allergens_tags":["en:gluten","en:gluten"]}}`))
+		case strings.HasPrefix(r.URL.Path, "/api/v2/product/"):
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"status":0,"status_verbose":"product not found"}`))
+		case r.URL.Path == "/cgi/search.pl" && r.URL.Query().Get("search_terms") == "milk":
+			_, _ = w.Write([]byte(`{"products":[{"code":"3033490004743","product_name":"Whole Milk","nutrition_grades":"unknown","allergens_tags":["en:milk"]}]}`))
+		case r.URL.Path == "/cgi/search.pl":
+			_, _ = w.Write([]byte(`{"products":[]}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestService_Lookup(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	off, requests := fakeOpenFoodFacts(t)
+	service := NewService(db)
+
+	if _, err := service.Lookup("4000417025005", "Oats"); err == nil {
+		t.Error("Expected lookups to fail while disabled")
+	}
+	service.BaseURL = off.URL
+
+	facts, err := service.Lookup("4000417025005", "Oats")
+	if err != nil {
+		t.Fatalf("Failed to look up barcode: %v", err)
+	}
+	if !facts.Found || facts.NutritionGrade != "a" || !slices.Equal(facts.Allergens, []string{"gluten"}) {
+		t.Errorf("Unexpected facts %+v", facts)
+	}
+
+	facts, err = service.Lookup("", "  Milk ")
+	if err != nil {
+		t.Fatalf("Failed to search name: %v", err)
+	}
+	if !facts.Found || facts.Barcode != "3033490004743" || facts.NutritionGrade != "" || !slices.Equal(facts.Allergens, []string{"milk"}) {
+		t.Errorf("Expected the search result without unknown grade, got %+v", facts)
+	}
+
+	for _, lookup := range [][2]string{{"12345678", ""}, {"", "unobtainium"}} {
+		facts, err := service.Lookup(lookup[0], lookup[1])
+		if err != nil || facts.Found {
+			t.Errorf("Expected %v not to be found, got %+v, %v", lookup, facts, err)
+		}
+	}
+
+	// Everything is cached now, including what was not found
+	before := requests.Load()
+	for _, lookup := range [][2]string{{"4000417025005", ""}, {"", "milk"}, {"12345678", ""}, {"", "Unobtainium"}} {
+		if _, err := service.Lookup(lookup[0], lookup[1]); err != nil {
+			t.Errorf("Failed to look up %v: %v", lookup, err)
+		}
+	}
+	if requests.Load() != before {
+		t.Errorf("Expected cached lookups, got %d more requests", requests.Load()-before)
+	}
+
+	// Expired facts are fetched again
+	db.Model(&models.NutritionFacts{}).Where("key = ?", "name:milk").Update("fetched_at", time.Now().Add(-CacheTTL-time.Hour))
+	if _, err := service.Lookup("", "milk"); err != nil || requests.Load() != before+1 {
+		t.Errorf("Expected expired facts to be fetched again, got %d requests, %v", requests.Load()-before, err)
+	}
+
+	service.BaseURL = off.URL + "/broken"
+	if _, err := service.Lookup("", "bread"); err == nil {
+		t.Error("Expected server errors to fail the lookup")
+	}
+}
+
+func TestService_Enrich(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	off, _ := fakeOpenFoodFacts(t)
+	service := NewService(db)
+	service.BaseURL = off.URL
+
+	item := models.ShoppingItem{ID: "item-1", ListID: "list-1", Name: "Oats", Barcode: "4000417025005"}
+	if err := db.Create(&item).Error; err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+
+	if err := service.Enrich(item); err != nil {
+		t.Fatalf("Failed to enrich item: %v", err)
+	}
+	var enriched models.ShoppingItem
+	db.First(&enriched, "id = ?", item.ID)
+	if enriched.NutritionGrade != "a" || !slices.Equal(enriched.Allergens, []string{"gluten"}) {
+		t.Errorf("Expected grade and allergens, got %q %v", enriched.NutritionGrade, enriched.Allergens)
+	}
+
+	// Another barcode that is unknown clears the facts
+	enriched.Barcode = "12345678"
+	if err := service.Enrich(enriched); err != nil {
+		t.Fatalf("Failed to enrich item: %v", err)
+	}
+	db.First(&enriched, "id = ?", item.ID)
+	if enriched.NutritionGrade != "" || enriched.Allergens != nil {
+		t.Errorf("Expected facts to be cleared, got %q %v", enriched.NutritionGrade, enriched.Allergens)
+	}
+}
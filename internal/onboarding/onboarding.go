@@ -0,0 +1,189 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package onboarding completes a login by verifying the magic link code and applying any
+// pending invitation as one atomic unit.
+package onboarding
+
+import (
+	"errors"
+
+	"github.com/oliverandrich/shopping-list-server/internal/auth"
+	"github.com/oliverandrich/shopping-list-server/internal/codes"
+	"github.com/oliverandrich/shopping-list-server/internal/invitations"
+	"github.com/oliverandrich/shopping-list-server/internal/lists"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/setup"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCode is returned when the magic link code is invalid or expired, or a new user
+// has no invitation.
+var ErrInvalidCode = errors.New("invalid or expired code")
+
+// ErrInvitationNotFound is returned when accepting an invitation that does not exist, was
+// addressed to someone else or is no longer pending.
+var ErrInvitationNotFound = errors.New("invitation not found")
+
+// Service runs the onboarding steps in a single database transaction.
+type Service struct {
+	DB   *gorm.DB
+	Auth *auth.Service
+}
+
+// NewService creates a new onboarding service. The auth service provides the JWT secret and
+// mailer for the transaction-bound auth service.
+func NewService(db *gorm.DB, authService *auth.Service) *Service {
+	return &Service{DB: db, Auth: authService}
+}
+
+// Complete verifies the magic link code and accepts all pending invitations of the email address:
+// it creates the default list for users joining the server and adds users invited to lists as
+// members of each of them. It returns the user and the lists joined, ordered by name.
+//
+// With an invitation code, only that invitation is accepted and the others stay pending, e.g.
+// when several invitations were sent to the same address.
+//
+// All steps run in one transaction, so a failure rolls back every step including marking the
+// code and invitation as used, and the client can retry with the same code. The steps are
+// idempotent on their own as well: a user who already owns a list gets no second default list
+// and joining a list twice is a no-op.
+func (s *Service) Complete(email, code, invitationCode, acceptLanguage string) (*models.User, []models.JoinedList, error) {
+	return s.complete(invitationCode, acceptLanguage, func(authService *auth.Service) (*models.User, error) {
+		user, _, err := authService.VerifyMagicLinkWithInvitation(email, code, invitationCode)
+		return user, err
+	})
+}
+
+// CompleteLink completes a login like Complete, verifying the token of a login link instead of
+// a code and accepting all pending invitations of the address it was sent to.
+func (s *Service) CompleteLink(token, acceptLanguage string) (*models.User, []models.JoinedList, error) {
+	return s.complete("", acceptLanguage, func(authService *auth.Service) (*models.User, error) {
+		user, _, err := authService.VerifyLoginLink(token)
+		return user, err
+	})
+}
+
+// complete runs the onboarding steps for the user returned by verify, which is called with an
+// auth service bound to the transaction.
+func (s *Service) complete(invitationCode, acceptLanguage string, verify func(*auth.Service) (*models.User, error)) (*models.User, []models.JoinedList, error) {
+	var user *models.User
+	joined := []models.JoinedList{}
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		authService := auth.NewService(tx, s.Auth.JWTSecret, s.Auth.Mailer)
+		invitationService := invitations.NewService(tx, nil)
+
+		var err error
+		user, err = verify(authService)
+		if err != nil {
+			return ErrInvalidCode
+		}
+
+		pending, err := invitationService.GetPendingInvitations(user.Email)
+		if err != nil {
+			return err
+		}
+
+		accepted := false
+		var listIDs []string
+		for i := range pending {
+			if invitationCode != "" && pending[i].Code != codes.Normalize(invitationCode) {
+				continue
+			}
+			ids, err := accept(tx, &pending[i], user, acceptLanguage)
+			if err != nil {
+				return err
+			}
+			listIDs = append(listIDs, ids...)
+			accepted = true
+		}
+		if invitationCode != "" && !accepted {
+			return ErrInvitationNotFound
+		}
+		if len(listIDs) == 0 {
+			return nil
+		}
+
+		return tx.Model(&models.ShoppingList{}).
+			Select("id, name").
+			Where("id IN ?", listIDs).
+			Order("name ASC").
+			Scan(&joined).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, joined, nil
+}
+
+// Accept accepts a pending invitation addressed to a logged-in user, e.g. an in-app invitation
+// to a list, in a single transaction. It returns the IDs of the lists joined.
+func (s *Service) Accept(user *models.User, invitationID string) ([]string, error) {
+	var joined []string
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		pending, err := invitations.NewService(tx, nil).GetPendingInvitations(user.Email)
+		if err != nil {
+			return err
+		}
+
+		for i := range pending {
+			if pending[i].ID == invitationID {
+				joined, err = accept(tx, &pending[i], user, "")
+				return err
+			}
+		}
+		return ErrInvitationNotFound
+	})
+	return joined, err
+}
+
+// accept marks an invitation as used and grants what it offers: a default list for server
+// invitations and membership for list and bundle invitations. It returns the IDs of the lists
+// joined.
+func accept(tx *gorm.DB, invitation *models.Invitation, user *models.User, acceptLanguage string) ([]string, error) {
+	if _, err := invitations.NewService(tx, nil).AcceptInvitation(user.Email, invitation.Code); err != nil {
+		return nil, err
+	}
+
+	listService := lists.NewService(tx)
+	var joined []string
+	switch invitation.Type {
+	case "server":
+		return nil, createDefaultList(tx, listService, setup.NewService(tx), user.ID, acceptLanguage)
+	case "list":
+		if invitation.ListID != nil {
+			joined = append(joined, *invitation.ListID)
+		}
+	case "bundle":
+		for _, item := range invitation.Lists {
+			joined = append(joined, item.ListID)
+		}
+	}
+
+	for _, listID := range joined {
+		if err := listService.JoinList(listID, user.ID); err != nil {
+			return nil, err
+		}
+	}
+	return joined, nil
+}
+
+// createDefaultList creates the default list for a new user unless disabled or the user
+// already owns a list.
+func createDefaultList(tx *gorm.DB, listService *lists.Service, setupService *setup.Service, userID, acceptLanguage string) error {
+	if !setupService.AutoCreateDefaultList() {
+		return nil
+	}
+
+	var owned int64
+	if err := tx.Model(&models.ShoppingList{}).Where("owner_id = ?", userID).Count(&owned).Error; err != nil {
+		return err
+	}
+	if owned > 0 {
+		return nil
+	}
+
+	_, err := listService.CreateDefaultListForUser(userID, setupService.DefaultListName(acceptLanguage))
+	return err
+}
@@ -0,0 +1,210 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package onboarding
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/oliverandrich/shopping-list-server/internal/auth"
+	"github.com/oliverandrich/shopping-list-server/internal/invitations"
+	"github.com/oliverandrich/shopping-list-server/internal/lists"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/setup"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+	"gorm.io/gorm"
+)
+
+func setupTestService(t *testing.T) (*Service, *gorm.DB, *models.User) {
+	t.Helper()
+	t.Setenv("GO_ENV", "test")
+
+	db := testutils.SetupTestDB(t)
+	admin, err := setup.NewService(db).SetupSystem("admin@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+
+	return NewService(db, auth.NewService(db, []byte("secret"), nil)), db, admin
+}
+
+func TestService_Complete(t *testing.T) {
+	service, db, admin := setupTestService(t)
+	invitationService := invitations.NewService(db, nil)
+
+	t.Run("new user with server invitation", func(t *testing.T) {
+		if _, err := invitationService.CreateInvitation(admin.ID, "new@example.com", "server", nil); err != nil {
+			t.Fatalf("Failed to create invitation: %v", err)
+		}
+		code, _ := service.Auth.CreateMagicLink("new@example.com")
+
+		user, joined, err := service.Complete("new@example.com", code, "", "de")
+		if err != nil {
+			t.Fatalf("Failed to complete onboarding: %v", err)
+		}
+		if len(joined) != 0 {
+			t.Errorf("Expected no joined lists for a server invitation, got %+v", joined)
+		}
+
+		var owned []models.ShoppingList
+		db.Where("owner_id = ?", user.ID).Find(&owned)
+		if len(owned) != 1 || owned[0].Name != "Meine Einkaufsliste" {
+			t.Errorf("Expected localized default list, got %+v", owned)
+		}
+	})
+
+	t.Run("invalid code", func(t *testing.T) {
+		if _, _, err := service.Complete("new@example.com", "000000", "", ""); !errors.Is(err, ErrInvalidCode) {
+			t.Errorf("Expected ErrInvalidCode, got %v", err)
+		}
+	})
+}
+
+func TestService_CompleteRollsBack(t *testing.T) {
+	service, db, admin := setupTestService(t)
+	listService := lists.NewService(db)
+
+	list, err := listService.CreateList(admin.ID, "Hardware store")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	invitation, err := invitations.NewService(db, nil).CreateInvitation(admin.ID, "guest@example.com", "list", &list.ID)
+	if err != nil {
+		t.Fatalf("Failed to create invitation: %v", err)
+	}
+	code, _ := service.Auth.CreateMagicLink("guest@example.com")
+
+	// Make joining the list fail after the code and invitation were accepted
+	if err := db.Exec("UPDATE shopping_lists SET id = 'moved' WHERE id = ?", list.ID).Error; err != nil {
+		t.Fatalf("Failed to move list: %v", err)
+	}
+
+	if _, _, err := service.Complete("guest@example.com", code, "", ""); err == nil || errors.Is(err, ErrInvalidCode) {
+		t.Fatalf("Expected joining the list to fail, got %v", err)
+	}
+
+	var users int64
+	db.Model(&models.User{}).Where("id <> ?", admin.ID).Count(&users)
+	if users != 0 {
+		t.Error("Expected user creation to be rolled back")
+	}
+	var stored models.Invitation
+	db.First(&stored, "id = ?", invitation.ID)
+	if stored.Used {
+		t.Error("Expected invitation to stay unused")
+	}
+
+	// Retrying with the same code succeeds once the list is back
+	if err := db.Exec("UPDATE shopping_lists SET id = ? WHERE id = 'moved'", list.ID).Error; err != nil {
+		t.Fatalf("Failed to restore list: %v", err)
+	}
+
+	user, _, err := service.Complete("guest@example.com", code, "", "")
+	if err != nil {
+		t.Fatalf("Failed to complete onboarding on retry: %v", err)
+	}
+	if !listService.HasListAccess(list.ID, user.ID) {
+		t.Error("Expected user to be member of the list")
+	}
+
+	var owned int64
+	db.Model(&models.ShoppingList{}).Where("owner_id = ?", user.ID).Count(&owned)
+	if owned != 0 {
+		t.Errorf("Expected no default list for list invitations, got %d", owned)
+	}
+}
+
+func TestService_CompleteAcceptsAllInvitations(t *testing.T) {
+	service, db, admin := setupTestService(t)
+	listService := lists.NewService(db)
+	invitationService := invitations.NewService(db, nil)
+
+	groceries, _ := listService.CreateList(admin.ID, "Groceries")
+	hardware, _ := listService.CreateList(admin.ID, "Hardware store")
+	for _, list := range []*models.ShoppingList{groceries, hardware} {
+		if _, err := invitationService.CreateInvitation(admin.ID, "guest@example.com", "list", &list.ID); err != nil {
+			t.Fatalf("Failed to create invitation: %v", err)
+		}
+	}
+	code, _ := service.Auth.CreateMagicLink("guest@example.com")
+
+	user, _, err := service.Complete("guest@example.com", code, "", "")
+	if err != nil {
+		t.Fatalf("Failed to complete onboarding: %v", err)
+	}
+
+	if !listService.HasListAccess(groceries.ID, user.ID) || !listService.HasListAccess(hardware.ID, user.ID) {
+		t.Error("Expected user to join every invited list")
+	}
+
+	pending, _ := invitationService.GetPendingInvitations("guest@example.com")
+	if len(pending) != 0 {
+		t.Errorf("Expected all invitations to be accepted, got %d pending", len(pending))
+	}
+}
+
+func TestService_CompleteWithInvitationCode(t *testing.T) {
+	service, db, admin := setupTestService(t)
+	listService := lists.NewService(db)
+	invitationService := invitations.NewService(db, nil)
+
+	groceries, _ := listService.CreateList(admin.ID, "Groceries")
+	hardware, _ := listService.CreateList(admin.ID, "Hardware store")
+	if _, err := invitationService.CreateInvitation(admin.ID, "guest@example.com", "list", &groceries.ID); err != nil {
+		t.Fatalf("Failed to create invitation: %v", err)
+	}
+	chosen, err := invitationService.CreateInvitation(admin.ID, "guest@example.com", "list", &hardware.ID)
+	if err != nil {
+		t.Fatalf("Failed to create invitation: %v", err)
+	}
+
+	code, _ := service.Auth.CreateMagicLink("guest@example.com")
+	if _, _, err := service.Complete("guest@example.com", code, "00000000", ""); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("Expected ErrInvalidCode for an unknown invitation code, got %v", err)
+	}
+
+	// Typed in lowercase with a dash in the middle
+	typed := strings.ToLower(chosen.Code[:4] + "-" + chosen.Code[4:])
+	code, _ = service.Auth.CreateMagicLink("guest@example.com")
+	user, joined, err := service.Complete("guest@example.com", code, typed, "")
+	if err != nil {
+		t.Fatalf("Failed to complete onboarding: %v", err)
+	}
+	if len(joined) != 1 || joined[0].ID != hardware.ID {
+		t.Errorf("Expected the chosen list to be reported as joined, got %+v", joined)
+	}
+
+	if !listService.HasListAccess(hardware.ID, user.ID) || listService.HasListAccess(groceries.ID, user.ID) {
+		t.Error("Expected user to join only the list of the chosen invitation")
+	}
+	if pending, _ := invitationService.GetPendingInvitations("guest@example.com"); len(pending) != 1 {
+		t.Errorf("Expected the other invitation to stay pending, got %d pending", len(pending))
+	}
+}
+
+func TestService_CompleteBundleInvitation(t *testing.T) {
+	service, db, admin := setupTestService(t)
+	listService := lists.NewService(db)
+
+	groceries, _ := listService.CreateList(admin.ID, "Groceries")
+	hardware, _ := listService.CreateList(admin.ID, "Hardware store")
+	_, err := invitations.NewService(db, nil).CreateBundleInvitation(admin.ID, "bundle@example.com", []string{groceries.ID, hardware.ID})
+	if err != nil {
+		t.Fatalf("Failed to create bundle invitation: %v", err)
+	}
+	code, _ := service.Auth.CreateMagicLink("bundle@example.com")
+
+	user, joined, err := service.Complete("bundle@example.com", code, "", "")
+	if err != nil {
+		t.Fatalf("Failed to complete onboarding: %v", err)
+	}
+	if len(joined) != 2 || joined[0].Name != "Groceries" || joined[1].Name != "Hardware store" {
+		t.Errorf("Expected both lists to be reported as joined, got %+v", joined)
+	}
+
+	if !listService.HasListAccess(groceries.ID, user.ID) || !listService.HasListAccess(hardware.ID, user.ID) {
+		t.Error("Expected user to join every list of the bundle")
+	}
+}
@@ -0,0 +1,73 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package openapi
+
+import (
+	"log/slog"
+	"mime"
+	"strconv"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Middleware validates the JSON bodies of the requests and responses of the routes described in
+// the document, and logs mismatches as warnings. Routes the document does not describe are logged
+// once, so gaps in the schema do not go unnoticed. Requests are served unchanged. Decoding every
+// body again is not free, so it is meant for development only.
+func Middleware(doc *Document) fiber.Handler {
+	var undescribed sync.Map
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+		if err != nil {
+			return err
+		}
+
+		// The route is known once the request was routed
+		route := c.Route()
+		operation, ok := doc.Operation(route.Method, route.Path)
+		if !ok {
+			if _, logged := undescribed.LoadOrStore(route.Method+" "+route.Path, true); !logged {
+				slog.Warn("Route is not described by the API schema", "method", route.Method, "route", route.Path)
+			}
+			return nil
+		}
+
+		var problems []string
+		if schema := operation.RequestSchema(); schema != nil && isJSON(c.Get(fiber.HeaderContentType)) && len(c.Body()) > 0 {
+			for _, problem := range doc.ValidateRequest(schema, c.Body()) {
+				problems = append(problems, "request "+problem)
+			}
+		}
+
+		status := c.Response().StatusCode()
+		schema, described := operation.ResponseSchema(status)
+		switch {
+		case status == fiber.StatusNotModified:
+		case !described:
+			problems = append(problems, "response status "+strconv.Itoa(status)+" is not described")
+		case schema != nil && isJSON(c.GetRespHeader(fiber.HeaderContentType)):
+			for _, problem := range doc.ValidateResponse(schema, c.Response().Body()) {
+				problems = append(problems, "response "+problem)
+			}
+		}
+
+		if len(problems) > 0 {
+			slog.Warn("Request or response does not match the API schema",
+				"method", route.Method,
+				"route", route.Path,
+				"status", status,
+				"mismatches", problems,
+			)
+		}
+		return nil
+	}
+}
+
+// isJSON reports whether a content type is plain JSON. Other encodings, like HAL or MessagePack,
+// are not described.
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == MediaType
+}
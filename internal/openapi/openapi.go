@@ -0,0 +1,335 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package openapi generates an OpenAPI 3.0 document of the API from the Go models of its requests
+// and responses, and validates JSON bodies against it, so handlers drifting from the models are
+// caught in development before clients notice.
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version is the OpenAPI version of the generated documents.
+const Version = "3.0.3"
+
+// MediaType is the media type of the bodies described by the documents.
+const MediaType = "application/json"
+
+// Document is an OpenAPI document. Schemas of named structs are shared as components.
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+
+	// types maps component names to the types they were generated from, to tell apart
+	// structs of the same name in different packages.
+	types map[string]reflect.Type
+}
+
+// Info describes the API.
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Components holds the shared schemas of a document.
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Operation describes the request and response bodies of a route.
+type Operation struct {
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// RequestBody describes the body of a request.
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaBody `json:"content"`
+}
+
+// Response describes a response by status code, with its body if it has one.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaBody `json:"content,omitempty"`
+}
+
+// MediaBody is the schema of a body of a media type.
+type MediaBody struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is the subset of JSON Schema the generator produces and the validator checks.
+// AdditionalProperties is false for structs, which allow no other properties, and the schema of
+// the values for maps.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties any                `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+// oneOf holds the models of a body that has several shapes, see OneOf.
+type oneOf []any
+
+// OneOf describes a body that is one of the models, e.g. a plain array or a page of it.
+func OneOf(models ...any) any {
+	return oneOf(models)
+}
+
+// errorSchema is the name of the schema of error responses.
+const errorSchema = "Error"
+
+// New creates an empty document of the API.
+func New(title, version string) *Document {
+	d := &Document{
+		OpenAPI:    Version,
+		Info:       Info{Title: title, Version: version},
+		Paths:      map[string]map[string]*Operation{},
+		Components: Components{Schemas: map[string]*Schema{}},
+		types:      map[string]reflect.Type{},
+	}
+	// Errors carry a message, and some more details like the fields that failed validation
+	d.Components.Schemas[errorSchema] = &Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{"error": {Type: "string"}},
+		Required:   []string{"error"},
+	}
+	return d
+}
+
+// Add describes the route of method and path, a Fiber path like /lists/:id. request is a value of
+// the model of its JSON body, nil for none, and responses maps the status codes of its successful
+// responses to values of their models, nil for responses without body. Error responses are
+// described once for all routes.
+func (d *Document) Add(method, path string, request any, responses map[int]any) {
+	operation := &Operation{Responses: map[string]*Response{
+		"default": {Description: "Error", Content: d.content(&Schema{Ref: ref(errorSchema)})},
+	}}
+	if request != nil {
+		operation.RequestBody = &RequestBody{Required: true, Content: d.content(d.schemaOf(request))}
+	}
+	for status, model := range responses {
+		response := &Response{Description: http.StatusText(status)}
+		if model != nil {
+			response.Content = d.content(d.schemaOf(model))
+		}
+		operation.Responses[strconv.Itoa(status)] = response
+	}
+
+	key := openAPIPath(path)
+	if d.Paths[key] == nil {
+		d.Paths[key] = map[string]*Operation{}
+	}
+	d.Paths[key][strings.ToLower(method)] = operation
+}
+
+// Operation returns the operation of the route of method and Fiber path, if described.
+func (d *Document) Operation(method, path string) (*Operation, bool) {
+	operation, ok := d.Paths[openAPIPath(path)][strings.ToLower(method)]
+	return operation, ok
+}
+
+// RequestSchema returns the schema of the request body, nil if it has none.
+func (o *Operation) RequestSchema() *Schema {
+	if o.RequestBody == nil {
+		return nil
+	}
+	return o.RequestBody.Content[MediaType].Schema
+}
+
+// ResponseSchema returns the schema of the response body of status, and whether the status is
+// described. Error statuses use the default response.
+func (o *Operation) ResponseSchema(status int) (*Schema, bool) {
+	response, ok := o.Responses[strconv.Itoa(status)]
+	if !ok && status >= http.StatusBadRequest {
+		response, ok = o.Responses["default"]
+	}
+	if !ok || response.Content == nil {
+		return nil, ok
+	}
+	return response.Content[MediaType].Schema, true
+}
+
+func (d *Document) content(schema *Schema) map[string]MediaBody {
+	return map[string]MediaBody{MediaType: {Schema: schema}}
+}
+
+func (d *Document) schemaOf(model any) *Schema {
+	if models, ok := model.(oneOf); ok {
+		schema := &Schema{}
+		for _, model := range models {
+			schema.OneOf = append(schema.OneOf, d.schemaOf(model))
+		}
+		return schema
+	}
+	return d.schema(reflect.TypeOf(model))
+}
+
+var (
+	timeType      = reflect.TypeFor[time.Time]()
+	jsonMarshaler = reflect.TypeFor[json.Marshaler]()
+	textMarshaler = reflect.TypeFor[encoding.TextMarshaler]()
+	rawMessage    = reflect.TypeFor[json.RawMessage]()
+	byteSlice     = reflect.TypeFor[[]byte]()
+	anyType       = reflect.TypeFor[any]()
+)
+
+// schema returns the schema of the JSON encoding of values of t, as encoding/json writes them.
+func (d *Document) schema(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Pointer {
+		schema := d.schema(t.Elem())
+		if schema.Ref != "" {
+			return &Schema{AllOf: []*Schema{schema}, Nullable: true}
+		}
+		schema.Nullable = true
+		return schema
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessage || t == anyType:
+		return &Schema{}
+	case implements(t, jsonMarshaler):
+		// Custom encodings, e.g. of gorm.DeletedAt, may take any shape
+		return &Schema{}
+	case implements(t, textMarshaler):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t == byteSlice {
+			return &Schema{Type: "string", Format: "byte", Nullable: true}
+		}
+		// Nil slices are encoded as null
+		return &Schema{Type: "array", Items: d.schema(t.Elem()), Nullable: t.Kind() == reflect.Slice}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schema(t.Elem()), Nullable: true}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.object(t)
+		}
+		return &Schema{Ref: ref(d.component(t))}
+	}
+	return &Schema{}
+}
+
+// component adds the schema of the named struct t to the components, unless already there, and
+// returns its name.
+func (d *Document) component(t reflect.Type) string {
+	name := t.Name()
+	if other, ok := d.types[name]; ok && other != t {
+		name = pathBase(t.PkgPath()) + "." + name
+	}
+	if _, ok := d.types[name]; ok {
+		return name
+	}
+
+	// Register the name first, so recursive types refer to it
+	d.types[name] = t
+	d.Components.Schemas[name] = &Schema{}
+	*d.Components.Schemas[name] = *d.object(t)
+	return name
+}
+
+// object returns the schema of a struct, with the fields of embedded structs inlined.
+func (d *Document) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}, AdditionalProperties: false}
+	d.fields(t, schema)
+	return schema
+}
+
+func (d *Document) fields(t reflect.Type, schema *Schema) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				d.fields(embedded, schema)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := d.schema(field.Type)
+		if hasOption(options, "string") {
+			property = &Schema{Type: "string"}
+		}
+		schema.Properties[name] = property
+		if !hasOption(options, "omitempty") && !hasOption(options, "omitzero") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+func implements(t, iface reflect.Type) bool {
+	return t.Implements(iface) || reflect.PointerTo(t).Implements(iface)
+}
+
+func hasOption(options, option string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+func ref(name string) string {
+	return "#/components/schemas/" + name
+}
+
+func pathBase(pkgPath string) string {
+	return pkgPath[strings.LastIndex(pkgPath, "/")+1:]
+}
+
+// openAPIPath turns a Fiber path like /lists/:id into /lists/{id}.
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			segments[i] = "{" + strings.TrimSuffix(name, "?") + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,181 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type audit struct {
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type note struct {
+	audit
+	ID      string            `json:"id"`
+	Text    *string           `json:"text"`
+	Tags    []string          `json:"tags"`
+	Labels  map[string]int    `json:"labels,omitempty"`
+	Parent  *note             `json:"parent,omitempty"`
+	Secret  string            `json:"-"`
+	Extra   json.RawMessage   `json:"extra,omitempty"`
+	Related []note            `json:"related,omitempty"`
+	Counts  map[string]string `json:"counts,omitempty"`
+}
+
+type notePage struct {
+	Notes []note `json:"notes"`
+	Total int    `json:"total"`
+}
+
+func TestDocument_Add(t *testing.T) {
+	doc := New("Notes", "1.0")
+	doc.Add("GET", "/notes/:id", nil, map[int]any{fiber.StatusOK: note{}})
+	doc.Add("DELETE", "/notes/:id", nil, map[int]any{fiber.StatusNoContent: nil})
+
+	if _, ok := doc.Paths["/notes/{id}"]["get"]; !ok {
+		t.Fatalf("Expected the route in OpenAPI form, got %v", doc.Paths)
+	}
+	schema := doc.Components.Schemas["note"]
+	if schema == nil {
+		t.Fatalf("Expected a component of the struct, got %v", doc.Components.Schemas)
+	}
+	if _, ok := schema.Properties["created_at"]; !ok {
+		t.Error("Expected the fields of embedded structs to be inlined")
+	}
+	if _, ok := schema.Properties["Secret"]; ok {
+		t.Error("Expected fields tagged with - to be left out")
+	}
+	if !slices.Equal(schema.Required, []string{"created_at", "id", "text", "tags"}) {
+		t.Errorf("Expected fields without omitempty to be required, got %v", schema.Required)
+	}
+	if text := schema.Properties["text"]; text.Type != "string" || !text.Nullable {
+		t.Errorf("Expected pointers to be nullable, got %+v", text)
+	}
+	if parent := schema.Properties["parent"]; len(parent.AllOf) != 1 || parent.AllOf[0].Ref != "#/components/schemas/note" {
+		t.Errorf("Expected recursive structs to refer to their component, got %+v", parent)
+	}
+
+	if _, ok := doc.Operation("DELETE", "/notes/:id"); !ok {
+		t.Error("Expected to find the operation by its Fiber path")
+	}
+	operation, _ := doc.Operation("GET", "/notes/:id")
+	if _, described := operation.ResponseSchema(fiber.StatusCreated); described {
+		t.Error("Expected other successful statuses not to be described")
+	}
+	if schema, described := operation.ResponseSchema(fiber.StatusNotFound); !described || schema.Ref != "#/components/schemas/Error" {
+		t.Errorf("Expected errors to use the default response, got %+v", schema)
+	}
+
+	encoded, err := json.Marshal(doc)
+	if err != nil || !bytes.Contains(encoded, []byte(`"openapi":"3.0.3"`)) {
+		t.Errorf("Expected the document to encode, got %s %v", encoded, err)
+	}
+}
+
+func TestDocument_Validate(t *testing.T) {
+	doc := New("Notes", "1.0")
+	doc.Add("POST", "/notes", note{}, map[int]any{fiber.StatusOK: OneOf([]note{}, notePage{})})
+	operation, _ := doc.Operation("POST", "/notes")
+	response, _ := operation.ResponseSchema(fiber.StatusOK)
+
+	valid := `[{"id":"1","created_at":"2025-03-01T10:00:00Z","text":null,"tags":null,"labels":{"a":1},"extra":[1]}]`
+	if problems := doc.ValidateResponse(response, []byte(valid)); len(problems) != 0 {
+		t.Errorf("Expected a valid response, got %v", problems)
+	}
+	if problems := doc.ValidateResponse(response, []byte(`{"notes":[],"total":0}`)); len(problems) != 0 {
+		t.Errorf("Expected the page to match the other shape, got %v", problems)
+	}
+
+	for body, expected := range map[string]string{
+		`{"id":1,"created_at":"2025-03-01T10:00:00Z","text":"","tags":[]}`:                       "$.id: expected string, got integer",
+		`{"id":"1","created_at":"yesterday","text":"","tags":[]}`:                                "$.created_at: expected a date-time, got yesterday",
+		`{"id":"1","created_at":"2025-03-01T10:00:00Z","text":"","tags":[],"color":"x"}`:         "$: unexpected property color",
+		`{"id":"1","created_at":"2025-03-01T10:00:00Z","text":""}`:                               "$: missing property tags",
+		`{"id":"1","created_at":"2025-03-01T10:00:00Z","text":"","tags":[2]}`:                    "$.tags[0]: expected string, got integer",
+		`{"id":"1","created_at":"2025-03-01T10:00:00Z","text":"","tags":[],"labels":{"a":1.5}}`:  "$.labels.a: expected integer, got number",
+		`{"id":"1","created_at":"2025-03-01T10:00:00Z","text":"","tags":[],"parent":{"id":"2"}}`: "$.parent: missing property created_at",
+	} {
+		problems := doc.ValidateResponse(operation.RequestSchema(), []byte(body))
+		if !slices.Contains(problems, expected) {
+			t.Errorf("%s: expected %q, got %v", body, expected, problems)
+		}
+	}
+
+	// Requests may leave out properties, but not send unknown ones
+	if problems := doc.ValidateRequest(operation.RequestSchema(), []byte(`{"text":"Hello"}`)); len(problems) != 0 {
+		t.Errorf("Expected missing properties to be fine in requests, got %v", problems)
+	}
+	if problems := doc.ValidateRequest(operation.RequestSchema(), []byte(`{"body":"Hello"}`)); !slices.Equal(problems, []string{"$: unexpected property body"}) {
+		t.Errorf("Expected unknown properties to be reported, got %v", problems)
+	}
+	if problems := doc.ValidateRequest(operation.RequestSchema(), []byte(`{"text":`)); len(problems) != 1 || !strings.HasPrefix(problems[0], "$: invalid JSON") {
+		t.Errorf("Expected invalid JSON to be reported, got %v", problems)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	defer slog.SetDefault(previous)
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	doc := New("Notes", "1.0")
+	doc.Add("POST", "/notes/:id", note{}, map[int]any{fiber.StatusCreated: note{}})
+
+	app := fiber.New()
+	app.Use(Middleware(doc))
+	app.Post("/notes/:id", func(c *fiber.Ctx) error {
+		if c.Params("id") == "drifted" {
+			return c.Status(fiber.StatusCreated).JSON(fiber.Map{"id": 1})
+		}
+		if c.Params("id") == "missing" {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Note not found"})
+		}
+		return c.Status(fiber.StatusCreated).JSON(note{ID: "1", Tags: []string{}})
+	})
+	app.Get("/undescribed", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{"anything": true})
+	})
+
+	request := func(method, target, body string) {
+		t.Helper()
+
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if _, err := app.Test(req); err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+	}
+
+	request("POST", "/notes/valid", `{"text":"Hello"}`)
+	request("POST", "/notes/missing", `{}`)
+	if buf.Len() != 0 {
+		t.Fatalf("Expected no mismatches, got %s", buf.String())
+	}
+
+	// Undescribed routes are logged once
+	request("GET", "/undescribed", "")
+	request("GET", "/undescribed", "")
+	if count := strings.Count(buf.String(), "Route is not described"); count != 1 || !strings.Contains(buf.String(), "route=/undescribed") {
+		t.Fatalf("Expected the undescribed route to be logged once, got %s", buf.String())
+	}
+	buf.Reset()
+
+	request("POST", "/notes/drifted", `{"body":"Hello"}`)
+	for _, expected := range []string{"route=/notes/:id", "request $: unexpected property body", "response $.id: expected string, got integer"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected %q to be logged, got %s", expected, buf.String())
+		}
+	}
+}
@@ -0,0 +1,181 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ValidateResponse checks a JSON response body against the schema and returns the mismatches,
+// each starting with the path of the offending value, e.g. "$.items[2].name: expected string,
+// got number".
+func (d *Document) ValidateResponse(schema *Schema, body []byte) []string {
+	return d.validateJSON(schema, body, true)
+}
+
+// ValidateRequest checks a JSON request body against the schema like ValidateResponse, except
+// that properties may be missing, as the handlers leave them at their zero values. Unknown
+// properties are reported, as the handlers ignore them.
+func (d *Document) ValidateRequest(schema *Schema, body []byte) []string {
+	return d.validateJSON(schema, body, false)
+}
+
+func (d *Document) validateJSON(schema *Schema, body []byte, required bool) []string {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return []string{"$: invalid JSON: " + err.Error()}
+	}
+	v := &validator{Document: d, required: required}
+	v.validate(schema, value, "$")
+	return v.problems
+}
+
+// validator collects the mismatches of a value, checking required properties if required is set.
+type validator struct {
+	*Document
+	required bool
+	problems []string
+}
+
+func (v *validator) report(problem string) {
+	v.problems = append(v.problems, problem)
+}
+
+func (v *validator) validate(schema *Schema, value any, path string) {
+	if schema == nil {
+		return
+	}
+	if schema.Ref != "" {
+		v.validate(v.Components.Schemas[strings.TrimPrefix(schema.Ref, ref(""))], value, path)
+		return
+	}
+	if value == nil {
+		if !schema.Nullable && (schema.Type != "" || len(schema.AllOf) > 0) {
+			v.report(path + ": must not be null")
+		}
+		return
+	}
+
+	for _, part := range schema.AllOf {
+		v.validate(part, value, path)
+	}
+	if len(schema.OneOf) > 0 {
+		matches := 0
+		for _, alternative := range schema.OneOf {
+			other := &validator{Document: v.Document, required: v.required}
+			if other.validate(alternative, value, path); len(other.problems) == 0 {
+				matches++
+			}
+		}
+		if matches == 0 {
+			v.report(fmt.Sprintf("%s: matches none of %d shapes", path, len(schema.OneOf)))
+		}
+	}
+
+	switch schema.Type {
+	case "":
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			v.report(mismatch(path, schema.Type, value))
+			return
+		}
+		v.validateObject(schema, object, path)
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			v.report(mismatch(path, schema.Type, value))
+			return
+		}
+		for i, item := range array {
+			v.validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i))
+		}
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			v.report(mismatch(path, schema.Type, value))
+			return
+		}
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, text); err != nil {
+				v.report(path + ": expected a date-time, got " + text)
+			}
+		}
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok || strings.ContainsAny(number.String(), ".eE") {
+			v.report(mismatch(path, schema.Type, value))
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			v.report(mismatch(path, schema.Type, value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			v.report(mismatch(path, schema.Type, value))
+		}
+	}
+}
+
+func (v *validator) validateObject(schema *Schema, object map[string]any, path string) {
+	for _, name := range schema.Required {
+		if _, ok := object[name]; !ok && v.required {
+			v.report(path + ": missing property " + name)
+		}
+	}
+
+	// Report in a stable order
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := object[name]
+		if property, ok := schema.Properties[name]; ok {
+			v.validate(property, value, path+"."+name)
+			continue
+		}
+		switch additional := schema.AdditionalProperties.(type) {
+		case bool:
+			if !additional {
+				v.report(path + ": unexpected property " + name)
+			}
+		case *Schema:
+			v.validate(additional, value, path+"."+name)
+		}
+	}
+}
+
+// mismatch describes a value of the wrong JSON type.
+func mismatch(path, expected string, value any) string {
+	return fmt.Sprintf("%s: expected %s, got %s", path, expected, jsonType(value))
+}
+
+func jsonType(value any) string {
+	switch value := value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case json.Number:
+		if strings.ContainsAny(value.String(), ".eE") {
+			return "number"
+		}
+		return "integer"
+	case bool:
+		return "boolean"
+	}
+	return "null"
+}
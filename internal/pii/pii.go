@@ -0,0 +1,186 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package pii provides optional application-level encryption of personal data such as email
+// addresses. Values are encrypted with AES-GCM using a nonce derived from the plaintext, so equal
+// values encrypt equally and unique indexes and equality lookups keep working.
+package pii
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm/schema"
+)
+
+// prefix marks encrypted values so plaintext values written before encryption was enabled can
+// still be read.
+const prefix = "enc:v1:"
+
+// ErrDecrypt is returned when an encrypted value cannot be decrypted with the configured key.
+var ErrDecrypt = errors.New("failed to decrypt personal data, check the encryption key")
+
+// Cipher encrypts and decrypts personal data with a single key.
+type Cipher struct {
+	aead     cipher.AEAD
+	nonceKey []byte
+}
+
+// NewCipher derives the encryption keys from an arbitrary secret. An empty secret returns a nil
+// Cipher, which leaves values unencrypted.
+func NewCipher(secret string) (*Cipher, error) {
+	if secret == "" {
+		return nil, nil
+	}
+
+	block, err := aes.NewCipher(derive(secret, "encryption"))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cipher{aead: aead, nonceKey: derive(secret, "nonce")}, nil
+}
+
+// Encrypt encrypts a value. A nil Cipher and empty values return the value unchanged.
+func (c *Cipher) Encrypt(value string) string {
+	if c == nil || value == "" || strings.HasPrefix(value, prefix) {
+		return value
+	}
+
+	nonce := derive(string(c.nonceKey), value)[:c.aead.NonceSize()]
+	sealed := c.aead.Seal(nonce, nonce, []byte(value), nil)
+	return prefix + base64.RawStdEncoding.EncodeToString(sealed)
+}
+
+// Decrypt decrypts a value. Values without the encryption prefix are returned unchanged.
+func (c *Cipher) Decrypt(value string) (string, error) {
+	if !strings.HasPrefix(value, prefix) {
+		return value, nil
+	}
+	if c == nil {
+		return "", ErrDecrypt
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(value, prefix))
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", ErrDecrypt
+	}
+
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	return string(plaintext), nil
+}
+
+// derive returns an HMAC-SHA256 of data keyed with secret.
+func derive(secret, data string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+var (
+	mu      sync.RWMutex
+	current *Cipher
+)
+
+// SetKey configures the key used by the "pii" serializer and the package-level helpers.
+// An empty key disables encryption.
+func SetKey(secret string) error {
+	c, err := NewCipher(secret)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	current = c
+	mu.Unlock()
+	return nil
+}
+
+// Enabled reports whether a key is configured.
+func Enabled() bool {
+	return active() != nil
+}
+
+// Encrypt encrypts a value with the configured key. Use it for query parameters compared
+// against encrypted columns.
+func Encrypt(value string) string {
+	return active().Encrypt(value)
+}
+
+// Decrypt decrypts a value read from an encrypted column without going through a model,
+// e.g. via Pluck or Scan.
+func Decrypt(value string) (string, error) {
+	return active().Decrypt(value)
+}
+
+// DecryptAll decrypts a list of values read from an encrypted column.
+func DecryptAll(values []string) ([]string, error) {
+	decrypted := make([]string, len(values))
+	for i, value := range values {
+		var err error
+		if decrypted[i], err = Decrypt(value); err != nil {
+			return nil, err
+		}
+	}
+	return decrypted, nil
+}
+
+func active() *Cipher {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+func init() {
+	schema.RegisterSerializer("pii", Serializer{})
+}
+
+// Serializer is a GORM serializer that transparently encrypts string fields tagged with
+// `gorm:"serializer:pii"`.
+type Serializer struct{}
+
+// Scan decrypts a database value into the field.
+func (Serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("failed to decrypt value of type %T", dbValue)
+	}
+
+	decrypted, err := Decrypt(value)
+	if err != nil {
+		return err
+	}
+	return field.Set(ctx, dst, decrypted)
+}
+
+// Value encrypts the field value before it is written to the database.
+func (Serializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("failed to encrypt value of type %T", fieldValue)
+	}
+	return Encrypt(value), nil
+}
@@ -0,0 +1,142 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package pii
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestCipher(t *testing.T) {
+	c, err := NewCipher("secret")
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+
+	encrypted := c.Encrypt("user@example.com")
+	if !strings.HasPrefix(encrypted, prefix) || strings.Contains(encrypted, "user@example.com") {
+		t.Errorf("Expected encrypted value, got %s", encrypted)
+	}
+	if c.Encrypt("user@example.com") != encrypted {
+		t.Error("Expected equal values to encrypt equally")
+	}
+	if c.Encrypt("other@example.com") == encrypted {
+		t.Error("Expected different values to encrypt differently")
+	}
+	if c.Encrypt(encrypted) != encrypted {
+		t.Error("Expected encrypted values not to be encrypted twice")
+	}
+
+	decrypted, err := c.Decrypt(encrypted)
+	if err != nil || decrypted != "user@example.com" {
+		t.Errorf("Expected decrypted value, got %q (%v)", decrypted, err)
+	}
+
+	if plain, err := c.Decrypt("plain@example.com"); err != nil || plain != "plain@example.com" {
+		t.Errorf("Expected plaintext to pass through, got %q (%v)", plain, err)
+	}
+
+	other, _ := NewCipher("other")
+	if _, err := other.Decrypt(encrypted); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Expected ErrDecrypt with wrong key, got %v", err)
+	}
+
+	var disabled *Cipher
+	if disabled.Encrypt("user@example.com") != "user@example.com" {
+		t.Error("Expected nil cipher to leave values unencrypted")
+	}
+	if _, err := disabled.Decrypt(encrypted); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Expected ErrDecrypt without key, got %v", err)
+	}
+}
+
+func TestRekey(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	for _, column := range Columns {
+		if err := db.Exec("CREATE TABLE " + column.Table + " (id TEXT PRIMARY KEY, " + column.Name + " TEXT)").Error; err != nil {
+			t.Fatalf("Failed to create table: %v", err)
+		}
+	}
+	if err := db.Exec("INSERT INTO users (id, email) VALUES ('1', 'a@example.com'), ('2', 'b@example.com')").Error; err != nil {
+		t.Fatalf("Failed to insert users: %v", err)
+	}
+
+	emailOf := func(id string) string {
+		var email string
+		db.Raw("SELECT email FROM users WHERE id = ?", id).Scan(&email)
+		return email
+	}
+
+	updated, err := Rekey(db, "", "first")
+	if err != nil || updated != 2 {
+		t.Fatalf("Expected 2 values to be encrypted, got %d (%v)", updated, err)
+	}
+	first, _ := NewCipher("first")
+	if emailOf("1") != first.Encrypt("a@example.com") {
+		t.Errorf("Expected value encrypted with first key, got %s", emailOf("1"))
+	}
+
+	if _, err := Rekey(db, "wrong", "second"); err == nil {
+		t.Error("Expected re-key with wrong old key to fail")
+	}
+
+	if _, err := Rekey(db, "first", "second"); err != nil {
+		t.Fatalf("Failed to re-key: %v", err)
+	}
+	second, _ := NewCipher("second")
+	if email, _ := second.Decrypt(emailOf("2")); email != "b@example.com" {
+		t.Errorf("Expected value encrypted with second key, got %s", emailOf("2"))
+	}
+
+	if _, err := Rekey(db, "second", ""); err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	if emailOf("1") != "a@example.com" {
+		t.Errorf("Expected plaintext value, got %s", emailOf("1"))
+	}
+}
+
+func TestSerializer(t *testing.T) {
+	if err := SetKey("secret"); err != nil {
+		t.Fatalf("Failed to set key: %v", err)
+	}
+	t.Cleanup(func() { _ = SetKey("") })
+
+	type contact struct {
+		ID    string
+		Email string `gorm:"serializer:pii"`
+	}
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if err := db.AutoMigrate(&contact{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if err := db.Create(&contact{ID: "1", Email: "user@example.com"}).Error; err != nil {
+		t.Fatalf("Failed to create contact: %v", err)
+	}
+
+	var stored string
+	db.Raw("SELECT email FROM contacts WHERE id = '1'").Scan(&stored)
+	if !strings.HasPrefix(stored, prefix) {
+		t.Errorf("Expected email to be stored encrypted, got %s", stored)
+	}
+
+	var found contact
+	if err := db.Where("email = ?", Encrypt("user@example.com")).First(&found).Error; err != nil {
+		t.Fatalf("Failed to look up contact by email: %v", err)
+	}
+	if found.Email != "user@example.com" {
+		t.Errorf("Expected decrypted email, got %s", found.Email)
+	}
+}
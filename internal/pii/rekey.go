@@ -0,0 +1,74 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package pii
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Column identifies a database column holding encrypted personal data.
+type Column struct {
+	Table string
+	Name  string
+}
+
+// Columns lists all columns stored with the "pii" serializer.
+var Columns = []Column{
+	{Table: "users", Name: "email"},
+	{Table: "invitations", Name: "email"},
+}
+
+// Rekey re-encrypts all personal data from oldKey to newKey in a single transaction and returns
+// the number of updated rows. An empty oldKey encrypts existing plaintext data, an empty newKey
+// decrypts it.
+func Rekey(db *gorm.DB, oldKey, newKey string) (int, error) {
+	oldCipher, err := NewCipher(oldKey)
+	if err != nil {
+		return 0, err
+	}
+	newCipher, err := NewCipher(newKey)
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, column := range Columns {
+			var rows []struct {
+				ID    string
+				Value string
+			}
+			query := fmt.Sprintf("SELECT id, %s AS value FROM %s", column.Name, column.Table)
+			if err := tx.Raw(query).Scan(&rows).Error; err != nil {
+				return err
+			}
+
+			for _, row := range rows {
+				plaintext, err := oldCipher.Decrypt(row.Value)
+				if err != nil {
+					return fmt.Errorf("%s %s: %w", column.Table, row.ID, err)
+				}
+
+				value := newCipher.Encrypt(plaintext)
+				if value == row.Value {
+					continue
+				}
+
+				update := fmt.Sprintf("UPDATE %s SET %s = ? WHERE id = ?", column.Table, column.Name)
+				if err := tx.Exec(update, value, row.ID).Error; err != nil {
+					return err
+				}
+				updated++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return updated, nil
+}
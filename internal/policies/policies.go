@@ -0,0 +1,106 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package policies provides versioned terms of service and privacy policy documents and
+// tracks which versions each user has accepted.
+package policies
+
+import (
+	"errors"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// Policy document types.
+const (
+	TypeTerms   = "terms"
+	TypePrivacy = "privacy"
+)
+
+// Service provides policy documents and acceptance tracking.
+type Service struct {
+	DB *gorm.DB
+	// Documents are the current versions of all policy documents users have to accept.
+	Documents []models.PolicyDocument
+	// Required blocks API use until all documents are accepted.
+	Required bool
+}
+
+// NewService creates a new policies service without any documents.
+func NewService(db *gorm.DB) *Service {
+	return &Service{
+		DB: db,
+	}
+}
+
+// AddDocument registers the current version of a policy document.
+func (s *Service) AddDocument(docType, version, url string) {
+	s.Documents = append(s.Documents, models.PolicyDocument{
+		Type:    docType,
+		Version: version,
+		URL:     url,
+	})
+}
+
+// Enforced reports whether users are blocked until they have accepted all documents.
+func (s *Service) Enforced() bool {
+	return s.Required && len(s.Documents) > 0
+}
+
+// GetAcceptances retrieves all policy versions the user has accepted, newest first.
+func (s *Service) GetAcceptances(userID string) ([]models.PolicyAcceptance, error) {
+	var acceptances []models.PolicyAcceptance
+	err := s.DB.Where("user_id = ?", userID).Order("accepted_at DESC").Find(&acceptances).Error
+	return acceptances, err
+}
+
+// PendingDocuments returns the documents whose current version the user has not accepted yet.
+func (s *Service) PendingDocuments(userID string) ([]models.PolicyDocument, error) {
+	pending := []models.PolicyDocument{}
+	for _, doc := range s.Documents {
+		var count int64
+		err := s.DB.Model(&models.PolicyAcceptance{}).
+			Where("user_id = ? AND document_type = ? AND version = ?", userID, doc.Type, doc.Version).
+			Count(&count).Error
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			pending = append(pending, doc)
+		}
+	}
+	return pending, nil
+}
+
+// Accept records that the user accepted the given version of a document. Only the current
+// version of a document can be accepted.
+func (s *Service) Accept(userID, docType, version string) (*models.PolicyAcceptance, error) {
+	var current *models.PolicyDocument
+	for i := range s.Documents {
+		if s.Documents[i].Type == docType {
+			current = &s.Documents[i]
+			break
+		}
+	}
+	if current == nil {
+		return nil, errors.New("unknown policy document")
+	}
+	if current.Version != version {
+		return nil, errors.New("policy version is outdated")
+	}
+
+	acceptance := models.PolicyAcceptance{
+		UserID:       userID,
+		DocumentType: docType,
+		Version:      version,
+		AcceptedAt:   time.Now(),
+	}
+
+	if err := s.DB.Save(&acceptance).Error; err != nil {
+		return nil, err
+	}
+
+	return &acceptance, nil
+}
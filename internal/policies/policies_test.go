@@ -0,0 +1,85 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package policies
+
+import (
+	"testing"
+
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+)
+
+func TestService_Enforced(t *testing.T) {
+	service := NewService(nil)
+	service.Required = true
+
+	if service.Enforced() {
+		t.Error("Expected acceptance not to be enforced without documents")
+	}
+
+	service.AddDocument(TypeTerms, "2025-01", "https://example.com/terms")
+	if !service.Enforced() {
+		t.Error("Expected acceptance to be enforced")
+	}
+}
+
+func TestService_Accept(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+	service.AddDocument(TypeTerms, "2025-01", "https://example.com/terms")
+	service.AddDocument(TypePrivacy, "v2", "https://example.com/privacy")
+
+	pending, err := service.PendingDocuments("user-1")
+	if err != nil {
+		t.Fatalf("Failed to get pending documents: %v", err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("Expected 2 pending documents, got %d", len(pending))
+	}
+
+	t.Run("accept current version", func(t *testing.T) {
+		if _, err := service.Accept("user-1", TypeTerms, "2025-01"); err != nil {
+			t.Fatalf("Failed to accept terms: %v", err)
+		}
+
+		pending, err := service.PendingDocuments("user-1")
+		if err != nil {
+			t.Fatalf("Failed to get pending documents: %v", err)
+		}
+		if len(pending) != 1 || pending[0].Type != TypePrivacy {
+			t.Errorf("Expected only privacy policy to be pending, got %+v", pending)
+		}
+	})
+
+	t.Run("reject outdated version", func(t *testing.T) {
+		if _, err := service.Accept("user-1", TypePrivacy, "v1"); err == nil {
+			t.Error("Expected outdated version to be rejected")
+		}
+	})
+
+	t.Run("reject unknown document", func(t *testing.T) {
+		if _, err := service.Accept("user-1", "cookies", "v1"); err == nil {
+			t.Error("Expected unknown document to be rejected")
+		}
+	})
+
+	t.Run("new version becomes pending again", func(t *testing.T) {
+		service.Documents[0].Version = "2025-06"
+
+		pending, err := service.PendingDocuments("user-1")
+		if err != nil {
+			t.Fatalf("Failed to get pending documents: %v", err)
+		}
+		if len(pending) != 2 {
+			t.Errorf("Expected 2 pending documents after terms update, got %d", len(pending))
+		}
+
+		acceptances, err := service.GetAcceptances("user-1")
+		if err != nil {
+			t.Fatalf("Failed to get acceptances: %v", err)
+		}
+		if len(acceptances) != 1 {
+			t.Errorf("Expected accepted version to be kept, got %d acceptances", len(acceptances))
+		}
+	})
+}
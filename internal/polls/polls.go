@@ -0,0 +1,135 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package polls provides small polls on items, like "crunchy or smooth peanut butter?", which
+// the members of a list vote on.
+package polls
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// Errors returned by the polls service.
+var (
+	ErrItemNotFound    = errors.New("item not found")
+	ErrPollNotFound    = errors.New("no poll on this item")
+	ErrInvalidOptions  = errors.New("poll options must not be empty or repeat")
+	ErrInvalidResponse = errors.New("invalid poll option")
+)
+
+// Service provides item poll operations.
+type Service struct {
+	DB *gorm.DB
+}
+
+// NewService creates a new polls service.
+func NewService(db *gorm.DB) *Service {
+	return &Service{DB: db}
+}
+
+// GetPoll retrieves the poll on an item of a list with all votes and their tally.
+func (s *Service) GetPoll(listID, itemID string) (*models.ItemPoll, error) {
+	var poll models.ItemPoll
+	err := s.DB.Preload("Votes").Where("list_id = ? AND item_id = ?", listID, itemID).First(&poll).Error
+	if err != nil {
+		return nil, ErrPollNotFound
+	}
+
+	poll.Tally = make([]int, len(poll.Options))
+	for _, vote := range poll.Votes {
+		if vote.Option < len(poll.Tally) {
+			poll.Tally[vote.Option]++
+		}
+	}
+	return &poll, nil
+}
+
+// CreatePoll starts a poll on an item of a list, replacing any previous poll on it.
+func (s *Service) CreatePoll(listID, itemID, userID, question string, options []string) (*models.ItemPoll, error) {
+	var count int64
+	if err := s.DB.Model(&models.ShoppingItem{}).Where("id = ? AND list_id = ?", itemID, listID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrItemNotFound
+	}
+
+	trimmed := make([]string, 0, len(options))
+	for _, option := range options {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			return nil, ErrInvalidOptions
+		}
+		for _, other := range trimmed {
+			if strings.EqualFold(option, other) {
+				return nil, ErrInvalidOptions
+			}
+		}
+		trimmed = append(trimmed, option)
+	}
+
+	if err := s.DeletePoll(itemID); err != nil {
+		return nil, err
+	}
+
+	poll := models.ItemPoll{
+		ID:        uuid.New().String(),
+		ItemID:    itemID,
+		ListID:    listID,
+		Question:  strings.TrimSpace(question),
+		Options:   trimmed,
+		CreatedBy: userID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := s.DB.Create(&poll).Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetPoll(listID, itemID)
+}
+
+// DeletePoll removes the poll on an item and its votes.
+func (s *Service) DeletePoll(itemID string) error {
+	var poll models.ItemPoll
+	if err := s.DB.Where("item_id = ?", itemID).First(&poll).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if err := s.DB.Where("poll_id = ?", poll.ID).Delete(&models.PollVote{}).Error; err != nil {
+		return err
+	}
+	return s.DB.Delete(&poll).Error
+}
+
+// Vote records a member's choice in the poll on an item, replacing their previous vote.
+func (s *Service) Vote(listID, itemID, userID string, option int) (*models.ItemPoll, error) {
+	poll, err := s.GetPoll(listID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if option < 0 || option >= len(poll.Options) {
+		return nil, ErrInvalidResponse
+	}
+
+	vote := models.PollVote{
+		PollID:    poll.ID,
+		UserID:    userID,
+		Option:    option,
+		UpdatedAt: time.Now(),
+	}
+	if err := s.DB.Save(&vote).Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetPoll(listID, itemID)
+}
@@ -0,0 +1,87 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package polls
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+)
+
+func TestService_Polls(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+
+	db.Create(&models.ShoppingItem{ID: "peanut-butter", ListID: "list-1", Name: "Peanut butter", CreatedAt: time.Now()})
+
+	t.Run("poll on a missing item", func(t *testing.T) {
+		_, err := service.CreatePoll("list-2", "peanut-butter", "user-1", "Which one?", []string{"Crunchy", "Smooth"})
+		if !errors.Is(err, ErrItemNotFound) {
+			t.Errorf("Expected item not found, got %v", err)
+		}
+	})
+
+	t.Run("repeated options", func(t *testing.T) {
+		_, err := service.CreatePoll("list-1", "peanut-butter", "user-1", "Which one?", []string{"Crunchy", " crunchy "})
+		if !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("Expected invalid options, got %v", err)
+		}
+	})
+
+	t.Run("vote and tally", func(t *testing.T) {
+		poll, err := service.CreatePoll("list-1", "peanut-butter", "user-1", " Which one? ", []string{" Crunchy", "Smooth "})
+		if err != nil {
+			t.Fatalf("Failed to create poll: %v", err)
+		}
+		if poll.Question != "Which one?" || !slices.Equal(poll.Options, []string{"Crunchy", "Smooth"}) {
+			t.Errorf("Expected trimmed question and options, got %q %v", poll.Question, poll.Options)
+		}
+
+		service.Vote("list-1", "peanut-butter", "user-1", 0)
+		service.Vote("list-1", "peanut-butter", "user-2", 0)
+		poll, err = service.Vote("list-1", "peanut-butter", "user-2", 1)
+		if err != nil {
+			t.Fatalf("Failed to vote: %v", err)
+		}
+		if !slices.Equal(poll.Tally, []int{1, 1}) || len(poll.Votes) != 2 {
+			t.Errorf("Expected one vote per member, got %v", poll.Tally)
+		}
+
+		if _, err := service.Vote("list-1", "peanut-butter", "user-1", 2); !errors.Is(err, ErrInvalidResponse) {
+			t.Errorf("Expected invalid option, got %v", err)
+		}
+	})
+
+	t.Run("new poll replaces the old one", func(t *testing.T) {
+		if _, err := service.CreatePoll("list-1", "peanut-butter", "user-2", "Which brand?", []string{"A", "B", "C"}); err != nil {
+			t.Fatalf("Failed to create poll: %v", err)
+		}
+		poll, err := service.GetPoll("list-1", "peanut-butter")
+		if err != nil {
+			t.Fatalf("Failed to get poll: %v", err)
+		}
+		if poll.Question != "Which brand?" || !slices.Equal(poll.Tally, []int{0, 0, 0}) {
+			t.Errorf("Expected a fresh poll, got %q %v", poll.Question, poll.Tally)
+		}
+
+		var votes int64
+		db.Model(&models.PollVote{}).Count(&votes)
+		if votes != 0 {
+			t.Errorf("Expected votes of the old poll to be removed, got %d", votes)
+		}
+	})
+
+	t.Run("delete poll", func(t *testing.T) {
+		if err := service.DeletePoll("peanut-butter"); err != nil {
+			t.Fatalf("Failed to delete poll: %v", err)
+		}
+		if _, err := service.GetPoll("list-1", "peanut-butter"); !errors.Is(err, ErrPollNotFound) {
+			t.Errorf("Expected poll to be gone, got %v", err)
+		}
+	})
+}
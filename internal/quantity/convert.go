@@ -0,0 +1,200 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package quantity
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Errors returned by Convert.
+var (
+	ErrUnknownUnit       = errors.New("unknown unit")
+	ErrIncompatibleUnits = errors.New("units cannot be converted into each other")
+)
+
+// Amount is a quantity in a unit.
+type Amount struct {
+	Quantity float64 `json:"quantity"`
+	Unit     string  `json:"unit"`
+}
+
+// conversion places a canonical unit in its dimension by its factor to the dimension's base unit.
+type conversion struct {
+	dimension string
+	factor    float64
+}
+
+// conversions holds the units that convert into each other. Packs, boxes and the like have no
+// fixed size and only convert into pieces given a pack size.
+var conversions = map[string]conversion{
+	"mg":    {"mass", 0.001},
+	"g":     {"mass", 1},
+	"kg":    {"mass", 1000},
+	"oz":    {"mass", 28.349523125},
+	"lb":    {"mass", 453.59237},
+	"ml":    {"volume", 1},
+	"cl":    {"volume", 10},
+	"dl":    {"volume", 100},
+	"l":     {"volume", 1000},
+	"piece": {"count", 1},
+	"dozen": {"count", 12},
+}
+
+// Dimension returns what a unit measures, like "mass" for kg and g, so amounts of the same
+// dimension can be added up. Units that convert into no other unit are their own dimension.
+func Dimension(unit string) string {
+	canonical := canonicalUnit(unit)
+	if c, ok := conversions[canonical]; ok {
+		return c.dimension
+	}
+	return canonical
+}
+
+// Convert converts a quantity between units of the same dimension. With a packSize above zero,
+// a pack holds that many pieces, so packs and pieces convert into each other as well.
+func Convert(quantity float64, from, to string, packSize float64) (float64, error) {
+	fromUnit, fromOK := LookupUnit(from)
+	toUnit, toOK := LookupUnit(to)
+	if !fromOK || !toOK {
+		return 0, ErrUnknownUnit
+	}
+	if fromUnit == toUnit {
+		return quantity, nil
+	}
+
+	source, sourceOK := packConversion(fromUnit, packSize)
+	target, targetOK := packConversion(toUnit, packSize)
+	if !sourceOK || !targetOK || source.dimension != target.dimension {
+		return 0, ErrIncompatibleUnits
+	}
+	return quantity * source.factor / target.factor, nil
+}
+
+// packConversion looks up the conversion of a canonical unit, counting a pack as packSize pieces.
+func packConversion(unit string, packSize float64) (conversion, bool) {
+	if unit == "pack" && packSize > 0 {
+		return conversion{"count", packSize}, true
+	}
+	c, ok := conversions[unit]
+	return c, ok
+}
+
+// Normalize expresses an amount in the metric unit that reads best: 1500 g as 1.5 kg, 0.25 l as
+// 250 ml. Amounts in other units are returned unchanged.
+func Normalize(amount Amount) Amount {
+	unit := canonicalUnit(amount.Unit)
+	c, ok := conversions[unit]
+	if !ok || c.dimension == "count" {
+		return amount
+	}
+
+	base := amount.Quantity * c.factor
+	var best string
+	switch c.dimension {
+	case "mass":
+		best = "g"
+		if base >= 1000 {
+			best = "kg"
+		} else if base < 1 {
+			best = "mg"
+		}
+	case "volume":
+		best = "ml"
+		if base >= 1000 {
+			best = "l"
+		}
+	}
+	return Amount{Quantity: round(base / conversions[best].factor), Unit: best}
+}
+
+// Add sums amounts of the same dimension. Amounts in the same unit keep it, mixed units are
+// normalized, so 500 g and 0.5 kg add up to 1 kg. It reports false if the dimensions differ.
+func Add(amounts ...Amount) (Amount, bool) {
+	if len(amounts) == 0 {
+		return Amount{}, true
+	}
+
+	unit := canonicalUnit(amounts[0].Unit)
+	mixed := false
+	for _, amount := range amounts[1:] {
+		if canonicalUnit(amount.Unit) != unit {
+			mixed = true
+		}
+	}
+	if !mixed {
+		sum := Amount{Unit: amounts[0].Unit}
+		for _, amount := range amounts {
+			sum.Quantity += amount.Quantity
+		}
+		return sum, true
+	}
+
+	dimension := Dimension(unit)
+	c, ok := conversions[unit]
+	if !ok {
+		return Amount{}, false
+	}
+	base := 0.0
+	for _, amount := range amounts {
+		other, ok := conversions[canonicalUnit(amount.Unit)]
+		if !ok || other.dimension != dimension {
+			return Amount{}, false
+		}
+		base += amount.Quantity * other.factor
+	}
+	return Normalize(Amount{Quantity: base / c.factor, Unit: unit}), true
+}
+
+// FormatLocale formats an amount like Format, with at most three decimals and the decimal
+// separator of the preferred language of an Accept-Language header value, e.g. "1,5 kg" for de.
+func FormatLocale(amount Amount, acceptLanguage string) string {
+	if amount.Quantity == 0 {
+		return ""
+	}
+	formatted := strconv.FormatFloat(round(amount.Quantity), 'f', -1, 64)
+	if DecimalComma(acceptLanguage) {
+		formatted = strings.Replace(formatted, ".", ",", 1)
+	}
+	if amount.Unit != "" {
+		formatted += " " + amount.Unit
+	}
+	return formatted
+}
+
+// commaLanguages are the languages that write decimals with a comma.
+var commaLanguages = map[string]bool{
+	"cs": true, "da": true, "de": true, "es": true, "fi": true, "fr": true, "it": true,
+	"nb": true, "nl": true, "pl": true, "pt": true, "ru": true, "sv": true, "tr": true,
+}
+
+// PrimaryLanguage returns the base language of the first language of an Accept-Language header
+// value in lower case, e.g. "de" for "de-AT,de;q=0.9".
+func PrimaryLanguage(acceptLanguage string) string {
+	first, _, _ := strings.Cut(acceptLanguage, ",")
+	tag := strings.ToLower(strings.TrimSpace(strings.SplitN(first, ";", 2)[0]))
+	base, _, _ := strings.Cut(tag, "-")
+	return base
+}
+
+// DecimalComma reports whether the first language of an Accept-Language header value writes
+// decimals with a comma.
+func DecimalComma(acceptLanguage string) bool {
+	return commaLanguages[PrimaryLanguage(acceptLanguage)]
+}
+
+// canonicalUnit returns the canonical spelling of a unit, or the unit in lower case if it is unknown.
+func canonicalUnit(unit string) string {
+	if canonical, ok := LookupUnit(unit); ok {
+		return canonical
+	}
+	return strings.ToLower(strings.TrimSpace(unit))
+}
+
+// round rounds to three decimals, hiding floating point noise like 0.30000000000000004.
+func round(value float64) float64 {
+	return math.Round(value*1000) / 1000
+}
@@ -0,0 +1,182 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package quantity provides natural language parsing of item inputs like "3x milk" or
+// "500 g flour" into a structured name, quantity and unit.
+package quantity
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Parsed holds the structured result of parsing a free-text item input.
+type Parsed struct {
+	Name     string  `json:"name"`
+	Quantity float64 `json:"quantity"`
+	Unit     string  `json:"unit"`
+}
+
+// Parse splits a free-text item input into name, quantity and unit. Inputs without a
+// recognizable quantity are returned unchanged as the name with a zero quantity.
+func Parse(input string) Parsed {
+	words := strings.Fields(input)
+	if len(words) == 0 {
+		return Parsed{}
+	}
+
+	// Trailing multiplier: "milk x3" or "milk 3x"
+	if len(words) > 1 {
+		if amount, ok := parseMultiplier(words[len(words)-1]); ok {
+			return Parsed{Name: strings.Join(words[:len(words)-1], " "), Quantity: amount}
+		}
+	}
+
+	amount, unit, rest, ok := parseLeading(words)
+	if !ok || len(rest) == 0 {
+		return Parsed{Name: strings.Join(words, " ")}
+	}
+
+	// Drop filler words: "2 bottles of water", "3 x milk"
+	if len(rest) > 1 && (strings.EqualFold(rest[0], "of") || strings.EqualFold(rest[0], "x")) {
+		rest = rest[1:]
+	}
+
+	return Parsed{Name: strings.Join(rest, " "), Quantity: amount, Unit: unit}
+}
+
+// Format formats a quantity with its unit for display, e.g. "1.5 kg". A zero quantity gives an
+// empty string.
+func Format(quantity float64, unit string) string {
+	if quantity == 0 {
+		return ""
+	}
+	formatted := strconv.FormatFloat(quantity, 'f', -1, 64)
+	if unit != "" {
+		formatted += " " + unit
+	}
+	return formatted
+}
+
+// parseLeading extracts a leading amount and optional unit from the given words.
+func parseLeading(words []string) (float64, string, []string, bool) {
+	first := words[0]
+
+	// "3x milk"
+	if amount, ok := parseMultiplier(first); ok {
+		return amount, "", words[1:], true
+	}
+
+	// "500g flour"
+	if amount, unit, ok := splitAttachedUnit(first); ok {
+		return amount, unit, words[1:], true
+	}
+
+	amount, ok := parseNumber(first)
+	if !ok {
+		return 0, "", nil, false
+	}
+
+	// "500 g flour"
+	if len(words) > 2 {
+		if unit, known := LookupUnit(words[1]); known {
+			return amount, unit, words[2:], true
+		}
+	}
+
+	return amount, "", words[1:], true
+}
+
+// parseMultiplier parses tokens like "3x", "x3" or "3×".
+func parseMultiplier(token string) (float64, bool) {
+	lower := strings.ToLower(token)
+	for _, marker := range []string{"x", "×"} {
+		var number string
+		switch {
+		case strings.HasSuffix(lower, marker):
+			number = strings.TrimSuffix(lower, marker)
+		case strings.HasPrefix(lower, marker):
+			number = strings.TrimPrefix(lower, marker)
+		default:
+			continue
+		}
+		if amount, ok := parseNumber(number); ok {
+			return amount, true
+		}
+	}
+	return 0, false
+}
+
+// splitAttachedUnit parses tokens like "500g" or "1,5l" where the unit follows the number directly.
+func splitAttachedUnit(token string) (float64, string, bool) {
+	end := strings.IndexFunc(token, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != ',' && r != '/'
+	})
+	if end <= 0 {
+		return 0, "", false
+	}
+
+	amount, ok := parseNumber(token[:end])
+	if !ok {
+		return 0, "", false
+	}
+	unit, known := LookupUnit(token[end:])
+	if !known {
+		return 0, "", false
+	}
+	return amount, unit, true
+}
+
+// parseNumber parses positive integers, decimals with dot or comma, and simple fractions.
+// Spellings strconv accepts beyond that, such as "NaN" or "Inf", are no quantities.
+func parseNumber(s string) (float64, bool) {
+	if s == "" {
+		return 0, false
+	}
+
+	var value float64
+	if numerator, denominator, found := strings.Cut(s, "/"); found {
+		n, err1 := strconv.ParseFloat(numerator, 64)
+		d, err2 := strconv.ParseFloat(denominator, 64)
+		if err1 != nil || err2 != nil || d == 0 {
+			return 0, false
+		}
+		value = n / d
+	} else {
+		var err error
+		value, err = strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+		if err != nil {
+			return 0, false
+		}
+	}
+
+	if value <= 0 || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, false
+	}
+	return value, true
+}
+
+// LookupUnit returns the canonical unit for the given spelling.
+func LookupUnit(s string) (string, bool) {
+	unit, ok := units[strings.ToLower(strings.TrimSuffix(s, "."))]
+	return unit, ok
+}
+
+var units = map[string]string{
+	"mg": "mg", "g": "g", "gr": "g", "gram": "g", "grams": "g", "gramm": "g",
+	"kg": "kg", "kilo": "kg", "kilos": "kg", "kilogram": "kg", "kilograms": "kg", "kilogramm": "kg",
+	"ml": "ml", "cl": "cl", "dl": "dl", "l": "l", "liter": "l", "liters": "l", "litre": "l", "litres": "l",
+	"oz": "oz", "ounce": "oz", "ounces": "oz",
+	"lb": "lb", "lbs": "lb", "pound": "lb", "pounds": "lb",
+	"pc": "piece", "pcs": "piece", "piece": "piece", "pieces": "piece", "stück": "piece", "stk": "piece",
+	"bottle": "bottle", "bottles": "bottle", "flasche": "bottle", "flaschen": "bottle",
+	"can": "can", "cans": "can", "dose": "can", "dosen": "can",
+	"pack": "pack", "packs": "pack", "package": "pack", "packages": "pack", "packung": "pack", "packungen": "pack", "pkg": "pack",
+	"box": "box", "boxes": "box", "karton": "box",
+	"bag": "bag", "bags": "bag", "beutel": "bag", "tüte": "bag", "tüten": "bag",
+	"jar": "jar", "jars": "jar", "glas": "jar", "gläser": "jar",
+	"cup": "cup", "cups": "cup", "becher": "cup",
+	"bunch": "bunch", "bunches": "bunch", "bund": "bunch",
+	"dozen": "dozen",
+}
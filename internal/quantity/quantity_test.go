@@ -0,0 +1,165 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package quantity
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Parsed
+	}{
+		{"3x milk", Parsed{Name: "milk", Quantity: 3}},
+		{"3 x milk", Parsed{Name: "milk", Quantity: 3}},
+		{"milk x2", Parsed{Name: "milk", Quantity: 2}},
+		{"500 g flour", Parsed{Name: "flour", Quantity: 500, Unit: "g"}},
+		{"500g flour", Parsed{Name: "flour", Quantity: 500, Unit: "g"}},
+		{"1,5 l Milch", Parsed{Name: "Milch", Quantity: 1.5, Unit: "l"}},
+		{"1/2 kg potatoes", Parsed{Name: "potatoes", Quantity: 0.5, Unit: "kg"}},
+		{"2 bottles sparkling water", Parsed{Name: "sparkling water", Quantity: 2, Unit: "bottle"}},
+		{"2 bottles of water", Parsed{Name: "water", Quantity: 2, Unit: "bottle"}},
+		{"6 Flaschen Bier", Parsed{Name: "Bier", Quantity: 6, Unit: "bottle"}},
+		{"2 eggs", Parsed{Name: "eggs", Quantity: 2}},
+		{"milk", Parsed{Name: "milk"}},
+		{"7up", Parsed{Name: "7up"}},
+		{"Kleenex", Parsed{Name: "Kleenex"}},
+		{"0 apples", Parsed{Name: "0 apples"}},
+		{"", Parsed{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := Parse(tt.input); got != tt.expected {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	testCases := []struct {
+		quantity float64
+		unit     string
+		expected string
+	}{
+		{0, "kg", ""},
+		{2, "", "2"},
+		{1.5, "kg", "1.5 kg"},
+		{500, "g", "500 g"},
+	}
+	for _, tc := range testCases {
+		if got := Format(tc.quantity, tc.unit); got != tc.expected {
+			t.Errorf("Format(%v, %q) = %q; want %q", tc.quantity, tc.unit, got, tc.expected)
+		}
+	}
+}
+
+func TestLookupUnit(t *testing.T) {
+	if unit, ok := LookupUnit("Kilos"); !ok || unit != "kg" {
+		t.Errorf("Expected 'Kilos' to map to 'kg', got %q", unit)
+	}
+
+	if _, ok := LookupUnit("apples"); ok {
+		t.Error("'apples' should not be a unit")
+	}
+}
+
+func TestParse_NoQuantity(t *testing.T) {
+	for _, input := range []string{"NaN milk", "inf milk", "1/-2 kg flour", "-3 eggs", "+Inf x"} {
+		if parsed := Parse(input); parsed.Quantity != 0 {
+			t.Errorf("Parse(%q): expected no quantity, got %+v", input, parsed)
+		}
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for _, input := range []string{"3x milk", "500g flour", "1,5 l Milch", "1/2 kg potatoes", "milk x2", "2 bottles of water", "NaN milk", "1/0 g", " "} {
+		f.Add(input)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		parsed := Parse(input)
+
+		if parsed.Quantity < 0 || math.IsNaN(parsed.Quantity) || math.IsInf(parsed.Quantity, 0) {
+			t.Errorf("Parse(%q): invalid quantity %v", input, parsed.Quantity)
+		}
+		if parsed.Quantity == 0 && parsed.Unit != "" {
+			t.Errorf("Parse(%q): unit %q without quantity", input, parsed.Unit)
+		}
+		if strings.TrimSpace(input) != "" && parsed.Name == "" {
+			t.Errorf("Parse(%q): empty name", input)
+		}
+		if parsed.Name != strings.Join(strings.Fields(parsed.Name), " ") {
+			t.Errorf("Parse(%q): name %q is not normalized", input, parsed.Name)
+		}
+	})
+}
+
+func TestConvert(t *testing.T) {
+	testCases := []struct {
+		quantity float64
+		from, to string
+		packSize float64
+		expected float64
+		err      error
+	}{
+		{500, "g", "kg", 0, 0.5, nil},
+		{1.5, "Liter", "ml", 0, 1500, nil},
+		{2, "dozen", "pcs", 0, 24, nil},
+		{1, "lb", "g", 0, 453.59237, nil},
+		{12, "piece", "pack", 6, 2, nil},
+		{12, "piece", "pack", 0, 0, ErrIncompatibleUnits},
+		{1, "kg", "l", 0, 0, ErrIncompatibleUnits},
+		{1, "kg", "handful", 0, 0, ErrUnknownUnit},
+	}
+	for _, tc := range testCases {
+		got, err := Convert(tc.quantity, tc.from, tc.to, tc.packSize)
+		if err != tc.err || math.Abs(got-tc.expected) > 1e-9 {
+			t.Errorf("Convert(%v, %q, %q, %v) = %v, %v; want %v, %v", tc.quantity, tc.from, tc.to, tc.packSize, got, err, tc.expected, tc.err)
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	testCases := []struct {
+		amounts  []Amount
+		expected Amount
+		ok       bool
+	}{
+		{[]Amount{{500, "g"}, {0.5, "kg"}}, Amount{1, "kg"}, true},
+		{[]Amount{{0.2, "l"}, {50, "ml"}}, Amount{250, "ml"}, true},
+		{[]Amount{{600, "g"}, {600, "g"}}, Amount{1200, "g"}, true},
+		{[]Amount{{2, "bottle"}, {1, "Flasche"}}, Amount{3, "bottle"}, true},
+		{[]Amount{{0.1, "l"}, {0.2, "l"}, {1, "ml"}}, Amount{301, "ml"}, true},
+		{[]Amount{{1, "kg"}, {1, "l"}}, Amount{}, false},
+	}
+	for _, tc := range testCases {
+		got, ok := Add(tc.amounts...)
+		if ok != tc.ok || got != tc.expected {
+			t.Errorf("Add(%v) = %v, %v; want %v, %v", tc.amounts, got, ok, tc.expected, tc.ok)
+		}
+	}
+}
+
+func TestFormatLocale(t *testing.T) {
+	testCases := []struct {
+		amount         Amount
+		acceptLanguage string
+		expected       string
+	}{
+		{Amount{1.5, "kg"}, "en-US,de;q=0.8", "1.5 kg"},
+		{Amount{1.5, "kg"}, "de-AT,en;q=0.8", "1,5 kg"},
+		{Amount{1.0 / 3, "l"}, "fr", "0,333 l"},
+		{Amount{2, ""}, "", "2"},
+		{Amount{0, "g"}, "de", ""},
+	}
+	for _, tc := range testCases {
+		if got := FormatLocale(tc.amount, tc.acceptLanguage); got != tc.expected {
+			t.Errorf("FormatLocale(%v, %q) = %q; want %q", tc.amount, tc.acceptLanguage, got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package quickadd provides parsing of free-text voice assistant commands like
+// "add milk and eggs to groceries" into a target list name and item inputs.
+package quickadd
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Command is the structured result of parsing a quick-add sentence.
+type Command struct {
+	ListName string   `json:"list_name"`
+	Items    []string `json:"items"`
+}
+
+// Parse splits a sentence into the target list name and the individual item inputs.
+// The list name is empty if the sentence does not name a list.
+func Parse(sentence string) Command {
+	text := strings.Join(strings.Fields(sentence), " ")
+	text = strings.TrimRight(text, ".!?")
+
+	text = stripLeadingWords(text, leadingVerbs)
+	text = stripTrailingWords(text, trailingWords)
+
+	itemsText, listName := splitTarget(text)
+
+	return Command{
+		ListName: strings.TrimSpace(listName),
+		Items:    splitItems(itemsText),
+	}
+}
+
+// splitTarget splits "milk and eggs to groceries" at the last target preposition.
+func splitTarget(text string) (string, string) {
+	lower := foldCase(text)
+	best := -1
+	bestLen := 0
+	for _, preposition := range targetPrepositions {
+		marker := " " + preposition + " "
+		if idx := strings.LastIndex(lower, marker); idx > best {
+			best = idx
+			bestLen = len(marker)
+		}
+	}
+	if best < 0 {
+		return text, ""
+	}
+	return text[:best], text[best+bestLen:]
+}
+
+// splitItems splits "milk, eggs and 2 bottles of water" into individual item inputs.
+func splitItems(text string) []string {
+	parts := []string{text}
+	for _, separator := range itemSeparators {
+		var next []string
+		for _, part := range parts {
+			next = append(next, splitFold(part, separator)...)
+		}
+		parts = next
+	}
+
+	items := make([]string, 0, len(parts))
+	for _, part := range parts {
+		part = strings.TrimSpace(stripLeadingWords(strings.TrimSpace(part), articles))
+		if part != "" {
+			items = append(items, part)
+		}
+	}
+	return items
+}
+
+// ListNameCandidates returns the spoken list name followed by progressively cleaned
+// variants without possessives, articles and a trailing "list", most specific first.
+func ListNameCandidates(name string) []string {
+	name = strings.TrimSpace(name)
+	withoutArticle := stripLeadingWords(name, listArticles)
+	withoutSuffix := stripTrailingWords(withoutArticle, []string{"list", "liste"})
+
+	var candidates []string
+	for _, candidate := range []string{name, withoutArticle, withoutSuffix} {
+		if candidate == "" {
+			continue
+		}
+		if len(candidates) > 0 && strings.EqualFold(candidates[len(candidates)-1], candidate) {
+			continue
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates
+}
+
+// splitFold splits s at every case-insensitive occurrence of sep.
+func splitFold(s, sep string) []string {
+	lowerSep := foldCase(sep)
+	var parts []string
+	for {
+		idx := strings.Index(foldCase(s), lowerSep)
+		if idx < 0 {
+			return append(parts, s)
+		}
+		parts = append(parts, s[:idx])
+		s = s[idx+len(sep):]
+	}
+}
+
+// stripLeadingWords removes any of the given leading phrases, repeatedly.
+func stripLeadingWords(text string, words []string) string {
+	for {
+		lower := foldCase(text)
+		stripped := false
+		for _, word := range words {
+			if strings.HasPrefix(lower, word+" ") {
+				text = strings.TrimSpace(text[len(word)+1:])
+				stripped = true
+				break
+			}
+		}
+		if !stripped {
+			return text
+		}
+	}
+}
+
+// stripTrailingWords removes any of the given trailing phrases once.
+func stripTrailingWords(text string, words []string) string {
+	lower := foldCase(text)
+	for _, word := range words {
+		if strings.HasSuffix(lower, " "+word) {
+			return strings.TrimSpace(text[:len(text)-len(word)-1])
+		}
+		if lower == word {
+			return ""
+		}
+	}
+	return text
+}
+
+// foldCase lowercases s for matching while keeping its byte offsets: runes whose lowercase form
+// has a different encoded length, like "İ", and invalid UTF-8 are left as they are, so indexes
+// found in the result can be used to slice s.
+func foldCase(s string) string {
+	b := []byte(s)
+	for i := 0; i < len(b); {
+		r, size := utf8.DecodeRune(b[i:])
+		if l := unicode.ToLower(r); l != r && utf8.RuneLen(l) == size {
+			utf8.EncodeRune(b[i:], l)
+		}
+		i += size
+	}
+	return string(b)
+}
+
+var leadingVerbs = []string{
+	"please", "hey", "ok", "okay",
+	"add", "put", "buy", "get", "we need", "i need",
+	"bitte", "füge", "setze", "schreibe", "pack", "kaufe", "wir brauchen", "ich brauche",
+}
+
+var trailingWords = []string{"please", "hinzu", "bitte", "drauf"}
+
+var targetPrepositions = []string{"to", "on", "onto", "in", "into", "zu", "zur", "zum", "auf"}
+
+var itemSeparators = []string{",", " and ", " und ", " & ", " plus "}
+
+var articles = []string{"some", "a", "an", "the", "etwas", "ein", "eine", "einen"}
+
+var listArticles = []string{"my", "our", "the", "meine", "unsere", "die", "der", "den"}
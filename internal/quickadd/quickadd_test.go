@@ -0,0 +1,108 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package quickadd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Command
+	}{
+		{
+			"add milk and eggs to groceries",
+			Command{ListName: "groceries", Items: []string{"milk", "eggs"}},
+		},
+		{
+			"Please add 2 bottles of water, bread and some cheese to my shopping list.",
+			Command{ListName: "my shopping list", Items: []string{"2 bottles of water", "bread", "cheese"}},
+		},
+		{
+			"put apples on the Party list",
+			Command{ListName: "the Party list", Items: []string{"apples"}},
+		},
+		{
+			"Füge Milch und Eier zur Einkaufsliste hinzu",
+			Command{ListName: "Einkaufsliste", Items: []string{"Milch", "Eier"}},
+		},
+		{
+			"milk",
+			Command{Items: []string{"milk"}},
+		},
+		{
+			"add",
+			Command{Items: []string{"add"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got := Parse(tt.input)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestListNameCandidates(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"my shopping list", []string{"my shopping list", "shopping list", "shopping"}},
+		{"the Party list", []string{"the Party list", "Party list", "Party"}},
+		{"groceries", []string{"groceries"}},
+		{"Einkaufsliste", []string{"Einkaufsliste"}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got := ListNameCandidates(tt.input)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ListNameCandidates(%q) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParse_CaseFoldingKeepsOffsets(t *testing.T) {
+	// "İ" lowercases to a longer byte sequence, which must not shift the split positions
+	command := Parse("add İce and milk to İstanbul")
+	expected := Command{ListName: "İstanbul", Items: []string{"İce", "milk"}}
+	if !reflect.DeepEqual(command, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, command)
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for _, sentence := range []string{
+		"add milk and eggs to groceries", "Füge Milch und Eier zur Einkaufsliste hinzu",
+		"milk, eggs & 2 bottles of water", "İİİ and İ to İ", "to", "\xff and \xfe",
+	} {
+		f.Add(sentence)
+	}
+	f.Fuzz(func(t *testing.T, sentence string) {
+		command := Parse(sentence)
+
+		for _, item := range command.Items {
+			if item == "" || item != strings.TrimSpace(item) {
+				t.Errorf("Parse(%q): item %q is empty or not trimmed", sentence, item)
+			}
+		}
+		if command.ListName != strings.TrimSpace(command.ListName) {
+			t.Errorf("Parse(%q): list name %q is not trimmed", sentence, command.ListName)
+		}
+		for _, candidate := range ListNameCandidates(command.ListName) {
+			if candidate == "" {
+				t.Errorf("Parse(%q): empty list name candidate", sentence)
+			}
+		}
+	})
+}
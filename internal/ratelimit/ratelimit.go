@@ -0,0 +1,56 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package ratelimit counts requests in fixed time windows, for the rate limits of API keys and
+// the per-user quotas of expensive endpoints.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter counts requests per key in fixed time windows. Unlike Fiber's limiter middleware it
+// allows a different limit per key, e.g. per API key.
+type Limiter struct {
+	mu      sync.Mutex
+	period  time.Duration
+	windows map[string]*window
+}
+
+type window struct {
+	start time.Time
+	count int
+}
+
+// New creates a limiter with windows of the given length.
+func New(period time.Duration) *Limiter {
+	return &Limiter{period: period, windows: make(map[string]*window)}
+}
+
+// Allow counts a request for the key and reports whether it is within the limit. If not, it
+// returns how long until the current window ends.
+func (l *Limiter) Allow(key string, limit int, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.period {
+		// Drop ended windows now and then so revoked keys do not pile up
+		if len(l.windows) > 1000 {
+			for k, other := range l.windows {
+				if now.Sub(other.start) >= l.period {
+					delete(l.windows, k)
+				}
+			}
+		}
+		w = &window{start: now}
+		l.windows[key] = w
+	}
+
+	if w.count >= limit {
+		return w.start.Add(l.period).Sub(now), false
+	}
+	w.count++
+	return 0, true
+}
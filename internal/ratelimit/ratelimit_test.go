@@ -0,0 +1,33 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	limiter := New(time.Minute)
+	now := time.Now()
+
+	for i := range 3 {
+		if _, ok := limiter.Allow("key", 3, now); !ok {
+			t.Fatalf("Expected request %d to be allowed", i+1)
+		}
+	}
+	retryAfter, ok := limiter.Allow("key", 3, now.Add(20*time.Second))
+	if ok {
+		t.Fatal("Expected request above the limit to be rejected")
+	}
+	if retryAfter != 40*time.Second {
+		t.Errorf("Expected retry after 40s, got %v", retryAfter)
+	}
+	if _, ok := limiter.Allow("other", 3, now); !ok {
+		t.Error("Expected other keys to have their own limit")
+	}
+	if _, ok := limiter.Allow("key", 3, now.Add(time.Minute)); !ok {
+		t.Error("Expected a new window to allow requests again")
+	}
+}
@@ -0,0 +1,202 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package realtime pushes changes of shared lists over WebSocket connections, so the members of a
+// list see each other's changes without polling. A connection receives the events of every list
+// its user is a member of at the time of the event. Clients that cannot use WebSockets can follow
+// a single list as Server-Sent Events instead, which are published to the same connections.
+package realtime
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// Events sent to clients.
+const (
+	EventItemCreated  = "item.created"
+	EventItemToggled  = "item.toggled"
+	EventItemDeleted  = "item.deleted"
+	EventItemRestored = "item.restored"
+	EventListRenamed  = "list.renamed"
+	EventMemberAdded  = "member.added"
+)
+
+// PingInterval is how often idle connections are pinged. Connections that send nothing, not even
+// the answer to a ping, for two intervals are closed.
+const PingInterval = 30 * time.Second
+
+// writeTimeout bounds how long writing a message to a client may take.
+const writeTimeout = 10 * time.Second
+
+// sendBuffer is the number of events queued for a client. Clients that fall further behind are
+// disconnected and have to reload their lists when reconnecting.
+const sendBuffer = 64
+
+// Event is the JSON message clients receive.
+type Event struct {
+	Event      string    `json:"event"`
+	ListID     string    `json:"list_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       any       `json:"data"`
+}
+
+// Service keeps track of the open connections and delivers events to them.
+type Service struct {
+	DB *gorm.DB
+
+	mu      sync.Mutex
+	clients map[string]map[*client]struct{}
+}
+
+// client is an open connection of a user. Event streams follow a single list, WebSockets all
+// lists of the user.
+type client struct {
+	userID string
+	listID string
+	send   chan []byte
+}
+
+// NewService creates a realtime service without connections.
+func NewService(db *gorm.DB) *Service {
+	return &Service{DB: db, clients: map[string]map[*client]struct{}{}}
+}
+
+// Connections returns the number of open connections.
+func (s *Service) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, clients := range s.clients {
+		count += len(clients)
+	}
+	return count
+}
+
+// Publish sends an event about a list to the connections of its members. It only queues the
+// event, so changes never wait for slow clients, and drops it if the members cannot be looked up.
+func (s *Service) Publish(event, listID string, data any) {
+	if s.Connections() == 0 {
+		return
+	}
+
+	var members []string
+	if err := s.DB.Model(&models.ListMember{}).Where("list_id = ?", listID).Pluck("user_id", &members).Error; err != nil {
+		return
+	}
+	message, err := json.Marshal(Event{Event: event, ListID: listID, OccurredAt: time.Now().UTC(), Data: data})
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, userID := range members {
+		for c := range s.clients[userID] {
+			if c.listID != "" && c.listID != listID {
+				continue
+			}
+			select {
+			case c.send <- message:
+			default:
+				s.removeLocked(c)
+			}
+		}
+	}
+}
+
+// Serve delivers events to a connection of a user that completed the WebSocket handshake, until
+// the client closes it or stops responding. It closes the connection when done.
+func (s *Service) Serve(conn net.Conn, userID string) {
+	c := &client{userID: userID, send: make(chan []byte, sendBuffer)}
+	s.add(c)
+	defer s.remove(c)
+	defer func() { _ = conn.Close() }()
+
+	var writeMu sync.Mutex
+	write := func(opcode byte, payload []byte) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		return writeFrame(conn, opcode, payload)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		reader := bufio.NewReader(conn)
+		for {
+			_ = conn.SetReadDeadline(time.Now().Add(2 * PingInterval))
+			opcode, payload, err := readFrame(reader)
+			if err != nil {
+				return
+			}
+			switch opcode {
+			case opPing:
+				if write(opPong, payload) != nil {
+					return
+				}
+			case opClose:
+				// Echo the status code of the client
+				_ = write(opClose, payload[:min(len(payload), 2)])
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(PingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case message, ok := <-c.send:
+			if !ok {
+				_ = write(opClose, closePayload(closeTryAgainLater, "too slow"))
+				return
+			}
+			if write(opText, message) != nil {
+				return
+			}
+		case <-ping.C:
+			if write(opPing, nil) != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+func (s *Service) add(c *client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.clients[c.userID] == nil {
+		s.clients[c.userID] = map[*client]struct{}{}
+	}
+	s.clients[c.userID][c] = struct{}{}
+}
+
+func (s *Service) remove(c *client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeLocked(c)
+}
+
+// removeLocked forgets a client and closes its queue, which ends Serve. The caller holds s.mu.
+func (s *Service) removeLocked(c *client) {
+	if _, ok := s.clients[c.userID][c]; !ok {
+		return
+	}
+	delete(s.clients[c.userID], c)
+	if len(s.clients[c.userID]) == 0 {
+		delete(s.clients, c.userID)
+	}
+	close(c.send)
+}
@@ -0,0 +1,195 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package realtime
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+)
+
+func TestAcceptKey(t *testing.T) {
+	// Example of RFC 6455, section 1.3
+	if got := AcceptKey("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Unexpected accept key %q", got)
+	}
+}
+
+func TestIsUpgrade(t *testing.T) {
+	if !IsUpgrade("keep-alive, Upgrade", "WebSocket") {
+		t.Error("Expected upgrade among other connection tokens to be accepted")
+	}
+	if IsUpgrade("keep-alive", "websocket") || IsUpgrade("Upgrade", "h2c") {
+		t.Error("Expected requests without WebSocket upgrade to be rejected")
+	}
+}
+
+func TestFrames(t *testing.T) {
+	for _, size := range []int{0, 125, 126, 70000} {
+		var buf bytes.Buffer
+		payload := bytes.Repeat([]byte("a"), size)
+		if err := writeFrame(&buf, opText, payload); err != nil {
+			t.Fatalf("Failed to write frame: %v", err)
+		}
+		opcode, read := readServerFrame(t, bufio.NewReader(&buf))
+		if opcode != opText || !bytes.Equal(read, payload) {
+			t.Errorf("Frame of %d bytes did not round-trip", size)
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.Write(clientFrame(opPing, []byte("hello")))
+	opcode, payload, err := readFrame(bufio.NewReader(&buf))
+	if err != nil || opcode != opPing || string(payload) != "hello" {
+		t.Errorf("Expected unmasked ping, got %x %q (%v)", opcode, payload, err)
+	}
+
+	buf.Reset()
+	if err := writeFrame(&buf, opPing, nil); err != nil {
+		t.Fatalf("Failed to write frame: %v", err)
+	}
+	if _, _, err := readFrame(bufio.NewReader(&buf)); err != errUnmasked {
+		t.Errorf("Expected unmasked client frames to be rejected, got %v", err)
+	}
+}
+
+func TestService_Publish(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+
+	db.Create(&models.ListMember{ListID: "shared", UserID: "alice", JoinedAt: time.Now()})
+	db.Create(&models.ListMember{ListID: "shared", UserID: "bob", JoinedAt: time.Now()})
+	db.Create(&models.ListMember{ListID: "private", UserID: "bob", JoinedAt: time.Now()})
+
+	server, conn := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		service.Serve(server, "alice")
+		close(done)
+	}()
+	for service.Connections() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	reader := bufio.NewReader(conn)
+
+	service.Publish(EventItemDeleted, "private", map[string]string{"item_id": "secret"})
+	service.Publish(EventItemDeleted, "shared", map[string]string{"item_id": "item-1"})
+
+	opcode, payload := readServerFrame(t, reader)
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil || opcode != opText {
+		t.Fatalf("Expected a JSON text frame, got %x %q (%v)", opcode, payload, err)
+	}
+	if event.Event != EventItemDeleted || event.ListID != "shared" {
+		t.Errorf("Expected only the event of the shared list, got %+v", event)
+	}
+
+	if _, err := conn.Write(clientFrame(opPing, []byte("ping"))); err != nil {
+		t.Fatalf("Failed to ping: %v", err)
+	}
+	if opcode, payload := readServerFrame(t, reader); opcode != opPong || string(payload) != "ping" {
+		t.Errorf("Expected pong, got %x %q", opcode, payload)
+	}
+
+	if _, err := conn.Write(clientFrame(opClose, closePayload(1000, "bye"))); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	if opcode, payload := readServerFrame(t, reader); opcode != opClose || binary.BigEndian.Uint16(payload) != 1000 {
+		t.Errorf("Expected the close frame to be echoed, got %x %q", opcode, payload)
+	}
+	<-done
+	if service.Connections() != 0 {
+		t.Errorf("Expected the connection to be gone, got %d", service.Connections())
+	}
+}
+
+func TestService_ServeEvents(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+
+	db.Create(&models.ListMember{ListID: "groceries", UserID: "alice", JoinedAt: time.Now()})
+	db.Create(&models.ListMember{ListID: "hardware", UserID: "alice", JoinedAt: time.Now()})
+
+	stream, w := io.Pipe()
+	shutdown := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		service.ServeEvents(bufio.NewWriter(w), "alice", "groceries", shutdown)
+		close(done)
+	}()
+	reader := bufio.NewReader(stream)
+	if line, _ := reader.ReadString('\n'); line != "retry: 5000\n" {
+		t.Errorf("Expected the retry interval, got %q", line)
+	}
+	_, _ = reader.ReadString('\n')
+
+	// Only events of the streamed list are sent
+	service.Publish(EventItemDeleted, "hardware", map[string]string{"item_id": "screws"})
+	service.Publish(EventItemDeleted, "groceries", map[string]string{"item_id": "milk"})
+	line, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "data: ") {
+		t.Fatalf("Expected a data line, got %q, %v", line, err)
+	}
+	var event Event
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+		t.Fatalf("Failed to parse event %q: %v", line, err)
+	}
+	if event.ListID != "groceries" || event.Data.(map[string]any)["item_id"] != "milk" {
+		t.Errorf("Expected the event of the streamed list, got %+v", event)
+	}
+	if blank, _ := reader.ReadString('\n'); blank != "\n" {
+		t.Errorf("Expected a blank line after the event, got %q", blank)
+	}
+
+	close(shutdown)
+	<-done
+	if service.Connections() != 0 {
+		t.Errorf("Expected the stream to be gone, got %d", service.Connections())
+	}
+}
+
+// clientFrame returns a masked frame as clients send it.
+func clientFrame(opcode byte, payload []byte) []byte {
+	mask := []byte{1, 2, 3, 4}
+	frame := append([]byte{0x80 | opcode, 0x80 | byte(len(payload))}, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	return frame
+}
+
+// readServerFrame reads an unmasked frame as the server sends it.
+func readServerFrame(t *testing.T, r *bufio.Reader) (byte, []byte) {
+	t.Helper()
+
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		t.Fatalf("Failed to read frame: %v", err)
+	}
+	length := uint64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var extended [2]byte
+		_, _ = io.ReadFull(r, extended[:])
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		_, _ = io.ReadFull(r, extended[:])
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		t.Fatalf("Failed to read payload: %v", err)
+	}
+	return head[0] & 0x0f, payload
+}
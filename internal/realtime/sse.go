@@ -0,0 +1,55 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package realtime
+
+import (
+	"bufio"
+	"fmt"
+	"time"
+)
+
+// retryMillis is how long EventSource clients wait before reconnecting a dropped stream.
+const retryMillis = 5000
+
+// ServeEvents streams the events of a list to a user as Server-Sent Events, one JSON Event per
+// message, until the client disconnects, falls too far behind or done is closed, e.g. when the
+// server shuts down. Comments are sent as heartbeats every PingInterval, so proxies keep idle
+// streams open and gone clients are noticed.
+func (s *Service) ServeEvents(w *bufio.Writer, userID, listID string, done <-chan struct{}) {
+	c := &client{userID: userID, listID: listID, send: make(chan []byte, sendBuffer)}
+	s.add(c)
+	defer s.remove(c)
+
+	write := func(format string, args ...any) error {
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return err
+		}
+		return w.Flush()
+	}
+
+	if write("retry: %d\n\n", retryMillis) != nil {
+		return
+	}
+
+	ping := time.NewTicker(PingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case message, ok := <-c.send:
+			if !ok {
+				return
+			}
+			// Marshalled JSON has no newlines, so it fits on a single data line
+			if write("data: %s\n\n", message) != nil {
+				return
+			}
+		case <-ping.C:
+			if write(": ping\n\n") != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package realtime
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+)
+
+// The parts of the WebSocket protocol (RFC 6455) the server needs: the handshake and unfragmented
+// frames. Clients only send control frames; anything else they send is read and ignored.
+
+// acceptGUID is appended to the key of the client to compute the accept key of the handshake.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Opcodes of frames.
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xa
+)
+
+// closeTryAgainLater is the close status for clients that fell behind.
+const closeTryAgainLater = 1013
+
+// maxPayload limits the frames clients may send. They have no reason to send large ones.
+const maxPayload = 64 << 10
+
+// errFrameTooLarge is returned for frames above maxPayload.
+var errFrameTooLarge = errors.New("frame too large")
+
+// errUnmasked is returned for client frames without mask, which RFC 6455 forbids.
+var errUnmasked = errors.New("client frame is not masked")
+
+// IsUpgrade reports whether the Connection and Upgrade headers of a request ask for a WebSocket.
+func IsUpgrade(connection, upgrade string) bool {
+	if !strings.EqualFold(strings.TrimSpace(upgrade), "websocket") {
+		return false
+	}
+	for _, token := range strings.Split(connection, ",") {
+		if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+			return true
+		}
+	}
+	return false
+}
+
+// AcceptKey returns the Sec-WebSocket-Accept header answering the Sec-WebSocket-Key of a client.
+func AcceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// writeFrame writes an unmasked, unfragmented frame.
+func writeFrame(w io.Writer, opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xffff:
+		header = binary.BigEndian.AppendUint16(append(header, 126), uint16(n))
+	default:
+		header = binary.BigEndian.AppendUint64(append(header, 127), uint64(n))
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// readFrame reads a masked frame of a client and returns its opcode and unmasked payload.
+func readFrame(r *bufio.Reader) (byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode := head[0] & 0x0f
+	if head[1]&0x80 == 0 {
+		return 0, nil, errUnmasked
+	}
+
+	length := uint64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(r, extended[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(r, extended[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	if length > maxPayload {
+		return 0, nil, errFrameTooLarge
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(r, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return opcode, payload, nil
+}
+
+// closePayload returns the payload of a close frame with a status code and reason.
+func closePayload(code uint16, reason string) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, code), reason...)
+}
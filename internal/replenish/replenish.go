@@ -0,0 +1,343 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package replenish predicts which regularly bought items a list is about to need again, from
+// the intervals between their past purchases, and learns from the feedback on its suggestions.
+package replenish
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oliverandrich/shopping-list-server/internal/mail"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gopkg.in/gomail.v2"
+	"gorm.io/gorm"
+)
+
+// Statuses of suggestions.
+const (
+	StatusOpen      = "open"
+	StatusWaiting   = "waiting"
+	StatusAccepted  = "accepted"
+	StatusDismissed = "dismissed"
+)
+
+const (
+	// AnalysisInterval is the time between two analyses of the purchases.
+	AnalysisInterval = 24 * time.Hour
+	// ReminderInterval is the time between two suggestion emails to a user.
+	ReminderInterval = 7 * 24 * time.Hour
+	// MinPurchases is the number of purchases of an item before it is suggested, so a single
+	// interval does not count as habit.
+	MinPurchases = 3
+	// Horizon is how far ahead of its due date an item is suggested.
+	Horizon = 3 * 24 * time.Hour
+
+	// Dismissing a suggestion stretches the interval of the item by dismissStretch; the factor
+	// stays between minFactor and maxFactor.
+	dismissStretch = 1.25
+	minFactor      = 0.5
+	maxFactor      = 4
+)
+
+// ErrNotFound is returned for suggestions that do not exist or are not open.
+var ErrNotFound = errors.New("suggestion not found")
+
+// Service analyzes purchases and manages the suggestions.
+type Service struct {
+	DB     *gorm.DB
+	Mailer *gomail.Dialer
+}
+
+// NewService creates a new replenishment service.
+func NewService(db *gorm.DB, mailer *gomail.Dialer) *Service {
+	return &Service{DB: db, Mailer: mailer}
+}
+
+// purchaseHistory sums up the purchases of items of a name on a list.
+type purchaseHistory struct {
+	name        string
+	first, last time.Time
+	count       int
+}
+
+// Analyze updates the suggestions of all lists that are not archived and returns the number of
+// open ones.
+func (s *Service) Analyze() (int, error) {
+	var listIDs []string
+	if err := s.DB.Model(&models.ShoppingList{}).Where("archived_at IS NULL").Pluck("id", &listIDs).Error; err != nil {
+		return 0, err
+	}
+
+	open := 0
+	now := time.Now()
+	for _, listID := range listIDs {
+		count, err := s.AnalyzeList(listID, now)
+		if err != nil {
+			return open, err
+		}
+		open += count
+	}
+	return open, nil
+}
+
+// AnalyzeList updates the suggestions of a list as of now and returns the number of open ones.
+// Items with MinPurchases or more are due their average interval, tuned by feedback, after their
+// last purchase and suggested from Horizon before, unless they are on the list already. A new
+// purchase ends the feedback on the previous suggestion.
+func (s *Service) AnalyzeList(listID string, now time.Time) (int, error) {
+	// Purchases are the completed items kept in the item history
+	var purchases []models.ShoppingItem
+	err := s.DB.Select("name", "completed_at").
+		Where("list_id = ? AND completed = ? AND completed_at IS NOT NULL", listID, true).
+		Order("completed_at").Find(&purchases).Error
+	if err != nil {
+		return 0, err
+	}
+	histories := make(map[string]*purchaseHistory)
+	for _, purchase := range purchases {
+		key := strings.ToLower(purchase.Name)
+		if histories[key] == nil {
+			histories[key] = &purchaseHistory{first: *purchase.CompletedAt}
+		}
+		histories[key].name = purchase.Name
+		histories[key].last = *purchase.CompletedAt
+		histories[key].count++
+	}
+
+	onList, err := s.openItemKeys(listID)
+	if err != nil {
+		return 0, err
+	}
+
+	var existing []models.ReplenishSuggestion
+	if err := s.DB.Where("list_id = ?", listID).Find(&existing).Error; err != nil {
+		return 0, err
+	}
+	byKey := make(map[string]models.ReplenishSuggestion, len(existing))
+	for _, suggestion := range existing {
+		byKey[suggestion.Key] = suggestion
+	}
+
+	open := 0
+	for key, history := range histories {
+		if history.count < MinPurchases {
+			continue
+		}
+		// Items bought several times a day are no habit to predict
+		interval := history.last.Sub(history.first).Hours() / 24 / float64(history.count-1)
+		if interval < 1 {
+			continue
+		}
+
+		suggestion, found := byKey[key]
+		delete(byKey, key)
+		if !found {
+			suggestion = models.ReplenishSuggestion{ID: uuid.New().String(), ListID: listID, Key: key, Factor: 1}
+		}
+		if !suggestion.LastPurchasedAt.Equal(history.last) {
+			suggestion.Status = StatusWaiting
+		}
+		suggestion.Name = history.name
+		suggestion.Purchases = history.count
+		suggestion.LastPurchasedAt = history.last
+		suggestion.IntervalDays = math.Round(interval*10) / 10
+		suggestion.DueAt = dueAt(history.last, interval, suggestion.Factor)
+
+		if suggestion.Status != StatusAccepted && suggestion.Status != StatusDismissed {
+			suggestion.Status = StatusWaiting
+			if !onList[key] && !suggestion.DueAt.After(now.Add(Horizon)) {
+				suggestion.Status = StatusOpen
+				open++
+			}
+		}
+		if err := s.DB.Save(&suggestion).Error; err != nil {
+			return open, err
+		}
+	}
+
+	// Items whose purchases were purged are no longer predicted, and their tuning is forgotten
+	for _, suggestion := range byKey {
+		if err := s.DB.Delete(&suggestion).Error; err != nil {
+			return open, err
+		}
+	}
+	return open, nil
+}
+
+// dueAt returns when an item bought at last is expected to be needed again.
+func dueAt(last time.Time, intervalDays, factor float64) time.Time {
+	return last.Add(time.Duration(intervalDays * factor * 24 * float64(time.Hour)))
+}
+
+// openItemKeys returns the lowercased names of the open items of a list.
+func (s *Service) openItemKeys(listID string) (map[string]bool, error) {
+	var names []string
+	err := s.DB.Model(&models.ShoppingItem{}).Where("list_id = ? AND completed = ?", listID, false).
+		Pluck("name", &names).Error
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]bool, len(names))
+	for _, name := range names {
+		keys[strings.ToLower(name)] = true
+	}
+	return keys, nil
+}
+
+// Suggestions returns the open suggestions of the lists the user is a member of, or of one of
+// them if listID is set, the most overdue first. Items added to their list since the last
+// analysis are left out.
+func (s *Service) Suggestions(userID, listID string) ([]models.ReplenishSuggestion, error) {
+	query := s.DB.Where("status = ?", StatusOpen).
+		Where("list_id IN (?)", s.DB.Model(&models.ListMember{}).Select("list_id").Where("user_id = ?", userID))
+	if listID != "" {
+		query = query.Where("list_id = ?", listID)
+	}
+	var found []models.ReplenishSuggestion
+	if err := query.Order("due_at").Order("id").Find(&found).Error; err != nil {
+		return nil, err
+	}
+
+	suggestions := []models.ReplenishSuggestion{}
+	lists := make(map[string]models.ShoppingList)
+	onList := make(map[string]map[string]bool)
+	for _, suggestion := range found {
+		if _, ok := lists[suggestion.ListID]; !ok {
+			var list models.ShoppingList
+			if err := s.DB.Select("id", "name", "archived_at").First(&list, "id = ?", suggestion.ListID).Error; err != nil {
+				return nil, err
+			}
+			keys, err := s.openItemKeys(suggestion.ListID)
+			if err != nil {
+				return nil, err
+			}
+			lists[suggestion.ListID] = list
+			onList[suggestion.ListID] = keys
+		}
+		list := lists[suggestion.ListID]
+		if list.ArchivedAt != nil || onList[suggestion.ListID][suggestion.Key] {
+			continue
+		}
+		suggestion.ListName = list.Name
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions, nil
+}
+
+// Get returns an open suggestion, or ErrNotFound.
+func (s *Service) Get(id string) (*models.ReplenishSuggestion, error) {
+	var suggestion models.ReplenishSuggestion
+	if err := s.DB.First(&suggestion, "id = ? AND status = ?", id, StatusOpen).Error; err != nil {
+		return nil, ErrNotFound
+	}
+	return &suggestion, nil
+}
+
+// Accept records that the suggested item was needed at now. The interval of the item is tuned
+// halfway towards when it was actually needed, so items accepted early come up earlier next time.
+func (s *Service) Accept(suggestion *models.ReplenishSuggestion, now time.Time) error {
+	needed := now.Sub(suggestion.LastPurchasedAt).Hours() / 24 / suggestion.IntervalDays
+	suggestion.Factor = clampFactor((suggestion.Factor + needed) / 2)
+	suggestion.Status = StatusAccepted
+	return s.save(suggestion)
+}
+
+// Dismiss records that the suggested item is not needed yet. It is not suggested again before
+// it is bought, and its interval is stretched.
+func (s *Service) Dismiss(suggestion *models.ReplenishSuggestion) error {
+	suggestion.Factor = clampFactor(suggestion.Factor * dismissStretch)
+	suggestion.Status = StatusDismissed
+	return s.save(suggestion)
+}
+
+// save stores the feedback on a suggestion with its new due date.
+func (s *Service) save(suggestion *models.ReplenishSuggestion) error {
+	suggestion.DueAt = dueAt(suggestion.LastPurchasedAt, suggestion.IntervalDays, suggestion.Factor)
+	return s.DB.Model(suggestion).Select("factor", "status", "due_at").Updates(suggestion).Error
+}
+
+// clampFactor keeps a tuned factor between minFactor and maxFactor.
+func clampFactor(factor float64) float64 {
+	return math.Min(math.Max(factor, minFactor), maxFactor)
+}
+
+// Settings returns the replenishment preferences of a user.
+func (s *Service) Settings(userID string) (*models.ReplenishSettings, error) {
+	var user models.User
+	if err := s.DB.Select("id", "replenish_reminders").First(&user, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+	return &models.ReplenishSettings{WeeklyEmail: user.ReplenishReminders}, nil
+}
+
+// UpdateSettings changes the replenishment preferences of a user.
+func (s *Service) UpdateSettings(userID string, settings models.ReplenishSettings) error {
+	return s.DB.Model(&models.User{}).Where("id = ?", userID).
+		Update("replenish_reminders", settings.WeeklyEmail).Error
+}
+
+// SendDueReminders emails the users who asked for it the open suggestions of their lists, at most
+// once per ReminderInterval. Users without suggestions are emailed once there are some. It
+// returns the number of users emailed.
+func (s *Service) SendDueReminders() (int, error) {
+	var users []models.User
+	err := s.DB.Where("replenish_reminders = ? AND (replenish_reminded_at IS NULL OR replenish_reminded_at <= ?)",
+		true, time.Now().Add(-ReminderInterval)).Find(&users).Error
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, user := range users {
+		suggestions, err := s.Suggestions(user.ID, "")
+		if err != nil {
+			return sent, err
+		}
+		if len(suggestions) == 0 {
+			continue
+		}
+		if err := s.sendReminder(user.Email, suggestions); err != nil {
+			slog.Warn("Failed to send replenishment reminder", "user", user.ID, "error", err)
+			continue
+		}
+
+		now := time.Now()
+		if err := s.DB.Model(&user).Update("replenish_reminded_at", &now).Error; err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// sendReminder emails a user the items their lists likely need soon.
+func (s *Service) sendReminder(email string, suggestions []models.ReplenishSuggestion) error {
+	// Skip email sending in test environment
+	if os.Getenv("GO_ENV") == "test" {
+		return nil
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", os.Getenv("SMTP_FROM"))
+	m.SetHeader("To", email)
+	m.SetHeader("Subject", fmt.Sprintf("%d items you may need soon", len(suggestions)))
+
+	body := "Going by how often you bought them, these items are running low:\n\n"
+	for _, suggestion := range suggestions {
+		body += fmt.Sprintf("- %s on %s, usually bought every %.0f days\n",
+			suggestion.Name, suggestion.ListName, suggestion.IntervalDays)
+	}
+	body += "\nAdd them to your lists, or dismiss the suggestions if you do not need them yet.\n"
+
+	m.SetBody("text/plain", body)
+
+	return mail.Send(s.Mailer, m)
+}
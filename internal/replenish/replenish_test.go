@@ -0,0 +1,180 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package replenish
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+	"gorm.io/gorm"
+)
+
+// buy records purchases of an item on a list the given numbers of days ago.
+func buy(t *testing.T, db *gorm.DB, listID, name string, now time.Time, daysAgo ...int) {
+	t.Helper()
+
+	for _, days := range daysAgo {
+		completedAt := now.AddDate(0, 0, -days)
+		item := models.ShoppingItem{
+			ID:          listID + "-" + name + "-" + completedAt.Format(time.RFC3339Nano),
+			ListID:      listID,
+			Name:        name,
+			Tags:        "[]",
+			Completed:   true,
+			CompletedAt: &completedAt,
+		}
+		if err := db.Create(&item).Error; err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+}
+
+func setupList(t *testing.T, db *gorm.DB) {
+	t.Helper()
+
+	user := models.User{ID: "user-1", Email: "user@example.com", ReplenishReminders: true}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	list := models.ShoppingList{ID: "home", Name: "Home", OwnerID: user.ID}
+	if err := db.Create(&list).Error; err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	member := models.ListMember{ListID: list.ID, UserID: user.ID, Role: "owner", JoinedAt: time.Now()}
+	if err := db.Create(&member).Error; err != nil {
+		t.Fatalf("Failed to create member: %v", err)
+	}
+}
+
+func TestService_AnalyzeList(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db, nil)
+	setupList(t, db)
+
+	now := time.Now()
+	buy(t, db, "home", "Milk", now, 30, 20, 10)
+	buy(t, db, "home", "Bread", now, 60, 45, 30)
+	buy(t, db, "home", "Butter", now, 40, 21, 2)
+	buy(t, db, "home", "Eggs", now, 40, 20)
+	buy(t, db, "home", "Coffee", now, 21, 14, 7)
+	coffee := models.ShoppingItem{ID: "coffee", ListID: "home", Name: "coffee", Tags: "[]"}
+	if err := db.Create(&coffee).Error; err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+
+	open, err := service.AnalyzeList("home", now)
+	if err != nil {
+		t.Fatalf("Failed to analyze list: %v", err)
+	}
+	if open != 2 {
+		t.Errorf("Expected milk and bread to be suggested, got %d suggestions", open)
+	}
+
+	suggestions, err := service.Suggestions("user-1", "")
+	if err != nil {
+		t.Fatalf("Failed to get suggestions: %v", err)
+	}
+	if len(suggestions) != 2 || suggestions[0].Name != "Bread" || suggestions[1].Name != "Milk" {
+		t.Fatalf("Expected bread and milk, the most overdue first, got %+v", suggestions)
+	}
+	if milk := suggestions[1]; milk.IntervalDays != 10 || milk.Purchases != 3 || milk.ListName != "Home" {
+		t.Errorf("Expected milk bought every 10 days on Home, got %+v", milk)
+	}
+	if others, _ := service.Suggestions("user-2", ""); len(others) != 0 {
+		t.Errorf("Expected no suggestions for other users, got %+v", others)
+	}
+
+	// Items added since the analysis are no longer suggested
+	bread := models.ShoppingItem{ID: "bread", ListID: "home", Name: "Bread", Tags: "[]"}
+	if err := db.Create(&bread).Error; err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+	if suggestions, _ := service.Suggestions("user-1", "home"); len(suggestions) != 1 || suggestions[0].Name != "Milk" {
+		t.Errorf("Expected only milk after adding bread, got %+v", suggestions)
+	}
+	db.Delete(&bread)
+
+	// Dismissed suggestions stay hidden until the item is bought again, then come later
+	milk, err := service.Get(suggestions[1].ID)
+	if err != nil {
+		t.Fatalf("Failed to get suggestion: %v", err)
+	}
+	if err := service.Dismiss(milk); err != nil {
+		t.Fatalf("Failed to dismiss suggestion: %v", err)
+	}
+	if _, err := service.Get(milk.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the dismissed suggestion to be gone, got %v", err)
+	}
+	if open, _ := service.AnalyzeList("home", now); open != 1 {
+		t.Errorf("Expected the dismissal to last until the next purchase, got %d suggestions", open)
+	}
+	buy(t, db, "home", "Milk", now, 0)
+	if _, err := service.AnalyzeList("home", now); err != nil {
+		t.Fatalf("Failed to analyze list: %v", err)
+	}
+	var stored models.ReplenishSuggestion
+	db.First(&stored, "id = ?", milk.ID)
+	if stored.Status != StatusWaiting || stored.Factor != dismissStretch ||
+		!stored.DueAt.Equal(now.Add(time.Duration(10*dismissStretch*24)*time.Hour)) {
+		t.Errorf("Expected milk to wait for its stretched interval, got %+v", stored)
+	}
+
+	// Accepting tunes the interval towards when the item was needed
+	breadSuggestion, err := service.Get(suggestions[0].ID)
+	if err != nil {
+		t.Fatalf("Failed to get suggestion: %v", err)
+	}
+	if err := service.Accept(breadSuggestion, now); err != nil {
+		t.Fatalf("Failed to accept suggestion: %v", err)
+	}
+	var accepted models.ReplenishSuggestion
+	db.First(&accepted, "id = ?", breadSuggestion.ID)
+	if accepted.Status != StatusAccepted || accepted.Factor != 1.5 {
+		t.Errorf("Expected bread needed after twice its interval to be tuned halfway, got %+v", accepted)
+	}
+
+	// Purged purchases end the prediction
+	db.Where("list_id = ? AND name = ?", "home", "Bread").Delete(&models.ShoppingItem{})
+	if _, err := service.AnalyzeList("home", now); err != nil {
+		t.Fatalf("Failed to analyze list: %v", err)
+	}
+	var count int64
+	db.Model(&models.ReplenishSuggestion{}).Where("key = ?", "bread").Count(&count)
+	if count != 0 {
+		t.Error("Expected the bread suggestion to be removed")
+	}
+}
+
+func TestService_SendDueReminders(t *testing.T) {
+	t.Setenv("GO_ENV", "test")
+
+	db := testutils.SetupTestDB(t)
+	service := NewService(db, nil)
+	setupList(t, db)
+
+	if sent, err := service.SendDueReminders(); err != nil || sent != 0 {
+		t.Fatalf("Expected no reminder without suggestions, got %d, %v", sent, err)
+	}
+
+	buy(t, db, "home", "Milk", time.Now(), 30, 20, 10)
+	if _, err := service.Analyze(); err != nil {
+		t.Fatalf("Failed to analyze lists: %v", err)
+	}
+	if sent, err := service.SendDueReminders(); err != nil || sent != 1 {
+		t.Fatalf("Expected a reminder, got %d, %v", sent, err)
+	}
+	if sent, _ := service.SendDueReminders(); sent != 0 {
+		t.Errorf("Expected no second reminder within a week, got %d", sent)
+	}
+
+	if err := service.UpdateSettings("user-1", models.ReplenishSettings{WeeklyEmail: false}); err != nil {
+		t.Fatalf("Failed to update settings: %v", err)
+	}
+	if settings, err := service.Settings("user-1"); err != nil || settings.WeeklyEmail {
+		t.Errorf("Expected the weekly email to be off, got %+v, %v", settings, err)
+	}
+}
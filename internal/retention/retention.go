@@ -0,0 +1,248 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package retention purges old activity, audit and item history data according to the retention
+// periods of the server settings, to keep the database small on long-running installations.
+// Deleted items are purged from the trash after items.TrashDays.
+package retention
+
+import (
+	"sync"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/attachments"
+	"github.com/oliverandrich/shopping-list-server/internal/items"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// Kinds of data with a retention period.
+const (
+	DataActivity    = "activity"
+	DataAudit       = "audit"
+	DataItemHistory = "item_history"
+	DataTrash       = "trash"
+)
+
+// policy describes where the rows of a kind of data live and which column ages them.
+type policy struct {
+	data   string
+	model  any
+	column string
+	// scope restricts the rows the policy applies to.
+	scope func(db *gorm.DB) *gorm.DB
+	days  func(settings *models.SystemSettings) int
+}
+
+var policies = []policy{
+	{
+		data:   DataActivity,
+		model:  &models.ItemMerge{},
+		column: "created_at",
+		scope:  func(db *gorm.DB) *gorm.DB { return db },
+		days:   func(settings *models.SystemSettings) int { return settings.ActivityRetentionDays },
+	},
+	{
+		data:   DataAudit,
+		model:  &models.AuditEntry{},
+		column: "created_at",
+		scope:  func(db *gorm.DB) *gorm.DB { return db },
+		days:   func(settings *models.SystemSettings) int { return settings.AuditRetentionDays },
+	},
+	{
+		data:   DataItemHistory,
+		model:  &models.ShoppingItem{},
+		column: "completed_at",
+		scope: func(db *gorm.DB) *gorm.DB {
+			return db.Where("completed = ? AND completed_at IS NOT NULL", true)
+		},
+		days: func(settings *models.SystemSettings) int { return settings.ItemHistoryRetentionDays },
+	},
+	{
+		data:   DataTrash,
+		model:  &models.ShoppingItem{},
+		column: "deleted_at",
+		scope: func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped().Where("deleted_at IS NOT NULL")
+		},
+		days: func(settings *models.SystemSettings) int { return items.TrashDays },
+	},
+}
+
+// Service enforces the retention periods.
+type Service struct {
+	DB          *gorm.DB
+	Attachments *attachments.Service
+	// Interval is the time between scheduled purges, used to predict the next ones. Zero means
+	// purges are not scheduled.
+	Interval time.Duration
+
+	mu      sync.Mutex
+	lastRun time.Time
+}
+
+// NewService creates a new retention service. Attachments of purged items are removed through
+// the attachments service.
+func NewService(db *gorm.DB, attachments *attachments.Service) *Service {
+	return &Service{DB: db, Attachments: attachments}
+}
+
+// Purge deletes the rows older than their retention period for good. Completed and trashed items
+// are purged with their attachments and polls.
+func (s *Service) Purge() (*models.RetentionPurgeResult, error) {
+	return s.purge(false)
+}
+
+// Preview counts the rows Purge would delete now without deleting anything.
+func (s *Service) Preview() (*models.RetentionPurgeResult, error) {
+	return s.purge(true)
+}
+
+func (s *Service) purge(dryRun bool) (*models.RetentionPurgeResult, error) {
+	settings, err := s.settings()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result := &models.RetentionPurgeResult{Purged: map[string]int64{}, DryRun: dryRun, RanAt: now}
+	for _, p := range policies {
+		days := p.days(settings)
+		if days <= 0 {
+			continue
+		}
+		cutoff := now.AddDate(0, 0, -days)
+
+		if dryRun {
+			var count int64
+			if err := p.scope(s.DB.Unscoped().Model(p.model)).Where(p.column+" < ?", cutoff).Count(&count).Error; err != nil {
+				return nil, err
+			}
+			result.Purged[p.data] = count
+			continue
+		}
+
+		if _, ok := p.model.(*models.ShoppingItem); ok {
+			if err := s.purgeAttachments(p, cutoff); err != nil {
+				return nil, err
+			}
+			if err := s.purgePolls(p, cutoff); err != nil {
+				return nil, err
+			}
+		}
+		// Unscoped, so purged items are removed instead of moved to the trash
+		deleted := p.scope(s.DB.Unscoped()).Where(p.column+" < ?", cutoff).Delete(p.model)
+		if deleted.Error != nil {
+			return nil, deleted.Error
+		}
+		result.Purged[p.data] = deleted.RowsAffected
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	s.mu.Lock()
+	s.lastRun = now
+	s.mu.Unlock()
+	return result, nil
+}
+
+// Status reports the retention policies with the number of rows they cover and when the oldest
+// row is purged, which is the first scheduled purge after it expires.
+func (s *Service) Status() (*models.RetentionStatus, error) {
+	settings, err := s.settings()
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.RetentionStatus{Policies: make([]models.RetentionPolicy, 0, len(policies))}
+	s.mu.Lock()
+	if !s.lastRun.IsZero() {
+		lastRun := s.lastRun
+		status.LastRunAt = &lastRun
+		if s.Interval > 0 {
+			nextRun := lastRun.Add(s.Interval)
+			status.NextRunAt = &nextRun
+		}
+	}
+	s.mu.Unlock()
+
+	for _, p := range policies {
+		entry := models.RetentionPolicy{Data: p.data, Days: p.days(settings)}
+		if err := p.scope(s.DB.Model(p.model)).Count(&entry.Rows).Error; err != nil {
+			return nil, err
+		}
+
+		if entry.Rows > 0 {
+			var oldest struct{ At time.Time }
+			err := p.scope(s.DB.Model(p.model)).Select(p.column + " AS at").Order(p.column + " ASC").Limit(1).Scan(&oldest).Error
+			if err != nil {
+				return nil, err
+			}
+			entry.OldestAt = &oldest.At
+		}
+		if entry.OldestAt != nil && entry.Days > 0 {
+			nextPurge := s.nextPurge(entry.OldestAt.AddDate(0, 0, entry.Days), status.NextRunAt)
+			entry.NextPurgeAt = &nextPurge
+		}
+		status.Policies = append(status.Policies, entry)
+	}
+	return status, nil
+}
+
+// nextPurge returns the first scheduled run at or after expiry. Without a schedule, rows are
+// purged by the first purge after expiry, so expiry is returned.
+func (s *Service) nextPurge(expiry time.Time, nextRun *time.Time) time.Time {
+	if nextRun == nil {
+		return expiry
+	}
+	if !expiry.After(*nextRun) {
+		return *nextRun
+	}
+	runs := (expiry.Sub(*nextRun) + s.Interval - 1) / s.Interval
+	return nextRun.Add(runs * s.Interval)
+}
+
+// purgeAttachments removes the attachments of the items a policy is about to purge.
+func (s *Service) purgeAttachments(p policy, cutoff time.Time) error {
+	var itemIDs []string
+	err := p.scope(s.DB.Unscoped().Model(p.model)).
+		Where(p.column+" < ? AND id IN (?)", cutoff, s.DB.Model(&models.Attachment{}).Select("item_id")).
+		Pluck("id", &itemIDs).Error
+	if err != nil {
+		return err
+	}
+
+	for _, itemID := range itemIDs {
+		if !s.Attachments.Enabled() {
+			// Without storage there are no files to remove
+			if err := s.DB.Where("item_id = ?", itemID).Delete(&models.Attachment{}).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		if err := s.Attachments.DeleteItemAttachments(itemID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// purgePolls removes the polls and their votes of the items a policy is about to purge.
+func (s *Service) purgePolls(p policy, cutoff time.Time) error {
+	itemIDs := p.scope(s.DB.Unscoped().Model(p.model)).Select("id").Where(p.column+" < ?", cutoff)
+	pollIDs := s.DB.Model(&models.ItemPoll{}).Select("id").Where("item_id IN (?)", itemIDs)
+	if err := s.DB.Where("poll_id IN (?)", pollIDs).Delete(&models.PollVote{}).Error; err != nil {
+		return err
+	}
+	return s.DB.Where("item_id IN (?)", itemIDs).Delete(&models.ItemPoll{}).Error
+}
+
+// settings loads the retention periods; before setup nothing is purged.
+func (s *Service) settings() (*models.SystemSettings, error) {
+	var settings models.SystemSettings
+	if err := s.DB.Limit(1).Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
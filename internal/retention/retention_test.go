@@ -0,0 +1,126 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package retention
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/attachments"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/storage"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+	"gorm.io/gorm"
+)
+
+func TestService_Purge(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	files := attachments.NewService(db)
+	files.Storage = storage.NewLocal(t.TempDir())
+	service := NewService(db, files)
+
+	settings := models.SystemSettings{ID: "settings", IsSetup: true, ActivityRetentionDays: 90, ItemHistoryRetentionDays: 730}
+	if err := db.Create(&settings).Error; err != nil {
+		t.Fatalf("Failed to create settings: %v", err)
+	}
+
+	now := time.Now()
+	old, recent := now.AddDate(-3, 0, 0), now.AddDate(0, 0, -10)
+	db.Create(&models.ItemMerge{ID: "old-merge", ListID: "list", ItemID: "item", CreatedAt: now.AddDate(0, 0, -100)})
+	db.Create(&models.ItemMerge{ID: "new-merge", ListID: "list", ItemID: "item", CreatedAt: recent})
+	db.Create(&models.AuditEntry{ID: "old-audit", ActorID: "admin", Action: "logout", TargetUserID: "user", CreatedAt: old})
+	db.Create(&models.ShoppingItem{ID: "old-item", ListID: "list", Name: "Milk", Tags: "[]", Completed: true, CompletedAt: &old, CreatedAt: old})
+	db.Create(&models.ShoppingItem{ID: "new-item", ListID: "list", Name: "Bread", Tags: "[]", Completed: true, CompletedAt: &recent, CreatedAt: old})
+	db.Create(&models.ShoppingItem{ID: "open-item", ListID: "list", Name: "Eggs", Tags: "[]", CreatedAt: old})
+	db.Create(&models.ShoppingItem{ID: "trashed-item", ListID: "list", Name: "Flour", Tags: "[]", CreatedAt: old,
+		DeletedAt: gorm.DeletedAt{Time: now.AddDate(0, 0, -31), Valid: true}})
+	db.Create(&models.ShoppingItem{ID: "deleted-item", ListID: "list", Name: "Sugar", Tags: "[]", CreatedAt: old,
+		DeletedAt: gorm.DeletedAt{Time: recent, Valid: true}})
+	db.Create(&models.ItemPoll{ID: "poll", ListID: "list", ItemID: "trashed-item", Question: "Which brand?", CreatedBy: "user"})
+	attachment, err := files.Upload("user", "list", "old-item", "receipt.txt", []byte("receipt"))
+	if err != nil {
+		t.Fatalf("Failed to upload attachment: %v", err)
+	}
+
+	preview, err := service.Preview()
+	if err != nil {
+		t.Fatalf("Failed to preview purge: %v", err)
+	}
+	if !preview.DryRun || preview.Purged[DataActivity] != 1 || preview.Purged[DataItemHistory] != 1 || preview.Purged[DataTrash] != 1 {
+		t.Errorf("Unexpected preview: %+v", preview)
+	}
+	var count int64
+	db.Unscoped().Model(&models.ShoppingItem{}).Count(&count)
+	if count != 5 {
+		t.Errorf("Expected the preview to keep every item, got %d", count)
+	}
+
+	result, err := service.Purge()
+	if err != nil {
+		t.Fatalf("Failed to purge: %v", err)
+	}
+	if result.DryRun || result.Purged[DataActivity] != 1 || result.Purged[DataItemHistory] != 1 || result.Purged[DataTrash] != 1 {
+		t.Errorf("Unexpected purge result: %+v", result.Purged)
+	}
+	if _, ok := result.Purged[DataAudit]; ok {
+		t.Error("Expected audit entries to be kept forever")
+	}
+
+	var items []string
+	db.Unscoped().Model(&models.ShoppingItem{}).Order("id").Pluck("id", &items)
+	if len(items) != 3 || items[0] != "deleted-item" || items[1] != "new-item" || items[2] != "open-item" {
+		t.Errorf("Expected recent, open and recently deleted items to be kept, got %v", items)
+	}
+	if _, err := files.Storage.Get("attachments/" + attachment.ID); err == nil {
+		t.Error("Expected attachment files of purged items to be removed")
+	}
+	db.Model(&models.Attachment{}).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected attachments of purged items to be deleted, got %d", count)
+	}
+	db.Model(&models.ItemPoll{}).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected polls of purged items to be deleted, got %d", count)
+	}
+}
+
+func TestService_Status(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db, attachments.NewService(db))
+	service.Interval = 24 * time.Hour
+
+	if err := db.Create(&models.SystemSettings{ID: "settings", IsSetup: true, AuditRetentionDays: 365}).Error; err != nil {
+		t.Fatalf("Failed to create settings: %v", err)
+	}
+	oldest := time.Now().AddDate(0, 0, -300)
+	db.Create(&models.AuditEntry{ID: "first", ActorID: "admin", Action: "logout", TargetUserID: "user", CreatedAt: oldest})
+	db.Create(&models.AuditEntry{ID: "second", ActorID: "admin", Action: "logout", TargetUserID: "user", CreatedAt: time.Now()})
+
+	if _, err := service.Purge(); err != nil {
+		t.Fatalf("Failed to purge: %v", err)
+	}
+	status, err := service.Status()
+	if err != nil {
+		t.Fatalf("Failed to get status: %v", err)
+	}
+	if status.LastRunAt == nil || status.NextRunAt == nil || !status.NextRunAt.Equal(status.LastRunAt.Add(24*time.Hour)) {
+		t.Errorf("Unexpected runs: %+v", status)
+	}
+
+	policies := map[string]models.RetentionPolicy{}
+	for _, policy := range status.Policies {
+		policies[policy.Data] = policy
+	}
+	audit := policies[DataAudit]
+	if audit.Rows != 2 || audit.NextPurgeAt == nil {
+		t.Fatalf("Unexpected audit policy: %+v", audit)
+	}
+	expiry := oldest.AddDate(0, 0, 365)
+	if audit.NextPurgeAt.Before(expiry) || audit.NextPurgeAt.Sub(expiry) >= 24*time.Hour {
+		t.Errorf("Expected the next purge within a day after %v, got %v", expiry, audit.NextPurgeAt)
+	}
+	if activity := policies[DataActivity]; activity.Days != 0 || activity.NextPurgeAt != nil {
+		t.Errorf("Expected activity to be kept forever, got %+v", activity)
+	}
+}
@@ -0,0 +1,443 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// MaxExpressionLength bounds the length of rule conditions.
+const MaxExpressionLength = 1000
+
+// Variables lists the item fields a condition can refer to.
+var Variables = map[string]string{
+	"name":      "string",
+	"category":  "string",
+	"unit":      "string",
+	"quantity":  "number",
+	"tags":      "list",
+	"completed": "bool",
+}
+
+// Condition is a compiled rule condition such as `"pharmacy" in tags` or
+// `name contains "bio" && !("organic" in tags)`. Conditions combine comparisons of the item's
+// variables with && (and), || (or), ! (not) and parentheses. Comparisons are == and != for
+// strings, numbers and booleans, <, <=, > and >= for numbers, `a contains b` for substrings or
+// list elements and `a in b` for list elements. Strings are compared ignoring case.
+type Condition struct {
+	source string
+	root   node
+}
+
+// String returns the source of the condition.
+func (c *Condition) String() string {
+	return c.source
+}
+
+// Compile parses a condition and checks that it only refers to known variables.
+func Compile(source string) (*Condition, error) {
+	if len(source) > MaxExpressionLength {
+		return nil, fmt.Errorf("condition is longer than %d characters", MaxExpressionLength)
+	}
+
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.peek().text, p.peek().pos)
+	}
+	return &Condition{source: source, root: root}, nil
+}
+
+// Match evaluates the condition for the variables of an item. It fails if the condition
+// compares values of different types or does not result in a boolean.
+func (c *Condition) Match(vars map[string]any) (bool, error) {
+	value, err := c.root.eval(vars)
+	if err != nil {
+		return false, err
+	}
+	matched, ok := value.(bool)
+	if !ok {
+		return false, errors.New("condition does not result in true or false")
+	}
+	return matched, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenString
+	tokenNumber
+	tokenIdent
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// operators holds the operator tokens, two-character ones first.
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "!", "<", ">", "(", ")", "[", "]", ","}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(source); {
+		r := rune(source[i])
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(source) && source[end] != '"' {
+				if source[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(source) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			text, err := strconv.Unquote(source[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d", i)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, pos: i})
+			i = end + 1
+		case unicode.IsDigit(r):
+			end := i
+			for end < len(source) && (unicode.IsDigit(rune(source[end])) || source[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: source[i:end], pos: i})
+			i = end
+		case unicode.IsLetter(r) || r == '_':
+			end := i
+			for end < len(source) && (unicode.IsLetter(rune(source[end])) || unicode.IsDigit(rune(source[end])) || source[end] == '_') {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: source[i:end], pos: i})
+			i = end
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(source[i:], op) {
+					tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected %q at position %d", source[i], i)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, text: "end of condition", pos: len(source)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the operator or keyword text.
+func (p *parser) accept(text string) bool {
+	t := p.peek()
+	if (t.kind == tokenOperator || t.kind == tokenIdent) && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = logical{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = logical{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (node, error) {
+	if p.accept("!") {
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return not{operand: operand}, nil
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (node, error) {
+	left, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">", "contains", "in"} {
+		if p.accept(op) {
+			right, err := p.primary()
+			if err != nil {
+				return nil, err
+			}
+			return compare{op: op, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString:
+		return literal{value: t.text}, nil
+	case tokenNumber:
+		number, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", t.text, t.pos)
+		}
+		return literal{value: number}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true", "false":
+			return literal{value: t.text == "true"}, nil
+		}
+		if _, ok := Variables[t.text]; !ok {
+			return nil, fmt.Errorf("unknown variable %q at position %d", t.text, t.pos)
+		}
+		return variable{name: t.text}, nil
+	case tokenOperator:
+		switch t.text {
+		case "(":
+			inner, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			if !p.accept(")") {
+				return nil, fmt.Errorf("missing ) at position %d", p.peek().pos)
+			}
+			return inner, nil
+		case "[":
+			var elements []string
+			for !p.accept("]") {
+				if len(elements) > 0 && !p.accept(",") {
+					return nil, fmt.Errorf("expected , or ] at position %d", p.peek().pos)
+				}
+				element := p.next()
+				if element.kind != tokenString {
+					return nil, fmt.Errorf("lists may only hold strings, found %q at position %d", element.text, element.pos)
+				}
+				elements = append(elements, element.text)
+			}
+			return literal{value: elements}, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos)
+}
+
+// node is a part of a compiled condition.
+type node interface {
+	eval(vars map[string]any) (any, error)
+}
+
+type literal struct {
+	value any
+}
+
+func (l literal) eval(map[string]any) (any, error) {
+	return l.value, nil
+}
+
+type variable struct {
+	name string
+}
+
+func (v variable) eval(vars map[string]any) (any, error) {
+	value, ok := vars[v.name]
+	if !ok {
+		return nil, fmt.Errorf("variable %q is not set", v.name)
+	}
+	return value, nil
+}
+
+type not struct {
+	operand node
+}
+
+func (n not) eval(vars map[string]any) (any, error) {
+	value, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return nil, errors.New("! needs true or false")
+	}
+	return !b, nil
+}
+
+type logical struct {
+	op          string
+	left, right node
+}
+
+func (l logical) eval(vars map[string]any) (any, error) {
+	left, err := l.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	lb, ok := left.(bool)
+	if !ok {
+		return nil, fmt.Errorf("%s needs true or false", l.op)
+	}
+	if (l.op == "&&" && !lb) || (l.op == "||" && lb) {
+		return lb, nil
+	}
+
+	right, err := l.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	rb, ok := right.(bool)
+	if !ok {
+		return nil, fmt.Errorf("%s needs true or false", l.op)
+	}
+	return rb, nil
+}
+
+type compare struct {
+	op          string
+	left, right node
+}
+
+func (c compare) eval(vars map[string]any) (any, error) {
+	left, err := c.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	right, err := c.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	switch c.op {
+	case "contains":
+		return contains(left, right)
+	case "in":
+		return contains(right, left)
+	}
+
+	switch l := left.(type) {
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			break
+		}
+		switch c.op {
+		case "==":
+			return strings.EqualFold(l, r), nil
+		case "!=":
+			return !strings.EqualFold(l, r), nil
+		}
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			break
+		}
+		switch c.op {
+		case "==":
+			return l == r, nil
+		case "!=":
+			return l != r, nil
+		case "<":
+			return l < r, nil
+		case "<=":
+			return l <= r, nil
+		case ">":
+			return l > r, nil
+		case ">=":
+			return l >= r, nil
+		}
+	case bool:
+		r, ok := right.(bool)
+		if !ok {
+			break
+		}
+		switch c.op {
+		case "==":
+			return l == r, nil
+		case "!=":
+			return l != r, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot compare %v %s %v", left, c.op, right)
+}
+
+// contains reports whether the string haystack contains the string needle, or the list haystack
+// holds it, ignoring case.
+func contains(haystack, needle any) (bool, error) {
+	n, ok := needle.(string)
+	if !ok {
+		return false, fmt.Errorf("cannot look for %v, only for strings", needle)
+	}
+	switch h := haystack.(type) {
+	case string:
+		return strings.Contains(strings.ToLower(h), strings.ToLower(n)), nil
+	case []string:
+		for _, element := range h {
+			if strings.EqualFold(element, n) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return false, fmt.Errorf("cannot look into %v, only into strings and lists", haystack)
+}
@@ -0,0 +1,305 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package rules lets list owners automate their lists with rules like "when an item tagged
+// pharmacy is added, notify Bob" or "tag anything containing bio as organic". Each rule has a
+// condition in a small expression language, evaluated for item events of its list, and actions
+// run on matching items.
+package rules
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oliverandrich/shopping-list-server/internal/catalog"
+	"github.com/oliverandrich/shopping-list-server/internal/lists"
+	"github.com/oliverandrich/shopping-list-server/internal/mail"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gopkg.in/gomail.v2"
+	"gorm.io/gorm"
+)
+
+// Item events rules are evaluated for.
+const (
+	EventItemCreated   = "item_created"
+	EventItemCompleted = "item_completed"
+)
+
+// Actions of rules.
+const (
+	ActionAddTag      = "add_tag"
+	ActionSetCategory = "set_category"
+	ActionNotify      = "notify"
+)
+
+// ErrNotFound is returned when a rule does not exist on the list.
+var ErrNotFound = errors.New("rule not found")
+
+// ErrNotOwner is returned when a user other than a list owner manages rules.
+var ErrNotOwner = errors.New("only list owners can manage rules")
+
+// Service manages list rules and applies them to items.
+type Service struct {
+	DB         *gorm.DB
+	Mailer     *gomail.Dialer
+	Dictionary *catalog.Dictionary
+}
+
+// NewService creates a new rules service. The dictionary, if any, provides the emoji of
+// categories set by rules.
+func NewService(db *gorm.DB, mailer *gomail.Dialer, dictionary *catalog.Dictionary) *Service {
+	return &Service{
+		DB:         db,
+		Mailer:     mailer,
+		Dictionary: dictionary,
+	}
+}
+
+// GetRules retrieves the rules of the list in the order they run, if the user is an owner.
+func (s *Service) GetRules(listID, userID string) ([]models.ListRule, error) {
+	if !s.isOwner(listID, userID) {
+		return nil, ErrNotOwner
+	}
+
+	var rules []models.ListRule
+	err := s.DB.Where("list_id = ?", listID).Order("created_at ASC").Find(&rules).Error
+	return rules, err
+}
+
+// CreateRule adds a rule to the list if the user is an owner. New rules are enabled unless the
+// request says otherwise.
+func (s *Service) CreateRule(listID, userID string, req models.ListRuleRequest) (*models.ListRule, error) {
+	if !s.isOwner(listID, userID) {
+		return nil, ErrNotOwner
+	}
+
+	rule := models.ListRule{
+		ID:        uuid.New().String(),
+		ListID:    listID,
+		CreatedBy: userID,
+		CreatedAt: time.Now(),
+	}
+	if err := s.fill(&rule, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.DB.Create(&rule).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// UpdateRule replaces a rule of the list if the user is an owner.
+func (s *Service) UpdateRule(listID, ruleID, userID string, req models.ListRuleRequest) (*models.ListRule, error) {
+	if !s.isOwner(listID, userID) {
+		return nil, ErrNotOwner
+	}
+
+	var rule models.ListRule
+	if err := s.DB.Where("id = ? AND list_id = ?", ruleID, listID).First(&rule).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if err := s.fill(&rule, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.DB.Save(&rule).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// DeleteRule removes a rule of the list if the user is an owner.
+func (s *Service) DeleteRule(listID, ruleID, userID string) error {
+	if !s.isOwner(listID, userID) {
+		return ErrNotOwner
+	}
+
+	result := s.DB.Where("id = ? AND list_id = ?", ruleID, listID).Delete(&models.ListRule{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// fill sets the rule from the request after checking that the condition compiles and that
+// notified users are members of the list.
+func (s *Service) fill(rule *models.ListRule, req models.ListRuleRequest) error {
+	if _, err := Compile(req.Condition); err != nil {
+		return fmt.Errorf("invalid condition: %w", err)
+	}
+	for _, action := range req.Actions {
+		if action.Type == ActionNotify && !s.isMember(rule.ListID, action.Value) {
+			return fmt.Errorf("user %s is not a member of this list", action.Value)
+		}
+	}
+
+	rule.Name = strings.TrimSpace(req.Name)
+	rule.Event = req.Event
+	rule.Condition = req.Condition
+	rule.Actions = req.Actions
+	rule.Enabled = req.Enabled == nil || *req.Enabled
+	return nil
+}
+
+func (s *Service) isOwner(listID, userID string) bool {
+	var member models.ListMember
+	err := s.DB.Where("list_id = ? AND user_id = ? AND role IN ?", listID, userID, lists.OwnerRoles).First(&member).Error
+	return err == nil
+}
+
+func (s *Service) isMember(listID, userID string) bool {
+	var member models.ListMember
+	return s.DB.Where("list_id = ? AND user_id = ?", listID, userID).First(&member).Error == nil
+}
+
+// Apply runs the enabled rules of the item's list for the event, in the order they were created,
+// so a rule sees the tags and category set by earlier ones. Changes are stored and set on the
+// item; notifications are sent in the background to members other than the user who caused the
+// event. Rules whose condition fails to evaluate are skipped; failures are logged, they never
+// fail the change of the item.
+func (s *Service) Apply(event, userID string, item *models.ShoppingItem) {
+	var rules []models.ListRule
+	err := s.DB.Where("list_id = ? AND event = ? AND enabled = ?", item.ListID, event, true).
+		Order("created_at ASC").Find(&rules).Error
+	if err != nil {
+		slog.Warn("Failed to load rules", "list", item.ListID, "event", event, "error", err)
+		return
+	}
+
+	changed := false
+	var notify, matchedRules []string
+	for _, rule := range rules {
+		condition, err := Compile(rule.Condition)
+		if err != nil {
+			continue
+		}
+		matched, err := condition.Match(Vars(*item))
+		if err != nil || !matched {
+			continue
+		}
+		matchedRules = append(matchedRules, rule.ID)
+
+		for _, action := range rule.Actions {
+			switch action.Type {
+			case ActionAddTag:
+				changed = addTag(item, action.Value) || changed
+			case ActionSetCategory:
+				if item.Category != action.Value {
+					item.Category = action.Value
+					item.Emoji = ""
+					if s.Dictionary != nil {
+						item.Emoji = s.Dictionary.Emoji(action.Value)
+					}
+					changed = true
+				}
+			case ActionNotify:
+				if action.Value != userID && !slices.Contains(notify, action.Value) {
+					notify = append(notify, action.Value)
+				}
+			}
+		}
+	}
+
+	if changed {
+		err := s.DB.Model(&models.ShoppingItem{}).Where("id = ?", item.ID).
+			UpdateColumns(map[string]any{"tags": item.Tags, "category": item.Category, "emoji": item.Emoji, "updated_at": time.Now()}).Error
+		if err != nil {
+			slog.Warn("Failed to store rule changes", "list", item.ListID, "item", item.ID, "rules", matchedRules, "error", err)
+		}
+	}
+	if len(notify) > 0 {
+		go s.notify(event, *item, notify, matchedRules)
+	}
+}
+
+// Vars returns the variables of an item conditions can refer to.
+func Vars(item models.ShoppingItem) map[string]any {
+	return map[string]any{
+		"name":      item.Name,
+		"category":  item.Category,
+		"unit":      item.Unit,
+		"quantity":  item.Quantity,
+		"tags":      tags(item),
+		"completed": item.Completed,
+	}
+}
+
+// tags returns the tags of the item, stored as JSON array.
+func tags(item models.ShoppingItem) []string {
+	tags := []string{}
+	_ = json.Unmarshal([]byte(item.Tags), &tags)
+	return tags
+}
+
+// addTag adds the tag to the item unless it already has it, ignoring case.
+func addTag(item *models.ShoppingItem, tag string) bool {
+	current := tags(*item)
+	for _, existing := range current {
+		if strings.EqualFold(existing, tag) {
+			return false
+		}
+	}
+	encoded, err := json.Marshal(append(current, tag))
+	if err != nil {
+		return false
+	}
+	item.Tags = string(encoded)
+	return true
+}
+
+// notify emails the members about the item, skipping those who left the list meanwhile. ruleIDs
+// are the rules that matched, for the log.
+func (s *Service) notify(event string, item models.ShoppingItem, userIDs, ruleIDs []string) {
+	var list models.ShoppingList
+	if err := s.DB.First(&list, "id = ?", item.ListID).Error; err != nil {
+		return
+	}
+
+	for _, userID := range userIDs {
+		if !s.isMember(item.ListID, userID) {
+			continue
+		}
+		var user models.User
+		if err := s.DB.First(&user, "id = ?", userID).Error; err != nil {
+			continue
+		}
+		if err := s.sendNotification(user.Email, event, &list, item); err != nil {
+			slog.Warn("Failed to send rule notification", "list", item.ListID, "item", item.ID, "rules", ruleIDs, "user", userID, "error", err)
+		}
+	}
+}
+
+// sendNotification sends a rule notification email to a member.
+func (s *Service) sendNotification(email, event string, list *models.ShoppingList, item models.ShoppingItem) error {
+	// Skip email sending in test environment
+	if os.Getenv("GO_ENV") == "test" {
+		return nil
+	}
+
+	verb := "added to"
+	if event == EventItemCompleted {
+		verb = "completed on"
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", os.Getenv("SMTP_FROM"))
+	m.SetHeader("To", email)
+	m.SetHeader("Subject", fmt.Sprintf("%s %s %s", item.Name, verb, list.Name))
+	m.SetBody("text/plain", fmt.Sprintf("\"%s\" was %s \"%s\".\n", item.Name, verb, list.Name))
+
+	return mail.Send(s.Mailer, m)
+}
@@ -0,0 +1,161 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package rules
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/oliverandrich/shopping-list-server/internal/catalog"
+	"github.com/oliverandrich/shopping-list-server/internal/lists"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+)
+
+func TestCondition(t *testing.T) {
+	vars := map[string]any{
+		"name":      "Bio Milk",
+		"category":  "Dairy",
+		"unit":      "l",
+		"quantity":  2.0,
+		"tags":      []string{"Pharmacy", "weekly"},
+		"completed": false,
+	}
+
+	tests := []struct {
+		condition string
+		expected  bool
+	}{
+		{`"pharmacy" in tags`, true},
+		{`tags contains "WEEKLY"`, true},
+		{`"organic" in tags`, false},
+		{`name contains "bio"`, true},
+		{`name contains "bio" && !("organic" in tags)`, true},
+		{`category == "dairy" || quantity > 5`, true},
+		{`category != "Dairy" || quantity >= 3`, false},
+		{`quantity == 2 && unit == "l" && completed == false`, true},
+		{`category in ["Dairy", "Bakery"]`, true},
+		{`!completed`, true},
+		{`name == "Milk" || (quantity < 2.5 && "weekly" in tags)`, true},
+		{`true`, true},
+	}
+	for _, tt := range tests {
+		condition, err := Compile(tt.condition)
+		if err != nil {
+			t.Errorf("Compile(%q) failed: %v", tt.condition, err)
+			continue
+		}
+		matched, err := condition.Match(vars)
+		if err != nil {
+			t.Errorf("Match(%q) failed: %v", tt.condition, err)
+			continue
+		}
+		if matched != tt.expected {
+			t.Errorf("Match(%q) = %v, expected %v", tt.condition, matched, tt.expected)
+		}
+	}
+
+	for _, invalid := range []string{`price > 2`, `name contains`, `(name == "Milk"`, `name == "Milk`, `quantity > 2 $`, `[1, 2] contains "a"`, strings.Repeat("x", MaxExpressionLength+1)} {
+		if _, err := Compile(invalid); err == nil {
+			t.Errorf("Compile(%q) should fail", invalid)
+		}
+	}
+
+	for _, mismatched := range []string{`quantity == "2"`, `name > 2`, `quantity contains "2"`, `name`} {
+		condition, err := Compile(mismatched)
+		if err != nil {
+			t.Fatalf("Compile(%q) failed: %v", mismatched, err)
+		}
+		if _, err := condition.Match(vars); err == nil {
+			t.Errorf("Match(%q) should fail", mismatched)
+		}
+	}
+}
+
+func TestService_Rules(t *testing.T) {
+	t.Setenv("GO_ENV", "test")
+	db := testutils.SetupTestDB(t)
+	service := NewService(db, nil, catalog.DefaultDictionary())
+
+	for _, id := range []string{"owner-id", "bob-id"} {
+		if err := db.Create(&models.User{ID: id, Email: id + "@example.com"}).Error; err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+	listService := lists.NewService(db)
+	list, err := listService.CreateList("owner-id", "Groceries")
+	if err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	if err := listService.JoinList(list.ID, "bob-id"); err != nil {
+		t.Fatalf("Failed to join list: %v", err)
+	}
+
+	organic := models.ListRuleRequest{
+		Name:      "Organic",
+		Event:     EventItemCreated,
+		Condition: `name contains "bio"`,
+		Actions:   []models.RuleAction{{Type: ActionAddTag, Value: "organic"}},
+	}
+	if _, err := service.CreateRule(list.ID, "bob-id", organic); !errors.Is(err, ErrNotOwner) {
+		t.Errorf("Expected ErrNotOwner for members, got %v", err)
+	}
+	invalid := organic
+	invalid.Condition = `name contains`
+	if _, err := service.CreateRule(list.ID, "owner-id", invalid); err == nil {
+		t.Error("Expected an invalid condition to be rejected")
+	}
+	stranger := organic
+	stranger.Actions = []models.RuleAction{{Type: ActionNotify, Value: "stranger-id"}}
+	if _, err := service.CreateRule(list.ID, "owner-id", stranger); err == nil {
+		t.Error("Expected notifying non-members to be rejected")
+	}
+
+	if _, err := service.CreateRule(list.ID, "owner-id", organic); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	// Sees the tag added by the first rule
+	chained, err := service.CreateRule(list.ID, "owner-id", models.ListRuleRequest{
+		Name:      "Organic shelf",
+		Event:     EventItemCreated,
+		Condition: `"organic" in tags`,
+		Actions:   []models.RuleAction{{Type: ActionSetCategory, Value: "Organic"}, {Type: ActionNotify, Value: "bob-id"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	item := models.ShoppingItem{ID: "item-id", ListID: list.ID, Name: "Bio Yoghurt", Category: "Dairy", Tags: `["weekly"]`}
+	if err := db.Create(&item).Error; err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+	service.Apply(EventItemCreated, "owner-id", &item)
+	if item.Tags != `["weekly","organic"]` || item.Category != "Organic" {
+		t.Errorf("Expected rules to tag and categorize the item, got %q %q", item.Tags, item.Category)
+	}
+	var stored models.ShoppingItem
+	db.First(&stored, "id = ?", item.ID)
+	if stored.Tags != item.Tags || stored.Category != "Organic" {
+		t.Errorf("Expected rule changes to be stored, got %+v", stored)
+	}
+
+	disabled := false
+	update := organic
+	update.Enabled = &disabled
+	if _, err := service.UpdateRule(list.ID, chained.ID, "owner-id", update); err != nil {
+		t.Fatalf("Failed to update rule: %v", err)
+	}
+	listRules, _ := service.GetRules(list.ID, "owner-id")
+	if len(listRules) != 2 || listRules[1].Enabled {
+		t.Errorf("Expected the second rule to be disabled, got %+v", listRules)
+	}
+
+	if err := service.DeleteRule(list.ID, chained.ID, "owner-id"); err != nil {
+		t.Fatalf("Failed to delete rule: %v", err)
+	}
+	if err := service.DeleteRule(list.ID, chained.ID, "owner-id"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package scheduler provides a minimal in-process runner for periodic background jobs
+// such as reminders and maintenance tasks.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Job is a named task that runs at a fixed interval.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func() error
+}
+
+// JobStatus reports the outcome of a job's last run.
+type JobStatus struct {
+	Name      string
+	LastRun   time.Time
+	LastError string
+	// Stalled is set if the job has not finished a run for twice its interval.
+	Stalled bool
+}
+
+// Scheduler runs registered jobs periodically until its context is cancelled.
+type Scheduler struct {
+	jobs []Job
+	wg   sync.WaitGroup
+
+	mu        sync.Mutex
+	startedAt time.Time
+	lastRun   map[string]time.Time
+	lastError map[string]string
+}
+
+// New creates a new scheduler without any jobs.
+func New() *Scheduler {
+	return &Scheduler{
+		lastRun:   map[string]time.Time{},
+		lastError: map[string]string{},
+	}
+}
+
+// Every registers a job that runs at the given interval.
+func (s *Scheduler) Every(interval time.Duration, name string, run func() error) {
+	s.jobs = append(s.jobs, Job{Name: name, Interval: interval, Run: run})
+}
+
+// Jobs returns the registered jobs.
+func (s *Scheduler) Jobs() []Job {
+	return s.jobs
+}
+
+// Start runs every job once immediately and then at its interval in a separate goroutine.
+// Jobs stop when the context is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	s.startedAt = time.Now()
+	s.mu.Unlock()
+
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go func(job Job) {
+			defer s.wg.Done()
+
+			ticker := time.NewTicker(job.Interval)
+			defer ticker.Stop()
+
+			for {
+				s.record(job.Name, runJob(job))
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(job)
+	}
+}
+
+// Wait blocks until all jobs have stopped.
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+// Statuses reports the last run of every job. Before Start is called no job is stalled.
+func (s *Scheduler) Statuses() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		lastRun := s.lastRun[job.Name]
+		since := lastRun
+		if since.IsZero() {
+			since = s.startedAt
+		}
+		statuses = append(statuses, JobStatus{
+			Name:      job.Name,
+			LastRun:   lastRun,
+			LastError: s.lastError[job.Name],
+			Stalled:   !s.startedAt.IsZero() && now.Sub(since) > 2*job.Interval,
+		})
+	}
+	return statuses
+}
+
+// record stores the outcome of a job run.
+func (s *Scheduler) record(name string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastRun[name] = time.Now()
+	if err != nil {
+		s.lastError[name] = err.Error()
+	} else {
+		delete(s.lastError, name)
+	}
+}
+
+// runJob runs a single job, logging errors and recovering from panics so one failing
+// job cannot take down the server.
+func runJob(job Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Scheduler job %s panicked: %v", job.Name, r)
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	if err = job.Run(); err != nil {
+		log.Printf("Scheduler job %s failed: %v", job.Name, err)
+	}
+	return err
+}
@@ -0,0 +1,74 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScheduler(t *testing.T) {
+	s := New()
+
+	var runs atomic.Int32
+	s.Every(10*time.Millisecond, "counter", func() error {
+		runs.Add(1)
+		return nil
+	})
+	s.Every(10*time.Millisecond, "failing", func() error {
+		return errors.New("boom")
+	})
+	s.Every(10*time.Millisecond, "panicking", func() error {
+		panic("boom")
+	})
+
+	if len(s.Jobs()) != 3 {
+		t.Fatalf("Expected 3 jobs, got %d", len(s.Jobs()))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	s.Wait()
+
+	if runs.Load() < 2 {
+		t.Errorf("Expected job to run at least twice, ran %d times", runs.Load())
+	}
+
+	for _, status := range s.Statuses() {
+		if status.LastRun.IsZero() {
+			t.Errorf("Expected job %s to have run", status.Name)
+		}
+		if failing := status.LastError != ""; failing != (status.Name != "counter") {
+			t.Errorf("Unexpected last error of job %s: %q", status.Name, status.LastError)
+		}
+	}
+}
+
+func TestScheduler_Stalled(t *testing.T) {
+	s := New()
+	s.Every(10*time.Millisecond, "blocked", func() error {
+		time.Sleep(100 * time.Millisecond)
+		return nil
+	})
+
+	if s.Statuses()[0].Stalled {
+		t.Error("Expected jobs not to be stalled before the scheduler is started")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	time.Sleep(50 * time.Millisecond)
+
+	if !s.Statuses()[0].Stalled {
+		t.Error("Expected job blocked for longer than twice its interval to be stalled")
+	}
+
+	cancel()
+	s.Wait()
+}
@@ -7,13 +7,43 @@ package setup
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/oliverandrich/shopping-list-server/internal/codes"
+	"github.com/oliverandrich/shopping-list-server/internal/mail"
 	"github.com/oliverandrich/shopping-list-server/internal/models"
 	"gorm.io/gorm"
 )
 
+// FallbackLocale is used for default list names when no better match exists.
+const FallbackLocale = "en"
+
+// DefaultListNames holds the built-in default list name per locale.
+var DefaultListNames = map[string]string{
+	"en": "My Shopping List",
+	"de": "Meine Einkaufsliste",
+}
+
+// Groups of expensive endpoints with a per-user quota.
+const (
+	EndpointsExport = "export"
+	EndpointsImport = "import"
+	EndpointsSearch = "search"
+	EndpointsStats  = "stats"
+)
+
+// DefaultEndpointQuotas holds the requests per minute and user allowed for each endpoint group
+// unless the system settings override it.
+var DefaultEndpointQuotas = map[string]int{
+	EndpointsExport: 10,
+	EndpointsImport: 5,
+	EndpointsSearch: 60,
+	EndpointsStats:  30,
+}
+
 // Service provides system initialization and migration services.
 type Service struct {
 	DB *gorm.DB
@@ -37,8 +67,164 @@ func (s *Service) IsSystemSetup() (bool, error) {
 	return settings.IsSetup, nil
 }
 
+// IsAdmin checks if the given user is the initial admin of the system.
+func (s *Service) IsAdmin(userID string) bool {
+	var settings models.SystemSettings
+	err := s.DB.First(&settings).Error
+	return err == nil && settings.InitialAdmin != "" && settings.InitialAdmin == userID
+}
+
+// GetSettings returns the system settings.
+func (s *Service) GetSettings() (*models.SystemSettings, error) {
+	var settings models.SystemSettings
+	if err := s.DB.First(&settings).Error; err != nil {
+		return nil, err
+	}
+	if settings.DefaultListNames == nil {
+		settings.DefaultListNames = map[string]string{}
+	}
+	if settings.EndpointQuotas == nil {
+		settings.EndpointQuotas = map[string]int{}
+	}
+	return &settings, nil
+}
+
+// UpdateSettings applies the given changes to the system settings.
+func (s *Service) UpdateSettings(req models.UpdateServerSettingsRequest) (*models.SystemSettings, error) {
+	settings, err := s.GetSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	if req.AutoCreateDefaultList != nil {
+		settings.AutoCreateDefaultList = *req.AutoCreateDefaultList
+	}
+	if req.AllowDirectMemberAdd != nil {
+		settings.AllowDirectMemberAdd = *req.AllowDirectMemberAdd
+	}
+	if req.LoginCodeLength != nil {
+		if length := *req.LoginCodeLength; length != 0 && length < 6 {
+			return nil, errors.New("login code length must be 0 for the server default or at least 6")
+		}
+		settings.LoginCodeLength = *req.LoginCodeLength
+	}
+	if req.LoginCodeAlphabet != nil {
+		if _, ok := codes.Alphabets[*req.LoginCodeAlphabet]; !ok {
+			return nil, errors.New("unknown login code alphabet")
+		}
+		settings.LoginCodeAlphabet = *req.LoginCodeAlphabet
+	}
+	if req.SingleSession != nil {
+		settings.SingleSession = *req.SingleSession
+	}
+	if req.ActivityRetentionDays != nil {
+		settings.ActivityRetentionDays = *req.ActivityRetentionDays
+	}
+	if req.AuditRetentionDays != nil {
+		settings.AuditRetentionDays = *req.AuditRetentionDays
+	}
+	if req.ItemHistoryRetentionDays != nil {
+		settings.ItemHistoryRetentionDays = *req.ItemHistoryRetentionDays
+	}
+	if req.WeeklyDigest != nil {
+		settings.WeeklyDigest = *req.WeeklyDigest
+	}
+	for group, quota := range req.EndpointQuotas {
+		if _, ok := DefaultEndpointQuotas[group]; !ok {
+			return nil, fmt.Errorf("unknown endpoint group %q", group)
+		}
+		if quota == nil {
+			delete(settings.EndpointQuotas, group)
+			continue
+		}
+		if *quota < 0 {
+			return nil, errors.New("endpoint quotas cannot be negative")
+		}
+		settings.EndpointQuotas[group] = *quota
+	}
+	for locale, name := range req.DefaultListNames {
+		locale = strings.ToLower(strings.TrimSpace(locale))
+		if locale == "" {
+			return nil, errors.New("locale cannot be empty")
+		}
+		if name = strings.TrimSpace(name); name == "" {
+			delete(settings.DefaultListNames, locale)
+		} else {
+			settings.DefaultListNames[locale] = name
+		}
+	}
+
+	if err := s.DB.Save(settings).Error; err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// EndpointQuota returns the requests per minute and user allowed for a group of endpoints, 0 for
+// no quota.
+func (s *Service) EndpointQuota(group string) int {
+	settings, err := s.GetSettings()
+	if err == nil {
+		if quota, ok := settings.EndpointQuotas[group]; ok {
+			return quota
+		}
+	}
+	return DefaultEndpointQuotas[group]
+}
+
+// AutoCreateDefaultList reports whether users joining via server invitation get a default list.
+func (s *Service) AutoCreateDefaultList() bool {
+	settings, err := s.GetSettings()
+	return err != nil || settings.AutoCreateDefaultList
+}
+
+// AllowDirectMemberAdd reports whether list owners may add registered users without an invitation.
+func (s *Service) AllowDirectMemberAdd() bool {
+	settings, err := s.GetSettings()
+	return err == nil && settings.AllowDirectMemberAdd
+}
+
+// DefaultListName returns the name of a new user's default list for the preferred language,
+// given as an Accept-Language header value. Configured names take precedence over the built-in
+// ones, and the language without region is tried before falling back to English.
+func (s *Service) DefaultListName(acceptLanguage string) string {
+	overrides := map[string]string{}
+	if settings, err := s.GetSettings(); err == nil {
+		overrides = settings.DefaultListNames
+	}
+
+	for _, locale := range candidateLocales(acceptLanguage) {
+		if name, ok := overrides[locale]; ok {
+			return name
+		}
+		if name, ok := DefaultListNames[locale]; ok {
+			return name
+		}
+	}
+	return DefaultListNames[FallbackLocale]
+}
+
+// candidateLocales returns the locales to try for an Accept-Language header in the order listed,
+// e.g. "de-AT,fr;q=0.8" yields de-at, de, fr and finally the fallback locale.
+func candidateLocales(acceptLanguage string) []string {
+	var locales []string
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag := strings.ToLower(strings.TrimSpace(strings.SplitN(part, ";", 2)[0]))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		locales = append(locales, tag)
+		if base, _, found := strings.Cut(tag, "-"); found {
+			locales = append(locales, base)
+		}
+	}
+	return append(locales, FallbackLocale)
+}
+
 // SetupSystem initializes the system with an admin user and default settings.
 func (s *Service) SetupSystem(email string) (*models.User, error) {
+	email = mail.NormalizeAddress(email)
+
 	// Check if system is already setup
 	isSetup, err := s.IsSystemSetup()
 	if err != nil {
@@ -64,7 +250,7 @@ func (s *Service) SetupSystem(email string) (*models.User, error) {
 	// Create default shopping list for the admin
 	defaultList := models.ShoppingList{
 		ID:        uuid.New().String(),
-		Name:      "My Shopping List",
+		Name:      s.DefaultListName(""),
 		OwnerID:   user.ID,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -136,7 +322,7 @@ func (s *Service) MigrateExistingData() error {
 		// Create default list
 		defaultList := models.ShoppingList{
 			ID:        uuid.New().String(),
-			Name:      "My Shopping List",
+			Name:      s.DefaultListName(""),
 			OwnerID:   user.ID,
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
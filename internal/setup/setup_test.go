@@ -63,6 +63,105 @@ func TestService_IsSystemSetup(t *testing.T) {
 	})
 }
 
+func TestService_IsAdmin(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+
+	if service.IsAdmin("") {
+		t.Error("Empty user ID should never be admin before setup")
+	}
+
+	user, err := service.SetupSystem("admin@example.com")
+	if err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+
+	if !service.IsAdmin(user.ID) {
+		t.Error("Initial admin should be recognized as admin")
+	}
+
+	if service.IsAdmin("someone-else") {
+		t.Error("Other users should not be recognized as admin")
+	}
+}
+
+func TestService_DefaultListName(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+
+	if _, err := service.SetupSystem("admin@example.com"); err != nil {
+		t.Fatalf("Failed to setup system: %v", err)
+	}
+
+	tests := []struct {
+		acceptLanguage string
+		expected       string
+	}{
+		{"", "My Shopping List"},
+		{"de-AT,en;q=0.8", "Meine Einkaufsliste"},
+		{"fr-FR,fr;q=0.9", "My Shopping List"},
+		{"fr,de;q=0.8", "Meine Einkaufsliste"},
+	}
+	for _, tt := range tests {
+		if name := service.DefaultListName(tt.acceptLanguage); name != tt.expected {
+			t.Errorf("DefaultListName(%q) = %q, expected %q", tt.acceptLanguage, name, tt.expected)
+		}
+	}
+
+	disabled := false
+	_, err := service.UpdateSettings(models.UpdateServerSettingsRequest{
+		AutoCreateDefaultList: &disabled,
+		DefaultListNames:      map[string]string{"FR": "Mes courses", "en": "Groceries"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to update settings: %v", err)
+	}
+
+	if service.AutoCreateDefaultList() {
+		t.Error("Expected default list creation to be disabled")
+	}
+	if name := service.DefaultListName("fr-FR"); name != "Mes courses" {
+		t.Errorf("Expected configured French name, got %q", name)
+	}
+	if name := service.DefaultListName("es"); name != "Groceries" {
+		t.Errorf("Expected configured fallback name, got %q", name)
+	}
+
+	// An empty name removes the override
+	if _, err := service.UpdateSettings(models.UpdateServerSettingsRequest{
+		DefaultListNames: map[string]string{"en": ""},
+	}); err != nil {
+		t.Fatalf("Failed to update settings: %v", err)
+	}
+	if name := service.DefaultListName("es"); name != "My Shopping List" {
+		t.Errorf("Expected built-in name after removing override, got %q", name)
+	}
+	if service.AutoCreateDefaultList() {
+		t.Error("Expected omitted fields to be left unchanged")
+	}
+
+	length, alphabet := 8, "alphanumeric"
+	settings, err := service.UpdateSettings(models.UpdateServerSettingsRequest{LoginCodeLength: &length, LoginCodeAlphabet: &alphabet})
+	if err != nil {
+		t.Fatalf("Failed to update settings: %v", err)
+	}
+	if settings.LoginCodeLength != 8 || settings.LoginCodeAlphabet != "alphanumeric" {
+		t.Errorf("Expected the login code format to be updated, got %d %q", settings.LoginCodeLength, settings.LoginCodeAlphabet)
+	}
+	unknown := "emoji"
+	if _, err := service.UpdateSettings(models.UpdateServerSettingsRequest{LoginCodeAlphabet: &unknown}); err == nil {
+		t.Error("Expected an unknown alphabet to be rejected")
+	}
+
+	enabled := true
+	if settings, err = service.UpdateSettings(models.UpdateServerSettingsRequest{SingleSession: &enabled}); err != nil {
+		t.Fatalf("Failed to update settings: %v", err)
+	}
+	if !settings.SingleSession || settings.LoginCodeLength != 8 {
+		t.Errorf("Expected single session to be enabled and the rest unchanged, got %+v", settings)
+	}
+}
+
 func TestService_SetupSystem(t *testing.T) {
 	db := testutils.SetupTestDB(t)
 	service := NewService(db)
@@ -0,0 +1,103 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package smartlists provides user-defined saved filters that are exposed as virtual lists,
+// e.g. all open items tagged "urgent" across every list the user is a member of.
+package smartlists
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when a smart list does not exist or belongs to another user.
+var ErrNotFound = errors.New("smart list not found")
+
+// Service provides smart list management.
+type Service struct {
+	DB *gorm.DB
+}
+
+// NewService creates a new smart lists service.
+func NewService(db *gorm.DB) *Service {
+	return &Service{DB: db}
+}
+
+// GetSmartLists retrieves all smart lists of a user ordered by name.
+func (s *Service) GetSmartLists(userID string) ([]models.SmartList, error) {
+	var smartLists []models.SmartList
+	err := s.DB.Where("user_id = ?", userID).Order("name ASC").Find(&smartLists).Error
+	return smartLists, err
+}
+
+// GetSmartList retrieves a smart list of the user.
+func (s *Service) GetSmartList(id, userID string) (*models.SmartList, error) {
+	var smartList models.SmartList
+	if err := s.DB.Where("id = ? AND user_id = ?", id, userID).First(&smartList).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &smartList, nil
+}
+
+// CreateSmartList stores a new saved filter for the user.
+func (s *Service) CreateSmartList(userID, name string, filter models.ItemFilter) (*models.SmartList, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("smart list name cannot be empty")
+	}
+
+	smartList := models.SmartList{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Name:      name,
+		Filter:    filter,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.DB.Create(&smartList).Error; err != nil {
+		return nil, err
+	}
+
+	return &smartList, nil
+}
+
+// UpdateSmartList replaces the name and filter of a smart list of the user.
+func (s *Service) UpdateSmartList(id, userID, name string, filter models.ItemFilter) (*models.SmartList, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("smart list name cannot be empty")
+	}
+
+	smartList, err := s.GetSmartList(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	smartList.Name = name
+	smartList.Filter = filter
+	if err := s.DB.Save(smartList).Error; err != nil {
+		return nil, err
+	}
+
+	return smartList, nil
+}
+
+// DeleteSmartList removes a smart list of the user.
+func (s *Service) DeleteSmartList(id, userID string) error {
+	result := s.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&models.SmartList{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
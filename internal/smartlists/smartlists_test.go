@@ -0,0 +1,62 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package smartlists
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+)
+
+func TestService_SmartLists(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	service := NewService(db)
+
+	open := false
+	smartList, err := service.CreateSmartList("user-1", " Urgent ", models.ItemFilter{Tags: []string{"urgent"}, Completed: &open})
+	if err != nil {
+		t.Fatalf("Failed to create smart list: %v", err)
+	}
+	if smartList.Name != "Urgent" {
+		t.Errorf("Expected trimmed name, got %q", smartList.Name)
+	}
+
+	if _, err := service.CreateSmartList("user-1", " ", models.ItemFilter{}); err == nil {
+		t.Error("Expected empty name to be rejected")
+	}
+
+	stored, err := service.GetSmartList(smartList.ID, "user-1")
+	if err != nil {
+		t.Fatalf("Failed to get smart list: %v", err)
+	}
+	if len(stored.Filter.Tags) != 1 || stored.Filter.Completed == nil || *stored.Filter.Completed {
+		t.Errorf("Expected filter to be stored, got %+v", stored.Filter)
+	}
+
+	if _, err := service.GetSmartList(smartList.ID, "user-2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected other users not to see the smart list, got %v", err)
+	}
+
+	updated, err := service.UpdateSmartList(smartList.ID, "user-1", "Dairy", models.ItemFilter{Categories: []string{"dairy"}})
+	if err != nil {
+		t.Fatalf("Failed to update smart list: %v", err)
+	}
+	if updated.Name != "Dairy" || len(updated.Filter.Tags) != 0 || len(updated.Filter.Categories) != 1 {
+		t.Errorf("Unexpected smart list after update: %+v", updated)
+	}
+
+	smartLists, err := service.GetSmartLists("user-1")
+	if err != nil || len(smartLists) != 1 {
+		t.Fatalf("Expected 1 smart list, got %d (%v)", len(smartLists), err)
+	}
+
+	if err := service.DeleteSmartList(smartList.ID, "user-2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected other users not to delete the smart list, got %v", err)
+	}
+	if err := service.DeleteSmartList(smartList.ID, "user-1"); err != nil {
+		t.Fatalf("Failed to delete smart list: %v", err)
+	}
+}
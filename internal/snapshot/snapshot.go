@@ -0,0 +1,208 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package snapshot creates consistent copies of the live SQLite database, copies them to blob
+// storage and hands them to an operator-defined hook, and checkpoints the WAL for replication
+// tools such as Litestream.
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/storage"
+	"gorm.io/gorm"
+)
+
+// HookTimeout bounds how long a snapshot hook may run.
+const HookTimeout = 5 * time.Minute
+
+// filePrefix and fileSuffix name snapshot files, e.g. shopping-20250101T120000Z.db.
+const (
+	filePrefix = "shopping-"
+	fileSuffix = ".db"
+)
+
+// StoragePrefix is the prefix of the storage keys of snapshots, e.g. snapshots/shopping-20250101T120000Z.db.
+const StoragePrefix = "snapshots/"
+
+// ErrInvalidCheckpointMode is returned for unknown WAL checkpoint modes.
+var ErrInvalidCheckpointMode = errors.New("invalid checkpoint mode, use PASSIVE, FULL, RESTART or TRUNCATE")
+
+// ErrNotSQLite is returned for snapshots and checkpoints of databases other than SQLite, which
+// are backed up with their own tools, e.g. pg_dump.
+var ErrNotSQLite = errors.New("snapshots and checkpoints require SQLite, use the tools of your database instead")
+
+// checkpointModes lists the supported PRAGMA wal_checkpoint modes.
+var checkpointModes = map[string]bool{"PASSIVE": true, "FULL": true, "RESTART": true, "TRUNCATE": true}
+
+// Service creates database snapshots.
+type Service struct {
+	DB *gorm.DB
+	// Dir is the directory snapshots are written to.
+	Dir string
+	// Hook is a shell command run after each snapshot with SNAPSHOT_PATH set, e.g. to upload
+	// the file off-site. An empty hook keeps the snapshot locally only.
+	Hook string
+	// Keep is the number of snapshots retained in Dir; older ones are deleted. Zero keeps all.
+	Keep int
+	// Storage receives a copy of each snapshot below StoragePrefix, e.g. in an S3 bucket, which
+	// is deleted along with the file when pruned. Nil keeps snapshots in Dir only.
+	Storage storage.Storage
+
+	mu sync.Mutex
+}
+
+// NewService creates a new snapshot service writing to dir.
+func NewService(db *gorm.DB, dir string) *Service {
+	return &Service{DB: db, Dir: dir}
+}
+
+// Snapshot writes a consistent copy of the database using VACUUM INTO, which reads from a single
+// transaction so writes continue while the copy is taken. It then copies the snapshot to the
+// storage, runs the hook and prunes old snapshots. Concurrent calls are serialized.
+func (s *Service) Snapshot() (*models.SnapshotResponse, error) {
+	if s.DB.Dialector.Name() != "sqlite" {
+		return nil, ErrNotSQLite
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.Dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	createdAt := time.Now().UTC()
+	path := filepath.Join(s.Dir, filePrefix+createdAt.Format("20060102T150405Z")+fileSuffix)
+	if _, err := os.Stat(path); err == nil {
+		return nil, errors.New("a snapshot was already taken this second")
+	}
+
+	if err := s.DB.Exec("VACUUM INTO ?", path).Error; err != nil {
+		return nil, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &models.SnapshotResponse{
+		Path:      path,
+		Size:      info.Size(),
+		CreatedAt: createdAt,
+	}
+
+	if s.Storage != nil {
+		key, err := s.store(path)
+		if err != nil {
+			return snapshot, err
+		}
+		snapshot.StorageKey = key
+	}
+
+	if s.Hook != "" {
+		if err := s.runHook(path); err != nil {
+			return snapshot, err
+		}
+		snapshot.HookRun = true
+	}
+
+	if err := s.prune(); err != nil {
+		return snapshot, err
+	}
+
+	return snapshot, nil
+}
+
+// Checkpoint copies the WAL back into the database file using the given mode (PASSIVE, FULL,
+// RESTART or TRUNCATE). Databases not in WAL mode report -1 frames.
+func (s *Service) Checkpoint(mode string) (*models.CheckpointResponse, error) {
+	mode = strings.ToUpper(mode)
+	if mode == "" {
+		mode = "PASSIVE"
+	}
+	if !checkpointModes[mode] {
+		return nil, ErrInvalidCheckpointMode
+	}
+	if s.DB.Dialector.Name() != "sqlite" {
+		return nil, ErrNotSQLite
+	}
+
+	var busy, logFrames, checkpointed int
+	// The mode is validated above and cannot be passed as a parameter to PRAGMA
+	if err := s.DB.Raw("PRAGMA wal_checkpoint("+mode+")").Row().Scan(&busy, &logFrames, &checkpointed); err != nil {
+		return nil, err
+	}
+
+	return &models.CheckpointResponse{
+		Mode:               mode,
+		Busy:               busy != 0,
+		LogFrames:          logFrames,
+		CheckpointedFrames: checkpointed,
+	}, nil
+}
+
+// store copies the snapshot at path to the storage and returns its key.
+func (s *Service) store(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	key := StoragePrefix + filepath.Base(path)
+	if err := s.Storage.Put(key, data, "application/vnd.sqlite3"); err != nil {
+		return "", fmt.Errorf("failed to store snapshot: %w", err)
+	}
+	return key, nil
+}
+
+// runHook runs the snapshot hook with the path of the new snapshot in SNAPSHOT_PATH.
+func (s *Service) runHook(path string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), HookTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", s.Hook)
+	cmd.Env = append(os.Environ(), "SNAPSHOT_PATH="+path)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("snapshot hook failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// prune deletes the oldest snapshots beyond Keep, and their copies in the storage.
+func (s *Service) prune() error {
+	if s.Keep <= 0 {
+		return nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(s.Dir, filePrefix+"*"+fileSuffix))
+	if err != nil {
+		return err
+	}
+	// Timestamps in the file names sort chronologically
+	sort.Strings(files)
+
+	for len(files) > s.Keep {
+		if err := os.Remove(files[0]); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if s.Storage != nil {
+			if err := s.Storage.Delete(StoragePrefix + filepath.Base(files[0])); err != nil {
+				return err
+			}
+		}
+		files = files[1:]
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package snapshot
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/db"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/storage"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+)
+
+func TestService_Snapshot(t *testing.T) {
+	database := testutils.SetupTestDB(t)
+	dir := t.TempDir()
+	service := NewService(database, dir)
+
+	user := models.User{ID: "user-1", Email: "user@example.com", JoinedAt: time.Now(), CreatedAt: time.Now()}
+	if err := database.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	t.Run("write snapshot and run hook", func(t *testing.T) {
+		marker := filepath.Join(dir, "hook.txt")
+		service.Hook = `printf '%s' "$SNAPSHOT_PATH" > ` + marker
+
+		result, err := service.Snapshot()
+		if err != nil {
+			t.Fatalf("Failed to create snapshot: %v", err)
+		}
+		if !result.HookRun || result.Size == 0 {
+			t.Errorf("Unexpected snapshot result: %+v", result)
+		}
+
+		hookPath, _ := os.ReadFile(marker)
+		if string(hookPath) != result.Path {
+			t.Errorf("Expected hook to receive %s, got %s", result.Path, string(hookPath))
+		}
+
+		copied, err := db.Init(result.Path)
+		if err != nil {
+			t.Fatalf("Failed to open snapshot: %v", err)
+		}
+		var count int64
+		copied.Model(&models.User{}).Count(&count)
+		if count != 1 {
+			t.Errorf("Expected snapshot to contain 1 user, got %d", count)
+		}
+	})
+
+	t.Run("report failing hook", func(t *testing.T) {
+		service.Hook = "echo upload failed >&2; exit 1"
+		time.Sleep(time.Second) // snapshot names have second resolution
+
+		result, err := service.Snapshot()
+		if err == nil || !strings.Contains(err.Error(), "upload failed") {
+			t.Errorf("Expected hook error, got %v", err)
+		}
+		if result == nil || result.HookRun {
+			t.Errorf("Expected snapshot without successful hook, got %+v", result)
+		}
+	})
+
+	t.Run("prune old snapshots", func(t *testing.T) {
+		service.Hook = ""
+		service.Keep = 2
+		for _, name := range []string{"shopping-20200101T000000Z.db", "shopping-20200102T000000Z.db"} {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte("old"), 0o600); err != nil {
+				t.Fatalf("Failed to write old snapshot: %v", err)
+			}
+		}
+		time.Sleep(time.Second)
+
+		if _, err := service.Snapshot(); err != nil {
+			t.Fatalf("Failed to create snapshot: %v", err)
+		}
+
+		files, _ := filepath.Glob(filepath.Join(dir, "shopping-*.db"))
+		if len(files) != 2 {
+			t.Errorf("Expected 2 snapshots to be kept, got %v", files)
+		}
+		for _, file := range files {
+			if strings.Contains(file, "2020") {
+				t.Errorf("Expected oldest snapshots to be deleted, found %s", file)
+			}
+		}
+	})
+
+	t.Run("copy snapshots to storage", func(t *testing.T) {
+		store := storage.NewLocal(t.TempDir())
+		service.Storage = store
+		service.Keep = 1
+		defer func() { service.Storage = nil }()
+
+		var keys []string
+		for range 2 {
+			time.Sleep(time.Second)
+			result, err := service.Snapshot()
+			if err != nil {
+				t.Fatalf("Failed to create snapshot: %v", err)
+			}
+			if !strings.HasPrefix(result.StorageKey, StoragePrefix) {
+				t.Fatalf("Expected the snapshot to be stored, got %+v", result)
+			}
+			keys = append(keys, result.StorageKey)
+		}
+
+		stored, err := store.Get(keys[1])
+		if err != nil {
+			t.Fatalf("Expected the latest snapshot in storage: %v", err)
+		}
+		_ = stored.Close()
+		if _, err := store.Get(keys[0]); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("Expected the pruned snapshot to be deleted from storage, got %v", err)
+		}
+	})
+}
+
+func TestService_Checkpoint(t *testing.T) {
+	database, err := db.Init(filepath.Join(t.TempDir(), "shopping.db"))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	if err := db.EnableWAL(database); err != nil {
+		t.Fatalf("Failed to enable WAL mode: %v", err)
+	}
+	service := NewService(database, t.TempDir())
+
+	result, err := service.Checkpoint("truncate")
+	if err != nil {
+		t.Fatalf("Failed to checkpoint: %v", err)
+	}
+	if result.Mode != "TRUNCATE" || result.LogFrames < 0 {
+		t.Errorf("Unexpected checkpoint result: %+v", result)
+	}
+
+	if _, err := service.Checkpoint("everything"); !errors.Is(err, ErrInvalidCheckpointMode) {
+		t.Errorf("Expected ErrInvalidCheckpointMode, got %v", err)
+	}
+}
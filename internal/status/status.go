@@ -0,0 +1,123 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package status provides the minimal public status report used by uptime monitors. Results
+// are cached briefly so frequent polling does not hit the database and mail server each time.
+package status
+
+import (
+	"sync"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/scheduler"
+	"gopkg.in/gomail.v2"
+	"gorm.io/gorm"
+)
+
+// Overall and component statuses.
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusDown     = "down"
+	StatusDisabled = "disabled"
+)
+
+// JobReporter reports the state of background jobs.
+type JobReporter interface {
+	Statuses() []scheduler.JobStatus
+}
+
+// Service checks the health of the server's components.
+type Service struct {
+	DB *gorm.DB
+	// Mailer is dialed to check the SMTP server; nil reports mail as disabled.
+	Mailer *gomail.Dialer
+	// Jobs reports the background jobs; nil reports the scheduler as disabled.
+	Jobs JobReporter
+	// CacheTTL is how long a report is reused before the components are checked again.
+	CacheTTL time.Duration
+
+	mu        sync.Mutex
+	cached    *models.StatusResponse
+	checkedAt time.Time
+}
+
+// NewService creates a new status service.
+func NewService(db *gorm.DB, mailer *gomail.Dialer) *Service {
+	return &Service{
+		DB:       db,
+		Mailer:   mailer,
+		CacheTTL: 30 * time.Second,
+	}
+}
+
+// Report returns the status of all components. The overall status is degraded if any
+// component is not ok.
+func (s *Service) Report() *models.StatusResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached != nil && time.Since(s.checkedAt) < s.CacheTTL {
+		return s.cached
+	}
+
+	report := &models.StatusResponse{
+		Status: StatusOK,
+		Components: map[string]string{
+			"db":        s.checkDB(),
+			"mail":      s.checkMail(),
+			"scheduler": s.checkScheduler(),
+		},
+	}
+	for _, status := range report.Components {
+		if status != StatusOK && status != StatusDisabled {
+			report.Status = StatusDegraded
+		}
+	}
+
+	s.cached = report
+	s.checkedAt = time.Now()
+	return report
+}
+
+// checkDB runs a trivial query against the database.
+func (s *Service) checkDB() string {
+	var one int
+	if err := s.DB.Raw("SELECT 1").Scan(&one).Error; err != nil || one != 1 {
+		return StatusDown
+	}
+	return StatusOK
+}
+
+// checkMail connects and authenticates to the SMTP server.
+func (s *Service) checkMail() string {
+	if s.Mailer == nil || s.Mailer.Host == "" {
+		return StatusDisabled
+	}
+
+	conn, err := s.Mailer.Dial()
+	if err != nil {
+		return StatusDown
+	}
+	_ = conn.Close()
+	return StatusOK
+}
+
+// checkScheduler reports stalled jobs as down and failing jobs as degraded.
+func (s *Service) checkScheduler() string {
+	if s.Jobs == nil {
+		return StatusDisabled
+	}
+
+	status := StatusOK
+	for _, job := range s.Jobs.Statuses() {
+		if job.Stalled {
+			return StatusDown
+		}
+		if job.LastError != "" {
+			status = StatusDegraded
+		}
+	}
+	return status
+}
@@ -0,0 +1,71 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package status
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/scheduler"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+)
+
+type fakeJobs []scheduler.JobStatus
+
+func (f fakeJobs) Statuses() []scheduler.JobStatus {
+	return f
+}
+
+func TestService_Report(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+
+	t.Run("all ok", func(t *testing.T) {
+		service := NewService(db, nil)
+		service.Jobs = fakeJobs{{Name: "job", LastRun: time.Now()}}
+
+		report := service.Report()
+		if report.Status != StatusOK {
+			t.Errorf("Expected ok, got %+v", report)
+		}
+		if report.Components["db"] != StatusOK || report.Components["mail"] != StatusDisabled {
+			t.Errorf("Unexpected components: %+v", report.Components)
+		}
+	})
+
+	t.Run("failing job degrades", func(t *testing.T) {
+		service := NewService(db, nil)
+		service.Jobs = fakeJobs{{Name: "job", LastError: "boom"}}
+
+		report := service.Report()
+		if report.Status != StatusDegraded || report.Components["scheduler"] != StatusDegraded {
+			t.Errorf("Expected degraded scheduler, got %+v", report)
+		}
+	})
+
+	t.Run("stalled job is down", func(t *testing.T) {
+		service := NewService(db, nil)
+		service.Jobs = fakeJobs{{Name: "job", LastError: "boom"}, {Name: "other", Stalled: true}}
+
+		if report := service.Report(); report.Components["scheduler"] != StatusDown {
+			t.Errorf("Expected scheduler down, got %+v", report)
+		}
+	})
+
+	t.Run("cache reports", func(t *testing.T) {
+		jobs := fakeJobs{{Name: "job"}}
+		service := NewService(db, nil)
+		service.Jobs = jobs
+
+		first := service.Report()
+		jobs[0].Stalled = true
+		if second := service.Report(); second != first {
+			t.Error("Expected cached report within the cache TTL")
+		}
+
+		service.CacheTTL = 0
+		if report := service.Report(); report.Components["scheduler"] != StatusDown {
+			t.Errorf("Expected fresh report after the cache TTL, got %+v", report)
+		}
+	})
+}
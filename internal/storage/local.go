@@ -0,0 +1,74 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Local stores objects as files below a base directory.
+type Local struct {
+	Dir string
+}
+
+// NewLocal creates a local-disk storage rooted at dir.
+func NewLocal(dir string) *Local {
+	return &Local{Dir: dir}
+}
+
+// Put writes data to the file of key, creating parent directories as needed.
+func (l *Local) Put(key string, data []byte, contentType string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o640)
+}
+
+// Get opens the file of key.
+func (l *Local) Get(key string) (io.ReadCloser, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+// Delete removes the file of key.
+func (l *Local) Delete(key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// PresignGet is not supported by local storage; files are served by the API instead.
+func (l *Local) PresignGet(key string, expires time.Duration) (string, error) {
+	return "", ErrPresignNotSupported
+}
+
+// path maps a key to a file below the base directory, rejecting keys that would escape it.
+func (l *Local) path(key string) (string, error) {
+	cleaned := filepath.Clean("/" + key)
+	if cleaned == "/" || strings.Contains(key, "..") {
+		return "", errors.New("invalid storage key")
+	}
+	return filepath.Join(l.Dir, filepath.FromSlash(cleaned)), nil
+}
@@ -0,0 +1,294 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// unsignedPayload is used as payload hash for presigned URLs.
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3 stores objects in an S3-compatible bucket such as AWS S3 or MinIO. Requests are
+// signed with AWS Signature Version 4.
+type S3 struct {
+	Endpoint  *url.URL
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PathStyle addresses the bucket as https://endpoint/bucket/key instead of
+	// https://bucket.endpoint/key, as required by most MinIO setups.
+	PathStyle bool
+	Client    *http.Client
+
+	// now returns the current time and is replaced in tests.
+	now func() time.Time
+}
+
+// NewS3 creates an S3-compatible storage. An endpoint without scheme defaults to HTTPS.
+func NewS3(endpoint, region, bucket, accessKey, secretKey string, pathStyle bool) *S3 {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		u = &url.URL{Scheme: "https", Host: endpoint}
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &S3{
+		Endpoint:  u,
+		Region:    region,
+		Bucket:    bucket,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		PathStyle: pathStyle,
+		Client:    &http.Client{Timeout: 60 * time.Second},
+		now:       time.Now,
+	}
+}
+
+// Put uploads data to the bucket.
+func (s *S3) Put(key string, data []byte, contentType string) error {
+	req, err := http.NewRequest(http.MethodPut, s.objectURL(key).String(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, data)
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return s.responseError(resp)
+	}
+	return nil
+}
+
+// Get downloads an object from the bucket.
+func (s *S3) Get(key string) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, s.objectURL(key).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, nil)
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		_ = resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer func() { _ = resp.Body.Close() }()
+		return nil, s.responseError(resp)
+	}
+	return resp.Body, nil
+}
+
+// Delete removes an object from the bucket.
+func (s *S3) Delete(key string) error {
+	req, err := http.NewRequest(http.MethodDelete, s.objectURL(key).String(), nil)
+	if err != nil {
+		return err
+	}
+	s.sign(req, nil)
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return s.responseError(resp)
+	}
+	return nil
+}
+
+// PresignGet returns a presigned URL for downloading an object directly from the bucket.
+func (s *S3) PresignGet(key string, expires time.Duration) (string, error) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := s.scope(now)
+
+	u := s.objectURL(key)
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", s.AccessKey+"/"+scope)
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		u.EscapedPath(),
+		canonicalQuery(query),
+		"host:" + u.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+
+	query.Set("X-Amz-Signature", s.signature(now, amzDate, scope, canonicalRequest))
+	u.RawQuery = canonicalQuery(query)
+	return u.String(), nil
+}
+
+// objectURL returns the URL of an object using path-style or virtual-hosted-style addressing.
+func (s *S3) objectURL(key string) *url.URL {
+	u := *s.Endpoint
+	path := "/" + strings.TrimPrefix(key, "/")
+	if s.PathStyle {
+		path = "/" + s.Bucket + path
+	} else {
+		u.Host = s.Bucket + "." + u.Host
+	}
+	u.Path = path
+	u.RawPath = escapePath(path)
+	return &u
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to the request.
+func (s *S3) sign(req *http.Request, payload []byte) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := s.scope(now)
+	payloadHash := hashHex(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}
+	if contentType := req.Header.Get("Content-Type"); contentType != "" {
+		headers["content-type"] = contentType
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	signature := s.signature(now, amzDate, scope, canonicalRequest)
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, scope, signedHeaders, signature))
+}
+
+// scope returns the credential scope of a request made at t.
+func (s *S3) scope(t time.Time) string {
+	return t.Format("20060102") + "/" + s.Region + "/s3/aws4_request"
+}
+
+// signature derives the signing key and signs the canonical request.
+func (s *S3) signature(t time.Time, amzDate, scope, canonicalRequest string) string {
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), t.Format("20060102"))
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// responseError turns an unexpected S3 response into an error including the response body.
+func (s *S3) responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("s3 request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+// canonicalQuery encodes query parameters sorted by name as required for signing.
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		vals := append([]string(nil), values[key]...)
+		sort.Strings(vals)
+		for _, val := range vals {
+			parts = append(parts, uriEncode(key, true)+"="+uriEncode(val, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// escapePath URI-encodes every segment of a path.
+func escapePath(path string) string {
+	return uriEncode(path, false)
+}
+
+// uriEncode percent-encodes everything except unreserved characters, and slashes unless
+// encodeSlash is set.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,64 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package storage provides a blob storage abstraction with local-disk and S3-compatible
+// implementations, used for attachments, exports and backups.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrNotFound is returned when a stored object does not exist.
+var ErrNotFound = errors.New("object not found")
+
+// ErrPresignNotSupported is returned by backends that cannot create presigned download URLs.
+var ErrPresignNotSupported = errors.New("presigned URLs are not supported by this storage backend")
+
+// Storage stores blobs under slash-separated keys such as "attachments/<id>".
+type Storage interface {
+	// Put stores data under key, replacing any existing object.
+	Put(key string, data []byte, contentType string) error
+	// Get opens the object stored under key.
+	Get(key string) (io.ReadCloser, error)
+	// Delete removes the object stored under key. Deleting a missing object is not an error.
+	Delete(key string) error
+	// PresignGet returns a URL that allows downloading the object without authentication
+	// until it expires.
+	PresignGet(key string, expires time.Duration) (string, error)
+}
+
+// Config selects and configures a storage backend.
+type Config struct {
+	// Backend is either "local" or "s3".
+	Backend string
+	// Dir is the base directory of the local backend.
+	Dir string
+
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	S3PathStyle bool
+}
+
+// New creates the storage backend selected by the configuration.
+func New(cfg Config) (Storage, error) {
+	switch cfg.Backend {
+	case "", "local":
+		if cfg.Dir == "" {
+			return nil, errors.New("local storage requires a directory")
+		}
+		return NewLocal(cfg.Dir), nil
+	case "s3":
+		if cfg.S3Endpoint == "" || cfg.S3Bucket == "" {
+			return nil, errors.New("s3 storage requires an endpoint and a bucket")
+		}
+		return NewS3(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKey, cfg.S3SecretKey, cfg.S3PathStyle), nil
+	}
+	return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+}
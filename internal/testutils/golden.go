@@ -0,0 +1,186 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package testutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UpdateGoldenEnv is the environment variable that makes golden tests rewrite their golden files
+// from the actual responses instead of comparing against them, e.g. `UPDATE_GOLDEN=1 go test ./...`.
+const UpdateGoldenEnv = "UPDATE_GOLDEN"
+
+// seedTime is the creation time of all seeded rows, so responses do not depend on when tests run.
+var seedTime = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// Seed holds the rows created by SeedData: Alice owns the Groceries list with an open and a
+// completed item and shares it with Bob; Carol has no lists.
+type Seed struct {
+	Alice     models.User
+	Bob       models.User
+	Carol     models.User
+	Groceries models.ShoppingList
+	Milk      models.ShoppingItem
+	Bread     models.ShoppingItem
+}
+
+// SeedData creates a small fixed data set with stable IDs and timestamps.
+func SeedData(t testing.TB, db *gorm.DB) *Seed {
+	t.Helper()
+
+	seed := &Seed{
+		Alice:     models.User{ID: "seed-alice", Email: "alice@example.com", JoinedAt: seedTime, CreatedAt: seedTime},
+		Bob:       models.User{ID: "seed-bob", Email: "bob@example.com", JoinedAt: seedTime, CreatedAt: seedTime},
+		Carol:     models.User{ID: "seed-carol", Email: "carol@example.com", JoinedAt: seedTime, CreatedAt: seedTime},
+		Groceries: models.ShoppingList{ID: "seed-groceries", Name: "Groceries", OwnerID: "seed-alice", StaleAfterDays: 14, CreatedAt: seedTime, UpdatedAt: seedTime},
+	}
+	seed.Milk = models.ShoppingItem{ID: "seed-milk", ListID: seed.Groceries.ID, Name: "Milk", Quantity: 2, Unit: "l", Tags: "[]", CreatedAt: seedTime}
+	seed.Bread = models.ShoppingItem{ID: "seed-bread", ListID: seed.Groceries.ID, Name: "Bread", Completed: true, Tags: "[]", CreatedAt: seedTime.Add(-time.Hour)}
+
+	rows := []any{
+		&seed.Alice, &seed.Bob, &seed.Carol, &seed.Groceries,
+		&models.ListMember{ListID: seed.Groceries.ID, UserID: seed.Alice.ID, Role: "owner", JoinedAt: seedTime},
+		&models.ListMember{ListID: seed.Groceries.ID, UserID: seed.Bob.ID, Role: "editor", JoinedAt: seedTime},
+		&seed.Milk, &seed.Bread,
+	}
+	for _, row := range rows {
+		if err := db.Omit(clause.Associations).Create(row).Error; err != nil {
+			t.Fatalf("Failed to seed test data: %v", err)
+		}
+	}
+
+	return seed
+}
+
+// Harness sends requests to a complete Fiber app and compares the responses against golden files.
+type Harness struct {
+	T   *testing.T
+	App *fiber.App
+	// Dir holds the golden files, one JSON file per case name.
+	Dir string
+}
+
+// NewHarness creates a harness for the app with its golden files in dir.
+func NewHarness(t *testing.T, app *fiber.App, dir string) *Harness {
+	return &Harness{T: t, App: app, Dir: dir}
+}
+
+// GoldenRequest describes the request of a golden case. Token is sent as bearer token and Body,
+// if any, as JSON.
+type GoldenRequest struct {
+	Method  string
+	Path    string
+	Token   string
+	Body    string
+	Headers map[string]string
+}
+
+// Do sends the request and returns the response.
+func (h *Harness) Do(req GoldenRequest) *http.Response {
+	h.T.Helper()
+
+	httpReq := httptest.NewRequest(req.Method, req.Path, strings.NewReader(req.Body))
+	if req.Body != "" {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if req.Token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+req.Token)
+	}
+	for name, value := range req.Headers {
+		httpReq.Header.Set(name, value)
+	}
+
+	resp, err := h.App.Test(httpReq)
+	if err != nil {
+		h.T.Fatalf("Failed to make request: %v", err)
+	}
+	return resp
+}
+
+// Golden sends the request and compares the status code and JSON body of the response against
+// the golden file of the case. Generated UUIDs and timestamps are replaced by placeholders first.
+func (h *Harness) Golden(name string, req GoldenRequest) {
+	h.T.Helper()
+
+	resp := h.Do(req)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		h.T.Fatalf("Failed to read response: %v", err)
+	}
+
+	var decoded any
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &decoded); err != nil {
+			h.T.Fatalf("%s: response is not JSON: %s", name, body)
+		}
+	}
+
+	var actual bytes.Buffer
+	encoder := json.NewEncoder(&actual)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(map[string]any{"status": resp.StatusCode, "body": normalize(decoded)}); err != nil {
+		h.T.Fatalf("Failed to encode response: %v", err)
+	}
+
+	path := filepath.Join(h.Dir, name+".json")
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(h.Dir, 0o755); err != nil {
+			h.T.Fatalf("Failed to create golden directory: %v", err)
+		}
+		if err := os.WriteFile(path, actual.Bytes(), 0o644); err != nil {
+			h.T.Fatalf("Failed to write golden file: %v", err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		h.T.Fatalf("Failed to read golden file, run with %s=1 to create it: %v", UpdateGoldenEnv, err)
+	}
+	if !bytes.Equal(expected, actual.Bytes()) {
+		h.T.Errorf("%s: response differs from %s (run with %s=1 to update)\nexpected:\n%s\nactual:\n%s",
+			name, path, UpdateGoldenEnv, expected, actual.Bytes())
+	}
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// normalize replaces the values that differ between runs: UUIDs by "<uuid>" and timestamps by
+// "<time>".
+func normalize(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			v[key] = normalize(field)
+		}
+	case []any:
+		for i, element := range v {
+			v[i] = normalize(element)
+		}
+	case string:
+		if uuidPattern.MatchString(v) {
+			return "<uuid>"
+		}
+		if _, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return "<time>"
+		}
+	}
+	return value
+}
@@ -15,7 +15,7 @@ import (
 )
 
 // SetupTestDB creates an in-memory SQLite database for testing
-func SetupTestDB(t *testing.T) *gorm.DB {
+func SetupTestDB(t testing.TB) *gorm.DB {
 	t.Helper()
 
 	database, err := db.Init(":memory:")
@@ -27,7 +27,7 @@ func SetupTestDB(t *testing.T) *gorm.DB {
 }
 
 // SetupTestConfig sets up test environment variables and returns a config
-func SetupTestConfig(t *testing.T) *config.Config {
+func SetupTestConfig(t testing.TB) *config.Config {
 	t.Helper()
 
 	// Set test environment variables
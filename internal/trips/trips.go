@@ -0,0 +1,198 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package trips provides planning of shared shopping trips per list, including member RSVPs
+// and email reminders before a trip.
+package trips
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oliverandrich/shopping-list-server/internal/mail"
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/pii"
+	"gopkg.in/gomail.v2"
+	"gorm.io/gorm"
+)
+
+// Valid RSVP statuses.
+const (
+	RSVPYes   = "yes"
+	RSVPNo    = "no"
+	RSVPMaybe = "maybe"
+)
+
+// Service provides shopping trip planning operations.
+type Service struct {
+	DB     *gorm.DB
+	Mailer *gomail.Dialer
+	// ReminderLead is how long before a trip the reminder email is sent.
+	ReminderLead time.Duration
+}
+
+// NewService creates a new trips service with database and email capabilities.
+func NewService(db *gorm.DB, mailer *gomail.Dialer) *Service {
+	return &Service{
+		DB:           db,
+		Mailer:       mailer,
+		ReminderLead: 24 * time.Hour,
+	}
+}
+
+// GetTrip retrieves the planned trip of a list including all RSVPs.
+func (s *Service) GetTrip(listID string) (*models.ShoppingTrip, error) {
+	var trip models.ShoppingTrip
+	err := s.DB.Preload("RSVPs").Where("list_id = ?", listID).First(&trip).Error
+	if err != nil {
+		return nil, errors.New("no trip planned")
+	}
+	return &trip, nil
+}
+
+// PlanTrip sets the next shopping trip of a list, replacing any previously planned trip.
+func (s *Service) PlanTrip(listID, userID string, scheduledAt time.Time, note string) (*models.ShoppingTrip, error) {
+	if scheduledAt.Before(time.Now()) {
+		return nil, errors.New("trip must be scheduled in the future")
+	}
+
+	if err := s.CancelTrip(listID); err != nil {
+		return nil, err
+	}
+
+	trip := models.ShoppingTrip{
+		ID:          uuid.New().String(),
+		ListID:      listID,
+		ScheduledAt: scheduledAt,
+		Note:        strings.TrimSpace(note),
+		CreatedBy:   userID,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	if err := s.DB.Create(&trip).Error; err != nil {
+		return nil, err
+	}
+
+	// The planner is going
+	if _, err := s.RSVP(listID, userID, RSVPYes); err != nil {
+		return nil, err
+	}
+
+	return s.GetTrip(listID)
+}
+
+// CancelTrip removes the planned trip of a list and its RSVPs.
+func (s *Service) CancelTrip(listID string) error {
+	var trip models.ShoppingTrip
+	if err := s.DB.Where("list_id = ?", listID).First(&trip).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if err := s.DB.Where("trip_id = ?", trip.ID).Delete(&models.TripRSVP{}).Error; err != nil {
+		return err
+	}
+	return s.DB.Delete(&trip).Error
+}
+
+// RSVP records a member's response to the planned trip of a list.
+func (s *Service) RSVP(listID, userID, status string) (*models.TripRSVP, error) {
+	if status != RSVPYes && status != RSVPNo && status != RSVPMaybe {
+		return nil, errors.New("invalid RSVP status")
+	}
+
+	var trip models.ShoppingTrip
+	if err := s.DB.Where("list_id = ?", listID).First(&trip).Error; err != nil {
+		return nil, errors.New("no trip planned")
+	}
+
+	rsvp := models.TripRSVP{
+		TripID:    trip.ID,
+		UserID:    userID,
+		Status:    status,
+		UpdatedAt: time.Now(),
+	}
+
+	if err := s.DB.Save(&rsvp).Error; err != nil {
+		return nil, err
+	}
+
+	return &rsvp, nil
+}
+
+// SendDueReminders emails all members of lists whose trip starts within the reminder lead
+// time and marks those trips as reminded. It returns the number of trips reminded.
+func (s *Service) SendDueReminders() (int, error) {
+	now := time.Now()
+
+	var trips []models.ShoppingTrip
+	err := s.DB.Where("reminder_sent_at IS NULL AND scheduled_at > ? AND scheduled_at <= ?",
+		now, now.Add(s.ReminderLead)).Find(&trips).Error
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range trips {
+		trip := &trips[i]
+
+		var listName string
+		s.DB.Model(&models.ShoppingList{}).Select("name").Where("id = ?", trip.ListID).Scan(&listName)
+
+		var emails []string
+		s.DB.Model(&models.User{}).
+			Joins("JOIN list_members ON users.id = list_members.user_id").
+			Where("list_members.list_id = ?", trip.ListID).
+			Pluck("users.email", &emails)
+		if emails, err = pii.DecryptAll(emails); err != nil {
+			return i, err
+		}
+
+		for _, email := range emails {
+			if err := s.sendReminder(email, listName, trip); err != nil {
+				slog.Warn("Failed to send trip reminder", "list", trip.ListID, "trip", trip.ID, "error", err)
+			}
+		}
+
+		reminded := time.Now()
+		trip.ReminderSentAt = &reminded
+		if err := s.DB.Model(trip).Update("reminder_sent_at", &reminded).Error; err != nil {
+			return i, err
+		}
+	}
+
+	return len(trips), nil
+}
+
+// sendReminder sends a trip reminder email to a list member.
+func (s *Service) sendReminder(email, listName string, trip *models.ShoppingTrip) error {
+	// Skip email sending in test environment
+	if os.Getenv("GO_ENV") == "test" {
+		return nil
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", os.Getenv("SMTP_FROM"))
+	m.SetHeader("To", email)
+	m.SetHeader("Subject", fmt.Sprintf("Shopping trip for %s: %s", listName, trip.ScheduledAt.Format("Monday 15:04")))
+
+	body := fmt.Sprintf(`
+We're going shopping for "%s" on %s.
+
+Add anything you need to the list before then.
+`, listName, trip.ScheduledAt.Format("Monday, January 2 at 15:04"))
+	if trip.Note != "" {
+		body += fmt.Sprintf("\nNote: %s\n", trip.Note)
+	}
+
+	m.SetBody("text/plain", body)
+
+	return mail.Send(s.Mailer, m)
+}
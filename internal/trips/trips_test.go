@@ -0,0 +1,121 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package trips
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+)
+
+func TestService_PlanTrip(t *testing.T) {
+	testutils.SetupTestConfig(t)
+	defer testutils.CleanupTestEnv(t)
+
+	db := testutils.SetupTestDB(t)
+	service := NewService(db, nil)
+
+	t.Run("plan trip in the future", func(t *testing.T) {
+		scheduledAt := time.Now().Add(48 * time.Hour)
+		trip, err := service.PlanTrip("list-1", "user-1", scheduledAt, " Saturday market ")
+		if err != nil {
+			t.Fatalf("Failed to plan trip: %v", err)
+		}
+
+		if trip.Note != "Saturday market" {
+			t.Errorf("Expected trimmed note, got '%s'", trip.Note)
+		}
+		if len(trip.RSVPs) != 1 || trip.RSVPs[0].Status != RSVPYes {
+			t.Error("Expected planner to be RSVPed as going")
+		}
+	})
+
+	t.Run("replanning replaces trip", func(t *testing.T) {
+		scheduledAt := time.Now().Add(72 * time.Hour)
+		if _, err := service.PlanTrip("list-1", "user-2", scheduledAt, ""); err != nil {
+			t.Fatalf("Failed to replan trip: %v", err)
+		}
+
+		var count int64
+		db.Model(&models.ShoppingTrip{}).Where("list_id = ?", "list-1").Count(&count)
+		if count != 1 {
+			t.Errorf("Expected 1 trip, got %d", count)
+		}
+	})
+
+	t.Run("reject past trip", func(t *testing.T) {
+		if _, err := service.PlanTrip("list-1", "user-1", time.Now().Add(-time.Hour), ""); err == nil {
+			t.Error("Expected trip in the past to be rejected")
+		}
+	})
+
+	t.Run("rsvp", func(t *testing.T) {
+		if _, err := service.RSVP("list-1", "user-3", "maybe"); err != nil {
+			t.Fatalf("Failed to RSVP: %v", err)
+		}
+		if _, err := service.RSVP("list-1", "user-3", "perhaps"); err == nil {
+			t.Error("Expected invalid status to be rejected")
+		}
+		if _, err := service.RSVP("no-trip-list", "user-3", "yes"); err == nil {
+			t.Error("Expected RSVP without trip to fail")
+		}
+	})
+
+	t.Run("cancel trip", func(t *testing.T) {
+		if err := service.CancelTrip("list-1"); err != nil {
+			t.Fatalf("Failed to cancel trip: %v", err)
+		}
+		if _, err := service.GetTrip("list-1"); err == nil {
+			t.Error("Expected trip to be gone")
+		}
+
+		var count int64
+		db.Model(&models.TripRSVP{}).Count(&count)
+		if count != 0 {
+			t.Errorf("Expected RSVPs to be removed, got %d", count)
+		}
+	})
+}
+
+func TestService_SendDueReminders(t *testing.T) {
+	testutils.SetupTestConfig(t)
+	defer testutils.CleanupTestEnv(t)
+
+	db := testutils.SetupTestDB(t)
+	service := NewService(db, nil)
+
+	if _, err := service.PlanTrip("soon-list", "user-1", time.Now().Add(2*time.Hour), ""); err != nil {
+		t.Fatalf("Failed to plan trip: %v", err)
+	}
+	if _, err := service.PlanTrip("later-list", "user-1", time.Now().Add(72*time.Hour), ""); err != nil {
+		t.Fatalf("Failed to plan trip: %v", err)
+	}
+
+	reminded, err := service.SendDueReminders()
+	if err != nil {
+		t.Fatalf("Failed to send reminders: %v", err)
+	}
+	if reminded != 1 {
+		t.Errorf("Expected 1 reminded trip, got %d", reminded)
+	}
+
+	trip, err := service.GetTrip("soon-list")
+	if err != nil {
+		t.Fatalf("Failed to get trip: %v", err)
+	}
+	if trip.ReminderSentAt == nil {
+		t.Error("Expected reminder to be recorded")
+	}
+
+	// Reminders are only sent once
+	reminded, err = service.SendDueReminders()
+	if err != nil {
+		t.Fatalf("Failed to send reminders: %v", err)
+	}
+	if reminded != 0 {
+		t.Errorf("Expected no further reminders, got %d", reminded)
+	}
+}
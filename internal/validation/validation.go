@@ -1,54 +1,143 @@
 // Licensed under the EUPL-1.2-or-later
 // Copyright (C) 2025 Oliver Andrich
 
-// Package validation provides request validation utilities and error formatting.
+// Package validation provides request validation utilities and error formatting. Field errors
+// are machine-readable codes with parameters, rendered as messages in the client's language.
 package validation
 
 import (
 	"strings"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/oliverandrich/shopping-list-server/internal/currency"
 )
 
 var validate *validator.Validate
 
 func init() {
 	validate = validator.New()
+	// ISO 4217 codes in any case, unlike the built-in iso4217 tag
+	_ = validate.RegisterValidation("currency", func(fl validator.FieldLevel) bool {
+		return currency.Valid(fl.Field().String())
+	})
 }
 
+// Error codes of field errors. The codes of the remaining validator tags are the tags themselves.
+const (
+	CodeRequired = "required"
+	CodeInvalid  = "invalid"
+)
+
+// FallbackLanguage is the language of messages when the client accepts none of Languages.
+const FallbackLanguage = "en"
+
+// Error is a machine-readable validation error of a field, e.g. code "min" with the param
+// "min": "3".
+type Error struct {
+	Code   string            `json:"code"`
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// messages holds the message templates per language and code. Params are inserted for their
+// names in braces.
+var messages = map[string]map[string]string{
+	"en": {
+		"required": "This field is required",
+		"email":    "Must be a valid email address",
+		"min":      "Value is too short",
+		"max":      "Value is too long",
+		"uuid":     "Must be a valid UUID",
+		"oneof":    "Must be one of: {values}",
+		"hexcolor": "Must be a hex color like #4caf50",
+		"datetime": "Must be a date in the format {format}",
+		"currency": "Must be an ISO 4217 currency code like EUR",
+		"invalid":  "Invalid value",
+	},
+	"de": {
+		"required": "Dieses Feld ist erforderlich",
+		"email":    "Muss eine gültige E-Mail-Adresse sein",
+		"min":      "Der Wert ist zu kurz",
+		"max":      "Der Wert ist zu lang",
+		"uuid":     "Muss eine gültige UUID sein",
+		"oneof":    "Muss einer der folgenden Werte sein: {values}",
+		"hexcolor": "Muss eine Hex-Farbe wie #4caf50 sein",
+		"datetime": "Muss ein Datum im Format {format} sein",
+		"currency": "Muss ein ISO-4217-Währungscode wie EUR sein",
+		"invalid":  "Ungültiger Wert",
+	},
+}
+
+// Languages lists the languages messages are available in.
+var Languages = []string{"en", "de"}
+
 // ValidateStruct validates a struct using the validator tags
 func ValidateStruct(s interface{}) error {
 	return validate.Struct(s)
 }
 
-// FormatValidationErrors converts validator errors to a user-friendly map
-func FormatValidationErrors(err error) map[string]string {
-	errors := make(map[string]string)
+// Errors converts validator errors to machine-readable errors by lowercased field name.
+func Errors(err error) map[string]Error {
+	errors := make(map[string]Error)
 
 	if validationErrors, ok := err.(validator.ValidationErrors); ok {
 		for _, e := range validationErrors {
 			field := strings.ToLower(e.Field())
-			errors[field] = getErrorMessage(e)
+			errors[field] = toError(e)
 		}
 	}
 
 	return errors
 }
 
-// getErrorMessage returns a user-friendly error message for a validation error
-func getErrorMessage(e validator.FieldError) string {
+// Messages renders errors as messages in the preferred language of an Accept-Language header
+// value, falling back to English.
+func Messages(errors map[string]Error, acceptLanguage string) map[string]string {
+	templates := messages[language(acceptLanguage)]
+
+	result := make(map[string]string, len(errors))
+	for field, e := range errors {
+		message, ok := templates[e.Code]
+		if !ok {
+			message = templates[CodeInvalid]
+		}
+		for name, value := range e.Params {
+			message = strings.ReplaceAll(message, "{"+name+"}", value)
+		}
+		result[field] = message
+	}
+	return result
+}
+
+// FormatValidationErrors converts validator errors to a map of English messages
+func FormatValidationErrors(err error) map[string]string {
+	return Messages(Errors(err), FallbackLanguage)
+}
+
+// toError returns the code and params of a validator error
+func toError(e validator.FieldError) Error {
 	switch e.Tag() {
-	case "required":
-		return "This field is required"
-	case "email":
-		return "Must be a valid email address"
-	case "min":
-		return "Value is too short"
-	case "max":
-		return "Value is too long"
-	case "uuid":
-		return "Must be a valid UUID"
+	case "required", "email", "uuid", "hexcolor", "currency":
+		return Error{Code: e.Tag()}
+	case "min", "max":
+		return Error{Code: e.Tag(), Params: map[string]string{e.Tag(): e.Param()}}
+	case "oneof":
+		return Error{Code: "oneof", Params: map[string]string{"values": e.Param()}}
+	case "datetime":
+		return Error{Code: "datetime", Params: map[string]string{"format": e.Param()}}
 	default:
-		return "Invalid value"
+		return Error{Code: CodeInvalid}
+	}
+}
+
+// language picks the first language of an Accept-Language header value with messages, trying
+// each tag without its region too, e.g. "de-AT,fr;q=0.8" yields de.
+func language(acceptLanguage string) string {
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag := strings.ToLower(strings.TrimSpace(strings.SplitN(part, ";", 2)[0]))
+		base, _, _ := strings.Cut(tag, "-")
+		if _, ok := messages[base]; ok {
+			return base
+		}
 	}
+	return FallbackLanguage
 }
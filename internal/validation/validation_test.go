@@ -147,3 +147,86 @@ func TestGetErrorMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestHexColorMessage(t *testing.T) {
+	err := ValidateStruct(models.TagRequest{Name: "organic", Color: "green"})
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+
+	if message := FormatValidationErrors(err)["color"]; message != "Must be a hex color like #4caf50" {
+		t.Errorf("Unexpected color error: %s", message)
+	}
+
+	if err := ValidateStruct(models.TagRequest{Name: "organic", Color: "#4caf50"}); err != nil {
+		t.Errorf("Expected hex color to be valid, got %v", err)
+	}
+}
+
+func TestDateTimeMessage(t *testing.T) {
+	dueDate := "01.03.2025"
+	err := ValidateStruct(models.CreateItemRequest{Name: "Gift", DueDate: &dueDate})
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+
+	if message := FormatValidationErrors(err)["duedate"]; message != "Must be a date in the format 2006-01-02" {
+		t.Errorf("Unexpected due date error: %s", message)
+	}
+
+	for _, valid := range []string{"2025-03-01", ""} {
+		if err := ValidateStruct(models.CreateItemRequest{Name: "Gift", DueDate: &valid}); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", valid, err)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	dueDate := "tomorrow"
+	err := ValidateStruct(models.CreateItemRequest{Name: "", DueDate: &dueDate})
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+
+	errors := Errors(err)
+	if errors["name"].Code != CodeRequired {
+		t.Errorf("Expected code required, got %+v", errors["name"])
+	}
+	if e := errors["duedate"]; e.Code != "datetime" || e.Params["format"] != "2006-01-02" {
+		t.Errorf("Expected code datetime with format, got %+v", e)
+	}
+}
+
+func TestMessages(t *testing.T) {
+	errors := map[string]Error{
+		"name":    {Code: CodeRequired},
+		"unit":    {Code: "oneof", Params: map[string]string{"values": "g kg"}},
+		"unknown": {Code: "something_new"},
+	}
+
+	tests := []struct {
+		acceptLanguage string
+		expected       map[string]string
+	}{
+		{"", map[string]string{"name": "This field is required", "unit": "Must be one of: g kg", "unknown": "Invalid value"}},
+		{"de-AT,en;q=0.8", map[string]string{"name": "Dieses Feld ist erforderlich", "unit": "Muss einer der folgenden Werte sein: g kg", "unknown": "Ungültiger Wert"}},
+		{"fr-FR,fr;q=0.9", map[string]string{"name": "This field is required", "unit": "Must be one of: g kg", "unknown": "Invalid value"}},
+	}
+
+	for _, tt := range tests {
+		got := Messages(errors, tt.acceptLanguage)
+		for field, expected := range tt.expected {
+			if got[field] != expected {
+				t.Errorf("Accept-Language %q, field %s: expected %q, got %q", tt.acceptLanguage, field, expected, got[field])
+			}
+		}
+	}
+
+	for _, language := range Languages {
+		for code := range messages[FallbackLanguage] {
+			if _, ok := messages[language][code]; !ok {
+				t.Errorf("Missing %s message for code %s", language, code)
+			}
+		}
+	}
+}
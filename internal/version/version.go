@@ -0,0 +1,144 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+// Package version holds the build information of the running server binary and an
+// optional check against GitHub releases for available updates.
+package version
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oliverandrich/shopping-list-server/internal/models"
+)
+
+// Build information, set at build time via ldflags, e.g.
+// -ldflags "-X github.com/oliverandrich/shopping-list-server/internal/version.Version=v1.2.3".
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildDate = "unknown"
+)
+
+// ReleasesURL is the GitHub API endpoint returning the latest published release.
+const ReleasesURL = "https://api.github.com/repos/oliverandrich/shopping-list-server/releases/latest"
+
+// Info returns the build information of the running binary.
+func Info() models.VersionResponse {
+	return models.VersionResponse{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+	}
+}
+
+// UpdateChecker periodically looks up the latest release and remembers whether it is newer
+// than the running version.
+type UpdateChecker struct {
+	URL    string
+	Client *http.Client
+
+	mu     sync.RWMutex
+	status models.UpdateStatus
+}
+
+// NewUpdateChecker creates an update checker querying the GitHub releases of the project.
+func NewUpdateChecker() *UpdateChecker {
+	return &UpdateChecker{
+		URL:    ReleasesURL,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Check fetches the latest release and updates the remembered status.
+func (c *UpdateChecker) Check() error {
+	req, err := http.NewRequest(http.MethodGet, c.URL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("update check failed with status %d", resp.StatusCode)
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return err
+	}
+
+	checkedAt := time.Now()
+	c.mu.Lock()
+	c.status = models.UpdateStatus{
+		LatestVersion:   release.TagName,
+		ReleaseURL:      release.HTMLURL,
+		UpdateAvailable: IsNewer(release.TagName, Version),
+		CheckedAt:       &checkedAt,
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+// Status returns the result of the last successful check.
+func (c *UpdateChecker) Status() models.UpdateStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.status
+}
+
+// IsNewer reports whether the semantic version latest is newer than current.
+// Versions that cannot be parsed, such as development builds, are never considered outdated.
+func IsNewer(latest, current string) bool {
+	l, ok := parseSemver(latest)
+	if !ok {
+		return false
+	}
+	c, ok := parseSemver(current)
+	if !ok {
+		return false
+	}
+	for i := range l {
+		if l[i] != c[i] {
+			return l[i] > c[i]
+		}
+	}
+	return false
+}
+
+// parseSemver parses the major, minor and patch numbers of a version like "v1.2.3",
+// ignoring pre-release and build metadata.
+func parseSemver(v string) ([3]int, bool) {
+	var parts [3]int
+
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+
+	fields := strings.Split(v, ".")
+	if len(fields) != 3 {
+		return parts, false
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
@@ -0,0 +1,81 @@
+// Licensed under the EUPL-1.2-or-later
+// Copyright (C) 2025 Oliver Andrich
+
+package version
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsNewer(t *testing.T) {
+	tests := []struct {
+		latest  string
+		current string
+		want    bool
+	}{
+		{"v1.2.0", "v1.1.9", true},
+		{"v2.0.0", "v1.9.9", true},
+		{"1.2.4", "v1.2.3", true},
+		{"v1.2.3", "v1.2.3", false},
+		{"v1.2.3", "v1.3.0", false},
+		{"v1.3.0-rc.1", "v1.2.0", true},
+		{"v1.2.3", "dev", false},
+		{"nightly", "v1.2.3", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsNewer(tt.latest, tt.current); got != tt.want {
+			t.Errorf("IsNewer(%q, %q) = %v, want %v", tt.latest, tt.current, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateChecker_Check(t *testing.T) {
+	originalVersion := Version
+	Version = "v1.0.0"
+	defer func() { Version = originalVersion }()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"tag_name": "v1.1.0", "html_url": "https://example.com/releases/v1.1.0"}`))
+	}))
+	defer ts.Close()
+
+	checker := NewUpdateChecker()
+	checker.URL = ts.URL
+
+	if status := checker.Status(); status.CheckedAt != nil {
+		t.Error("Expected no status before the first check")
+	}
+
+	if err := checker.Check(); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+
+	status := checker.Status()
+	if status.LatestVersion != "v1.1.0" {
+		t.Errorf("Expected latest version v1.1.0, got %q", status.LatestVersion)
+	}
+	if !status.UpdateAvailable {
+		t.Error("Expected an update to be available")
+	}
+	if status.CheckedAt == nil {
+		t.Error("Expected checked_at to be set")
+	}
+
+	t.Run("failed lookup keeps previous status", func(t *testing.T) {
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer failing.Close()
+
+		checker.URL = failing.URL
+		if err := checker.Check(); err == nil {
+			t.Error("Expected error for failed lookup")
+		}
+		if checker.Status().LatestVersion != "v1.1.0" {
+			t.Error("Expected previous status to be kept")
+		}
+	})
+}
@@ -3,6 +3,11 @@ package main
 import (
 	"os"
 	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/oliverandrich/shopping-list-server/internal/handlers"
+	"github.com/oliverandrich/shopping-list-server/internal/testutils"
+	"gopkg.in/gomail.v2"
 )
 
 func TestMain(m *testing.M) {
@@ -32,6 +37,42 @@ func TestSetupRoutes(t *testing.T) {
 	t.Log("setupRoutes function exists and is accessible")
 }
 
+// TestGoldenResponses runs requests against the app with all its routes and seeded data and
+// compares the responses with testdata/golden. Update the files with UPDATE_GOLDEN=1 after
+// intended changes of the response format.
+func TestGoldenResponses(t *testing.T) {
+	testutils.SetupTestConfig(t)
+	database := testutils.SetupTestDB(t)
+	server := handlers.NewServer(database, []byte("test-secret"), gomail.NewDialer("localhost", 587, "test", "test"))
+	app := fiber.New()
+	setupRoutes(app, server)
+
+	seed := testutils.SeedData(t, database)
+	aliceToken, err := server.Auth.GenerateJWT(&seed.Alice)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	carolToken, err := server.Auth.GenerateJWT(&seed.Carol)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	h := testutils.NewHarness(t, app, "testdata/golden")
+	h.Golden("compact_list", testutils.GoldenRequest{
+		Method: "GET", Path: "/api/v1/lists/" + seed.Groceries.ID + "/compact", Token: aliceToken,
+	})
+	h.Golden("access_denied", testutils.GoldenRequest{
+		Method: "GET", Path: "/api/v1/lists/" + seed.Groceries.ID + "/compact", Token: carolToken,
+	})
+	h.Golden("missing_token", testutils.GoldenRequest{
+		Method: "GET", Path: "/api/v1/lists",
+	})
+	h.Golden("validation_failed_de", testutils.GoldenRequest{
+		Method: "POST", Path: "/api/v1/auth/login", Body: `{"email":"not-an-email"}`,
+		Headers: map[string]string{"Accept-Language": "de"},
+	})
+}
+
 // Note: Testing the main() function directly is challenging because it starts a server
 // In production scenarios, you would typically:
 // 1. Extract the server setup logic into testable functions